@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/spf13/cobra"
+)
+
+type reminder struct {
+	ID          uint       `json:"id"`
+	Summary     string     `json:"summary"`
+	Priority    int        `json:"priority"`
+	Kind        string     `json:"kind"`
+	Streak      int        `json:"streak"`
+	DueAt       *time.Time `json:"due_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+func newUsersCommand(c *client) *cobra.Command {
+	return &cobra.Command{
+		Use:   "users",
+		Short: "List users with their reminder counts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp struct {
+				Users []struct {
+					UserID             string `json:"user_id"`
+					OpenReminders      int64  `json:"open_reminders"`
+					CompletedReminders int64  `json:"completed_reminders"`
+					Paused             bool   `json:"paused"`
+					Delivery           string `json:"delivery"`
+				} `json:"users"`
+			}
+			data, err := c.call(http.MethodGet, "/admin/users", nil, &resp)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return writeRaw(cmd.OutOrStdout(), data)
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "USER\tOPEN\tDONE\tDELIVERY\tPAUSED")
+			for _, u := range resp.Users {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%t\n", u.UserID, u.OpenReminders, u.CompletedReminders, u.Delivery, u.Paused)
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func newRemindersCommand(c *client) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reminders",
+		Short: "List or add reminders for a user",
+	}
+
+	list := &cobra.Command{
+		Use:   "list <user-id>",
+		Short: "List a user's open reminders",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp struct {
+				Reminders []reminder `json:"reminders"`
+			}
+			data, err := c.call(http.MethodGet, "/admin/reminders?user_id="+url.QueryEscape(args[0]), nil, &resp)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return writeRaw(cmd.OutOrStdout(), data)
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tPRIORITY\tDUE\tREMINDER")
+			for _, r := range resp.Reminders {
+				fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", r.ID, r.Priority, formatDue(r.DueAt), describe(r))
+			}
+			return tw.Flush()
+		},
+	}
+
+	var (
+		priority int
+		due      string
+	)
+	add := &cobra.Command{
+		Use:   "add <user-id> <text>",
+		Short: "Add a reminder on a user's behalf",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payload := map[string]any{"user_id": args[0], "content": args[1], "priority": priority}
+			if due != "" {
+				payload["due_at"] = due
+			}
+			var created reminder
+			data, err := c.call(http.MethodPost, "/admin/reminders", payload, &created)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return writeRaw(cmd.OutOrStdout(), data)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added reminder %d: %s (priority %d)\n", created.ID, created.Summary, created.Priority)
+			return nil
+		},
+	}
+	add.Flags().IntVarP(&priority, "priority", "p", 3, "priority from 1 (low) to 5 (high)")
+	add.Flags().StringVar(&due, "due", "", "due date as YYYY-MM-DD")
+
+	cmd.AddCommand(list, add)
+	return cmd
+}
+
+func newDispatchCommand(c *client) *cobra.Command {
+	var userID string
+	cmd := &cobra.Command{
+		Use:   "dispatch",
+		Short: "Send reminders now instead of waiting for the daily run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var payload any
+			if userID != "" {
+				payload = map[string]string{"user_id": userID}
+			}
+			var resp struct {
+				Users []string `json:"users"`
+			}
+			data, err := c.call(http.MethodPost, "/admin/dispatch", payload, &resp)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return writeRaw(cmd.OutOrStdout(), data)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Dispatch started for %d user(s)\n", len(resp.Users))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&userID, "user", "", "only dispatch to this user")
+	return cmd
+}
+
+func newExportCommand(c *client) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export <user-id>",
+		Short: "Export a user's reminders, notes, and lists as JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := c.do(http.MethodGet, "/admin/export?user_id="+url.QueryEscape(args[0]), nil)
+			if err != nil {
+				return err
+			}
+			if output == "" || output == "-" {
+				return writeRaw(cmd.OutOrStdout(), data)
+			}
+			if err := os.WriteFile(output, data, 0o600); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s\n", output)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write to a file instead of stdout")
+	return cmd
+}
+
+func newImportCommand(c *client) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "import <user-id> <file>",
+		Short: "Import a Google Keep Takeout or Todoist CSV export for a user",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = "keep"
+				if strings.EqualFold(filepath.Ext(args[1]), ".csv") {
+					format = "todoist"
+				}
+			}
+			file, err := os.Open(args[1])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			query := url.Values{"user_id": {args[0]}, "format": {format}}
+			data, err := c.send(http.MethodPost, "/admin/import?"+query.Encode(), "application/octet-stream", file)
+			if err != nil {
+				return err
+			}
+			if c.json {
+				return writeRaw(cmd.OutOrStdout(), data)
+			}
+			var result struct {
+				Reminders int `json:"reminders"`
+				Memos     int `json:"memos"`
+				Skipped   int `json:"skipped"`
+			}
+			if err := json.Unmarshal(data, &result); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Imported %d reminder(s) and %d note(s), skipped %d\n", result.Reminders, result.Memos, result.Skipped)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "", "keep or todoist (default: todoist for .csv files, keep otherwise)")
+	return cmd
+}
+
+func describe(r reminder) string {
+	if r.Kind == model.KindHabit {
+		return fmt.Sprintf("Habit: %s (day %d)", r.Summary, r.Streak)
+	}
+	switch r.Kind {
+	case model.KindCountdown:
+		return "Countdown: " + r.Summary
+	case model.KindBirthday, model.KindAnniversary:
+		return "Yearly: " + r.Summary
+	}
+	return r.Summary
+}
+
+func formatDue(due *time.Time) string {
+	if due == nil {
+		return "-"
+	}
+	return due.Format("2006-01-02")
+}
+
+func writeRaw(w io.Writer, data []byte) error {
+	_, err := w.Write(data)
+	return err
+}
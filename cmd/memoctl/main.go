@@ -0,0 +1,54 @@
+// Command memoctl manages a myMemo server from the command line through its
+// admin API.
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	c := &client{http: http.Client{Timeout: 30 * time.Second}}
+	root := &cobra.Command{
+		Use:          "memoctl",
+		Short:        "Manage a myMemo server",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			c.server = strings.TrimRight(c.server, "/")
+			if c.token == "" {
+				return fmt.Errorf("an admin token is required: pass --token or set MEMOCTL_TOKEN")
+			}
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&c.server, "server", envOr("MEMOCTL_SERVER", "http://localhost:8080"), "myMemo server URL (MEMOCTL_SERVER)")
+	root.PersistentFlags().StringVar(&c.token, "token", os.Getenv("MEMOCTL_TOKEN"), "admin token (MEMOCTL_TOKEN)")
+	root.PersistentFlags().BoolVar(&c.json, "json", false, "print raw JSON responses")
+
+	root.AddCommand(
+		newUsersCommand(c),
+		newRemindersCommand(c),
+		newDispatchCommand(c),
+		newExportCommand(c),
+		newImportCommand(c),
+	)
+	return root
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
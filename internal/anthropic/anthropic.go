@@ -0,0 +1,232 @@
+// Package anthropic classifies messages and summarises reminders with
+// Anthropic's Claude models, as an alternative to OpenAI.
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+const (
+	defaultBaseURL = "https://api.anthropic.com/v1"
+	apiVersion     = "2023-06-01"
+)
+
+// DefaultModel is the Claude model used when none is configured.
+const DefaultModel = "claude-haiku-4-5"
+
+// summaryBatchSize caps how many reminders go into one SummarizeReminders
+// request.
+const summaryBatchSize = 25
+
+// APIError is an error response from the Messages API.
+type APIError struct {
+	Status int
+	Type   string
+	// Message is Anthropic's description of the error.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("anthropic: %d %s: %s", e.Status, e.Type, e.Message)
+}
+
+// Client calls the Claude Messages API.
+type Client struct {
+	// BaseURL overrides the API root, mainly for tests.
+	BaseURL string
+	apiKey  string
+	model   string
+	http    *http.Client
+}
+
+// New returns a Client using apiKey and model, or DefaultModel when model
+// is empty.
+func New(apiKey, model string) *Client {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &Client{
+		BaseURL: defaultBaseURL,
+		apiKey:  apiKey,
+		model:   model,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ClassifyIntent asks Claude for the user's intent. Claude has no token
+// probabilities to offer, so it rates its own confidence and names a
+// runner-up.
+func (c *Client) ClassifyIntent(ctx context.Context, content string) (myopenai.Classification, error) {
+	unknown := myopenai.Classification{Intent: myopenai.IntentUnknown, Alternative: myopenai.IntentUnknown}
+	if strings.TrimSpace(content) == "" {
+		return unknown, fmt.Errorf("content cannot be empty")
+	}
+
+	reply, err := c.complete(ctx, message{
+		System: `Classify the user's request for a reminder bot as one label: add_reminder, list_reminders, delete_reminder, clear_reminders, help, connect (how to set up or join the bot), or unknown. ` +
+			`Reply with only a JSON object with "intent" (the label), "confidence" (0 to 1, how sure you are), ` +
+			`and "alternative" (the next most likely label, or unknown).`,
+		User:      content,
+		MaxTokens: 60,
+	})
+	if err != nil {
+		return unknown, err
+	}
+	var parsed struct {
+		Intent      string  `json:"intent"`
+		Confidence  float64 `json:"confidence"`
+		Alternative string  `json:"alternative"`
+	}
+	if err := json.Unmarshal([]byte(stripFence(reply)), &parsed); err != nil {
+		return unknown, fmt.Errorf("anthropic: parse classification: %w", err)
+	}
+	return myopenai.Classification{
+		Intent:      myopenai.ParseIntent(parsed.Intent),
+		Confidence:  min(max(parsed.Confidence, 0), 1),
+		Alternative: myopenai.ParseIntent(parsed.Alternative),
+	}, nil
+}
+
+// SummarizeReminder summarises content in one short sentence in the named
+// language. An empty language means English.
+func (c *Client) SummarizeReminder(ctx context.Context, content, language string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("content cannot be empty")
+	}
+	return c.complete(ctx, message{
+		System:    summarySystemPrompt(language),
+		User:      fmt.Sprintf("Summarise the following reminder in one sentence: %s", content),
+		MaxTokens: 60,
+	})
+}
+
+// SummarizeReminders summarises each of contents with one request per batch
+// of reminders. The summaries come back in the order of contents.
+func (c *Client) SummarizeReminders(ctx context.Context, contents []string, language string) ([]string, error) {
+	for _, content := range contents {
+		if strings.TrimSpace(content) == "" {
+			return nil, fmt.Errorf("content cannot be empty")
+		}
+	}
+	summaries := make([]string, 0, len(contents))
+	for start := 0; start < len(contents); start += summaryBatchSize {
+		batch := contents[start:min(start+summaryBatchSize, len(contents))]
+		var numbered strings.Builder
+		for i, content := range batch {
+			fmt.Fprintf(&numbered, "%d. %s\n", i+1, strings.ReplaceAll(content, "\n", " "))
+		}
+		reply, err := c.complete(ctx, message{
+			System: summarySystemPrompt(language) + ` You will get a numbered list of reminders. ` +
+				`Reply with only a JSON array of strings holding one summary per reminder, in the same order.`,
+			User:      numbered.String(),
+			MaxTokens: 60 * len(batch),
+		})
+		if err != nil {
+			return nil, err
+		}
+		var batchSummaries []string
+		if err := json.Unmarshal([]byte(stripFence(reply)), &batchSummaries); err != nil {
+			return nil, fmt.Errorf("anthropic: parse summaries: %w", err)
+		}
+		if len(batchSummaries) != len(batch) {
+			return nil, fmt.Errorf("anthropic: got %d summaries for %d reminders", len(batchSummaries), len(batch))
+		}
+		summaries = append(summaries, batchSummaries...)
+	}
+	return summaries, nil
+}
+
+func summarySystemPrompt(language string) string {
+	system := "You summarise reminder texts in one short sentence."
+	if language != "" && language != "English" {
+		system += " Write the summary in " + language + "."
+	}
+	return system
+}
+
+// stripFence removes a Markdown code fence around a JSON reply.
+func stripFence(reply string) string {
+	reply = strings.TrimSpace(reply)
+	reply = strings.TrimPrefix(strings.TrimPrefix(reply, "```json"), "```")
+	return strings.TrimSpace(strings.TrimSuffix(reply, "```"))
+}
+
+// message is a single-turn request to the Messages API.
+type message struct {
+	System    string
+	User      string
+	MaxTokens int
+}
+
+// complete sends m and returns the trimmed text of the reply.
+func (c *Client) complete(ctx context.Context, m message) (string, error) {
+	payload := map[string]any{
+		"model":       c.model,
+		"max_tokens":  m.MaxTokens,
+		"system":      m.System,
+		"temperature": 0,
+		"messages":    []map[string]string{{"role": "user", "content": m.User}},
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("anthropic: encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.BaseURL, "/")+"/messages", bytes.NewReader(encoded))
+	if err != nil {
+		return "", fmt.Errorf("anthropic: build request: %w", err)
+	}
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var body struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		apiErr := &APIError{Status: resp.StatusCode}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil {
+			apiErr.Type, apiErr.Message = body.Error.Type, body.Error.Message
+		} else {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return "", apiErr
+	}
+
+	var out struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("anthropic: decode response: %w", err)
+	}
+	var text strings.Builder
+	for _, block := range out.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("anthropic: no text in reply")
+	}
+	return strings.TrimSpace(text.String()), nil
+}
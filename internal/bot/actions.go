@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// defaultActionPriority is used for reminders added from a compound message
+// that didn't give a priority, since there's no room to ask for one.
+const defaultActionPriority = 3
+
+var (
+	conjunctionPattern = regexp.MustCompile(`(?i)\b(?:and|then|also)\b|;`)
+	actionVerbPattern  = regexp.MustCompile(`(?i)\b(?:delete|remove|remind|add|done|finished|complete|mark)\b`)
+)
+
+// looksCompound reports whether a message may ask for several things, such
+// as "delete the rent reminder and remind me to call the plumber". It only
+// decides whether the model is worth asking.
+func looksCompound(body string) bool {
+	return conjunctionPattern.MatchString(body) && len(actionVerbPattern.FindAllString(body, -1)) >= 2
+}
+
+// handleCompoundMessage has the model split a message into several actions
+// and carries them out together. It reports false when the message isn't
+// compound or the model isn't available.
+func (b *Bot) handleCompoundMessage(ctx context.Context, userID, body string) (string, bool) {
+	if !looksCompound(body) || b.openAI == nil || !b.useOpenAI(ctx, userID) {
+		return "", false
+	}
+	actions, err := b.openAI.PlanActions(ctx, body)
+	if err != nil {
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("plan actions: %v", err)
+		}
+		return "", false
+	}
+	if len(actions) < 2 {
+		return "", false
+	}
+	return b.runActions(ctx, userID, actions), true
+}
+
+// runActions carries out actions in order inside one transaction, so either
+// all of them happen or none do, and confirms them in one reply.
+func (b *Bot) runActions(ctx context.Context, userID string, actions []myopenai.Action) string {
+	// Summaries and categories come from the model, so fetch them before
+	// the transaction.
+	var (
+		added      []int
+		contents   []string
+		summaries  = make([]string, len(actions))
+		categories = make([]string, len(actions))
+	)
+	for i, a := range actions {
+		if a.Intent == myopenai.IntentAddReminder {
+			added = append(added, i)
+			contents = append(contents, a.Text)
+			categories[i] = b.categorize(ctx, userID, a.Text)
+		}
+	}
+	for j, summary := range b.summarizeRemindersWithOpenAI(ctx, userID, contents) {
+		summaries[added[j]] = summary
+	}
+
+	now := time.Now()
+	var (
+		lines                       []string
+		created, deleted, completed []model.Reminder
+	)
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, a := range actions {
+			switch a.Intent {
+			case myopenai.IntentAddReminder:
+				priority := a.Priority
+				if priority < 1 || priority > 5 {
+					priority = defaultActionPriority
+				}
+				r := model.Reminder{UserID: userID, Content: a.Text, Priority: priority, Summary: summaries[i], Category: categories[i]}
+				if err := tx.Create(&r).Error; err != nil {
+					return err
+				}
+				created = append(created, r)
+				lines = append(lines, fmt.Sprintf("Added %s: %s (priority %d).", r.ShortID(), fallback(r.Summary, r.Content), r.Priority))
+			case myopenai.IntentDeleteReminder:
+				r, err := b.actionTarget(tx, userID, a.Text)
+				if err != nil {
+					return err
+				}
+				if err := tx.Delete(&r).Error; err != nil {
+					return err
+				}
+				deleted = append(deleted, r)
+				lines = append(lines, fmt.Sprintf("Deleted %s: %s.", r.ShortID(), fallback(r.Summary, r.Content)))
+			case myopenai.IntentCompleteReminder:
+				r, err := b.actionTarget(tx, userID, a.Text)
+				if err != nil {
+					return err
+				}
+				if r.Recurring() {
+					return userError{fmt.Sprintf("%s %s repeats, so mark it done on its own.", r.ShortID(), fallback(r.Summary, r.Content))}
+				}
+				if err := tx.Model(&r).Update("completed_at", now).Error; err != nil {
+					return err
+				}
+				completed = append(completed, r)
+				lines = append(lines, fmt.Sprintf("Marked %s done: %s.", r.ShortID(), fallback(r.Summary, r.Content)))
+			default:
+				return userError{fmt.Sprintf("I can't do '%s' as part of a longer message. Send it on its own.", a.Text)}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if isUserError(err) {
+			return err.Error() + " I haven't changed anything."
+		}
+		b.logger.Printf("run actions: %v", err)
+		return "I couldn't do all of that, so I haven't changed anything. Please try again."
+	}
+
+	b.embedReminders(ctx, userID, created)
+	for _, r := range created {
+		b.emit(webhook.EventReminderCreated, r, "")
+	}
+	for _, r := range deleted {
+		b.emit(webhook.EventReminderDeleted, r, "")
+	}
+	for _, r := range completed {
+		b.emit(webhook.EventReminderCompleted, r, "")
+	}
+	return "Done:\n- " + strings.Join(lines, "\n- ")
+}
+
+// actionTarget finds the single open reminder an action names, by code or by
+// words from its text.
+func (b *Bot) actionTarget(tx *gorm.DB, userID, text string) (model.Reminder, error) {
+	text = strings.TrimSpace(text)
+	open := func() *gorm.DB {
+		return tx.Where("user_id = ? AND completed_at IS NULL", userID)
+	}
+	if codes := parseReminderCodes(text); len(codes) == 1 {
+		var r model.Reminder
+		if err := open().Where("code = ?", codes[0]).First(&r).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return r, userError{fmt.Sprintf("You don't have an open reminder R%d.", codes[0])}
+			}
+			return r, err
+		}
+		return r, nil
+	}
+
+	var reminders []model.Reminder
+	if err := open().Clauses(b.reminderOrder(userID)).Find(&reminders).Error; err != nil {
+		return model.Reminder{}, err
+	}
+	switch matches := matchReminders(reminders, text); len(matches) {
+	case 0:
+		return model.Reminder{}, userError{fmt.Sprintf("I couldn't find a reminder matching '%s'.", text)}
+	case 1:
+		return matches[0], nil
+	default:
+		return model.Reminder{}, userError{fmt.Sprintf("'%s' matches %d reminders, so say which one on its own.", text, len(matches))}
+	}
+}
@@ -0,0 +1,384 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"gorm.io/gorm"
+)
+
+// SchedulerStatus describes what the scheduler will do next.
+type SchedulerStatus struct {
+	Timezone     string             `json:"timezone"`
+	Jobs         []JobStatus        `json:"jobs"`
+	PendingSends []PendingSendState `json:"pending_sends"`
+	PausedUsers  []string           `json:"paused_users"`
+}
+
+// JobStatus describes a registered cron job.
+type JobStatus struct {
+	Name    string     `json:"name"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+	PrevRun *time.Time `json:"prev_run,omitempty"`
+}
+
+// PendingSendState describes a delayed reminder send waiting for its slot.
+type PendingSendState struct {
+	UserID     string    `json:"user_id"`
+	ReminderID uint      `json:"reminder_id"`
+	SendAt     time.Time `json:"send_at"`
+}
+
+// SchedulerStatus reports registered jobs, pending sends, and paused users.
+func (b *Bot) SchedulerStatus() SchedulerStatus {
+	status := SchedulerStatus{
+		Timezone:     b.cfg.LocalTimezone.String(),
+		Jobs:         []JobStatus{},
+		PendingSends: []PendingSendState{},
+		PausedUsers:  []string{},
+	}
+
+	for _, entry := range b.cron.Entries() {
+		job := JobStatus{Name: b.jobName(entry.ID)}
+		if !entry.Next.IsZero() {
+			next := entry.Next
+			job.NextRun = &next
+		}
+		if !entry.Prev.IsZero() {
+			prev := entry.Prev
+			job.PrevRun = &prev
+		}
+		status.Jobs = append(status.Jobs, job)
+	}
+	sort.Slice(status.Jobs, func(i, j int) bool { return status.Jobs[i].Name < status.Jobs[j].Name })
+
+	for _, send := range b.sends.Snapshot() {
+		status.PendingSends = append(status.PendingSends, PendingSendState{
+			UserID:     send.UserID,
+			ReminderID: send.ReminderID,
+			SendAt:     send.SendAt,
+		})
+	}
+
+	if err := b.db.Model(&model.UserPreference{}).Where("paused = ?", true).Order("user_id ASC").Pluck("user_id", &status.PausedUsers).Error; err != nil {
+		b.logger.Printf("admin: load paused users: %v", err)
+	}
+	return status
+}
+
+// AdminSchedulerHandler serves the scheduler status as JSON for operators.
+func (b *Bot) AdminSchedulerHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, b.SchedulerStatus())
+	}))
+}
+
+// AdminOpenAIHandler reports how often OpenAI requests were retried after
+// rate limits and server errors, so operators can tell a flaky upstream from
+// a quiet one.
+func (b *Bot) AdminOpenAIHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var stats myopenai.RetryStats
+		if b.openAI != nil {
+			stats = b.openAI.RetryStats()
+		}
+		writeJSON(w, http.StatusOK, stats)
+	}))
+}
+
+// UserSummary describes one user for operators.
+type UserSummary struct {
+	UserID             string `json:"user_id"`
+	OpenReminders      int64  `json:"open_reminders"`
+	CompletedReminders int64  `json:"completed_reminders"`
+	Paused             bool   `json:"paused"`
+	Delivery           string `json:"delivery"`
+	OptedOut           bool   `json:"opted_out"`
+}
+
+// UserExport is everything stored for one user.
+type UserExport struct {
+	UserID     string             `json:"user_id"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Reminders  []ReminderResource `json:"reminders"`
+	Memos      []MemoExport       `json:"memos"`
+	Lists      []ListExport       `json:"lists"`
+}
+
+// MemoExport is a note or saved link in a UserExport.
+type MemoExport struct {
+	Kind      string    `json:"kind"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListExport is a named list in a UserExport.
+type ListExport struct {
+	Name   string   `json:"name"`
+	Format string   `json:"format"`
+	Items  []string `json:"items"`
+}
+
+// AdminUsersHandler lists every user who has reminders or preferences.
+func (b *Bot) AdminUsersHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		users, err := b.userSummaries()
+		if err != nil {
+			b.writeAdminError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"users": users})
+	}))
+}
+
+func (b *Bot) userSummaries() ([]UserSummary, error) {
+	var counts []struct {
+		UserID    string
+		Open      int64
+		Completed int64
+	}
+	if err := b.db.Model(&model.Reminder{}).
+		Select("user_id, SUM(CASE WHEN completed_at IS NULL THEN 1 ELSE 0 END) AS open, SUM(CASE WHEN completed_at IS NOT NULL THEN 1 ELSE 0 END) AS completed").
+		Group("user_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	var prefs []model.UserPreference
+	if err := b.db.Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[string]*UserSummary)
+	summary := func(userID string) *UserSummary {
+		if s, ok := byUser[userID]; ok {
+			return s
+		}
+		s := &UserSummary{UserID: userID, Delivery: model.DeliveryWhatsApp}
+		byUser[userID] = s
+		return s
+	}
+	for _, c := range counts {
+		s := summary(c.UserID)
+		s.OpenReminders = c.Open
+		s.CompletedReminders = c.Completed
+	}
+	for _, p := range prefs {
+		s := summary(p.UserID)
+		s.Paused = p.Paused
+		s.OptedOut = p.OptedOutAt != nil
+		if p.Delivery != "" {
+			s.Delivery = p.Delivery
+		}
+	}
+
+	users := make([]UserSummary, 0, len(byUser))
+	for _, s := range byUser {
+		users = append(users, *s)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	return users, nil
+}
+
+// adminReminderRequest is the body of POST /admin/reminders.
+type adminReminderRequest struct {
+	UserID string `json:"user_id"`
+	reminderInput
+}
+
+// AdminRemindersHandler lists a user's open reminders (GET ?user_id=) or adds
+// one on their behalf (POST).
+func (b *Bot) AdminRemindersHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			userID := r.URL.Query().Get("user_id")
+			if userID == "" {
+				b.writeAdminError(w, userError{"user_id is required"})
+				return
+			}
+			reminders, err := b.openReminders(r.Context(), userID)
+			if err != nil {
+				b.writeAdminError(w, err)
+				return
+			}
+			now := time.Now().In(b.cfg.LocalTimezone)
+			out := make([]ReminderResource, len(reminders))
+			for i, rem := range reminders {
+				out[i] = reminderResource(rem, now)
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"reminders": out})
+		case http.MethodPost:
+			var req adminReminderRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			if req.UserID == "" {
+				b.writeAdminError(w, userError{"user_id is required"})
+				return
+			}
+			reminder, err := b.createReminder(r.Context(), req.UserID, req.reminderInput)
+			if err != nil {
+				b.writeAdminError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusCreated, reminderResource(*reminder, time.Now().In(b.cfg.LocalTimezone)))
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+// AdminDispatchHandler sends reminders now instead of waiting for the daily
+// job: to one user with {"user_id": ...}, or to everyone with an empty body.
+func (b *Bot) AdminDispatchHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			UserID string `json:"user_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		if req.UserID != "" {
+			userID := req.UserID
+			if !b.sends.Go(func() { b.dispatchUserReminders(userID) }) {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "shutting down"})
+				return
+			}
+			writeJSON(w, http.StatusAccepted, map[string]any{"users": []string{userID}})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"users": b.sendScheduledReminders()})
+	}))
+}
+
+// AdminExportHandler returns everything stored for ?user_id= as JSON.
+func (b *Bot) AdminExportHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		export, err := b.exportUser(r.URL.Query().Get("user_id"))
+		if err != nil {
+			b.writeAdminError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, export)
+	}))
+}
+
+func (b *Bot) exportUser(userID string) (*UserExport, error) {
+	if userID == "" {
+		return nil, userError{"user_id is required"}
+	}
+	now := time.Now().In(b.cfg.LocalTimezone)
+	export := &UserExport{
+		UserID:     userID,
+		ExportedAt: now,
+		Reminders:  []ReminderResource{},
+		Memos:      []MemoExport{},
+		Lists:      []ListExport{},
+	}
+
+	var reminders []model.Reminder
+	if err := b.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range reminders {
+		export.Reminders = append(export.Reminders, reminderResource(r, now))
+	}
+
+	var memos []model.Memo
+	if err := b.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&memos).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range memos {
+		export.Memos = append(export.Memos, MemoExport{Kind: m.Kind, Title: m.Title, Content: m.Content, URL: m.URL, CreatedAt: m.CreatedAt})
+	}
+
+	var lists []model.List
+	if err := b.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Where("user_id = ?", userID).Order("name ASC").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range lists {
+		items := make([]string, len(l.Items))
+		for i, item := range l.Items {
+			items[i] = item.Content
+		}
+		export.Lists = append(export.Lists, ListExport{Name: l.Name, Format: l.Format, Items: items})
+	}
+	return export, nil
+}
+
+// requireAdmin rejects requests that don't carry the configured admin token.
+// Admin endpoints are disabled entirely when no token is configured.
+func (b *Bot) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.cfg.AdminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(b.cfg.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="myMemo admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeAdminError reports user errors as 400 responses and logs anything else
+// as a 500.
+func (b *Bot) writeAdminError(w http.ResponseWriter, err error) {
+	if isUserError(err) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	b.logger.Printf("admin: %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// adminUsersListed caps how many users "admin users" lists in one message.
+const adminUsersListed = 20
+
+const adminHelp = "Admin commands:\n" +
+	"- \"admin stats\" for totals across all users\n" +
+	"- \"admin users\" to list users\n" +
+	"- \"admin feedback\" for the latest feedback from users\n" +
+	"- \"admin dispatch +15551234567\" to send one user's reminders now, or \"admin dispatch all\"\n" +
+	"- \"admin pause-all\" to stop every user's reminders, and \"admin resume-all\" to restart them\n" +
+	"- \"admin announce <message>\" to send a message to everyone subscribed to announcements"
+
+// isAdmin reports whether userID is one of the configured admin numbers.
+func (b *Bot) isAdmin(userID string) bool {
+	number := normalizePhoneNumber(userID)
+	if number == "" {
+		return false
+	}
+	for _, admin := range b.cfg.AdminNumbers {
+		if normalizePhoneNumber(admin) == number {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAdminCommand answers "admin stats" and the other admin commands sent
+// from an admin number. It reports false for other messages, and for every
+// message from anyone else, so "admin ..." from a user is handled as usual.
+// Only messages from a signed Twilio webhook count, as the sender's number
+// is only known to be theirs then.
+func (b *Bot) handleAdminCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	fields := strings.Fields(strings.TrimRight(strings.TrimSpace(lowerBody), ".!?"))
+	if len(fields) == 0 || fields[0] != "admin" || !b.isAdmin(userID) || !fromSignedTwilio(ctx) {
+		return "", false
+	}
+	switch strings.Join(fields[1:], " ") {
+	case "", "help":
+		return adminHelp, true
+	case "stats":
+		return b.adminStats(), true
+	case "users":
+		return b.adminUsers(), true
+	case "feedback":
+		return b.adminFeedback(ctx), true
+	case "pause-all", "pause all":
+		return b.adminPauseAll(), true
+	case "resume-all", "resume all":
+		return b.adminResumeAll(), true
+	}
+	if fields[1] == "announce" {
+		return b.adminAnnounce(body), true
+	}
+	if fields[1] == "dispatch" {
+		return b.adminDispatch(ctx, strings.Join(fields[2:], " ")), true
+	}
+	return "I don't know that admin command.\n\n" + adminHelp, true
+}
+
+// adminStats sums up users, reminders, and the send queue.
+func (b *Bot) adminStats() string {
+	users, err := b.userSummaries()
+	if err != nil {
+		b.logger.Printf("admin stats: %v", err)
+		return "I couldn't load the stats. Please try again later."
+	}
+	var open, completed int64
+	var paused, optedOut int
+	for _, u := range users {
+		open += u.OpenReminders
+		completed += u.CompletedReminders
+		if u.Paused {
+			paused++
+		}
+		if u.OptedOut {
+			optedOut++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Bot stats:\n")
+	fmt.Fprintf(&sb, "- Users: %d (%d paused, %d opted out)\n", len(users), paused, optedOut)
+	fmt.Fprintf(&sb, "- Reminders: %d open, %d done\n", open, completed)
+	fmt.Fprintf(&sb, "- Sends waiting: %d\n", b.sends.Len())
+	if run, ok := b.dispatchTime(time.Now().In(b.cfg.LocalTimezone)); ok {
+		fmt.Fprintf(&sb, "- Next daily run: %s\n", run.Format("Mon Jan 2 3:04pm MST"))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// adminUsers lists users with their reminder counts.
+func (b *Bot) adminUsers() string {
+	users, err := b.userSummaries()
+	if err != nil {
+		b.logger.Printf("admin users: %v", err)
+		return "I couldn't load the users. Please try again later."
+	}
+	if len(users) == 0 {
+		return "No users yet."
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s:\n", plural(len(users), "user"))
+	for i, u := range users {
+		if i == adminUsersListed {
+			fmt.Fprintf(&sb, "…and %d more\n", len(users)-adminUsersListed)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s: %d open, %d done", u.UserID, u.OpenReminders, u.CompletedReminders)
+		switch {
+		case u.OptedOut:
+			sb.WriteString(", opted out")
+		case u.Paused:
+			sb.WriteString(", paused")
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// adminFeedback lists the latest feedback, newest first.
+func (b *Bot) adminFeedback(ctx context.Context) string {
+	var feedback []model.Feedback
+	if err := b.db.WithContext(ctx).Order("id DESC").Limit(adminUsersListed).Find(&feedback).Error; err != nil {
+		b.logger.Printf("admin feedback: %v", err)
+		return "I couldn't load the feedback. Please try again later."
+	}
+	if len(feedback) == 0 {
+		return "No feedback yet."
+	}
+	var sb strings.Builder
+	sb.WriteString("Latest feedback:\n")
+	for _, f := range feedback {
+		fmt.Fprintf(&sb, "- #%d %s from %s: %s\n", f.ID, f.CreatedAt.In(b.cfg.LocalTimezone).Format("Jan 2"), f.UserID, truncate(f.Text, 120))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// adminDispatch sends one user's reminders now, or everyone's for "all",
+// as the daily run would.
+func (b *Bot) adminDispatch(ctx context.Context, target string) string {
+	if target == "all" {
+		started := b.sendScheduledReminders()
+		return fmt.Sprintf("Sending reminders to %s now.", plural(len(started), "user"))
+	}
+	userID := normalizePhoneNumber(target)
+	if userID == "" {
+		return "Say which user, such as 'admin dispatch +15551234567', or 'admin dispatch all'."
+	}
+	var open int64
+	if err := b.db.WithContext(ctx).Model(&model.Reminder{}).Where("user_id = ? AND completed_at IS NULL", userID).Count(&open).Error; err != nil {
+		b.logger.Printf("admin dispatch %s: %v", userID, err)
+		return "I couldn't look up that user. Please try again later."
+	}
+	if open == 0 {
+		return fmt.Sprintf("%s has no open reminders.", userID)
+	}
+	pref := b.preferences(userID)
+	switch {
+	case pref.OptedOutAt != nil:
+		return fmt.Sprintf("%s has opted out, so nothing was sent.", userID)
+	case pref.Paused:
+		return fmt.Sprintf("%s has paused reminders, so nothing was sent.", userID)
+	}
+	if !b.sends.Go(func() { b.dispatchUserReminders(userID) }) {
+		return "The bot is shutting down, so nothing was sent."
+	}
+	return fmt.Sprintf("Sending %s to %s now.", plural(int(open), "reminder"), userID)
+}
+
+// adminPauseAll pauses every user who hasn't paused themselves, marking the
+// pause as the admin's so that adminResumeAll undoes only it.
+func (b *Bot) adminPauseAll() string {
+	users, err := b.userSummaries()
+	if err != nil {
+		b.logger.Printf("admin pause-all: %v", err)
+		return "I couldn't load the users. Please try again later."
+	}
+	paused := 0
+	for _, u := range users {
+		if u.Paused || u.OptedOut {
+			continue
+		}
+		if err := b.updatePreferences(u.UserID, func(p *model.UserPreference) {
+			p.Paused = true
+			p.PausedByAdmin = true
+		}); err != nil {
+			b.logger.Printf("admin pause-all %s: %v", u.UserID, err)
+			continue
+		}
+		paused++
+	}
+	return fmt.Sprintf("Paused reminders for %s. Send 'admin resume-all' to turn them back on.", plural(paused, "user"))
+}
+
+// adminResumeAll resumes the users adminPauseAll paused. Users who paused
+// themselves stay paused.
+func (b *Bot) adminResumeAll() string {
+	var users []string
+	if err := b.db.Model(&model.UserPreference{}).Where("paused_by_admin = ?", true).Pluck("user_id", &users).Error; err != nil {
+		b.logger.Printf("admin resume-all: %v", err)
+		return "I couldn't load the users. Please try again later."
+	}
+	resumed := 0
+	for _, userID := range users {
+		if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+			p.Paused = false
+			p.PausedByAdmin = false
+		}); err != nil {
+			b.logger.Printf("admin resume-all %s: %v", userID, err)
+			continue
+		}
+		resumed++
+	}
+	return fmt.Sprintf("Resumed reminders for %s. Users who paused reminders themselves stay paused.", plural(resumed, "user"))
+}
+
+// adminAnnounce sends the rest of an "admin announce" message, as typed, to
+// the users subscribed to announcements.
+func (b *Bot) adminAnnounce(body string) string {
+	text := strings.TrimSpace(body)
+	text = strings.TrimSpace(text[len("admin"):])
+	text = strings.TrimSpace(text[len("announce"):])
+	if text == "" {
+		return "Say what to announce, such as 'admin announce Reminders will be late today.'"
+	}
+	sent, err := b.announce(text)
+	if err != nil {
+		b.logger.Printf("admin announce: %v", err)
+		return "I couldn't send the announcement. Please try again later."
+	}
+	if sent == 0 {
+		return "Nobody has subscribed to announcements yet."
+	}
+	return fmt.Sprintf("Sending your announcement to %s.", plural(sent, "subscriber"))
+}
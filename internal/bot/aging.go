@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/config"
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm/clause"
+)
+
+// maxPriority is the highest priority a reminder can have, aged or not.
+const maxPriority = 5
+
+// agingSteps returns the PRIORITY_AGING_DAYS thresholds, or nil when aging
+// is off.
+func (b *Bot) agingSteps() []int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	// Validated on startup and reload.
+	steps, _ := config.ParsePriorityAging(b.cfg.PriorityAging)
+	return steps
+}
+
+// agedPriority returns the priority r is listed and sent with: its own,
+// raised one level for each aging threshold it has been open past, up to
+// maxPriority. Habits and countdowns come back by design and never age.
+func (b *Bot) agedPriority(r model.Reminder, now time.Time) int {
+	priority := r.Priority
+	if r.Recurring() || r.CompletedAt != nil {
+		return priority
+	}
+	for _, days := range b.agingSteps() {
+		if r.CreatedAt.Before(now.AddDate(0, 0, -days)) {
+			priority++
+		}
+	}
+	return min(priority, maxPriority)
+}
+
+// agedPriorityExpr is the SQL equivalent of agedPriority for ordering
+// queries. It only compares created_at against bound times so it works the
+// same on SQLite and Postgres.
+func (b *Bot) agedPriorityExpr(now time.Time) (string, []any) {
+	steps := b.agingSteps()
+	if len(steps) == 0 {
+		return "priority", nil
+	}
+	bumps := make([]string, len(steps))
+	vars := make([]any, len(steps))
+	for i, days := range steps {
+		bumps[i] = "CASE WHEN created_at < ? THEN 1 ELSE 0 END"
+		vars[i] = now.AddDate(0, 0, -days)
+	}
+	aged := fmt.Sprintf("priority + %s", strings.Join(bumps, " + "))
+	expr := fmt.Sprintf("(CASE WHEN kind <> '%s' THEN priority WHEN %s > %d THEN %d ELSE %s END)", model.KindReminder, aged, maxPriority, maxPriority, aged)
+	// aged appears twice in expr, so its times are bound twice.
+	return expr, append(vars, vars...)
+}
+
+// priorityLabel shows a reminder's priority in lists, e.g. "4" or "2↑4"
+// when it has aged.
+func (b *Bot) priorityLabel(r model.Reminder, now time.Time) string {
+	if aged := b.agedPriority(r, now); aged != r.Priority {
+		return fmt.Sprintf("%d↑%d", r.Priority, aged)
+	}
+	return fmt.Sprint(r.Priority)
+}
+
+// orderBy builds an ORDER BY clause that may have bound parameters. Pass it
+// to Clauses: Order only takes plain strings.
+func orderBy(sql string, vars ...any) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{SQL: sql, Vars: vars, WithoutParentheses: true}}
+}
@@ -0,0 +1,424 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/database"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// defaultAPIPriority is used when a reminder is created without a priority.
+const defaultAPIPriority = 3
+
+// ReminderResource is the REST representation of a reminder.
+type ReminderResource struct {
+	ID           uint       `json:"id"`
+	Code         string     `json:"code"`
+	Content      string     `json:"content"`
+	Summary      string     `json:"summary"`
+	Priority     int        `json:"priority"`
+	Kind         string     `json:"kind"`
+	Category     string     `json:"category,omitempty"`
+	TimeOfDay    string     `json:"time_of_day,omitempty"`
+	RRule        string     `json:"rrule,omitempty"`
+	BusinessDays bool       `json:"business_days,omitempty"`
+	Streak       int        `json:"streak,omitempty"`
+	Skips        int        `json:"skips,omitempty"`
+	DueAt        *time.Time `json:"due_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// reminderResource converts a stored reminder, reporting the streak that is
+// still alive at now for habits.
+func reminderResource(r model.Reminder, now time.Time) ReminderResource {
+	res := ReminderResource{
+		ID:           r.ID,
+		Code:         r.ShortID(),
+		Content:      r.Content,
+		Summary:      fallback(r.Summary, r.Content),
+		Priority:     r.Priority,
+		Kind:         r.Kind,
+		Category:     r.Category,
+		TimeOfDay:    r.TimeOfDay,
+		RRule:        r.RRule,
+		BusinessDays: r.BusinessDays,
+		DueAt:        r.DueAt,
+		CompletedAt:  r.CompletedAt,
+		CreatedAt:    r.CreatedAt,
+	}
+	if r.IsHabit() {
+		res.Streak = currentStreak(r, now)
+		res.Skips = r.Skips
+	}
+	return res
+}
+
+// reminderInput is the body accepted when creating or editing a reminder.
+// Omitted fields are left unchanged on edit; an empty due_at clears it.
+type reminderInput struct {
+	Content  *string `json:"content"`
+	Priority *int    `json:"priority"`
+	DueAt    *string `json:"due_at"`
+}
+
+// APIHandler serves the REST API used by the web dashboard. Every route
+// except the OpenAPI document and the automation hook requires a dashboard
+// session, and request bodies are validated against that document.
+func (b *Bot) APIHandler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/me", b.apiMe)
+	api.HandleFunc("GET /api/reminders", b.apiListReminders)
+	api.HandleFunc("POST /api/reminders", b.validated("/reminders", b.apiCreateReminder))
+	api.HandleFunc("POST /api/reminders/batch", b.validated("/reminders/batch", b.apiCreateReminders))
+	api.HandleFunc("GET /api/reminders/{id}", b.apiGetReminder)
+	api.HandleFunc("PATCH /api/reminders/{id}", b.validated("/reminders/{id}", b.apiUpdateReminder))
+	api.HandleFunc("POST /api/reminders/{id}/complete", b.apiCompleteReminder)
+	api.HandleFunc("DELETE /api/reminders/{id}", b.apiDeleteReminder)
+	api.HandleFunc("GET /api/insights", b.apiInsights)
+	api.HandleFunc("GET /api/export", b.apiExport)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/openapi.json", serveOpenAPI)
+	mux.HandleFunc("POST /api/v1/hooks/create-reminder", b.requireHookToken(b.validated("/v1/hooks/create-reminder", b.apiHookCreateReminder)))
+	mux.Handle("/api/", b.requireSession(api))
+	return mux
+}
+
+func (b *Bot) apiMe(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"user_id": sessionUserID(r.Context())})
+}
+
+// apiListReminders returns open reminders in list order, or completed ones
+// with ?status=completed. ?q= narrows either to a full-text search.
+func (b *Bot) apiListReminders(w http.ResponseWriter, r *http.Request) {
+	userID := sessionUserID(r.Context())
+	query := r.URL.Query().Get("q")
+	var (
+		reminders []model.Reminder
+		err       error
+	)
+	switch r.URL.Query().Get("status") {
+	case "", "open":
+		if query != "" {
+			reminders, err = b.searchReminders(r.Context(), userID, query)
+		} else {
+			reminders, err = b.openReminders(r.Context(), userID)
+		}
+	case "completed":
+		tx := b.db.WithContext(r.Context()).Where("user_id = ? AND completed_at IS NOT NULL", userID)
+		err = database.SearchReminders(tx, database.SearchMode(b.db), query).Order("completed_at DESC").Limit(100).Find(&reminders).Error
+	default:
+		b.writeAdminError(w, userError{"status must be open or completed"})
+		return
+	}
+	if err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+
+	now := time.Now().In(b.cfg.LocalTimezone)
+	out := make([]ReminderResource, len(reminders))
+	for i, rem := range reminders {
+		out[i] = reminderResource(rem, now)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reminders": out})
+}
+
+func (b *Bot) apiCreateReminder(w http.ResponseWriter, r *http.Request) {
+	var in reminderInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		b.writeAdminError(w, userError{"invalid JSON body"})
+		return
+	}
+	reminder, err := b.createReminder(r.Context(), sessionUserID(r.Context()), in)
+	if err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, reminderResource(*reminder, time.Now().In(b.cfg.LocalTimezone)))
+}
+
+// createReminder validates in and saves it as a new reminder for userID.
+func (b *Bot) createReminder(ctx context.Context, userID string, in reminderInput) (*model.Reminder, error) {
+	reminder, err := b.newReminder(ctx, userID, in)
+	if err != nil {
+		return nil, err
+	}
+	reminder.Summary = b.summarizeReminderWithOpenAI(ctx, userID, reminder.Content)
+	reminder.Category = b.categorize(ctx, userID, reminder.Content)
+	if err := b.db.WithContext(ctx).Create(reminder).Error; err != nil {
+		return nil, err
+	}
+	b.embedReminders(ctx, userID, []model.Reminder{*reminder})
+	b.emit(webhook.EventReminderCreated, *reminder, "")
+	return reminder, nil
+}
+
+// newReminder validates in and builds an unsaved reminder for userID,
+// without its summary or category.
+func (b *Bot) newReminder(ctx context.Context, userID string, in reminderInput) (*model.Reminder, error) {
+	if in.Content == nil || strings.TrimSpace(*in.Content) == "" {
+		return nil, userError{"content is required"}
+	}
+	priority := defaultAPIPriority
+	if in.Priority != nil {
+		priority = *in.Priority
+	}
+	if priority < 1 || priority > 5 {
+		return nil, userError{"priority must be between 1 and 5"}
+	}
+	due, err := b.parseDueInput(in.DueAt)
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(*in.Content)
+	if b.flagged(ctx, userID, "api", content) {
+		return nil, userError{"content was rejected by moderation"}
+	}
+	return &model.Reminder{
+		UserID:   userID,
+		Content:  content,
+		Priority: priority,
+		DueAt:    due,
+	}, nil
+}
+
+// maxBatchReminders caps how many reminders one bulk add may create.
+const maxBatchReminders = 100
+
+// reminderBatchInput is the body of a bulk add.
+type reminderBatchInput struct {
+	Reminders []reminderInput `json:"reminders"`
+}
+
+// apiCreateReminders adds several reminders at once, all or none, with one
+// batched summarisation call instead of one per reminder.
+func (b *Bot) apiCreateReminders(w http.ResponseWriter, r *http.Request) {
+	var in reminderBatchInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		b.writeAdminError(w, userError{"invalid JSON body"})
+		return
+	}
+	reminders, err := b.createReminders(r.Context(), sessionUserID(r.Context()), in.Reminders)
+	if err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+	now := time.Now().In(b.cfg.LocalTimezone)
+	out := make([]ReminderResource, len(reminders))
+	for i, rem := range reminders {
+		out[i] = reminderResource(rem, now)
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{"reminders": out})
+}
+
+// createReminders validates and saves inputs as new reminders for userID in
+// one transaction. The summaries come from a single batched OpenAI request.
+func (b *Bot) createReminders(ctx context.Context, userID string, inputs []reminderInput) ([]model.Reminder, error) {
+	if len(inputs) == 0 {
+		return nil, userError{"reminders must not be empty"}
+	}
+	if len(inputs) > maxBatchReminders {
+		return nil, userError{fmt.Sprintf("at most %d reminders can be added at once", maxBatchReminders)}
+	}
+
+	reminders := make([]model.Reminder, len(inputs))
+	contents := make([]string, len(inputs))
+	for i, in := range inputs {
+		reminder, err := b.newReminder(ctx, userID, in)
+		if err != nil {
+			if isUserError(err) {
+				return nil, userError{fmt.Sprintf("reminder %d: %s", i+1, err)}
+			}
+			return nil, err
+		}
+		reminders[i] = *reminder
+		contents[i] = reminder.Content
+	}
+	summaries := b.summarizeRemindersWithOpenAI(ctx, userID, contents)
+	for i := range reminders {
+		reminders[i].Summary = summaries[i]
+		reminders[i].Category = b.categorize(ctx, userID, reminders[i].Content)
+	}
+
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range reminders {
+			if err := tx.Create(&reminders[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.embedReminders(ctx, userID, reminders)
+	for _, reminder := range reminders {
+		b.emit(webhook.EventReminderCreated, reminder, "")
+	}
+	return reminders, nil
+}
+
+func (b *Bot) apiGetReminder(w http.ResponseWriter, r *http.Request) {
+	reminder, ok := b.apiReminder(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, reminderResource(reminder, time.Now().In(b.cfg.LocalTimezone)))
+}
+
+// apiUpdateReminder edits content, priority, or due date. Changing the
+// content refreshes the summary.
+func (b *Bot) apiUpdateReminder(w http.ResponseWriter, r *http.Request) {
+	reminder, ok := b.apiReminder(w, r)
+	if !ok {
+		return
+	}
+	var in reminderInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		b.writeAdminError(w, userError{"invalid JSON body"})
+		return
+	}
+
+	updates := map[string]any{}
+	if in.Content != nil {
+		content := strings.TrimSpace(*in.Content)
+		if content == "" {
+			b.writeAdminError(w, userError{"content must not be empty"})
+			return
+		}
+		if content != reminder.Content {
+			if b.flagged(r.Context(), reminder.UserID, "api", content) {
+				b.writeAdminError(w, userError{"content was rejected by moderation"})
+				return
+			}
+			reminder.Content = content
+			reminder.Summary = b.summarizeReminderWithOpenAI(r.Context(), reminder.UserID, content)
+			reminder.Category = b.categorize(r.Context(), reminder.UserID, content)
+			updates["content"] = reminder.Content
+			updates["summary"] = reminder.Summary
+			updates["category"] = reminder.Category
+		}
+	}
+	if in.Priority != nil {
+		if *in.Priority < 1 || *in.Priority > 5 {
+			b.writeAdminError(w, userError{"priority must be between 1 and 5"})
+			return
+		}
+		reminder.Priority = *in.Priority
+		updates["priority"] = reminder.Priority
+	}
+	if in.DueAt != nil {
+		due, err := b.parseDueInput(in.DueAt)
+		if err != nil {
+			b.writeAdminError(w, err)
+			return
+		}
+		reminder.OccursAt = nil
+		if due != nil {
+			moved := b.workingDue(reminder, *due)
+			reminder.OccursAt = occursAt(*due, moved)
+			*due = moved
+		}
+		if reminder.RRule != "" {
+			updates["occurs_at"] = reminder.OccursAt
+		}
+		if due != nil && reminder.DueAt != nil && due.After(*reminder.DueAt) {
+			reminder.Snoozes++
+			updates["snoozes"] = reminder.Snoozes
+		}
+		reminder.DueAt = due
+		updates["due_at"] = due
+	}
+
+	if len(updates) > 0 {
+		if err := b.db.WithContext(r.Context()).Model(&reminder).Updates(updates).Error; err != nil {
+			b.writeAdminError(w, err)
+			return
+		}
+		if _, ok := updates["content"]; ok {
+			b.embedReminders(r.Context(), reminder.UserID, []model.Reminder{reminder})
+		}
+		b.emit(webhook.EventReminderUpdated, reminder, "")
+	}
+	writeJSON(w, http.StatusOK, reminderResource(reminder, time.Now().In(b.cfg.LocalTimezone)))
+}
+
+// apiCompleteReminder checks in a habit or marks a reminder done.
+func (b *Bot) apiCompleteReminder(w http.ResponseWriter, r *http.Request) {
+	reminder, ok := b.apiReminder(w, r)
+	if !ok {
+		return
+	}
+	if reminder.CompletedAt != nil {
+		b.writeAdminError(w, userError{"reminder is already completed"})
+		return
+	}
+	now := time.Now().In(b.cfg.LocalTimezone)
+	message, err := b.completeReminder(r.Context(), &reminder, now)
+	if err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+	if !reminder.IsHabit() {
+		reminder.CompletedAt = &now
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"message": message, "reminder": reminderResource(reminder, now)})
+}
+
+func (b *Bot) apiDeleteReminder(w http.ResponseWriter, r *http.Request) {
+	reminder, ok := b.apiReminder(w, r)
+	if !ok {
+		return
+	}
+	if _, err := b.removeReminders(r.Context(), b.db.Where("user_id = ? AND id = ?", reminder.UserID, reminder.ID)); err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiReminder loads the reminder named by the {id} path value, writing a
+// 404 when it doesn't exist or belongs to someone else.
+func (b *Bot) apiReminder(w http.ResponseWriter, r *http.Request) (model.Reminder, bool) {
+	var reminder model.Reminder
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "reminder not found"})
+		return reminder, false
+	}
+	err = b.db.WithContext(r.Context()).Where("id = ? AND user_id = ?", id, sessionUserID(r.Context())).First(&reminder).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "reminder not found"})
+		return reminder, false
+	}
+	if err != nil {
+		b.writeAdminError(w, err)
+		return reminder, false
+	}
+	return reminder, true
+}
+
+// parseDueInput accepts a YYYY-MM-DD date or an RFC 3339 timestamp. A nil
+// or empty value means no due date.
+func (b *Bot) parseDueInput(value *string) (*time.Time, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+	due, ok := parseNotionDate(strings.TrimSpace(*value), b.cfg.LocalTimezone)
+	if !ok {
+		return nil, userError{"due_at must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"}
+	}
+	return &due, nil
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+const (
+	// archiveRetentionSpec runs the cleanup of archived messages older than
+	// MESSAGE_ARCHIVE_DAYS.
+	archiveRetentionSpec = "40 3 * * *"
+	// archiveResultsShown caps how many archived messages one reply lists.
+	archiveResultsShown = 10
+)
+
+var (
+	// archiveQuestionPattern matches "what did I ask you last Tuesday?" and
+	// "what did you send me yesterday", capturing who sent the messages and
+	// when or what about.
+	archiveQuestionPattern = regexp.MustCompile(`^what did (i|you) (?:ask|tell|send|say to|message|text|remind)(?: (?:you|me))?(?: (?:about|regarding) (.+?)| (.+?))?\??$`)
+	archiveSearchPattern   = regexp.MustCompile(`^(?:search|find)\s+(?:my\s+)?(?:messages|message history|chat history|history)\s+(?:for|about|with)\s+(.+?)[?.!]*$`)
+	daysAgoPattern         = regexp.MustCompile(`^(\d+|a|one|two|three|four|five|six|seven) days? ago$`)
+	hookTokenReplyPattern  = regexp.MustCompile(`(?i)(hook token: )\S+`)
+)
+
+// hiddenCredential stands in for a token or password in kept messages.
+const hiddenCredential = "[hidden]"
+
+// redactCredentials hides the tokens and passwords in a message, such as
+// "connect notion <token> ..." or a hook token reply, before it is archived
+// or kept as conversation context.
+func redactCredentials(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if m := connectNotionPattern.FindStringSubmatchIndex(trimmed); m != nil {
+		return trimmed[:m[2]] + hiddenCredential + trimmed[m[3]:]
+	}
+	if m := connectCalDAVPattern.FindStringSubmatchIndex(trimmed); m != nil {
+		return trimmed[:m[6]] + hiddenCredential + trimmed[m[7]:]
+	}
+	return hookTokenReplyPattern.ReplaceAllString(body, "${1}"+hiddenCredential)
+}
+
+// archiveMessage records a message to or from userID when the archive is on.
+func (b *Bot) archiveMessage(ctx context.Context, userID, direction, body string) {
+	if b.cfg.MessageArchiveDays <= 0 || strings.TrimSpace(body) == "" {
+		return
+	}
+	message := model.ArchivedMessage{UserID: userID, Direction: direction, Body: redactCredentials(plainText(body))}
+	if err := b.db.WithContext(ctx).Create(&message).Error; err != nil {
+		b.logger.Printf("archive message for %s: %v", userID, err)
+	}
+}
+
+// pruneArchive deletes archived messages older than MESSAGE_ARCHIVE_DAYS.
+func (b *Bot) pruneArchive() {
+	if b.cfg.MessageArchiveDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -b.cfg.MessageArchiveDays)
+	result := b.db.Where("created_at < ?", cutoff).Delete(&model.ArchivedMessage{})
+	if result.Error != nil {
+		b.logger.Printf("scheduler: prune message archive: %v", result.Error)
+		return
+	}
+	if result.RowsAffected > 0 {
+		b.logger.Printf("scheduler: pruned %d archived messages", result.RowsAffected)
+	}
+}
+
+// handleArchiveCommand answers questions about past messages, such as "what
+// did I ask you last Tuesday?" or "search my messages for dentist", and
+// clears the archive on "delete my message history". It reports false for
+// other messages.
+func (b *Bot) handleArchiveCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	lowerBody = strings.TrimSpace(lowerBody)
+	switch strings.TrimRight(lowerBody, ".!") {
+	case "delete my message history", "clear my message history", "delete my chat history", "clear my chat history":
+		return b.clearArchive(ctx, userID), true
+	}
+
+	direction, since, until, words := "", time.Time{}, time.Time{}, ""
+	now := time.Now().In(b.location(b.preferences(userID)))
+	if m := archiveQuestionPattern.FindStringSubmatch(lowerBody); m != nil {
+		direction = model.DirectionIn
+		if m[1] == "you" {
+			direction = model.DirectionOut
+		}
+		switch {
+		case m[2] != "":
+			words = m[2]
+		case m[3] != "":
+			var ok bool
+			if since, until, ok = parsePastDays(m[3], now); !ok {
+				return "", false
+			}
+		}
+	} else if m := archiveSearchPattern.FindStringSubmatch(lowerBody); m != nil {
+		words = m[1]
+	} else {
+		return "", false
+	}
+	if b.cfg.MessageArchiveDays <= 0 {
+		return "I don't keep a history of our messages on this server.", true
+	}
+
+	query := b.db.WithContext(ctx).Where("user_id = ?", userID)
+	if direction != "" {
+		query = query.Where("direction = ?", direction)
+	}
+	if !since.IsZero() {
+		query = query.Where("created_at >= ? AND created_at < ?", since, until)
+	}
+	for _, word := range strings.Fields(words) {
+		query = query.Where("LOWER(body) LIKE ?", "%"+strings.ToLower(word)+"%")
+	}
+	var messages []model.ArchivedMessage
+	if err := query.Order("created_at DESC, id DESC").Limit(archiveResultsShown + 1).Find(&messages).Error; err != nil {
+		b.logger.Printf("search message archive for %s: %v", userID, err)
+		return "I couldn't search your messages right now. Please try again later.", true
+	}
+
+	what := "messages"
+	switch direction {
+	case model.DirectionIn:
+		what = "messages from you"
+	case model.DirectionOut:
+		what = "messages from me"
+	}
+	switch {
+	case words != "":
+		what += fmt.Sprintf(" about '%s'", words)
+	case !since.IsZero():
+		what += " " + describePastDays(since, until, now)
+	}
+	if len(messages) == 0 {
+		return fmt.Sprintf("I couldn't find any %s. I keep our messages for %s.", what, plural(b.cfg.MessageArchiveDays, "day")), true
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Your %s, newest first:\n", what)
+	for i, m := range messages {
+		if i == archiveResultsShown {
+			sb.WriteString("…and more. Ask about a shorter time or add a word to narrow it down.\n")
+			break
+		}
+		who := "You"
+		if m.Direction == model.DirectionOut {
+			who = "Me"
+		}
+		fmt.Fprintf(&sb, "- %s, %s: %s\n", m.CreatedAt.In(now.Location()).Format("Mon Jan 2 3:04pm"), who, truncate(m.Body, 200))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), true
+}
+
+// clearArchive deletes the user's archived messages.
+func (b *Bot) clearArchive(ctx context.Context, userID string) string {
+	result := b.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.ArchivedMessage{})
+	if result.Error != nil {
+		b.logger.Printf("clear message archive for %s: %v", userID, result.Error)
+		return "I couldn't delete your message history. Please try again later."
+	}
+	return fmt.Sprintf("Deleted %s from your history.", plural(int(result.RowsAffected), "message"))
+}
+
+// parsePastDays reads "today", "yesterday", "last Tuesday", "3 days ago",
+// "this week", "last week", or a date such as "Mar 3" as a span of past
+// days. It returns the start of the first day and the start of the day after
+// the last.
+func parsePastDays(text string, now time.Time) (time.Time, time.Time, bool) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "on ")
+	today := startOfDay(now)
+	intoWeek := (int(now.Weekday()) + 6) % 7
+	switch text {
+	case "today":
+		return today, today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, true
+	case "this week":
+		return today.AddDate(0, 0, -intoWeek), today.AddDate(0, 0, 1), true
+	case "last week":
+		monday := today.AddDate(0, 0, -intoWeek-7)
+		return monday, monday.AddDate(0, 0, 7), true
+	}
+	if m := daysAgoPattern.FindStringSubmatch(text); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			n = map[string]int{"a": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7}[m[1]]
+		}
+		day := today.AddDate(0, 0, -n)
+		return day, day.AddDate(0, 0, 1), true
+	}
+	name := strings.TrimPrefix(text, "last ")
+	for _, weekday := range weekdaysFromMonday {
+		if name == strings.ToLower(weekday.String()) {
+			back := (int(now.Weekday()) - int(weekday) + 7) % 7
+			if back == 0 {
+				back = 7
+			}
+			day := today.AddDate(0, 0, -back)
+			return day, day.AddDate(0, 0, 1), true
+		}
+	}
+	if day, ok := parseDay(text, now); ok {
+		// parseDay reads a date without a year as the next one.
+		if day.After(today) {
+			day = day.AddDate(-1, 0, 0)
+		}
+		return day, day.AddDate(0, 0, 1), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// describePastDays names a span from parsePastDays for a reply.
+func describePastDays(since, until, now time.Time) string {
+	if until.Sub(since) <= 25*time.Hour {
+		switch daysUntil(now, since) {
+		case 0:
+			return "today"
+		case 1:
+			return "yesterday"
+		}
+		return "on " + since.Format("Mon Jan 2")
+	}
+	return fmt.Sprintf("from %s to %s", since.Format("Mon Jan 2"), until.AddDate(0, 0, -1).Format("Mon Jan 2"))
+}
@@ -0,0 +1,288 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/config"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/storage"
+)
+
+const (
+	// maxAttachmentBytes caps the size of a photo or file kept with a
+	// reminder. WhatsApp itself allows up to 16 MB.
+	maxAttachmentBytes = 16 << 20
+	// attachmentLinkTTL is how long the link in a reminder message works.
+	attachmentLinkTTL = 7 * 24 * time.Hour
+)
+
+// twilioMediaHosts are the hosts Twilio serves media from. Account
+// credentials are only ever sent to them.
+var twilioMediaHosts = map[string]bool{
+	"api.twilio.com": true,
+}
+
+// attachmentExtensions names stored files for the media types WhatsApp
+// sends most, where mime.ExtensionsByType would pick an odd extension.
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"audio/ogg":       ".ogg",
+	"audio/mpeg":      ".mp3",
+	"video/mp4":       ".mp4",
+}
+
+var (
+	attachmentReminderPattern = regexp.MustCompile(`(?i)^remind me (?:about|of) (?:this|that)(?: (?:photo|picture|image|file|document|attachment|pdf))?\s*[:,-]\s*(.+?)[.!]*$`)
+	attachFilePattern         = regexp.MustCompile(`(?i)^(?:attach|add|link) (?:this|that|the) (?:photo|picture|image|file|document|attachment|pdf) to (r\d+)[.!]*$`)
+)
+
+// sharedMedia is the first photo or file Twilio reports on an incoming
+// message.
+type sharedMedia struct {
+	URL         string
+	ContentType string
+}
+
+// pendingAttachment is a stored photo or file waiting to be attached to a
+// reminder.
+type pendingAttachment struct {
+	Key         string
+	ContentType string
+}
+
+// parseMedia reads the NumMedia, MediaUrl0, and MediaContentType0 fields
+// Twilio posts when a user sends a photo or file.
+func parseMedia(form map[string]string) (sharedMedia, bool) {
+	if n, err := strconv.Atoi(form["NumMedia"]); err != nil || n < 1 || form["MediaUrl0"] == "" {
+		return sharedMedia{}, false
+	}
+	return sharedMedia{URL: form["MediaUrl0"], ContentType: form["MediaContentType0"]}, true
+}
+
+// newBlobStore returns the blob store cfg configures. The disk store's links
+// point at FilesHandler under PUBLIC_BASE_URL, so without one it can keep
+// files but not link to them.
+func newBlobStore(cfg *config.Config, logger *log.Logger) storage.BlobStore {
+	key := []byte(cfg.BlobSigningKey)
+	if len(key) == 0 {
+		// Links then stop working when the process restarts.
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			logger.Printf("storage: attachments disabled: %v", err)
+			return nil
+		}
+	}
+	baseURL := ""
+	if cfg.PublicBaseURL != "" {
+		baseURL = cfg.PublicBaseURL + "/files"
+	}
+	store, err := storage.New(storage.Config{
+		Backend:    cfg.BlobStore,
+		Dir:        cfg.BlobDir,
+		BaseURL:    baseURL,
+		SigningKey: key,
+		S3: storage.S3Config{
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			Endpoint:        cfg.S3Endpoint,
+			PathStyle:       cfg.S3PathStyle,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			SessionToken:    cfg.S3SessionToken,
+		},
+	})
+	if err != nil {
+		logger.Printf("storage: attachments disabled: %v", err)
+		return nil
+	}
+	return store
+}
+
+// FilesHandler serves files from the disk blob store to holders of a signed
+// link. Other stores hand out their own links, so it serves nothing for
+// them.
+func (b *Bot) FilesHandler() http.Handler {
+	disk, ok := b.blobs.(*storage.Disk)
+	if !ok {
+		return http.NotFoundHandler()
+	}
+	return http.StripPrefix("/files", disk.Handler())
+}
+
+// receiveMedia stores a photo or file the user sent. With a caption it saves
+// a reminder with the file right away; otherwise it holds the file until the
+// user says what it is for.
+func (b *Bot) receiveMedia(ctx context.Context, userID string, media sharedMedia, caption string) string {
+	if b.blobs == nil {
+		return "I can't keep photos or files on this server. Send your reminder as text instead."
+	}
+	attachment, err := b.storeMedia(ctx, userID, media)
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("receive media from %s: %v", userID, err)
+		}
+		return err.Error()
+	}
+	if caption = strings.TrimSpace(caption); caption != "" {
+		return b.saveAttachmentReminder(ctx, userID, attachment, caption)
+	}
+	b.state.SetPendingAttachment(userID, attachment)
+	return "Got the file. Reply 'remind me about this: renew passport' to save a reminder with it, or 'attach this file to R3' to add it to one you have."
+}
+
+// storeMedia downloads media from Twilio and puts it in the blob store under
+// a random name in a folder for the user.
+func (b *Bot) storeMedia(ctx context.Context, userID string, media sharedMedia) (pendingAttachment, error) {
+	u, err := url.Parse(media.URL)
+	if err != nil || u.Scheme != "https" || !twilioMediaHosts[strings.ToLower(u.Host)] {
+		return pendingAttachment{}, fmt.Errorf("download media: %q is not a Twilio media URL", media.URL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return pendingAttachment{}, err
+	}
+	// Twilio media URLs need the account's credentials when HTTP basic
+	// auth is enabled for media. The redirect to Twilio's CDN drops them,
+	// as it goes to another host.
+	tenant, err := b.userTenant(ctx, userID)
+	if err != nil {
+		return pendingAttachment{}, fmt.Errorf("load tenant: %w", err)
+	}
+	req.SetBasicAuth(b.twilioCredentials(tenant))
+	resp, err := b.media.Do(req)
+	if err != nil {
+		return pendingAttachment{}, fmt.Errorf("download media: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return pendingAttachment{}, fmt.Errorf("download media: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentBytes+1))
+	if err != nil {
+		return pendingAttachment{}, fmt.Errorf("download media: %w", err)
+	}
+	if len(data) > maxAttachmentBytes {
+		return pendingAttachment{}, userError{"That file is too big to keep. Send one under 16 MB."}
+	}
+
+	contentType := media.ContentType
+	if contentType == "" {
+		contentType = resp.Header.Get("Content-Type")
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	name, err := newToken()
+	if err != nil {
+		return pendingAttachment{}, err
+	}
+	key := "media/" + hashToken(userID)[:16] + "/" + name[:32] + attachmentExtension(contentType)
+	if err := b.blobs.Put(ctx, key, data, contentType); err != nil {
+		return pendingAttachment{}, fmt.Errorf("store media: %w", err)
+	}
+	return pendingAttachment{Key: key, ContentType: contentType}, nil
+}
+
+// attachmentExtension returns the file extension for a MIME type, or "" when
+// there is none.
+func attachmentExtension(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if ext, ok := attachmentExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// handleAttachmentCommand attaches the last photo or file the user sent to a
+// new or existing reminder. It reports false when there is no file waiting,
+// so "remind me about this: ..." is handled as usual otherwise.
+func (b *Bot) handleAttachmentCommand(ctx context.Context, userID, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	create := attachmentReminderPattern.FindStringSubmatch(body)
+	attach := attachFilePattern.FindStringSubmatch(body)
+	if create == nil && attach == nil {
+		return "", false
+	}
+	attachment, ok := b.state.PopPendingAttachment(userID)
+	if !ok {
+		if attach != nil {
+			return "Send a photo or file first, then tell me which reminder to add it to.", true
+		}
+		return "", false
+	}
+	if create != nil {
+		return b.saveAttachmentReminder(ctx, userID, attachment, create[1]), true
+	}
+
+	found, err := b.remindersByCode(ctx, b.db.Where("user_id = ?", userID), parseReminderCodes(attach[1]))
+	if err != nil {
+		b.state.SetPendingAttachment(userID, attachment)
+		if !isUserError(err) {
+			b.logger.Printf("attach file: %v", err)
+		}
+		return err.Error(), true
+	}
+	r := found[0]
+	old := r.AttachmentKey
+	if err := b.db.WithContext(ctx).Model(&r).Updates(map[string]any{
+		"attachment_key":  attachment.Key,
+		"attachment_type": attachment.ContentType,
+	}).Error; err != nil {
+		b.logger.Printf("attach file: %v", err)
+		return "I couldn't save the file. Please try again later.", true
+	}
+	if old != "" && old != attachment.Key {
+		if err := b.blobs.Delete(ctx, old); err != nil {
+			b.logger.Printf("attach file: delete replaced %s: %v", old, err)
+		}
+	}
+	return fmt.Sprintf("Added the file to %s %s. I'll include a link to it when I remind you.", r.ShortID(), fallback(r.Summary, r.Content)), true
+}
+
+// saveAttachmentReminder saves content as a new reminder with attachment.
+func (b *Bot) saveAttachmentReminder(ctx context.Context, userID string, attachment pendingAttachment, content string) string {
+	reminder := &model.Reminder{
+		UserID:         userID,
+		Content:        content,
+		Priority:       defaultPlacePriority,
+		Summary:        b.summarizeReminderWithOpenAI(ctx, userID, content),
+		AttachmentKey:  attachment.Key,
+		AttachmentType: attachment.ContentType,
+	}
+	if err := b.insertReminder(ctx, reminder); err != nil {
+		b.logger.Printf("save reminder: %v", err)
+		return "I couldn't save the reminder. Please try again."
+	}
+	return fmt.Sprintf("Got it! I'll remind you: %s (priority %d), with a link to your file.", reminder.Summary, reminder.Priority)
+}
+
+// attachmentLink returns a signed link to the reminder's attachment, or ""
+// when it has none or the store can't make links.
+func (b *Bot) attachmentLink(r model.Reminder) string {
+	if r.AttachmentKey == "" || b.blobs == nil {
+		return ""
+	}
+	link, err := b.blobs.SignedURL(context.Background(), r.AttachmentKey, attachmentLinkTTL)
+	if err != nil {
+		b.logger.Printf("attachment link for reminder %d: %v", r.ID, err)
+		return ""
+	}
+	return link
+}
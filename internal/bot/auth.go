@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionCookieName = "mymemo_session"
+	loginTokenTTL     = 15 * time.Minute
+	sessionTTL        = 30 * 24 * time.Hour
+	// loginLinkCooldown limits how often the login page will message one user.
+	loginLinkCooldown = time.Minute
+)
+
+type sessionContextKey struct{}
+
+// handleDashboardCommand replies with a sign-in link for the web dashboard.
+// It reports false when the message is not a dashboard command.
+func (b *Bot) handleDashboardCommand(userID, lowerBody string) (string, bool) {
+	switch strings.TrimSuffix(strings.TrimSpace(lowerBody), ".") {
+	case "dashboard", "login", "log in", "open dashboard", "web dashboard":
+	default:
+		return "", false
+	}
+
+	link, err := b.loginLink(userID)
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("dashboard link: %v", err)
+		}
+		return err.Error(), true
+	}
+	return fmt.Sprintf("Open this link within 15 minutes to manage your reminders on the web:\n%s", link), true
+}
+
+// loginLink issues a single-use magic link for userID.
+func (b *Bot) loginLink(userID string) (string, error) {
+	if b.cfg.PublicBaseURL == "" {
+		return "", userError{"The web dashboard isn't set up on this server yet."}
+	}
+	token, err := newToken()
+	if err != nil {
+		return "", fmt.Errorf("I couldn't create a login link. Please try again later")
+	}
+	login := &model.LoginToken{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(loginTokenTTL),
+	}
+	if err := b.db.Create(login).Error; err != nil {
+		return "", fmt.Errorf("I couldn't create a login link. Please try again later")
+	}
+	return b.cfg.PublicBaseURL + "/auth/verify?token=" + token, nil
+}
+
+// AuthHandler serves the magic link flow: POST /auth/magic-link sends a link
+// to a known WhatsApp number, GET /auth/verify exchanges it for a session
+// cookie, and POST /auth/logout ends the session.
+func (b *Bot) AuthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/magic-link", b.handleMagicLinkRequest)
+	mux.HandleFunc("GET /auth/verify", b.handleMagicLinkVerify)
+	mux.HandleFunc("POST /auth/logout", b.handleLogout)
+	return mux
+}
+
+// handleMagicLinkRequest messages a sign-in link to the submitted number. The
+// response is the same whether or not the number belongs to a user so the
+// form can't be used to discover who uses the bot.
+func (b *Bot) handleMagicLinkRequest(w http.ResponseWriter, r *http.Request) {
+	userID := normalizePhoneNumber(r.FormValue("phone"))
+	if userID != "" && b.knownUser(userID) && !b.recentLoginLink(userID) {
+		if link, err := b.loginLink(userID); err != nil {
+			b.logger.Printf("auth: login link for %s: %v", userID, err)
+		} else if err := b.notify(r.Context(), userID, "Tap to sign in to your myMemo dashboard (valid for 15 minutes):\n"+link); err != nil {
+			b.logger.Printf("auth: send login link to %s: %v", userID, err)
+		}
+	}
+	http.Redirect(w, r, "/login?sent=1", http.StatusSeeOther)
+}
+
+// handleMagicLinkVerify consumes a login token and starts a session.
+func (b *Bot) handleMagicLinkVerify(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Redirect(w, r, "/login?error=invalid", http.StatusSeeOther)
+		return
+	}
+
+	now := time.Now()
+	var login model.LoginToken
+	err := b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), now).First(&login).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&model.LoginToken{}).Where("token_hash = ? AND used_at IS NULL", login.TokenHash).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Redirect(w, r, "/login?error=expired", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		b.logger.Printf("auth: verify login token: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	sessionToken, err := newToken()
+	if err != nil {
+		b.logger.Printf("auth: new session token: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	session := &model.Session{
+		TokenHash: hashToken(sessionToken),
+		UserID:    login.UserID,
+		ExpiresAt: now.Add(sessionTTL),
+	}
+	if err := b.db.Create(session).Error; err != nil {
+		b.logger.Printf("auth: create session: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sessionToken,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(b.cfg.PublicBaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// handleLogout deletes the current session and clears the cookie.
+func (b *Bot) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if err := b.db.Where("token_hash = ?", hashToken(cookie.Value)).Delete(&model.Session{}).Error; err != nil {
+			b.logger.Printf("auth: delete session: %v", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// sessionUser returns the user signed in with the request's session cookie.
+func (b *Bot) sessionUser(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	var session model.Session
+	if err := b.db.Where("token_hash = ? AND expires_at > ?", hashToken(cookie.Value), time.Now()).First(&session).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			b.logger.Printf("auth: load session: %v", err)
+		}
+		return "", false
+	}
+	return session.UserID, true
+}
+
+// requireSession rejects requests without a valid session and stores the
+// signed-in user on the request context. Requests that change state must be
+// JSON, which browsers won't send cross-site without a CORS preflight.
+func (b *Bot) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := b.sessionUser(r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "sign in required"})
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead &&
+			!strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "requests must be JSON"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, userID)))
+	})
+}
+
+// sessionUserID returns the user stored on the context by requireSession.
+func sessionUserID(ctx context.Context) string {
+	userID, _ := ctx.Value(sessionContextKey{}).(string)
+	return userID
+}
+
+// knownUser reports whether userID has ever used the bot.
+func (b *Bot) knownUser(userID string) bool {
+	var count int64
+	if err := b.db.Model(&model.Reminder{}).Where("user_id = ?", userID).Count(&count).Error; err == nil && count > 0 {
+		return true
+	}
+	if err := b.db.Model(&model.UserPreference{}).Where("user_id = ?", userID).Count(&count).Error; err == nil && count > 0 {
+		return true
+	}
+	return false
+}
+
+// recentLoginLink reports whether a link was issued to userID within the cooldown.
+func (b *Bot) recentLoginLink(userID string) bool {
+	var count int64
+	err := b.db.Model(&model.LoginToken{}).
+		Where("user_id = ? AND created_at > ?", userID, time.Now().Add(-loginLinkCooldown)).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+// normalizePhoneNumber turns user input like "+1 (555) 123-4567" into the
+// "+15551234567" form Twilio uses for WhatsApp senders.
+func normalizePhoneNumber(input string) string {
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, strings.TrimPrefix(strings.TrimSpace(input), "whatsapp:"))
+	if len(digits) < 6 {
+		return ""
+	}
+	return "+" + digits
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+const (
+	defaultBirthdayPriority = 4
+	// defaultBirthdayLeadDays is how many days ahead the heads-up goes out
+	// unless the user picks another number.
+	defaultBirthdayLeadDays = 3
+	maxBirthdayLeadDays     = 60
+)
+
+var (
+	birthdayRequestPattern = regexp.MustCompile(`(?i)^(birthday|anniversary)\s*:\s*(.+?)\s+on\s+(.+?)[.!]*$`)
+	birthdayLeadPattern    = regexp.MustCompile(`^(?:remind me (?:of|about) birthdays|birthday (?:heads[- ]up|notice|reminders?))\s+(\d+)\s+days?(?:\s+(?:before|ahead|early))?$`)
+)
+
+// handleBirthdayCommand saves "birthday: Asha on March 3" or "anniversary:
+// Mum and Dad on June 12" as a reminder that comes back every year, lists
+// them with "birthdays", and sets how early the heads-up goes out. It
+// reports false for other messages.
+func (b *Bot) handleBirthdayCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	lowerBody = strings.TrimSuffix(strings.TrimSpace(lowerBody), ".")
+	switch lowerBody {
+	case "birthdays", "anniversaries", "birthdays and anniversaries", "show birthdays", "list birthdays", "upcoming birthdays":
+		return b.listBirthdays(ctx, userID), true
+	}
+	if m := birthdayLeadPattern.FindStringSubmatch(lowerBody); m != nil {
+		days, _ := strconv.Atoi(m[1])
+		return b.setBirthdayLead(userID, days), true
+	}
+
+	m := birthdayRequestPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if m == nil {
+		return "", false
+	}
+	now := time.Now().In(b.cfg.LocalTimezone)
+	day, ok := parseDay(m[3], now)
+	if !ok {
+		return fmt.Sprintf("I couldn't read the date '%s'. Try 'birthday: Asha on March 3'.", strings.TrimSpace(m[3])), true
+	}
+	next := nextAnnual(day, now)
+
+	kind := model.KindBirthday
+	if strings.EqualFold(m[1], "anniversary") {
+		kind = model.KindAnniversary
+	}
+	who := strings.TrimSpace(m[2])
+	reminder := &model.Reminder{
+		UserID:   userID,
+		Content:  who,
+		Priority: defaultBirthdayPriority,
+		Summary:  occasion(kind, who),
+		Kind:     kind,
+		DueAt:    &next,
+	}
+	if err := b.db.WithContext(ctx).Create(reminder).Error; err != nil {
+		b.logger.Printf("save birthday: %v", err)
+		return "I couldn't save that. Please try again.", true
+	}
+	b.emit(webhook.EventReminderCreated, *reminder, "")
+	return fmt.Sprintf("Saved %s on %s. I'll give you a heads-up %s before, every year.",
+		reminder.Summary, next.Format("January 2"), dayCount(b.birthdayLead(userID))), true
+}
+
+// occasion names a birthday or anniversary, e.g. "Asha's birthday".
+func occasion(kind, who string) string {
+	possessive := who + "'s"
+	if strings.HasSuffix(strings.ToLower(who), "s") {
+		possessive = who + "'"
+	}
+	if kind == model.KindAnniversary {
+		return possessive + " anniversary"
+	}
+	return possessive + " birthday"
+}
+
+// nextAnnual returns the next date on or after today that falls on day's
+// month and day. A February 29 date moves to March 1 outside leap years.
+func nextAnnual(day, now time.Time) time.Time {
+	next := time.Date(now.Year(), day.Month(), day.Day(), 0, 0, 0, 0, now.Location())
+	if next.Before(startOfDay(now)) {
+		next = time.Date(now.Year()+1, day.Month(), day.Day(), 0, 0, 0, 0, now.Location())
+	}
+	return next
+}
+
+// birthdayLead returns how many days before a birthday the user hears about it.
+func (b *Bot) birthdayLead(userID string) int {
+	if pref := b.preferences(userID); pref.BirthdayLeadDays != nil {
+		return *pref.BirthdayLeadDays
+	}
+	return defaultBirthdayLeadDays
+}
+
+func (b *Bot) setBirthdayLead(userID string, days int) string {
+	if days > maxBirthdayLeadDays {
+		return fmt.Sprintf("Please pick a number of days up to %d.", maxBirthdayLeadDays)
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.BirthdayLeadDays = &days
+	}); err != nil {
+		b.logger.Printf("settings command: %v", err)
+		return "I couldn't update your settings. Please try again later."
+	}
+	if days == 0 {
+		return "Done! I'll only remind you of birthdays and anniversaries on the day."
+	}
+	return fmt.Sprintf("Done! I'll give you a heads-up %s before birthdays and anniversaries.", dayCount(days))
+}
+
+// annualDue reports whether a birthday or anniversary is sent today: on the
+// heads-up day and on the day itself.
+func annualDue(r model.Reminder, lead int, now time.Time) bool {
+	left := daysUntil(*r.DueAt, now)
+	return left == 0 || left == lead
+}
+
+func annualMessage(r model.Reminder, now time.Time) string {
+	text := fallback(r.Summary, r.Content)
+	left := daysUntil(*r.DueAt, now)
+	if left == 0 {
+		return fmt.Sprintf("Today is %s!", text)
+	}
+	return fmt.Sprintf("Heads-up: %s is in %s (%s).", text, dayCount(left), r.DueAt.In(now.Location()).Format("Mon Jan 2"))
+}
+
+// rollAnnual moves the user's birthdays and anniversaries that have passed
+// on to next year.
+func (b *Bot) rollAnnual(ctx context.Context, userID string, now time.Time) {
+	var passed []model.Reminder
+	err := b.db.WithContext(ctx).
+		Where("user_id = ? AND kind IN ? AND completed_at IS NULL AND due_at < ?", userID, []string{model.KindBirthday, model.KindAnniversary}, startOfDay(now)).
+		Find(&passed).Error
+	if err != nil {
+		b.logger.Printf("scheduler: load birthdays for %s: %v", userID, err)
+		return
+	}
+	for _, r := range passed {
+		next := nextAnnual(r.DueAt.In(now.Location()), now)
+		if err := b.db.WithContext(ctx).Model(&r).Update("due_at", next).Error; err != nil {
+			b.logger.Printf("scheduler: move %s to next year: %v", r.ShortID(), err)
+		}
+	}
+}
+
+// listBirthdays lists the user's birthdays and anniversaries, soonest first.
+func (b *Bot) listBirthdays(ctx context.Context, userID string) string {
+	now := time.Now().In(b.cfg.LocalTimezone)
+	b.rollAnnual(ctx, userID, now)
+	var annual []model.Reminder
+	err := b.db.WithContext(ctx).
+		Where("user_id = ? AND kind IN ? AND completed_at IS NULL", userID, []string{model.KindBirthday, model.KindAnniversary}).
+		Find(&annual).Error
+	if err != nil {
+		b.logger.Printf("list birthdays: %v", err)
+		return "I couldn't look up your birthdays right now. Please try again later."
+	}
+	if len(annual) == 0 {
+		return "You haven't saved any birthdays yet. Try 'birthday: Asha on March 3'."
+	}
+	sort.SliceStable(annual, func(i, j int) bool { return annual[i].DueAt.Before(*annual[j].DueAt) })
+
+	var sb strings.Builder
+	sb.WriteString("Upcoming birthdays and anniversaries:\n")
+	for i, r := range annual {
+		left := daysUntil(*r.DueAt, now)
+		when := "today"
+		if left > 0 {
+			when = "in " + dayCount(left)
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s %s — %s (%s)\n", i+1, r.ShortID(), fallback(r.Summary, r.Content), r.DueAt.In(now.Location()).Format("Jan 2"), when))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func dayCount(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// maxBlackoutDays caps how long a single blackout may run.
+const maxBlackoutDays = 90
+
+var (
+	awayRequestPattern     = regexp.MustCompile(`^(?:i'?m|i am|i'll be|i will be)\s+(?:traveling|travelling|away|on (?:vacation|holiday|leave)|out of (?:the )?office|off)\s+(.+?)[.!]*$`)
+	blackoutRequestPattern = regexp.MustCompile(`^(?:no|pause|hold|don'?t send(?: me)?|do not send(?: me)?)\s+(?:my\s+)?reminders\s+(.+?)[.!]*$`)
+	dayRangePattern        = regexp.MustCompile(`^(?:from\s+|between\s+)?(.+?)\s+(?:to|until|till|through|thru|and|-)\s+(.+)$`)
+	untilDayPattern        = regexp.MustCompile(`^(?:until|till|through|thru)\s+(.+)$`)
+)
+
+// parseDayWord parses "today", "tomorrow", a weekday name meaning the next
+// one from today, or a date parseDay accepts.
+func parseDayWord(text string, now time.Time) (time.Time, bool) {
+	text = strings.TrimPrefix(strings.TrimSpace(text), "on ")
+	today := startOfDay(now)
+	switch text {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	}
+	for _, day := range weekdaysFromMonday {
+		if text == strings.ToLower(day.String()) || text == "next "+strings.ToLower(day.String()) {
+			ahead := (int(day) - int(now.Weekday()) + 7) % 7
+			if strings.HasPrefix(text, "next ") && ahead == 0 {
+				ahead = 7
+			}
+			return today.AddDate(0, 0, ahead), true
+		}
+	}
+	return parseDay(text, now)
+}
+
+// parseDayRange reads the days of a blackout from phrases such as "next
+// week", "tomorrow", "until Friday", or "from Mar 3 to Mar 10". It returns
+// the first and last days.
+func parseDayRange(text string, now time.Time) (time.Time, time.Time, bool) {
+	text = strings.TrimSpace(text)
+	today := startOfDay(now)
+	// Days since Monday, as the week runs Monday to Sunday.
+	intoWeek := (int(now.Weekday()) + 6) % 7
+	switch text {
+	case "this week":
+		return today, today.AddDate(0, 0, 6-intoWeek), true
+	case "next week":
+		monday := today.AddDate(0, 0, 7-intoWeek)
+		return monday, monday.AddDate(0, 0, 6), true
+	case "this weekend", "over the weekend", "at the weekend", "on the weekend":
+		if now.Weekday() == time.Sunday {
+			return today, today, true
+		}
+		saturday := today.AddDate(0, 0, 5-intoWeek)
+		return saturday, saturday.AddDate(0, 0, 1), true
+	}
+	if m := untilDayPattern.FindStringSubmatch(text); m != nil {
+		last, ok := parseDayWord(m[1], now)
+		return today, last, ok
+	}
+	if m := dayRangePattern.FindStringSubmatch(text); m != nil {
+		first, ok1 := parseDayWord(m[1], now)
+		last, ok2 := parseDayWord(m[2], now)
+		if ok1 && ok2 {
+			return first, last, true
+		}
+	}
+	day, ok := parseDayWord(text, now)
+	return day, day, ok
+}
+
+// handleBlackoutCommand sets, lists, and clears the days a user gets no
+// reminders, such as "I'm traveling next week". It reports false for other
+// messages.
+func (b *Bot) handleBlackoutCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	lowerBody = strings.TrimSuffix(strings.TrimSpace(lowerBody), ".")
+	now := time.Now().In(b.cfg.LocalTimezone)
+	switch lowerBody {
+	case "blackouts", "my blackouts", "show blackouts", "show my blackouts", "when am i away":
+		return b.listBlackouts(ctx, userID, now), true
+	case "cancel blackout", "cancel blackouts", "clear blackouts", "i'm back", "im back", "i am back":
+		return b.clearBlackouts(ctx, userID, now), true
+	}
+
+	var phrase string
+	if m := awayRequestPattern.FindStringSubmatch(lowerBody); m != nil {
+		phrase = m[1]
+	} else if m := blackoutRequestPattern.FindStringSubmatch(lowerBody); m != nil {
+		phrase = m[1]
+	} else {
+		return "", false
+	}
+	first, last, ok := parseDayRange(phrase, now)
+	if !ok {
+		return "", false
+	}
+	msg, err := b.addBlackout(ctx, userID, first, last, now)
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("blackout for %s: %v", userID, err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// addBlackout stops reminders for userID from first to last, inclusive.
+func (b *Bot) addBlackout(ctx context.Context, userID string, first, last, now time.Time) (string, error) {
+	today := startOfDay(now)
+	if first.Before(today) {
+		first = today
+	}
+	switch {
+	case last.Before(first):
+		return "", userError{"That range ends before it starts. Try 'no reminders from Mar 3 to Mar 10'."}
+	case daysUntil(last, first) >= maxBlackoutDays:
+		return "", userError{fmt.Sprintf("I can hold reminders for up to %d days at a time. Say 'pause reminders' to stop them for longer.", maxBlackoutDays)}
+	}
+	blackout := &model.Blackout{UserID: userID, FirstDay: first, LastDay: last}
+	if err := b.db.WithContext(ctx).Create(blackout).Error; err != nil {
+		return "", fmt.Errorf("I couldn't save those dates. Please try again later")
+	}
+	resume := last.AddDate(0, 0, 1).Format("Mon Jan 2")
+	if first.Equal(last) {
+		return fmt.Sprintf("Got it, no reminders on %s. Anything due then comes on %s.", first.Format("Mon Jan 2"), resume), nil
+	}
+	return fmt.Sprintf("Got it, no reminders from %s to %s. Anything due then comes on %s.", first.Format("Mon Jan 2"), last.Format("Mon Jan 2"), resume), nil
+}
+
+// listBlackouts lists the user's current and upcoming blackouts.
+func (b *Bot) listBlackouts(ctx context.Context, userID string, now time.Time) string {
+	var blackouts []model.Blackout
+	err := b.db.WithContext(ctx).
+		Where("user_id = ? AND last_day >= ?", userID, startOfDay(now)).
+		Order("first_day ASC").
+		Find(&blackouts).Error
+	if err != nil {
+		b.logger.Printf("list blackouts: %v", err)
+		return "I couldn't look up your days off right now. Please try again later."
+	}
+	if len(blackouts) == 0 {
+		return "You have no days without reminders coming up. Try 'I'm traveling next week'."
+	}
+	var sb strings.Builder
+	sb.WriteString("No reminders on these days:\n")
+	for _, bo := range blackouts {
+		if bo.FirstDay.Equal(bo.LastDay) {
+			fmt.Fprintf(&sb, "- %s\n", bo.FirstDay.In(now.Location()).Format("Mon Jan 2"))
+			continue
+		}
+		fmt.Fprintf(&sb, "- %s to %s\n", bo.FirstDay.In(now.Location()).Format("Mon Jan 2"), bo.LastDay.In(now.Location()).Format("Mon Jan 2"))
+	}
+	sb.WriteString("Say 'I'm back' to cancel them.")
+	return sb.String()
+}
+
+// clearBlackouts ends the user's current blackout and drops upcoming ones.
+func (b *Bot) clearBlackouts(ctx context.Context, userID string, now time.Time) string {
+	res := b.db.WithContext(ctx).
+		Where("user_id = ? AND last_day >= ?", userID, startOfDay(now)).
+		Delete(&model.Blackout{})
+	if res.Error != nil {
+		b.logger.Printf("clear blackouts: %v", res.Error)
+		return "I couldn't cancel your days off right now. Please try again later."
+	}
+	if res.RowsAffected == 0 {
+		return "You have no days without reminders to cancel."
+	}
+	return "Welcome back! Your reminders are on again."
+}
+
+// blackedOut reports whether userID asked for no reminders on now's day.
+func (b *Bot) blackedOut(ctx context.Context, userID string, now time.Time) bool {
+	today := startOfDay(now)
+	var count int64
+	err := b.db.WithContext(ctx).Model(&model.Blackout{}).
+		Where("user_id = ? AND first_day <= ? AND last_day >= ?", userID, today, today).
+		Count(&count).Error
+	if err != nil {
+		b.logger.Printf("blackouts for %s: %v", userID, err)
+		return false
+	}
+	return count > 0
+}
+
+// heldSince returns the first day of the blackout that ended yesterday, so
+// the first dispatch after it can send what fell due during it, or the zero
+// time when none did.
+func (b *Bot) heldSince(ctx context.Context, userID string, now time.Time) time.Time {
+	var blackout model.Blackout
+	err := b.db.WithContext(ctx).
+		Where("user_id = ? AND last_day = ?", userID, startOfDay(now).AddDate(0, 0, -1)).
+		Order("first_day ASC").
+		Limit(1).
+		Find(&blackout).Error
+	if err != nil {
+		b.logger.Printf("blackouts for %s: %v", userID, err)
+	}
+	return blackout.FirstDay
+}
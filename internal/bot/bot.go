@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -12,64 +13,248 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
+	"github.com/pathakanu/myMemo/internal/caldav"
+	"github.com/pathakanu/myMemo/internal/channel"
 	"github.com/pathakanu/myMemo/internal/config"
+	"github.com/pathakanu/myMemo/internal/discord"
+	"github.com/pathakanu/myMemo/internal/i18n"
+	"github.com/pathakanu/myMemo/internal/linkfetch"
 	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/netguard"
+	"github.com/pathakanu/myMemo/internal/notion"
 	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"github.com/pathakanu/myMemo/internal/storage"
 	"github.com/pathakanu/myMemo/internal/twilio"
+	"github.com/pathakanu/myMemo/internal/webhook"
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// listPageSize is how many reminders one list reply shows.
+const listPageSize = 10
+
+// dailyDispatchSpec is the cron schedule for the daily reminder dispatch
+// when DISPATCH_SCHEDULE is not set.
+const dailyDispatchSpec = "56 12 * * *"
+
 // Bot coordinates reminder persistence, messaging, and scheduling.
 type Bot struct {
 	cfg    *config.Config
 	db     *gorm.DB
 	openAI *myopenai.Client
-	twilio *twilio.Client
-	cron   *cron.Cron
+	// llm classifies messages and writes summaries. It is openAI unless
+	// another provider is configured.
+	llm languageModel
+	// embedder is nil when there is no OpenAI client.
+	embedder embedder
+	cron     *cron.Cron
+	// mu guards jobs and the settings Reload may change in cfg.
+	mu     sync.RWMutex
+	jobs   map[cron.EntryID]string
 	state  *conversationStore
+	recent *exchangeLog
+	sends  *sendQueue
+	// blobs keeps media attachments and export files. It is nil when the
+	// configured store couldn't be set up.
+	blobs storage.BlobStore
+	// media downloads the photos and files users send through Twilio.
+	media  *http.Client
+	links  *linkfetch.Fetcher
+	hooks  *webhook.Client
+	notion *notion.Client
+	caldav *caldav.Client
+	events *eventHub
 	logger *log.Logger
+
+	// channels holds a Sender per configured delivery channel.
+	channels map[string]channel.Sender
+	// discord is nil unless a bot token is configured. discordKey verifies
+	// interaction requests and is nil when they are disabled.
+	discord    *discord.Client
+	discordKey ed25519.PublicKey
+
+	// pool is nil unless several default WhatsApp numbers are configured.
+	pool *senderPool
+
+	// webhookPanics counts webhook requests that panicked.
+	webhookPanics atomic.Int64
+	// eventCounts counts reminder events by type.
+	eventCounts eventCounter
+
+	// commands holds chat commands registered from other packages.
+	commands CommandRegistry
+
+	// tenantSenders caches a WhatsApp Sender per tenant ID.
+	tenantMu      sync.Mutex
+	tenantSenders map[uint]channel.Sender
 }
 
 // New creates a fully configured Bot instance.
 func New(cfg *config.Config, db *gorm.DB, openAI *myopenai.Client, twilioClient *twilio.Client, logger *log.Logger) *Bot {
 	c := cron.New(cron.WithLocation(cfg.LocalTimezone))
+	channels := map[string]channel.Sender{
+		channel.WhatsApp: channel.NewWhatsApp(twilioClient),
+	}
+	if email := channel.NewEmail(channel.EmailConfig{
+		From:           cfg.EmailFrom,
+		SendGridAPIKey: cfg.SendGridAPIKey,
+		SMTPHost:       cfg.SMTPHost,
+		SMTPPort:       cfg.SMTPPort,
+		SMTPUsername:   cfg.SMTPUsername,
+		SMTPPassword:   cfg.SMTPPassword,
+	}); email != nil {
+		channels[channel.Email] = email
+	}
+	var pool *senderPool
+	if numbers := cfg.SenderNumbers(); len(numbers) > 1 {
+		senders := make(map[string]channel.Sender, len(numbers))
+		senders[numbers[0]] = channels[channel.WhatsApp]
+		for _, number := range numbers[1:] {
+			senders[number] = channel.NewWhatsApp(twilio.New(cfg.TwilioAccountSID, cfg.TwilioAuthToken, number))
+		}
+		pool = newSenderPool(numbers, senders)
+	}
+	if cfg.TwilioVoiceNumber != "" {
+		channels[channel.Voice] = channel.NewVoice(twilioClient, cfg.TwilioVoiceNumber)
+	}
+	var (
+		discordClient *discord.Client
+		discordKey    ed25519.PublicKey
+	)
+	if cfg.DiscordBotToken != "" {
+		discordClient = discord.New(cfg.DiscordBotToken)
+		channels[channel.Discord] = channel.NewDiscord(discordClient)
+		key, err := discord.ParsePublicKey(cfg.DiscordPublicKey)
+		if err != nil {
+			logger.Printf("discord: interactions disabled: %v", err)
+		} else {
+			discordKey = key
+		}
+	}
 	b := &Bot{
-		cfg:    cfg,
-		db:     db,
-		openAI: openAI,
-		twilio: twilioClient,
-		cron:   c,
-		state:  newConversationStore(),
-		logger: logger,
+		cfg:        cfg,
+		db:         db,
+		openAI:     openAI,
+		llm:        newLanguageModel(cfg, openAI),
+		channels:   channels,
+		pool:       pool,
+		discord:    discordClient,
+		discordKey: discordKey,
+		cron:       c,
+		jobs:       make(map[cron.EntryID]string),
+		state:      newConversationStore(),
+		recent:     newExchangeLog(),
+		sends:      newSendQueue(),
+		media:      netguard.NewClient(time.Minute),
+		links:      linkfetch.New(),
+		hooks:      webhook.New(),
+		notion:     notion.New(),
+		caldav:     caldav.New(),
+		events:     newEventHub(),
+		blobs:      newBlobStore(cfg, logger),
+		logger:     logger,
+	}
+	if openAI != nil {
+		b.embedder = openAI
 	}
+	b.subscribeEffects()
 	return b
 }
 
-// StartScheduler registers cron jobs and starts the scheduler loop.
+// StartScheduler registers cron jobs, replays sends left over from the last
+// shutdown, starts the scheduler loop, and catches up on any run missed today
+// while the process was down.
 func (b *Bot) StartScheduler() error {
-	_, err := b.cron.AddFunc("56 12 * * *", func() {
-		go b.sendScheduledReminders()
-	})
-	if err != nil {
+	if err := b.addDispatchJobs(b.dispatchSpec()); err != nil {
+		return err
+	}
+	if err := b.addJob("notion-sync", notionSyncSpec, b.pullNotionConnections); err != nil {
+		return err
+	}
+	if err := b.addJob("caldav-sync", caldavSyncSpec, b.syncCalDAVConnections); err != nil {
+		return err
+	}
+	if err := b.addJob("reschedule-suggestions", rescheduleSpec, b.sendRescheduleSuggestions); err != nil {
+		return err
+	}
+	if err := b.addJob("weekly-review", weeklyReviewSpec, b.sendWeeklyReviews); err != nil {
+		return err
+	}
+	if err := b.addJob("reminder-embeddings", embeddingBackfillSpec, b.backfillEmbeddings); err != nil {
+		return err
+	}
+	if err := b.addJob("delivery-follow-ups", followUpSpec, b.followUpDeliveries); err != nil {
+		return err
+	}
+	if err := b.addJob("conversation-sweep", conversationSweepSpec, b.sweepConversations); err != nil {
 		return err
 	}
+	if err := b.addJob("message-archive-retention", archiveRetentionSpec, b.pruneArchive); err != nil {
+		return err
+	}
+	if err := b.addJob("morning-briefings", morningBriefingSpec, b.sendMorningBriefings); err != nil {
+		return err
+	}
+	b.restoreOutbox()
 	b.cron.Start()
+	b.recoverMissedJobs(time.Now().In(b.cfg.LocalTimezone))
 	return nil
 }
 
-// StopScheduler stops the cron scheduler gracefully.
-func (b *Bot) StopScheduler() {
-	ctx := b.cron.Stop()
-	<-ctx.Done()
+// addJob registers a named cron job whose runs are tracked by the send queue.
+func (b *Bot) addJob(name, spec string, fn func()) error {
+	id, err := b.cron.AddFunc(spec, func() {
+		b.sends.Go(func() {
+			fn()
+			b.recordJobRun(name, time.Now())
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("register %s job: %w", name, err)
+	}
+	b.mu.Lock()
+	b.jobs[id] = name
+	b.mu.Unlock()
+	return nil
+}
+
+// addDispatchJobs registers the daily reminder, article, and tip jobs on spec.
+func (b *Bot) addDispatchJobs(spec string) error {
+	if err := b.addJob("daily-reminders", spec, func() { b.sendScheduledReminders() }); err != nil {
+		return err
+	}
+	if err := b.addJob("daily-articles", spec, b.sendDailyArticles); err != nil {
+		return err
+	}
+	return b.addJob("daily-tips", spec, b.sendTips)
 }
 
-// Handler returns the HTTP handler for incoming Twilio messages.
+// StopScheduler stops the cron scheduler and drains in-flight dispatches.
+// Sends that have not fired by the time ctx expires are persisted to the
+// outbox and replayed on the next start.
+func (b *Bot) StopScheduler(ctx context.Context) {
+	cronCtx := b.cron.Stop()
+	select {
+	case <-cronCtx.Done():
+	case <-ctx.Done():
+	}
+
+	unsent, err := b.sends.Drain(ctx)
+	if err != nil {
+		b.logger.Printf("scheduler: drain: %v", err)
+	}
+	b.saveOutbox(unsent)
+}
+
+// Handler returns the HTTP handler for incoming Twilio messages. Requests
+// must carry Twilio's signature.
 func (b *Bot) Handler() http.HandlerFunc {
-	return b.handleIncomingMessage
+	return b.recoverWebhook(b.requireTwilioSignature(b.handleIncomingMessage))
 }
 
 // handleIncomingMessage processes Twilio webhook POST requests.
@@ -82,62 +267,353 @@ func (b *Bot) handleIncomingMessage(w http.ResponseWriter, r *http.Request) {
 
 	from := r.FormValue("From")
 	body := strings.TrimSpace(r.FormValue("Body"))
-	if from == "" || body == "" {
+	form := DecodeTwilioForm(r.Form)
+	loc, hasLocation := parseLocation(form)
+	media, hasMedia := parseMedia(form)
+	if from == "" || (body == "" && !hasLocation && !hasMedia) {
 		b.writeTwilioResponse(w, "I need a message to work with. Please try again.")
 		return
 	}
 
 	userID := sanitizeWhatsAppNumber(from)
+	tenant, ok, err := b.inboundTenant(r.Context(), userID, r.FormValue("To"))
+	if err != nil {
+		b.logger.Printf("webhook: resolve tenant: %v", err)
+		b.writeTwilioResponse(w, "Something went wrong. Please try again later.")
+		return
+	} else if !ok {
+		b.writeTwilioResponse(w, tenantMismatchReply)
+		return
+	}
+	if tenant == nil {
+		b.pinSender(userID, r.FormValue("To"))
+	}
+
+	format := twilioChannel(from)
+	if hasMedia {
+		b.writeTwilioResponse(w, renderFormatting(b.translate(userID, b.receiveMedia(r.Context(), userID, media, body)), format))
+		return
+	}
+	if body == "" {
+		b.writeTwilioResponse(w, renderFormatting(b.receiveLocation(r.Context(), userID, loc), format))
+		return
+	}
+	repliedSID := r.FormValue("OriginalRepliedMessageSid")
+	if msg, ok := b.handleReaction(r.Context(), userID, body, repliedSID); ok {
+		b.writeTwilioResponse(w, renderFormatting(b.translate(userID, msg), format))
+		return
+	}
+	if msg, ok := b.handleQuotedReply(r.Context(), userID, strings.ToLower(body), repliedSID); ok {
+		b.writeTwilioResponse(w, renderFormatting(b.translate(userID, msg), format))
+		return
+	}
+	reply := b.respond(r.Context(), userID, body)
+	if note := b.welcomeBack(userID); note != "" {
+		reply = b.translate(userID, note) + "\n\n" + reply
+	}
+	b.writeTwilioResponse(w, renderFormatting(reply, format))
+}
+
+// respond runs a message from any channel through the command handlers and
+// intent pipeline and returns the reply for the user, in their language.
+func (b *Bot) respond(ctx context.Context, userID, body string) string {
+	if b.flagged(ctx, userID, "chat", body) {
+		return b.translate(userID, moderationRefusal)
+	}
+	msg := b.reply(ctx, userID, body)
+	b.recent.Add(userID, redactCredentials(body), redactCredentials(plainText(msg)), time.Now())
+	reply := b.translate(userID, msg)
+	b.archiveMessage(ctx, userID, model.DirectionIn, body)
+	b.archiveMessage(ctx, userID, model.DirectionOut, reply)
+	return reply
+}
+
+// reply picks the handler for a message and returns its English reply.
+func (b *Bot) reply(ctx context.Context, userID, body string) string {
 	lowerBody := strings.ToLower(body)
 
+	if msg, ok := b.handleOptOutCommand(userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleAdminCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleCancel(userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleHelpCommand(userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleFeedbackCommand(ctx, userID, body); ok {
+		return msg
+	}
+
 	if b.state.IsAwaitingPriority(userID) {
-		b.handlePriorityResponse(w, userID, body)
-		return
+		return b.handlePriorityResponse(ctx, userID, body)
+	}
+
+	if msg, ok := b.handlePendingDelete(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleClarification(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleRescheduleReply(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleRebalanceReply(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleMoreCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleCorrection(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleFollowUp(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleLocationCommand(ctx, userID, body); ok {
+		return msg
 	}
 
-	intent, keyword := b.determineIntent(r.Context(), body, lowerBody)
+	if msg, ok := b.handleAttachmentCommand(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleProfileCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleSettingsCommand(userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleDashboardCommand(userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleExportCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleInsightsCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleRebalanceCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleBlackoutCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleTimezoneCommand(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleEmailCommand(userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleTemplateCommand(userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if tmpl, ok := b.findTemplate(userID, lowerBody); ok {
+		if tmpl.Priority == 0 {
+			b.state.SetPendingMessage(userID, tmpl.Content)
+			return b.askForPriority()
+		}
+		return b.addReminder(ctx, userID, tmpl.Content, tmpl.Priority)
+	}
+
+	if msg, ok := b.handleWebhookCommand(userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleHookCommand(userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleNotionCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleCalDAVCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleLinkCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleMemoCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleProjectCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleStepCommand(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handlePostponeAllCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleBriefingCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleWeekCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleInviteCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleListFilterCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
 
+	if msg, ok := b.handleShowCommand(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handlePriorityChange(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleArchiveCommand(ctx, userID, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleSearchCommand(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleForwardCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleListCommand(userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleCountdownCommand(ctx, userID, body); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleBirthdayCommand(ctx, userID, body, lowerBody); ok {
+		return msg
+	}
+
+	if msg, ok := b.commands.handle(ctx, CommandRequest{UserID: userID, Body: body}); ok {
+		return msg
+	}
+
+	if msg, ok := b.handleCompoundMessage(ctx, userID, body); ok {
+		return msg
+	}
+
+	if content, ok := parseHabitRequest(body); ok {
+		return b.addHabit(ctx, userID, content)
+	}
+
+	if selector, ok := parseDoneRequest(body); ok {
+		msg, err := b.markDone(ctx, userID, selector)
+		if err != nil {
+			if !isUserError(err) {
+				b.logger.Printf("mark done: %v", err)
+			}
+			return err.Error()
+		}
+		return msg
+	}
+
+	if selector, ok := parseSkipRequest(body); ok {
+		msg, err := b.skipHabit(ctx, userID, selector)
+		if err != nil {
+			if !isUserError(err) {
+				b.logger.Printf("skip habit: %v", err)
+			}
+			return err.Error()
+		}
+		return msg
+	}
+
+	intent, keyword := b.determineIntent(ctx, userID, body, lowerBody)
+	if intent == intentUnclear {
+		return keyword
+	}
+	return b.runIntent(ctx, userID, body, lowerBody, intent, keyword)
+}
+
+// runIntent carries out a classified message. keyword is the delete keyword
+// for IntentDeleteReminder.
+func (b *Bot) runIntent(ctx context.Context, userID, body, lowerBody string, intent myopenai.Intent, keyword string) string {
 	switch intent {
 	case myopenai.IntentListReminders:
-		list := b.listReminders(userID)
+		page := 1
+		if m := listPagePattern.FindStringSubmatch(lowerBody); m != nil {
+			page, _ = strconv.Atoi(m[1])
+		}
+		list := b.listRemindersPage(ctx, userID, listFilter{}, page)
 		if list == "" {
-			b.writeTwilioResponse(w, "You have no reminders yet. Send me one to get started!")
-			return
+			return "You have no reminders yet. Send me one to get started!"
 		}
-		b.writeTwilioResponse(w, list)
+		return list
 	case myopenai.IntentClearReminders:
-		msg, err := b.deleteReminder(userID, "")
+		msg, err := b.deleteReminder(ctx, userID, "")
 		if err != nil {
 			if !isUserError(err) {
 				b.logger.Printf("clear reminders: %v", err)
 			}
-			b.writeTwilioResponse(w, err.Error())
-			return
+			return err.Error()
 		}
-		b.writeTwilioResponse(w, msg)
+		return msg
 	case myopenai.IntentDeleteReminder:
 		if keyword == "" {
-			b.writeTwilioResponse(w, "Tell me which reminder to delete, e.g. 'delete reminder about milk'.")
-			return
+			return "Tell me which reminder to delete, e.g. 'delete reminder about milk'."
 		}
-		msg, err := b.deleteReminder(userID, keyword)
+		msg, err := b.deleteReminder(ctx, userID, keyword)
 		if err != nil {
 			if !isUserError(err) {
 				b.logger.Printf("delete reminder: %v", err)
 			}
-			b.writeTwilioResponse(w, err.Error())
-			return
+			return err.Error()
 		}
-		b.writeTwilioResponse(w, msg)
+		return msg
 	case myopenai.IntentHelp:
-		b.writeTwilioResponse(w, helpResponse())
+		return helpResponse() + b.commands.helpLines()
+	case myopenai.IntentConnect:
+		return b.connectInstructions()
 	default:
 		b.state.SetPendingMessage(userID, body)
-		b.writeTwilioResponse(w, b.askForPriority())
+		return b.askForPriority()
 	}
 }
 
-func (b *Bot) determineIntent(ctx context.Context, message, lowerMessage string) (myopenai.Intent, string) {
+// determineIntent classifies a message, trying cheap patterns before the
+// model. When the model isn't sure it returns intentUnclear and a question
+// for the user in place of the keyword.
+func (b *Bot) determineIntent(ctx context.Context, userID, message, lowerMessage string) (myopenai.Intent, string) {
 	if isClearAllRequest(lowerMessage) {
 		return myopenai.IntentClearReminders, ""
 	}
@@ -147,25 +623,32 @@ func (b *Bot) determineIntent(ctx context.Context, message, lowerMessage string)
 	if keyword := extractDeleteKeyword(message); keyword != "" {
 		return myopenai.IntentDeleteReminder, keyword
 	}
+	if isConnectRequest(lowerMessage) {
+		return myopenai.IntentConnect, ""
+	}
 
-	if b.openAI == nil {
+	if b.llm == nil || !b.useOpenAI(ctx, userID) {
 		return myopenai.IntentAddReminder, ""
 	}
 
-	intent, err := b.openAI.ClassifyIntent(ctx, message)
+	classification, err := b.llm.ClassifyIntent(ctx, message)
 	if err != nil {
 		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
 			b.logger.Printf("intent classification error: %v", err)
 		}
 		return myopenai.IntentAddReminder, ""
 	}
+	if classification.Confidence < minIntentConfidence {
+		return intentUnclear, b.askToClarify(userID, message, classification)
+	}
 
-	switch intent {
+	switch intent := classification.Intent; intent {
 	case myopenai.IntentDeleteReminder:
 		return intent, extractDeleteKeyword(message)
 	case myopenai.IntentListReminders,
 		myopenai.IntentClearReminders,
 		myopenai.IntentHelp,
+		myopenai.IntentConnect,
 		myopenai.IntentAddReminder:
 		return intent, ""
 	default:
@@ -173,169 +656,712 @@ func (b *Bot) determineIntent(ctx context.Context, message, lowerMessage string)
 	}
 }
 
-func (b *Bot) handlePriorityResponse(w http.ResponseWriter, userID, priorityText string) {
-	priority, err := strconv.Atoi(strings.TrimSpace(priorityText))
-	if err != nil || priority < 1 || priority > 5 {
-		b.writeTwilioResponse(w, "Please send a priority between 1 (lowest) and 5 (highest).")
+const (
+	// maxPriorityMisses is how many replies that aren't a priority the bot
+	// takes before offering to save with defaultPromptPriority or cancel.
+	maxPriorityMisses     = 2
+	defaultPromptPriority = 3
+)
+
+func (b *Bot) handlePriorityResponse(ctx context.Context, userID, priorityText string) string {
+	text := strings.Trim(strings.ToLower(strings.TrimSpace(priorityText)), ".!")
+	priority, err := strconv.Atoi(text)
+	if err != nil || priority < 1 || priority > 5 {
+		if b.state.PriorityMisses(userID) >= maxPriorityMisses {
+			switch text {
+			case "save", "save it", "default", "keep it":
+				priority = defaultPromptPriority
+			case "discard", "drop it":
+				b.state.PopPendingMessage(userID)
+				return "Okay, I dropped that reminder."
+			}
+		}
+	}
+	if priority < 1 || priority > 5 {
+		if b.state.MissPriority(userID) < maxPriorityMisses {
+			return "Please send a priority between 1 (lowest) and 5 (highest)."
+		}
+		return fmt.Sprintf("I still need a number from 1 to 5. Reply 'save' to keep it at priority %d, or 'cancel' to drop it.", defaultPromptPriority)
+	}
+
+	content, ok := b.state.PopPendingMessage(userID)
+	if !ok {
+		return "I lost track of that reminder. Please send it again."
+	}
+
+	return b.addReminder(ctx, userID, content, priority)
+}
+
+// addReminder summarises and saves a reminder, returning the reply for the
+// user. A reminder that ends "after I finish …" waits for the reminder named.
+func (b *Bot) addReminder(ctx context.Context, userID, content string, priority int) string {
+	summary := b.summarizeReminderWithOpenAI(ctx, userID, content)
+	reminder := &model.Reminder{
+		UserID:       userID,
+		Content:      content,
+		Priority:     priority,
+		Summary:      summary,
+		TimeOfDay:    parseTimeOfDay(content),
+		BusinessDays: parseBusinessDays(content),
+	}
+	rule, repeats := b.recurrenceRule(ctx, userID, content)
+	if repeats {
+		if first := firstOccurrence(rule, time.Now().In(b.cfg.LocalTimezone)); !first.IsZero() {
+			due := b.workingDue(*reminder, first)
+			reminder.RRule = rule.String()
+			reminder.DueAt = &due
+			reminder.OccursAt = occursAt(first, due)
+		}
+	}
+	parent, note := b.dependencyParent(ctx, userID, content)
+	if parent != nil {
+		reminder.ParentID = &parent.ID
+	}
+	if err := b.insertReminder(ctx, reminder); err != nil {
+		b.logger.Printf("save reminder: %v", err)
+		return "I couldn't save the reminder. Please try again."
+	}
+	b.state.SetJustSaved(userID, justSaved{ReminderID: reminder.ID, At: time.Now()})
+
+	reply := fmt.Sprintf("Got it! I'll remind you: %s (priority %d).", summary, priority)
+	if reminder.TimeOfDay != "" {
+		reply = fmt.Sprintf("Got it! I'll remind you in the %s: %s (priority %d).", reminder.TimeOfDay, summary, priority)
+	}
+	if reminder.RRule != "" {
+		reply += fmt.Sprintf(" It repeats %s, starting %s.", rule.Describe(), reminder.DueAt.Format("Mon Jan 2"))
+	}
+	if reminder.BusinessDays {
+		reply += " I'll skip weekends and public holidays."
+	}
+	switch {
+	case parent != nil:
+		reply += fmt.Sprintf(" I'll hold it until %s %s is done.", parent.ShortID(), fallback(parent.Summary, parent.Content))
+	case note != "":
+		reply += " " + note
+	}
+	if dup := b.duplicateOf(ctx, *reminder); dup != nil {
+		reply += fmt.Sprintf("\nLooks like you already have %s: %s.", dup.ShortID(), fallback(dup.Summary, dup.Content))
+	}
+	return reply
+}
+
+// askForPriority prompts the user to provide a priority for their reminder.
+func (b *Bot) askForPriority() string {
+	return "What priority should I set? Reply with a number between 1 (low) and 5 (high)."
+}
+
+// saveReminder persists a reminder to the database.
+func (b *Bot) saveReminder(ctx context.Context, userID, message string, priority int, summary string) error {
+	return b.insertReminder(ctx, &model.Reminder{
+		UserID:   userID,
+		Content:  message,
+		Priority: priority,
+		Summary:  summary,
+	})
+}
+
+// insertReminder categorizes, saves, and embeds reminder and emits its
+// created event.
+func (b *Bot) insertReminder(ctx context.Context, reminder *model.Reminder) error {
+	if reminder.Category == "" {
+		reminder.Category = b.categorize(ctx, reminder.UserID, reminder.Content)
+	}
+	if err := b.db.WithContext(ctx).Create(reminder).Error; err != nil {
+		return err
+	}
+	b.embedReminders(ctx, reminder.UserID, []model.Reminder{*reminder})
+	b.emit(webhook.EventReminderCreated, *reminder, "")
+	return nil
+}
+
+// openReminders returns the user's reminders that are not completed, in list order.
+func (b *Bot) openReminders(ctx context.Context, userID string) ([]model.Reminder, error) {
+	var reminders []model.Reminder
+	err := b.db.WithContext(ctx).Where("user_id = ? AND completed_at IS NULL", userID).
+		Where("NOT " + waitingOnParent).
+		Clauses(b.reminderOrder(userID)).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// openRemindersPage returns up to limit open reminders matching filter, in
+// list order, starting at offset, along with how many match in total.
+func (b *Bot) openRemindersPage(ctx context.Context, userID string, filter listFilter, offset, limit int) ([]model.Reminder, int64, error) {
+	now := time.Now().In(b.cfg.LocalTimezone)
+	open := func() *gorm.DB {
+		query := b.db.WithContext(ctx).Model(&model.Reminder{}).
+			Where("user_id = ? AND completed_at IS NULL", userID).
+			Where("NOT " + waitingOnParent)
+		return filter.apply(query, now)
+	}
+	var total int64
+	if err := open().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var reminders []model.Reminder
+	err := open().Clauses(b.reminderOrder(userID)).Offset(offset).Limit(limit).Find(&reminders).Error
+	return reminders, total, err
+}
+
+// listReminders returns the first page of a user's reminders.
+func (b *Bot) listReminders(ctx context.Context, userID string) string {
+	return b.listRemindersPage(ctx, userID, listFilter{}, 1)
+}
+
+// listRemindersPage returns one page of a user's reminders matching filter in
+// human-readable form, numbered by place in the full list. When more follow it
+// says so and remembers the next page for a "more" reply. It returns an empty
+// string when nothing matches.
+func (b *Bot) listRemindersPage(ctx context.Context, userID string, filter listFilter, page int) string {
+	offset := (page - 1) * listPageSize
+	reminders, total, err := b.openRemindersPage(ctx, userID, filter, offset, listPageSize)
+	if err != nil {
+		b.logger.Printf("list reminders error: %v", err)
+		return ""
+	}
+	if total == 0 {
+		return ""
+	}
+	if len(reminders) == 0 {
+		pages := (int(total) + listPageSize - 1) / listPageSize
+		return fmt.Sprintf("There's no page %d. Your reminders fit on %d page(s).", page, pages)
+	}
+
+	// Filtered entries keep their place in the full list, like search
+	// results, so "delete 3" still means the third reminder.
+	position := make(map[uint]int, len(reminders))
+	if filter.empty() {
+		for i, r := range reminders {
+			position[r.ID] = offset + i + 1
+		}
+	} else {
+		all, err := b.openReminders(ctx, userID)
+		if err != nil {
+			b.logger.Printf("list reminders error: %v", err)
+			return ""
+		}
+		for i, r := range all {
+			position[r.ID] = i + 1
+		}
+	}
+
+	now := time.Now().In(b.cfg.LocalTimezone)
+	var sb strings.Builder
+	var labels []string
+	if !filter.empty() {
+		labels = append(labels, filter.String())
+	}
+	if page > 1 {
+		labels = append(labels, fmt.Sprintf("page %d", page))
+	}
+	if len(labels) == 0 {
+		sb.WriteString("Here are your reminders:\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Here are your reminders (%s):\n", strings.Join(labels, ", ")))
+	}
+	if groups := b.groupByTopic(ctx, userID, reminders, filter); groups != nil {
+		for _, g := range groups {
+			sb.WriteString(g.Topic + ":\n")
+			for _, r := range g.Reminders {
+				sb.WriteString(b.reminderLine(r, position[r.ID], now))
+			}
+		}
+	} else {
+		for _, r := range reminders {
+			sb.WriteString(b.reminderLine(r, position[r.ID], now))
+		}
+	}
+	if rest := int(total) - offset - len(reminders); rest > 0 {
+		sb.WriteString(fmt.Sprintf("…and %d more, reply 'more' to continue.\n", rest))
+		b.state.SetListPage(userID, filter, page+1)
+	} else if waiting := b.waitingCount(ctx, userID); waiting > 0 && filter.empty() {
+		sb.WriteString(fmt.Sprintf("%d more waiting for other reminders to be done.\n", waiting))
+	}
+	return sb.String()
+}
+
+// reminderLine renders r as line n of a reminder list, with its position in
+// a fixed-width font and its text in bold.
+func (b *Bot) reminderLine(r model.Reminder, n int, now time.Time) string {
+	position, text := mono(strconv.Itoa(n)+"."), bold(fallback(r.Summary, r.Content))
+	if r.Steps > 0 {
+		text += " (" + stepProgress(r) + ")"
+	}
+	switch {
+	case r.IsHabit():
+		return fmt.Sprintf("%s %s [%d] %s — habit, %s\n", position, r.ShortID(), r.Priority, text, streakLabel(r, now))
+	case r.IsCountdown():
+		return fmt.Sprintf("%s %s [%d] %s — countdown, %s\n", position, r.ShortID(), r.Priority, text, daysLeftLabel(daysUntil(*r.DueAt, now)))
+	case r.IsAnnual():
+		return fmt.Sprintf("%s %s [%d] %s — every year, next %s\n", position, r.ShortID(), r.Priority, text, r.DueAt.In(now.Location()).Format("Jan 02"))
+	case r.DueAt != nil:
+		return fmt.Sprintf("%s %s [%s] %s — due %s\n", position, r.ShortID(), b.priorityLabel(r, now), text, r.DueAt.In(b.cfg.LocalTimezone).Format("Jan 02"))
+	}
+	return fmt.Sprintf("%s %s [%s] %s — saved %s\n", position, r.ShortID(), b.priorityLabel(r, now), text, r.CreatedAt.Format("Jan 02 15:04"))
+}
+
+// handleMoreCommand shows the next page after a list that was cut short. It
+// reports false when the message is not "more" or no list is in progress.
+func (b *Bot) handleMoreCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	switch strings.TrimRight(strings.TrimSpace(lowerBody), ".!") {
+	case "more", "next", "next page", "show more":
+	default:
+		return "", false
+	}
+	filter, page, ok := b.state.PopListPage(userID)
+	if !ok {
+		return "", false
+	}
+	if list := b.listRemindersPage(ctx, userID, filter, page); list != "" {
+		return list, true
+	}
+	return "You have no reminders yet. Send me one to get started!", true
+}
+
+// deleteReminder deletes reminders based on a keyword or index list and returns a status message.
+// When a keyword matches several reminders it asks which to delete instead.
+func (b *Bot) deleteReminder(ctx context.Context, userID, keyword string) (string, error) {
+	trimmed := strings.TrimSpace(keyword)
+	if trimmed == "" {
+		deleted, err := b.removeReminders(ctx, b.db.Where("user_id = ?", userID))
+		if err != nil {
+			return "", fmt.Errorf("I couldn't clear your reminders. Please try again later")
+		}
+		if len(deleted) == 0 {
+			return "", userError{"You don't have any reminders to clear."}
+		}
+		return "All reminders cleared.", nil
+	}
+
+	if indices := parseIndices(trimmed); len(indices) > 0 {
+		if _, err := b.deleteReminderByIndices(ctx, userID, indices); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted reminder(s): %s.", formatIndices(indices)), nil
+	}
+
+	if codes := parseReminderCodes(trimmed); len(codes) > 0 {
+		targets, err := b.remindersByCode(ctx, b.db.Where("user_id = ?", userID), codes)
+		if err != nil {
+			return "", err
+		}
+		ids := make([]uint, len(targets))
+		labels := make([]string, len(targets))
+		for i, r := range targets {
+			ids[i], labels[i] = r.ID, r.ShortID()
+		}
+		if _, err := b.removeReminders(ctx, b.db.Where("id IN ?", ids)); err != nil {
+			return "", fmt.Errorf("I couldn't delete those reminders. Please try again later")
+		}
+		return fmt.Sprintf("Deleted reminder(s): %s.", strings.Join(labels, ", ")), nil
+	}
+
+	var reminders []model.Reminder
+	if err := b.db.WithContext(ctx).Where("user_id = ?", userID).
+		Clauses(b.reminderOrder(userID)).
+		Find(&reminders).Error; err != nil {
+		return "", fmt.Errorf("I couldn't delete that reminder. Please try again later")
+	}
+	matches := matchReminders(reminders, trimmed)
+	switch len(matches) {
+	case 0:
+		return "", userError{"I couldn't find any reminders matching that description."}
+	case 1:
+		if _, err := b.removeReminders(ctx, b.db.Where("id = ?", matches[0].ID)); err != nil {
+			return "", fmt.Errorf("I couldn't delete that reminder. Please try again later")
+		}
+		return fmt.Sprintf("Deleted reminders matching '%s'.", trimmed), nil
+	}
+
+	// Several reminders match: ask which ones rather than deleting them all.
+	ids := make([]uint, len(matches))
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d reminders match '%s':\n", len(matches), trimmed))
+	for i, r := range matches {
+		ids[i] = r.ID
+		sb.WriteString(fmt.Sprintf("%d. [%d] %s\n", i+1, r.Priority, fallback(r.Summary, r.Content)))
+	}
+	sb.WriteString("Which should I delete? Reply with the number(s), 'all', or 'cancel'.")
+	b.state.SetPendingDelete(userID, ids)
+	return sb.String(), nil
+}
+
+// handlePendingDelete answers the question deleteReminder asks when a keyword
+// matches several reminders. It reports false, dropping the question, when
+// the message is not an answer so it can be handled as usual.
+func (b *Bot) handlePendingDelete(ctx context.Context, userID, body string) (string, bool) {
+	ids, ok := b.state.PopPendingDelete(userID)
+	if !ok {
+		return "", false
+	}
+
+	var chosen []uint
+	switch answer := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(body), ".")); answer {
+	case "all", "all of them", "both":
+		chosen = ids
+	case "none", "no":
+		return "Okay, I won't delete anything.", true
+	default:
+		indices := parseIndices(answer)
+		if len(indices) == 0 {
+			return "", false
+		}
+		for _, idx := range indices {
+			if idx < 1 || idx > len(ids) {
+				b.state.SetPendingDelete(userID, ids)
+				return fmt.Sprintf("Please reply with a number between 1 and %d, 'all', or 'cancel'.", len(ids)), true
+			}
+			chosen = append(chosen, ids[idx-1])
+		}
+	}
+
+	deleted, err := b.removeReminders(ctx, b.db.Where("user_id = ? AND id IN ?", userID, chosen))
+	if err != nil {
+		b.logger.Printf("delete reminder: %v", err)
+		return "I couldn't delete that reminder. Please try again later.", true
+	}
+	if len(deleted) == 0 {
+		return "Those reminders are already gone.", true
+	}
+	names := make([]string, len(deleted))
+	for i, r := range deleted {
+		names[i] = fallback(r.Summary, r.Content)
+	}
+	return "Deleted: " + strings.Join(names, "; ") + ".", true
+}
+
+func (b *Bot) deleteReminderByIndices(ctx context.Context, userID string, indices []int) (int64, error) {
+	reminders, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("I couldn't look up your reminders right now. Please try again later")
+	}
+	if len(reminders) == 0 {
+		return 0, userError{"You don't have any reminders yet."}
+	}
+
+	ids := make([]uint, 0, len(indices))
+	for _, idx := range indices {
+		if idx < 1 || idx > len(reminders) {
+			return 0, userError{fmt.Sprintf("Reminder %d doesn't exist. Choose between 1 and %d.", idx, len(reminders))}
+		}
+		ids = append(ids, reminders[idx-1].ID)
+	}
+
+	deleted, err := b.removeReminders(ctx, b.db.Where("user_id = ? AND id IN ?", userID, ids))
+	if err != nil {
+		return 0, fmt.Errorf("I couldn't delete those reminders. Please try again later")
+	}
+	if len(deleted) == 0 {
+		return 0, userError{"I couldn't delete those reminders. Please try again later."}
+	}
+	return int64(len(deleted)), nil
+}
+
+// remindersByCode loads the reminders matched by query with the given codes,
+// in code order. It fails with a user error naming the first code that
+// matches nothing.
+func (b *Bot) remindersByCode(ctx context.Context, query *gorm.DB, codes []int) ([]model.Reminder, error) {
+	var found []model.Reminder
+	if err := query.WithContext(ctx).Where("code IN ?", codes).Order("code ASC").Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("I couldn't look up your reminders right now. Please try again later")
+	}
+	if len(found) < len(codes) {
+		have := make(map[int]bool, len(found))
+		for _, r := range found {
+			have[r.Code] = true
+		}
+		for _, code := range codes {
+			if !have[code] {
+				return nil, userError{fmt.Sprintf("I couldn't find reminder R%d.", code)}
+			}
+		}
+	}
+	return found, nil
+}
+
+// removeReminders deletes the reminders matched by query, emits a deleted
+// event for each, and returns them. Both statements run under ctx.
+func (b *Bot) removeReminders(ctx context.Context, query *gorm.DB) ([]model.Reminder, error) {
+	var reminders []model.Reminder
+	if err := query.WithContext(ctx).Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	if len(reminders) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(reminders))
+	for i, r := range reminders {
+		ids[i] = r.ID
+	}
+	if err := b.db.WithContext(ctx).Where("id IN ?", ids).Delete(&model.Reminder{}).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range reminders {
+		b.emit(webhook.EventReminderDeleted, r, "")
+	}
+	return reminders, nil
+}
+
+// sendScheduledReminders sends all reminders sorted by priority starting at
+// 8AM local time. It returns the users whose dispatch was started.
+func (b *Bot) sendScheduledReminders() []string {
+	b.pruneSentMessages(time.Now())
+	var users []string
+	if err := b.db.Model(&model.Reminder{}).Distinct().Pluck("user_id", &users).Error; err != nil {
+		b.logger.Printf("scheduler: fetch users: %v", err)
+		return nil
+	}
+
+	started := make([]string, 0, len(users))
+	for _, userID := range users {
+		if b.sends.Go(func() { b.dispatchUserReminders(userID) }) {
+			started = append(started, userID)
+		}
+	}
+	return started
+}
+
+// dispatchUserReminders sends a user's open reminders over their chosen
+// channels: an email digest, spaced WhatsApp messages, or both. If the digest
+// can't be sent, WhatsApp is used instead.
+func (b *Bot) dispatchUserReminders(userID string) {
+	pref := b.preferences(userID)
+	if pref.Paused || pref.OptedOutAt != nil || pref.UnjoinedAt != nil {
 		return
 	}
-
-	content, ok := b.state.PopPendingMessage(userID)
-	if !ok {
-		b.writeTwilioResponse(w, "I lost track of that reminder. Please send it again.")
+	now := time.Now().In(b.cfg.LocalTimezone)
+	if b.blackedOut(context.Background(), userID, now) {
 		return
 	}
-
-	summary := b.summarizeReminderWithOpenAI(content)
-	if err := b.saveReminder(userID, content, priority, summary); err != nil {
-		b.logger.Printf("save reminder: %v", err)
-		b.writeTwilioResponse(w, "I couldn't save the reminder. Please try again.")
+	b.finishCountdowns(context.Background(), userID, now)
+	b.rollAnnual(context.Background(), userID, now)
+	b.rollRecurring(context.Background(), userID, now)
+	reminders, err := b.openReminders(context.Background(), userID)
+	if err != nil {
+		b.logger.Printf("scheduler: user %s: %v", userID, err)
 		return
 	}
 
-	b.writeTwilioResponse(w, fmt.Sprintf("Got it! I'll remind you: %s (priority %d).", summary, priority))
-}
+	read := b.readRecently(context.Background(), userID, now)
+	due := reminders[:0]
+	for _, reminder := range reminders {
+		if read[reminder.ID] {
+			continue
+		}
+		if reminder.SnoozedUntil != nil && now.Before(*reminder.SnoozedUntil) {
+			continue
+		}
+		if reminder.IsHabit() && coveredOn(reminder, now) {
+			continue
+		}
+		if reminder.IsCountdown() && !countdownDue(reminder, now) {
+			continue
+		}
+		if reminder.IsAnnual() && !annualDue(reminder, b.birthdayLead(userID), now) {
+			continue
+		}
+		if reminder.RRule != "" && !dueBy(reminder, now) {
+			continue
+		}
+		if reminder.BusinessDays && !b.calendar().WorkingDay(now) {
+			continue
+		}
+		due = append(due, reminder)
+	}
+	if len(due) == 0 {
+		return
+	}
 
-// askForPriority prompts the user to provide a priority for their reminder.
-func (b *Bot) askForPriority() string {
-	return "What priority should I set? Reply with a number between 1 (low) and 5 (high)."
-}
+	if pref.EmailDelivery() {
+		if err := b.sendDigest(pref.Email, due, now); err != nil {
+			b.logger.Printf("scheduler: email digest for %s: %v", userID, err)
+		} else if !pref.WhatsAppDelivery() {
+			return
+		}
+	}
 
-// saveReminder persists a reminder to the database.
-func (b *Bot) saveReminder(userID, message string, priority int, summary string) error {
-	reminder := &model.Reminder{
-		UserID:   userID,
-		Content:  message,
-		Priority: priority,
-		Summary:  summary,
+	// Parts of the day follow the clock where the user is.
+	due, slots := dispatchSlots(due, pref, b.reminderGap(userID), now.In(b.location(pref)))
+	var unsent []*pendingSend
+	for i, reminder := range due {
+		send := &pendingSend{
+			UserID:     userID,
+			ReminderID: reminder.ID,
+			Body:       i18n.Translate(pref.Language, b.reminderMessage(reminder, now)),
+			SendAt:     slots[i],
+			Key:        sendKey(reminder.ID, now),
+		}
+		if !b.sends.Schedule(send, b.deliver) {
+			unsent = append(unsent, send)
+		}
 	}
-	return b.db.Create(reminder).Error
+	b.saveOutbox(unsent)
 }
 
-// listReminders returns a human-readable list of reminders for a user.
-func (b *Bot) listReminders(userID string) string {
-	var reminders []model.Reminder
-	if err := b.db.Where("user_id = ?", userID).
-		Order("priority DESC, created_at ASC").
-		Find(&reminders).Error; err != nil {
-		b.logger.Printf("list reminders error: %v", err)
-		return ""
+// reminderMessage renders the scheduled message for a reminder.
+func (b *Bot) reminderMessage(reminder model.Reminder, now time.Time) string {
+	text := fallback(reminder.Summary, reminder.Content)
+	if reminder.IsHabit() {
+		return fmt.Sprintf("Habit: %s (priority %d) — %s Reply 'done' when you've finished, or 'skip' to take today off.", bold(text), reminder.Priority, streakMessage(reminder, now))
 	}
-	if len(reminders) == 0 {
-		return ""
+	if reminder.IsCountdown() {
+		return countdownMessage(reminder, now)
 	}
-
-	var sb strings.Builder
-	sb.WriteString("Here are your reminders:\n")
-	for i, r := range reminders {
-		sb.WriteString(fmt.Sprintf("%d. [%d] %s — saved %s\n", i+1, r.Priority, fallback(r.Summary, r.Content), r.CreatedAt.Format("Jan 02 15:04")))
+	if reminder.IsAnnual() {
+		return annualMessage(reminder, now)
 	}
-	return sb.String()
+	msg := fmt.Sprintf("Reminder: %s (priority %d)", bold(text), reminder.Priority)
+	if link := mapsLink(reminder); link != "" {
+		msg += "\n" + link
+	}
+	if link := b.attachmentLink(reminder); link != "" {
+		msg += "\n" + link
+	}
+	return msg
 }
 
-// deleteReminder deletes reminders based on a keyword or index list and returns a status message.
-func (b *Bot) deleteReminder(userID, keyword string) (string, error) {
-	trimmed := strings.TrimSpace(keyword)
-	if trimmed == "" {
-		res := b.db.Where("user_id = ?", userID).Delete(&model.Reminder{})
-		if res.Error != nil {
-			return "", fmt.Errorf("I couldn't clear your reminders. Please try again later")
-		}
-		if res.RowsAffected == 0 {
-			return "", userError{"You don't have any reminders to clear."}
-		}
-		return "All reminders cleared.", nil
+// deliver sends a single scheduled reminder message.
+func (b *Bot) deliver(send *pendingSend) {
+	// The user may have opted out, paused, or blocked today after the send
+	// was queued. A pause-all from an admin stops sends already queued too.
+	if b.optedOut(send.UserID) || b.preferences(send.UserID).Paused || b.blackedOut(context.Background(), send.UserID, time.Now().In(b.cfg.LocalTimezone)) {
+		return
 	}
-
-	if indices := parseIndices(trimmed); len(indices) > 0 {
-		if _, err := b.deleteReminderByIndices(userID, indices); err != nil {
-			return "", err
+	if !b.claimSend(send) {
+		b.logger.Printf("scheduler: skip duplicate send %s", send.Key)
+		return
+	}
+	reminder := model.Reminder{ID: send.ReminderID, UserID: send.UserID}
+	if send.ReminderID != 0 {
+		if err := b.db.First(&reminder, send.ReminderID).Error; err != nil {
+			reminder = model.Reminder{ID: send.ReminderID, UserID: send.UserID}
 		}
-		return fmt.Sprintf("Deleted reminder(s): %s.", formatIndices(indices)), nil
 	}
 
-	query := b.db.Where("user_id = ? AND LOWER(content) LIKE ?", userID, "%"+strings.ToLower(trimmed)+"%")
-	res := query.Delete(&model.Reminder{})
-	if res.Error != nil {
-		return "", fmt.Errorf("I couldn't delete that reminder. Please try again later")
+	// Placeholders such as {date} are filled in now rather than when the
+	// send was queued.
+	body := expandPlaceholders(send.Body, reminder, time.Now().In(b.location(b.preferences(send.UserID))))
+	err := b.notifyReminder(context.Background(), reminder, body, false)
+	if err != nil {
+		b.logger.Printf("scheduler: send reminder: %v", err)
 	}
-	if res.RowsAffected == 0 {
-		return "", userError{"I couldn't find any reminders matching that description."}
+	// A call that gets through after a failed message still counts as
+	// delivered.
+	if called := b.callAbout(context.Background(), reminder, body, err != nil); err != nil && !called {
+		b.releaseSend(send)
+		return
 	}
-	return fmt.Sprintf("Deleted reminders matching '%s'.", trimmed), nil
+	b.emit(webhook.EventReminderSent, reminder, body)
 }
 
-func (b *Bot) deleteReminderByIndices(userID string, indices []int) (int64, error) {
-	var reminders []model.Reminder
-	if err := b.db.Where("user_id = ?", userID).
-		Order("priority DESC, created_at ASC").
-		Find(&reminders).Error; err != nil {
-		return 0, fmt.Errorf("I couldn't look up your reminders right now. Please try again later")
+// claimSend records send's idempotency key before it goes out. It returns
+// false when a send with the same key was already delivered. Sends without a
+// key, and sends whose key can't be recorded, always go out.
+func (b *Bot) claimSend(send *pendingSend) bool {
+	if send.Key == "" {
+		return true
 	}
-	if len(reminders) == 0 {
-		return 0, userError{"You don't have any reminders yet."}
+	result := b.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.SentMessage{
+		IdempotencyKey: send.Key,
+		UserID:         send.UserID,
+		SentAt:         time.Now(),
+	})
+	if result.Error != nil {
+		b.logger.Printf("scheduler: record send %s: %v", send.Key, result.Error)
+		return true
 	}
+	return result.RowsAffected > 0
+}
 
-	ids := make([]uint, 0, len(indices))
-	for _, idx := range indices {
-		if idx < 1 || idx > len(reminders) {
-			return 0, userError{fmt.Sprintf("Reminder %d doesn't exist. Choose between 1 and %d.", idx, len(reminders))}
-		}
-		ids = append(ids, reminders[idx-1].ID)
+// releaseSend forgets the key of a send that failed, so a retry may deliver
+// it.
+func (b *Bot) releaseSend(send *pendingSend) {
+	if send.Key == "" {
+		return
 	}
-
-	res := b.db.Where("user_id = ? AND id IN ?", userID, ids).Delete(&model.Reminder{})
-	if res.Error != nil {
-		return 0, fmt.Errorf("I couldn't delete those reminders. Please try again later")
+	if err := b.db.Delete(&model.SentMessage{}, "idempotency_key = ?", send.Key).Error; err != nil {
+		b.logger.Printf("scheduler: release send %s: %v", send.Key, err)
 	}
-	if res.RowsAffected == 0 {
-		return 0, userError{"I couldn't delete those reminders. Please try again later."}
+}
+
+// pruneSentMessages drops idempotency keys too old to matter: keys name a
+// day, so a week's worth covers any retry.
+func (b *Bot) pruneSentMessages(now time.Time) {
+	if err := b.db.Where("sent_at < ?", now.AddDate(0, 0, -7)).Delete(&model.SentMessage{}).Error; err != nil {
+		b.logger.Printf("scheduler: prune sent messages: %v", err)
 	}
-	return res.RowsAffected, nil
 }
 
-// sendScheduledReminders sends all reminders sorted by priority starting at 8AM local time.
-func (b *Bot) sendScheduledReminders() {
-	var users []string
-	if err := b.db.Model(&model.Reminder{}).Distinct().Pluck("user_id", &users).Error; err != nil {
-		b.logger.Printf("scheduler: fetch users: %v", err)
+// saveOutbox persists sends that could not be delivered before shutdown.
+func (b *Bot) saveOutbox(sends []*pendingSend) {
+	if len(sends) == 0 {
 		return
 	}
-
-	for _, userID := range users {
-		go b.dispatchUserReminders(userID)
+	rows := make([]model.OutboxMessage, 0, len(sends))
+	for _, send := range sends {
+		rows = append(rows, model.OutboxMessage{
+			UserID:         send.UserID,
+			ReminderID:     send.ReminderID,
+			Body:           send.Body,
+			SendAt:         send.SendAt,
+			IdempotencyKey: send.Key,
+		})
+	}
+	if err := b.db.Create(&rows).Error; err != nil {
+		b.logger.Printf("scheduler: persist %d pending sends: %v", len(rows), err)
+		return
 	}
+	b.logger.Printf("scheduler: persisted %d pending sends", len(rows))
 }
 
-func (b *Bot) dispatchUserReminders(userID string) {
-	var reminders []model.Reminder
-	if err := b.db.Where("user_id = ?", userID).
-		Order("priority DESC, created_at ASC").
-		Find(&reminders).Error; err != nil {
-		b.logger.Printf("scheduler: user %s: %v", userID, err)
+// restoreOutbox reschedules sends persisted by a previous shutdown. Overdue
+// sends go out immediately; sends for deleted reminders are dropped.
+func (b *Bot) restoreOutbox() {
+	var rows []model.OutboxMessage
+	if err := b.db.Order("send_at ASC").Find(&rows).Error; err != nil {
+		b.logger.Printf("scheduler: load outbox: %v", err)
 		return
 	}
-	if len(reminders) == 0 {
+	if len(rows) == 0 {
+		return
+	}
+	if err := b.db.Delete(&rows).Error; err != nil {
+		b.logger.Printf("scheduler: clear outbox: %v", err)
 		return
 	}
 
-	for index, reminder := range reminders {
-		delay := time.Duration(index) * time.Hour
-		time.AfterFunc(delay, func(rem model.Reminder) func() {
-			return func() {
-				message := fmt.Sprintf("Reminder: %s (priority %d)", fallback(rem.Summary, rem.Content), rem.Priority)
-				if err := b.twilio.SendWhatsAppMessage(userID, message); err != nil {
-					b.logger.Printf("scheduler: send reminder: %v", err)
-				}
+	restored := 0
+	for _, row := range rows {
+		if row.ReminderID != 0 {
+			var count int64
+			if err := b.db.Model(&model.Reminder{}).Where("id = ?", row.ReminderID).Count(&count).Error; err == nil && count == 0 {
+				continue
 			}
-		}(reminder))
+		}
+		send := &pendingSend{
+			UserID:     row.UserID,
+			ReminderID: row.ReminderID,
+			Body:       row.Body,
+			SendAt:     row.SendAt,
+			Key:        row.IdempotencyKey,
+		}
+		if b.sends.Schedule(send, b.deliver) {
+			restored++
+		}
 	}
+	b.logger.Printf("scheduler: restored %d pending sends", restored)
 }
 
-// summarizeReminderWithOpenAI generates a short summary for the reminder content.
-func (b *Bot) summarizeReminderWithOpenAI(content string) string {
-	ctx := context.Background()
-	summary, err := b.openAI.SummarizeReminder(ctx, content)
+// summarizeReminderWithOpenAI generates a short summary for the reminder
+// content, or returns the content unchanged when the user's tenant is out of
+// OpenAI calls.
+func (b *Bot) summarizeReminderWithOpenAI(ctx context.Context, userID, content string) string {
+	if hasPlaceholders(content) || !b.useOpenAI(ctx, userID) {
+		return content
+	}
+	language := i18n.ByCode(b.preferences(userID).Language)
+	summary, err := b.llm.SummarizeReminder(ctx, content, language.Name)
 	if err != nil {
 		b.logger.Printf("openai summarise error: %v", err)
 		return content
@@ -343,12 +1369,34 @@ func (b *Bot) summarizeReminderWithOpenAI(content string) string {
 	return summary
 }
 
+// summarizeRemindersWithOpenAI summarises several reminders at once for
+// imports and bulk adds. It spends one OpenAI call per batch instead of one
+// per reminder, and returns the contents unchanged when that fails.
+func (b *Bot) summarizeRemindersWithOpenAI(ctx context.Context, userID string, contents []string) []string {
+	if len(contents) == 0 || !b.useOpenAI(ctx, userID) {
+		return contents
+	}
+	language := i18n.ByCode(b.preferences(userID).Language)
+	summaries, err := b.llm.SummarizeReminders(ctx, contents, language.Name)
+	if err != nil {
+		b.logger.Printf("openai summarise batch error: %v", err)
+		return contents
+	}
+	for i, content := range contents {
+		if hasPlaceholders(content) {
+			summaries[i] = content
+		}
+	}
+	return summaries
+}
+
 func (b *Bot) writeTwilioResponse(w http.ResponseWriter, message string) {
 	twiml := struct {
 		XMLName xml.Name `xml:"Response"`
 		Message string   `xml:"Message"`
 	}{
-		Message: message,
+		// XML can't carry the formatting marks, so any left are dropped.
+		Message: plainText(message),
 	}
 
 	w.Header().Set("Content-Type", "application/xml")
@@ -384,7 +1432,7 @@ func fallback(primary, secondary string) string {
 }
 
 func helpResponse() string {
-	return "You can say things like:\n- \"Remind me to pay rent\" to add a reminder\n- \"List reminders\" to see everything saved\n- \"Delete reminder about rent\" to remove one\n- \"Clear all reminders\" to wipe everything"
+	return "You can say things like:\n- \"Remind me to pay rent\" to add a reminder, then \"no, make it Friday\", \"change priority to 2\", or \"undo\" within 5 minutes to fix it\n- \"List reminders\" to see everything saved\n- \"Delete reminder about rent\" to remove one\n- \"Set R3 priority 5\" to change a reminder's priority, or \"rebalance\" for suggested priorities when too many are urgent\n- \"Clear all reminders\" to wipe everything\n- \"Cancel\" or \"never mind\" to back out of a question I asked\n- \"My settings\" to see your timezone, delivery time, language, digest, and quiet hours, with how to change each\n- \"Send my reminders 10 minutes apart\" to change the spacing, \"set delivery time 6pm\" to hold them later, or \"quiet hours 10pm to 7am\" to keep nights free\n- \"Remind me in the evening to water plants\" to wait for the morning, afternoon, or evening, and \"set evening to 8pm\" to move it\n- \"Sort by date\", \"sort by due date\", or \"sort by priority\" to change the order\n- \"Set language Spanish\" to get replies in Spanish, French, or Portuguese\n- \"My email is you@example.com\" then \"send my reminders by email\" for a daily digest\n- \"Pause reminders\" and \"resume reminders\" to take a break, or \"I'm traveling next week\" to hold them for set days (\"I'm back\" to undo)\n- \"Tips on\" for an occasional tip on features you haven't tried, plus news about the bot\n- \"Save template gym: Go to the gym, priority 4\" then send \"gym\" to reuse it\n- \"Habit: meditate 10 minutes\" to track a daily habit, then \"done\" to keep the streak, \"skip\" or \"not today\" to take a day off without losing it\n- \"Countdown to Dec 25: trip to Goa\" for a daily days-left message (\"weekly countdown ...\" for weekly)\n- \"Birthday: Asha on March 3\" or \"anniversary: ...\" for a yearly heads-up, \"birthdays\" to list them\n- \"Water the plants every 3 days\" or \"pay rent on the first Monday of every month\" for a reminder that repeats (add \"business days only\" to skip weekends and holidays)\n- Put {date}, {weekday}, {days_since_created}, or {days_until_due} in a reminder, as in \"water plants, last done {days_since_created} days ago\", and it is filled in when the reminder is sent\n- \"Done 2\" or \"done R7\" to mark a reminder as finished (R-codes never change, list numbers can)\n- React to a reminder message with 👍 to mark it done, 🔁 to snooze it a day, or ❌ to delete it (or reply to it with \"done\", \"snooze\", or \"delete this\")\n- \"Remind me to submit the report after I finish the draft\" to hold one reminder until another is done\n- Share a location pin, then \"remind me about this place: buy bread\" to get a map link with the reminder\n- Send a photo or file with a caption, such as a passport photo with \"renew passport\", to save a reminder that links to it\n- \"I'm in London now\" when you travel, so reminders follow your local time\n- \"Find reminders about dentist\" to search them\n- \"What did I ask you last Tuesday?\" or \"search my messages for dentist\" to look back at what you sent\n- \"Show 2\" or \"show R7\" for everything saved about one reminder\n- \"List high priority\", \"list overdue\", \"list #work\", or \"list created this week\" to filter\n- \"Project: kitchen renovation\", then \"project kitchen: buy tiles\" or \"add R3 to project kitchen\", and \"list project kitchen\" to see its progress\n- \"Add step buy paint to reminder 2\" for a checklist on a reminder, then \"check off paint\" as you go\n- \"Push everything to tomorrow\" or \"postpone all by 2 days\" to clear your plate for a while\n- \"Morning briefing on\" for what's due and overdue each morning with where to start, or \"plan my day\" for it now\n- \"Show my week\" or \"next week\" for reminders with due dates, day by day\n- \"Calendar invites on\" to get a calendar file for each new reminder with a due date, or \"add R3 to my calendar\" for one\n- \"Add War and Peace to my books list\" or \"show my books list\" for named lists\n- \"Share my shopping list with +14155550123\" (add \"as viewer\" for read-only) to share one\n- \"Share 3 with +14155550123\" to send someone a copy of a reminder they can save with \"save\" (\"no shared reminders\" stops others sending you any)\n- \"Note: ...\" to jot something down without a reminder, \"notes\" to see them\n- Send a link on its own to save it for later, \"show my links\" to see your reading list\n- \"Add webhook https://...\" to get reminder events posted to your own server\n- \"Hook token\" for a token that lets Zapier, IFTTT, or your own scripts add reminders for you\n- \"Connect notion <token> <database link>\" to mirror reminders into Notion\n- \"Connect caldav https://caldav.icloud.com <apple id> <app password>\" to keep reminders in Apple Reminders or another CalDAV task list\n- \"Stats\" to see how quickly you finish reminders and which ones you keep putting off\n- \"Dashboard\" for a link to manage reminders on the web\n- \"Export my data\" for a download link to everything you've saved\n- \"How do I connect?\" for steps to set up another phone\n- \"Feedback: the bot forgot my reminder\" to report a problem or tell us what you think\n- \"Help delete\", \"help recurring\", \"help priorities\", or \"help habits\" for more on one topic"
 }
 
 var deleteKeywordRegex = regexp.MustCompile(`(?i)delete(?:\s+reminder(?:s)?(?:\s+about)?)?\s*(.*)`)
@@ -399,6 +1447,34 @@ func extractDeleteKeyword(message string) string {
 
 var indexListPattern = regexp.MustCompile(`^\s*\d+(?:[\s,]+\d+)*\s*$`)
 
+var listPagePattern = regexp.MustCompile(`\bpage\s+([1-9]\d{0,3})\b`)
+
+var reminderCodePattern = regexp.MustCompile(`^\s*[rR]\d+(?:[\s,]+[rR]\d+)*\s*$`)
+
+// parseReminderCodes parses reminder codes such as "R7" or "r7, r9".
+func parseReminderCodes(input string) []int {
+	if !reminderCodePattern.MatchString(input) {
+		return nil
+	}
+	parts := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	seen := make(map[int]struct{}, len(parts))
+	codes := make([]int, 0, len(parts))
+	for _, part := range parts {
+		code, err := strconv.Atoi(part[1:])
+		if err != nil || code <= 0 {
+			return nil
+		}
+		if _, exists := seen[code]; exists {
+			continue
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, code)
+	}
+	return codes
+}
+
 func parseIndices(input string) []int {
 	if !indexListPattern.MatchString(input) {
 		return nil
@@ -452,8 +1528,35 @@ type conversationStore struct {
 }
 
 type conversationState struct {
+	// At is when the state was set. It expires conversationTTL later.
+	At               time.Time
 	AwaitingPriority bool
 	PendingMessage   string
+	// PriorityMisses counts replies to the priority question that weren't
+	// a priority.
+	PriorityMisses int
+	// DeleteCandidates holds the reminder IDs offered when a delete keyword
+	// matched several reminders.
+	DeleteCandidates []uint
+	// ListPage is the page a "more" reply shows after a shortened list,
+	// and ListFilter the filter that list used.
+	ListPage   int
+	ListFilter listFilter
+	// Location is a shared pin waiting to be attached to a reminder.
+	Location *sharedLocation
+	// Attachment is a stored photo or file waiting to be attached to a
+	// reminder.
+	Attachment *pendingAttachment
+	// Reschedule is a new time offered for an overdue reminder.
+	Reschedule *rescheduleOffer
+	// Rebalance holds new priorities waiting for the user to confirm them.
+	Rebalance []priorityChange
+	// JustSaved is the reminder the user saved last, which a correction
+	// within correctionWindow changes.
+	JustSaved *justSaved
+	// Clarify lists the intents offered for PendingMessage when the model
+	// wasn't sure what it meant.
+	Clarify []myopenai.Intent
 }
 
 func newConversationStore() *conversationStore {
@@ -462,19 +1565,56 @@ func newConversationStore() *conversationStore {
 	}
 }
 
+// set replaces userID's state, stamping it with the current time.
+func (c *conversationStore) set(userID string, state conversationState) {
+	state.At = time.Now()
+	c.state[userID] = state
+}
+
+// get returns userID's state unless it has expired. The caller holds c.mu.
+func (c *conversationStore) get(userID string) (conversationState, bool) {
+	state, ok := c.state[userID]
+	if !ok || time.Since(state.At) > conversationTTL {
+		return conversationState{}, false
+	}
+	return state, true
+}
+
+// Expire drops the states set more than conversationTTL before now and
+// returns them by user.
+func (c *conversationStore) Expire(now time.Time) map[string]conversationState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expired := make(map[string]conversationState)
+	for userID, state := range c.state {
+		if now.Sub(state.At) > conversationTTL {
+			expired[userID] = state
+			delete(c.state, userID)
+		}
+	}
+	return expired
+}
+
+// Len returns how many users have conversation state.
+func (c *conversationStore) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.state)
+}
+
 func (c *conversationStore) SetPendingMessage(userID, message string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.state[userID] = conversationState{
+	c.set(userID, conversationState{
 		AwaitingPriority: true,
 		PendingMessage:   message,
-	}
+	})
 }
 
 func (c *conversationStore) PopPendingMessage(userID string) (string, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	state, ok := c.state[userID]
+	state, ok := c.get(userID)
 	if !ok {
 		return "", false
 	}
@@ -482,10 +1622,192 @@ func (c *conversationStore) PopPendingMessage(userID string) (string, bool) {
 	return state.PendingMessage, true
 }
 
+func (c *conversationStore) SetPendingDelete(userID string, ids []uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{DeleteCandidates: ids})
+}
+
+func (c *conversationStore) PopPendingDelete(userID string) ([]uint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || len(state.DeleteCandidates) == 0 {
+		return nil, false
+	}
+	delete(c.state, userID)
+	return state.DeleteCandidates, true
+}
+
+func (c *conversationStore) SetListPage(userID string, filter listFilter, page int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{ListPage: page, ListFilter: filter})
+}
+
+func (c *conversationStore) PopListPage(userID string) (listFilter, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || state.ListPage == 0 {
+		return listFilter{}, 0, false
+	}
+	delete(c.state, userID)
+	return state.ListFilter, state.ListPage, true
+}
+
+func (c *conversationStore) SetPendingLocation(userID string, loc sharedLocation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{Location: &loc})
+}
+
+func (c *conversationStore) PopPendingLocation(userID string) (sharedLocation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || state.Location == nil {
+		return sharedLocation{}, false
+	}
+	delete(c.state, userID)
+	return *state.Location, true
+}
+
+func (c *conversationStore) SetPendingAttachment(userID string, a pendingAttachment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{Attachment: &a})
+}
+
+func (c *conversationStore) PopPendingAttachment(userID string) (pendingAttachment, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || state.Attachment == nil {
+		return pendingAttachment{}, false
+	}
+	delete(c.state, userID)
+	return *state.Attachment, true
+}
+
+func (c *conversationStore) SetClarification(userID, message string, options []myopenai.Intent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{PendingMessage: message, Clarify: options})
+}
+
+func (c *conversationStore) PopClarification(userID string) (string, []myopenai.Intent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || len(state.Clarify) == 0 {
+		return "", nil, false
+	}
+	delete(c.state, userID)
+	return state.PendingMessage, state.Clarify, true
+}
+
+func (c *conversationStore) SetReschedule(userID string, offer rescheduleOffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{Reschedule: &offer})
+}
+
+func (c *conversationStore) PopReschedule(userID string) (rescheduleOffer, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || state.Reschedule == nil {
+		return rescheduleOffer{}, false
+	}
+	delete(c.state, userID)
+	return *state.Reschedule, true
+}
+
+func (c *conversationStore) SetRebalance(userID string, changes []priorityChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{Rebalance: changes})
+}
+
+func (c *conversationStore) PopRebalance(userID string) ([]priorityChange, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || len(state.Rebalance) == 0 {
+		return nil, false
+	}
+	delete(c.state, userID)
+	return state.Rebalance, true
+}
+
+func (c *conversationStore) SetJustSaved(userID string, saved justSaved) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(userID, conversationState{JustSaved: &saved})
+}
+
+// JustSaved returns the reminder the user saved within correctionWindow of
+// now, leaving it in place for further corrections.
+func (c *conversationStore) JustSaved(userID string, now time.Time) (justSaved, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	state, ok := c.get(userID)
+	if !ok || state.JustSaved == nil || now.Sub(state.JustSaved.At) > correctionWindow {
+		return justSaved{}, false
+	}
+	return *state.JustSaved, true
+}
+
+func (c *conversationStore) ClearJustSaved(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if state, ok := c.get(userID); ok && state.JustSaved != nil {
+		delete(c.state, userID)
+	}
+}
+
+// MissPriority records a reply to the priority question that wasn't a
+// priority and returns how many there have been.
+func (c *conversationStore) MissPriority(userID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	if !ok || !state.AwaitingPriority {
+		return 0
+	}
+	state.PriorityMisses++
+	c.set(userID, state)
+	return state.PriorityMisses
+}
+
+func (c *conversationStore) PriorityMisses(userID string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	state, _ := c.get(userID)
+	return state.PriorityMisses
+}
+
+// Peek returns userID's live state without clearing it.
+func (c *conversationStore) Peek(userID string) (conversationState, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.get(userID)
+}
+
+// Cancel drops userID's state and returns it, if any was live.
+func (c *conversationStore) Cancel(userID string) (conversationState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.get(userID)
+	delete(c.state, userID)
+	return state, ok
+}
+
 func (c *conversationStore) IsAwaitingPriority(userID string) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	state, ok := c.state[userID]
+	state, ok := c.get(userID)
 	return ok && state.AwaitingPriority
 }
 
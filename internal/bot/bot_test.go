@@ -1,17 +1,59 @@
 package bot
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/pathakanu/myMemo/internal/anthropic"
+	"github.com/pathakanu/myMemo/internal/caldav"
+	"github.com/pathakanu/myMemo/internal/channel"
 	"github.com/pathakanu/myMemo/internal/config"
+	"github.com/pathakanu/myMemo/internal/database"
+	"github.com/pathakanu/myMemo/internal/discord"
+	"github.com/pathakanu/myMemo/internal/holidays"
+	"github.com/pathakanu/myMemo/internal/i18n"
+	"github.com/pathakanu/myMemo/internal/linkfetch"
 	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/netguard"
+	"github.com/pathakanu/myMemo/internal/notion"
 	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"github.com/pathakanu/myMemo/internal/openapi"
+	"github.com/pathakanu/myMemo/internal/reminderpb"
+	"github.com/pathakanu/myMemo/internal/storage"
+	"github.com/pathakanu/myMemo/internal/twilio"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"github.com/robfig/cron/v3"
+	"github.com/twilio/twilio-go/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -26,18 +68,93 @@ func newTestBot(t *testing.T) *Bot {
 	if err != nil {
 		t.Fatalf("open sqlite memory: %v", err)
 	}
-	if err := db.AutoMigrate(&model.Reminder{}); err != nil {
+	if err := database.Migrate(db); err != nil {
 		t.Fatalf("auto migrate: %v", err)
 	}
 
-	return &Bot{
-		cfg:    &config.Config{LocalTimezone: time.UTC},
-		db:     db,
-		openAI: myopenai.New(""),
-		twilio: nil,
-		cron:   nil,
-		state:  newConversationStore(),
-		logger: log.New(io.Discard, "", 0),
+	openAI := myopenai.New("")
+	b := &Bot{
+		cfg:      &config.Config{LocalTimezone: time.UTC, ReminderGap: time.Hour},
+		db:       db,
+		openAI:   openAI,
+		llm:      openAI,
+		channels: map[string]channel.Sender{},
+		cron:     nil,
+		jobs:     make(map[cron.EntryID]string),
+		state:    newConversationStore(),
+		recent:   newExchangeLog(),
+		sends:    newSendQueue(),
+		links:    linkfetch.New(),
+		events:   newEventHub(),
+		logger:   log.New(io.Discard, "", 0),
+	}
+	b.subscribeEffects()
+	return b
+}
+
+func TestDatabaseCallsHonourContextAndStatementTimeout(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	seedReminders(t, b, []model.Reminder{{UserID: "user", Content: "Pay rent", Priority: 3}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.openReminders(ctx, "user"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled request to stop the query, got %v", err)
+	}
+	if err := b.saveReminder(ctx, "user", "Book dentist", 2, "Book dentist"); err == nil {
+		t.Fatalf("expected save to fail with a cancelled context")
+	}
+
+	if err := database.SetStatementTimeout(b.db, time.Minute); err != nil {
+		t.Fatalf("SetStatementTimeout: %v", err)
+	}
+	if msg, err := b.deleteReminder(context.Background(), "user", "rent"); err != nil {
+		t.Fatalf("expected statements within the timeout to succeed, got %q %v", msg, err)
+	}
+
+	slow := newTestBot(t)
+	if err := database.SetStatementTimeout(slow.db, time.Nanosecond); err != nil {
+		t.Fatalf("SetStatementTimeout: %v", err)
+	}
+	if _, err := slow.openReminders(context.Background(), "user"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the statement timeout to apply, got %v", err)
+	}
+}
+
+func TestSearchReminders(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "Pay rent", Summary: "Pay rent", Priority: 5},
+		{UserID: "user", Content: "Call the dentist to book appointments for the kids", Summary: "Book dentist appointments", Priority: 3},
+		{UserID: "user", Content: "Dentist bill", Summary: "Pay dentist bill", Priority: 2},
+		{UserID: "other", Content: "Dentist appointment", Summary: "Dentist appointment", Priority: 4},
+	})
+
+	msg := b.respond(context.Background(), "user", "find reminders about dentist appointment")
+	if !strings.Contains(msg, "2. R2 [3] Book dentist appointments") || strings.Contains(msg, "bill") {
+		t.Fatalf("unexpected search reply: %q", msg)
+	}
+	if msg := b.respond(context.Background(), "user", "search reminders for dentist"); !containsAll(msg, []string{"2. R2 [3]", "3. R3 [2] Pay dentist bill"}) {
+		t.Fatalf("expected both dentist reminders, got %q", msg)
+	}
+	if msg := b.respond(context.Background(), "user", "find reminders about holiday"); msg != "I couldn't find any reminders about 'holiday'." {
+		t.Fatalf("unexpected empty search reply: %q", msg)
+	}
+
+	if database.SearchMode(b.db) == database.SearchFTS5 {
+		// Stemming: "appointment" and "calling" match "appointments" and "Call".
+		reminders, err := b.searchReminders(context.Background(), "user", "calling appointment")
+		if err != nil || len(reminders) != 1 {
+			t.Fatalf("expected a stemmed match, got %v (%v)", reminders, err)
+		}
+		if err := b.db.Model(&reminders[0]).Update("summary", "Renew passport").Error; err != nil {
+			t.Fatalf("update summary: %v", err)
+		}
+		if reminders, _ := b.searchReminders(context.Background(), "user", "passport"); len(reminders) != 1 {
+			t.Fatalf("expected the index to follow updates, got %v", reminders)
+		}
 	}
 }
 
@@ -78,7 +195,7 @@ func TestDeleteReminderByIndices(t *testing.T) {
 		{UserID: "user", Content: "gamma", Priority: 1},
 	})
 
-	msg, err := b.deleteReminder("user", "1,3")
+	msg, err := b.deleteReminder(context.Background(), "user", "1,3")
 	if err != nil {
 		t.Fatalf("deleteReminder returned error: %v", err)
 	}
@@ -94,7 +211,7 @@ func TestDeleteReminderByIndices(t *testing.T) {
 		t.Fatalf("expected only \"beta\" reminder remaining, got %+v", reminders)
 	}
 
-	if _, err := b.deleteReminder("user", "4"); err == nil {
+	if _, err := b.deleteReminder(context.Background(), "user", "4"); err == nil {
 		t.Fatalf("expected error for invalid index, got nil")
 	}
 }
@@ -108,7 +225,7 @@ func TestDeleteReminderByKeyword(t *testing.T) {
 		{UserID: "user", Content: "buy milk", Priority: 2},
 	})
 
-	msg, err := b.deleteReminder("user", "rent")
+	msg, err := b.deleteReminder(context.Background(), "user", "rent")
 	if err != nil {
 		t.Fatalf("deleteReminder by keyword error: %v", err)
 	}
@@ -124,12 +241,92 @@ func TestDeleteReminderByKeyword(t *testing.T) {
 		t.Fatalf("expected one reminder remaining, got %d", count)
 	}
 
-	_, err = b.deleteReminder("user", "doctor")
+	_, err = b.deleteReminder(context.Background(), "user", "doctor")
 	if err == nil {
 		t.Fatalf("expected error when keyword not found")
 	}
 }
 
+func TestDeleteReminderAsksWhenSeveralMatch(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "buy milk", Priority: 2},
+		{UserID: "user", Content: "buy oat milk for work", Priority: 4},
+		{UserID: "user", Content: "pay rent", Priority: 3},
+	})
+
+	reply := b.respond(ctx, "user", "delete reminder about milk")
+	if !containsAll(reply, []string{"2 reminders match 'milk'", "1. [4] buy oat milk for work", "2. [2] buy milk"}) {
+		t.Fatalf("unexpected disambiguation reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "3"); !strings.Contains(reply, "between 1 and 2") {
+		t.Fatalf("expected an out-of-range answer to be rejected, got %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "2"); reply != "Deleted: buy milk." {
+		t.Fatalf("unexpected delete reply: %q", reply)
+	}
+
+	var remaining []string
+	if err := b.db.Model(&model.Reminder{}).Order("id ASC").Pluck("content", &remaining).Error; err != nil {
+		t.Fatalf("load reminders: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0] != "buy oat milk for work" {
+		t.Fatalf("unexpected remaining reminders: %v", remaining)
+	}
+
+	seedReminders(t, b, []model.Reminder{{UserID: "user", Content: "milk the cows", Priority: 1}})
+	b.respond(ctx, "user", "delete reminder about milk")
+	if reply := b.respond(ctx, "user", "cancel"); !strings.Contains(reply, "won't delete") {
+		t.Fatalf("unexpected cancel reply: %q", reply)
+	}
+	b.respond(ctx, "user", "delete reminder about milk")
+	if reply := b.respond(ctx, "user", "list my reminders"); !strings.Contains(reply, "milk the cows") {
+		t.Fatalf("expected an unrelated message to drop the question, got %q", reply)
+	}
+	if b.state.IsAwaitingPriority("user") {
+		t.Fatalf("expected no pending state after the question was dropped")
+	}
+}
+
+func TestKeywordsTolerateTypos(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "buy milk", Priority: 2},
+		{UserID: "user", Content: "water the plants", Priority: 3},
+		{UserID: "user", Content: "renew passport", Priority: 4},
+	})
+
+	if reply := b.respond(ctx, "user", "delete milk remimder"); reply != "Deleted reminders matching 'milk remimder'." {
+		t.Fatalf("unexpected delete reply: %q", reply)
+	}
+	if msg, err := b.markDone(ctx, "user", "pasport"); err != nil || !strings.Contains(msg, "renew passport") {
+		t.Fatalf("unexpected done reply: %q, %v", msg, err)
+	}
+	if _, err := b.deleteReminder(ctx, "user", "car"); err == nil {
+		t.Fatalf("expected an unrelated short keyword not to match")
+	}
+
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"milk", "mlik", 1},
+		{"reminder", "remimder", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	} {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
 func TestListRemindersFormatting(t *testing.T) {
 	t.Parallel()
 	b := newTestBot(t)
@@ -139,7 +336,7 @@ func TestListRemindersFormatting(t *testing.T) {
 		{UserID: "user", Content: "task two", Summary: "Task two", Priority: 2},
 	})
 
-	output := b.listReminders("user")
+	output := b.listReminders(context.Background(), "user")
 	if output == "" {
 		t.Fatalf("expected non-empty list output")
 	}
@@ -148,13 +345,210 @@ func TestListRemindersFormatting(t *testing.T) {
 	}
 }
 
+func TestReminderCodesAreStable(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "pay rent", Priority: 2},
+		{UserID: "user", Content: "buy milk", Priority: 3},
+		{UserID: "user", Content: "call mum", Priority: 1},
+		{UserID: "other", Content: "walk dog", Priority: 1},
+	})
+	var other model.Reminder
+	if err := b.db.Where("user_id = ?", "other").First(&other).Error; err != nil || other.Code != 1 {
+		t.Fatalf("expected codes to be numbered per user, got %d (%v)", other.Code, err)
+	}
+
+	if list := plainText(b.listReminders(ctx, "user")); !containsAll(list, []string{"1. R2 [3] buy milk", "2. R1 [2] pay rent", "3. R3 [1] call mum"}) {
+		t.Fatalf("unexpected list: %q", list)
+	}
+	if err := b.db.Model(&model.Reminder{}).Where("content = ?", "call mum").Update("priority", 5).Error; err != nil {
+		t.Fatalf("update priority: %v", err)
+	}
+	if msg, err := b.deleteReminder(ctx, "user", "R2"); err != nil || msg != "Deleted reminder(s): R2." {
+		t.Fatalf("unexpected delete reply: %q, %v", msg, err)
+	}
+	if _, err := b.deleteReminder(ctx, "user", "r1, r9"); err == nil || err.Error() != "I couldn't find reminder R9." {
+		t.Fatalf("expected an unknown code to be reported, got %v", err)
+	}
+	if msg, err := b.markDone(ctx, "user", "r3"); err != nil || !strings.Contains(msg, "call mum") {
+		t.Fatalf("unexpected done reply: %q, %v", msg, err)
+	}
+	if list := plainText(b.listReminders(ctx, "user")); !strings.Contains(list, "1. R1 [2] pay rent") {
+		t.Fatalf("expected R1 to survive the other changes, got %q", list)
+	}
+
+	seedReminders(t, b, []model.Reminder{{UserID: "user", Content: "legacy", Priority: 1}})
+	if err := b.db.Model(&model.Reminder{}).Where("content = ?", "legacy").Update("code", 0).Error; err != nil {
+		t.Fatalf("clear code: %v", err)
+	}
+	if err := database.Migrate(b.db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	var legacy model.Reminder
+	if err := b.db.Where("content = ?", "legacy").First(&legacy).Error; err != nil || legacy.Code != 4 {
+		t.Fatalf("expected the backfill to continue after R3, got %d (%v)", legacy.Code, err)
+	}
+}
+
+func TestShowReminderDetail(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	long := "Call the insurance company about the broken windscreen and ask whether the excess is waived for glass-only claims"
+	due := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "pay rent", Priority: 5},
+		{UserID: "user", Content: long, Summary: "Call insurer about windscreen", Priority: 3, DueAt: &due},
+	})
+
+	reply := b.respond(ctx, "user", "show 2")
+	if !containsAll(reply, []string{"R2 — priority 3", long, "Summary: Call insurer about windscreen", "Due: Sun Jun 1, 2025", "Saved: "}) {
+		t.Fatalf("unexpected detail: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "show R1"); !strings.HasPrefix(reply, "R1 — priority 5\npay rent\nSaved: ") {
+		t.Fatalf("unexpected detail by code: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "show 5"); reply != "Reminder 5 doesn't exist. Choose between 1 and 2." {
+		t.Fatalf("unexpected out-of-range reply: %q", reply)
+	}
+}
+
+func TestListRemindersPaginates(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	reminders := make([]model.Reminder, 23)
+	for i := range reminders {
+		reminders[i] = model.Reminder{UserID: "user", Content: fmt.Sprintf("task %02d", i+1), Priority: 3}
+	}
+	seedReminders(t, b, reminders)
+
+	first := plainText(b.respond(ctx, "user", "list my reminders"))
+	if !containsAll(first, []string{"1. R1 [3] task 01", "10. R10 [3] task 10", "…and 13 more, reply 'more' to continue."}) || strings.Contains(first, "task 11") {
+		t.Fatalf("unexpected first page: %q", first)
+	}
+	second := plainText(b.respond(ctx, "user", "more"))
+	if !containsAll(second, []string{"(page 2)", "11. R11 [3] task 11", "…and 3 more"}) || strings.Contains(second, "task 10") {
+		t.Fatalf("unexpected second page: %q", second)
+	}
+	third := plainText(b.respond(ctx, "user", "More"))
+	if !strings.Contains(third, "23. R23 [3] task 23") || strings.Contains(third, "more") {
+		t.Fatalf("unexpected last page: %q", third)
+	}
+
+	if page := plainText(b.respond(ctx, "user", "list reminders page 3")); !strings.HasPrefix(page, "Here are your reminders (page 3):\n21. ") {
+		t.Fatalf("unexpected explicit page: %q", page)
+	}
+	if page := b.respond(ctx, "user", "list reminders page 4"); page != "There's no page 4. Your reminders fit on 3 page(s)." {
+		t.Fatalf("unexpected empty page reply: %q", page)
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	now := time.Now().In(b.cfg.LocalTimezone)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "ship release #work", Priority: 5, DueAt: &yesterday},
+		{UserID: "user", Content: "book flights", Priority: 4, DueAt: &tomorrow},
+		{UserID: "user", Content: "tidy desk #work", Priority: 2, CreatedAt: now.AddDate(0, -2, 0)},
+		{UserID: "user", Content: "water plants", Priority: 1},
+	})
+
+	for _, tc := range []struct {
+		message string
+		want    []string
+		not     []string
+	}{
+		{"list high priority", []string{"(high priority)", "1. R1 [5] ship release #work", "2. R2 [4] book flights"}, []string{"tidy", "water"}},
+		{"list overdue", []string{"(overdue)", "ship release"}, []string{"book flights"}},
+		{"list #work", []string{"(#work)", "ship release", "3. R3 [2] tidy desk #work"}, []string{"water"}},
+		{"show reminders created this week", []string{"(created this week)", "water plants"}, []string{"tidy"}},
+		{"list low priority #work", []string{"(low priority, #work)", "tidy desk"}, []string{"ship"}},
+	} {
+		reply := plainText(b.respond(ctx, "user", tc.message))
+		if !containsAll(reply, tc.want) {
+			t.Errorf("%q: expected %q in %q", tc.message, tc.want, reply)
+		}
+		for _, s := range tc.not {
+			if strings.Contains(reply, s) {
+				t.Errorf("%q: did not expect %q in %q", tc.message, s, reply)
+			}
+		}
+	}
+
+	if reply := b.respond(ctx, "user", "list priority 3"); reply != "You have no reminders matching: priority 3." {
+		t.Fatalf("unexpected empty filter reply: %q", reply)
+	}
+	if _, _, ok := parseListFilter("show my shopping list", config.DefaultCategories); ok {
+		t.Fatalf("expected unknown words not to parse as a filter")
+	}
+}
+
+func TestSortPreference(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	due := time.Now().AddDate(0, 0, 3)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "oldest", Priority: 1},
+		{UserID: "user", Content: "urgent", Priority: 5},
+		{UserID: "user", Content: "due soon", Priority: 2, DueAt: &due},
+	})
+	contents := func() []string {
+		reminders, err := b.openReminders(ctx, "user")
+		if err != nil {
+			t.Fatalf("openReminders: %v", err)
+		}
+		out := make([]string, len(reminders))
+		for i, r := range reminders {
+			out[i] = r.Content
+		}
+		return out
+	}
+
+	if got := contents(); !reflect.DeepEqual(got, []string{"urgent", "due soon", "oldest"}) {
+		t.Fatalf("unexpected default order: %v", got)
+	}
+	if reply := b.respond(ctx, "user", "Sort by date"); !strings.Contains(reply, "oldest first") {
+		t.Fatalf("unexpected sort reply: %q", reply)
+	}
+	if got := contents(); !reflect.DeepEqual(got, []string{"oldest", "urgent", "due soon"}) {
+		t.Fatalf("unexpected date order: %v", got)
+	}
+	if list := plainText(b.listReminders(ctx, "user")); !strings.Contains(list, "1. R1 [1] oldest") {
+		t.Fatalf("expected the list to follow the preference: %q", list)
+	}
+	b.respond(ctx, "user", "sort my reminders by due date")
+	if got := contents(); !reflect.DeepEqual(got, []string{"due soon", "urgent", "oldest"}) {
+		t.Fatalf("unexpected due order: %v", got)
+	}
+
+	b.dispatchUserReminders("user")
+	pending := b.sends.Snapshot()
+	b.sends.Drain(ctx)
+	// The first send may already have fired; the last one goes out two gaps later.
+	if len(pending) < 2 || !strings.Contains(pending[len(pending)-1].Body, "oldest") || !strings.Contains(pending[len(pending)-2].Body, "urgent") {
+		t.Fatalf("expected the dispatch to follow the preference, got %+v", pending)
+	}
+}
+
 func TestSummarizeReminderFallback(t *testing.T) {
 	t.Parallel()
 	client := myopenai.New("")
 	ctx := context.Background()
 	content := "Lorem ipsum dolor sit amet, consectetur adipiscing elit. Sed varius aliquet felis."
 
-	summary, err := client.SummarizeReminder(ctx, content)
+	summary, err := client.SummarizeReminder(ctx, content, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -163,24 +557,4447 @@ func TestSummarizeReminderFallback(t *testing.T) {
 	}
 }
 
-// seedReminders inserts reminders and updates CreatedAt to ensure ordering.
-func seedReminders(t *testing.T, b *Bot, reminders []model.Reminder) {
-	t.Helper()
-	for i := range reminders {
-		if reminders[i].CreatedAt.IsZero() {
-			reminders[i].CreatedAt = time.Now().Add(time.Duration(i) * time.Minute)
+func TestStopSchedulerPersistsPendingSends(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cron = cron.New()
+
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "beta", Priority: 3},
+	})
+	var reminder model.Reminder
+	if err := b.db.First(&reminder).Error; err != nil {
+		t.Fatalf("fetch reminder: %v", err)
+	}
+
+	b.sends.Schedule(&pendingSend{UserID: "user", ReminderID: reminder.ID, Body: "beta", SendAt: time.Now().Add(time.Hour)}, b.deliver)
+	b.StopScheduler(context.Background())
+
+	var rows []model.OutboxMessage
+	if err := b.db.Find(&rows).Error; err != nil {
+		t.Fatalf("fetch outbox: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Body != "beta" {
+		t.Fatalf("expected pending \"beta\" send in outbox, got %+v", rows)
+	}
+	if b.sends.Schedule(&pendingSend{SendAt: time.Now()}, b.deliver) {
+		t.Fatalf("expected drained queue to reject new sends")
+	}
+
+	b.sends = newSendQueue()
+	b.restoreOutbox()
+	if got := b.sends.Len(); got != 1 {
+		t.Fatalf("expected 1 restored send, got %d", got)
+	}
+	b.sends.Drain(context.Background())
+}
+
+func TestParseGapRequest(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]time.Duration{
+		"send my reminders 10 minutes apart": 10 * time.Minute,
+		"send reminders 2 hours apart":       2 * time.Hour,
+		"send my reminders 0 min apart":      0,
+		"send my reminders all at once":      0,
+	}
+	for input, want := range cases {
+		got, ok := parseGapRequest(input)
+		if !ok || got != want {
+			t.Fatalf("parseGapRequest(%q) = %v, %v; want %v, true", input, got, ok, want)
 		}
-		if err := b.db.Create(&reminders[i]).Error; err != nil {
-			t.Fatalf("seed reminder %d: %v", i, err)
+	}
+	if _, ok := parseGapRequest("send flowers to mum"); ok {
+		t.Fatalf("expected unrelated message not to parse as a gap request")
+	}
+}
+
+func TestReminderGapPreference(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	if got := b.reminderGap("user"); got != time.Hour {
+		t.Fatalf("expected global default gap, got %v", got)
+	}
+	if _, err := b.setReminderGap("user", 0); err != nil {
+		t.Fatalf("setReminderGap: %v", err)
+	}
+	if got := b.reminderGap("user"); got != 0 {
+		t.Fatalf("expected zero gap after update, got %v", got)
+	}
+	if _, err := b.setReminderGap("user", 15*time.Minute); err != nil {
+		t.Fatalf("setReminderGap: %v", err)
+	}
+	if got := b.reminderGap("user"); got != 15*time.Minute {
+		t.Fatalf("expected 15m gap after update, got %v", got)
+	}
+}
+
+func TestUpdatePreferencesWritesOnlyChangedColumns(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	optedOut := time.Now().Add(-time.Hour)
+	if err := b.db.Create(&model.UserPreference{UserID: "user", OptedOutAt: &optedOut, Email: "me@example.com", Timezone: "Europe/Berlin"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.setLanguage("user", "spanish"); err != nil {
+		t.Fatalf("setLanguage: %v", err)
+	}
+	pref := b.preferences("user")
+	if pref.Language != "es" || pref.OptedOutAt == nil || pref.Email != "me@example.com" || pref.Timezone != "Europe/Berlin" {
+		t.Fatalf("expected only the language to change, got %+v", pref)
+	}
+
+	// A failed load must not be saved as an empty record.
+	if err := b.db.Callback().Query().Before("gorm:query").Register("test:fail_preferences", func(db *gorm.DB) {
+		if db.Statement.Table == "user_preferences" {
+			db.AddError(errors.New("connection reset"))
 		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.setPaused("user", true); err == nil {
+		t.Fatal("expected setPaused to fail when preferences can't be loaded")
+	}
+	b.db.Callback().Query().Remove("test:fail_preferences")
+	if pref := b.preferences("user"); pref.OptedOutAt == nil || pref.Paused || pref.Language != "es" {
+		t.Fatalf("expected the stored preferences to be left alone, got %+v", pref)
 	}
 }
 
-func containsAll(haystack string, needles []string) bool {
-	for _, needle := range needles {
-		if needle != "" && !strings.Contains(haystack, needle) {
-			return false
+func TestTemplateLifecycle(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	body := "save template Gym: Go to the gym, priority 4, weekdays 6pm"
+	if _, ok := b.handleTemplateCommand("user", body, strings.ToLower(body)); !ok {
+		t.Fatalf("expected save template command to be handled")
+	}
+
+	tmpl, ok := b.findTemplate("user", "gym")
+	if !ok {
+		t.Fatalf("expected template 'gym' to be found")
+	}
+	if tmpl.Priority != 4 || tmpl.Content != "Go to the gym, priority 4, weekdays 6pm" {
+		t.Fatalf("unexpected template: %+v", tmpl)
+	}
+	if list := b.listTemplates("user"); !strings.Contains(list, "gym →") {
+		t.Fatalf("unexpected template list: %q", list)
+	}
+
+	if _, err := b.saveTemplate("user", "help", "anything"); err == nil {
+		t.Fatalf("expected reserved template name to be rejected")
+	}
+	if _, err := b.deleteTemplate("user", "gym"); err != nil {
+		t.Fatalf("deleteTemplate: %v", err)
+	}
+	if _, ok := b.findTemplate("user", "gym"); ok {
+		t.Fatalf("expected template to be gone after delete")
+	}
+}
+
+func TestApplyCheckInStreak(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2024, time.March, 10, 9, 0, 0, 0, time.UTC)
+	habit := model.Reminder{Kind: model.KindHabit}
+
+	if !applyCheckIn(&habit, day) || habit.Streak != 1 {
+		t.Fatalf("expected first check-in to start a streak, got %d", habit.Streak)
+	}
+	if applyCheckIn(&habit, day.Add(2*time.Hour)) {
+		t.Fatalf("expected second check-in on the same day to be rejected")
+	}
+	if !applyCheckIn(&habit, day.AddDate(0, 0, 1)) || habit.Streak != 2 {
+		t.Fatalf("expected consecutive day to extend streak to 2, got %d", habit.Streak)
+	}
+	if got := streakMessage(habit, day.AddDate(0, 0, 2)); got != "Day 2 streak!" {
+		t.Fatalf("unexpected streak message: %q", got)
+	}
+	if got := currentStreak(habit, day.AddDate(0, 0, 3)); got != 0 {
+		t.Fatalf("expected streak to lapse after a missed day, got %d", got)
+	}
+	if !applyCheckIn(&habit, day.AddDate(0, 0, 3)) || habit.Streak != 1 {
+		t.Fatalf("expected check-in after a gap to restart the streak, got %d", habit.Streak)
+	}
+}
+
+func TestMarkDone(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "meditate", Priority: 5, Kind: model.KindHabit},
+		{UserID: "user", Content: "pay rent", Priority: 3},
+	})
+
+	msg, err := b.markDone(context.Background(), "user", "")
+	if err != nil {
+		t.Fatalf("markDone habit: %v", err)
+	}
+	if !strings.Contains(msg, "Day 1 streak") {
+		t.Fatalf("unexpected habit check-in reply: %q", msg)
+	}
+
+	if _, err := b.markDone(context.Background(), "user", "rent"); err != nil {
+		t.Fatalf("markDone reminder: %v", err)
+	}
+	reminders, err := b.openReminders(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("openReminders: %v", err)
+	}
+	if len(reminders) != 1 || !reminders[0].IsHabit() || reminders[0].Streak != 1 {
+		t.Fatalf("expected only the habit to remain open, got %+v", reminders)
+	}
+}
+
+func TestNamedLists(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	for _, body := range []string{
+		"add War and Peace to my books list",
+		"Add Dune to my Books list",
+		"add eggs to the shopping list",
+	} {
+		if _, ok := b.handleListCommand("user", body, strings.ToLower(body)); !ok {
+			t.Fatalf("expected %q to be handled as a list command", body)
 		}
 	}
-	return true
+
+	if _, err := b.setListFormat("user", "books", model.ListFormatNumbered); err != nil {
+		t.Fatalf("setListFormat: %v", err)
+	}
+	out, err := b.showList("user", "books")
+	if err != nil {
+		t.Fatalf("showList: %v", err)
+	}
+	if !containsAll(out, []string{"Your books list", "1. War and Peace", "2. Dune"}) {
+		t.Fatalf("unexpected list output: %q", out)
+	}
+
+	if _, err := b.removeListItem("user", "books", "1"); err != nil {
+		t.Fatalf("removeListItem: %v", err)
+	}
+	if out, _ := b.showList("user", "books"); strings.Contains(out, "War and Peace") {
+		t.Fatalf("expected item to be removed, got %q", out)
+	}
+
+	if _, err := b.deleteList("user", "shopping"); err != nil {
+		t.Fatalf("deleteList: %v", err)
+	}
+	if _, err := b.showList("user", "shopping"); !isUserError(err) {
+		t.Fatalf("expected missing list error, got %v", err)
+	}
+}
+
+func TestMemoCaptureAndSearch(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	for _, body := range []string{
+		"note: the wifi password at the cabin is on the fridge",
+		"Note: gift ideas for Asha — a watercolour set",
+	} {
+		if _, ok := b.handleMemoCommand(ctx, "user", body, strings.ToLower(body)); !ok {
+			t.Fatalf("expected %q to be handled as a memo command", body)
+		}
+	}
+
+	out, err := b.listMemos("user", "wifi")
+	if err != nil {
+		t.Fatalf("listMemos search: %v", err)
+	}
+	if !strings.Contains(out, "cabin") || strings.Contains(out, "watercolour") {
+		t.Fatalf("unexpected search output: %q", out)
+	}
+
+	if _, err := b.deleteMemos("user", "1"); err != nil {
+		t.Fatalf("deleteMemos: %v", err)
+	}
+	memos, err := b.memos("user", model.MemoKindNote, "")
+	if err != nil {
+		t.Fatalf("memos: %v", err)
+	}
+	if len(memos) != 1 {
+		t.Fatalf("expected one memo to remain, got %d", len(memos))
+	}
+
+	var reminders int64
+	b.db.Model(&model.Reminder{}).Count(&reminders)
+	if reminders != 0 {
+		t.Fatalf("expected memos not to create reminders, found %d", reminders)
+	}
+}
+
+func TestSaveLink(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>Deep Work &amp; Focus</title><style>p{}</style></head>
+<body><script>track()</script><p>Focus is a skill you can train.</p></body></html>`)
+	}))
+	defer srv.Close()
+	b.links = linkfetch.NewWithClient(srv.Client())
+
+	msg, ok := b.handleLinkCommand(context.Background(), "user", srv.URL, strings.ToLower(srv.URL))
+	if !ok {
+		t.Fatalf("expected bare URL to be handled as a link")
+	}
+	if !containsAll(msg, []string{"Deep Work & Focus", "Focus is a skill"}) || strings.Contains(msg, "track()") {
+		t.Fatalf("unexpected save reply: %q", msg)
+	}
+
+	links, err := b.memos("user", model.MemoKindLink, "")
+	if err != nil {
+		t.Fatalf("memos: %v", err)
+	}
+	if len(links) != 1 || links[0].URL == "" {
+		t.Fatalf("expected one saved link, got %+v", links)
+	}
+	if notes, _ := b.memos("user", model.MemoKindNote, ""); len(notes) != 0 {
+		t.Fatalf("expected links to stay out of notes, got %+v", notes)
+	}
+}
+
+func TestLinkFetchRefusesPrivateAddresses(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<title>internal</title>")
+	}))
+	defer srv.Close()
+
+	_, err := linkfetch.New().Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, netguard.ErrPrivateAddress) {
+		t.Fatalf("expected loopback fetch to be refused, got %v", err)
+	}
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "::1", "fd00::1", "fe80::1", "::ffff:10.0.0.1", "0.0.0.0"} {
+		if netguard.IsPublic(netip.MustParseAddr(ip)) {
+			t.Errorf("expected %s not to be public", ip)
+		}
+	}
+	for _, ip := range []string{"93.184.216.34", "1.1.1.1", "2606:4700:4700::1111"} {
+		if !netguard.IsPublic(netip.MustParseAddr(ip)) {
+			t.Errorf("expected %s to be public", ip)
+		}
+	}
+}
+
+func TestReloadReschedulesDispatchAndUpdatesGap(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cron = cron.New(cron.WithLocation(time.UTC))
+	if err := b.addDispatchJobs(b.dispatchSpec()); err != nil {
+		t.Fatalf("addDispatchJobs: %v", err)
+	}
+	if err := b.addJob("notion-sync", notionSyncSpec, func() {}); err != nil {
+		t.Fatalf("addJob: %v", err)
+	}
+	b.cron.Start()
+	defer b.cron.Stop()
+
+	if err := b.Reload(&config.Config{DispatchSchedule: "every day", ReminderGap: time.Minute}); err == nil {
+		t.Fatalf("expected an invalid schedule to be rejected")
+	}
+	if b.reminderGap("user") != time.Hour {
+		t.Fatalf("expected a rejected reload to leave settings alone")
+	}
+
+	if err := b.Reload(&config.Config{DispatchSchedule: "30 7 * * *", ReminderGap: 15 * time.Minute}); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if b.reminderGap("user") != 15*time.Minute {
+		t.Fatalf("expected the default gap to change, got %v", b.reminderGap("user"))
+	}
+	names := map[string]int{}
+	for _, job := range b.SchedulerStatus().Jobs {
+		names[job.Name]++
+		if job.Name != "notion-sync" && (job.NextRun == nil || job.NextRun.Hour() != 7 || job.NextRun.Minute() != 30) {
+			t.Fatalf("expected %s to move to 07:30, got %v", job.Name, job.NextRun)
+		}
+	}
+	if names["daily-reminders"] != 1 || names["daily-articles"] != 1 || names["notion-sync"] != 1 {
+		t.Fatalf("unexpected jobs after reload: %v", names)
+	}
+}
+
+func TestAdminSchedulerHandler(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+	b.cron = cron.New()
+	if err := b.addJob("daily-reminders", dailyDispatchSpec, func() {}); err != nil {
+		t.Fatalf("addJob: %v", err)
+	}
+	b.cron.Start()
+	defer b.cron.Stop()
+
+	b.sends.Schedule(&pendingSend{UserID: "user", ReminderID: 7, SendAt: time.Now().Add(time.Hour)}, func(*pendingSend) {})
+	defer b.sends.Drain(context.Background())
+	if msg, ok := b.handleSettingsCommand("user", "pause reminders"); !ok || !strings.Contains(msg, "paused") {
+		t.Fatalf("unexpected pause reply: %q", msg)
+	}
+
+	handler := b.AdminSchedulerHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with token, got %d", rec.Code)
+	}
+
+	var status SchedulerStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if len(status.Jobs) != 1 || status.Jobs[0].Name != "daily-reminders" || status.Jobs[0].NextRun == nil {
+		t.Fatalf("unexpected jobs: %+v", status.Jobs)
+	}
+	if len(status.PendingSends) != 1 || status.PendingSends[0].ReminderID != 7 {
+		t.Fatalf("unexpected pending sends: %+v", status.PendingSends)
+	}
+	if len(status.PausedUsers) != 1 || status.PausedUsers[0] != "user" {
+		t.Fatalf("unexpected paused users: %+v", status.PausedUsers)
+	}
+}
+
+func TestAdminUsersRemindersAndExport(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+	seedReminders(t, b, []model.Reminder{{UserID: "alice", Content: "Pay rent", Summary: "Pay rent", Priority: 5}})
+	if err := b.db.Create(&model.Memo{UserID: "alice", Kind: model.MemoKindNote, Title: "Wifi", Content: "The wifi password is on the fridge"}).Error; err != nil {
+		t.Fatalf("create memo: %v", err)
+	}
+	if msg, ok := b.handleSettingsCommand("bob", "pause reminders"); !ok || !strings.Contains(msg, "paused") {
+		t.Fatalf("unexpected pause reply: %q", msg)
+	}
+
+	call := func(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(b.AdminRemindersHandler(), http.MethodPost, "/admin/reminders", `{"user_id":"alice","content":"Book dentist","priority":4}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add reminder: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(b.AdminRemindersHandler(), http.MethodPost, "/admin/reminders", `{"content":"No user"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without user_id, got %d", rec.Code)
+	}
+
+	rec = call(b.AdminUsersHandler(), http.MethodGet, "/admin/users", "")
+	var users struct {
+		Users []UserSummary `json:"users"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&users); err != nil {
+		t.Fatalf("decode users: %v", err)
+	}
+	want := []UserSummary{
+		{UserID: "alice", OpenReminders: 2, Delivery: model.DeliveryWhatsApp},
+		{UserID: "bob", Paused: true, Delivery: model.DeliveryWhatsApp},
+	}
+	if !reflect.DeepEqual(users.Users, want) {
+		t.Fatalf("unexpected users: %+v", users.Users)
+	}
+
+	rec = call(b.AdminExportHandler(), http.MethodGet, "/admin/export?user_id=alice", "")
+	var export UserExport
+	if err := json.NewDecoder(rec.Body).Decode(&export); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if len(export.Reminders) != 2 || len(export.Memos) != 1 || export.Memos[0].Title != "Wifi" {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+}
+
+func TestRecoverMissedJobs(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cron = cron.New(cron.WithLocation(time.UTC))
+
+	ran := make(chan struct{}, 2)
+	if err := b.addJob("daily-reminders", "0 0 * * *", func() { ran <- struct{}{} }); err != nil {
+		t.Fatalf("addJob: %v", err)
+	}
+
+	now := time.Date(2024, time.May, 2, 9, 30, 0, 0, time.UTC)
+
+	// No history: baseline only.
+	b.recoverMissedJobs(now)
+	var run model.JobRun
+	if err := b.db.Where("name = ?", "daily-reminders").First(&run).Error; err != nil {
+		t.Fatalf("expected baseline run to be recorded: %v", err)
+	}
+
+	// Last run yesterday: today's midnight run was missed.
+	b.recordJobRun("daily-reminders", now.AddDate(0, 0, -1))
+	b.recoverMissedJobs(now)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatalf("expected missed job to run")
+	}
+	if _, err := b.sends.Drain(context.Background()); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("expected a single catch-up run")
+	}
+}
+
+func TestLastOccurrenceToday(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := cron.ParseStandard("56 12 * * *")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	morning := time.Date(2024, time.May, 2, 9, 0, 0, 0, time.UTC)
+	if got := lastOccurrenceToday(schedule, morning); !got.IsZero() {
+		t.Fatalf("expected no occurrence before 12:56, got %v", got)
+	}
+	evening := time.Date(2024, time.May, 2, 18, 0, 0, 0, time.UTC)
+	want := time.Date(2024, time.May, 2, 12, 56, 0, 0, time.UTC)
+	if got := lastOccurrenceToday(schedule, evening); !got.Equal(want) {
+		t.Fatalf("lastOccurrenceToday = %v, want %v", got, want)
+	}
+}
+
+func TestWebhookDeliveredOnCreate(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	type delivery struct {
+		event     webhook.Event
+		signature string
+		body      []byte
+	}
+	received := make(chan delivery, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event webhook.Event
+		json.Unmarshal(body, &event)
+		received <- delivery{event: event, signature: r.Header.Get(webhook.SignatureHeader), body: body}
+	}))
+	defer srv.Close()
+	// The endpoint has a public-looking name; the test client dials the
+	// local server for it, since the default client refuses loopback.
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	b.hooks = webhook.NewWithClient(&http.Client{Transport: transport})
+
+	if msg, _ := b.handleWebhookCommand("user", "add webhook "+srv.URL, ""); !strings.Contains(msg, "public addresses") {
+		t.Fatalf("expected loopback webhook to be refused, got %q", msg)
+	}
+	if err := webhook.New().Deliver(context.Background(), srv.URL, "secret", webhook.NewEvent(webhook.EventReminderCreated, "user", webhook.Reminder{})); !errors.Is(err, netguard.ErrPrivateAddress) {
+		t.Fatalf("expected delivery to loopback to be refused, got %v", err)
+	}
+
+	msg, ok := b.handleWebhookCommand("user", "add webhook http://hooks.example.com/mymemo for reminder.created", "")
+	if !ok || !strings.Contains(msg, "whsec_") {
+		t.Fatalf("unexpected add webhook reply: %q", msg)
+	}
+	hooks, err := b.webhooks("user")
+	if err != nil || len(hooks) != 1 {
+		t.Fatalf("expected one webhook, got %v (%v)", hooks, err)
+	}
+
+	if err := b.saveReminder(context.Background(), "user", "pay rent", 4, "Pay rent"); err != nil {
+		t.Fatalf("saveReminder: %v", err)
+	}
+
+	select {
+	case d := <-received:
+		if d.event.Type != webhook.EventReminderCreated || d.event.Reminder.Content != "pay rent" {
+			t.Fatalf("unexpected event: %+v", d.event)
+		}
+		if want := "sha256=" + webhook.Sign(hooks[0].Secret, d.body); d.signature != want {
+			t.Fatalf("signature = %q, want %q", d.signature, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected webhook delivery")
+	}
+
+	if _, err := b.deleteReminder(context.Background(), "user", "rent"); err != nil {
+		t.Fatalf("deleteReminder: %v", err)
+	}
+	b.sends.Drain(context.Background())
+	if len(received) != 0 {
+		t.Fatalf("expected webhook filtered to reminder.created only")
+	}
+}
+
+func TestNotionSync(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.notion = notion.New()
+
+	const databaseID = "0123456789abcdef0123456789abcdef"
+	var (
+		mu      sync.Mutex
+		created []map[string]notion.PropertyValue
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"object":"error","status":401,"code":"unauthorized","message":"API token is invalid."}`)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/databases/"):
+			fmt.Fprint(w, `{"id":"db","title":[{"plain_text":"Tasks"}],"properties":{
+				"Name":{"type":"title"},
+				"Priority":{"type":"number"},
+				"Due":{"type":"date"},
+				"Status":{"type":"status","status":{"options":[{"name":"Not started"},{"name":"In progress"},{"name":"Done"}]}}}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/pages":
+			var req struct {
+				Properties map[string]notion.PropertyValue `json:"properties"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			created = append(created, req.Properties)
+			mu.Unlock()
+			fmt.Fprint(w, `{"id":"page-pushed"}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/query"):
+			fmt.Fprint(w, `{"results":[
+				{"id":"page-new","properties":{"Name":{"title":[{"plain_text":"Renew passport"}]},"Priority":{"number":5},"Due":{"date":{"start":"2030-03-01"}},"Status":{"status":{"name":"Not started"}}}},
+				{"id":"page-pushed","properties":{"Name":{"title":[{"plain_text":"Pay rent"}]},"Priority":{"number":4},"Status":{"status":{"name":"Done"}}}},
+				{"id":"page-finished","properties":{"Name":{"title":[{"plain_text":"Old task"}]},"Status":{"status":{"name":"Done"}}}}
+			],"has_more":false}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	b.notion.BaseURL = srv.URL
+
+	msg, ok := b.handleNotionCommand(context.Background(), "user", "connect notion wrong_token "+databaseID, "")
+	if !ok || !strings.Contains(msg, "rejected") {
+		t.Fatalf("expected access error, got %q", msg)
+	}
+
+	msg, _ = b.handleNotionCommand(context.Background(), "user", "connect notion secret_token https://www.notion.so/team/Tasks-"+databaseID+"?v=abc", "")
+	if !containsAll(msg, []string{"Tasks", "Title → Name", "Priority → Priority", "Due date → Due", "Status → Status (Not started / Done)"}) {
+		t.Fatalf("unexpected connect reply: %q", msg)
+	}
+
+	if err := b.saveReminder(context.Background(), "user", "pay rent", 4, "Pay rent"); err != nil {
+		t.Fatalf("saveReminder: %v", err)
+	}
+	b.sends.Drain(context.Background())
+
+	if len(created) != 1 || notion.PlainText(created[0]["Name"].Title) != "Pay rent" || *created[0]["Priority"].Number != 4 || created[0]["Status"].Status.Name != "Not started" {
+		t.Fatalf("unexpected pushed page: %+v", created)
+	}
+
+	msg, _ = b.handleNotionCommand(context.Background(), "user", "sync notion", "sync notion")
+	if msg != "Synced with Notion: 1 new reminder(s), 1 marked done." {
+		t.Fatalf("unexpected sync reply: %q", msg)
+	}
+
+	open, err := b.openReminders(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("openReminders: %v", err)
+	}
+	if len(open) != 1 || open[0].Content != "Renew passport" || open[0].Priority != 5 || open[0].DueAt == nil || open[0].DueAt.Format("2006-01-02") != "2030-03-01" {
+		t.Fatalf("unexpected reminders after sync: %+v", open)
+	}
+}
+
+func TestParseDatabaseID(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"0123456789abcdef0123456789abcdef":                                      "01234567-89ab-cdef-0123-456789abcdef",
+		"https://www.notion.so/team/Tasks-0123456789abcdef0123456789abcdef?v=1": "01234567-89ab-cdef-0123-456789abcdef",
+		"01234567-89AB-cdef-0123-456789abcdef":                                  "01234567-89ab-cdef-0123-456789abcdef",
+	}
+	for input, want := range cases {
+		got, ok := notion.ParseDatabaseID(input)
+		if !ok || got != want {
+			t.Errorf("ParseDatabaseID(%q) = %q, %v; want %q", input, got, ok, want)
+		}
+	}
+	if _, ok := notion.ParseDatabaseID("not a database"); ok {
+		t.Errorf("expected invalid input to be rejected")
+	}
+}
+
+// recordingSender captures messages instead of delivering them.
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []channel.Message
+}
+
+func (s *recordingSender) Send(_ context.Context, msg channel.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func TestEmailDigestDelivery(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "water plants", Priority: 2},
+		{UserID: "user", Content: "pay rent", Priority: 5},
+	})
+
+	if msg, _ := b.handleEmailCommand("user", "send my reminders by email", "send my reminders by email"); !strings.Contains(msg, "isn't set up") {
+		t.Fatalf("expected unconfigured email error, got %q", msg)
+	}
+
+	email := &recordingSender{}
+	b.channels[channel.Email] = email
+	if msg, _ := b.handleEmailCommand("user", "send my reminders by email", "send my reminders by email"); !strings.Contains(msg, "address first") {
+		t.Fatalf("expected missing address error, got %q", msg)
+	}
+	if msg, _ := b.handleEmailCommand("user", "My email is Me@Example.com", "my email is me@example.com"); !strings.Contains(msg, "Me@Example.com") {
+		t.Fatalf("unexpected set email reply: %q", msg)
+	}
+	if _, ok := b.handleEmailCommand("user", "send my reminders by email", "send my reminders by email"); !ok {
+		t.Fatalf("expected delivery command to be handled")
+	}
+
+	b.dispatchUserReminders("user")
+	if len(email.messages) != 1 {
+		t.Fatalf("expected one digest email, got %d", len(email.messages))
+	}
+	digest := email.messages[0]
+	if digest.To != "Me@Example.com" || !strings.HasPrefix(digest.Subject, "Your reminders for") {
+		t.Fatalf("unexpected digest envelope: %+v", digest)
+	}
+	if !strings.Contains(digest.Body, "1. [5]") {
+		t.Fatalf("expected highest priority first in digest: %q", digest.Body)
+	}
+	if b.sends.Len() != 0 {
+		t.Fatalf("expected no WhatsApp sends for email-only delivery, got %d", b.sends.Len())
+	}
+
+	b.handleEmailCommand("user", "send my reminders by email and whatsapp", "send my reminders by email and whatsapp")
+	b.dispatchUserReminders("user")
+	if len(email.messages) != 2 || b.sends.Len() == 0 {
+		t.Fatalf("expected digest and WhatsApp sends, got %d emails and %d sends", len(email.messages), b.sends.Len())
+	}
+	b.sends.Drain(context.Background())
+}
+
+func TestStopOptsOutOfScheduledSends(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "Pay rent", Priority: 3},
+		{UserID: "user", Content: "Call mum", Priority: 2},
+	})
+	defer b.sends.Drain(context.Background())
+
+	if reply := b.respond(context.Background(), "user", "STOP"); !strings.Contains(reply, "unsubscribed") {
+		t.Fatalf("unexpected STOP reply: %q", reply)
+	}
+	b.dispatchUserReminders("user")
+	if b.sends.Len() != 0 {
+		t.Fatalf("expected no sends scheduled after STOP, got %d", b.sends.Len())
+	}
+	b.deliver(&pendingSend{UserID: "user", Body: "queued before STOP"})
+	if len(whatsapp.messages) != 0 {
+		t.Fatalf("expected sends queued before STOP to be dropped, got %+v", whatsapp.messages)
+	}
+	if reply := b.respond(context.Background(), "user", "list my reminders"); !strings.Contains(reply, "Pay rent") {
+		t.Fatalf("expected opted-out users to still get replies, got %q", reply)
+	}
+
+	if reply := b.respond(context.Background(), "user", "start"); !strings.Contains(reply, "subscribed again") {
+		t.Fatalf("unexpected START reply: %q", reply)
+	}
+	b.dispatchUserReminders("user")
+	if b.sends.Len() == 0 {
+		t.Fatalf("expected sends to be scheduled after START")
+	}
+}
+
+func TestInboundEmailCreatesReminder(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.InboundEmailToken = "inbound-secret"
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	if err := b.updatePreferences("15551234567", func(p *model.UserPreference) { p.Email = "Traveller@Example.com" }); err != nil {
+		t.Fatalf("updatePreferences: %v", err)
+	}
+
+	post := func(token, from string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		form.WriteField("from", "Jo Traveller <"+from+">")
+		form.WriteField("subject", "Fwd: Your flight to Lisbon on May 3")
+		form.WriteField("text", "Flight TP123 departs 09:40.")
+		form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/email/inbound?token="+token, &buf)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		b.InboundEmailHandler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("wrong", "traveller@example.com"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad token, got %d", rec.Code)
+	}
+	if rec := post("inbound-secret", "stranger@example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for an unknown sender, got %d", rec.Code)
+	}
+	if rec := post("inbound-secret", "traveller@example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	reminders, err := b.openReminders(context.Background(), "15551234567")
+	if err != nil {
+		t.Fatalf("openReminders: %v", err)
+	}
+	if len(reminders) != 1 || !containsAll(reminders[0].Content, []string{"Your flight to Lisbon on May 3", "TP123"}) || strings.Contains(reminders[0].Content, "Fwd") {
+		t.Fatalf("unexpected reminders: %+v", reminders)
+	}
+	if len(whatsapp.messages) != 1 || !strings.Contains(whatsapp.messages[0].Body, "Added from your email") {
+		t.Fatalf("expected WhatsApp confirmation, got %+v", whatsapp.messages)
+	}
+}
+
+func TestVerifyMailgunSignature(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+	timestamp := "1700000000"
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte(timestamp + "tok"))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	if !verifyMailgunSignature("key", timestamp, "tok", signature, now) {
+		t.Fatalf("expected valid signature")
+	}
+	if verifyMailgunSignature("other", timestamp, "tok", signature, now) {
+		t.Fatalf("expected signature with the wrong key to fail")
+	}
+	if verifyMailgunSignature("key", timestamp, "tok", signature, now.Add(time.Hour)) {
+		t.Fatalf("expected stale timestamp to fail")
+	}
+}
+
+func TestDiscordInteractions(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	edits := make(chan string, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch && strings.HasSuffix(r.URL.Path, "/webhooks/app/interaction-token/messages/@original") {
+			var body struct {
+				Content string `json:"content"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			edits <- body.Content
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+	b.discord = discord.New("bot-token")
+	b.discord.BaseURL = api.URL
+	b.discordKey = pub
+
+	post := func(payload string, sign bool) *httptest.ResponseRecorder {
+		timestamp := "1700000000"
+		signature := hex.EncodeToString(ed25519.Sign(priv, []byte(timestamp+payload)))
+		if !sign {
+			signature = strings.Repeat("0", 128)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/discord/interactions", strings.NewReader(payload))
+		req.Header.Set("X-Signature-Ed25519", signature)
+		req.Header.Set("X-Signature-Timestamp", timestamp)
+		rec := httptest.NewRecorder()
+		b.DiscordInteractionsHandler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(`{"type":1}`, false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad signature, got %d", rec.Code)
+	}
+	if rec := post(`{"type":1}`, true); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"type":1`) {
+		t.Fatalf("expected pong, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec := post(`{"type":2,"application_id":"app","token":"interaction-token","guild_id":"g1","member":{"user":{"id":"42"}},"data":{"name":"memo","options":[{"name":"message","type":3,"value":"list reminders"}]}}`, true)
+	var resp discord.InteractionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Type != discord.ResponseDeferredChannelMessage || resp.Data.Flags != discord.MessageFlagEphemeral {
+		t.Fatalf("expected ephemeral deferred response, got %+v", resp)
+	}
+	select {
+	case content := <-edits:
+		if !strings.Contains(content, "no reminders") {
+			t.Fatalf("unexpected reply: %q", content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the deferred response to be edited")
+	}
+}
+
+func TestNotifyRoutesByUser(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	whatsapp, discordDMs := &recordingSender{}, &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	b.channels[channel.Discord] = discordDMs
+
+	if err := b.notify(context.Background(), "15551234567", "hello"); err != nil {
+		t.Fatalf("notify whatsapp: %v", err)
+	}
+	if err := b.notify(context.Background(), "discord:42", "hello"); err != nil {
+		t.Fatalf("notify discord: %v", err)
+	}
+	if len(whatsapp.messages) != 1 || whatsapp.messages[0].To != "15551234567" {
+		t.Fatalf("unexpected WhatsApp messages: %+v", whatsapp.messages)
+	}
+	if len(discordDMs.messages) != 1 || discordDMs.messages[0].To != "42" {
+		t.Fatalf("unexpected Discord messages: %+v", discordDMs.messages)
+	}
+}
+
+// seedReminders inserts reminders and updates CreatedAt to ensure ordering.
+func TestDashboardLoginAndAPI(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.PublicBaseURL = "https://memo.example.com"
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "+15551234567", Content: "Pay rent", Summary: "Pay rent", Priority: 5},
+		{UserID: "+15550000000", Content: "Someone else's", Summary: "Someone else's", Priority: 3},
+	})
+
+	msg, ok := b.handleDashboardCommand("+15551234567", "dashboard")
+	if !ok || !strings.Contains(msg, "https://memo.example.com/auth/verify?token=") {
+		t.Fatalf("unexpected dashboard reply: %q", msg)
+	}
+	link := msg[strings.Index(msg, "https://"):]
+	token := link[strings.Index(link, "token=")+len("token="):]
+
+	verify := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		b.AuthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/verify?token="+token, nil))
+		return rec
+	}
+	rec := verify()
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/" {
+		t.Fatalf("expected redirect to dashboard, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly || !cookie.Secure {
+		t.Fatalf("expected a secure session cookie, got %+v", cookie)
+	}
+	if rec := verify(); rec.Header().Get("Location") != "/login?error=expired" {
+		t.Fatalf("expected the link to be single use, got %q", rec.Header().Get("Location"))
+	}
+
+	call := func(method, path, body string, withCookie bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if withCookie {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		b.APIHandler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := call(http.MethodGet, "/api/reminders", "", false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", rec.Code)
+	}
+	rec = call(http.MethodGet, "/api/reminders", "", true)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Pay rent") || strings.Contains(rec.Body.String(), "Someone else") {
+		t.Fatalf("unexpected list: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = call(http.MethodPost, "/api/reminders", `{"content":"Book dentist","priority":4,"due_at":"2030-05-03"}`, true)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body.String())
+	}
+	var created ReminderResource
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created reminder: %v", err)
+	}
+	if created.Priority != 4 || created.DueAt == nil || created.DueAt.Format("2006-01-02") != "2030-05-03" {
+		t.Fatalf("unexpected created reminder: %+v", created)
+	}
+	path := fmt.Sprintf("/api/reminders/%d", created.ID)
+
+	formReq := httptest.NewRequest(http.MethodPost, "/api/reminders", strings.NewReader("content=x"))
+	formReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	formReq.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	b.APIHandler().ServeHTTP(rec, formReq)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected form posts to be rejected, got %d", rec.Code)
+	}
+
+	if rec := call(http.MethodPatch, path, `{"priority":2,"due_at":""}`, true); rec.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body.String())
+	}
+	var stored model.Reminder
+	if err := b.db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("load reminder: %v", err)
+	}
+	if stored.Priority != 2 || stored.DueAt != nil {
+		t.Fatalf("patch not applied: %+v", stored)
+	}
+
+	if rec := call(http.MethodPost, path+"/complete", "{}", true); rec.Code != http.StatusOK {
+		t.Fatalf("complete: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(http.MethodGet, "/api/reminders?status=completed", "", true); !strings.Contains(rec.Body.String(), "Book dentist") {
+		t.Fatalf("expected completed reminder, got %s", rec.Body.String())
+	}
+
+	var other model.Reminder
+	if err := b.db.Where("user_id = ?", "+15550000000").First(&other).Error; err != nil {
+		t.Fatalf("load other reminder: %v", err)
+	}
+	if rec := call(http.MethodDelete, fmt.Sprintf("/api/reminders/%d", other.ID), "{}", true); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user's reminder, got %d", rec.Code)
+	}
+	if rec := call(http.MethodDelete, path, "{}", true); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(http.MethodGet, path, "", true); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected deleted reminder to be gone, got %d", rec.Code)
+	}
+}
+
+func TestAPIOpenAPIDocumentAndValidation(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	session := &model.Session{TokenHash: hashToken("session-token"), UserID: "+15551234567", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := b.db.Create(session).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	b.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the document without a session, got %d", rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	for path, methods := range map[string][]string{
+		"/me":                      {"get"},
+		"/reminders":               {"get", "post"},
+		"/reminders/{id}":          {"get", "patch", "delete"},
+		"/reminders/{id}/complete": {"post"},
+	} {
+		for _, method := range methods {
+			if op := doc.Paths[path][method]; op == nil || op.OperationID == "" {
+				t.Fatalf("missing operation %s %s", method, path)
+			}
+		}
+	}
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "session-token"})
+		rec := httptest.NewRecorder()
+		b.APIHandler().ServeHTTP(rec, req)
+		return rec
+	}
+	for body, want := range map[string]string{
+		`{"content":"Pay rent","priority":"high"}`: "priority: must be an integer",
+		`{"content":"Pay rent","priority":9}`:      "priority: must be at most 5",
+		`{"priority":2}`:                           "content: is required",
+		`{"content":"Pay rent","colour":"red"}`:    "colour: is not a known field",
+	} {
+		rec := call(http.MethodPost, "/api/reminders", body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("POST %s: expected 400 %q, got %d %s", body, want, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = call(http.MethodPost, "/api/reminders", `{"content":"Pay rent","priority":4}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body.String())
+	}
+	var created ReminderResource
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created reminder: %v", err)
+	}
+	path := fmt.Sprintf("/api/reminders/%d", created.ID)
+	if rec := call(http.MethodPatch, path, `{"id":7}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown patch fields to be rejected, got %d", rec.Code)
+	}
+	if rec := call(http.MethodPatch, path, `{"content":"Pay the rent"}`); rec.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGRPCReminders(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+
+	listener := bufconn.Listen(1 << 20)
+	srv := b.GRPCServer()
+	go srv.Serve(listener)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	client := reminderpb.NewRemindersClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.ListReminders(ctx, &reminderpb.ListRemindersRequest{UserId: "alice"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a token, got %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	stream, err := client.WatchEvents(ctx, &reminderpb.WatchEventsRequest{UserId: "alice"})
+	if err != nil {
+		t.Fatalf("WatchEvents: %v", err)
+	}
+	// The subscription is registered asynchronously; wait for it before emitting.
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		b.events.mu.Lock()
+		n := len(b.events.subs)
+		b.events.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("event subscription was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	created, err := client.CreateReminder(ctx, &reminderpb.CreateReminderRequest{UserId: "alice", Content: "Pay rent", Priority: 5})
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+	if _, err := client.CreateReminder(ctx, &reminderpb.CreateReminderRequest{UserId: "alice", Content: "Too high", Priority: 9}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a bad priority, got %v", err)
+	}
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if event.GetType() != webhook.EventReminderCreated || event.GetReminder().GetId() != created.GetId() {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+
+	list, err := client.ListReminders(ctx, &reminderpb.ListRemindersRequest{UserId: "alice"})
+	if err != nil || len(list.GetReminders()) != 1 || list.GetReminders()[0].GetSummary() != "Pay rent" {
+		t.Fatalf("unexpected list: %+v, %v", list, err)
+	}
+
+	if _, err := client.DeleteReminder(ctx, &reminderpb.DeleteReminderRequest{UserId: "bob", Id: created.GetId()}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for another user's reminder, got %v", err)
+	}
+	if _, err := client.DeleteReminder(ctx, &reminderpb.DeleteReminderRequest{UserId: "alice", Id: created.GetId()}); err != nil {
+		t.Fatalf("DeleteReminder: %v", err)
+	}
+	if event, err := stream.Recv(); err != nil || event.GetType() != webhook.EventReminderDeleted {
+		t.Fatalf("expected a deleted event, got %+v, %v", event, err)
+	}
+}
+
+func TestTenantsRouteByInboundNumber(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+	b.cfg.TwilioWhatsAppNumber = "+14155238886"
+
+	call := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/tenants", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		b.AdminTenantsHandler().ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := call(`{"name":"acme","whatsapp_number":"whatsapp:+14155238886"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected the default number to be rejected, got %d", rec.Code)
+	}
+	if rec := call(`{"name":"acme","whatsapp_number":"+15550001111","reminder_gap_minutes":5,"openai_monthly_limit":2}`); rec.Code != http.StatusOK {
+		t.Fatalf("create tenant: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(`{"name":"other","whatsapp_number":"+15550001111"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a duplicate number to be rejected, got %d", rec.Code)
+	}
+
+	message := func(from, to, body string) string {
+		form := "From=whatsapp:" + from + "&To=whatsapp:" + to + "&Body=" + body
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(strings.ReplaceAll(form, "+", "%2B")))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		b.handleIncomingMessage(rec, req)
+		return rec.Body.String()
+	}
+	if reply := message("+15557654321", "+15550001111", "list my reminders"); strings.Contains(reply, "different myMemo workspace") {
+		t.Fatalf("expected a new user to join the tenant, got %q", reply)
+	}
+	if reply := message("+15557654321", "+14155238886", "list my reminders"); !strings.Contains(reply, "different myMemo workspace") {
+		t.Fatalf("expected the default number to turn away a tenant user, got %q", reply)
+	}
+	if reply := message("+15559999999", "+14155238886", "list my reminders"); strings.Contains(reply, "different myMemo workspace") {
+		t.Fatalf("expected default users to be unaffected, got %q", reply)
+	}
+
+	tenant, err := b.userTenant(context.Background(), "+15557654321")
+	if err != nil || tenant == nil || tenant.Name != "acme" {
+		t.Fatalf("unexpected tenant: %+v, %v", tenant, err)
+	}
+	if gap := b.reminderGap("+15557654321"); gap != 5*time.Minute {
+		t.Fatalf("expected the tenant gap, got %v", gap)
+	}
+	if gap := b.reminderGap("+15559999999"); gap != time.Hour {
+		t.Fatalf("expected the default gap, got %v", gap)
+	}
+
+	defaultSender, tenantSender := &recordingSender{}, &recordingSender{}
+	b.channels[channel.WhatsApp] = defaultSender
+	b.tenantSenders = map[uint]channel.Sender{tenant.ID: tenantSender}
+	if err := b.notify(context.Background(), "+15557654321", "hi"); err != nil {
+		t.Fatalf("notify tenant user: %v", err)
+	}
+	if err := b.notify(context.Background(), "+15559999999", "hi"); err != nil {
+		t.Fatalf("notify default user: %v", err)
+	}
+	if len(tenantSender.messages) != 1 || len(defaultSender.messages) != 1 {
+		t.Fatalf("expected one message per sender, got %d tenant and %d default", len(tenantSender.messages), len(defaultSender.messages))
+	}
+
+	for i, want := range []bool{true, true, false} {
+		if got := b.useOpenAI(context.Background(), "+15557654321"); got != want {
+			t.Fatalf("call %d: useOpenAI = %v, want %v", i+1, got, want)
+		}
+	}
+	if !b.useOpenAI(context.Background(), "+15559999999") {
+		t.Fatalf("expected default users to have no OpenAI limit")
+	}
+	if summary := b.summarizeReminderWithOpenAI(context.Background(), "+15557654321", "Renew the office lease"); summary != "Renew the office lease" {
+		t.Fatalf("expected the content back once the limit is reached, got %q", summary)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/tenants", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	b.AdminTenantsHandler().ServeHTTP(rec, req)
+	var list struct {
+		Tenants []TenantSummary `json:"tenants"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode tenants: %v", err)
+	}
+	if len(list.Tenants) != 1 || list.Tenants[0].Users != 1 || list.Tenants[0].OpenAICallsMonth != 2 {
+		t.Fatalf("unexpected tenants: %+v", list.Tenants)
+	}
+}
+
+func TestWeeklyReview(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	now := time.Now()
+	finished := now.Add(-48 * time.Hour)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "Renew passport", Priority: 3, CreatedAt: now.AddDate(0, 0, -30)},
+		{UserID: "user", Content: "Pay rent", Priority: 4},
+		{UserID: "user", Content: "Book dentist", Priority: 2, CreatedAt: now.AddDate(0, 0, -20), CompletedAt: &finished},
+		{UserID: "paused", Content: "Water plants", Priority: 2},
+	})
+	if err := b.updatePreferences("paused", func(p *model.UserPreference) { p.Paused = true }); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+
+	b.sendWeeklyReviews()
+
+	if len(whatsapp.messages) != 1 {
+		t.Fatalf("expected one review, got %+v", whatsapp.messages)
+	}
+	msg := whatsapp.messages[0]
+	if msg.To != "user" {
+		t.Fatalf("expected the review to go to user, got %q", msg.To)
+	}
+	want := []string{"1 done this week, 2 still open", "R1 Renew passport (30 days)", "delete R"}
+	if !containsAll(msg.Body, want) || strings.Contains(msg.Body, "Pay rent") {
+		t.Fatalf("unexpected review: %q", msg.Body)
+	}
+}
+
+func TestPriorityAging(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.PriorityAging = "7,14"
+	now := time.Now()
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "Fix the fence", Priority: 2, CreatedAt: now.AddDate(0, 0, -20)},
+		{UserID: "user", Content: "Stretch", Priority: 1, Kind: model.KindHabit, CreatedAt: now.AddDate(0, 0, -20)},
+		{UserID: "user", Content: "Pay rent", Priority: 5},
+		{UserID: "user", Content: "Buy milk", Priority: 3},
+	})
+
+	list := plainText(b.respond(context.Background(), "user", "list my reminders"))
+	want := []string{"1. R3 [5] Pay rent", "2. R1 [2↑4] Fix the fence", "3. R4 [3] Buy milk", "4. R2 [1] Stretch"}
+	for i, line := range strings.Split(strings.TrimSpace(list), "\n")[1:] {
+		if !strings.HasPrefix(line, want[i]) {
+			t.Fatalf("unexpected aged list: %q", list)
+		}
+	}
+	if detail := b.respond(context.Background(), "user", "show R1"); !strings.Contains(detail, "priority 2, listed as 4 after 20 days open") {
+		t.Fatalf("unexpected detail: %q", detail)
+	}
+
+	b.cfg.PriorityAging = ""
+	if list := plainText(b.respond(context.Background(), "user", "list my reminders")); !strings.Contains(list, "3. R1 [2] Fix the fence") {
+		t.Fatalf("expected aging to be off without PRIORITY_AGING_DAYS: %q", list)
+	}
+}
+
+func TestReminderWaitsForParent(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "Write the draft", Priority: 3},
+		{UserID: "user", Content: "Buy milk", Priority: 2},
+	})
+	ctx := context.Background()
+
+	reply := b.addReminder(ctx, "user", "Submit the report after I finish the drfat", 4)
+	if !strings.Contains(reply, "until R1 Write the draft is done") {
+		t.Fatalf("expected the reminder to wait for R1, got %q", reply)
+	}
+	if reply := b.addReminder(ctx, "user", "Call mum after lunch", 2); strings.Contains(reply, "waiting") || strings.Contains(reply, "until") {
+		t.Fatalf("expected a plain time not to create a dependency, got %q", reply)
+	}
+
+	list := b.listReminders(ctx, "user")
+	if strings.Contains(list, "Submit the report") || !strings.Contains(list, "1 more waiting for other reminders") {
+		t.Fatalf("expected the dependent reminder to be hidden: %q", list)
+	}
+	if detail := b.respond(ctx, "user", "show R3"); !strings.Contains(detail, "Waiting for: R1 Write the draft") {
+		t.Fatalf("unexpected detail: %q", detail)
+	}
+	b.dispatchUserReminders("user")
+	for _, send := range b.sends.Snapshot() {
+		if strings.Contains(send.Body, "Submit the report") {
+			t.Fatalf("expected the dependent reminder not to be sent: %+v", send)
+		}
+	}
+	b.sends.Drain(ctx)
+
+	if reply := b.respond(ctx, "user", "done R1"); !strings.Contains(reply, "Up next: R3 Submit the report") {
+		t.Fatalf("expected the done reply to name the next reminder, got %q", reply)
+	}
+	if list := b.listReminders(ctx, "user"); !strings.Contains(list, "Submit the report") || strings.Contains(list, "waiting") {
+		t.Fatalf("expected the dependent reminder to be listed once its parent is done: %q", list)
+	}
+}
+
+func TestCountdowns(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+
+	for text, want := range map[string]time.Time{
+		"2030-12-25":                          time.Date(2030, 12, 25, 0, 0, 0, 0, now.Location()),
+		"25th December, 2030":                 time.Date(2030, 12, 25, 0, 0, 0, 0, now.Location()),
+		now.AddDate(0, 0, -1).Format("Jan 2"): startOfDay(now).AddDate(1, 0, -1),
+	} {
+		if got, ok := parseDay(text, now); !ok || !got.Equal(want) {
+			t.Fatalf("parseDay(%q) = %v, %v; want %v", text, got, ok, want)
+		}
+	}
+
+	target := now.AddDate(0, 0, 10).Format("Jan 2")
+	if reply := b.respond(ctx, "user", "Countdown to "+target+": trip to Goa"); !strings.Contains(reply, "10 days left") || !strings.Contains(reply, "every day") {
+		t.Fatalf("unexpected countdown reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "weekly countdown to "+now.AddDate(0, 0, 15).Format("2006-01-02")+": exams"); !strings.Contains(reply, "every week") {
+		t.Fatalf("unexpected weekly countdown reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "countdown to someday: nothing"); !strings.Contains(reply, "couldn't read the date") {
+		t.Fatalf("expected a bad date to be rejected, got %q", reply)
+	}
+	yesterday := now.AddDate(0, 0, -1)
+	seedReminders(t, b, []model.Reminder{{UserID: "user", Content: "Concert", Priority: 3, Kind: model.KindCountdown, Interval: model.IntervalDaily, DueAt: &yesterday}})
+
+	b.finishCountdowns(ctx, "user", now)
+	reminders, err := b.openReminders(ctx, "user")
+	if err != nil || len(reminders) != 2 {
+		t.Fatalf("expected the passed countdown to be finished, got %+v (%v)", reminders, err)
+	}
+	for _, r := range reminders {
+		switch r.Content {
+		case "trip to Goa":
+			if !countdownDue(r, now) || b.reminderMessage(r, now) != "Countdown: 10 days left until trip to Goa ("+target+")." {
+				t.Fatalf("unexpected daily countdown message: %q", b.reminderMessage(r, now))
+			}
+			if got := countdownMessage(r, now.AddDate(0, 0, 10)); got != "Today's the day: trip to Goa!" {
+				t.Fatalf("unexpected message on the day: %q", got)
+			}
+		case "exams":
+			if countdownDue(r, now) || !countdownDue(r, now.AddDate(0, 0, 1)) {
+				t.Fatalf("expected the weekly countdown to go out on whole weeks only")
+			}
+		}
+	}
+}
+
+func TestBirthdays(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+
+	inFive := now.AddDate(0, 0, 5)
+	if reply := b.respond(ctx, "user", "Birthday: Asha on "+inFive.Format("January 2")); !strings.Contains(reply, "Asha's birthday on "+inFive.Format("January 2")) || !strings.Contains(reply, "3 days before") {
+		t.Fatalf("unexpected birthday reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "anniversary: Mum and Dad on "+now.AddDate(-30, 0, 1).Format("2006-01-02")); !strings.Contains(reply, "Mum and Dad's anniversary") {
+		t.Fatalf("unexpected anniversary reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "remind me of birthdays 5 days before"); !strings.Contains(reply, "5 days before") {
+		t.Fatalf("unexpected lead reply: %q", reply)
+	}
+
+	list := b.respond(ctx, "user", "birthdays")
+	if !containsAll(list, []string{"1. R2 Mum and Dad's anniversary", "(in 1 day)", "2. R1 Asha's birthday", "(in 5 days)"}) {
+		t.Fatalf("unexpected birthdays list: %q", list)
+	}
+
+	reminders, err := b.openReminders(ctx, "user")
+	if err != nil || len(reminders) != 2 {
+		t.Fatalf("load reminders: %+v (%v)", reminders, err)
+	}
+	for _, r := range reminders {
+		if r.Content != "Asha" {
+			continue
+		}
+		if !annualDue(r, 5, now) || annualDue(r, 5, now.AddDate(0, 0, 1)) || !annualDue(r, 5, inFive) {
+			t.Fatalf("expected the birthday to go out on the heads-up day and the day itself")
+		}
+		if got := b.reminderMessage(r, now); got != "Heads-up: Asha's birthday is in 5 days ("+inFive.Format("Mon Jan 2")+")." {
+			t.Fatalf("unexpected heads-up: %q", got)
+		}
+		b.rollAnnual(ctx, "user", now.AddDate(0, 0, 6))
+		var rolled model.Reminder
+		if err := b.db.First(&rolled, r.ID).Error; err != nil {
+			t.Fatalf("reload: %v", err)
+		}
+		if want := startOfDay(inFive).AddDate(1, 0, 0); !rolled.DueAt.Equal(want) {
+			t.Fatalf("expected the birthday to move to %v, got %v", want, rolled.DueAt)
+		}
+	}
+	if reply := b.respond(ctx, "user", "done Asha"); !strings.Contains(reply, "every year") {
+		t.Fatalf("expected birthdays not to be completed, got %q", reply)
+	}
+}
+
+func TestLocationReminders(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	message := func(form url.Values) string {
+		form.Set("From", "whatsapp:+15551234567")
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		b.handleIncomingMessage(rec, req)
+		return rec.Body.String()
+	}
+
+	if reply := b.respond(ctx, "+15551234567", "remind me about this place"); !strings.Contains(reply, "Share a location pin first") {
+		t.Fatalf("expected a pin to be needed first, got %q", reply)
+	}
+	pin := url.Values{"Latitude": {"12.971599"}, "Longitude": {"77.594566"}, "Label": {"Corner Bakery"}}
+	if reply := message(pin); !strings.Contains(reply, "Got the location") {
+		t.Fatalf("unexpected reply to a pin: %q", reply)
+	}
+	if reply := message(url.Values{"Body": {"Remind me about this place: buy bread"}}); !strings.Contains(reply, "buy bread") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+
+	message(pin)
+	seedReminders(t, b, []model.Reminder{{UserID: "+15551234567", Content: "Return the library book", Priority: 2}})
+	if reply := b.respond(ctx, "+15551234567", "attach this place to R2"); !strings.Contains(reply, "Added the location to R2") {
+		t.Fatalf("unexpected attach reply: %q", reply)
+	}
+
+	reminders, err := b.openReminders(ctx, "+15551234567")
+	if err != nil || len(reminders) != 2 {
+		t.Fatalf("load reminders: %+v (%v)", reminders, err)
+	}
+	for _, r := range reminders {
+		if msg := b.reminderMessage(r, time.Now()); !strings.HasSuffix(msg, "\nhttps://maps.google.com/?q=12.971599,77.594566") {
+			t.Fatalf("expected a maps link in %q", msg)
+		}
+	}
+	if detail := b.respond(ctx, "+15551234567", "show R1"); !strings.Contains(detail, "Place: Corner Bakery https://maps.google.com/") {
+		t.Fatalf("unexpected detail: %q", detail)
+	}
+}
+
+func TestSharedLists(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	ctx := context.Background()
+	const alice, bob, carol = "+15550000001", "+15550000002", "+15550000003"
+	say := func(user, body string) string { return b.respond(ctx, user, body) }
+
+	say(alice, "add eggs to my groceries list")
+	if reply := say(alice, "share my groceries list with +1 (555) 000-0002 as viewer"); reply != "Shared your groceries list with "+bob+" as viewer." {
+		t.Fatalf("unexpected share reply: %q", reply)
+	}
+	if len(whatsapp.messages) != 1 || whatsapp.messages[0].To != bob || !strings.Contains(whatsapp.messages[0].Body, "so you can view it") {
+		t.Fatalf("expected bob to be told about the list, got %+v", whatsapp.messages)
+	}
+	if reply := say(bob, "show my groceries list"); !containsAll(reply, []string{"eggs", "Shared with you by " + alice}) {
+		t.Fatalf("unexpected list for a viewer: %q", reply)
+	}
+	if reply := say(bob, "add milk to my groceries list"); !strings.Contains(reply, "view only") {
+		t.Fatalf("expected viewers not to edit, got %q", reply)
+	}
+	if reply := say(carol, "show my groceries list"); !strings.Contains(reply, "don't have a groceries list") {
+		t.Fatalf("expected other users not to see the list, got %q", reply)
+	}
+
+	say(alice, "share my groceries list with "+bob)
+	if reply := say(bob, "add milk to my groceries list"); !strings.Contains(reply, "Added 'milk'") {
+		t.Fatalf("expected editors to add items, got %q", reply)
+	}
+	if reply := say(bob, "delete my groceries list"); !strings.Contains(reply, "Only "+alice) {
+		t.Fatalf("expected only the owner to delete the list, got %q", reply)
+	}
+	if reply := say(alice, "show my groceries list"); !containsAll(reply, []string{"eggs", "milk", "Shared with " + bob + " (editor)"}) {
+		t.Fatalf("unexpected list for the owner: %q", reply)
+	}
+	if reply := say(bob, "lists"); !strings.Contains(reply, "groceries (2 item(s), shared by "+alice+")") {
+		t.Fatalf("unexpected lists overview: %q", reply)
+	}
+
+	if reply := say(bob, "leave the groceries list"); !strings.Contains(reply, "You've left") {
+		t.Fatalf("unexpected leave reply: %q", reply)
+	}
+	if reply := say(bob, "show my groceries list"); !strings.Contains(reply, "don't have a groceries list") {
+		t.Fatalf("expected bob to lose access after leaving, got %q", reply)
+	}
+}
+
+func seedReminders(t *testing.T, b *Bot, reminders []model.Reminder) {
+	t.Helper()
+	for i := range reminders {
+		if reminders[i].CreatedAt.IsZero() {
+			reminders[i].CreatedAt = time.Now().Add(time.Duration(i) * time.Minute)
+		}
+		if err := b.db.Create(&reminders[i]).Error; err != nil {
+			t.Fatalf("seed reminder %d: %v", i, err)
+		}
+	}
+}
+
+func containsAll(haystack string, needles []string) bool {
+	for _, needle := range needles {
+		if needle != "" && !strings.Contains(haystack, needle) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLanguagePreference(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	if reply := b.respond(ctx, "user", "Set language Spanish"); reply != "Idioma cambiado a español." {
+		t.Fatalf("unexpected language reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "remind me to pay rent"); !strings.HasPrefix(reply, "¿Qué prioridad") {
+		t.Fatalf("expected a Spanish priority prompt, got %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "4"); !strings.HasPrefix(reply, "¡Listo! Te recordaré: ") || !strings.HasSuffix(reply, "(prioridad 4).") {
+		t.Fatalf("unexpected confirmation: %q", reply)
+	}
+	if list := plainText(b.respond(ctx, "user", "list my reminders")); !strings.HasPrefix(list, "Estos son tus recordatorios:\n1. R1 [4]") {
+		t.Fatalf("unexpected list: %q", list)
+	}
+	if reply := b.respond(ctx, "user", "set language klingon"); !strings.HasPrefix(reply, "Todavía no hablo klingon.") {
+		t.Fatalf("unexpected reply for an unknown language: %q", reply)
+	}
+
+	var reminder model.Reminder
+	if err := b.db.First(&reminder).Error; err != nil {
+		t.Fatalf("load reminder: %v", err)
+	}
+	if msg := i18n.Translate("es", b.reminderMessage(reminder, time.Now())); !strings.HasPrefix(msg, "Recordatorio: ") {
+		t.Fatalf("unexpected scheduled message: %q", msg)
+	}
+
+	if reply := b.respond(ctx, "user", "language: english"); reply != "Language set to English." {
+		t.Fatalf("unexpected reply switching back: %q", reply)
+	}
+}
+
+func TestIntentClarification(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	seedReminders(t, b, []model.Reminder{{UserID: "user", Content: "buy milk", Priority: 3}})
+
+	unsure := myopenai.Classification{Intent: myopenai.IntentDeleteReminder, Confidence: 0.4, Alternative: myopenai.IntentAddReminder}
+	if q := b.askToClarify("user", "delete milk", unsure); q != "Did you want to delete a reminder or add a reminder? Reply 1 or 2." {
+		t.Fatalf("unexpected question: %q", q)
+	}
+	if reply := b.respond(ctx, "user", "Delete."); reply != "Deleted reminders matching 'milk'." {
+		t.Fatalf("unexpected reply to the question: %q", reply)
+	}
+
+	b.askToClarify("user", "dentist on friday", unsure)
+	if reply := b.respond(ctx, "user", "2"); !strings.HasPrefix(reply, "What priority") {
+		t.Fatalf("expected the add flow, got %q", reply)
+	}
+	b.respond(ctx, "user", "3")
+
+	if q := b.askToClarify("user", "hmm", myopenai.Classification{Intent: myopenai.IntentUnknown, Confidence: 0.2}); q != "Did you want to add a reminder? Reply yes or no." {
+		t.Fatalf("unexpected single-option question: %q", q)
+	}
+	if reply := b.respond(ctx, "user", "no"); !strings.HasPrefix(reply, "Okay, I'll leave it.") {
+		t.Fatalf("unexpected reply to no: %q", reply)
+	}
+
+	b.askToClarify("user", "hmm", unsure)
+	if reply := b.respond(ctx, "user", "list my reminders"); !strings.Contains(reply, "dentist on friday") {
+		t.Fatalf("an unrelated reply should be handled as a new message, got %q", reply)
+	}
+}
+
+func TestCompoundActions(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "pay rent", Priority: 4},
+		{UserID: "user", Content: "water the plants", Priority: 2},
+	})
+
+	if !looksCompound("delete the rent reminder and remind me to call the plumber tomorrow") {
+		t.Fatal("expected a compound message")
+	}
+	if looksCompound("remind me to buy bread and milk") {
+		t.Fatal("a single reminder mentioning 'and' is not compound")
+	}
+
+	reply := b.runActions(ctx, "user", []myopenai.Action{
+		{Intent: myopenai.IntentDeleteReminder, Text: "rent"},
+		{Intent: myopenai.IntentCompleteReminder, Text: "R2"},
+		{Intent: myopenai.IntentAddReminder, Text: "call the plumber tomorrow"},
+	})
+	want := "Done:\n- Deleted R1: pay rent.\n- Marked R2 done: water the plants.\n- Added R3: call the plumber tomorrow (priority 3)."
+	if reply != want {
+		t.Fatalf("unexpected reply:\n%s", reply)
+	}
+
+	reply = b.runActions(ctx, "user", []myopenai.Action{
+		{Intent: myopenai.IntentAddReminder, Text: "book flights"},
+		{Intent: myopenai.IntentDeleteReminder, Text: "dentist"},
+	})
+	if reply != "I couldn't find a reminder matching 'dentist'. I haven't changed anything." {
+		t.Fatalf("unexpected reply for a failed action: %q", reply)
+	}
+	var count int64
+	b.db.Model(&model.Reminder{}).Where("content = ?", "book flights").Count(&count)
+	if count != 0 {
+		t.Fatal("a failed action should roll back the others")
+	}
+}
+
+func TestPriorityChangeAndRecentExchanges(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "pay rent", Priority: 2},
+		{UserID: "user", Content: "water the plants", Priority: 2},
+		{UserID: "user", Content: "water the lawn", Priority: 2},
+	})
+
+	if reply := b.respond(ctx, "user", "set R1 priority 5"); reply != "R1 pay rent is now priority 5." {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "change the priority of plants to 4"); reply != "R2 water the plants is now priority 4." {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "make water priority 1"); !strings.HasPrefix(reply, "'water' matches 2 reminders.") {
+		t.Fatalf("unexpected reply for an ambiguous selector: %q", reply)
+	}
+	if reply := b.respond(ctx, "user", "make dinner priority 5"); !strings.HasPrefix(reply, "What priority") {
+		t.Fatalf("a message naming no reminder should be saved as one, got %q", reply)
+	}
+
+	recent := b.recent.Recent("user", time.Now())
+	if len(recent) != 4 || recent[0].Message != "set R1 priority 5" || recent[0].Reply != "R1 pay rent is now priority 5." {
+		t.Fatalf("unexpected recent exchanges: %+v", recent)
+	}
+	for i := 0; i < maxRecentExchanges+2; i++ {
+		b.recent.Add("user", fmt.Sprintf("message %d", i), "ok", time.Now())
+	}
+	if recent := b.recent.Recent("user", time.Now()); len(recent) != maxRecentExchanges || recent[0].Message != "message 2" {
+		t.Fatalf("expected the last %d exchanges, got %+v", maxRecentExchanges, recent)
+	}
+	if recent := b.recent.Recent("user", time.Now().Add(recentExchangeTTL+time.Minute)); len(recent) != 0 {
+		t.Fatalf("expected old exchanges to expire, got %+v", recent)
+	}
+	if !followUpPattern.MatchString("actually make that priority 5") || followUpPattern.MatchString("remind me to call mum") {
+		t.Fatal("unexpected follow-up detection")
+	}
+}
+
+func TestModerationFailsOpen(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.ModerateContent = true
+
+	// Without a working moderation endpoint messages are handled as usual.
+	if reply := b.respond(context.Background(), "user", "remind me to call the bank"); !strings.HasPrefix(reply, "What priority") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+}
+
+func TestReminderCategories(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	b.cfg.ReminderCategories = "work,home,health"
+
+	b.respond(ctx, "user", "send the invoice #work")
+	b.respond(ctx, "user", "4")
+	b.respond(ctx, "user", "book a physio appointment #Health")
+	b.respond(ctx, "user", "2")
+	b.respond(ctx, "user", "buy stamps #post")
+	b.respond(ctx, "user", "3")
+
+	var reminders []model.Reminder
+	if err := b.db.Order("code").Find(&reminders).Error; err != nil {
+		t.Fatalf("load reminders: %v", err)
+	}
+	if got := []string{reminders[0].Category, reminders[1].Category, reminders[2].Category}; got[0] != "work" || got[1] != "health" || got[2] != "" {
+		t.Fatalf("unexpected categories: %q", got)
+	}
+
+	if list := b.respond(ctx, "user", "list work"); !strings.HasPrefix(list, "Here are your reminders (work):\n") || !strings.Contains(list, "invoice") || strings.Contains(list, "physio") {
+		t.Fatalf("unexpected filtered list: %q", list)
+	}
+	if list := b.respond(ctx, "user", "list high priority in work"); !strings.Contains(list, "invoice") {
+		t.Fatalf("unexpected combined filter: %q", list)
+	}
+	if reply := b.respond(ctx, "user", "list home"); reply != "You have no reminders matching: home." {
+		t.Fatalf("unexpected reply for an empty category: %q", reply)
+	}
+	if detail := b.respond(ctx, "user", "show R2"); !strings.Contains(detail, "Category: health") {
+		t.Fatalf("expected the category in the detail view, got %q", detail)
+	}
+
+	review, err := b.weeklyReview(ctx, "user", time.Now())
+	if err != nil {
+		t.Fatalf("weekly review: %v", err)
+	}
+	if !strings.Contains(review, "By category: health 1, work 1.") {
+		t.Fatalf("expected a category breakdown, got %q", review)
+	}
+}
+
+func TestRescheduleOverdueReminders(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.RescheduleAfterDays = 3
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+	longAgo, lastWeek, yesterday := now.AddDate(0, 0, -10), now.AddDate(0, 0, -7), now.AddDate(0, 0, -1)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Content: "renew passport", Priority: 3, DueAt: &lastWeek},
+		{UserID: "user", Content: "return library books", Priority: 3, DueAt: &longAgo},
+		{UserID: "user", Content: "call the bank", Priority: 3, DueAt: &yesterday},
+		{UserID: "other", Content: "book flights", Priority: 3, DueAt: &yesterday},
+	})
+
+	b.sendRescheduleSuggestions()
+	if len(whatsapp.messages) != 1 {
+		t.Fatalf("expected one offer, got %+v", whatsapp.messages)
+	}
+	want := "'return library books' was due " + longAgo.Format("Mon Jan 2") + ". Want me to move it to tomorrow morning ("
+	if msg := whatsapp.messages[0]; msg.To != "user" || !strings.HasPrefix(msg.Body, want) {
+		t.Fatalf("unexpected offer: %+v", msg)
+	}
+
+	if reply := b.respond(ctx, "user", "Yes please"); !strings.HasPrefix(reply, "Done, 'return library books' is now due tomorrow morning") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	var moved model.Reminder
+	b.db.Where("content = ?", "return library books").First(&moved)
+	if tomorrow := startOfDay(now).AddDate(0, 0, 1).Add(9 * time.Hour); moved.DueAt == nil || !moved.DueAt.Equal(tomorrow) {
+		t.Fatalf("expected the reminder to move to %v, got %v", tomorrow, moved.DueAt)
+	}
+
+	b.sendRescheduleSuggestions()
+	if len(whatsapp.messages) != 2 || !strings.Contains(whatsapp.messages[1].Body, "renew passport") {
+		t.Fatalf("expected an offer for the next overdue reminder, got %+v", whatsapp.messages)
+	}
+	if reply := b.respond(ctx, "user", "no"); reply != "Okay, I'll leave 'renew passport' as it is." {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	b.sendRescheduleSuggestions()
+	if len(whatsapp.messages) != 2 {
+		t.Fatalf("a declined offer should not be repeated, got %+v", whatsapp.messages[2:])
+	}
+}
+
+func TestBatchAddReminders(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	session := &model.Session{TokenHash: hashToken("session-token"), UserID: "user", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := b.db.Create(session).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	summaries, err := myopenai.New("").SummarizeReminders(context.Background(), []string{"pay rent", strings.Repeat("x", 100)}, "")
+	if err != nil || len(summaries) != 2 || summaries[0] != "pay rent" || len(summaries[1]) != 83 {
+		t.Fatalf("expected one fallback summary per reminder, got %q, %v", summaries, err)
+	}
+
+	call := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/reminders/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "session-token"})
+		rec := httptest.NewRecorder()
+		b.APIHandler().ServeHTTP(rec, req)
+		return rec
+	}
+	for body, want := range map[string]string{
+		`{"reminders":[]}`: "must have at least 1 items",
+		`{"reminders":[{"content":"pay rent"},{"content":"book flights","due_at":"someday"}]}`: "reminder 2:",
+	} {
+		rec := call(body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+			t.Fatalf("POST %s: expected 400 %q, got %d %s", body, want, rec.Code, rec.Body.String())
+		}
+	}
+	var count int64
+	b.db.Model(&model.Reminder{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("a rejected batch should add nothing, found %d reminders", count)
+	}
+
+	rec := call(`{"reminders":[{"content":"pay rent","priority":5},{"content":"book flights"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("batch: %d %s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		Reminders []ReminderResource `json:"reminders"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode batch: %v", err)
+	}
+	if len(out.Reminders) != 2 || out.Reminders[0].Code != "R1" || out.Reminders[0].Priority != 5 ||
+		out.Reminders[1].Code != "R2" || out.Reminders[1].Summary != "book flights" {
+		t.Fatalf("unexpected batch result: %+v", out.Reminders)
+	}
+}
+
+func TestAdminOpenAIRetryStats(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+	handler := b.AdminOpenAIHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/openai", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+
+	// Without an API key nothing reaches OpenAI, so nothing is retried.
+	b.summarizeReminderWithOpenAI(context.Background(), "user", "pay rent")
+	req := httptest.NewRequest(http.MethodGet, "/admin/openai", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var stats myopenai.RetryStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %v", rec.Code, err)
+	}
+	if stats != (myopenai.RetryStats{}) {
+		t.Fatalf("expected no requests or retries, got %+v", stats)
+	}
+}
+
+func TestAzureOpenAIConfig(t *testing.T) {
+	t.Parallel()
+	cfg := &config.Config{
+		TwilioAccountSID:     "AC123",
+		TwilioAuthToken:      "token",
+		TwilioWhatsAppNumber: "+14155238886",
+		Port:                 "8080",
+		OpenAIAPIKey:         "key",
+		OpenAIDeployment:     "gpt-4o-mini",
+		OpenAIAPIVersion:     config.DefaultAzureAPIVersion,
+		ModerateContent:      true,
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "OPENAI_AZURE_DEPLOYMENT requires OPENAI_BASE_URL") ||
+		!strings.Contains(err.Error(), "OPENAI_MODERATION is not available") {
+		t.Fatalf("expected Azure settings to be checked, got %v", err)
+	}
+	cfg.OpenAIBaseURL = "https://acme.openai.azure.com"
+	cfg.ModerateContent = false
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error for a complete Azure setup: %v", err)
+	}
+
+	b := newTestBot(t)
+	b.cfg.OpenAIDeployment = cfg.OpenAIDeployment
+	if err := b.Reload(&config.Config{ModerateContent: true, ReminderGap: time.Hour}); err == nil {
+		t.Fatal("expected reload to refuse moderation on Azure OpenAI")
+	}
+}
+
+func TestClaudeProvider(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" || r.Header.Get("x-api-key") != "claude-key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("unexpected request %s with headers %v", r.URL.Path, r.Header)
+		}
+		var req struct {
+			Model  string `json:"model"`
+			System string `json:"system"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != anthropic.DefaultModel {
+			t.Errorf("expected the default model, got %q", req.Model)
+		}
+		text := "Pay the rent."
+		switch {
+		case strings.Contains(req.System, "Classify"):
+			text = `{"intent": "list_reminders", "confidence": 0.9, "alternative": "unknown"}`
+		case strings.Contains(req.System, "numbered list"):
+			text = "```json\n[\"Pay the rent.\", \"Book flights.\"]\n```"
+		}
+		json.NewEncoder(w).Encode(map[string]any{"content": []map[string]string{{"type": "text", "text": text}}})
+	}))
+	defer server.Close()
+
+	b := newTestBot(t)
+	b.cfg.LLMProvider = config.ProviderAnthropic
+	b.cfg.AnthropicAPIKey = "claude-key"
+	claude, ok := newLanguageModel(b.cfg, b.openAI).(*anthropic.Client)
+	if !ok {
+		t.Fatal("expected LLM_PROVIDER=anthropic to select Claude")
+	}
+	claude.BaseURL = server.URL
+	b.llm = claude
+	ctx := context.Background()
+
+	if intent, _ := b.determineIntent(ctx, "user", "what have I got on", "what have i got on"); intent != myopenai.IntentListReminders {
+		t.Fatalf("expected Claude's intent, got %q", intent)
+	}
+	if summary := b.summarizeReminderWithOpenAI(ctx, "user", "pay the rent on friday"); summary != "Pay the rent." {
+		t.Fatalf("unexpected summary %q", summary)
+	}
+	summaries := b.summarizeRemindersWithOpenAI(ctx, "user", []string{"pay the rent", "book flights to Rome"})
+	if !reflect.DeepEqual(summaries, []string{"Pay the rent.", "Book flights."}) {
+		t.Fatalf("unexpected batch summaries %q", summaries)
+	}
+}
+
+// wordEmbedder embeds text as counts of a few words, treating the words in
+// each group as synonyms.
+type wordEmbedder [][]string
+
+func (e wordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(e))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for dim, group := range e {
+				if slices.Contains(group, word) {
+					vectors[i][dim]++
+				}
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func TestReminderEmbeddings(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.embedder = wordEmbedder{{"rent", "landlord"}, {"pay"}, {"dentist", "teeth"}, {"book"}}
+	ctx := context.Background()
+
+	b.addReminder(ctx, "user", "pay the rent", 3)
+	b.addReminder(ctx, "user", "book the dentist", 2)
+	var stored int64
+	b.db.Model(&model.ReminderEmbedding{}).Count(&stored)
+	if stored != 2 {
+		t.Fatalf("expected an embedding per saved reminder, got %d", stored)
+	}
+
+	reply := b.addReminder(ctx, "user", "pay rent", 4)
+	if !strings.HasSuffix(reply, "\nLooks like you already have R1: pay the rent.") {
+		t.Fatalf("expected a duplicate warning, got %q", reply)
+	}
+
+	msg, err := b.searchReminderList(ctx, "user", "landlord")
+	if err != nil {
+		t.Fatalf("semantic search: %v", err)
+	}
+	if !strings.Contains(msg, "R1") || !strings.Contains(msg, "R3") || strings.Contains(msg, "R2") {
+		t.Fatalf("expected the rent reminders to match by meaning, got %q", msg)
+	}
+
+	seedReminders(t, b, []model.Reminder{{UserID: "other", Content: "teeth cleaning", Priority: 2}})
+	b.backfillEmbeddings()
+	b.db.Model(&model.ReminderEmbedding{}).Count(&stored)
+	if stored != 4 {
+		t.Fatalf("expected the backfill to embed the imported reminder, got %d embeddings", stored)
+	}
+	neighbors, err := database.NearestReminders(b.db, "other", myopenai.EmbeddingModel, model.Vector{0, 0, 1, 0}, 5)
+	if err != nil || len(neighbors) != 1 || neighbors[0].Distance > 0.001 {
+		t.Fatalf("unexpected neighbours %+v, %v", neighbors, err)
+	}
+}
+
+func TestListGroupedByTopic(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.embedder = wordEmbedder{{"dentist", "teeth"}, {"report"}, {"milk"}, {"gym"}}
+	ctx := context.Background()
+	reminders := []model.Reminder{
+		{UserID: "user", Content: "finish the report", Priority: 5, Category: "work"},
+		{UserID: "user", Content: "dentist checkup", Priority: 4, Category: "health"},
+		{UserID: "user", Content: "email the team", Priority: 3, Category: "work"},
+		{UserID: "user", Content: "book teeth cleaning", Priority: 3},
+		{UserID: "user", Content: "buy milk", Priority: 2},
+		{UserID: "user", Content: "gym session", Priority: 1, Category: "health"},
+	}
+	seedReminders(t, b, reminders)
+	b.embedReminders(ctx, "user", reminders)
+
+	list := plainText(b.listReminders(ctx, "user"))
+	for _, section := range []string{
+		"Work:\n1. R1 [5] finish the report",
+		"3. R3 [3] email the team",
+		"Health:\n2. R2 [4] dentist checkup",
+		"4. R4 [3] book teeth cleaning",
+		"6. R6 [1] gym session",
+		"Other:\n5. R5 [2] buy milk",
+	} {
+		if !strings.Contains(list, section) {
+			t.Fatalf("expected %q in grouped list:\n%s", section, list)
+		}
+	}
+	if strings.Index(list, "Work:") > strings.Index(list, "Health:") || strings.Index(list, "Health:") > strings.Index(list, "Other:") {
+		t.Fatalf("expected topics in order of their first reminder:\n%s", list)
+	}
+
+	if list, _ := b.handleListFilterCommand(ctx, "user", "list health reminders"); !strings.Contains(list, "dentist") || strings.Contains(list, "Health:") {
+		t.Fatalf("a category filter should not be grouped:\n%s", list)
+	}
+	b.db.Where("code > 3").Delete(&model.Reminder{})
+	if list := b.listReminders(ctx, "user"); strings.Contains(list, "Work:") {
+		t.Fatalf("a short list should not be grouped:\n%s", list)
+	}
+}
+
+type failingSender struct{}
+
+func (failingSender) Send(context.Context, channel.Message) error {
+	return errors.New("delivery failed")
+}
+
+func TestVoiceCallAlerts(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+
+	if reply := b.respond(ctx, "15551234567", "call me for urgent reminders"); !strings.Contains(reply, "aren't set up") {
+		t.Fatalf("expected calls to be unavailable without a voice number, got %q", reply)
+	}
+	voice := &recordingSender{}
+	b.channels[channel.Voice] = voice
+
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "15551234567", Content: "Renew passport", Priority: 5},
+		{UserID: "15551234567", Content: "Water plants", Priority: 2},
+	})
+	var urgent, routine model.Reminder
+	b.db.Where("content = ?", "Renew passport").First(&urgent)
+	b.db.Where("content = ?", "Water plants").First(&routine)
+
+	b.deliver(&pendingSend{UserID: "15551234567", ReminderID: urgent.ID, Body: "Reminder: Renew passport"})
+	if len(voice.messages) != 0 {
+		t.Fatalf("expected no call without opting in, got %+v", voice.messages)
+	}
+
+	if reply := b.respond(ctx, "15551234567", "call me for urgent reminders"); !strings.Contains(reply, "call you") {
+		t.Fatalf("unexpected opt-in reply: %q", reply)
+	}
+	b.deliver(&pendingSend{UserID: "15551234567", ReminderID: routine.ID, Body: "Reminder: Water plants"})
+	b.deliver(&pendingSend{UserID: "15551234567", ReminderID: urgent.ID, Body: "Reminder: Renew passport"})
+	if len(voice.messages) != 1 || voice.messages[0].To != "15551234567" || !strings.Contains(voice.messages[0].Body, "Renew passport") {
+		t.Fatalf("expected one call about the urgent reminder, got %+v", voice.messages)
+	}
+	if len(whatsapp.messages) != 3 {
+		t.Fatalf("expected calls to come on top of messages, got %d messages", len(whatsapp.messages))
+	}
+
+	if reply := b.respond(ctx, "15551234567", "stop calling me"); !strings.Contains(reply, "No more calls") {
+		t.Fatalf("unexpected opt-out reply: %q", reply)
+	}
+	b.channels[channel.WhatsApp] = failingSender{}
+	b.deliver(&pendingSend{UserID: "15551234567", ReminderID: urgent.ID, Body: "Reminder: Renew passport"})
+	if len(voice.messages) != 2 {
+		t.Fatalf("expected a call when the message fails, got %d calls", len(voice.messages))
+	}
+	b.deliver(&pendingSend{UserID: "15551234567", ReminderID: routine.ID, Body: "Reminder: Water plants"})
+	if len(voice.messages) != 2 {
+		t.Fatalf("expected no call for a failed routine reminder, got %d calls", len(voice.messages))
+	}
+}
+
+func TestReadReceiptFollowUps(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.TwilioStatusCallbacks = true
+	b.cfg.PublicBaseURL = "https://memo.example"
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	reminders := []model.Reminder{
+		{UserID: "15551234567", Content: "Renew passport", Priority: 5},
+		{UserID: "15551234567", Content: "Water plants", Priority: 2},
+	}
+	seedReminders(t, b, reminders)
+	urgent, routine := reminders[0], reminders[1]
+
+	b.deliver(&pendingSend{UserID: urgent.UserID, ReminderID: urgent.ID, Body: "Reminder: Renew passport"})
+	b.deliver(&pendingSend{UserID: routine.UserID, ReminderID: routine.ID, Body: "Reminder: Water plants"})
+	if len(whatsapp.messages) != 2 {
+		t.Fatalf("expected two messages, got %d", len(whatsapp.messages))
+	}
+	urgentCallback, routineCallback := whatsapp.messages[0].StatusCallback, whatsapp.messages[1].StatusCallback
+	if !strings.HasPrefix(urgentCallback, "https://memo.example/twilio/status?") {
+		t.Fatalf("unexpected status callback: %q", urgentCallback)
+	}
+
+	post := func(callback, status string) int {
+		u, err := url.Parse(callback)
+		if err != nil {
+			t.Fatalf("parse callback: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, u.RequestURI(), strings.NewReader("MessageStatus="+status))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		b.StatusCallbackHandler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := post(strings.Replace(urgentCallback, "token=", "token=x", 1), "read"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a bad token, got %d", code)
+	}
+	if code := post(urgentCallback, "delivered"); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	post(routineCallback, "failed")
+
+	b.followUpDeliveries()
+	if len(whatsapp.messages) != 3 || !strings.Contains(whatsapp.messages[2].Body, "Water plants") {
+		t.Fatalf("expected only the failed message to be resent, got %+v", whatsapp.messages)
+	}
+	b.db.Model(&model.ReminderDelivery{}).Where("reminder_id = ?", urgent.ID).Update("created_at", time.Now().Add(-3*time.Hour))
+	b.followUpDeliveries()
+	b.followUpDeliveries()
+	if len(whatsapp.messages) != 4 || !strings.Contains(whatsapp.messages[3].Body, "Renew passport") {
+		t.Fatalf("expected one resend of the unread urgent reminder, got %+v", whatsapp.messages)
+	}
+
+	post(whatsapp.messages[3].StatusCallback, "read")
+	post(whatsapp.messages[3].StatusCallback, "delivered")
+	var latest model.ReminderDelivery
+	b.db.Where("reminder_id = ?", urgent.ID).Order("id DESC").First(&latest)
+	if latest.Status != model.DeliveryRead || latest.ReadAt == nil {
+		t.Fatalf("expected a late callback not to undo the read receipt, got %+v", latest)
+	}
+	b.dispatchUserReminders(urgent.UserID)
+	defer b.sends.Drain(context.Background())
+	if b.sends.Len() != 1 {
+		t.Fatalf("expected the read reminder to be left out of the dispatch, got %d sends", b.sends.Len())
+	}
+}
+
+func TestOutboundRateLimiter(t *testing.T) {
+	t.Parallel()
+	limiter := twilio.NewLimiter(100)
+	start := time.Now()
+	for range 120 {
+		limiter.Wait()
+	}
+	// The first second's worth goes out at once; the other 20 are paced.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Fatalf("expected about 200ms for 120 messages at 100/s, took %v", elapsed)
+	}
+
+	unlimited := twilio.NewLimiter(0)
+	start = time.Now()
+	for range 1000 {
+		unlimited.Wait()
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected no pacing without a limit, took %v", elapsed)
+	}
+}
+
+type flakySender struct {
+	recordingSender
+	failing atomic.Bool
+}
+
+func (s *flakySender) Send(ctx context.Context, msg channel.Message) error {
+	if s.failing.Load() {
+		return errors.New("sender unavailable")
+	}
+	return s.recordingSender.Send(ctx, msg)
+}
+
+func TestSenderPoolFailover(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	first, second := &flakySender{}, &flakySender{}
+	b.pool = newSenderPool([]string{"+15550000001", "+15550000002"}, map[string]channel.Sender{
+		"+15550000001": first,
+		"+15550000002": second,
+	})
+	ctx := context.Background()
+	sender := func(userID string) string { return b.preferences(userID).SenderNumber }
+
+	for _, user := range []string{"111", "222", "333", "111"} {
+		if err := b.notify(ctx, user, "hello"); err != nil {
+			t.Fatalf("notify %s: %v", user, err)
+		}
+	}
+	if len(first.messages) != 3 || len(second.messages) != 1 {
+		t.Fatalf("expected round-robin with a stable sender per user, got %d and %d", len(first.messages), len(second.messages))
+	}
+	if sender("111") != "+15550000001" || sender("222") != "+15550000002" || sender("333") != "+15550000001" {
+		t.Fatalf("unexpected assignments: %q %q %q", sender("111"), sender("222"), sender("333"))
+	}
+
+	first.failing.Store(true)
+	if err := b.notify(ctx, "111", "still there?"); err != nil {
+		t.Fatalf("expected failover to the second number, got %v", err)
+	}
+	if len(second.messages) != 2 || sender("111") != "+15550000002" {
+		t.Fatalf("expected 111 to move to the second number, got %q", sender("111"))
+	}
+	second.failing.Store(true)
+	if err := b.notify(ctx, "222", "anyone?"); err == nil {
+		t.Fatalf("expected an error when every number fails")
+	}
+
+	b.pinSender("222", "whatsapp:+15550000001")
+	if sender("222") != "+15550000001" {
+		t.Fatalf("expected an inbound message to pin the sender, got %q", sender("222"))
+	}
+	b.pinSender("222", "whatsapp:+15559999999")
+	if sender("222") != "+15550000001" {
+		t.Fatalf("expected numbers outside the pool to be ignored, got %q", sender("222"))
+	}
+}
+
+type senderFunc func(context.Context, channel.Message) error
+
+func (f senderFunc) Send(ctx context.Context, msg channel.Message) error { return f(ctx, msg) }
+
+func TestSandboxOnboarding(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.TwilioWhatsAppNumber = "whatsapp:+14155238886"
+	b.cfg.TwilioSandboxKeyword = "join Bright-Tiger"
+	b.cfg.PublicBaseURL = "https://memo.example"
+	ctx := context.Background()
+
+	reply := b.respond(ctx, "+15551234567", "How do I connect?")
+	if !containsAll(reply, []string{`"join bright-tiger"`, "+14155238886", "https://wa.me/14155238886?text=join%20bright-tiger", "https://memo.example/join"}) {
+		t.Fatalf("unexpected connect instructions: %q", reply)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		b.JoinHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := get("/join"); rec.Code != http.StatusOK || !containsAll(rec.Body.String(), []string{"join bright-tiger", "/join/qr.png", "14155238886"}) {
+		t.Fatalf("unexpected join page: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/join/qr.png"); rec.Code != http.StatusOK || !bytes.HasPrefix(rec.Body.Bytes(), []byte("\x89PNG")) {
+		t.Fatalf("expected a PNG QR code, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	b.channels[channel.WhatsApp] = &recordingSender{}
+	seedReminders(t, b, []model.Reminder{{UserID: "+15551234567", Content: "Pay rent", Priority: 3}})
+	b.deliver(&pendingSend{UserID: "+15551234567", Body: "Reminder: Pay rent"})
+	if b.preferences("+15551234567").UnjoinedAt != nil {
+		t.Fatalf("expected a delivered message not to mark the user unjoined")
+	}
+	b.channels[channel.WhatsApp] = senderFunc(func(context.Context, channel.Message) error {
+		return fmt.Errorf("twilio send message error: %w", &client.TwilioRestError{Code: twilio.NotJoinedCode})
+	})
+	b.deliver(&pendingSend{UserID: "+15551234567", Body: "Reminder: Pay rent"})
+	if b.preferences("+15551234567").UnjoinedAt == nil {
+		t.Fatalf("expected a sandbox refusal to mark the user unjoined")
+	}
+	b.dispatchUserReminders("+15551234567")
+	if b.sends.Len() != 0 {
+		t.Fatalf("expected no sends for a user outside the sandbox, got %d", b.sends.Len())
+	}
+
+	form := "From=whatsapp:%2B15551234567&To=whatsapp:%2B14155238886&Body=list+my+reminders"
+	req := httptest.NewRequest(http.MethodPost, "/twilio/webhook", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	b.handleIncomingMessage(rec, req)
+	if !containsAll(rec.Body.String(), []string{"Welcome back", "Pay rent"}) {
+		t.Fatalf("expected a welcome back note with the reply, got %q", rec.Body.String())
+	}
+	if b.preferences("+15551234567").UnjoinedAt != nil {
+		t.Fatalf("expected writing again to clear the unjoined mark")
+	}
+}
+
+func TestIdempotentSends(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	whatsapp := &flakySender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	reminders := []model.Reminder{
+		{UserID: "user", Content: "Pay rent", Priority: 3},
+		{UserID: "user", Content: "Call mum", Priority: 2},
+	}
+	seedReminders(t, b, reminders)
+
+	b.dispatchUserReminders("user")
+	b.dispatchUserReminders("user")
+	if b.sends.Len() != 2 {
+		t.Fatalf("expected a repeated dispatch to queue no duplicates, got %d sends", b.sends.Len())
+	}
+	unsent, _ := b.sends.Drain(context.Background())
+	b.saveOutbox(unsent)
+	var outbox []model.OutboxMessage
+	b.db.Order("id").Find(&outbox)
+	if len(outbox) != 2 || outbox[0].IdempotencyKey == "" {
+		t.Fatalf("expected outbox rows with idempotency keys, got %+v", outbox)
+	}
+
+	send := &pendingSend{UserID: "user", ReminderID: reminders[0].ID, Body: "Reminder: Pay rent", Key: outbox[0].IdempotencyKey}
+	whatsapp.failing.Store(true)
+	b.deliver(send)
+	whatsapp.failing.Store(false)
+	b.deliver(send)
+	b.deliver(send)
+	if len(whatsapp.messages) != 1 {
+		t.Fatalf("expected one delivery after a failed attempt and a retry, got %d", len(whatsapp.messages))
+	}
+	b.deliver(&pendingSend{UserID: "user", ReminderID: reminders[0].ID, Body: "Reminder: Pay rent", Key: sendKey(reminders[0].ID, time.Now().AddDate(0, 0, 1))})
+	if len(whatsapp.messages) != 2 {
+		t.Fatalf("expected the next day's send to go out, got %d", len(whatsapp.messages))
+	}
+}
+
+func TestWebhookPanicRecovery(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+	handler := b.recoverWebhook(func(http.ResponseWriter, *http.Request) {
+		var payload map[string]any
+		_ = payload["media"].([]string)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/twilio/webhook", strings.NewReader("From=whatsapp:%2B15551234567&Body=hi"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusOK || !containsAll(rec.Body.String(), []string{"<Response>", "something went wrong"}) {
+		t.Fatalf("expected a TwiML apology, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	b.AdminMetricsHandler().ServeHTTP(rec, req)
+	var metrics Metrics
+	if err := json.Unmarshal(rec.Body.Bytes(), &metrics); err != nil || metrics.WebhookPanics != 1 {
+		t.Fatalf("expected one counted panic, got %q (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	var logs bytes.Buffer
+	b.logger = log.New(&logs, "", 0)
+	handler := b.LogRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("Body") != "hi" {
+			t.Errorf("handler lost the form payload: %q", r.FormValue("Body"))
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	post := func() {
+		req := httptest.NewRequest(http.MethodPost, "/twilio/status?token=secret", strings.NewReader("Body=hi&NumMedia=0"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	post()
+	if logs.Len() != 0 {
+		t.Fatalf("expected nothing logged while disabled, got %q", logs.String())
+	}
+
+	b.cfg.RequestLogging = true
+	b.cfg.RequestLogPayloadPct = 0
+	post()
+	if got := logs.String(); !strings.Contains(got, "POST /twilio/status 202") || strings.Contains(got, "payload") || strings.Contains(got, "secret") {
+		t.Fatalf("expected an unsampled request line, got %q", got)
+	}
+
+	logs.Reset()
+	b.cfg.RequestLogPayloadPct = 100
+	post()
+	if got := logs.String(); !strings.Contains(got, `payload="Body=[redacted 2 bytes]&NumMedia=0"`) {
+		t.Fatalf("expected the sampled payload to be logged without the message, got %q", got)
+	}
+
+	for _, tt := range []struct{ contentType, body, want string }{
+		{"application/x-www-form-urlencoded", "From=whatsapp%3A%2B15551234567&Body=connect+caldav+https%3A%2F%2Fcaldav.icloud.com+me+app-password", "Body=[redacted 56 bytes]&From=whatsapp%3A%2B15551234567"},
+		{"application/json; charset=utf-8", `{"content":"connect notion secret_abc","priority":3,"items":[{"token":"t0k"}]}`, `{"content":"[redacted 25 bytes]","items":[{"token":"[redacted 3 bytes]"}],"priority":3}`},
+		{"application/json", `{"content":"cut sho`, "[19 bytes]"},
+		{"text/plain", "connect notion secret_abc", "[25 bytes]"},
+	} {
+		if got := redactPayload(tt.contentType, []byte(tt.body)); got != tt.want {
+			t.Errorf("redactPayload(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+	b.state.SetPendingMessage("user", "buy milk")
+	handler := b.DebugHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected pprof to need the admin token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Fatalf("expected a goroutine profile, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var vars DebugVars
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil || vars.Goroutines == 0 || vars.ConversationStates != 1 {
+		t.Fatalf("unexpected debug vars %q (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestTLSDomainsConfig(t *testing.T) {
+	t.Parallel()
+	cfg := &config.Config{
+		TwilioAccountSID:     "AC123",
+		TwilioAuthToken:      "token",
+		TwilioWhatsAppNumber: "+14155238886",
+		Port:                 "8080",
+		GRPCPort:             "443",
+		TLSDomains:           []string{"memo.example.com", "https://memo.example.com"},
+	}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `TLS_DOMAINS entries must be host names such as memo.example.com (got "https://memo.example.com")`) ||
+		!strings.Contains(err.Error(), "GRPC_PORT must not be 80 or 443") {
+		t.Fatalf("expected TLS settings to be checked, got %v", err)
+	}
+	cfg.TLSDomains = cfg.TLSDomains[:1]
+	cfg.GRPCPort = "9090"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unexpected error for a valid TLS setup: %v", err)
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	var seen []string
+	b.events.handle(func(e reminderEvent) {
+		seen = append(seen, e.Type+":"+e.Origin)
+	})
+
+	reminder := model.Reminder{ID: 9, UserID: "user", Content: "Water plants", Priority: 2}
+	sent := make(chan struct{}, 1)
+	b.sends.Schedule(&pendingSend{UserID: "user", ReminderID: 9, SendAt: time.Now().Add(time.Hour)}, func(*pendingSend) { sent <- struct{}{} })
+	defer b.sends.Drain(context.Background())
+
+	b.emit(webhook.EventReminderCreated, reminder, "")
+	b.publish(webhook.EventReminderCompleted, reminder, "")
+	if want := []string{"reminder.created:", "reminder.completed:notion"}; !slices.Equal(seen, want) {
+		t.Fatalf("expected handlers to see %v, got %v", want, seen)
+	}
+	if b.sends.Len() != 0 {
+		t.Fatalf("expected completing the reminder to cancel its pending send")
+	}
+	counts := b.eventCounts.Snapshot()
+	if counts[webhook.EventReminderCreated] != 1 || counts[webhook.EventReminderCompleted] != 1 {
+		t.Fatalf("unexpected event counts %v", counts)
+	}
+}
+
+func TestCommandRegistry(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	var logged []string
+	expense := PrefixCommand("expense:", func(_ context.Context, userID, args string) string {
+		logged = append(logged, userID+" "+args)
+		return "Logged " + args
+	})
+	if err := b.Commands().Register("expense", `"Expense: 12 lunch" to log spending`, expense); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := b.Commands().Register("expense", "", expense); err == nil {
+		t.Fatalf("expected a duplicate command name to be refused")
+	}
+
+	if msg := b.reply(context.Background(), "user", "Expense: 12 lunch"); msg != "Logged 12 lunch" {
+		t.Fatalf("unexpected command reply %q", msg)
+	}
+	if !slices.Equal(logged, []string{"user 12 lunch"}) {
+		t.Fatalf("unexpected command calls %v", logged)
+	}
+	if msg := b.reply(context.Background(), "user", "list reminders"); strings.Contains(msg, "Logged") {
+		t.Fatalf("expected other messages to skip the command, got %q", msg)
+	}
+	if help := b.runIntent(context.Background(), "user", "help", "help", myopenai.IntentHelp, ""); !strings.HasSuffix(help, "\n- \"Expense: 12 lunch\" to log spending") {
+		t.Fatalf("expected the command in the help reply, got %q", help)
+	}
+}
+
+func TestImportKeepAndTodoist(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminToken = "secret"
+	post := func(format string, body []byte) (int, ImportResult) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/import?user_id=user&format="+format, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		b.AdminImportHandler().ServeHTTP(rec, req)
+		var result ImportResult
+		_ = json.Unmarshal(rec.Body.Bytes(), &result)
+		return rec.Code, result
+	}
+
+	var takeout bytes.Buffer
+	archive := zip.NewWriter(&takeout)
+	for name, note := range map[string]string{
+		"Takeout/Keep/Groceries.json": `{"title":"Groceries","listContent":[{"text":"Milk","isChecked":false},{"text":"Eggs","isChecked":true}]}`,
+		"Takeout/Keep/Idea.json":      `{"title":"","textContent":"Start a herb garden on the balcony","createdTimestampUsec":1700000000000000}`,
+		"Takeout/Keep/Old.json":       `{"title":"Old","textContent":"gone","isTrashed":true}`,
+		"Takeout/Keep/Idea.html":      `<html></html>`,
+	} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		io.WriteString(w, note)
+	}
+	archive.Close()
+	if code, result := post("keep", takeout.Bytes()); code != http.StatusCreated || result != (ImportResult{Reminders: 1, Memos: 1, Skipped: 2}) {
+		t.Fatalf("unexpected Keep import %d %+v", code, result)
+	}
+
+	csv := "\ufeffTYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+		"section,Work,,,,,,,,\n" +
+		"task,File taxes,Use last year's forms,1,1,,,2030-04-15,en,UTC\n" +
+		"note,Ask about deductions,,,,,,,,\n" +
+		"task,Water plants,,4,1,,,every day,en,UTC\n"
+	if code, result := post("todoist", []byte(csv)); code != http.StatusCreated || result != (ImportResult{Reminders: 2, Memos: 1, Skipped: 1}) {
+		t.Fatalf("unexpected Todoist import %d %+v", code, result)
+	}
+	if code, _ := post("evernote", []byte("x")); code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown format to be refused, got %d", code)
+	}
+
+	var reminders []model.Reminder
+	b.db.Where("user_id = ?", "user").Order("id").Find(&reminders)
+	if len(reminders) != 3 || reminders[0].Content != "Milk" || reminders[0].Priority != defaultAPIPriority ||
+		reminders[1].Content != "File taxes\nUse last year's forms" || reminders[1].Priority != 5 || reminders[1].DueAt == nil ||
+		reminders[2].Priority != 2 || reminders[2].DueAt != nil {
+		t.Fatalf("unexpected imported reminders %+v", reminders)
+	}
+	var memos []model.Memo
+	b.db.Where("user_id = ?", "user").Order("id").Find(&memos)
+	if len(memos) != 2 || memos[0].Title != "Start a herb garden on the balcony" || memos[0].CreatedAt.Year() != 2023 || memos[1].Content != "Ask about deductions" {
+		t.Fatalf("unexpected imported memos %+v", memos)
+	}
+}
+
+func TestAPIExportBundle(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	session := &model.Session{TokenHash: hashToken("session-token"), UserID: "user", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := b.db.Create(session).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	seedReminders(t, b, []model.Reminder{{UserID: "user", Content: "Pay rent", Summary: "Pay rent", Priority: 4}, {UserID: "other", Content: "Secret", Priority: 1}})
+	b.db.Create(&model.Memo{UserID: "user", Kind: model.MemoKindNote, Title: "Idea", Content: "Herb garden"})
+	b.db.Create(&model.ReminderDelivery{ReminderID: 1, UserID: "user", Status: model.DeliveryRead, CallbackHash: "x"})
+	if _, err := b.setLanguage("user", "spanish"); err != nil {
+		t.Fatalf("set language: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "session-token"})
+	rec := httptest.NewRecorder()
+	b.APIHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" ||
+		!strings.Contains(rec.Header().Get("Content-Disposition"), "mymemo-export-") {
+		t.Fatalf("unexpected export response %d %v", rec.Code, rec.Header())
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", file.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(data)
+	}
+	if len(files) != 5 || !strings.Contains(files["reminders.json"], "Pay rent") || strings.Contains(files["reminders.json"], "Secret") ||
+		!strings.Contains(files["memos.json"], "Herb garden") || !strings.Contains(files["preferences.json"], `"language": "es"`) ||
+		!strings.Contains(files["deliveries.json"], `"status": "read"`) {
+		t.Fatalf("unexpected export bundle %v", files)
+	}
+}
+
+func TestDeliveryHistory(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.channels[channel.WhatsApp] = &recordingSender{}
+	b.channels[channel.Voice] = &recordingSender{}
+	b.channels[channel.Email] = senderFunc(func(context.Context, channel.Message) error {
+		return errors.New("smtp down")
+	})
+	reminders := []model.Reminder{{UserID: "15551234567", Content: "Renew passport", Priority: 5}}
+	seedReminders(t, b, reminders)
+	if err := b.updatePreferences("15551234567", func(p *model.UserPreference) { p.CallAlerts = true }); err != nil {
+		t.Fatalf("update preferences: %v", err)
+	}
+
+	b.deliver(&pendingSend{UserID: "15551234567", ReminderID: reminders[0].ID, Body: "Reminder: Renew passport"})
+	if err := b.sendDigest("me@example.com", reminders, time.Now()); err == nil {
+		t.Fatalf("expected the digest to fail")
+	}
+
+	var deliveries []model.ReminderDelivery
+	b.db.Order("id").Find(&deliveries)
+	var got []string
+	for _, d := range deliveries {
+		got = append(got, d.Channel+":"+d.Status)
+	}
+	if want := []string{"whatsapp:sent", "voice:sent", "email:failed"}; !slices.Equal(got, want) {
+		t.Fatalf("expected deliveries %v, got %v", want, got)
+	}
+
+	msg, ok := b.handleShowCommand(context.Background(), "15551234567", "show R1")
+	if !ok || !containsAll(msg, []string{"Reminded:", "by email, failed", "by phone call, sent", "on WhatsApp, sent"}) {
+		t.Fatalf("expected the delivery history in the detail view, got %q", msg)
+	}
+}
+
+func TestInsights(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	now := time.Now()
+	var reminders []model.Reminder
+	for i, daysAgo := range []int{7, 7, 7, 7, 8, 8, 9} {
+		done := now.AddDate(0, 0, -daysAgo)
+		reminders = append(reminders, model.Reminder{UserID: "user", Content: "Done", Priority: 3, CreatedAt: done.Add(-time.Duration(i+1) * time.Hour), CompletedAt: &done})
+	}
+	old := now.AddDate(0, 0, -200)
+	due := now.AddDate(0, 0, 2)
+	reminders = append(reminders,
+		model.Reminder{UserID: "user", Content: "Ancient", Priority: 3, CreatedAt: old.Add(-time.Hour), CompletedAt: &old},
+		model.Reminder{UserID: "user", Kind: model.KindHabit, Content: "Meditate", Priority: 3, CreatedAt: now.AddDate(0, 0, -3), CompletedAt: &now},
+		model.Reminder{UserID: "user", Content: "Call the bank", Summary: "Call the bank", Priority: 4, Snoozes: 4, CreatedAt: now.AddDate(0, 0, -20), DueAt: &due},
+		model.Reminder{UserID: "user", Content: "Renew passport", Priority: 2, Snoozes: 1, CreatedAt: now.AddDate(0, 0, -5), DueAt: &due},
+		model.Reminder{UserID: "user", Content: "Water plants", Priority: 2, CreatedAt: now.AddDate(0, 0, -1)},
+	)
+	seedReminders(t, b, reminders)
+
+	in, err := b.insights(context.Background(), "user", now)
+	if err != nil {
+		t.Fatalf("insights: %v", err)
+	}
+	best := now.AddDate(0, 0, -7).In(b.cfg.LocalTimezone).Weekday().String()
+	if in.Completed != 7 || in.Open != 3 || in.MedianHoursToComplete == nil || *in.MedianHoursToComplete != 4 ||
+		in.BestDay != best || in.WorstDay == "" || in.CompletedByWeekday[in.WorstDay] != 0 {
+		t.Fatalf("unexpected insights %+v", in)
+	}
+	if len(in.MostSnoozed) != 2 || in.MostSnoozed[0].Code != "R10" || in.MostSnoozed[0].Snoozes != 4 || in.MostSnoozed[0].DaysOpen != 20 {
+		t.Fatalf("unexpected most snoozed %+v", in.MostSnoozed)
+	}
+
+	msg, ok := b.handleInsightsCommand(context.Background(), "user", "my stats")
+	if !ok || !containsAll(msg, []string{"last 90 days: 7. Still open: 3.", "4 hours after adding it", "most done on " + best + "s (4)", "Put off 4 times: R10 Call the bank (open 20 days)"}) {
+		t.Fatalf("unexpected stats reply %q", msg)
+	}
+	if msg, _ := b.handleInsightsCommand(context.Background(), "nobody", "stats"); !strings.Contains(msg, "No stats yet") {
+		t.Fatalf("expected an empty-stats reply, got %q", msg)
+	}
+
+	session := &model.Session{TokenHash: hashToken("session-token"), UserID: "user", ExpiresAt: now.Add(time.Hour)}
+	if err := b.db.Create(session).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	patch := httptest.NewRequest(http.MethodPatch, "/api/reminders/11", strings.NewReader(`{"due_at":"`+due.AddDate(0, 0, 3).Format(time.DateOnly)+`"}`))
+	patch.Header.Set("Content-Type", "application/json")
+	patch.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "session-token"})
+	rec := httptest.NewRecorder()
+	b.APIHandler().ServeHTTP(rec, patch)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/insights", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "session-token"})
+	rec = httptest.NewRecorder()
+	b.APIHandler().ServeHTTP(rec, req)
+	var got Insights
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&got) != nil {
+		t.Fatalf("unexpected insights response %d %s", rec.Code, rec.Body.String())
+	}
+	if got.Completed != 7 || len(got.MostSnoozed) != 2 || got.MostSnoozed[1].Code != "R11" || got.MostSnoozed[1].Snoozes != 2 {
+		t.Fatalf("expected the later due date to count as a snooze, got %+v", got)
+	}
+}
+
+func TestSkipHabitKeepsStreak(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+	yesterday := now.AddDate(0, 0, -1)
+	lapsed := now.AddDate(0, 0, -3)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "user", Kind: model.KindHabit, Content: "Meditate", Priority: 3, Streak: 4, LastCheckIn: &yesterday},
+		{UserID: "user", Kind: model.KindHabit, Content: "Stretch", Priority: 3, Streak: 9, LastCheckIn: &lapsed},
+		{UserID: "user", Content: "Pay rent", Priority: 4},
+	})
+
+	if selector, ok := parseSkipRequest("Not today."); !ok || selector != "" {
+		t.Fatalf("expected a bare skip request, got %q %v", selector, ok)
+	}
+	if _, err := b.skipHabit(ctx, "user", ""); err == nil || !strings.Contains(err.Error(), "Which habit") {
+		t.Fatalf("expected a question about which habit, got %v", err)
+	}
+	if _, err := b.skipHabit(ctx, "user", "R3"); err == nil || !strings.Contains(err.Error(), "isn't a daily habit") {
+		t.Fatalf("expected a one-off reminder to be refused, got %v", err)
+	}
+	msg, err := b.skipHabit(ctx, "user", "meditate")
+	if err != nil || !strings.Contains(msg, "Day 4 streak is safe") {
+		t.Fatalf("skip meditate: %q %v", msg, err)
+	}
+	if msg, err := b.skipHabit(ctx, "user", "R1"); err != nil || !strings.Contains(msg, "already skipped") {
+		t.Fatalf("expected a second skip to be a no-op, got %q %v", msg, err)
+	}
+	if msg, err := b.skipHabit(ctx, "user", ""); err != nil || !strings.Contains(msg, "fresh start") {
+		t.Fatalf("expected the lapsed habit to be picked and reset, got %q %v", msg, err)
+	}
+
+	var meditate, stretch model.Reminder
+	b.db.First(&meditate, 1)
+	b.db.First(&stretch, 2)
+	if meditate.Skips != 1 || currentStreak(meditate, now) != 4 || stretch.Streak != 0 || currentStreak(stretch, now) != 0 {
+		t.Fatalf("unexpected habits after skipping: %+v %+v", meditate, stretch)
+	}
+	if !applyCheckIn(&meditate, now.AddDate(0, 0, 1)) || meditate.Streak != 5 {
+		t.Fatalf("expected a check-in after the skipped day to extend the streak, got %d", meditate.Streak)
+	}
+	if msg, ok := b.handleShowCommand(ctx, "user", "show R1"); !ok || !strings.Contains(msg, "1 day skipped on purpose") {
+		t.Fatalf("expected the skip count in the detail view, got %q", msg)
+	}
+}
+
+func TestTimeOfDayBuckets(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	for content, want := range map[string]string{
+		"Remind me in the evening to water plants": model.TimeEvening,
+		"call mum tonight":                         model.TimeEvening,
+		"stretch every Morning":                    model.TimeMorning,
+		"pay the morning newspaper bill":           "",
+	} {
+		if got := parseTimeOfDay(content); got != want {
+			t.Fatalf("parseTimeOfDay(%q) = %q, want %q", content, got, want)
+		}
+	}
+	if bucket, hour, ok := parseBucketRequest("set evening to 8pm"); !ok || bucket != model.TimeEvening || hour != 20 {
+		t.Fatalf("unexpected bucket request %q %d %v", bucket, hour, ok)
+	}
+	if bucket, hour, ok := parseBucketRequest("afternoon at 3"); !ok || bucket != model.TimeAfternoon || hour != 15 {
+		t.Fatalf("expected a bare afternoon hour to mean pm, got %q %d %v", bucket, hour, ok)
+	}
+	if msg, ok := b.handleSettingsCommand("user", "morning at 2"); !ok || !strings.Contains(msg, "between 4am and 11am") {
+		t.Fatalf("expected an out-of-range morning to be refused, got %q", msg)
+	}
+	if msg, ok := b.handleSettingsCommand("user", "set evening to 8pm"); !ok || !strings.Contains(msg, "evening reminders at 8pm") {
+		t.Fatalf("unexpected reply %q", msg)
+	}
+
+	if msg := b.addReminder(context.Background(), "user", "Remind me in the evening to water plants", 3); !strings.Contains(msg, "in the evening") {
+		t.Fatalf("expected the time of day in the reply, got %q", msg)
+	}
+	var saved model.Reminder
+	if err := b.db.Where("user_id = ?", "user").First(&saved).Error; err != nil || saved.TimeOfDay != model.TimeEvening {
+		t.Fatalf("expected the reminder to be saved for the evening, got %+v %v", saved, err)
+	}
+
+	now := time.Date(2026, time.March, 10, 8, 0, 0, 0, time.UTC)
+	due := []model.Reminder{
+		{ID: 1, TimeOfDay: model.TimeEvening},
+		{ID: 2, TimeOfDay: model.TimeMorning},
+		{ID: 3},
+		{ID: 4, TimeOfDay: model.TimeMorning},
+		{ID: 5},
+	}
+	ordered, slots := dispatchSlots(due, b.preferences("user"), time.Hour, now)
+	var got []string
+	for i, r := range ordered {
+		got = append(got, fmt.Sprintf("%d@%s", r.ID, slots[i].Format("15:04")))
+	}
+	if want := "3@08:00 5@09:00 2@10:00 4@11:00 1@20:00"; strings.Join(got, " ") != want {
+		t.Fatalf("dispatch slots = %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestRepeatingReminder(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+
+	msg := b.addReminder(ctx, "user", "Water the plants every 3 days", 3)
+	if !strings.Contains(msg, "It repeats every 3 days, starting "+startOfDay(now).Format("Mon Jan 2")) {
+		t.Fatalf("unexpected reply %q", msg)
+	}
+	var plants model.Reminder
+	if err := b.db.Where("user_id = ?", "user").First(&plants).Error; err != nil || plants.RRule != "FREQ=DAILY;INTERVAL=3" || !plants.Recurring() {
+		t.Fatalf("expected a repeating reminder, got %+v %v", plants, err)
+	}
+
+	msg, err := b.markDone(ctx, "user", "R1")
+	next := startOfDay(now).AddDate(0, 0, 3)
+	if err != nil || !strings.Contains(msg, "next on "+next.Format("Mon Jan 2")) {
+		t.Fatalf("done: %q %v", msg, err)
+	}
+	b.db.First(&plants, plants.ID)
+	if plants.CompletedAt != nil || plants.DueAt == nil || !plants.DueAt.Equal(next) || dueBy(plants, now) {
+		t.Fatalf("expected the reminder to move to its next occurrence, got %+v", plants)
+	}
+
+	missed := startOfDay(now).AddDate(0, 0, -2)
+	b.db.Model(&plants).Update("due_at", missed)
+	b.rollRecurring(ctx, "user", now)
+	b.db.First(&plants, plants.ID)
+	if want := missed.AddDate(0, 0, 3); !plants.DueAt.Equal(want) {
+		t.Fatalf("expected a missed occurrence to roll to %s, got %s", want, plants.DueAt)
+	}
+	if msg, ok := b.handleShowCommand(ctx, "user", "show R1"); !ok || !strings.Contains(msg, "Repeats every 3 days") {
+		t.Fatalf("expected the rule in the detail view, got %q", msg)
+	}
+}
+
+func TestBusinessDaysReminder(t *testing.T) {
+	t.Parallel()
+	cal, err := holidays.New("US", []time.Time{time.Date(2026, time.December, 24, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	for day, want := range map[string]string{
+		"2026-11-26": "Thanksgiving",
+		"2026-05-25": "Memorial Day",
+		"2027-12-31": "New Year's Day",
+		"2026-12-24": "holiday",
+	} {
+		d, _ := time.Parse(time.DateOnly, day)
+		if name, ok := cal.Holiday(d); !ok || name != want {
+			t.Fatalf("Holiday(%s) = %q %v, want %q", day, name, ok, want)
+		}
+	}
+	if gb, _ := holidays.New("GB", nil); gb.WorkingDay(time.Date(2026, time.April, 3, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected Good Friday 2026 to be a UK bank holiday")
+	}
+	if got := cal.NextWorkingDay(time.Date(2026, time.November, 26, 9, 0, 0, 0, time.UTC)); got.Format(time.DateTime) != "2026-11-27 09:00:00" {
+		t.Fatalf("expected Thanksgiving to roll to Friday, got %s", got)
+	}
+
+	cfg := &config.Config{TwilioAccountSID: "AC123", TwilioAuthToken: "token", TwilioWhatsAppNumber: "+14155238886", Port: "8080", HolidayCountry: "FR", HolidayDates: []string{"24/12/2026"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "HOLIDAY_COUNTRY must be one of DE, GB, IN, US") || !strings.Contains(err.Error(), "HOLIDAY_DATES") {
+		t.Fatalf("expected holiday settings to be checked, got %v", err)
+	}
+
+	b := newTestBot(t)
+	b.cfg.HolidayCountry = "US"
+	msg := b.addReminder(context.Background(), "user", "File timesheets every Thursday, business days only", 3)
+	if !strings.Contains(msg, "skip weekends and public holidays") {
+		t.Fatalf("unexpected reply %q", msg)
+	}
+	var saved model.Reminder
+	if err := b.db.Where("user_id = ?", "user").First(&saved).Error; err != nil || !saved.BusinessDays || saved.DueAt == nil ||
+		!b.calendar().WorkingDay(*saved.DueAt) {
+		t.Fatalf("expected a business-days reminder due on a working day, got %+v %v", saved, err)
+	}
+
+	weekBefore := time.Date(2026, time.November, 19, 0, 0, 0, 0, b.cfg.LocalTimezone)
+	b.db.Model(&saved).Update("due_at", weekBefore)
+	saved.DueAt = &weekBefore
+	if _, err := b.completeOccurrence(context.Background(), &saved, weekBefore.Add(10*time.Hour)); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	b.db.First(&saved, saved.ID)
+	if got := saved.DueAt.In(b.cfg.LocalTimezone).Format(time.DateOnly); got != "2026-11-27" {
+		t.Fatalf("expected Thanksgiving's occurrence to move to Friday, got %s", got)
+	}
+	if msg, ok := b.handleShowCommand(context.Background(), "user", "show R1"); !ok || !strings.Contains(msg, "Business days only") {
+		t.Fatalf("expected the flag in the detail view, got %q", msg)
+	}
+}
+
+func TestBusinessDaysKeepSchedule(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	tz := b.cfg.LocalTimezone
+
+	// Monthly on the 1st; Feb 1 and Mar 1 2026 are both Sundays.
+	jan1 := time.Date(2026, time.January, 1, 9, 0, 0, 0, tz)
+	r := model.Reminder{UserID: "user", Content: "Pay rent", Priority: 3, RRule: "FREQ=MONTHLY", BusinessDays: true, DueAt: &jan1}
+	if err := b.insertReminder(ctx, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(due, occurs string) {
+		t.Helper()
+		var saved model.Reminder
+		b.db.First(&saved, r.ID)
+		gotOccurs := ""
+		if saved.OccursAt != nil {
+			gotOccurs = saved.OccursAt.In(tz).Format(time.DateOnly)
+		}
+		if got := saved.DueAt.In(tz).Format(time.DateOnly); got != due || gotOccurs != occurs {
+			t.Fatalf("got due %s occurring %q, want due %s occurring %q", got, gotOccurs, due, occurs)
+		}
+	}
+	b.rollRecurring(ctx, "user", time.Date(2026, time.January, 5, 8, 0, 0, 0, tz))
+	check("2026-02-02", "2026-02-01")
+	b.rollRecurring(ctx, "user", time.Date(2026, time.February, 5, 8, 0, 0, 0, tz))
+	check("2026-03-02", "2026-03-01")
+
+	var saved model.Reminder
+	b.db.First(&saved, r.ID)
+	msg, err := b.completeOccurrence(ctx, &saved, time.Date(2026, time.March, 2, 10, 0, 0, 0, tz))
+	if err != nil || !strings.Contains(msg, "next on Wed Apr 1") {
+		t.Fatalf("expected the rule to come back to the 1st, got %q %v", msg, err)
+	}
+	check("2026-04-01", "")
+}
+
+func TestBlackoutDates(t *testing.T) {
+	t.Parallel()
+	wednesday := time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC)
+	for phrase, want := range map[string][2]string{
+		"next week":            {"2026-10-19", "2026-10-25"},
+		"this weekend":         {"2026-10-17", "2026-10-18"},
+		"tomorrow":             {"2026-10-15", "2026-10-15"},
+		"until friday":         {"2026-10-14", "2026-10-16"},
+		"from mar 3 to mar 10": {"2027-03-03", "2027-03-10"},
+		"on 2026-12-24":        {"2026-12-24", "2026-12-24"},
+	} {
+		first, last, ok := parseDayRange(phrase, wednesday)
+		if !ok || first.Format(time.DateOnly) != want[0] || last.Format(time.DateOnly) != want[1] {
+			t.Fatalf("%q: got %s to %s (%v), want %v", phrase, first, last, ok, want)
+		}
+	}
+
+	b := newTestBot(t)
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+	msg, ok := b.handleBlackoutCommand(ctx, "user", "i'm traveling tomorrow")
+	resume := startOfDay(now).AddDate(0, 0, 2).Format("Mon Jan 2")
+	if !ok || !strings.Contains(msg, "comes on "+resume) {
+		t.Fatalf("unexpected reply %q %v", msg, ok)
+	}
+	if b.blackedOut(ctx, "user", now) || !b.blackedOut(ctx, "user", now.AddDate(0, 0, 1)) {
+		t.Fatalf("expected only tomorrow to be blocked")
+	}
+	if msg, _ := b.handleBlackoutCommand(ctx, "user", "no reminders from tomorrow to today"); !strings.Contains(msg, "ends before it starts") {
+		t.Fatalf("expected a backwards range to be refused, got %q", msg)
+	}
+	if msg, _ := b.handleBlackoutCommand(ctx, "user", "blackouts"); !strings.Contains(msg, startOfDay(now).AddDate(0, 0, 1).Format("Mon Jan 2")) {
+		t.Fatalf("expected the block in the list, got %q", msg)
+	}
+	if msg, _ := b.handleBlackoutCommand(ctx, "user", "i'm back"); !strings.Contains(msg, "Welcome back") || b.blackedOut(ctx, "user", now.AddDate(0, 0, 1)) {
+		t.Fatalf("expected the block to be cleared, got %q", msg)
+	}
+
+	// An occurrence that fell in a block ending yesterday waits for today.
+	b.addReminder(ctx, "user", "Water the plants every 3 days", 3)
+	b.db.Create(&model.Blackout{UserID: "user", FirstDay: startOfDay(now).AddDate(0, 0, -4), LastDay: startOfDay(now).AddDate(0, 0, -1)})
+	held := startOfDay(now).AddDate(0, 0, -2)
+	b.db.Model(&model.Reminder{}).Where("user_id = ?", "user").Update("due_at", held)
+	b.rollRecurring(ctx, "user", now)
+	var plants model.Reminder
+	b.db.Where("user_id = ?", "user").First(&plants)
+	if !plants.DueAt.Equal(held) || !dueBy(plants, now) {
+		t.Fatalf("expected the held occurrence to stay due, got %s", plants.DueAt)
+	}
+}
+
+func TestTimezoneFollowsUser(t *testing.T) {
+	t.Parallel()
+	if got := ianaCase("america/new_york"); got != "America/New_York" {
+		t.Fatalf("ianaCase: got %q", got)
+	}
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	msg, ok := b.handleTimezoneCommand(ctx, "user", "I'm in London now")
+	if !ok || !strings.Contains(msg, "Europe/London time") || b.preferences("user").Timezone != "Europe/London" {
+		t.Fatalf("unexpected reply %q %v", msg, ok)
+	}
+	if _, ok := b.handleTimezoneCommand(ctx, "user", "I'm now in a meeting"); ok {
+		t.Fatalf("expected an unknown place in chat to fall through")
+	}
+
+	london, _ := time.LoadLocation("Europe/London")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	sendAt := time.Now().Add(48 * time.Hour).Truncate(time.Minute)
+	send := &pendingSend{UserID: "user", ReminderID: 1, Body: "Reminder", SendAt: sendAt}
+	b.sends.Schedule(send, func(*pendingSend) {})
+	msg, _ = b.handleTimezoneCommand(ctx, "user", "set timezone asia/tokyo")
+	if !strings.Contains(msg, "moved 1 waiting reminder ") {
+		t.Fatalf("expected the waiting send to move, got %q", msg)
+	}
+	pending := b.sends.Snapshot()
+	if len(pending) != 1 || pending[0].SendAt.In(tokyo).Format("15:04") != sendAt.In(london).Format("15:04") {
+		t.Fatalf("expected the send to keep its time of day, got %+v", pending)
+	}
+	b.sends.Drain(ctx)
+
+	if note := b.followPin(ctx, "user", sharedLocation{Latitude: 35.68, Longitude: 139.69}); note != "" {
+		t.Fatalf("expected a pin in the same timezone to change nothing, got %q", note)
+	}
+	if note := b.followPin(ctx, "user", sharedLocation{Latitude: 51.5, Longitude: -0.12}); !strings.Contains(note, "UTC time") {
+		t.Fatalf("expected a pin far away to move the user, got %q", note)
+	}
+}
+
+func TestRebalancePriorities(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	reminders := []model.Reminder{
+		{UserID: "user", Content: "Tidy the garage", Priority: 5},
+		{UserID: "user", Content: "Pay rent", Priority: 5},
+	}
+	seedReminders(t, b, reminders)
+	if msg, _ := b.handleRebalanceCommand(ctx, "user", "rebalance"); !strings.Contains(msg, "needs OpenAI") {
+		t.Fatalf("expected rebalancing to need OpenAI, got %q", msg)
+	}
+
+	open, _ := b.openReminders(ctx, "user")
+	changes, lines := rebalanceChanges(open, []myopenai.PriorityChange{
+		{Code: "r1", Priority: 2, Reason: "No deadline."},
+		{Code: "R2", Priority: 5, Reason: "unchanged"},
+		{Code: "R9", Priority: 1},
+		{Code: "R1", Priority: 7},
+	})
+	if len(changes) != 1 || changes[0].Priority != 2 || len(lines) != 1 || lines[0] != "- R1 Tidy the garage: 5 → 2 (No deadline)" {
+		t.Fatalf("expected only the real change to be kept, got %+v %q", changes, lines)
+	}
+
+	b.state.SetRebalance("user", changes)
+	if msg := b.reply(ctx, "user", "no"); !strings.Contains(msg, "stay as they are") {
+		t.Fatalf("unexpected reply to no: %q", msg)
+	}
+	b.state.SetRebalance("user", changes)
+	if msg := b.reply(ctx, "user", "yes"); !strings.Contains(msg, "changed the priority of 1 reminder") {
+		t.Fatalf("unexpected reply to yes: %q", msg)
+	}
+	var garage model.Reminder
+	b.db.First(&garage, changes[0].ReminderID)
+	if garage.Priority != 2 {
+		t.Fatalf("expected the new priority after confirming, got %d", garage.Priority)
+	}
+}
+
+func TestCorrectionAfterSave(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+
+	b.addReminder(ctx, "user", "Call the plumber", 3)
+	if msg := b.reply(ctx, "user", "No, change priority to 2"); !strings.Contains(msg, "R1 Call the plumber is now priority 2") {
+		t.Fatalf("unexpected reply %q", msg)
+	}
+	if msg := b.reply(ctx, "user", "actually make it tomorrow"); !strings.Contains(msg, "due "+startOfDay(now).AddDate(0, 0, 1).Format("Mon Jan 2")) {
+		t.Fatalf("unexpected reply %q", msg)
+	}
+	if msg := b.reply(ctx, "user", "make it evening"); !strings.Contains(msg, "sent in the evening") {
+		t.Fatalf("unexpected reply %q", msg)
+	}
+	var plumber model.Reminder
+	b.db.Where("user_id = ?", "user").First(&plumber)
+	if plumber.Priority != 2 || plumber.DueAt == nil || plumber.TimeOfDay != model.TimeEvening {
+		t.Fatalf("expected the corrections on the saved reminder, got %+v", plumber)
+	}
+	var count int64
+	if b.db.Model(&model.Reminder{}).Where("user_id = ?", "user").Count(&count); count != 1 {
+		t.Fatalf("expected corrections not to save new reminders, got %d", count)
+	}
+	if msg := b.reply(ctx, "user", "undo"); !strings.Contains(msg, "Undone") {
+		t.Fatalf("unexpected reply to undo %q", msg)
+	}
+
+	b.addReminder(ctx, "user", "Renew passport", 4)
+	b.state.SetJustSaved("user", justSaved{ReminderID: 2, At: time.Now().Add(-correctionWindow - time.Minute)})
+	if _, ok := b.handleCorrection(ctx, "user", "change priority to 1"); ok {
+		t.Fatalf("expected no correction once the window has passed")
+	}
+}
+
+func TestConversationStateExpires(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+
+	b.state.SetPendingMessage("user", "Book the dentist")
+	b.state.SetPendingLocation("other", sharedLocation{Latitude: 1, Longitude: 2})
+	if !b.state.IsAwaitingPriority("user") {
+		t.Fatalf("expected a fresh question to be waiting")
+	}
+	for _, userID := range []string{"user", "other"} {
+		state := b.state.state[userID]
+		state.At = time.Now().Add(-conversationTTL - time.Minute)
+		b.state.state[userID] = state
+	}
+	if b.state.IsAwaitingPriority("user") {
+		t.Fatalf("expected an expired question to be ignored before the sweep")
+	}
+
+	b.sweepConversations()
+	if b.state.Len() != 0 {
+		t.Fatalf("expected the sweep to drop expired state, %d left", b.state.Len())
+	}
+	if len(whatsapp.messages) != 1 || whatsapp.messages[0].To != "user" || !strings.Contains(whatsapp.messages[0].Body, "discarded your unsaved reminder 'Book the dentist'") {
+		t.Fatalf("expected one expiry notice for the unsaved reminder, got %+v", whatsapp.messages)
+	}
+}
+
+func TestPriorityPromptReaskLimit(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	b.state.SetPendingMessage("user", "Book the dentist")
+	if msg := b.reply(ctx, "user", "soon"); !strings.Contains(msg, "Please send a priority") {
+		t.Fatalf("unexpected first re-ask %q", msg)
+	}
+	if msg := b.reply(ctx, "user", "whenever"); !strings.Contains(msg, "Reply 'save' to keep it at priority 3, or 'cancel'") {
+		t.Fatalf("expected a way out after two misses, got %q", msg)
+	}
+	if msg := b.reply(ctx, "user", "Save"); !strings.Contains(msg, "(priority 3)") {
+		t.Fatalf("expected the reminder saved at the default priority, got %q", msg)
+	}
+
+	b.state.SetPendingMessage("user", "Water the plants")
+	b.reply(ctx, "user", "hmm")
+	b.reply(ctx, "user", "hmm")
+	if msg := b.reply(ctx, "user", "cancel"); !strings.Contains(msg, "dropped your unsaved reminder") || b.state.IsAwaitingPriority("user") {
+		t.Fatalf("expected the reminder to be dropped, got %q", msg)
+	}
+	var count int64
+	if b.db.Model(&model.Reminder{}).Where("user_id = ?", "user").Count(&count); count != 1 {
+		t.Fatalf("expected only the saved reminder, got %d", count)
+	}
+}
+
+func TestCancelPendingFlow(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	if msg := b.reply(ctx, "user", "cancel"); msg != "There's nothing to cancel." {
+		t.Fatalf("unexpected reply with nothing pending %q", msg)
+	}
+	b.state.SetPendingMessage("user", "Book the dentist")
+	if msg := b.reply(ctx, "user", "Never mind"); !strings.Contains(msg, "dropped your unsaved reminder 'Book the dentist'") || b.state.IsAwaitingPriority("user") {
+		t.Fatalf("expected the priority question to be dropped, got %q", msg)
+	}
+	b.state.SetPendingDelete("user", []uint{1, 2})
+	if msg := b.reply(ctx, "user", "cancel."); !strings.Contains(msg, "won't delete anything") {
+		t.Fatalf("expected the delete choice to be dropped, got %q", msg)
+	}
+	b.state.SetRebalance("user", []priorityChange{{ReminderID: 1, Priority: 2}})
+	if msg := b.reply(ctx, "user", "nvm"); !strings.Contains(msg, "priorities stay as they are") || b.state.Len() != 0 {
+		t.Fatalf("expected the rebalance to be dropped, got %q", msg)
+	}
+}
+
+func TestContextualHelp(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	if msg := b.reply(ctx, "user", "help"); !strings.Contains(msg, "You can say things like") {
+		t.Fatalf("expected the general help, got %q", msg)
+	}
+	if msg := b.reply(ctx, "user", "help recurring"); !strings.Contains(msg, "Repeating reminders:") {
+		t.Fatalf("expected the recurring topic, got %q", msg)
+	}
+	if msg := b.reply(ctx, "user", "Help with deleting"); !strings.Contains(msg, "Deleting reminders:") {
+		t.Fatalf("expected the delete topic, got %q", msg)
+	}
+	if msg := b.reply(ctx, "user", "help calendars"); !strings.Contains(msg, "help delete, help habits") {
+		t.Fatalf("expected the topics to be listed, got %q", msg)
+	}
+	if _, ok := b.handleHelpCommand("user", "help me remember to call mum"); ok {
+		t.Fatalf("expected a reminder starting with help to fall through")
+	}
+
+	b.state.SetPendingMessage("user", "Book the dentist")
+	if msg := b.reply(ctx, "user", "help"); !strings.Contains(msg, "'Book the dentist' and need its priority") || !b.state.IsAwaitingPriority("user") {
+		t.Fatalf("expected help on the priority question, got %q", msg)
+	}
+	b.state.SetPendingDelete("user", []uint{1, 2})
+	if msg := b.reply(ctx, "user", "?"); !strings.Contains(msg, "Several reminders matched") {
+		t.Fatalf("expected help on the delete choice, got %q", msg)
+	}
+}
+
+func TestReactionQuickActions(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.TwilioStatusCallbacks = true
+	b.cfg.PublicBaseURL = "https://memo.example"
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	due := time.Now().Add(-24 * time.Hour)
+	reminders := []model.Reminder{
+		{UserID: "15551234567", Content: "Renew passport", Priority: 5},
+		{UserID: "15551234567", Content: "Call the bank", Priority: 3, DueAt: &due},
+		{UserID: "15551234567", Content: "Water plants", Priority: 2},
+	}
+	seedReminders(t, b, reminders)
+	ctx := context.Background()
+	for i, r := range reminders {
+		b.deliver(&pendingSend{UserID: r.UserID, ReminderID: r.ID, Body: "Reminder: " + r.Content})
+		u, err := url.Parse(whatsapp.messages[i].StatusCallback)
+		if err != nil {
+			t.Fatalf("parse callback: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, u.RequestURI(), strings.NewReader(fmt.Sprintf("MessageStatus=delivered&MessageSid=SM%d", i+1)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		b.StatusCallbackHandler().ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if _, ok := b.handleReaction(ctx, "15551234567", "thanks", "SM1"); ok {
+		t.Fatalf("expected a plain reply not to be taken as a reaction")
+	}
+	if msg, ok := b.handleReaction(ctx, "15551234567", "👍🏽", "SM1"); !ok || !strings.Contains(plainText(msg), "Marked 'Renew passport' as done") {
+		t.Fatalf("expected the thumbs up to complete the reminder, got %q", msg)
+	}
+	if msg, _ := b.handleReaction(ctx, "15551234567", "👍", "SM1"); !strings.Contains(msg, "already done or gone") {
+		t.Fatalf("expected a second reaction to find nothing to do, got %q", msg)
+	}
+
+	if msg, ok := b.handleReaction(ctx, "15551234567", "🔁", "SM2"); !ok || !strings.Contains(msg, "Snoozed 'Call the bank' until tomorrow") {
+		t.Fatalf("expected the repeat emoji to snooze the reminder, got %q", msg)
+	}
+	var snoozed model.Reminder
+	b.db.First(&snoozed, reminders[1].ID)
+	tomorrow := startOfDay(time.Now().In(b.cfg.LocalTimezone)).AddDate(0, 0, 1)
+	if snoozed.Snoozes != 1 || snoozed.DueAt == nil || snoozed.DueAt.Before(tomorrow) || snoozed.SnoozedUntil == nil {
+		t.Fatalf("expected the overdue reminder to move to tomorrow, got %+v", snoozed)
+	}
+
+	if msg, ok := b.handleReaction(ctx, "15551234567", "❌", ""); !ok || !strings.Contains(msg, "Deleted 'Water plants'") {
+		t.Fatalf("expected a bare cross to delete the last reminder sent, got %q", msg)
+	}
+	if msg, ok := b.handleReaction(ctx, "15551234567", "❌", "SM404"); !ok || !strings.Contains(msg, "only act on reactions to reminder messages") {
+		t.Fatalf("expected a reaction to an unknown message to be refused, got %q", msg)
+	}
+
+	b.dispatchUserReminders("15551234567")
+	defer b.sends.Drain(context.Background())
+	if b.sends.Len() != 0 {
+		t.Fatalf("expected the snoozed reminder to stay out of today's dispatch, got %d sends", b.sends.Len())
+	}
+}
+
+func TestChannelFormatting(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	whatsapp, email := &recordingSender{}, &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+	b.channels[channel.Email] = email
+	reminders := []model.Reminder{{UserID: "+15551234567", Content: "Pay rent *now*", Priority: 4}}
+	seedReminders(t, b, reminders)
+
+	if got := renderFormatting(bold("Pay rent")+" "+strike("gym")+" "+mono("1."), channel.Discord); got != "**Pay rent** ~~gym~~ `1.`" {
+		t.Fatalf("unexpected Discord markup: %q", got)
+	}
+
+	list := func(from string) string {
+		form := url.Values{"From": {from}, "Body": {"list my reminders"}}
+		req := httptest.NewRequest(http.MethodPost, "/twilio/webhook", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		b.handleIncomingMessage(rec, req)
+		return rec.Body.String()
+	}
+	if got := list("whatsapp:+15551234567"); !strings.Contains(got, "```1.``` R1 [4] *Pay rent *now**") {
+		t.Fatalf("expected WhatsApp markdown in the list, got %q", got)
+	}
+	if got := list("+15551234567"); !strings.Contains(got, "1. R1 [4] Pay rent *now* — saved") {
+		t.Fatalf("expected plain text for SMS, got %q", got)
+	}
+
+	message := b.reminderMessage(reminders[0], time.Now())
+	if err := b.send(context.Background(), channel.WhatsApp, channel.Message{To: "+15551234567", Body: message}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if err := b.send(context.Background(), channel.Email, channel.Message{To: "me@example.com", Subject: bold("Reminder"), Body: message}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got := whatsapp.messages[0].Body; got != "Reminder: *Pay rent *now** (priority 4)" {
+		t.Fatalf("unexpected WhatsApp reminder: %q", got)
+	}
+	if got := email.messages[0]; got.Body != "Reminder: Pay rent *now* (priority 4)" || got.Subject != "Reminder" {
+		t.Fatalf("expected a plain email, got %+v", got)
+	}
+}
+
+func TestQuotedReplyResolvesReminder(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	reminders := []model.Reminder{
+		{UserID: "+15551234567", Content: "Renew passport", Priority: 5},
+		{UserID: "+15551234567", Content: "Call the bank", Priority: 3},
+		{UserID: "+15551234567", Content: "Water plants", Priority: 2},
+	}
+	seedReminders(t, b, reminders)
+	for i, r := range reminders {
+		b.db.Create(&model.ReminderDelivery{ReminderID: r.ID, UserID: r.UserID, Channel: channel.WhatsApp, Status: model.DeliveryDelivered, MessageSID: fmt.Sprintf("SM%d", i+1)})
+	}
+	post := func(body, sid string) string {
+		form := url.Values{"From": {"whatsapp:+15551234567"}, "Body": {body}, "OriginalRepliedMessageSid": {sid}}
+		req := httptest.NewRequest(http.MethodPost, "/twilio/webhook", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		b.handleIncomingMessage(rec, req)
+		return rec.Body.String()
+	}
+
+	if got := post("Done", "SM2"); !strings.Contains(got, "Marked &#39;~Call the bank~&#39; as done") {
+		t.Fatalf("expected the quoted reminder to be completed, got %q", got)
+	}
+	if got := post("delete this", "SM3"); !strings.Contains(got, "Water plants") || !strings.Contains(got, "Deleted") {
+		t.Fatalf("expected the quoted reminder to be deleted, got %q", got)
+	}
+	var open []model.Reminder
+	b.db.Where("completed_at IS NULL").Find(&open)
+	if len(open) != 1 || open[0].Content != "Renew passport" {
+		t.Fatalf("expected only the unquoted reminder to be left, got %+v", open)
+	}
+	if got := post("delete this", "SM9"); strings.Contains(got, "Deleted") {
+		t.Fatalf("expected a reply quoting an unknown message not to delete anything, got %q", got)
+	}
+}
+
+func TestProfileSettings(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+
+	profile := b.respond(ctx, "user", "My settings")
+	if !containsAll(profile, []string{"Timezone:", "Delivery time: at 12:56pm, when the daily run starts", "Language: English", "Digest: off", "Quiet hours: off"}) || strings.Contains(profile, "Smart features") {
+		t.Fatalf("unexpected default settings: %q", profile)
+	}
+
+	if msg := b.respond(ctx, "user", "set delivery time 7am"); !strings.Contains(msg, "still go out then rather than at 7am") {
+		t.Fatalf("expected a delivery time before the run to be explained, got %q", msg)
+	}
+	if msg := b.respond(ctx, "user", "set delivery time 6pm"); !strings.Contains(msg, "start going out at 6pm") {
+		t.Fatalf("unexpected delivery time reply: %q", msg)
+	}
+	if msg := b.respond(ctx, "user", "quiet hours 9pm to 9pm"); !strings.Contains(msg, "different times") {
+		t.Fatalf("expected empty quiet hours to be refused, got %q", msg)
+	}
+	if msg := b.respond(ctx, "user", "quiet hours 10pm to 7am"); !strings.Contains(msg, "No reminders from 10pm to 7am") {
+		t.Fatalf("unexpected quiet hours reply: %q", msg)
+	}
+
+	tenant := model.Tenant{Name: "Acme", WhatsAppNumber: "+14155550100", OpenAIMonthlyLimit: 500}
+	b.db.Create(&tenant)
+	b.db.Create(&model.TenantUser{UserID: "user", TenantID: tenant.ID})
+	b.db.Create(&model.OpenAIUsage{TenantID: tenant.ID, Month: time.Now().In(b.cfg.LocalTimezone).Format("2006-01"), Calls: 12})
+	profile = b.respond(ctx, "user", "settings")
+	if !containsAll(profile, []string{"Delivery time: from 6pm", "Quiet hours: 10pm to 7am", "12 of 500 requests used by Acme"}) {
+		t.Fatalf("unexpected settings after changes: %q", profile)
+	}
+
+	pref := b.preferences("user")
+	now := time.Date(2026, time.March, 10, 12, 56, 0, 0, time.UTC)
+	due := []model.Reminder{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}
+	ordered, slots := dispatchSlots(due, pref, time.Hour, now)
+	var got []string
+	for i, r := range ordered {
+		got = append(got, fmt.Sprintf("%d@%s", r.ID, slots[i].Format("15:04")))
+	}
+	if want := "1@18:00 2@19:00 3@20:00 4@21:00"; strings.Join(got, " ") != want {
+		t.Fatalf("dispatch slots = %s, want %s", strings.Join(got, " "), want)
+	}
+
+	lunch, end := 12, 14
+	pref.DeliveryHour, pref.QuietStart, pref.QuietEnd = nil, &lunch, &end
+	ordered, slots = dispatchSlots(due[:2], pref, time.Hour, now)
+	if len(ordered) != 2 || slots[0].Format("15:04") != "14:00" || slots[1].Format("15:04") != "15:00" {
+		t.Fatalf("expected sends in quiet hours to wait for them to end, got %v", slots)
+	}
+}
+
+func TestAdminWhatsAppCommands(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.AdminNumbers = []string{"+15550000001"}
+	ctx := withSignedTwilio(context.Background())
+
+	seedReminders(t, b, []model.Reminder{
+		{UserID: "+15551112222", Content: "pay rent", Priority: 3},
+		{UserID: "+15551112222", Content: "call mum", Priority: 3},
+		{UserID: "+15553334444", Content: "water plants", Priority: 3},
+	})
+	if _, err := b.setPaused("+15553334444", true); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+
+	if msg := b.respond(ctx, "+15551112222", "admin stats"); strings.Contains(msg, "Bot stats") {
+		t.Fatalf("expected admin commands to be refused to other users, got %q", msg)
+	}
+
+	stats := b.respond(ctx, "whatsapp:+15550000001", "Admin stats")
+	if !containsAll(stats, []string{"Users: 2 (1 paused, 0 opted out)", "Reminders: 3 open, 0 done", "Sends waiting: 0"}) {
+		t.Fatalf("unexpected stats: %q", stats)
+	}
+	users := b.respond(ctx, "+15550000001", "admin users")
+	if !containsAll(users, []string{"2 users:", "+15551112222: 2 open, 0 done", "+15553334444: 1 open, 0 done, paused"}) {
+		t.Fatalf("unexpected users: %q", users)
+	}
+	if msg := b.respond(ctx, "+15550000001", "admin dispatch +1 555 999 0000"); !strings.Contains(msg, "+15559990000 has no open reminders") {
+		t.Fatalf("unexpected dispatch to unknown user: %q", msg)
+	}
+
+	if msg := b.respond(ctx, "+15550000001", "admin pause-all"); !strings.Contains(msg, "Paused reminders for 1 user.") {
+		t.Fatalf("unexpected pause-all reply: %q", msg)
+	}
+	if !b.preferences("+15551112222").Paused {
+		t.Fatal("expected pause-all to pause the user")
+	}
+	if msg := b.respond(ctx, "+15550000001", "admin dispatch +15551112222"); !strings.Contains(msg, "paused reminders, so nothing was sent") {
+		t.Fatalf("expected dispatch to a paused user to be refused, got %q", msg)
+	}
+	if msg := b.respond(ctx, "+15550000001", "admin resume-all"); !strings.Contains(msg, "Resumed reminders for 1 user.") {
+		t.Fatalf("unexpected resume-all reply: %q", msg)
+	}
+	if b.preferences("+15551112222").Paused || !b.preferences("+15553334444").Paused {
+		t.Fatal("expected resume-all to undo only the admin's pause")
+	}
+}
+
+func TestTwilioWebhookSignature(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.TwilioAuthToken = "auth-token"
+	b.cfg.PublicBaseURL = "https://memo.example.com"
+	b.cfg.AdminNumbers = []string{"+15550000001"}
+
+	// Twilio's documented example of a request signature.
+	example := url.Values{"CallSid": {"CA1234567890ABCDE"}, "Caller": {"+12349013030"}, "Digits": {"1234"}, "From": {"+12349013030"}, "To": {"+18005551212"}}
+	if got := twilio.Signature("12345", "https://mycompany.com/myapp.php?foo=1&bar=2", example); got != "0/KCTR6DLpKmkAf8muzZqo1nDgQ=" {
+		t.Fatalf("Signature = %q, want Twilio's example", got)
+	}
+
+	post := func(form url.Values, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/twilio/webhook", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if signature != "" {
+			req.Header.Set(twilio.SignatureHeader, signature)
+		}
+		rec := httptest.NewRecorder()
+		b.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+	form := url.Values{"From": {"whatsapp:+15550000001"}, "Body": {"admin stats"}}
+	signature := twilio.Signature("auth-token", "https://memo.example.com/twilio/webhook", form)
+
+	if rec := post(form, ""); rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "Bot stats") {
+		t.Fatalf("expected an unsigned request to be refused, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := post(form, twilio.Signature("wrong-token", "https://memo.example.com/twilio/webhook", form)); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a request signed with another token to be refused, got %d", rec.Code)
+	}
+	forged := url.Values{"From": {"whatsapp:+15550000001"}, "Body": {"admin pause-all"}}
+	if rec := post(forged, signature); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a tampered request to be refused, got %d", rec.Code)
+	}
+	if rec := post(form, signature); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Bot stats") {
+		t.Fatalf("expected a signed admin request to be answered, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Admin commands need a signed webhook, not just an admin's number.
+	if msg := b.respond(context.Background(), "+15550000001", "admin stats"); strings.Contains(msg, "Bot stats") {
+		t.Fatalf("expected admin commands to need a signed request, got %q", msg)
+	}
+}
+
+func TestTipsAndAnnouncements(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	sender := &recordingSender{}
+	b.channels[channel.WhatsApp] = sender
+	b.cfg.AdminNumbers = []string{"+15550000001"}
+	ctx := withSignedTwilio(context.Background())
+
+	b.sendTips()
+	if len(sender.messages) != 0 {
+		t.Fatalf("expected no tips before opting in, got %v", sender.messages)
+	}
+	if msg := b.respond(ctx, "+15551112222", "tips on"); !strings.Contains(msg, "Say 'tips off'") {
+		t.Fatalf("unexpected tips on reply: %q", msg)
+	}
+	seedReminders(t, b, []model.Reminder{{UserID: "+15551112222", Content: "meditate", Priority: 3, Kind: model.KindHabit}})
+
+	b.sendTips()
+	b.sendTips()
+	if len(sender.messages) != 1 || !strings.Contains(sender.messages[0].Body, "Tip: \"Water the plants every 3 days\"") {
+		t.Fatalf("expected one tip skipping habits, got %v", sender.messages)
+	}
+	pref := b.preferences("+15551112222")
+	if pref.TipsSent != "recurring" {
+		t.Fatalf("expected the tip to be recorded, got %q", pref.TipsSent)
+	}
+	week := time.Now().Add(-tipInterval)
+	b.updatePreferences("+15551112222", func(p *model.UserPreference) { p.TipSentAt = &week })
+	b.sendTips()
+	if len(sender.messages) != 2 || !strings.Contains(sender.messages[1].Body, "in the evening") {
+		t.Fatalf("expected the next tip a week later, got %v", sender.messages)
+	}
+
+	if msg := b.respond(ctx, "+15550000001", "admin announce Reminders will be late today."); !strings.Contains(msg, "to 1 subscriber.") {
+		t.Fatalf("unexpected announce reply: %q", msg)
+	}
+	b.sends.Drain(context.Background())
+	if len(sender.messages) != 3 || !strings.Contains(sender.messages[2].Body, "Announcement: Reminders will be late today.") {
+		t.Fatalf("expected the announcement as typed, got %v", sender.messages)
+	}
+
+	b.respond(ctx, "+15551112222", "tips off")
+	if msg := b.respond(ctx, "+15550000001", "admin announce hello"); !strings.Contains(msg, "Nobody has subscribed") {
+		t.Fatalf("expected no subscribers after tips off, got %q", msg)
+	}
+}
+
+func TestFeedbackCommand(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	sender := &recordingSender{}
+	b.channels[channel.WhatsApp] = sender
+	b.cfg.AdminNumbers = []string{"whatsapp:+15550000001"}
+	ctx := withSignedTwilio(context.Background())
+
+	seedReminders(t, b, []model.Reminder{{UserID: "+15551112222", Content: "pay rent", Priority: 3}})
+	b.recent.Add("+15551112222", "list reminders", "1. pay rent", time.Now())
+
+	if msg := b.respond(ctx, "+15551112222", "feedback"); !strings.Contains(msg, "starting with 'feedback:'") {
+		t.Fatalf("expected a prompt for bare feedback, got %q", msg)
+	}
+	msg := b.respond(ctx, "+15551112222", "Feedback: The bot forgot my reminder")
+	if !strings.Contains(msg, "feedback #1") {
+		t.Fatalf("unexpected feedback reply: %q", msg)
+	}
+	var feedback model.Feedback
+	if err := b.db.First(&feedback).Error; err != nil {
+		t.Fatalf("load feedback: %v", err)
+	}
+	if feedback.Text != "The bot forgot my reminder" || feedback.OpenReminders != 1 || !strings.Contains(feedback.RecentMessages, "> list reminders\n< 1. pay rent") {
+		t.Fatalf("unexpected feedback row: %+v", feedback)
+	}
+	if len(sender.messages) != 1 || sender.messages[0].To != "+15550000001" || !strings.Contains(sender.messages[0].Body, "Feedback #1 from +15551112222: The bot forgot my reminder") {
+		t.Fatalf("expected the admin to be told, got %v", sender.messages)
+	}
+	if list := b.respond(ctx, "+15550000001", "admin feedback"); !strings.Contains(list, "#1") || !strings.Contains(list, "The bot forgot my reminder") {
+		t.Fatalf("unexpected admin feedback list: %q", list)
+	}
+}
+
+func TestShareReminderCard(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	sender := &recordingSender{}
+	b.channels[channel.WhatsApp] = sender
+	ctx := context.Background()
+
+	due := time.Date(2026, time.March, 3, 0, 0, 0, 0, time.UTC)
+	seedReminders(t, b, []model.Reminder{{UserID: "+15551112222", Content: "Book the venue for Asha's party", Summary: "Book party venue", Priority: 4, DueAt: &due}})
+
+	if msg := b.respond(ctx, "+15551112222", "share 1 with +15551112222"); !strings.Contains(msg, "your own number") {
+		t.Fatalf("expected sharing with yourself to be refused, got %q", msg)
+	}
+	msg := b.respond(ctx, "+15551112222", "share R1 with +1 (555) 333-4444")
+	if !strings.Contains(msg, "Sent 'Book party venue' to +15553334444") {
+		t.Fatalf("unexpected share reply: %q", msg)
+	}
+	if len(sender.messages) != 1 || sender.messages[0].To != "+15553334444" ||
+		!containsAll(sender.messages[0].Body, []string{"+15551112222 shared a reminder with you", "*Book party venue*", "Due: Tue Mar 3, 2026", "Priority: 4", "Reply 'save'"}) {
+		t.Fatalf("unexpected card: %v", sender.messages)
+	}
+
+	saved := b.respond(ctx, "+15553334444", "Save")
+	if !strings.Contains(saved, "Saved 'Book party venue' to your reminders as R1 (priority 4)") {
+		t.Fatalf("unexpected save reply: %q", saved)
+	}
+	var copy model.Reminder
+	if err := b.db.Where("user_id = ?", "+15553334444").First(&copy).Error; err != nil || copy.Content != "Book the venue for Asha's party" || copy.DueAt == nil {
+		t.Fatalf("expected the copy to be saved, got %+v (%v)", copy, err)
+	}
+	if len(sender.messages) != 2 || !strings.Contains(sender.messages[1].Body, "+15553334444 saved 'Book party venue'") {
+		t.Fatalf("expected the sharer to hear about the save, got %v", sender.messages)
+	}
+
+	b.respond(ctx, "+15553334444", "no shared reminders")
+	if msg := b.respond(ctx, "+15551112222", "share 1 with +15553334444"); !strings.Contains(msg, "isn't accepting shared reminders") {
+		t.Fatalf("expected the refusal to be respected, got %q", msg)
+	}
+}
+
+func TestReminderPlaceholders(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	sender := &recordingSender{}
+	b.channels[channel.WhatsApp] = sender
+
+	now := time.Date(2026, time.March, 9, 10, 0, 0, 0, time.UTC)
+	due := now.AddDate(0, 0, 4)
+	r := model.Reminder{Content: "Water plants, last done {days_since_created} days ago", CreatedAt: now.AddDate(0, 0, -3).Add(5 * time.Hour), DueAt: &due}
+	if !hasPlaceholders(r.Content) || hasPlaceholders("pay {rent}") {
+		t.Fatal("expected only known placeholders to count")
+	}
+	for text, want := range map[string]string{
+		r.Content:                               "Water plants, last done 3 days ago",
+		"Standup on {weekday} {date} at {time}": "Standup on Monday Mon Mar 9 at 10:00am",
+		"{days_until_due} days to go, {rent}":   "4 days to go, {rent}",
+	} {
+		if got := expandPlaceholders(text, r, now); got != want {
+			t.Fatalf("expandPlaceholders(%q) = %q, want %q", text, got, want)
+		}
+	}
+	if got := expandPlaceholders("due in {days_until_due} days", model.Reminder{}, now); got != "due in {days_until_due} days" {
+		t.Fatalf("expected a placeholder without a value to stay, got %q", got)
+	}
+	if summary := b.summarizeReminderWithOpenAI(context.Background(), "user", r.Content); summary != r.Content {
+		t.Fatalf("expected reminders with placeholders to keep their text, got %q", summary)
+	}
+
+	seedReminders(t, b, []model.Reminder{{UserID: "user", Content: "Today is {weekday}", Priority: 3}})
+	var saved model.Reminder
+	b.db.Where("user_id = ?", "user").First(&saved)
+	b.deliver(&pendingSend{UserID: "user", ReminderID: saved.ID, Body: b.reminderMessage(saved, time.Now())})
+	if len(sender.messages) != 1 || !strings.Contains(sender.messages[0].Body, "Today is "+time.Now().Weekday().String()) {
+		t.Fatalf("expected the weekday to be filled in at send time, got %v", sender.messages)
+	}
+}
+
+func TestMediaAttachmentsAndExportLinks(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.TwilioAccountSID, b.cfg.TwilioAuthToken = "AC123", "secret"
+	b.blobs = storage.NewDisk(t.TempDir(), "https://memo.example.com/files", []byte("signing-key"))
+	ctx := context.Background()
+
+	photo := []byte("\x89PNG\r\n\x1a\npassport")
+	var (
+		mu                 sync.Mutex
+		wantSID, wantToken = "AC123", "secret"
+		downloads          int
+	)
+	media := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		downloads++
+		if user, pass, ok := r.BasicAuth(); !ok || user != wantSID || pass != wantToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(photo)
+	}))
+	defer media.Close()
+	// Media comes from api.twilio.com; the test client dials the local
+	// server for it.
+	transport := media.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, media.Listener.Addr().String())
+	}
+	transport.TLSClientConfig.ServerName = "example.com"
+	b.media = &http.Client{Transport: transport}
+	message := func(form url.Values) string {
+		form.Set("From", "whatsapp:+15551234567")
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		b.handleIncomingMessage(rec, req)
+		return rec.Body.String()
+	}
+	download := func(link string) *httptest.ResponseRecorder {
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("parse link %q: %v", link, err)
+		}
+		rec := httptest.NewRecorder()
+		b.FilesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+		return rec
+	}
+	const mediaURL = "https://api.twilio.com/2010-04-01/Accounts/AC123/Messages/MM1/Media/ME1"
+	for _, forged := range []string{media.URL + "/Media/ME1", "http://api.twilio.com/2010-04-01/Media/ME1", "https://api.twilio.com.evil.example/Media/ME1"} {
+		if reply := message(url.Values{"NumMedia": {"1"}, "MediaUrl0": {forged}}); strings.Contains(reply, "Got the file") {
+			t.Fatalf("expected media from %s to be refused, got %q", forged, reply)
+		}
+	}
+	if downloads != 0 {
+		t.Fatalf("expected no credentials to be sent for non-Twilio media, got %d requests", downloads)
+	}
+	file := url.Values{"NumMedia": {"1"}, "MediaUrl0": {mediaURL}, "MediaContentType0": {"image/png"}}
+
+	if reply := message(file); !strings.Contains(reply, "Got the file") {
+		t.Fatalf("expected the file to be held, got %q", reply)
+	}
+	if reply := b.respond(ctx, "+15551234567", "remind me about this: renew passport"); !strings.Contains(reply, "with a link to your file") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	var reminder model.Reminder
+	if err := b.db.Where("user_id = ?", "+15551234567").First(&reminder).Error; err != nil {
+		t.Fatalf("load reminder: %v", err)
+	}
+	if reminder.Content != "renew passport" || reminder.AttachmentType != "image/png" || !strings.HasSuffix(reminder.AttachmentKey, ".png") {
+		t.Fatalf("unexpected reminder: %+v", reminder)
+	}
+
+	msg := b.reminderMessage(reminder, time.Now())
+	link := msg[strings.LastIndex(msg, "\n")+1:]
+	if !strings.HasPrefix(link, "https://memo.example.com/files/media/") {
+		t.Fatalf("expected an attachment link, got %q", msg)
+	}
+	if rec := download(link); rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), photo) || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("unexpected download: %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	if rec := download(strings.Replace(link, "signature=", "signature=0", 1)); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a tampered link to be refused, got %d", rec.Code)
+	}
+	if err := b.blobs.Put(ctx, "media/page.html", []byte("<script>alert(1)</script>"), "text/html"); err != nil {
+		t.Fatalf("put html: %v", err)
+	}
+	page, err := b.blobs.SignedURL(ctx, "media/page.html", time.Hour)
+	if err != nil {
+		t.Fatalf("html link: %v", err)
+	}
+	if rec := download(page); rec.Header().Get("Content-Disposition") != `attachment; filename=page.html` || rec.Header().Get("Content-Security-Policy") != "sandbox" || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("expected an HTML upload to be a sandboxed download, got %v", rec.Header())
+	}
+
+	file.Set("Body", "Insurance card, renew by March")
+	if reply := message(file); !strings.Contains(reply, "Got it!") {
+		t.Fatalf("expected a captioned file to save a reminder, got %q", reply)
+	}
+	if reply := b.respond(ctx, "+15551234567", "attach this file to R1"); !strings.Contains(reply, "Send a photo or file first") {
+		t.Fatalf("expected no file to be waiting, got %q", reply)
+	}
+
+	// A tenant's users' media is fetched with the tenant's own account.
+	tenant := model.Tenant{Name: "Acme", WhatsAppNumber: "+14155550100", TwilioAccountSID: "AC999", TwilioAuthToken: "tenant-secret"}
+	b.db.Create(&tenant)
+	b.db.Create(&model.TenantUser{UserID: "+15551234567", TenantID: tenant.ID})
+	mu.Lock()
+	wantSID, wantToken = "AC999", "tenant-secret"
+	mu.Unlock()
+	file.Del("Body")
+	file.Set("To", "whatsapp:+14155550100")
+	if reply := message(file); !strings.Contains(reply, "Got the file") {
+		t.Fatalf("expected the tenant's credentials to fetch the file, got %q", reply)
+	}
+
+	reply := b.respond(ctx, "+15551234567", "export my data")
+	link = reply[strings.LastIndex(reply, "\n")+1:]
+	if !strings.HasPrefix(link, "https://memo.example.com/files/exports/") {
+		t.Fatalf("expected an export link, got %q", reply)
+	}
+	rec := download(link)
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a ZIP download, got %q (%v)", rec.Header().Get("Content-Type"), err)
+	}
+	if len(archive.File) == 0 || archive.File[0].Name != "reminders.json" {
+		t.Fatalf("unexpected export files: %v", archive.File)
+	}
+}
+
+func TestMessageArchiveSearch(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.MessageArchiveDays = 30
+	ctx := context.Background()
+	sender := &recordingSender{}
+	b.channels[channel.WhatsApp] = sender
+
+	now := time.Now().UTC()
+	threeDaysAgo := startOfDay(now).AddDate(0, 0, -3).Add(10 * time.Hour)
+	for _, m := range []model.ArchivedMessage{
+		{UserID: "+15551234567", Direction: model.DirectionIn, Body: "remind me to book the dentist", CreatedAt: threeDaysAgo},
+		{UserID: "+15551234567", Direction: model.DirectionOut, Body: "Got it! I'll remind you: Book dentist", CreatedAt: threeDaysAgo.Add(time.Second)},
+		{UserID: "+15551234567", Direction: model.DirectionIn, Body: "buy paint for the fence", CreatedAt: now.AddDate(0, 0, -40)},
+		{UserID: "+15559999999", Direction: model.DirectionIn, Body: "someone else's dentist", CreatedAt: threeDaysAgo},
+	} {
+		if err := b.db.Create(&m).Error; err != nil {
+			t.Fatalf("seed archive: %v", err)
+		}
+	}
+
+	weekday := strings.ToLower(threeDaysAgo.Weekday().String())
+	reply := b.respond(ctx, "+15551234567", "What did I ask you last "+weekday+"?")
+	if !containsAll(reply, []string{"messages from you on", "You: remind me to book the dentist"}) || strings.Contains(reply, "Me:") {
+		t.Fatalf("unexpected reply for last %s: %q", weekday, reply)
+	}
+	if reply := b.respond(ctx, "+15551234567", "search my messages for dentist"); !containsAll(reply, []string{"You: remind me to book the dentist", "Me: Got it!"}) || strings.Contains(reply, "someone else") {
+		t.Fatalf("unexpected search reply: %q", reply)
+	}
+	if reply := b.respond(ctx, "+15551234567", "what did I tell you yesterday"); !strings.Contains(reply, "couldn't find any messages from you yesterday") {
+		t.Fatalf("unexpected reply for an empty day: %q", reply)
+	}
+
+	b.pruneArchive()
+	var old int64
+	b.db.Model(&model.ArchivedMessage{}).Where("body LIKE ?", "%paint%").Count(&old)
+	if old != 0 {
+		t.Fatal("expected messages past the retention limit to be pruned")
+	}
+	if err := b.notify(ctx, "+15551234567", "Your dentist reminder moved to Friday"); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if reply := b.respond(ctx, "+15551234567", "what did you tell me about friday?"); !strings.Contains(reply, "Me: Your dentist reminder moved to Friday") {
+		t.Fatalf("expected sent messages to be archived, got %q", reply)
+	}
+
+	if reply := b.respond(ctx, "+15551234567", "delete my message history"); !strings.Contains(reply, "Deleted") {
+		t.Fatalf("unexpected clear reply: %q", reply)
+	}
+	var left int64
+	b.db.Model(&model.ArchivedMessage{}).Where("user_id = ?", "+15551234567").Count(&left)
+	if left > 2 {
+		t.Fatalf("expected only the clear exchange to be left, got %d messages", left)
+	}
+
+	for _, tt := range []struct{ in, want string }{
+		{"connect notion secret_abc https://www.notion.so/0123456789abcdef0123456789abcdef", "connect notion [hidden] https://www.notion.so/0123456789abcdef0123456789abcdef"},
+		{"Connect CalDAV https://caldav.icloud.com me@icloud.com abcd-efgh-ijkl", "Connect CalDAV https://caldav.icloud.com me@icloud.com [hidden]"},
+		{"Your hook token: 0a1b2c\nPOST reminders as JSON", "Your hook token: [hidden]\nPOST reminders as JSON"},
+		{"remind me to connect the printer", "remind me to connect the printer"},
+	} {
+		if got := redactCredentials(tt.in); got != tt.want {
+			t.Errorf("redactCredentials(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	b.respond(ctx, "+15551234567", "hook token")
+	var archived []model.ArchivedMessage
+	b.db.Where("user_id = ?", "+15551234567").Find(&archived)
+	for _, m := range archived {
+		if strings.Contains(m.Body, "hook token: ") && !strings.Contains(m.Body, "hook token: [hidden]") {
+			t.Fatalf("expected the hook token to be kept out of the archive, got %q", m.Body)
+		}
+	}
+}
+
+func TestProjects(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	user := "+15551234567"
+	seedReminders(t, b, []model.Reminder{
+		{UserID: user, Content: "Call the electrician", Summary: "Call electrician", Priority: 4},
+		{UserID: user, Content: "Pay rent", Summary: "Pay rent", Priority: 5},
+	})
+
+	if reply := b.respond(ctx, user, "Project: Kitchen Renovation"); !strings.Contains(reply, "Started the kitchen renovation project") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "project: kitchen renovation"); !strings.Contains(reply, "already have a project") {
+		t.Fatalf("expected a duplicate to be refused, got %q", reply)
+	}
+	if reply := b.respond(ctx, user, "project kitchen: buy tiles"); !strings.Contains(reply, "Added R3 buy tiles to the kitchen renovation project") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "add R1 to project kitchen"); !strings.Contains(reply, "Added R1 Call electrician to the kitchen renovation project") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "add R2 to project garden"); !strings.Contains(reply, "don't have a project called garden") {
+		t.Fatalf("expected an unknown project to be named, got %q", reply)
+	}
+	b.respond(ctx, user, "done R3")
+
+	reply := plainText(b.respond(ctx, user, "list project kitchen"))
+	if !containsAll(reply, []string{"Project kitchen renovation: 1/2 done", "R1 [4] Call electrician", "✓ R3"}) || strings.Contains(reply, "Pay rent") {
+		t.Fatalf("unexpected project list: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "projects"); !strings.Contains(reply, "- kitchen renovation: 1/2 done") {
+		t.Fatalf("unexpected projects reply: %q", reply)
+	}
+	review, err := b.weeklyReview(ctx, user, time.Now())
+	if err != nil || !strings.Contains(review, "Projects: kitchen renovation 1/2 done (1 this week).") {
+		t.Fatalf("expected project progress in the weekly review, got %q (%v)", review, err)
+	}
+
+	if reply := b.respond(ctx, user, "delete the kitchen project"); !strings.Contains(reply, "Its reminders are still saved") {
+		t.Fatalf("unexpected delete reply: %q", reply)
+	}
+	var inProject int64
+	b.db.Model(&model.Reminder{}).Where("project_id IS NOT NULL").Count(&inProject)
+	if inProject != 0 {
+		t.Fatalf("expected reminders to leave the deleted project, %d still in it", inProject)
+	}
+}
+
+func TestReminderSteps(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	user := "+15551234567"
+	seedReminders(t, b, []model.Reminder{
+		{UserID: user, Content: "Paint the fence", Summary: "Paint the fence", Priority: 4},
+		{UserID: user, Content: "Plan the party", Summary: "Plan the party", Priority: 3},
+	})
+
+	if reply := b.respond(ctx, user, "add step buy paint to reminder 1"); !strings.Contains(reply, "Added 'buy paint' to R1 Paint the fence (0/1)") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "add steps sand, prime, paint the posts to R1"); !strings.Contains(reply, "Added 3 steps to R1 Paint the fence (0/4)") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	b.respond(ctx, user, "add step buy paper plates to R2")
+
+	if reply := b.respond(ctx, user, "check off buy"); !strings.Contains(reply, "matches 2 steps") {
+		t.Fatalf("expected an ambiguous step to be questioned, got %q", reply)
+	}
+	if reply := b.respond(ctx, user, "check off buy paint"); !strings.Contains(reply, "Checked off 'buy paint' on R1 Paint the fence (1/4)") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "check off sand"); !strings.Contains(reply, "(2/4)") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "check off sand"); !strings.Contains(reply, "couldn't find an open step matching 'sand'") {
+		t.Fatalf("expected a checked step not to be checked twice, got %q", reply)
+	}
+	if reply := plainText(b.respond(ctx, user, "list reminders")); !strings.Contains(reply, "Paint the fence (2/4)") {
+		t.Fatalf("expected step progress in the list, got %q", reply)
+	}
+	if reply := b.respond(ctx, user, "uncheck sand"); !strings.Contains(reply, "(1/4)") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "remove step buy paint from R1"); !strings.Contains(reply, "Removed 'buy paint' from R1") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	reply := b.respond(ctx, user, "show R1")
+	if !containsAll(reply, []string{"Steps (0/3):", "☐ sand", "☐ prime", "☐ paint the posts"}) || strings.Contains(reply, "buy paint") {
+		t.Fatalf("unexpected detail: %q", reply)
+	}
+
+	b.respond(ctx, user, "delete R2")
+	var left int64
+	b.db.Model(&model.ReminderItem{}).Where("text = ?", "buy paper plates").Count(&left)
+	if left != 0 {
+		t.Fatalf("expected the steps of a deleted reminder to go with it, %d left", left)
+	}
+}
+
+func TestPostponeAll(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	user := "+15551234567"
+	now := time.Now().In(b.cfg.LocalTimezone)
+	today := startOfDay(now)
+	overdue := today.AddDate(0, 0, -1).Add(9 * time.Hour)
+	later := today.AddDate(0, 0, 10)
+	countdown := today.AddDate(0, 0, 1)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: user, Content: "File taxes", Summary: "File taxes", Priority: 4, DueAt: &overdue},
+		{UserID: user, Content: "Buy milk", Summary: "Buy milk", Priority: 3},
+		{UserID: user, Content: "Renew lease", Summary: "Renew lease", Priority: 3, DueAt: &later},
+		{UserID: user, Content: "Trip", Summary: "Trip", Priority: 3, Kind: model.KindCountdown, DueAt: &countdown, Interval: "daily"},
+	})
+
+	if reply := b.respond(ctx, user, "postpone all by 0 days"); !strings.Contains(reply, "Pick a day after today") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	reply := b.respond(ctx, user, "Postpone all my reminders by 2 days")
+	if !containsAll(reply, []string{"hold 4 reminders", "1 reminder due before then is now due that day"}) {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+
+	var reminders []model.Reminder
+	b.db.Order("id").Find(&reminders)
+	held := today.AddDate(0, 0, 2)
+	for _, r := range reminders {
+		if r.SnoozedUntil == nil || !r.SnoozedUntil.Equal(held) {
+			t.Fatalf("expected %s held until %v, got %v", r.ShortID(), held, r.SnoozedUntil)
+		}
+	}
+	if due := reminders[0].DueAt.In(b.cfg.LocalTimezone); !due.Equal(held.Add(9*time.Hour)) || reminders[0].Snoozes != 1 {
+		t.Fatalf("expected the overdue reminder to move to %v, got %v (%d snoozes)", held.Add(9*time.Hour), due, reminders[0].Snoozes)
+	}
+	if !reminders[2].DueAt.Equal(later) || !reminders[3].DueAt.Equal(countdown) {
+		t.Fatalf("expected later and countdown dates to stay, got %v and %v", reminders[2].DueAt, reminders[3].DueAt)
+	}
+
+	if reply := b.respond(ctx, user, "push everything to tomorrow"); !strings.Contains(reply, "hold 4 reminders until tomorrow") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	var milk model.Reminder
+	b.db.First(&milk, reminders[1].ID)
+	if !milk.SnoozedUntil.Equal(held) {
+		t.Fatalf("expected an earlier day not to shorten the hold, got %v", milk.SnoozedUntil)
+	}
+}
+
+func TestMorningBriefing(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	user := "+15551234567"
+	now := time.Now().In(b.cfg.LocalTimezone)
+	today := startOfDay(now)
+	dueToday := today.Add(17 * time.Hour)
+	overdue := today.AddDate(0, 0, -3)
+	nextWeek := today.AddDate(0, 0, 7)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: user, Content: "Submit the report", Summary: "Submit report", Priority: 3, DueAt: &dueToday},
+		{UserID: user, Content: "Call the bank", Summary: "Call the bank", Priority: 2, DueAt: &overdue, CreatedAt: now.AddDate(0, 0, -5)},
+		{UserID: user, Content: "Book flights", Summary: "Book flights", Priority: 5, DueAt: &nextWeek},
+		{UserID: user, Content: "Buy milk", Summary: "Buy milk", Priority: 4},
+	})
+
+	if reply := b.respond(ctx, user, "morning briefing on"); !strings.Contains(reply, "Every morning at") || !b.preferences(user).MorningBriefing {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+	if reply := b.respond(ctx, user, "my settings"); !strings.Contains(reply, "Morning briefing: on") {
+		t.Fatalf("expected the briefing in settings, got %q", reply)
+	}
+
+	reply := b.respond(ctx, user, "plan my day")
+	want := []string{"Due today:\n- R1 Submit report", "Overdue:\n- R2 Call the bank (3 days late)", "Start with Call the bank, it's been waiting 5 days."}
+	if !containsAll(reply, want) || strings.Contains(reply, "Book flights") || strings.Contains(reply, "Buy milk") {
+		t.Fatalf("unexpected briefing: %q", reply)
+	}
+
+	b.respond(ctx, user, "push everything to tomorrow")
+	if reply := b.respond(ctx, user, "briefing"); !strings.Contains(reply, "Nothing is due today") {
+		t.Fatalf("expected postponed reminders to be left out, got %q", reply)
+	}
+	if reply := b.respond(ctx, user, "morning briefing off"); !strings.Contains(reply, "no more morning briefings") || b.preferences(user).MorningBriefing {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+}
+
+func TestWeekView(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	user := "+15551234567"
+	now := time.Now().In(b.cfg.LocalTimezone)
+	monday := startOfDay(now).AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	wednesday := monday.AddDate(0, 0, 2).Add(15 * time.Hour)
+	nextMonday := monday.AddDate(0, 0, 7)
+	lastWeek := monday.AddDate(0, 0, -3)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: user, Content: "Dentist", Summary: "Dentist", Priority: 3, DueAt: &wednesday},
+		{UserID: user, Content: "Stand-up", Summary: "Stand-up", Priority: 2, DueAt: &monday, RRule: "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{UserID: user, Content: "Pay rent", Summary: "Pay rent", Priority: 4, DueAt: &nextMonday},
+		{UserID: user, Content: "Buy milk", Summary: "Buy milk", Priority: 4},
+		{UserID: user, Content: "File taxes", Summary: "File taxes", Priority: 5, DueAt: &lastWeek},
+	})
+
+	reply := plainText(b.respond(ctx, user, "show my week"))
+	want := []string{
+		"Your week, " + monday.Format("Mon Jan 2") + " to " + monday.AddDate(0, 0, 6).Format("Mon Jan 2"),
+		monday.Format("Mon 2") + ":\n- R2 Stand-up",
+		wednesday.Format("Mon 2") + ":\n- R1 Dentist, 3:00pm",
+		monday.AddDate(0, 0, 3).Format("Mon 2") + ":\n- R2 Stand-up",
+		monday.AddDate(0, 0, 6).Format("Mon 2") + ": —",
+	}
+	if !containsAll(reply, want) || strings.Contains(reply, "Pay rent") || strings.Contains(reply, "Buy milk") {
+		t.Fatalf("unexpected week: %q", reply)
+	}
+	if !containsAll(reply, []string{"(today)", "Also 1 reminder overdue from before this week"}) || strings.Contains(reply, "File taxes") {
+		t.Fatalf("expected today marked and last week's reminder counted, got %q", reply)
+	}
+
+	reply = plainText(b.respond(ctx, user, "next week"))
+	if !containsAll(reply, []string{nextMonday.Format("Mon 2") + ":\n- R3 Pay rent\n- R2 Stand-up"}) || strings.Contains(reply, "Dentist") || strings.Contains(reply, "overdue") {
+		t.Fatalf("unexpected next week: %q", reply)
+	}
+}
+
+func TestCalendarInvites(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	user := "+15551234567"
+	whatsapp := &recordingSender{}
+	b.channels[channel.WhatsApp] = whatsapp
+
+	if reply := b.respond(ctx, user, "calendar invites on"); !strings.Contains(reply, "can't send calendar invites") {
+		t.Fatalf("expected invites to need a blob store, got %q", reply)
+	}
+	dir := t.TempDir()
+	b.blobs = storage.NewDisk(dir, "https://memo.example.com/files", []byte("signing-key"))
+	if reply := b.respond(ctx, user, "calendar invites on"); !strings.Contains(reply, "I'll send an invite") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+
+	due := time.Date(2026, time.March, 9, 15, 30, 0, 0, b.cfg.LocalTimezone)
+	dentist := &model.Reminder{UserID: user, Content: "Dentist appointment; bring forms, insurance card", Summary: "Dentist", Priority: 3, DueAt: &due}
+	if err := b.insertReminder(ctx, dentist); err != nil {
+		t.Fatalf("insert reminder: %v", err)
+	}
+	if err := b.insertReminder(ctx, &model.Reminder{UserID: user, Content: "Buy milk", Priority: 2}); err != nil {
+		t.Fatalf("insert reminder: %v", err)
+	}
+	b.sends.Drain(ctx)
+
+	if len(whatsapp.messages) != 1 {
+		t.Fatalf("expected one invite, got %+v", whatsapp.messages)
+	}
+	msg := whatsapp.messages[0]
+	if !strings.Contains(msg.Body, "Add Dentist to your calendar") || !strings.HasPrefix(msg.MediaURL, "https://memo.example.com/files/invites/") {
+		t.Fatalf("unexpected invite message: %+v", msg)
+	}
+	ics, err := b.blobs.Get(ctx, inviteKey(*dentist))
+	if err != nil {
+		t.Fatalf("read invite: %v", err)
+	}
+	want := []string{"BEGIN:VEVENT\r\n", "DTSTART:20260309T153000\r\n", "DTEND:20260309T160000\r\n", "SUMMARY:Dentist\r\n", `DESCRIPTION:Dentist appointment\; bring forms\, insurance card`}
+	if !containsAll(string(ics), want) {
+		t.Fatalf("unexpected invite: %q", ics)
+	}
+
+	if reply := b.respond(ctx, user, "add R2 to my calendar"); !strings.Contains(reply, "no due date") {
+		t.Fatalf("unexpected reply: %q", reply)
+	}
+}
+
+func TestCalDAVSync(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	ctx := context.Background()
+	user := "+15551234567"
+
+	var (
+		mu      sync.Mutex
+		tasks   = map[string]string{}
+		deleted []string
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me@icloud.com" || pass != "app-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == "PROPFIND" && r.URL.Path == "/":
+			w.WriteHeader(http.StatusMultiStatus)
+			fmt.Fprint(w, `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:response><d:href>/</d:href>
+				<d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype><c:calendar-home-set><d:href>/calendars/me/</d:href></c:calendar-home-set></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+			</d:response></d:multistatus>`)
+		case r.Method == "PROPFIND" && r.URL.Path == "/calendars/me/":
+			w.WriteHeader(http.StatusMultiStatus)
+			fmt.Fprint(w, `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+				<d:response><d:href>/calendars/me/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+				<d:response><d:href>/calendars/me/home/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/><c:calendar/></d:resourcetype><d:displayname>Home</d:displayname><c:supported-calendar-component-set><c:comp name="VEVENT"/></c:supported-calendar-component-set></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+				<d:response><d:href>/calendars/me/tasks/</d:href><d:propstat><d:prop><d:resourcetype><d:collection/><c:calendar/></d:resourcetype><d:displayname>Reminders</d:displayname><c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>
+			</d:multistatus>`)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/calendars/me/tasks/"):
+			data, _ := io.ReadAll(r.Body)
+			tasks[r.URL.Path] = string(data)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			if _, ok := tasks[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(tasks, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "REPORT" && r.URL.Path == "/calendars/me/tasks/":
+			w.WriteHeader(http.StatusMultiStatus)
+			fmt.Fprint(w, `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+			for path, data := range tasks {
+				fmt.Fprintf(w, `<d:response><d:href>%s</d:href><d:propstat><d:prop><c:calendar-data>`, path)
+				xml.EscapeText(w, []byte(data))
+				fmt.Fprint(w, `</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+			}
+			fmt.Fprint(w, `</d:multistatus>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	due := time.Date(2030, time.March, 9, 15, 30, 0, 0, time.UTC)
+	seedReminders(t, b, []model.Reminder{
+		{UserID: user, Code: 1, Content: "Dentist appointment, bring forms", Summary: "Dentist", Priority: 4, DueAt: &due},
+		{UserID: user, Code: 2, Content: "Buy milk", Priority: 2},
+		{UserID: user, Code: 3, Content: "Meditate", Priority: 3, Kind: model.KindHabit},
+	})
+
+	// The server has a public-looking name; the test client dials the local
+	// server for it, since the default client refuses loopback.
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	transport.TLSClientConfig.ServerName = "example.com"
+	b.caldav = caldav.NewWithClient(&http.Client{Transport: transport})
+	const server = "https://caldav.example.com/"
+
+	if reply := b.respond(ctx, user, "connect caldav http://caldav.example.com/ me@icloud.com app-password"); !strings.Contains(reply, "secure server address") {
+		t.Fatalf("expected a plain http server to be refused, got %q", reply)
+	}
+	if reply := b.respond(ctx, user, "connect caldav "+srv.URL+"/ me@icloud.com app-password"); !strings.Contains(reply, "public internet") {
+		t.Fatalf("expected a loopback server to be refused, got %q", reply)
+	}
+	if reply := b.respond(ctx, user, "connect caldav "+server+" me@icloud.com app-password"); reply != "CalDAV sync isn't available right now." {
+		t.Fatalf("expected CalDAV to be off without a credentials key, got %q", reply)
+	}
+	b.cfg.CredentialsKey = "credentials-key"
+
+	if reply := b.respond(ctx, user, "connect caldav "+server+" me@icloud.com wrong"); !strings.Contains(reply, "rejected that username or password") {
+		t.Fatalf("expected a credentials error, got %q", reply)
+	}
+	if reply := b.respond(ctx, user, "connect caldav "+server+" me@icloud.com app-password"); !strings.Contains(reply, "Connected to your task list") {
+		t.Fatalf("unexpected connect reply: %q", reply)
+	}
+	if conn, err := b.caldavConnection(user); err != nil || conn.Password == "app-password" || !strings.HasPrefix(conn.CollectionURL, server) {
+		t.Fatalf("expected the password to be stored encrypted, got %+v (%v)", conn, err)
+	}
+	b.sends.Drain(ctx)
+	b.sends = newSendQueue()
+
+	dentist := tasks["/calendars/me/tasks/reminder-1@mymemo.ics"]
+	want := []string{"BEGIN:VTODO\r\n", "SUMMARY:Dentist\r\n", `DESCRIPTION:Dentist appointment\, bring forms`, "DUE:20300309T153000\r\n", "PRIORITY:3\r\n", "STATUS:NEEDS-ACTION\r\n"}
+	if len(tasks) != 2 || !containsAll(dentist, want) {
+		t.Fatalf("unexpected tasks after connect: %q", tasks)
+	}
+
+	// Tick off the dentist on the phone and delete the milk task there.
+	tasks["/calendars/me/tasks/reminder-1@mymemo.ics"] = strings.Replace(dentist, "STATUS:NEEDS-ACTION", "STATUS:COMPLETED", 1)
+	delete(tasks, "/calendars/me/tasks/reminder-2@mymemo.ics")
+	if reply := b.respond(ctx, user, "sync caldav"); reply != "Synced with your task list: 0 reminder(s) added there, 1 marked done here." {
+		t.Fatalf("unexpected sync reply: %q", reply)
+	}
+	b.sends.Drain(ctx)
+	b.sends = newSendQueue()
+
+	open, err := b.openReminders(ctx, user)
+	if err != nil {
+		t.Fatalf("openReminders: %v", err)
+	}
+	if len(open) != 2 || slices.ContainsFunc(open, func(r model.Reminder) bool { return r.Code == 1 }) {
+		t.Fatalf("unexpected open reminders after sync: %+v", open)
+	}
+	if len(tasks) != 1 || !strings.Contains(tasks["/calendars/me/tasks/reminder-1@mymemo.ics"], "STATUS:COMPLETED") {
+		t.Fatalf("unexpected tasks after sync: %q", tasks)
+	}
+
+	b.respond(ctx, user, "delete R2")
+	b.sends.Drain(ctx)
+	if !slices.Equal(deleted, []string{"/calendars/me/tasks/reminder-2@mymemo.ics"}) {
+		t.Fatalf("expected the deleted reminder's task to be removed, got %q", deleted)
+	}
+}
+
+func TestAPIHookCreateReminder(t *testing.T) {
+	t.Parallel()
+	b := newTestBot(t)
+	b.cfg.PublicBaseURL = "https://memo.example.com"
+	ctx := context.Background()
+	user := "+15551234567"
+	seedReminders(t, b, []model.Reminder{{UserID: user, Code: 1, Content: "Buy milk", Priority: 2}})
+
+	call := func(target, auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		rec := httptest.NewRecorder()
+		b.APIHandler().ServeHTTP(rec, req)
+		return rec
+	}
+	const path = "/api/v1/hooks/create-reminder"
+	body := `{"content":"Fix the failing build","due_at":"2030-03-09T15:30:00Z","priority":4}`
+	issue := func(userID string) string {
+		reply := b.respond(ctx, userID, "hook token")
+		token, _, ok := strings.Cut(strings.TrimPrefix(reply, "Your hook token: "), "\n")
+		if !ok || !strings.Contains(reply, "https://memo.example.com"+path) {
+			t.Fatalf("unexpected hook token reply: %q", reply)
+		}
+		return token
+	}
+
+	if rec := call(path, "", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a request without a token to be rejected, got %d", rec.Code)
+	}
+	token := issue(user)
+	var stored model.HookToken
+	if err := b.db.Where("user_id = ?", user).First(&stored).Error; err != nil || stored.TokenHash != hashToken(token) {
+		t.Fatalf("expected only the token's hash to be stored, got %+v (%v)", stored, err)
+	}
+	if rec := call(path, "wrong", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong token to be rejected, got %d", rec.Code)
+	}
+	if rec := call(path+"?token="+token, "", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a query token to be rejected, got %d", rec.Code)
+	}
+	if rec := call(path, token, `{"priority":4}`); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "content: is required") {
+		t.Fatalf("expected missing content to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := call(path, token, `{"user":"+15550000000","content":"Hello"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a user in the body to be rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec := call(path, token, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body.String())
+	}
+	var created ReminderResource
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created reminder: %v", err)
+	}
+	if created.Code != "R2" || created.Content != "Fix the failing build" || created.Priority != 4 || created.DueAt == nil || !created.DueAt.Equal(time.Date(2030, time.March, 9, 15, 30, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected reminder: %+v", created)
+	}
+
+	// Another user's token adds reminders for them, not for user.
+	other := issue("+15559876543")
+	if rec := call(path, other, `{"content":"Take out the bins"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create for other user: %d %s", rec.Code, rec.Body.String())
+	}
+	if open, err := b.openReminders(ctx, user); err != nil || len(open) != 2 {
+		t.Fatalf("expected two open reminders for the first user, got %+v (%v)", open, err)
+	}
+
+	// A new token replaces the old one, and revoking turns the hook off.
+	renewed := issue(user)
+	if rec := call(path, token, body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the replaced token to be rejected, got %d", rec.Code)
+	}
+	if reply := b.respond(ctx, user, "revoke hook token"); !strings.Contains(reply, "revoked") {
+		t.Fatalf("unexpected revoke reply: %q", reply)
+	}
+	if rec := call(path, renewed, body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked token to be rejected, got %d", rec.Code)
+	}
 }
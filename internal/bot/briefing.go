@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+// morningBriefingSpec checks every hour for users whose morning hour it is.
+const morningBriefingSpec = "0 * * * *"
+
+// sendMorningBriefings sends each subscribed user their briefing at the hour
+// their morning reminders go out, in their own timezone.
+func (b *Bot) sendMorningBriefings() {
+	var users []string
+	if err := b.db.Model(&model.UserPreference{}).Where("morning_briefing = ? AND paused = ? AND opted_out_at IS NULL", true, false).Pluck("user_id", &users).Error; err != nil {
+		b.logger.Printf("scheduler: fetch briefing subscribers: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+	for _, userID := range users {
+		pref := b.preferences(userID)
+		now := time.Now().In(b.location(pref))
+		if now.Hour() != bucketHour(pref, model.TimeMorning) || b.blackedOut(ctx, userID, now) {
+			continue
+		}
+		msg, err := b.morningBriefing(ctx, userID, now)
+		if err != nil {
+			b.logger.Printf("scheduler: morning briefing for %s: %v", userID, err)
+			continue
+		}
+		if msg == "" {
+			continue
+		}
+		if err := b.notify(ctx, userID, msg); err != nil {
+			b.logger.Printf("scheduler: send morning briefing to %s: %v", userID, err)
+		}
+	}
+}
+
+// handleBriefingCommand turns the morning briefing on and off, and sends it
+// right away on "briefing" or "plan my day". It reports false for other
+// messages.
+func (b *Bot) handleBriefingCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!?") {
+	case "morning briefing on", "turn on morning briefing", "turn on the morning briefing", "send me a morning briefing", "send me a briefing every morning":
+		return b.setMorningBriefing(userID, true), true
+	case "morning briefing off", "turn off morning briefing", "turn off the morning briefing", "stop morning briefing", "stop the morning briefing":
+		return b.setMorningBriefing(userID, false), true
+	case "briefing", "morning briefing", "my briefing", "plan my day":
+	default:
+		return "", false
+	}
+	msg, err := b.morningBriefing(ctx, userID, time.Now().In(b.location(b.preferences(userID))))
+	if err != nil {
+		b.logger.Printf("briefing for %s: %v", userID, err)
+		return "I couldn't put your briefing together right now. Please try again later.", true
+	}
+	if msg == "" {
+		return "Nothing is due today and nothing is overdue. Enjoy your day!", true
+	}
+	return msg, true
+}
+
+// setMorningBriefing turns the morning briefing on or off.
+func (b *Bot) setMorningBriefing(userID string, on bool) string {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.MorningBriefing = on
+	}); err != nil {
+		b.logger.Printf("morning briefing for %s: %v", userID, err)
+		return "I couldn't update that setting. Please try again later."
+	}
+	if !on {
+		return "Okay, no more morning briefings."
+	}
+	hour := formatHour(bucketHour(b.preferences(userID), model.TimeMorning))
+	return fmt.Sprintf("Every morning at %s I'll send what's due today, what's overdue, and where to start. Say 'set morning to 7am' to change the time, or 'morning briefing off' to stop.", hour)
+}
+
+// morningBriefing renders the user's reminders due today and overdue, with
+// a one-line plan for where to start. Reminders held past today with
+// "postpone" are left out. It returns an empty string when nothing is due.
+func (b *Bot) morningBriefing(ctx context.Context, userID string, now time.Time) (string, error) {
+	open, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	today := startOfDay(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	var dueToday, overdue []model.Reminder
+	for _, r := range open {
+		if r.Kind != model.KindReminder || r.DueAt == nil || !r.DueAt.Before(tomorrow) {
+			continue
+		}
+		if r.SnoozedUntil != nil && r.SnoozedUntil.After(now) {
+			continue
+		}
+		if r.DueAt.Before(today) {
+			overdue = append(overdue, r)
+		} else {
+			dueToday = append(dueToday, r)
+		}
+	}
+	if len(dueToday) == 0 && len(overdue) == 0 {
+		return "", nil
+	}
+	// Most overdue first, as that is where the plan starts.
+	sort.SliceStable(overdue, func(i, j int) bool { return overdue[i].DueAt.Before(*overdue[j].DueAt) })
+
+	var sb strings.Builder
+	sb.WriteString("Good morning! Here's your day.\n")
+	described := make([]string, 0, len(dueToday)+len(overdue))
+	if len(dueToday) > 0 {
+		sb.WriteString("Due today:\n")
+		for _, r := range dueToday {
+			fmt.Fprintf(&sb, "- %s %s\n", r.ShortID(), fallback(r.Summary, r.Content))
+			described = append(described, fmt.Sprintf("%s: %s (due today, open %s, priority %d)", r.ShortID(), fallback(r.Summary, r.Content), plural(daysUntil(now, r.CreatedAt), "day"), r.Priority))
+		}
+	}
+	if len(overdue) > 0 {
+		sb.WriteString("Overdue:\n")
+		for _, r := range overdue {
+			late := plural(daysUntil(now, *r.DueAt), "day")
+			fmt.Fprintf(&sb, "- %s %s (%s late)\n", r.ShortID(), fallback(r.Summary, r.Content), late)
+			described = append(described, fmt.Sprintf("%s: %s (overdue %s, open %s, priority %d)", r.ShortID(), fallback(r.Summary, r.Content), late, plural(daysUntil(now, r.CreatedAt), "day"), r.Priority))
+		}
+	}
+
+	if b.useOpenAI(ctx, userID) {
+		plan, err := b.openAI.PlanDay(ctx, described)
+		switch {
+		case err == nil && plan != "":
+			sb.WriteString(plan)
+			return sb.String(), nil
+		case err != nil && !errors.Is(err, myopenai.ErrClientNotInitialised):
+			b.logger.Printf("openai briefing error: %v", err)
+		}
+	}
+	sb.WriteString(dayPlan(dueToday, overdue, now))
+	return sb.String(), nil
+}
+
+// dayPlan picks where to start without the model: the most overdue
+// reminder, or else the most urgent one due today.
+func dayPlan(dueToday, overdue []model.Reminder, now time.Time) string {
+	if len(overdue) > 0 {
+		r := overdue[0]
+		waiting := max(daysUntil(now, r.CreatedAt), daysUntil(now, *r.DueAt))
+		return fmt.Sprintf("Start with %s, it's been waiting %s.", fallback(r.Summary, r.Content), plural(waiting, "day"))
+	}
+	first := dueToday[0]
+	for _, r := range dueToday[1:] {
+		if r.Priority > first.Priority {
+			first = r
+		}
+	}
+	return fmt.Sprintf("Start with %s, it's the most urgent thing due today.", fallback(first.Summary, first.Content))
+}
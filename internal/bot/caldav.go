@@ -0,0 +1,337 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/caldav"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/netguard"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// caldavSyncSpec is the cron schedule for syncing connected CalDAV task
+// lists, offset from the Notion pull.
+const caldavSyncSpec = "30 * * * *"
+
+var connectCalDAVPattern = regexp.MustCompile(`(?i)^connect caldav\s+(\S+)\s+(\S+)\s+(\S+)$`)
+
+// handleCalDAVCommand connects, syncs, and disconnects a user's CalDAV task
+// list. It reports false when the message is not a CalDAV command.
+func (b *Bot) handleCalDAVCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	switch {
+	case connectCalDAVPattern.MatchString(body):
+		m := connectCalDAVPattern.FindStringSubmatch(body)
+		msg, err = b.connectCalDAV(ctx, userID, m[1], m[2], m[3])
+	case lowerBody == "caldav" || lowerBody == "caldav status":
+		msg, err = b.describeCalDAV(userID)
+	case lowerBody == "sync caldav" || lowerBody == "caldav sync":
+		msg, err = b.syncCalDAVNow(ctx, userID)
+	case lowerBody == "disconnect caldav":
+		msg, err = b.disconnectCalDAV(userID)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("caldav command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// connectCalDAV finds the task list on the user's server, stores the
+// connection, and writes their open reminders to it in the background.
+func (b *Bot) connectCalDAV(ctx context.Context, userID, server, username, password string) (string, error) {
+	if b.caldav == nil {
+		return "", userError{"CalDAV sync isn't available right now."}
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", userError{"That doesn't look like a secure server address. Try 'connect caldav https://caldav.icloud.com <apple id> <app password>'."}
+	}
+	if err := netguard.CheckURL(server); err != nil {
+		return "", userError{"CalDAV sync only works with servers on the public internet."}
+	}
+	sealed, err := b.sealCredential(password)
+	if err != nil {
+		b.logger.Printf("caldav: seal password: %v", err)
+		return "", userError{"CalDAV sync isn't available right now."}
+	}
+	collection, err := b.caldav.FindTaskList(ctx, server, username, password)
+	if err != nil {
+		return "", b.caldavError("find task list", err)
+	}
+	conn := &model.CalDAVConnection{
+		UserID:        userID,
+		CollectionURL: collection,
+		Username:      username,
+		Password:      sealed,
+	}
+	if err := b.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(conn).Error; err != nil {
+		return "", fmt.Errorf("I couldn't save your CalDAV connection. Please try again later")
+	}
+	b.sends.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if _, _, err := b.syncCalDAV(ctx, conn); err != nil {
+			b.logger.Printf("caldav: first sync for %s: %v", userID, err)
+		}
+	})
+	return "Connected to your task list. Your open reminders will show up there, and anything you tick off there is marked done here within the hour. Say 'sync caldav' to check now.", nil
+}
+
+// describeCalDAV reports the user's CalDAV connection.
+func (b *Bot) describeCalDAV(userID string) (string, error) {
+	conn, err := b.caldavConnection(userID)
+	if err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("Connected to %s as %s. Completed tasks are synced every hour.", conn.CollectionURL, conn.Username)
+	if conn.LastSyncedAt != nil {
+		msg += "\nLast synced " + conn.LastSyncedAt.In(b.cfg.LocalTimezone).Format("Jan 02 15:04") + "."
+	}
+	return msg, nil
+}
+
+// syncCalDAVNow syncs the user's task list immediately.
+func (b *Bot) syncCalDAVNow(ctx context.Context, userID string) (string, error) {
+	conn, err := b.caldavConnection(userID)
+	if err != nil {
+		return "", err
+	}
+	if b.caldav == nil {
+		return "", userError{"CalDAV sync isn't available right now."}
+	}
+	pushed, completed, err := b.syncCalDAV(ctx, conn)
+	if err != nil {
+		return "", b.caldavError("sync", err)
+	}
+	return fmt.Sprintf("Synced with your task list: %d reminder(s) added there, %d marked done here.", pushed, completed), nil
+}
+
+// disconnectCalDAV removes the user's CalDAV connection. Tasks already on
+// the server are left alone.
+func (b *Bot) disconnectCalDAV(userID string) (string, error) {
+	result := b.db.Where("user_id = ?", userID).Delete(&model.CalDAVConnection{})
+	if result.Error != nil {
+		return "", fmt.Errorf("I couldn't disconnect CalDAV. Please try again later")
+	}
+	if result.RowsAffected == 0 {
+		return "", userError{"CalDAV isn't connected."}
+	}
+	if err := b.db.Model(&model.Reminder{}).Where("user_id = ? AND caldav_synced = ?", userID, true).Update("caldav_synced", false).Error; err != nil {
+		b.logger.Printf("caldav: reset sync marks for %s: %v", userID, err)
+	}
+	return "Disconnected from CalDAV. Existing tasks stay in your task list.", nil
+}
+
+// caldavConnection loads the user's connection, returning a userError when
+// there is none.
+func (b *Bot) caldavConnection(userID string) (*model.CalDAVConnection, error) {
+	var conn model.CalDAVConnection
+	err := b.db.Where("user_id = ?", userID).First(&conn).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, userError{"CalDAV isn't connected. Send 'connect caldav <server> <username> <app password>' first."}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("I couldn't load your CalDAV connection. Please try again later")
+	}
+	return &conn, nil
+}
+
+// caldavError turns a CalDAV failure into a reply. Credential and address
+// problems are the user's to fix; anything else is logged.
+func (b *Bot) caldavError(action string, err error) error {
+	if errors.Is(err, caldav.ErrInsecureURL) {
+		return userError{"That server sent me to an address that isn't https, so I stopped before sending your password."}
+	}
+	if errors.Is(err, netguard.ErrPrivateAddress) {
+		return userError{"CalDAV sync only works with servers on the public internet."}
+	}
+	if errors.Is(err, caldav.ErrNoTaskList) {
+		return userError{"I couldn't find a task list on that server. Check the address, or create a Reminders or Tasks list there first."}
+	}
+	var httpErr *caldav.HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.Status {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return userError{"The server rejected that username or password. iCloud needs an app-specific password from appleid.apple.com."}
+		case http.StatusNotFound, http.StatusMethodNotAllowed:
+			return userError{"I couldn't find a task list on that server. Check the address, or create a Reminders or Tasks list there first."}
+		}
+	}
+	b.logger.Printf("caldav: %s: %v", action, err)
+	return userError{"I couldn't reach your CalDAV server right now. Please try again later."}
+}
+
+// syncCalDAV completes reminders whose tasks were ticked off on the server,
+// and writes open reminders that aren't there yet. A reminder whose task was
+// deleted on the server isn't written back.
+func (b *Bot) syncCalDAV(ctx context.Context, conn *model.CalDAVConnection) (pushed, completed int, err error) {
+	if b.caldav == nil {
+		return 0, 0, errors.New("caldav client not configured")
+	}
+	acct, err := b.caldavAccount(conn)
+	if err != nil {
+		return 0, 0, err
+	}
+	todos, err := b.caldav.Todos(ctx, acct)
+	if err != nil {
+		return 0, 0, err
+	}
+	byUID := make(map[string]caldav.Todo, len(todos))
+	for _, todo := range todos {
+		byUID[todo.UID] = todo
+	}
+
+	var open []model.Reminder
+	if err := b.db.WithContext(ctx).Where("user_id = ? AND kind = ? AND completed_at IS NULL", conn.UserID, model.KindReminder).Find(&open).Error; err != nil {
+		return 0, 0, err
+	}
+	loc := b.location(b.preferences(conn.UserID))
+	now := time.Now().In(loc)
+	for i := range open {
+		r := &open[i]
+		todo, ok := byUID[caldavUID(*r)]
+		switch {
+		case ok && todo.Completed != nil:
+			if _, err := b.completeReminder(ctx, r, now); err != nil {
+				b.logger.Printf("caldav: complete %s for %s: %v", r.ShortID(), conn.UserID, err)
+				continue
+			}
+			completed++
+		case !ok && !r.CalDAVSynced:
+			if err := b.caldav.Put(ctx, acct, caldavTodo(*r, loc, false)); err != nil {
+				return pushed, completed, err
+			}
+			if err := b.db.WithContext(ctx).Model(r).Update("caldav_synced", true).Error; err != nil {
+				return pushed, completed, err
+			}
+			pushed++
+		}
+	}
+
+	if err := b.db.Model(conn).Update("last_synced_at", now).Error; err != nil {
+		b.logger.Printf("caldav: record sync for %s: %v", conn.UserID, err)
+	}
+	return pushed, completed, nil
+}
+
+// syncCalDAVConnections runs a sync for every connected user.
+func (b *Bot) syncCalDAVConnections() {
+	var conns []model.CalDAVConnection
+	if err := b.db.Find(&conns).Error; err != nil {
+		b.logger.Printf("scheduler: fetch caldav connections: %v", err)
+		return
+	}
+	for i := range conns {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		if _, _, err := b.syncCalDAV(ctx, &conns[i]); err != nil {
+			b.logger.Printf("scheduler: caldav sync for %s: %v", conns[i].UserID, err)
+		}
+		cancel()
+	}
+}
+
+// pushToCalDAV mirrors a reminder lifecycle event into the owner's task list
+// in the background. Habits, countdowns, and yearly reminders aren't tasks,
+// so they stay out of it.
+func (b *Bot) pushToCalDAV(eventType string, reminder model.Reminder) {
+	if b.caldav == nil || reminder.Kind != model.KindReminder {
+		return
+	}
+	switch eventType {
+	case webhook.EventReminderCreated, webhook.EventReminderUpdated, webhook.EventReminderCompleted, webhook.EventReminderDeleted:
+	default:
+		return
+	}
+
+	var conn model.CalDAVConnection
+	err := b.db.Where("user_id = ?", reminder.UserID).First(&conn).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		b.logger.Printf("caldav: load connection for %s: %v", reminder.UserID, err)
+		return
+	}
+
+	b.sends.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		acct, err := b.caldavAccount(&conn)
+		if err != nil {
+			b.logger.Printf("caldav: %s reminder %d: %v", eventType, reminder.ID, err)
+			return
+		}
+		if eventType == webhook.EventReminderDeleted {
+			err = b.caldav.Delete(ctx, acct, caldavUID(reminder))
+		} else {
+			// A repeating reminder moves to its next date when it is done,
+			// so its task stays open.
+			done := eventType == webhook.EventReminderCompleted && reminder.RRule == ""
+			todo := caldavTodo(reminder, b.location(b.preferences(reminder.UserID)), done)
+			if err = b.caldav.Put(ctx, acct, todo); err == nil && !reminder.CalDAVSynced {
+				err = b.db.Model(&model.Reminder{}).Where("id = ?", reminder.ID).Update("caldav_synced", true).Error
+			}
+		}
+		if err != nil {
+			b.logger.Printf("caldav: %s reminder %d: %v", eventType, reminder.ID, err)
+		}
+	})
+}
+
+// caldavAccount decrypts the stored password for conn.
+func (b *Bot) caldavAccount(conn *model.CalDAVConnection) (caldav.Account, error) {
+	password, err := b.openCredential(conn.Password)
+	if err != nil {
+		return caldav.Account{}, err
+	}
+	return caldav.Account{CollectionURL: conn.CollectionURL, Username: conn.Username, Password: password}, nil
+}
+
+// caldavUID names the task for r. It matches the UID of calendar invites,
+// so a calendar app can tell the two belong together.
+func caldavUID(r model.Reminder) string {
+	return fmt.Sprintf("reminder-%d@mymemo", r.ID)
+}
+
+// caldavTodo renders r as a task, with its due date as a floating local
+// time in loc like calendar invites.
+func caldavTodo(r model.Reminder, loc *time.Location, done bool) caldav.Todo {
+	todo := caldav.Todo{
+		UID:      caldavUID(r),
+		Summary:  fallback(r.Summary, r.Content),
+		Priority: r.Priority,
+	}
+	if r.Summary != "" && r.Summary != r.Content {
+		todo.Description = r.Content
+	}
+	if r.DueAt != nil {
+		due := r.DueAt.In(loc)
+		todo.Due = &due
+	}
+	switch {
+	case r.CompletedAt != nil:
+		todo.Completed = r.CompletedAt
+	case done:
+		now := time.Now()
+		todo.Completed = &now
+	}
+	return todo
+}
@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cancelWords end whatever question the bot is waiting on an answer to.
+var cancelWords = map[string]bool{
+	"cancel": true, "cancel that": true, "never mind": true, "nevermind": true, "nvm": true, "forget it": true, "abort": true,
+}
+
+// handleCancel drops any pending conversation state, such as a priority
+// question, a confirmation, or a choice between reminders, and says what was
+// dropped. It reports false for other messages.
+func (b *Bot) handleCancel(userID, lowerBody string) (string, bool) {
+	if !cancelWords[strings.Trim(strings.TrimSpace(lowerBody), ".!")] {
+		return "", false
+	}
+	state, ok := b.state.Cancel(userID)
+	switch {
+	case !ok:
+		return "There's nothing to cancel.", true
+	case state.AwaitingPriority:
+		return fmt.Sprintf("Okay, I dropped your unsaved reminder '%s'.", truncate(state.PendingMessage, 60)), true
+	case len(state.DeleteCandidates) > 0:
+		return "Okay, I won't delete anything.", true
+	case len(state.Clarify) > 0:
+		return "Okay, I'll leave it. Try saying it another way.", true
+	case state.Reschedule != nil:
+		return "Okay, I'll leave that reminder's due date as it is.", true
+	case len(state.Rebalance) > 0:
+		return "Okay, your priorities stay as they are.", true
+	case state.Location != nil:
+		return "Okay, I forgot the location you shared.", true
+	case state.Attachment != nil:
+		return "Okay, I won't add the file you sent to a reminder.", true
+	}
+	return "There's nothing to cancel.", true
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/config"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_-]+)`)
+
+// categories returns the configured reminder categories.
+func (b *Bot) categories() []string {
+	b.mu.RLock()
+	value := b.cfg.ReminderCategories
+	b.mu.RUnlock()
+	categories, err := config.ParseCategories(value)
+	if err != nil {
+		// Validate and Reload reject bad values, so this only guards tests.
+		return config.DefaultCategories
+	}
+	return categories
+}
+
+// categorize picks a category for a new reminder. A hashtag naming one, as
+// in "send the invoice #work", wins; otherwise the model is asked. It
+// returns an empty string when nothing fits or the model isn't available.
+func (b *Bot) categorize(ctx context.Context, userID, content string) string {
+	categories := b.categories()
+	for _, m := range hashtagPattern.FindAllStringSubmatch(strings.ToLower(content), -1) {
+		if slices.Contains(categories, m[1]) {
+			return m[1]
+		}
+	}
+	if !b.useOpenAI(ctx, userID) {
+		return ""
+	}
+	category, err := b.openAI.Categorize(ctx, content, categories)
+	if err != nil {
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("openai categorize error: %v", err)
+		}
+		return ""
+	}
+	return category
+}
+
+var categoryTermPrefix = regexp.MustCompile(`^(?:in|category)\s+`)
+
+// matchCategoryTerm matches a category name, optionally after "in" or
+// "category", at the start of a list filter. It returns the category and
+// the length of text consumed.
+func matchCategoryTerm(rest string, categories []string) (string, int) {
+	skip := len(categoryTermPrefix.FindString(rest))
+	word := rest[skip:]
+	if end := strings.IndexAny(word, " ,"); end >= 0 {
+		word = word[:end]
+	}
+	if slices.Contains(categories, word) {
+		return word, skip + len(word)
+	}
+	return "", 0
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/twilio"
+)
+
+// discordUserPrefix marks user IDs that belong to Discord users rather than
+// WhatsApp numbers.
+const discordUserPrefix = "discord:"
+
+// send delivers msg over the named channel, with its formatting written the
+// way that channel shows it.
+func (b *Bot) send(ctx context.Context, name string, msg channel.Message) error {
+	msg.Subject = plainText(msg.Subject)
+	msg.Body = renderFormatting(msg.Body, name)
+	sender, ok := b.channels[name]
+	if name == channel.WhatsApp {
+		// Tenant users hear back from their workspace's own number.
+		if s := b.tenantSender(ctx, msg.To); s != nil {
+			sender, ok = s, true
+		} else if b.pool != nil {
+			err := b.sendFromPool(ctx, msg)
+			if twilio.IsNotJoined(err) {
+				b.markUnjoined(msg.To)
+			}
+			return err
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%s channel not configured", name)
+	}
+	err := sender.Send(ctx, msg)
+	if name == channel.WhatsApp && twilio.IsNotJoined(err) {
+		b.markUnjoined(msg.To)
+	}
+	return err
+}
+
+// notify sends body to a user on the chat channel they talk to the bot on,
+// translated into their language.
+func (b *Bot) notify(ctx context.Context, userID, body string) error {
+	name, to := chatChannel(userID)
+	msg := channel.Message{To: to, Body: b.translate(userID, body)}
+	if err := b.send(ctx, name, msg); err != nil {
+		return err
+	}
+	b.archiveMessage(ctx, userID, model.DirectionOut, msg.Body)
+	return nil
+}
+
+// chatChannel returns the channel and recipient address for a user ID.
+func chatChannel(userID string) (name, to string) {
+	if id, ok := strings.CutPrefix(userID, discordUserPrefix); ok {
+		return channel.Discord, id
+	}
+	return channel.WhatsApp, userID
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+// minIntentConfidence is the confidence below which the bot asks what a
+// message meant instead of guessing.
+const minIntentConfidence = 0.6
+
+// intentUnclear marks a message the model couldn't classify confidently.
+const intentUnclear myopenai.Intent = "unclear"
+
+// intentChoices describes the intents a clarifying question can offer, and
+// the words that pick each one in a reply.
+var intentChoices = map[myopenai.Intent]struct {
+	phrase string
+	words  []string
+}{
+	myopenai.IntentAddReminder:    {"add a reminder", []string{"add", "remind", "save"}},
+	myopenai.IntentListReminders:  {"see your reminders", []string{"list", "see", "show"}},
+	myopenai.IntentDeleteReminder: {"delete a reminder", []string{"delete", "remove"}},
+	myopenai.IntentClearReminders: {"clear all your reminders", []string{"clear"}},
+	myopenai.IntentHelp:           {"see what I can do", []string{"help"}},
+	myopenai.IntentConnect:        {"connect a phone to me", []string{"connect", "join"}},
+}
+
+// clarifyOptions returns the intents to offer for an unsure classification,
+// most likely first.
+func clarifyOptions(c myopenai.Classification) []myopenai.Intent {
+	var options []myopenai.Intent
+	for _, intent := range []myopenai.Intent{c.Intent, c.Alternative} {
+		if _, ok := intentChoices[intent]; ok && !slices.Contains(options, intent) {
+			options = append(options, intent)
+		}
+	}
+	if len(options) == 0 {
+		options = append(options, myopenai.IntentAddReminder)
+	}
+	return options
+}
+
+// askToClarify remembers message and returns a question offering the likely
+// intents.
+func (b *Bot) askToClarify(userID, message string, c myopenai.Classification) string {
+	options := clarifyOptions(c)
+	b.state.SetClarification(userID, message, options)
+	if len(options) == 1 {
+		return fmt.Sprintf("Did you want to %s? Reply yes or no.", intentChoices[options[0]].phrase)
+	}
+	return fmt.Sprintf("Did you want to %s or %s? Reply 1 or 2.", intentChoices[options[0]].phrase, intentChoices[options[1]].phrase)
+}
+
+// handleClarification answers a pending clarifying question. A reply that
+// picks none of the options is handled as a new message.
+func (b *Bot) handleClarification(ctx context.Context, userID, lowerBody string) (string, bool) {
+	message, options, ok := b.state.PopClarification(userID)
+	if !ok {
+		return "", false
+	}
+	answer := strings.Trim(strings.TrimSpace(lowerBody), ".!")
+	switch answer {
+	case "no", "nope", "neither":
+		return "Okay, I'll leave it. Try saying it another way.", true
+	case "yes", "yeah", "yep", "y":
+		return b.runClarified(ctx, userID, message, options[0]), true
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		return b.runClarified(ctx, userID, message, options[n-1]), true
+	}
+	if words := strings.Fields(answer); len(words) <= 3 {
+		for _, intent := range options {
+			for _, word := range intentChoices[intent].words {
+				if slices.Contains(words, word) {
+					return b.runClarified(ctx, userID, message, intent), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// runClarified carries out the original message with the intent the user
+// picked.
+func (b *Bot) runClarified(ctx context.Context, userID, message string, intent myopenai.Intent) string {
+	var keyword string
+	if intent == myopenai.IntentDeleteReminder {
+		keyword = extractDeleteKeyword(message)
+	}
+	return b.runIntent(ctx, userID, message, strings.ToLower(message), intent, keyword)
+}
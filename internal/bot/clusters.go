@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+const (
+	// clusterMinReminders is how many reminders a list page needs before
+	// it is grouped by topic.
+	clusterMinReminders = 6
+	// topicDistance is how close an uncategorized reminder must be to a
+	// categorized one to join its topic.
+	topicDistance = 0.35
+	otherTopic    = "Other"
+)
+
+// topicGroup is one section of a list grouped by topic.
+type topicGroup struct {
+	Topic     string
+	Reminders []model.Reminder
+}
+
+// groupByTopic sorts a page of reminders into topics such as "Work" and
+// "Health", keeping list order within each. Topics come from categories; a
+// reminder without one takes the category of its closest categorized
+// neighbour when embeddings are available. It returns nil when grouping
+// would not help: a short page, a category filter, or a single topic.
+func (b *Bot) groupByTopic(ctx context.Context, userID string, reminders []model.Reminder, filter listFilter) []topicGroup {
+	if len(reminders) < clusterMinReminders || filter.Category != "" {
+		return nil
+	}
+
+	var groups []topicGroup
+	index := make(map[string]int)
+	var other []model.Reminder
+	for _, r := range reminders {
+		topic := r.Category
+		if topic == "" {
+			topic = b.inferTopic(ctx, userID, r)
+		}
+		if topic == "" {
+			other = append(other, r)
+			continue
+		}
+		i, ok := index[topic]
+		if !ok {
+			i = len(groups)
+			index[topic] = i
+			groups = append(groups, topicGroup{Topic: topicTitle(topic)})
+		}
+		groups[i].Reminders = append(groups[i].Reminders, r)
+	}
+	if len(other) > 0 {
+		groups = append(groups, topicGroup{Topic: otherTopic, Reminders: other})
+	}
+	if len(groups) < 2 {
+		return nil
+	}
+	return groups
+}
+
+// inferTopic returns the category of the categorized open reminder closest
+// in meaning to r, or "" when none is close enough.
+func (b *Bot) inferTopic(ctx context.Context, userID string, r model.Reminder) string {
+	if !b.canEmbed() {
+		return ""
+	}
+	var stored model.ReminderEmbedding
+	err := b.db.WithContext(ctx).Where("reminder_id = ? AND model = ?", r.ID, myopenai.EmbeddingModel).Limit(1).Find(&stored).Error
+	if err != nil || stored.ReminderID == 0 {
+		return ""
+	}
+	neighbors, err := b.nearestReminders(ctx, userID, stored.Embedding, topicDistance, 5)
+	if err != nil {
+		b.logger.Printf("infer topic: %v", err)
+		return ""
+	}
+	for _, n := range neighbors {
+		if n.ID != r.ID && n.Category != "" {
+			return n.Category
+		}
+	}
+	return ""
+}
+
+// topicTitle turns a category such as "errands" into a heading.
+func topicTitle(category string) string {
+	return strings.ToUpper(category[:1]) + category[1:]
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// CommandRequest is a chat message offered to a registered command.
+type CommandRequest struct {
+	UserID string
+	// Body is the message as sent, trimmed of surrounding space.
+	Body string
+}
+
+// CommandFunc handles a chat message. It returns the reply and true when the
+// message was meant for it, or false to let the bot carry on as usual.
+type CommandFunc func(ctx context.Context, req CommandRequest) (string, bool)
+
+// CommandRegistry holds chat commands added from outside the bot, such as an
+// "expense:" tracker. The bot offers each message to them in registration
+// order after its own commands and before working out the message's intent,
+// so they can't shadow built-in commands. The zero value is ready to use.
+type CommandRegistry struct {
+	mu       sync.RWMutex
+	commands []registeredCommand
+}
+
+type registeredCommand struct {
+	name string
+	help string
+	fn   CommandFunc
+}
+
+// Register adds a command under a unique name. help, if not empty, is a line
+// for the help reply, such as `"Expense: 12 lunch" to log spending`.
+func (r *CommandRegistry) Register(name, help string, fn CommandFunc) error {
+	if name == "" || fn == nil {
+		return fmt.Errorf("command needs a name and a handler")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cmd := range r.commands {
+		if cmd.name == name {
+			return fmt.Errorf("command %q is already registered", name)
+		}
+	}
+	r.commands = append(r.commands, registeredCommand{name: name, help: help, fn: fn})
+	return nil
+}
+
+// PrefixCommand adapts fn into a CommandFunc for messages starting with
+// prefix, ignoring case, such as "expense:". fn gets the rest of the message.
+func PrefixCommand(prefix string, fn func(ctx context.Context, userID, args string) string) CommandFunc {
+	return func(ctx context.Context, req CommandRequest) (string, bool) {
+		if len(req.Body) < len(prefix) || !strings.EqualFold(req.Body[:len(prefix)], prefix) {
+			return "", false
+		}
+		return fn(ctx, req.UserID, strings.TrimSpace(req.Body[len(prefix):])), true
+	}
+}
+
+// handle offers a message to each command in turn.
+func (r *CommandRegistry) handle(ctx context.Context, req CommandRequest) (string, bool) {
+	r.mu.RLock()
+	commands := r.commands
+	r.mu.RUnlock()
+	for _, cmd := range commands {
+		if msg, ok := cmd.fn(ctx, req); ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// helpLines returns the help lines of the registered commands, one per line
+// in the help reply's format.
+func (r *CommandRegistry) helpLines() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var sb strings.Builder
+	for _, cmd := range r.commands {
+		if cmd.help != "" {
+			sb.WriteString("\n- " + cmd.help)
+		}
+	}
+	return sb.String()
+}
+
+// Commands returns the registry for adding chat commands to the bot.
+func (b *Bot) Commands() *CommandRegistry {
+	return &b.commands
+}
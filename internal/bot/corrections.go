@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+// correctionWindow is how long after saving a reminder a correction such as
+// "no, make it Friday" changes it instead of saving a new one.
+const correctionWindow = 5 * time.Minute
+
+// justSaved is the reminder a correction applies to.
+type justSaved struct {
+	ReminderID uint
+	At         time.Time
+}
+
+var (
+	correctionLeadPattern     = regexp.MustCompile(`^(?:no|nope|actually|sorry|oops|wait)\b[,.!]?\s*`)
+	correctionPriorityPattern = regexp.MustCompile(`^(?:(?:change|set|make)\s+(?:it|that|the)?\s*priority\s+(?:to\s+)?|(?:make\s+(?:it|that)\s+)?priority\s+)([1-5])[.!]*$`)
+	correctionDayPattern      = regexp.MustCompile(`^(?:make\s+(?:it|that)|move\s+(?:it|that)\s+to|change\s+(?:it|that)\s+to|i\s+meant)\s+(.+?)[.!]*$`)
+)
+
+// handleCorrection patches the reminder saved in the last few minutes when
+// the user corrects it: "no, make it Friday", "change priority to 2", "make
+// it evening", or "undo". It reports false for other messages, and once the
+// window has passed.
+func (b *Bot) handleCorrection(ctx context.Context, userID, lowerBody string) (string, bool) {
+	now := time.Now().In(b.cfg.LocalTimezone)
+	saved, ok := b.state.JustSaved(userID, now)
+	if !ok {
+		return "", false
+	}
+	text := strings.TrimSpace(correctionLeadPattern.ReplaceAllString(strings.TrimSpace(lowerBody), ""))
+
+	updates := map[string]any{}
+	var change string
+	switch {
+	case text == "undo" || text == "undo that":
+		return b.undoSave(ctx, userID, saved.ReminderID), true
+	case correctionPriorityPattern.MatchString(text):
+		priority, _ := strconv.Atoi(correctionPriorityPattern.FindStringSubmatch(text)[1])
+		updates["priority"] = priority
+		change = fmt.Sprintf("priority %d", priority)
+	default:
+		m := correctionDayPattern.FindStringSubmatch(text)
+		if m == nil {
+			return "", false
+		}
+		when := strings.TrimPrefix(strings.TrimPrefix(m[1], "in the "), "this ")
+		if when == "tonight" {
+			when = model.TimeEvening
+		}
+		if _, ok := defaultBucketHours[when]; ok {
+			updates["time_of_day"] = when
+			change = "sent in the " + when
+			break
+		}
+		day, ok := parseDayWord(when, now)
+		if !ok {
+			return "", false
+		}
+		updates["due_at"] = day
+		change = "due " + day.Format("Mon Jan 2")
+	}
+
+	var r model.Reminder
+	if err := b.db.WithContext(ctx).Where("id = ? AND user_id = ? AND completed_at IS NULL", saved.ReminderID, userID).First(&r).Error; err != nil {
+		return "", false
+	}
+	if day, ok := updates["due_at"].(time.Time); ok {
+		due := b.workingDue(r, day)
+		updates["due_at"] = due
+		if r.RRule != "" {
+			updates["occurs_at"] = occursAt(day, due)
+		}
+		change = "due " + due.Format("Mon Jan 2")
+	}
+	if err := b.db.WithContext(ctx).Model(&r).Updates(updates).Error; err != nil {
+		b.logger.Printf("correct %s: %v", r.ShortID(), err)
+		return "I couldn't change that reminder. Please try again later.", true
+	}
+	b.emit(webhook.EventReminderUpdated, r, "")
+	return fmt.Sprintf("Fixed: %s %s is now %s.", r.ShortID(), fallback(r.Summary, r.Content), change), true
+}
+
+// undoSave deletes the reminder that was just saved.
+func (b *Bot) undoSave(ctx context.Context, userID string, reminderID uint) string {
+	deleted, err := b.removeReminders(ctx, b.db.Where("id = ? AND user_id = ?", reminderID, userID))
+	if err != nil {
+		b.logger.Printf("undo save: %v", err)
+		return "I couldn't remove that reminder. Please try again later."
+	}
+	b.state.ClearJustSaved(userID)
+	if len(deleted) == 0 {
+		return "That reminder is already gone."
+	}
+	return fmt.Sprintf("Undone, I didn't keep '%s'.", fallback(deleted[0].Summary, deleted[0].Content))
+}
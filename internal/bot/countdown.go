@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+const defaultCountdownPriority = 3
+
+var (
+	countdownRequestPattern = regexp.MustCompile(`(?i)^(daily\s+|weekly\s+)?countdown\s+(?:to|until|till)\s+([^:]+?)\s*:\s*(.+)$`)
+	ordinalSuffix           = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)\b`)
+)
+
+// dayLayouts are the date formats accepted in chat, with and without a year.
+var dayLayouts = []struct {
+	layout  string
+	hasYear bool
+}{
+	{"2006-01-02", true},
+	{"Jan 2 2006", true},
+	{"January 2 2006", true},
+	{"2 Jan 2006", true},
+	{"2 January 2006", true},
+	{"Jan 2", false},
+	{"January 2", false},
+	{"2 Jan", false},
+	{"2 January", false},
+}
+
+// parseDay parses a calendar date such as "Dec 25", "25 December 2026", or
+// "2026-12-25" in now's location. A date without a year is the next one on
+// or after today.
+func parseDay(text string, now time.Time) (time.Time, bool) {
+	text = strings.Join(strings.Fields(strings.ReplaceAll(text, ",", " ")), " ")
+	text = ordinalSuffix.ReplaceAllString(text, "$1")
+	for _, l := range dayLayouts {
+		t, err := time.ParseInLocation(l.layout, text, now.Location())
+		if err != nil {
+			continue
+		}
+		if l.hasYear {
+			return t, true
+		}
+		day := time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+		if day.Before(startOfDay(now)) {
+			day = day.AddDate(1, 0, 0)
+		}
+		return day, true
+	}
+	return time.Time{}, false
+}
+
+// daysUntil counts calendar days from now to day; zero means today.
+func daysUntil(day, now time.Time) int {
+	from, to := startOfDay(now), startOfDay(day.In(now.Location()))
+	// Round rather than truncate so a DST change doesn't lose a day.
+	return int((to.Sub(from) + 12*time.Hour) / (24 * time.Hour))
+}
+
+// handleCountdownCommand saves "countdown to Dec 25: trip to Goa", which
+// sends an "N days left" message every day (or every week with "weekly
+// countdown …") until the date. It reports false for other messages.
+func (b *Bot) handleCountdownCommand(ctx context.Context, userID, body string) (string, bool) {
+	m := countdownRequestPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if m == nil {
+		return "", false
+	}
+	now := time.Now().In(b.cfg.LocalTimezone)
+	day, ok := parseDay(m[2], now)
+	if !ok {
+		return fmt.Sprintf("I couldn't read the date '%s'. Try 'countdown to Dec 25: trip to Goa'.", strings.TrimSpace(m[2])), true
+	}
+	if daysUntil(day, now) < 0 {
+		return fmt.Sprintf("%s has already passed.", day.Format("Jan 2, 2006")), true
+	}
+
+	content := strings.TrimSpace(m[3])
+	interval := model.IntervalDaily
+	if strings.EqualFold(strings.TrimSpace(m[1]), "weekly") {
+		interval = model.IntervalWeekly
+	}
+	countdown := &model.Reminder{
+		UserID:   userID,
+		Content:  content,
+		Priority: defaultCountdownPriority,
+		Summary:  content,
+		Kind:     model.KindCountdown,
+		Interval: interval,
+		DueAt:    &day,
+	}
+	if err := b.db.WithContext(ctx).Create(countdown).Error; err != nil {
+		b.logger.Printf("save countdown: %v", err)
+		return "I couldn't save the countdown. Please try again.", true
+	}
+	b.emit(webhook.EventReminderCreated, *countdown, "")
+	every := "every day"
+	if interval == model.IntervalWeekly {
+		every = "every week"
+	}
+	return fmt.Sprintf("Counting down to %s on %s — %s. I'll send you the days left %s.",
+		content, day.Format("Mon Jan 2"), daysLeftLabel(daysUntil(day, now)), every), true
+}
+
+// countdownDue reports whether a countdown's message goes out today: every
+// day, or for weekly ones on whole weeks before the date and on the day.
+func countdownDue(r model.Reminder, now time.Time) bool {
+	left := daysUntil(*r.DueAt, now)
+	if left < 0 {
+		return false
+	}
+	return r.Interval != model.IntervalWeekly || left%7 == 0
+}
+
+func countdownMessage(r model.Reminder, now time.Time) string {
+	text := fallback(r.Summary, r.Content)
+	left := daysUntil(*r.DueAt, now)
+	if left == 0 {
+		return fmt.Sprintf("Today's the day: %s!", text)
+	}
+	return fmt.Sprintf("Countdown: %s until %s (%s).", daysLeftLabel(left), text, r.DueAt.In(now.Location()).Format("Jan 2"))
+}
+
+func daysLeftLabel(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "1 day left"
+	}
+	return fmt.Sprintf("%d days left", days)
+}
+
+// finishCountdowns completes the user's countdowns whose date has passed.
+func (b *Bot) finishCountdowns(ctx context.Context, userID string, now time.Time) {
+	err := b.db.WithContext(ctx).Model(&model.Reminder{}).
+		Where("user_id = ? AND kind = ? AND completed_at IS NULL AND due_at < ?", userID, model.KindCountdown, startOfDay(now)).
+		Update("completed_at", now).Error
+	if err != nil {
+		b.logger.Printf("scheduler: finish countdowns for %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// errNoCredentialsKey means CREDENTIALS_KEY is unset, so passwords users
+// give the bot can't be stored.
+var errNoCredentialsKey = errors.New("CREDENTIALS_KEY is not set")
+
+// credentialsCipher returns AES-256-GCM keyed by CREDENTIALS_KEY.
+func (b *Bot) credentialsCipher() (cipher.AEAD, error) {
+	if b.cfg.CredentialsKey == "" {
+		return nil, errNoCredentialsKey
+	}
+	key := sha256.Sum256([]byte(b.cfg.CredentialsKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealCredential encrypts a password for storage.
+func (b *Bot) sealCredential(plain string) (string, error) {
+	aead, err := b.credentialsCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+// openCredential decrypts a password sealed by sealCredential.
+func (b *Bot) openCredential(sealed string) (string, error) {
+	aead, err := b.credentialsCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New("stored credential is malformed")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt stored credential: %w", err)
+	}
+	return string(plain), nil
+}
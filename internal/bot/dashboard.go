@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"embed"
+	"net/http"
+)
+
+//go:embed web/index.html web/login.html web/join.html
+var dashboardFiles embed.FS
+
+// DashboardHandler serves the web dashboard: the reminder list at "/" for
+// signed-in users and the sign-in page at "/login".
+func (b *Bot) DashboardHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/":
+			if _, ok := b.sessionUser(r); !ok {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			serveDashboardPage(w, "web/index.html")
+		case "/login":
+			serveDashboardPage(w, "web/login.html")
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func serveDashboardPage(w http.ResponseWriter, name string) {
+	page, err := dashboardFiles.ReadFile(name)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	_, _ = w.Write(page)
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+)
+
+// DebugVars is a snapshot of the process's runtime state and the bot's
+// in-memory queues and caches, for chasing leaks.
+type DebugVars struct {
+	Goroutines         int    `json:"goroutines"`
+	HeapAllocBytes     uint64 `json:"heap_alloc_bytes"`
+	HeapObjects        uint64 `json:"heap_objects"`
+	GCCycles           uint32 `json:"gc_cycles"`
+	ScheduledJobs      int    `json:"scheduled_jobs"`
+	PendingSends       int    `json:"pending_sends"`
+	ConversationStates int    `json:"conversation_states"`
+	RecentExchanges    int    `json:"recent_exchange_users"`
+	EventSubscribers   int    `json:"event_subscribers"`
+	TenantSenders      int    `json:"tenant_senders"`
+}
+
+// DebugHandler serves the pprof profiles under /debug/pprof/ and DebugVars
+// at /debug/vars, behind the admin token. It must be mounted on a mux other
+// than http.DefaultServeMux, where importing net/http/pprof registers the
+// profiles without any auth.
+func (b *Bot) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, b.DebugVars())
+	})
+	return b.requireAdmin(mux)
+}
+
+// DebugVars reports the current DebugVars.
+func (b *Bot) DebugVars() DebugVars {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	vars := DebugVars{
+		Goroutines:         runtime.NumGoroutine(),
+		HeapAllocBytes:     mem.HeapAlloc,
+		HeapObjects:        mem.HeapObjects,
+		GCCycles:           mem.NumGC,
+		PendingSends:       b.sends.Len(),
+		ConversationStates: b.state.Len(),
+		RecentExchanges:    b.recent.Len(),
+		EventSubscribers:   b.events.Len(),
+	}
+	if b.cron != nil {
+		vars.ScheduledJobs = len(b.cron.Entries())
+	}
+	b.tenantMu.Lock()
+	vars.TenantSenders = len(b.tenantSenders)
+	b.tenantMu.Unlock()
+	return vars
+}
@@ -0,0 +1,258 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/twilio"
+	"gorm.io/gorm"
+)
+
+const (
+	// followUpSpec runs every ten minutes, resending or escalating reminder
+	// messages that failed or went unread.
+	followUpSpec = "*/10 * * * *"
+	// unreadFollowUpAfter is how long a priority 5 reminder may sit unread
+	// before it is sent again.
+	unreadFollowUpAfter = 2 * time.Hour
+	// followUpWindow is how old a message may be and still get a follow-up;
+	// past it the next dispatch covers the reminder anyway.
+	followUpWindow = 24 * time.Hour
+	// readQuietPeriod keeps a dispatch from repeating a reminder the user
+	// read this recently.
+	readQuietPeriod = 12 * time.Hour
+)
+
+// statusRank orders Twilio message statuses so late or repeated callbacks
+// never move a delivery backwards.
+var statusRank = map[string]int{
+	model.DeliveryQueued:      0,
+	"sending":                 1,
+	model.DeliverySent:        2,
+	model.DeliveryDelivered:   3,
+	model.DeliveryRead:        4,
+	model.DeliveryFailed:      5,
+	model.DeliveryUndelivered: 5,
+}
+
+// trackDeliveries reports whether reminder messages get Twilio status
+// callbacks.
+func (b *Bot) trackDeliveries() bool {
+	return b.cfg.TwilioStatusCallbacks && b.cfg.PublicBaseURL != ""
+}
+
+// notifyReminder sends body about reminder like notify and records the
+// message in the reminder's delivery history. WhatsApp messages get a status
+// callback when tracking is on, so their status can be followed. followUp
+// marks a resend, which never gets a follow-up of its own.
+func (b *Bot) notifyReminder(ctx context.Context, reminder model.Reminder, body string, followUp bool) error {
+	name, to := chatChannel(reminder.UserID)
+	msg := channel.Message{To: to, Body: b.translate(reminder.UserID, body)}
+	var delivery *model.ReminderDelivery
+	if name == channel.WhatsApp && reminder.ID != 0 && b.trackDeliveries() {
+		var err error
+		delivery, msg.StatusCallback, err = b.trackDelivery(ctx, reminder, followUp)
+		if err != nil {
+			b.logger.Printf("track delivery of reminder %d: %v", reminder.ID, err)
+		}
+	}
+	err := b.send(ctx, name, msg)
+	if delivery == nil {
+		b.recordDelivery(ctx, reminder, name, err)
+	} else if err != nil {
+		// The caller deals with a message that fails outright.
+		if err := b.db.WithContext(ctx).Model(delivery).Updates(map[string]any{"status": model.DeliveryFailed, "followed_up": true}).Error; err != nil {
+			b.logger.Printf("track delivery of reminder %d: %v", reminder.ID, err)
+		}
+	}
+	if err == nil {
+		b.archiveMessage(ctx, reminder.UserID, model.DirectionOut, msg.Body)
+	}
+	return err
+}
+
+// recordDelivery adds a message about reminder on channelName, which failed
+// if sendErr is set, to the reminder's delivery history.
+func (b *Bot) recordDelivery(ctx context.Context, reminder model.Reminder, channelName string, sendErr error) {
+	if reminder.ID == 0 {
+		return
+	}
+	status := model.DeliverySent
+	if sendErr != nil {
+		status = model.DeliveryFailed
+	}
+	delivery := &model.ReminderDelivery{
+		ReminderID: reminder.ID,
+		UserID:     reminder.UserID,
+		Channel:    channelName,
+		Status:     status,
+		FollowedUp: true,
+	}
+	if err := b.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		b.logger.Printf("record delivery of reminder %d: %v", reminder.ID, err)
+	}
+}
+
+// trackDelivery records a WhatsApp message about to be sent about reminder
+// and returns its status callback URL.
+func (b *Bot) trackDelivery(ctx context.Context, reminder model.Reminder, followUp bool) (*model.ReminderDelivery, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	delivery := &model.ReminderDelivery{
+		ReminderID:   reminder.ID,
+		UserID:       reminder.UserID,
+		Channel:      channel.WhatsApp,
+		Status:       model.DeliveryQueued,
+		CallbackHash: hashToken(token),
+		FollowedUp:   followUp,
+	}
+	if err := b.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return nil, "", err
+	}
+	query := url.Values{"delivery": {strconv.FormatUint(uint64(delivery.ID), 10)}, "token": {token}}
+	return delivery, b.cfg.PublicBaseURL + "/twilio/status?" + query.Encode(), nil
+}
+
+// StatusCallbackHandler receives Twilio's status callbacks for reminder
+// messages. Each callback URL carries the delivery ID and a one-off token.
+func (b *Bot) StatusCallbackHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		id, err := strconv.ParseUint(r.URL.Query().Get("delivery"), 10, 64)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var delivery model.ReminderDelivery
+		if err := b.db.WithContext(r.Context()).First(&delivery, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			b.logger.Printf("status callback: load delivery %d: %v", id, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if delivery.CallbackHash == "" || subtle.ConstantTimeCompare([]byte(hashToken(r.URL.Query().Get("token"))), []byte(delivery.CallbackHash)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if r.PostFormValue("ErrorCode") == strconv.Itoa(twilio.NotJoinedCode) {
+			b.markUnjoined(delivery.UserID)
+		}
+		if sid := r.PostFormValue("MessageSid"); sid != "" && delivery.MessageSID == "" {
+			if err := b.db.WithContext(r.Context()).Model(&delivery).Update("message_sid", sid).Error; err != nil {
+				b.logger.Printf("status callback: delivery %d: %v", id, err)
+			}
+		}
+		if err := b.updateDeliveryStatus(r.Context(), &delivery, r.PostFormValue("MessageStatus"), time.Now()); err != nil {
+			b.logger.Printf("status callback: delivery %d: %v", id, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// updateDeliveryStatus moves delivery on to status, ignoring statuses it has
+// already passed and ones the bot doesn't act on.
+func (b *Bot) updateDeliveryStatus(ctx context.Context, delivery *model.ReminderDelivery, status string, now time.Time) error {
+	rank, known := statusRank[status]
+	if !known || rank <= statusRank[delivery.Status] {
+		return nil
+	}
+	updates := map[string]any{"status": status}
+	if status == model.DeliveryRead {
+		updates["read_at"] = now
+	}
+	return b.db.WithContext(ctx).Model(delivery).Updates(updates).Error
+}
+
+// followUpDeliveries resends reminder messages that failed, calling instead
+// for priority 5 reminders when calls are set up, and sends priority 5
+// reminders again when they have gone unread. Each message gets at most one
+// follow-up.
+func (b *Bot) followUpDeliveries() {
+	if !b.trackDeliveries() {
+		return
+	}
+	ctx := context.Background()
+	now := time.Now()
+	var due []model.ReminderDelivery
+	err := b.db.WithContext(ctx).
+		Table("reminder_deliveries AS d").
+		Select("d.*").
+		Joins("JOIN reminders AS r ON r.id = d.reminder_id").
+		Where("d.followed_up = ? AND d.created_at > ? AND r.completed_at IS NULL", false, now.Add(-followUpWindow)).
+		Where("d.status IN ? OR (r.priority = 5 AND d.status IN ? AND d.created_at <= ?)",
+			[]string{model.DeliveryFailed, model.DeliveryUndelivered},
+			[]string{model.DeliverySent, model.DeliveryDelivered},
+			now.Add(-unreadFollowUpAfter)).
+		Order("d.id").
+		Find(&due).Error
+	if err != nil {
+		b.logger.Printf("follow-ups: load deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range due {
+		if err := b.db.Model(&delivery).Update("followed_up", true).Error; err != nil {
+			b.logger.Printf("follow-ups: delivery %d: %v", delivery.ID, err)
+			continue
+		}
+		pref := b.preferences(delivery.UserID)
+		if pref.Paused || pref.OptedOutAt != nil || b.blackedOut(ctx, delivery.UserID, now.In(b.cfg.LocalTimezone)) {
+			continue
+		}
+		var reminder model.Reminder
+		if err := b.db.First(&reminder, delivery.ReminderID).Error; err != nil {
+			continue
+		}
+		body := expandPlaceholders(b.reminderMessage(reminder, now.In(b.cfg.LocalTimezone)), reminder, now.In(b.location(pref)))
+		if delivery.Failed() && b.callAbout(ctx, reminder, body, true) {
+			continue
+		}
+		if err := b.notifyReminder(ctx, reminder, body, true); err != nil {
+			b.logger.Printf("follow-ups: resend reminder %d: %v", reminder.ID, err)
+		}
+	}
+}
+
+// readRecently returns the IDs of userID's reminders with a message the user
+// read within readQuietPeriod.
+func (b *Bot) readRecently(ctx context.Context, userID string, now time.Time) map[uint]bool {
+	if !b.trackDeliveries() {
+		return nil
+	}
+	var ids []uint
+	err := b.db.WithContext(ctx).Model(&model.ReminderDelivery{}).
+		Where("user_id = ? AND status = ? AND read_at > ?", userID, model.DeliveryRead, now.Add(-readQuietPeriod)).
+		Distinct().
+		Pluck("reminder_id", &ids).Error
+	if err != nil {
+		b.logger.Printf("read receipts for %s: %v", userID, err)
+		return nil
+	}
+	read := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		read[id] = true
+	}
+	return read
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// waitingOnParent matches reminders whose parent is still open. Those stay
+// out of lists and dispatches; a reminder whose parent was deleted is no
+// longer waiting.
+const waitingOnParent = "EXISTS (SELECT 1 FROM reminders AS parents WHERE parents.id = reminders.parent_id AND parents.completed_at IS NULL)"
+
+// dependencyPattern finds "after I finish the draft" or "after R3" at the end
+// of a new reminder. Without a verb only a code counts, so plain times such
+// as "after lunch" are left alone.
+var dependencyPattern = regexp.MustCompile(`(?i)\s+after\s+((?:i(?:'ve| have)?|i'm|i am)\s+(?:finish(?:ed)?|complete(?:d)?|do(?:ne)?|done with|through with)\s+|(?:finishing|completing|doing)\s+)?(.+?)[.!]*$`)
+
+// dependencyParent returns the open reminder a new reminder should wait for,
+// if its content names one. note explains why nothing was linked when the
+// content looks like a dependency that can't be resolved.
+func (b *Bot) dependencyParent(ctx context.Context, userID, content string) (parent *model.Reminder, note string) {
+	m := dependencyPattern.FindStringSubmatch(content)
+	if m == nil {
+		return nil, ""
+	}
+	named := strings.TrimSpace(m[2])
+	codes := parseReminderCodes(named)
+	if m[1] == "" && len(codes) != 1 {
+		return nil, ""
+	}
+
+	reminders, err := b.openReminders(ctx, userID)
+	if err != nil {
+		b.logger.Printf("dependency: load reminders for %s: %v", userID, err)
+		return nil, ""
+	}
+	var matches []model.Reminder
+	if len(codes) == 1 {
+		for _, r := range reminders {
+			if r.Code == codes[0] {
+				matches = append(matches, r)
+			}
+		}
+	} else {
+		for _, r := range matchReminders(reminders, named) {
+			if !r.IsHabit() {
+				matches = append(matches, r)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Sprintf("I couldn't find an open reminder for '%s', so this one isn't waiting on anything.", named)
+	case 1:
+		return &matches[0], ""
+	}
+	return nil, fmt.Sprintf("Several reminders match '%s', so this one isn't waiting on anything. Say 'after R3' with the right code to link them.", named)
+}
+
+// unblocked returns the open reminders that were waiting for parent.
+func (b *Bot) unblocked(ctx context.Context, parent model.Reminder) ([]model.Reminder, error) {
+	var children []model.Reminder
+	err := b.db.WithContext(ctx).
+		Where("parent_id = ? AND completed_at IS NULL", parent.ID).
+		Order("code ASC").
+		Find(&children).Error
+	return children, err
+}
+
+// waitingCount counts the user's open reminders hidden behind an unfinished
+// parent.
+func (b *Bot) waitingCount(ctx context.Context, userID string) int64 {
+	var n int64
+	err := b.db.WithContext(ctx).Model(&model.Reminder{}).
+		Where("user_id = ? AND completed_at IS NULL", userID).
+		Where(waitingOnParent).
+		Count(&n).Error
+	if err != nil {
+		b.logger.Printf("dependency: count waiting for %s: %v", userID, err)
+	}
+	return n
+}
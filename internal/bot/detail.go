@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/recurrence"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+var showReminderPattern = regexp.MustCompile(`(?i)^(?:show|view|details?(?:\s+(?:of|for))?)\s+(?:reminder\s+)?(r?\d+)[?.!]*$`)
+
+// handleShowCommand replies with everything stored for one reminder, picked
+// by list number ("show 3") or code ("show R7"), since the list shortens long
+// reminders. It reports false when the message is not a show command.
+func (b *Bot) handleShowCommand(ctx context.Context, userID, body string) (string, bool) {
+	match := showReminderPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if match == nil {
+		return "", false
+	}
+	reminder, err := b.reminderBySelector(ctx, userID, match[1])
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("show reminder: %v", err)
+		}
+		return err.Error(), true
+	}
+	return b.reminderDetail(ctx, *reminder, time.Now().In(b.cfg.LocalTimezone)), true
+}
+
+// reminderBySelector resolves a list number among the open reminders, or a
+// code such as "R7" among all of the user's reminders.
+func (b *Bot) reminderBySelector(ctx context.Context, userID, selector string) (*model.Reminder, error) {
+	if codes := parseReminderCodes(selector); len(codes) == 1 {
+		found, err := b.remindersByCode(ctx, b.db.Where("user_id = ?", userID), codes)
+		if err != nil {
+			return nil, err
+		}
+		return &found[0], nil
+	}
+
+	idx, err := strconv.Atoi(selector)
+	if err != nil {
+		return nil, userError{"Tell me which reminder to show, e.g. 'show 2' or 'show R7'."}
+	}
+	reminders, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("I couldn't look up your reminders right now. Please try again later")
+	}
+	if len(reminders) == 0 {
+		return nil, userError{"You don't have any reminders yet."}
+	}
+	if idx < 1 || idx > len(reminders) {
+		return nil, userError{fmt.Sprintf("Reminder %d doesn't exist. Choose between 1 and %d.", idx, len(reminders))}
+	}
+	return &reminders[idx-1], nil
+}
+
+// reminderDetail renders every stored field of a reminder.
+func (b *Bot) reminderDetail(ctx context.Context, r model.Reminder, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s — priority %d", r.ShortID(), r.Priority)
+	if aged := b.agedPriority(r, now); aged != r.Priority {
+		fmt.Fprintf(&sb, ", listed as %d after %d days open", aged, int(now.Sub(r.CreatedAt)/(24*time.Hour)))
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "%s\n", r.Content)
+	if r.Summary != "" && r.Summary != r.Content {
+		fmt.Fprintf(&sb, "Summary: %s\n", r.Summary)
+	}
+	if r.Category != "" {
+		fmt.Fprintf(&sb, "Category: %s\n", r.Category)
+	}
+	if r.IsHabit() {
+		fmt.Fprintf(&sb, "Daily habit, %s", streakLabel(r, now))
+		if r.Skips > 0 {
+			fmt.Fprintf(&sb, ", %s skipped on purpose", plural(r.Skips, "day"))
+		}
+		sb.WriteString("\n")
+	}
+	if r.RRule != "" {
+		if rule, err := recurrence.Parse(r.RRule); err == nil {
+			fmt.Fprintf(&sb, "Repeats %s\n", rule.Describe())
+		}
+	}
+	if r.BusinessDays {
+		sb.WriteString("Business days only\n")
+	}
+	if r.TimeOfDay != "" {
+		fmt.Fprintf(&sb, "Sent in the %s, at %s\n", r.TimeOfDay, formatHour(bucketHour(b.preferences(r.UserID), r.TimeOfDay)))
+	}
+	if r.IsAnnual() {
+		fmt.Fprintf(&sb, "Every year, next on %s\n", r.DueAt.In(now.Location()).Format("Mon Jan 2, 2006"))
+	}
+	if r.IsCountdown() {
+		fmt.Fprintf(&sb, "Countdown, sent %s: %s\n", r.Interval, daysLeftLabel(daysUntil(*r.DueAt, now)))
+	}
+	fmt.Fprintf(&sb, "Saved: %s\n", r.CreatedAt.In(now.Location()).Format("Mon Jan 2, 2006 15:04"))
+	if r.DueAt != nil {
+		fmt.Fprintf(&sb, "Due: %s\n", r.DueAt.In(now.Location()).Format("Mon Jan 2, 2006"))
+	}
+	if link := mapsLink(r); link != "" {
+		fmt.Fprintf(&sb, "Place: %s\n", strings.TrimSpace(r.Place+" "+link))
+	}
+	if r.ProjectID != nil {
+		var project model.Project
+		if err := b.db.WithContext(ctx).Where("id = ?", *r.ProjectID).Limit(1).Find(&project).Error; err != nil {
+			b.logger.Printf("show reminder: load project of %s: %v", r.ShortID(), err)
+		} else if project.ID != 0 {
+			fmt.Fprintf(&sb, "Project: %s\n", project.Name)
+		}
+	}
+	if steps, err := b.reminderSteps(ctx, r); err != nil {
+		b.logger.Printf("show reminder: load steps of %s: %v", r.ShortID(), err)
+	} else if len(steps) > 0 {
+		fmt.Fprintf(&sb, "Steps (%s):\n", stepProgress(r))
+		for _, step := range steps {
+			mark := "☐"
+			if step.DoneAt != nil {
+				mark = "☑"
+			}
+			fmt.Fprintf(&sb, "%s %s\n", mark, step.Text)
+		}
+	}
+	if r.ParentID != nil {
+		var parent model.Reminder
+		if err := b.db.WithContext(ctx).Where("id = ?", *r.ParentID).Limit(1).Find(&parent).Error; err != nil {
+			b.logger.Printf("show reminder: load parent of %s: %v", r.ShortID(), err)
+		} else if parent.ID != 0 && parent.CompletedAt == nil {
+			fmt.Fprintf(&sb, "Waiting for: %s %s\n", parent.ShortID(), fallback(parent.Summary, parent.Content))
+		}
+	}
+	if r.CompletedAt != nil {
+		fmt.Fprintf(&sb, "Done: %s\n", r.CompletedAt.In(now.Location()).Format("Mon Jan 2, 2006 15:04"))
+	}
+	var deliveries []model.ReminderDelivery
+	if err := b.db.WithContext(ctx).Where("reminder_id = ?", r.ID).Order("created_at DESC, id DESC").Limit(detailDeliveries).Find(&deliveries).Error; err != nil {
+		b.logger.Printf("show reminder: load deliveries of %s: %v", r.ShortID(), err)
+	} else if len(deliveries) > 0 {
+		sb.WriteString("Reminded:\n")
+		for _, d := range deliveries {
+			fmt.Fprintf(&sb, "- %s %s, %s\n", d.CreatedAt.In(now.Location()).Format("Mon Jan 2, 2006 15:04"), channelLabel(d.Channel), deliveryStatusLabel(d.Status))
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// detailDeliveries is how many of a reminder's latest messages its detail
+// view lists.
+const detailDeliveries = 5
+
+// channelLabel names a delivery channel for the detail view.
+func channelLabel(name string) string {
+	switch name {
+	case channel.WhatsApp:
+		return "on WhatsApp"
+	case channel.Email:
+		return "by email"
+	case channel.Voice:
+		return "by phone call"
+	case channel.Discord:
+		return "on Discord"
+	}
+	return "on " + name
+}
+
+// deliveryStatusLabel describes a delivery status for the detail view.
+func deliveryStatusLabel(status string) string {
+	switch status {
+	case model.DeliveryQueued:
+		return "sending"
+	case model.DeliveryUndelivered:
+		return model.DeliveryFailed
+	}
+	return status
+}
+
+var (
+	setPriorityPattern   = regexp.MustCompile(`(?i)^(?:set|make|change|move)\s+(?:reminder\s+)?(.+?)\s+(?:to\s+)?priority\s+([1-5])[.!]*$`)
+	priorityOfPattern    = regexp.MustCompile(`(?i)^(?:set|change)\s+(?:the\s+)?priority\s+(?:of|for|on)\s+(?:reminder\s+)?(.+?)\s+to\s+([1-5])[.!]*$`)
+	reminderSelectorForm = regexp.MustCompile(`(?i)^r?\d+$`)
+)
+
+// handlePriorityChange changes the priority of one reminder, named by list
+// number, code, or a few words of its text: "set R3 priority 5" or "change
+// the priority of rent to 2". It reports false when the message is not a
+// priority change, or names no reminder in words, so "make dinner priority
+// 5" can still be saved as a new reminder.
+func (b *Bot) handlePriorityChange(ctx context.Context, userID, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	match := setPriorityPattern.FindStringSubmatch(body)
+	if match == nil {
+		match = priorityOfPattern.FindStringSubmatch(body)
+	}
+	if match == nil {
+		return "", false
+	}
+	selector := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(match[1]), "the "), "my ")
+	priority, _ := strconv.Atoi(match[2])
+
+	var reminder *model.Reminder
+	if reminderSelectorForm.MatchString(selector) {
+		r, err := b.reminderBySelector(ctx, userID, selector)
+		if err != nil {
+			if !isUserError(err) {
+				b.logger.Printf("change priority: %v", err)
+			}
+			return err.Error(), true
+		}
+		reminder = r
+	} else {
+		open, err := b.openReminders(ctx, userID)
+		if err != nil {
+			b.logger.Printf("change priority: %v", err)
+			return "I couldn't update that reminder. Please try again later.", true
+		}
+		matches := matchReminders(open, selector)
+		switch len(matches) {
+		case 0:
+			return "", false
+		case 1:
+			reminder = &matches[0]
+		default:
+			return fmt.Sprintf("'%s' matches %d reminders. Use its code instead, e.g. 'set %s priority %d'.", selector, len(matches), matches[0].ShortID(), priority), true
+		}
+	}
+
+	if err := b.db.WithContext(ctx).Model(reminder).Update("priority", priority).Error; err != nil {
+		b.logger.Printf("change priority: %v", err)
+		return "I couldn't update that reminder. Please try again later.", true
+	}
+	b.emit(webhook.EventReminderUpdated, *reminder, "")
+	return fmt.Sprintf("%s %s is now priority %d.", reminder.ShortID(), fallback(reminder.Summary, reminder.Content), priority), true
+}
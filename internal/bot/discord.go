@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/discord"
+)
+
+// discordCommandName is the slash command users talk to the bot through.
+const discordCommandName = "memo"
+
+// RegisterDiscordCommands publishes the /memo slash command. It does nothing
+// unless a bot token and application ID are configured.
+func (b *Bot) RegisterDiscordCommands(ctx context.Context) error {
+	if b.discord == nil || b.cfg.DiscordApplicationID == "" {
+		return nil
+	}
+	dmPermission := true
+	return b.discord.RegisterCommands(ctx, b.cfg.DiscordApplicationID, []discord.Command{{
+		Name:         discordCommandName,
+		Description:  "Add, list, or complete reminders with myMemo",
+		Type:         discord.CommandTypeChatInput,
+		DMPermission: &dmPermission,
+		Options: []discord.CommandOption{{
+			Type:        discord.OptionTypeString,
+			Name:        "message",
+			Description: "What you'd say to the bot, e.g. 'remind me to water the plants'",
+			Required:    true,
+		}},
+	}})
+}
+
+// DiscordInteractionsHandler serves Discord's interactions endpoint. /memo
+// messages run through the same pipeline as WhatsApp messages; the reply is
+// computed in the background and edited into a deferred response because
+// Discord requires an answer within three seconds.
+func (b *Bot) DiscordInteractionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.discordKey == nil || b.discord == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		if !discord.Verify(b.discordKey, r.Header.Get("X-Signature-Ed25519"), r.Header.Get("X-Signature-Timestamp"), body) {
+			http.Error(w, "invalid request signature", http.StatusUnauthorized)
+			return
+		}
+
+		var interaction discord.Interaction
+		if err := json.Unmarshal(body, &interaction); err != nil {
+			http.Error(w, "invalid interaction", http.StatusBadRequest)
+			return
+		}
+
+		switch interaction.Type {
+		case discord.InteractionPing:
+			writeJSON(w, http.StatusOK, discord.InteractionResponse{Type: discord.ResponsePong})
+		case discord.InteractionApplicationCommand:
+			resp, followUp := b.handleDiscordCommand(interaction)
+			writeJSON(w, http.StatusOK, resp)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			if followUp != nil && !b.sends.Go(followUp) {
+				b.logger.Printf("discord: shutting down, dropped reply to interaction %s", interaction.ID)
+			}
+		default:
+			http.Error(w, "unsupported interaction type", http.StatusBadRequest)
+		}
+	})
+}
+
+// handleDiscordCommand answers a /memo invocation. When the message needs
+// processing it returns a deferred response and a follow-up that edits in the
+// real reply once the deferred response has been sent. Replies in servers are
+// ephemeral so reminders stay private.
+func (b *Bot) handleDiscordCommand(interaction discord.Interaction) (discord.InteractionResponse, func()) {
+	flags := 0
+	if interaction.GuildID != "" {
+		flags = discord.MessageFlagEphemeral
+	}
+	reply := func(content string) discord.InteractionResponse {
+		return discord.InteractionResponse{
+			Type: discord.ResponseChannelMessage,
+			Data: &discord.ResponseData{Content: content, Flags: flags},
+		}
+	}
+
+	user, ok := interaction.Caller()
+	if !ok || interaction.Data.Name != discordCommandName {
+		return reply("I don't know that command."), nil
+	}
+	message := strings.TrimSpace(interaction.Data.Option("message"))
+	if message == "" {
+		return reply("I need a message to work with. Try '/memo remind me to water the plants'."), nil
+	}
+
+	userID := discordUserPrefix + user.ID
+	followUp := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		content := renderFormatting(b.respond(ctx, userID, message), channel.Discord)
+		if err := b.discord.EditOriginalResponse(ctx, interaction.ApplicationID, interaction.Token, content); err != nil {
+			b.logger.Printf("discord: reply to %s: %v", userID, err)
+		}
+	}
+	return discord.InteractionResponse{
+		Type: discord.ResponseDeferredChannelMessage,
+		Data: &discord.ResponseData{Flags: flags},
+	}, followUp
+}
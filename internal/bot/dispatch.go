@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// pendingSend is a reminder message waiting for its delivery slot.
+type pendingSend struct {
+	UserID     string
+	ReminderID uint
+	Body       string
+	SendAt     time.Time
+	// Key is the idempotency key, from sendKey, or "" for sends that may
+	// repeat.
+	Key string
+
+	timer *time.Timer
+}
+
+// sendKey returns the idempotency key for a scheduled message about
+// reminderID on the day of sendAt.
+func sendKey(reminderID uint, sendAt time.Time) string {
+	return fmt.Sprintf("reminder-%d-%s", reminderID, sendAt.Format(time.DateOnly))
+}
+
+// sendQueue tracks dispatch goroutines and delayed sends so shutdown can
+// wait for in-flight work and hand back anything that has not fired yet.
+type sendQueue struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	pending map[*pendingSend]struct{}
+}
+
+func newSendQueue() *sendQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &sendQueue{
+		ctx:     ctx,
+		cancel:  cancel,
+		pending: make(map[*pendingSend]struct{}),
+	}
+}
+
+// Go runs fn in a tracked goroutine. It returns false once the queue is draining.
+func (q *sendQueue) Go(fn func()) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx.Err() != nil {
+		return false
+	}
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+// Schedule arranges for send to be called with p at p.SendAt. It returns false
+// once the queue is draining, in which case the caller owns p. A send whose
+// key is already waiting is dropped as a duplicate.
+func (q *sendQueue) Schedule(p *pendingSend, send func(*pendingSend)) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx.Err() != nil {
+		return false
+	}
+	if p.Key != "" {
+		for other := range q.pending {
+			if other.Key == p.Key {
+				return true
+			}
+		}
+	}
+
+	q.wg.Add(1)
+	q.pending[p] = struct{}{}
+	p.timer = time.AfterFunc(time.Until(p.SendAt), func() {
+		defer q.wg.Done()
+		q.mu.Lock()
+		delete(q.pending, p)
+		q.mu.Unlock()
+		send(p)
+	})
+	return true
+}
+
+// Cancel drops the waiting sends for reminderID and reports how many there
+// were.
+func (q *sendQueue) Cancel(reminderID uint) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	cancelled := 0
+	for p := range q.pending {
+		if p.ReminderID == reminderID && p.timer.Stop() {
+			delete(q.pending, p)
+			q.wg.Done()
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
+// Shift moves userID's waiting sends to the times move returns and reports
+// how many moved. Sends whose timer has already fired are left alone.
+func (q *sendQueue) Shift(userID string, move func(time.Time) time.Time) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	moved := 0
+	for p := range q.pending {
+		if p.UserID != userID {
+			continue
+		}
+		at := move(p.SendAt)
+		if at.Equal(p.SendAt) || !p.timer.Stop() {
+			continue
+		}
+		p.SendAt = at
+		p.timer.Reset(time.Until(at))
+		moved++
+	}
+	return moved
+}
+
+// Len reports the number of sends still waiting for their timer.
+func (q *sendQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Snapshot returns copies of the sends still waiting for their timer, ordered by send time.
+func (q *sendQueue) Snapshot() []pendingSend {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]pendingSend, 0, len(q.pending))
+	for p := range q.pending {
+		out = append(out, pendingSend{
+			UserID:     p.UserID,
+			ReminderID: p.ReminderID,
+			Body:       p.Body,
+			SendAt:     p.SendAt,
+			Key:        p.Key,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SendAt.Before(out[j].SendAt) })
+	return out
+}
+
+// Drain stops accepting work, cancels timers that have not fired, and waits
+// for running sends to finish or ctx to expire. Cancelled sends are returned.
+func (q *sendQueue) Drain(ctx context.Context) ([]*pendingSend, error) {
+	q.mu.Lock()
+	q.cancel()
+	unsent := make([]*pendingSend, 0, len(q.pending))
+	for p := range q.pending {
+		if p.timer.Stop() {
+			unsent = append(unsent, p)
+			q.wg.Done()
+		}
+		delete(q.pending, p)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return unsent, nil
+	case <-ctx.Done():
+		return unsent, ctx.Err()
+	}
+}
@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+var (
+	setEmailPattern      = regexp.MustCompile(`(?i)^(?:set my email(?: address)? to|my email(?: address)? is)\s+(\S+)$`)
+	deliveryPattern      = regexp.MustCompile(`(?i)^send (?:me )?my reminders (?:by|via|on|over) (email|whatsapp|email and whatsapp|whatsapp and email|both)$`)
+	removeEmailResponses = map[string]bool{"remove my email": true, "delete my email": true, "forget my email": true}
+)
+
+// handleEmailCommand manages the user's email address and delivery channel.
+// It reports false when the message is not an email command.
+func (b *Bot) handleEmailCommand(userID, body, lowerBody string) (string, bool) {
+	lowerBody = strings.TrimSuffix(strings.TrimSpace(lowerBody), ".")
+	var (
+		msg string
+		err error
+	)
+	switch {
+	case setEmailPattern.MatchString(body):
+		msg, err = b.setEmail(userID, setEmailPattern.FindStringSubmatch(body)[1])
+	case removeEmailResponses[lowerBody]:
+		msg, err = b.removeEmail(userID)
+	case deliveryPattern.MatchString(lowerBody):
+		mode := model.DeliveryBoth
+		switch deliveryPattern.FindStringSubmatch(lowerBody)[1] {
+		case "email":
+			mode = model.DeliveryEmail
+		case "whatsapp":
+			mode = model.DeliveryWhatsApp
+		}
+		msg, err = b.setDelivery(userID, mode)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("email command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// setEmail stores the address the daily digest is sent to.
+func (b *Bot) setEmail(userID, address string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSuffix(address, "."))
+	if err != nil {
+		return "", userError{"That doesn't look like an email address."}
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.Email = parsed.Address
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't save your email address. Please try again later")
+	}
+	return fmt.Sprintf("Saved %s. Say 'send my reminders by email' to get a daily digest there, or 'by email and whatsapp' for both.", parsed.Address), nil
+}
+
+// removeEmail forgets the user's address and goes back to WhatsApp only.
+func (b *Bot) removeEmail(userID string) (string, error) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.Email = ""
+		p.Delivery = model.DeliveryWhatsApp
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't remove your email address. Please try again later")
+	}
+	return "Removed your email address. Reminders will come on WhatsApp only.", nil
+}
+
+// setDelivery chooses where the daily reminders go.
+func (b *Bot) setDelivery(userID, mode string) (string, error) {
+	if mode != model.DeliveryWhatsApp {
+		if _, ok := b.channels[channel.Email]; !ok {
+			return "", userError{"Email delivery isn't set up on this server yet."}
+		}
+		if b.preferences(userID).Email == "" {
+			return "", userError{"Tell me your address first, e.g. 'my email is you@example.com'."}
+		}
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.Delivery = mode
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't update your delivery settings. Please try again later")
+	}
+
+	switch mode {
+	case model.DeliveryEmail:
+		return "Done! Your daily reminders will arrive as one email digest instead of WhatsApp messages.", nil
+	case model.DeliveryBoth:
+		return "Done! You'll get a daily email digest as well as your WhatsApp reminders.", nil
+	}
+	return "Done! Your reminders will come on WhatsApp only.", nil
+}
+
+// sendDigest emails every due reminder to address in a single message.
+func (b *Bot) sendDigest(address string, reminders []model.Reminder, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	lines := make([]string, len(reminders))
+	for i, r := range reminders {
+		lines[i] = digestLine(r, now)
+	}
+	msg := channel.Message{
+		To:      address,
+		Subject: fmt.Sprintf("Your reminders for %s", now.Format("Monday, Jan 2")),
+		Body:    digestBody(lines),
+	}
+	err := b.send(ctx, channel.Email, msg)
+	for _, r := range reminders {
+		b.recordDelivery(ctx, r, channel.Email, err)
+	}
+	if err != nil {
+		return err
+	}
+	for i, r := range reminders {
+		b.emit(webhook.EventReminderSent, r, lines[i])
+	}
+	return nil
+}
+
+func digestLine(reminder model.Reminder, now time.Time) string {
+	text := expandPlaceholders(fallback(reminder.Summary, reminder.Content), reminder, now)
+	if reminder.IsHabit() {
+		return fmt.Sprintf("[%d] Habit: %s — %s", reminder.Priority, text, streakMessage(reminder, now))
+	}
+	if reminder.IsCountdown() {
+		return fmt.Sprintf("[%d] %s", reminder.Priority, countdownMessage(reminder, now))
+	}
+	if reminder.IsAnnual() {
+		return fmt.Sprintf("[%d] %s", reminder.Priority, annualMessage(reminder, now))
+	}
+	if link := mapsLink(reminder); link != "" {
+		text += " " + link
+	}
+	if reminder.DueAt != nil {
+		return fmt.Sprintf("[%d] %s (due %s)", reminder.Priority, text, reminder.DueAt.In(now.Location()).Format("Jan 02"))
+	}
+	return fmt.Sprintf("[%d] %s", reminder.Priority, text)
+}
+
+func digestBody(lines []string) string {
+	var sb strings.Builder
+	sb.WriteString("Good morning! Here's what's on your list today, highest priority first:\n\n")
+	for i, line := range lines {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, line))
+	}
+	sb.WriteString("\nReply to myMemo on WhatsApp to add, complete, or delete reminders.\n")
+	return sb.String()
+}
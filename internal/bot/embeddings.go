@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pathakanu/myMemo/internal/database"
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// duplicateDistance is the cosine distance under which a new reminder
+	// is taken to repeat an open one.
+	duplicateDistance = 0.08
+	// semanticSearchDistance is how far a reminder may be from a search and
+	// still match it when no words match.
+	semanticSearchDistance = 0.55
+	semanticSearchLimit    = 5
+
+	// embeddingBackfillSpec runs hourly, embedding reminders saved without
+	// an embedding, such as Notion imports or those saved while OpenAI was
+	// unreachable.
+	embeddingBackfillSpec  = "40 * * * *"
+	embeddingBackfillBatch = 100
+)
+
+// canEmbed reports whether reminders can be embedded and stored.
+func (b *Bot) canEmbed() bool {
+	return b.embedder != nil && database.EmbeddingsEnabled(b.db)
+}
+
+// embed returns a vector for each of texts, or nil when embeddings are
+// unavailable or the request fails.
+func (b *Bot) embed(ctx context.Context, userID string, texts []string) []model.Vector {
+	if len(texts) == 0 || !b.canEmbed() || !b.useOpenAI(ctx, userID) {
+		return nil
+	}
+	vectors, err := b.embedder.Embed(ctx, texts)
+	if err != nil {
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("openai embed error: %v", err)
+		}
+		return nil
+	}
+	out := make([]model.Vector, len(vectors))
+	for i, v := range vectors {
+		out[i] = v
+	}
+	return out
+}
+
+// embedReminders stores embeddings of the content of reminders, which all
+// belong to userID, replacing any they had.
+func (b *Bot) embedReminders(ctx context.Context, userID string, reminders []model.Reminder) {
+	texts := make([]string, len(reminders))
+	for i, r := range reminders {
+		texts[i] = r.Content
+	}
+	vectors := b.embed(ctx, userID, texts)
+	if vectors == nil {
+		return
+	}
+	rows := make([]model.ReminderEmbedding, len(reminders))
+	for i, r := range reminders {
+		rows[i] = model.ReminderEmbedding{ReminderID: r.ID, UserID: userID, Model: myopenai.EmbeddingModel, Embedding: vectors[i]}
+	}
+	err := b.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "reminder_id"}}, UpdateAll: true}).
+		Create(&rows).Error
+	if err != nil {
+		b.logger.Printf("save embeddings: %v", err)
+	}
+}
+
+// nearestReminders returns up to limit of userID's open reminders within
+// maxDistance of vector, nearest first.
+func (b *Bot) nearestReminders(ctx context.Context, userID string, vector model.Vector, maxDistance float64, limit int) ([]model.Reminder, error) {
+	neighbors, err := database.NearestReminders(b.db.WithContext(ctx), userID, myopenai.EmbeddingModel, vector, limit)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for _, n := range neighbors {
+		if n.Distance <= maxDistance {
+			ids = append(ids, n.ReminderID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var found []model.Reminder
+	if err := b.db.WithContext(ctx).Where("id IN ?", ids).Where("NOT " + waitingOnParent).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.Reminder, len(found))
+	for _, r := range found {
+		byID[r.ID] = r
+	}
+	reminders := make([]model.Reminder, 0, len(found))
+	for _, id := range ids {
+		if r, ok := byID[id]; ok {
+			reminders = append(reminders, r)
+		}
+	}
+	return reminders, nil
+}
+
+// semanticSearch returns open reminders close in meaning to query, such as
+// "pay the landlord" for a search about rent.
+func (b *Bot) semanticSearch(ctx context.Context, userID, query string) ([]model.Reminder, error) {
+	vectors := b.embed(ctx, userID, []string{query})
+	if vectors == nil {
+		return nil, nil
+	}
+	return b.nearestReminders(ctx, userID, vectors[0], semanticSearchDistance, semanticSearchLimit)
+}
+
+// duplicateOf returns another open reminder that says the same thing as
+// reminder, which has just been saved and embedded, or nil.
+func (b *Bot) duplicateOf(ctx context.Context, reminder model.Reminder) *model.Reminder {
+	if !b.canEmbed() {
+		return nil
+	}
+	var stored model.ReminderEmbedding
+	if err := b.db.WithContext(ctx).Where("reminder_id = ?", reminder.ID).Limit(1).Find(&stored).Error; err != nil || stored.ReminderID == 0 {
+		return nil
+	}
+	matches, err := b.nearestReminders(ctx, reminder.UserID, stored.Embedding, duplicateDistance, 2)
+	if err != nil {
+		b.logger.Printf("find duplicates: %v", err)
+		return nil
+	}
+	for _, m := range matches {
+		if m.ID != reminder.ID {
+			return &m
+		}
+	}
+	return nil
+}
+
+// backfillEmbeddings embeds a batch of open reminders that have no
+// embedding from the current model.
+func (b *Bot) backfillEmbeddings() {
+	if !b.canEmbed() {
+		return
+	}
+	var missing []model.Reminder
+	err := b.db.Where("completed_at IS NULL").
+		Where("id NOT IN (SELECT reminder_id FROM reminder_embeddings WHERE model = ?)", myopenai.EmbeddingModel).
+		Order("user_id, id").
+		Limit(embeddingBackfillBatch).
+		Find(&missing).Error
+	if err != nil {
+		b.logger.Printf("embedding backfill: %v", err)
+		return
+	}
+	for start := 0; start < len(missing); {
+		end := start
+		for end < len(missing) && missing[end].UserID == missing[start].UserID {
+			end++
+		}
+		b.embedReminders(context.Background(), missing[start].UserID, missing[start:end])
+		start = end
+	}
+}
@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+// eventBuffer is how many events a slow subscriber may fall behind before
+// further events are dropped for it.
+const eventBuffer = 64
+
+// originNotion marks events for changes pulled from Notion, which must not
+// be pushed back to it.
+const originNotion = "notion"
+
+// reminderEvent is a lifecycle event with the full reminder it describes.
+type reminderEvent struct {
+	webhook.Event
+	Reminder model.Reminder
+	// Origin names the integration the change came from, or is empty for
+	// changes made through the bot.
+	Origin string
+}
+
+// eventHandler reacts to a reminder event. It runs on the publisher's
+// goroutine, so anything slow belongs on the send queue.
+type eventHandler func(reminderEvent)
+
+// eventHub is the bot's event bus. It runs each registered handler for
+// every reminder event and fans events out to stream subscribers such as
+// gRPC event streams.
+type eventHub struct {
+	mu       sync.Mutex
+	subs     map[*eventSubscription]struct{}
+	handlers []eventHandler
+}
+
+// eventSubscription receives events matching its user and type filters.
+type eventSubscription struct {
+	C      chan reminderEvent
+	userID string
+	types  map[string]bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[*eventSubscription]struct{})}
+}
+
+// Len returns the number of open subscriptions.
+func (h *eventHub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// handle registers fn to run for every published event, after the handlers
+// registered before it.
+func (h *eventHub) handle(fn eventHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers = append(h.handlers, fn)
+}
+
+// subscribe registers a subscriber for userID's events (everyone's if empty)
+// of the given types (all if none).
+func (h *eventHub) subscribe(userID string, types []string) *eventSubscription {
+	sub := &eventSubscription{
+		C:      make(chan reminderEvent, eventBuffer),
+		userID: userID,
+	}
+	if len(types) > 0 {
+		sub.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// unsubscribe stops delivery to sub and closes its channel.
+func (h *eventHub) unsubscribe(sub *eventSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.C)
+	}
+}
+
+// publish runs the handlers for event and delivers it to every matching
+// subscriber without blocking.
+func (h *eventHub) publish(event reminderEvent) {
+	h.mu.Lock()
+	handlers := h.handlers
+	h.mu.Unlock()
+	for _, fn := range handlers {
+		fn(event)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if sub.userID != "" && sub.userID != event.UserID {
+			continue
+		}
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.C <- event:
+		default:
+			// The subscriber isn't keeping up; drop rather than block emit.
+		}
+	}
+}
+
+// closeAll ends every subscription, e.g. on shutdown.
+func (h *eventHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.C)
+	}
+}
+
+// eventCounter counts published events by type.
+type eventCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *eventCounter) add(eventType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[eventType]++
+}
+
+// Snapshot returns a copy of the counts.
+func (c *eventCounter) Snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.counts)
+}
+
+// subscribeEffects registers the bot's reactions to reminder events, so the
+// code that changes a reminder only has to emit.
+func (b *Bot) subscribeEffects() {
+	b.events.handle(func(e reminderEvent) {
+		b.eventCounts.add(e.Type)
+	})
+	b.events.handle(func(e reminderEvent) {
+		b.emitWebhooks(e.Event)
+	})
+	b.events.handle(func(e reminderEvent) {
+		if e.Origin != originNotion {
+			b.pushToNotion(e.Type, e.Reminder)
+		}
+	})
+	b.events.handle(func(e reminderEvent) {
+		b.pushToCalDAV(e.Type, e.Reminder)
+	})
+	b.events.handle(func(e reminderEvent) {
+		// A reminder that is done or gone shouldn't be sent later today.
+		if e.Type == webhook.EventReminderCompleted || e.Type == webhook.EventReminderDeleted {
+			b.sends.Cancel(e.Reminder.ID)
+		}
+	})
+	b.events.handle(func(e reminderEvent) {
+		switch {
+		case e.Type == webhook.EventReminderCreated && e.Origin == "" && e.Reminder.DueAt != nil:
+			b.sends.Go(func() { b.inviteNewReminder(e.Reminder) })
+		case e.Type == webhook.EventReminderDeleted && e.Reminder.DueAt != nil && b.blobs != nil:
+			// Remove any invite sent for it; there is usually none.
+			b.sends.Go(func() {
+				if err := b.blobs.Delete(context.Background(), inviteKey(e.Reminder)); err != nil {
+					b.logger.Printf("delete invite for %s: %v", e.Reminder.ShortID(), err)
+				}
+			})
+		}
+	})
+	b.events.handle(func(e reminderEvent) {
+		if e.Type == webhook.EventReminderDeleted && e.Reminder.Steps > 0 {
+			if err := b.db.Where("reminder_id = ?", e.Reminder.ID).Delete(&model.ReminderItem{}).Error; err != nil {
+				b.logger.Printf("delete steps of reminder %d: %v", e.Reminder.ID, err)
+			}
+		}
+	})
+}
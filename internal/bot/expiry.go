@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// conversationTTL is how long the bot waits for a reply to a question,
+	// such as which priority to give a reminder, before dropping it. A
+	// number sent later is then read as a new message.
+	conversationTTL = 30 * time.Minute
+	// conversationSweepSpec runs the sweep for expired conversation state
+	// every five minutes.
+	conversationSweepSpec = "*/5 * * * *"
+)
+
+// sweepConversations drops conversation state older than conversationTTL
+// and tells users whose unsaved reminder went with it.
+func (b *Bot) sweepConversations() {
+	for userID, state := range b.state.Expire(time.Now()) {
+		if (!state.AwaitingPriority && len(state.Clarify) == 0) || state.PendingMessage == "" {
+			continue
+		}
+		msg := fmt.Sprintf("I discarded your unsaved reminder '%s' because I didn't hear back. Send it again whenever you're ready.", truncate(state.PendingMessage, 60))
+		if err := b.notify(context.Background(), userID, msg); err != nil {
+			b.logger.Printf("conversation sweep: notify %s: %v", userID, err)
+		}
+	}
+}
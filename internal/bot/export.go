@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// exportLinkTTL is how long the link to an export bundle sent in chat works.
+const exportLinkTTL = 24 * time.Hour
+
+// PreferencesExport is a user's settings in an export bundle.
+type PreferencesExport struct {
+	Language           string `json:"language,omitempty"`
+	Email              string `json:"email,omitempty"`
+	Delivery           string `json:"delivery"`
+	SortOrder          string `json:"sort_order"`
+	ReminderGapMinutes *int   `json:"reminder_gap_minutes,omitempty"`
+	BirthdayLeadDays   *int   `json:"birthday_lead_days,omitempty"`
+	MorningHour        *int   `json:"morning_hour,omitempty"`
+	AfternoonHour      *int   `json:"afternoon_hour,omitempty"`
+	EveningHour        *int   `json:"evening_hour,omitempty"`
+	Timezone           string `json:"timezone,omitempty"`
+	DeliveryHour       *int   `json:"delivery_hour,omitempty"`
+	QuietStart         *int   `json:"quiet_start,omitempty"`
+	QuietEnd           *int   `json:"quiet_end,omitempty"`
+	DailyArticle       bool   `json:"daily_article"`
+	CallAlerts         bool   `json:"call_alerts"`
+	Tips               bool   `json:"tips"`
+	NoShares           bool   `json:"no_shares"`
+	MorningBriefing    bool   `json:"morning_briefing"`
+	CalendarInvites    bool   `json:"calendar_invites"`
+	Paused             bool   `json:"paused"`
+	OptedOut           bool   `json:"opted_out"`
+}
+
+// DeliveryExport is one reminder message in an export bundle.
+type DeliveryExport struct {
+	ReminderID uint       `json:"reminder_id"`
+	Channel    string     `json:"channel"`
+	Status     string     `json:"status"`
+	SentAt     time.Time  `json:"sent_at"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+}
+
+// exportBundle writes everything stored for userID as a ZIP of JSON files:
+// reminders, memos, lists, preferences, and deliveries.
+func (b *Bot) exportBundle(userID string) ([]byte, error) {
+	export, err := b.exportUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	pref := b.preferences(userID)
+	preferences := PreferencesExport{
+		Language:           pref.Language,
+		Email:              pref.Email,
+		Delivery:           pref.Delivery,
+		SortOrder:          pref.SortOrder,
+		ReminderGapMinutes: pref.ReminderGapMinutes,
+		BirthdayLeadDays:   pref.BirthdayLeadDays,
+		MorningHour:        pref.MorningHour,
+		AfternoonHour:      pref.AfternoonHour,
+		EveningHour:        pref.EveningHour,
+		Timezone:           pref.Timezone,
+		DeliveryHour:       pref.DeliveryHour,
+		QuietStart:         pref.QuietStart,
+		QuietEnd:           pref.QuietEnd,
+		DailyArticle:       pref.DailyArticle,
+		CallAlerts:         pref.CallAlerts,
+		Tips:               pref.Tips,
+		NoShares:           pref.NoShares,
+		MorningBriefing:    pref.MorningBriefing,
+		CalendarInvites:    pref.CalendarInvites,
+		Paused:             pref.Paused,
+		OptedOut:           pref.OptedOutAt != nil,
+	}
+	var deliveries []model.ReminderDelivery
+	if err := b.db.Where("user_id = ?", userID).Order("created_at ASC, id ASC").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	history := make([]DeliveryExport, len(deliveries))
+	for i, d := range deliveries {
+		history[i] = DeliveryExport{ReminderID: d.ReminderID, Channel: d.Channel, Status: d.Status, SentAt: d.CreatedAt, ReadAt: d.ReadAt}
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		data any
+	}{
+		{"reminders.json", export.Reminders},
+		{"memos.json", export.Memos},
+		{"lists.json", export.Lists},
+		{"preferences.json", preferences},
+		{"deliveries.json", history},
+	} {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// apiExport downloads the signed-in user's data as a ZIP bundle.
+func (b *Bot) apiExport(w http.ResponseWriter, r *http.Request) {
+	userID := sessionUserID(r.Context())
+	data, err := b.exportBundle(userID)
+	if err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+	name := fmt.Sprintf("mymemo-export-%s.zip", time.Now().In(b.cfg.LocalTimezone).Format(time.DateOnly))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	_, _ = w.Write(data)
+}
+
+// handleExportCommand answers "export my data" with a link to the user's
+// export bundle, kept in the blob store. It reports false for other
+// messages.
+func (b *Bot) handleExportCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!?") {
+	case "export", "export my data", "export my reminders", "download my data":
+	default:
+		return "", false
+	}
+	if b.blobs == nil {
+		return "Exports aren't set up on this server yet.", true
+	}
+	data, err := b.exportBundle(userID)
+	if err != nil {
+		b.logger.Printf("export for %s: %v", userID, err)
+		return "I couldn't put your export together. Please try again later.", true
+	}
+	name, err := newToken()
+	if err != nil {
+		b.logger.Printf("export for %s: %v", userID, err)
+		return "I couldn't put your export together. Please try again later.", true
+	}
+	key := fmt.Sprintf("exports/%s/mymemo-export-%s-%s.zip", hashToken(userID)[:16], time.Now().In(b.cfg.LocalTimezone).Format(time.DateOnly), name[:16])
+	if err := b.blobs.Put(ctx, key, data, "application/zip"); err != nil {
+		b.logger.Printf("export for %s: store: %v", userID, err)
+		return "I couldn't put your export together. Please try again later.", true
+	}
+	link, err := b.blobs.SignedURL(ctx, key, exportLinkTTL)
+	if err != nil {
+		b.logger.Printf("export for %s: link: %v", userID, err)
+		return "Exports aren't set up on this server yet.", true
+	}
+	return fmt.Sprintf("Your reminders, notes, lists, and settings are ready as a ZIP file. The link works for 24 hours:\n%s", link), true
+}
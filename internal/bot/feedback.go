@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// feedbackPattern matches "feedback: ..." and "bug: ...", capturing the text.
+var feedbackPattern = regexp.MustCompile(`(?is)^\s*(?:feedback|bug(?: report)?|report a bug)(?:\s*[:-]\s*(.*?))?\s*$`)
+
+// handleFeedbackCommand stores "feedback: the bot forgot my reminder" with the
+// user's recent messages and settings, and passes it on to the admin numbers.
+// It reports false for other messages.
+func (b *Bot) handleFeedbackCommand(ctx context.Context, userID, body string) (string, bool) {
+	m := feedbackPattern.FindStringSubmatch(body)
+	if m == nil {
+		return "", false
+	}
+	text := m[1]
+	if text == "" {
+		return "Tell me what happened, starting with 'feedback:', such as 'feedback: the bot forgot my reminder'.", true
+	}
+	feedback, err := b.saveFeedback(ctx, userID, text)
+	if err != nil {
+		b.logger.Printf("feedback from %s: %v", userID, err)
+		return "I couldn't save your feedback. Please try again later.", true
+	}
+	for _, admin := range b.cfg.AdminNumbers {
+		message := fmt.Sprintf("Feedback #%d from %s: %s", feedback.ID, userID, text)
+		if err := b.notify(ctx, normalizePhoneNumber(admin), message); err != nil {
+			b.logger.Printf("feedback #%d: notify %s: %v", feedback.ID, admin, err)
+		}
+	}
+	return fmt.Sprintf("Thanks for telling me. I've passed it on as feedback #%d.", feedback.ID), true
+}
+
+// saveFeedback stores text with the user's last few exchanges, open reminder
+// count, language, and timezone.
+func (b *Bot) saveFeedback(ctx context.Context, userID, text string) (model.Feedback, error) {
+	pref := b.preferences(userID)
+	var recent strings.Builder
+	for _, e := range b.recent.Recent(userID, time.Now()) {
+		fmt.Fprintf(&recent, "> %s\n< %s\n", e.Message, e.Reply)
+	}
+	feedback := model.Feedback{
+		UserID:         userID,
+		Text:           text,
+		RecentMessages: strings.TrimSuffix(recent.String(), "\n"),
+		Language:       pref.Language,
+		Timezone:       zoneName(b.location(pref)),
+	}
+	if err := b.db.WithContext(ctx).Model(&model.Reminder{}).Where("user_id = ? AND completed_at IS NULL", userID).Count(&feedback.OpenReminders).Error; err != nil {
+		return feedback, err
+	}
+	if err := b.db.WithContext(ctx).Create(&feedback).Error; err != nil {
+		return feedback, err
+	}
+	return feedback, nil
+}
@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// listFilter narrows a reminder listing. The zero value matches everything.
+type listFilter struct {
+	MinPriority int
+	MaxPriority int
+	Overdue     bool
+	// Tag is a hashtag, without the #, that must appear in the content.
+	Tag string
+	// CreatedSince is "today", "this week", or "this month".
+	CreatedSince string
+	Category     string
+}
+
+func (f listFilter) empty() bool {
+	return f == listFilter{}
+}
+
+// apply adds the filter's conditions to query.
+func (f listFilter) apply(query *gorm.DB, now time.Time) *gorm.DB {
+	if f.MinPriority > 0 {
+		query = query.Where("priority >= ?", f.MinPriority)
+	}
+	if f.MaxPriority > 0 {
+		query = query.Where("priority <= ?", f.MaxPriority)
+	}
+	if f.Overdue {
+		query = query.Where("due_at IS NOT NULL AND due_at < ?", startOfDay(now))
+	}
+	if f.Tag != "" {
+		query = query.Where("LOWER(content) LIKE ?", "%#"+f.Tag+"%")
+	}
+	if f.Category != "" {
+		query = query.Where("category = ?", f.Category)
+	}
+	if since := f.createdSince(now); !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+	return query
+}
+
+func (f listFilter) createdSince(now time.Time) time.Time {
+	today := startOfDay(now)
+	switch f.CreatedSince {
+	case "today":
+		return today
+	case "this week":
+		// Weeks start on Monday.
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	case "this month":
+		return today.AddDate(0, 0, 1-today.Day())
+	}
+	return time.Time{}
+}
+
+// String describes the filter for list headers, e.g. "high priority, #work".
+func (f listFilter) String() string {
+	var parts []string
+	switch {
+	case f.MinPriority > 0 && f.MinPriority == f.MaxPriority:
+		parts = append(parts, fmt.Sprintf("priority %d", f.MinPriority))
+	case f.MinPriority > 0:
+		parts = append(parts, "high priority")
+	case f.MaxPriority > 0:
+		parts = append(parts, "low priority")
+	}
+	if f.Overdue {
+		parts = append(parts, "overdue")
+	}
+	if f.Tag != "" {
+		parts = append(parts, "#"+f.Tag)
+	}
+	if f.Category != "" {
+		parts = append(parts, f.Category)
+	}
+	if f.CreatedSince != "" {
+		parts = append(parts, "created "+f.CreatedSince)
+	}
+	return strings.Join(parts, ", ")
+}
+
+var (
+	listFilterPrefix = regexp.MustCompile(`^(?:list|show)(?:\s+me)?\b`)
+	listFilterTerms  = []struct {
+		pattern *regexp.Regexp
+		apply   func(f *listFilter, page *int, match []string)
+	}{
+		{regexp.MustCompile(`^(?:high|top|urgent)(?:[\s-]+priority)?\b`), func(f *listFilter, _ *int, _ []string) { f.MinPriority = 4 }},
+		{regexp.MustCompile(`^low(?:[\s-]+priority)?\b`), func(f *listFilter, _ *int, _ []string) { f.MaxPriority = 2 }},
+		{regexp.MustCompile(`^priority\s+([1-5])\b`), func(f *listFilter, _ *int, m []string) {
+			p, _ := strconv.Atoi(m[1])
+			f.MinPriority, f.MaxPriority = p, p
+		}},
+		{regexp.MustCompile(`^overdue\b`), func(f *listFilter, _ *int, _ []string) { f.Overdue = true }},
+		{regexp.MustCompile(`^#([\p{L}\p{N}_-]+)`), func(f *listFilter, _ *int, m []string) { f.Tag = m[1] }},
+		{regexp.MustCompile(`^(?:created|added|saved|from)\s+(today|this week|this month)\b`), func(f *listFilter, _ *int, m []string) { f.CreatedSince = m[1] }},
+		{regexp.MustCompile(`^page\s+([1-9]\d{0,3})\b`), func(_ *listFilter, page *int, m []string) { *page, _ = strconv.Atoi(m[1]) }},
+		// Words that only glue the filters together.
+		{regexp.MustCompile(`^(?:my|and|with|tagged|reminders?|tasks?|,)`), func(*listFilter, *int, []string) {}},
+	}
+)
+
+// parseListFilter recognises listings such as "list high priority",
+// "list overdue", "list #work", "list health" (one of categories), and "show
+// reminders created this week", optionally with "page 2". It reports false
+// unless the message is made up entirely of known filter terms and names at
+// least one filter.
+func parseListFilter(lowerBody string, categories []string) (listFilter, int, bool) {
+	rest := strings.TrimRight(strings.TrimSpace(lowerBody), "?.!")
+	prefix := listFilterPrefix.FindString(rest)
+	if prefix == "" {
+		return listFilter{}, 0, false
+	}
+	rest = rest[len(prefix):]
+
+	var f listFilter
+	page := 1
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		matched := false
+		for _, term := range listFilterTerms {
+			if m := term.pattern.FindStringSubmatch(rest); m != nil {
+				term.apply(&f, &page, m)
+				rest = rest[len(m[0]):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			if category, n := matchCategoryTerm(rest, categories); n > 0 {
+				f.Category = category
+				rest = rest[n:]
+				matched = true
+			}
+		}
+		if !matched {
+			return listFilter{}, 0, false
+		}
+	}
+	if f.empty() {
+		return listFilter{}, 0, false
+	}
+	return f, page, true
+}
+
+// handleListFilterCommand lists the open reminders matching a filter
+// expression. It reports false when the message is not a filtered listing.
+func (b *Bot) handleListFilterCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	f, page, ok := parseListFilter(lowerBody, b.categories())
+	if !ok {
+		return "", false
+	}
+	if list := b.listRemindersPage(ctx, userID, f, page); list != "" {
+		return list, true
+	}
+	return fmt.Sprintf("You have no reminders matching: %s.", f), true
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
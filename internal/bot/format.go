@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+)
+
+// Inline formatting marks. Replies and reminder messages wrap text in them
+// with bold, strike, and mono, and renderFormatting turns them into the
+// markup of the channel the text goes out on. They are control characters
+// nobody types, so a '*' or '~' in a user's own reminder text is never taken
+// for formatting.
+const (
+	markBold   = "\x02"
+	markStrike = "\x1e"
+	markMono   = "\x11"
+)
+
+// markup is how one channel writes each kind of formatting.
+type markup struct {
+	bold, strike, mono string
+}
+
+// channelMarkup maps channel names onto their markup. Channels not listed,
+// such as email, voice, and SMS, get plain text.
+var channelMarkup = map[string]markup{
+	channel.WhatsApp: {bold: "*", strike: "~", mono: "```"},
+	channel.Discord:  {bold: "**", strike: "~~", mono: "`"},
+}
+
+// bold marks text to be shown in bold, as reminder titles are.
+func bold(text string) string {
+	return markBold + text + markBold
+}
+
+// strike marks text to be struck through, as completed reminders are.
+func strike(text string) string {
+	return markStrike + text + markStrike
+}
+
+// mono marks text to be shown in a fixed-width font, as list positions are.
+func mono(text string) string {
+	return markMono + text + markMono
+}
+
+// renderFormatting writes the formatting marks in text as the markup of the
+// named channel, or drops them for channels without any.
+func renderFormatting(text, channelName string) string {
+	if !strings.ContainsAny(text, markBold+markStrike+markMono) {
+		return text
+	}
+	m := channelMarkup[channelName]
+	return strings.NewReplacer(markBold, m.bold, markStrike, m.strike, markMono, m.mono).Replace(text)
+}
+
+// plainText drops the formatting marks in text.
+func plainText(text string) string {
+	return renderFormatting(text, "")
+}
+
+// twilioChannel returns the channel an incoming Twilio message came in on.
+// Twilio posts SMS to the same webhook as WhatsApp, without the whatsapp:
+// prefix on the sender, and those replies get plain text.
+func twilioChannel(from string) string {
+	if strings.HasPrefix(from, "whatsapp:") {
+		return channel.WhatsApp
+	}
+	return ""
+}
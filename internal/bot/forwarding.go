@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/recurrence"
+)
+
+// shareOfferWindow is how long a shared reminder can be saved with "save".
+const shareOfferWindow = 7 * 24 * time.Hour
+
+var shareReminderPattern = regexp.MustCompile(`(?i)^(?:share|forward|send)\s+(?:reminder\s+)?(r?\d+)\s+(?:with|to)\s+([+\d][\d\s().-]*)$`)
+
+// handleForwardCommand sends a copy of a reminder to another number with
+// "share 3 with +14155550123", saves one shared with the user on "save", and
+// lets the user refuse shared reminders. It reports false for other messages.
+func (b *Bot) handleForwardCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	share := shareReminderPattern.FindStringSubmatch(strings.TrimSpace(body))
+	switch lowerBody = strings.TrimRight(strings.TrimSpace(lowerBody), ".!"); {
+	case share != nil:
+		msg, err = b.shareReminder(ctx, userID, share[1], share[2])
+	case lowerBody == "save" || lowerBody == "save it" || lowerBody == "save to my reminders" || lowerBody == "save shared reminder":
+		var ok bool
+		msg, ok, err = b.saveSharedReminder(ctx, userID)
+		if !ok && err == nil {
+			return "", false
+		}
+	case lowerBody == "no shared reminders" || lowerBody == "block shared reminders" || lowerBody == "stop shared reminders":
+		msg, err = b.setNoShares(userID, true)
+	case lowerBody == "allow shared reminders":
+		msg, err = b.setNoShares(userID, false)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("forward command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// shareReminder sends a card with one of the user's reminders to number,
+// unless they have opted out or refuse shared reminders.
+func (b *Bot) shareReminder(ctx context.Context, userID, selector, number string) (string, error) {
+	reminder, err := b.reminderBySelector(ctx, userID, selector)
+	if err != nil {
+		return "", err
+	}
+	toID, ok := phoneNumber(number)
+	if !ok {
+		return "", userError{fmt.Sprintf("'%s' doesn't look like a phone number. Include the country code, e.g. +14155550123.", strings.TrimSpace(number))}
+	}
+	if toID == userID {
+		return "", userError{"That's your own number."}
+	}
+	to := b.preferences(toID)
+	if to.OptedOutAt != nil || to.NoShares {
+		return "", userError{fmt.Sprintf("%s isn't accepting shared reminders.", toID)}
+	}
+
+	share := model.SharedReminder{
+		FromUserID: userID,
+		ToUserID:   toID,
+		Content:    reminder.Content,
+		Summary:    reminder.Summary,
+		Priority:   reminder.Priority,
+		DueAt:      reminder.DueAt,
+		RRule:      reminder.RRule,
+	}
+	if err := b.db.WithContext(ctx).Create(&share).Error; err != nil {
+		return "", fmt.Errorf("I couldn't share that reminder. Please try again later")
+	}
+	if err := b.notify(ctx, toID, b.shareCard(share, b.location(to))); err != nil {
+		b.logger.Printf("share reminder: notify %s: %v", toID, err)
+		b.db.WithContext(ctx).Delete(&share)
+		return "", userError{fmt.Sprintf("I couldn't reach %s. They may need to message me first.", toID)}
+	}
+	return fmt.Sprintf("Sent '%s' to %s. They can reply 'save' to add it to their reminders.", fallback(reminder.Summary, reminder.Content), toID), nil
+}
+
+// shareCard is the message a shared reminder arrives as.
+func (b *Bot) shareCard(share model.SharedReminder, loc *time.Location) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s shared a reminder with you:\n", share.FromUserID)
+	fmt.Fprintf(&sb, "%s\n", bold(fallback(share.Summary, share.Content)))
+	if share.Summary != "" && share.Summary != share.Content {
+		fmt.Fprintf(&sb, "%s\n", share.Content)
+	}
+	if share.DueAt != nil {
+		fmt.Fprintf(&sb, "Due: %s\n", share.DueAt.In(loc).Format("Mon Jan 2, 2006"))
+	}
+	if share.RRule != "" {
+		if rule, err := recurrence.Parse(share.RRule); err == nil {
+			fmt.Fprintf(&sb, "Repeats: %s\n", rule.Describe())
+		}
+	}
+	fmt.Fprintf(&sb, "Priority: %d\n", share.Priority)
+	sb.WriteString("\nReply 'save' to add it to your reminders, or 'no shared reminders' to stop getting these.")
+	return sb.String()
+}
+
+// saveSharedReminder copies the last reminder shared with the user in the
+// offer window into their own reminders and tells whoever shared it. It
+// reports false when there is none.
+func (b *Bot) saveSharedReminder(ctx context.Context, userID string) (string, bool, error) {
+	var share model.SharedReminder
+	err := b.db.WithContext(ctx).
+		Where("to_user_id = ? AND saved_at IS NULL AND created_at > ?", userID, time.Now().Add(-shareOfferWindow)).
+		Order("id DESC").
+		Limit(1).
+		Find(&share).Error
+	if err != nil {
+		return "", false, fmt.Errorf("I couldn't look up that reminder. Please try again later")
+	}
+	if share.ID == 0 {
+		return "", false, nil
+	}
+
+	reminder := &model.Reminder{
+		UserID:   userID,
+		Content:  share.Content,
+		Summary:  share.Summary,
+		Priority: share.Priority,
+		DueAt:    share.DueAt,
+		RRule:    share.RRule,
+	}
+	if err := b.insertReminder(ctx, reminder); err != nil {
+		return "", true, fmt.Errorf("I couldn't save that reminder. Please try again later")
+	}
+	if err := b.db.WithContext(ctx).Model(&share).Update("saved_at", time.Now()).Error; err != nil {
+		b.logger.Printf("share %d: mark saved: %v", share.ID, err)
+	}
+	text := fallback(share.Summary, share.Content)
+	if err := b.notify(ctx, share.FromUserID, fmt.Sprintf("%s saved '%s' to their reminders.", userID, text)); err != nil {
+		b.logger.Printf("share %d: notify %s: %v", share.ID, share.FromUserID, err)
+	}
+	return fmt.Sprintf("Saved '%s' to your reminders as %s (priority %d).", text, reminder.ShortID(), reminder.Priority), true, nil
+}
+
+// setNoShares refuses reminders other users share, or accepts them again.
+func (b *Bot) setNoShares(userID string, refuse bool) (string, error) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.NoShares = refuse
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't update that setting. Please try again later")
+	}
+	if refuse {
+		return "Okay, nobody can share reminders with you now. Say 'allow shared reminders' to change that.", nil
+	}
+	return "Other people can share reminders with you again.", nil
+}
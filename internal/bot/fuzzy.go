@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// keywordFillers are words people add around a delete or done keyword
+// ("delete the milk reminder") that say nothing about which reminder they
+// mean. They are ignored by fuzzy matching, typos included.
+var keywordFillers = []string{"a", "an", "the", "my", "to", "for", "about", "reminder", "reminders", "task", "tasks", "todo"}
+
+// matchReminders returns the reminders, in the given order, whose content or
+// summary contains keyword. When none do it falls back to fuzzy matching so
+// small typos still find the right reminder: every meaningful word of the
+// keyword must be within a few edits of a word in the reminder.
+func matchReminders(reminders []model.Reminder, keyword string) []model.Reminder {
+	needle := strings.ToLower(strings.TrimSpace(keyword))
+	var matches []model.Reminder
+	for _, r := range reminders {
+		if strings.Contains(strings.ToLower(r.Content), needle) || strings.Contains(strings.ToLower(r.Summary), needle) {
+			matches = append(matches, r)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	var terms []string
+	for _, word := range keywordWords(needle) {
+		if !isFiller(word) {
+			terms = append(terms, word)
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	for _, r := range reminders {
+		words := keywordWords(strings.ToLower(r.Content + " " + r.Summary))
+		if allTermsMatch(terms, words) {
+			matches = append(matches, r)
+		}
+	}
+	return matches
+}
+
+func allTermsMatch(terms, words []string) bool {
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if closeEnough(term, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func isFiller(word string) bool {
+	for _, filler := range keywordFillers {
+		if closeEnough(word, filler) {
+			return true
+		}
+	}
+	return false
+}
+
+// closeEnough reports whether a typed word plausibly means target. Short
+// words must match exactly; longer ones may be one or two edits away.
+func closeEnough(word, target string) bool {
+	allowed := 2
+	switch n := len([]rune(target)); {
+	case n < 4:
+		allowed = 0
+	case n <= 6:
+		allowed = 1
+	}
+	return editDistance(word, target) <= allowed
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the Levenshtein distance, with swapping two adjacent letters counting as
+// a single edit.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(t)]
+}
+
+func keywordWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/reminderpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// GRPCServer returns a gRPC server exposing the Reminders service. Every call
+// must carry the admin token as "authorization: Bearer <token>" metadata.
+func (b *Bot) GRPCServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := b.authorizeGRPC(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := b.authorizeGRPC(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	reminderpb.RegisterRemindersServer(srv, &grpcReminders{bot: b})
+	return srv
+}
+
+// CloseEventStreams ends open WatchEvents streams so the gRPC server can stop
+// gracefully.
+func (b *Bot) CloseEventStreams() {
+	b.events.closeAll()
+}
+
+func (b *Bot) authorizeGRPC(ctx context.Context) error {
+	if b.cfg.AdminToken == "" {
+		return status.Error(codes.Unavailable, "the gRPC API is disabled until ADMIN_TOKEN is set")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token := strings.TrimPrefix(value, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(b.cfg.AdminToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing admin token")
+}
+
+// grpcReminders implements reminderpb.RemindersServer on top of the Bot.
+type grpcReminders struct {
+	reminderpb.UnimplementedRemindersServer
+	bot *Bot
+}
+
+func (s *grpcReminders) CreateReminder(ctx context.Context, req *reminderpb.CreateReminderRequest) (*reminderpb.Reminder, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	content := req.GetContent()
+	in := reminderInput{Content: &content}
+	if req.GetPriority() != 0 {
+		priority := int(req.GetPriority())
+		in.Priority = &priority
+	}
+	if req.GetDueAt() != nil {
+		due := req.GetDueAt().AsTime().Format(time.RFC3339)
+		in.DueAt = &due
+	}
+	reminder, err := s.bot.createReminder(ctx, req.GetUserId(), in)
+	if err != nil {
+		return nil, s.bot.grpcError(err)
+	}
+	return reminderProto(*reminder, time.Now().In(s.bot.cfg.LocalTimezone)), nil
+}
+
+func (s *grpcReminders) ListReminders(ctx context.Context, req *reminderpb.ListRemindersRequest) (*reminderpb.ListRemindersResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	reminders, err := s.bot.openReminders(ctx, req.GetUserId())
+	if err != nil {
+		return nil, s.bot.grpcError(err)
+	}
+	now := time.Now().In(s.bot.cfg.LocalTimezone)
+	resp := &reminderpb.ListRemindersResponse{Reminders: make([]*reminderpb.Reminder, len(reminders))}
+	for i, r := range reminders {
+		resp.Reminders[i] = reminderProto(r, now)
+	}
+	return resp, nil
+}
+
+func (s *grpcReminders) DeleteReminder(ctx context.Context, req *reminderpb.DeleteReminderRequest) (*reminderpb.DeleteReminderResponse, error) {
+	if req.GetUserId() == "" || req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id and id are required")
+	}
+	deleted, err := s.bot.removeReminders(ctx, s.bot.db.Where("user_id = ? AND id = ?", req.GetUserId(), req.GetId()))
+	if err != nil {
+		return nil, s.bot.grpcError(err)
+	}
+	if len(deleted) == 0 {
+		return nil, status.Error(codes.NotFound, "reminder not found")
+	}
+	return &reminderpb.DeleteReminderResponse{}, nil
+}
+
+// WatchEvents streams events until the client goes away or the server shuts
+// down. Events are dropped for clients that fall too far behind.
+func (s *grpcReminders) WatchEvents(req *reminderpb.WatchEventsRequest, stream grpc.ServerStreamingServer[reminderpb.Event]) error {
+	sub := s.bot.events.subscribe(req.GetUserId(), req.GetEventTypes())
+	defer s.bot.events.unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if err := stream.Send(&reminderpb.Event{
+				Id:         event.ID,
+				Type:       event.Type,
+				OccurredAt: timestamppb.New(event.OccurredAt),
+				UserId:     event.UserID,
+				Reminder:   reminderProto(event.Reminder, time.Now().In(s.bot.cfg.LocalTimezone)),
+				Message:    event.Event.Reminder.Message,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError maps user errors to InvalidArgument and logs anything else.
+func (b *Bot) grpcError(err error) error {
+	if isUserError(err) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status.Error(codes.NotFound, "not found")
+	}
+	b.logger.Printf("grpc: %v", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func reminderProto(r model.Reminder, now time.Time) *reminderpb.Reminder {
+	res := reminderResource(r, now)
+	pb := &reminderpb.Reminder{
+		Id:        uint64(res.ID),
+		UserId:    r.UserID,
+		Content:   res.Content,
+		Summary:   res.Summary,
+		Priority:  int32(res.Priority),
+		Kind:      res.Kind,
+		Streak:    int32(res.Streak),
+		CreatedAt: timestamppb.New(res.CreatedAt),
+	}
+	if res.DueAt != nil {
+		pb.DueAt = timestamppb.New(*res.DueAt)
+	}
+	if res.CompletedAt != nil {
+		pb.CompletedAt = timestamppb.New(*res.CompletedAt)
+	}
+	return pb
+}
@@ -0,0 +1,346 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+var (
+	habitRequestPattern = regexp.MustCompile(`(?i)^(?:new )?habit\s*:\s*(.+)$`)
+	doneRequestPattern  = regexp.MustCompile(`(?i)^done(?:\s+(.+))?$`)
+	skipRequestPattern  = regexp.MustCompile(`(?i)^(?:skip|not today)(?:\s+(.+?))?[.!]?$`)
+)
+
+const defaultHabitPriority = 3
+
+// parseHabitRequest extracts the habit text from "habit: meditate 10 minutes".
+func parseHabitRequest(body string) (string, bool) {
+	matches := habitRequestPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if len(matches) < 2 || strings.TrimSpace(matches[1]) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(matches[1]), true
+}
+
+// parseDoneRequest recognises "done", "done 2", and "done meditate".
+func parseDoneRequest(body string) (string, bool) {
+	matches := doneRequestPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if matches == nil {
+		return "", false
+	}
+	return strings.TrimSpace(matches[1]), true
+}
+
+// parseSkipRequest recognises "skip", "not today", "skip 2", and "skip R3".
+func parseSkipRequest(body string) (string, bool) {
+	matches := skipRequestPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if matches == nil {
+		return "", false
+	}
+	return strings.TrimSpace(matches[1]), true
+}
+
+// addHabit saves a daily habit and returns the reply for the user.
+func (b *Bot) addHabit(ctx context.Context, userID, content string) string {
+	priority := parseTemplatePriority(content)
+	if priority == 0 {
+		priority = defaultHabitPriority
+	}
+	summary := b.summarizeReminderWithOpenAI(ctx, userID, content)
+	habit := &model.Reminder{
+		UserID:   userID,
+		Content:  content,
+		Priority: priority,
+		Summary:  summary,
+		Kind:     model.KindHabit,
+	}
+	if err := b.db.WithContext(ctx).Create(habit).Error; err != nil {
+		b.logger.Printf("save habit: %v", err)
+		return "I couldn't save the habit. Please try again."
+	}
+	b.emit(webhook.EventReminderCreated, *habit, "")
+	return fmt.Sprintf("New habit: %s (priority %d). I'll nudge you every day — reply 'done' to keep your streak going.", summary, priority)
+}
+
+// markDone checks in a habit or completes a reminder selected by index or keyword.
+// An empty selector picks the only habit still open today.
+func (b *Bot) markDone(ctx context.Context, userID, selector string) (string, error) {
+	reminders, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't look up your reminders right now. Please try again later")
+	}
+	if len(reminders) == 0 {
+		return "", userError{"You don't have any reminders to mark as done."}
+	}
+
+	now := time.Now().In(b.cfg.LocalTimezone)
+	var targets []model.Reminder
+	switch indices, codes := parseIndices(selector), parseReminderCodes(selector); {
+	case selector == "":
+		for _, r := range reminders {
+			if r.IsHabit() && !checkedInOn(r, now) {
+				targets = append(targets, r)
+			}
+		}
+		if len(targets) == 0 {
+			return "", userError{"Tell me what you finished, e.g. 'done 2' or 'done rent'."}
+		}
+	case len(indices) > 0:
+		for _, idx := range indices {
+			if idx < 1 || idx > len(reminders) {
+				return "", userError{fmt.Sprintf("Reminder %d doesn't exist. Choose between 1 and %d.", idx, len(reminders))}
+			}
+			targets = append(targets, reminders[idx-1])
+		}
+	case len(codes) > 0:
+		byCode := make(map[int]model.Reminder, len(reminders))
+		for _, r := range reminders {
+			byCode[r.Code] = r
+		}
+		for _, code := range codes {
+			r, ok := byCode[code]
+			if !ok {
+				return "", userError{fmt.Sprintf("R%d isn't one of your open reminders.", code)}
+			}
+			targets = append(targets, r)
+		}
+	default:
+		targets = matchReminders(reminders, selector)
+		if len(targets) == 0 {
+			return "", userError{"I couldn't find any reminders matching that description."}
+		}
+	}
+
+	if len(targets) > 1 && len(parseIndices(selector)) == 0 && len(parseReminderCodes(selector)) == 0 {
+		var sb strings.Builder
+		sb.WriteString("Which one did you finish? Reply with its number:\n")
+		for i, r := range reminders {
+			for _, t := range targets {
+				if t.ID == r.ID {
+					sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, fallback(r.Summary, r.Content)))
+				}
+			}
+		}
+		return "", userError{strings.TrimSpace(sb.String())}
+	}
+
+	replies := make([]string, 0, len(targets))
+	for i := range targets {
+		reply, err := b.completeReminder(ctx, &targets[i], now)
+		if err != nil {
+			return "", err
+		}
+		replies = append(replies, reply)
+	}
+	return strings.Join(replies, "\n"), nil
+}
+
+// completeReminder records a habit check-in or marks a one-off reminder complete.
+func (b *Bot) completeReminder(ctx context.Context, reminder *model.Reminder, now time.Time) (string, error) {
+	text := fallback(reminder.Summary, reminder.Content)
+	if reminder.IsAnnual() {
+		return "", userError{fmt.Sprintf("%s comes back every year, so there's nothing to mark done. Say 'delete %s' to remove it.", text, reminder.ShortID())}
+	}
+	if reminder.RRule != "" {
+		return b.completeOccurrence(ctx, reminder, now)
+	}
+	if !reminder.IsHabit() {
+		if err := b.db.WithContext(ctx).Model(reminder).Update("completed_at", now).Error; err != nil {
+			return "", fmt.Errorf("I couldn't update that reminder. Please try again later")
+		}
+		b.emit(webhook.EventReminderCompleted, *reminder, "")
+		reply := fmt.Sprintf("Marked '%s' as done.", strike(text))
+		children, err := b.unblocked(ctx, *reminder)
+		if err != nil {
+			b.logger.Printf("dependency: load reminders after %s: %v", reminder.ShortID(), err)
+		}
+		for _, child := range children {
+			reply += fmt.Sprintf(" Up next: %s %s.", child.ShortID(), fallback(child.Summary, child.Content))
+		}
+		return reply, nil
+	}
+
+	if !applyCheckIn(reminder, now) {
+		return fmt.Sprintf("You've already checked in '%s' today — Day %d streak.", text, reminder.Streak), nil
+	}
+	if err := b.db.WithContext(ctx).Model(reminder).Updates(map[string]any{
+		"streak":        reminder.Streak,
+		"last_check_in": reminder.LastCheckIn,
+	}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't record that check-in. Please try again later")
+	}
+	b.emit(webhook.EventReminderCompleted, *reminder, "")
+	return fmt.Sprintf("Nice! '%s' — Day %d streak!", text, reminder.Streak), nil
+}
+
+// skipHabit marks today as skipped on purpose for the habit selected by list
+// number, code, or keyword, so its streak carries on to tomorrow. An empty
+// selector picks the only habit not yet done or skipped today.
+func (b *Bot) skipHabit(ctx context.Context, userID, selector string) (string, error) {
+	reminders, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't look up your reminders right now. Please try again later")
+	}
+	var habits []model.Reminder
+	for _, r := range reminders {
+		if r.IsHabit() {
+			habits = append(habits, r)
+		}
+	}
+	if len(habits) == 0 {
+		return "", userError{"Only daily habits can be skipped, and you don't have any."}
+	}
+
+	now := time.Now().In(b.cfg.LocalTimezone)
+	var targets []model.Reminder
+	switch indices, codes := parseIndices(selector), parseReminderCodes(selector); {
+	case selector == "":
+		for _, h := range habits {
+			if !coveredOn(h, now) {
+				targets = append(targets, h)
+			}
+		}
+		if len(targets) == 0 {
+			return "", userError{"Your habits are all done or skipped for today."}
+		}
+	case len(indices) == 1:
+		if indices[0] < 1 || indices[0] > len(reminders) {
+			return "", userError{fmt.Sprintf("Reminder %d doesn't exist. Choose between 1 and %d.", indices[0], len(reminders))}
+		}
+		targets = append(targets, reminders[indices[0]-1])
+	case len(codes) == 1:
+		for _, r := range reminders {
+			if r.Code == codes[0] {
+				targets = append(targets, r)
+			}
+		}
+		if len(targets) == 0 {
+			return "", userError{fmt.Sprintf("R%d isn't one of your open reminders.", codes[0])}
+		}
+	case len(indices) > 1 || len(codes) > 1:
+		return "", userError{"Skip one habit at a time, e.g. 'skip R3'."}
+	default:
+		targets = matchReminders(habits, selector)
+		if len(targets) == 0 {
+			return "", userError{"I couldn't find a habit matching that description."}
+		}
+	}
+
+	if len(targets) > 1 {
+		var sb strings.Builder
+		sb.WriteString("Which habit are you skipping today? Reply 'skip' with its code:\n")
+		for _, h := range targets {
+			sb.WriteString(fmt.Sprintf("%s %s\n", h.ShortID(), fallback(h.Summary, h.Content)))
+		}
+		return "", userError{strings.TrimSpace(sb.String())}
+	}
+	habit := targets[0]
+	text := fallback(habit.Summary, habit.Content)
+	if !habit.IsHabit() {
+		return "", userError{fmt.Sprintf("'%s' isn't a daily habit, so there's no day to skip.", text)}
+	}
+	switch {
+	case checkedInOn(habit, now):
+		return fmt.Sprintf("You've already checked in '%s' today — Day %d streak.", text, habit.Streak), nil
+	case skippedOn(habit, now):
+		return fmt.Sprintf("'%s' is already skipped for today.", text), nil
+	}
+
+	applySkip(&habit, now)
+	if err := b.db.WithContext(ctx).Model(&habit).Updates(map[string]any{
+		"streak":    habit.Streak,
+		"last_skip": habit.LastSkip,
+		"skips":     habit.Skips,
+	}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't skip that habit. Please try again later")
+	}
+	b.emit(webhook.EventReminderUpdated, habit, "")
+	if habit.Streak == 0 {
+		return fmt.Sprintf("Skipped '%s' for today. See you tomorrow for a fresh start!", text), nil
+	}
+	return fmt.Sprintf("Skipped '%s' for today — your Day %d streak is safe.", text, habit.Streak), nil
+}
+
+// applyCheckIn updates the streak for a check-in at now. It returns false when
+// the habit was already checked in that day. A skipped day before now keeps
+// the streak going.
+func applyCheckIn(habit *model.Reminder, now time.Time) bool {
+	if checkedInOn(*habit, now) {
+		return false
+	}
+	if coveredOn(*habit, now.AddDate(0, 0, -1)) {
+		habit.Streak++
+	} else {
+		habit.Streak = 1
+	}
+	checkIn := now
+	habit.LastCheckIn = &checkIn
+	return true
+}
+
+// applySkip marks now's day as skipped. Skipping a day after the streak has
+// already lapsed doesn't bring it back.
+func applySkip(habit *model.Reminder, now time.Time) {
+	if !coveredOn(*habit, now.AddDate(0, 0, -1)) {
+		habit.Streak = 0
+	}
+	skip := now
+	habit.LastSkip = &skip
+	habit.Skips++
+}
+
+// checkedInOn reports whether the habit's last check-in fell on the same calendar day as day.
+func checkedInOn(habit model.Reminder, day time.Time) bool {
+	return sameDay(habit.LastCheckIn, day)
+}
+
+// skippedOn reports whether the habit was last skipped on the same calendar day as day.
+func skippedOn(habit model.Reminder, day time.Time) bool {
+	return sameDay(habit.LastSkip, day)
+}
+
+// coveredOn reports whether the habit was checked in or skipped on day.
+func coveredOn(habit model.Reminder, day time.Time) bool {
+	return checkedInOn(habit, day) || skippedOn(habit, day)
+}
+
+func sameDay(t *time.Time, day time.Time) bool {
+	if t == nil {
+		return false
+	}
+	y1, m1, d1 := t.In(day.Location()).Date()
+	y2, m2, d2 := day.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// currentStreak returns the streak that is still alive at now.
+func currentStreak(habit model.Reminder, now time.Time) int {
+	if coveredOn(habit, now) || coveredOn(habit, now.AddDate(0, 0, -1)) {
+		return habit.Streak
+	}
+	return 0
+}
+
+// streakMessage renders the streak line used in scheduled habit messages.
+func streakMessage(habit model.Reminder, now time.Time) string {
+	streak := currentStreak(habit, now)
+	if streak == 0 {
+		return "Start a new streak today!"
+	}
+	return fmt.Sprintf("Day %d streak!", streak)
+}
+
+// streakLabel renders the streak shown in list output.
+func streakLabel(habit model.Reminder, now time.Time) string {
+	streak := currentStreak(habit, now)
+	if streak == 1 {
+		return "1-day streak"
+	}
+	return fmt.Sprintf("%d-day streak", streak)
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var helpRequestPattern = regexp.MustCompile(`^(?:help(?:\s+me)?|\?|what can you do|how does this work)(?:\s+(?:with\s+|on\s+)?(.+?))?[?.!]*$`)
+
+// helpTopics are the pages "help <topic>" shows, keyed by topic. Aliases map
+// onto them through helpTopicAliases.
+var helpTopics = map[string]string{
+	"delete": "Deleting reminders:\n" +
+		"- \"Delete R7\" removes one by its code, \"delete 2\" by its list number, and \"delete 1,3\" several at once\n" +
+		"- \"Delete reminder about rent\" finds it by its words; if several match I'll ask which, and you can reply with numbers, \"all\", or \"cancel\"\n" +
+		"- \"Clear all reminders\" wipes everything\n" +
+		"- Right after saving one, \"undo\" removes it",
+	"recurring": "Repeating reminders:\n" +
+		"- \"Water the plants every 3 days\", \"pay rent on the first Monday of every month\", or \"team sync every Tuesday and Thursday\"\n" +
+		"- I send them only on the days they fall on, and \"done\" moves one on to its next date\n" +
+		"- Add \"business days only\" to skip weekends and public holidays\n" +
+		"- \"Show R7\" tells you how one repeats; \"delete R7\" stops it",
+	"priorities": "Priorities:\n" +
+		"- Each reminder has a priority from 1 (can wait) to 5 (urgent), and higher ones are sent first\n" +
+		"- \"Set R3 priority 5\" or \"change the priority of rent to 2\" changes one\n" +
+		"- \"List high priority\" or \"list priority 3\" filters your list\n" +
+		"- \"Rebalance\" suggests new priorities when too many are urgent",
+	"habits": "Habits:\n" +
+		"- \"Habit: meditate 10 minutes\" tracks something you do every day\n" +
+		"- \"Done\" keeps the streak going, and \"skip\" or \"not today\" takes a day off without breaking it\n" +
+		"- \"Show R7\" shows the streak",
+}
+
+var helpTopicAliases = map[string]string{
+	"deleting":            "delete",
+	"delete reminders":    "delete",
+	"remove":              "delete",
+	"repeat":              "recurring",
+	"repeating":           "recurring",
+	"recurring reminders": "recurring",
+	"recurrence":          "recurring",
+	"priority":            "priorities",
+	"habit":               "habits",
+	"streaks":             "habits",
+}
+
+// handleHelpCommand answers "help". While the bot is waiting on an answer it
+// explains that step; "help delete" and the like show one topic. It reports
+// false for other messages.
+func (b *Bot) handleHelpCommand(userID, lowerBody string) (string, bool) {
+	m := helpRequestPattern.FindStringSubmatch(strings.TrimSpace(lowerBody))
+	if m == nil {
+		return "", false
+	}
+	if topic := m[1]; topic != "" {
+		if alias, ok := helpTopicAliases[topic]; ok {
+			topic = alias
+		}
+		if page, ok := helpTopics[topic]; ok {
+			return page, true
+		}
+		if len(strings.Fields(topic)) > 2 {
+			// "help me remember to call mum" is a reminder, not a topic.
+			return "", false
+		}
+		topics := make([]string, 0, len(helpTopics))
+		for name := range helpTopics {
+			topics = append(topics, "help "+name)
+		}
+		slices.Sort(topics)
+		return fmt.Sprintf("I don't have help on %s yet. Try %s.", topic, strings.Join(topics, ", ")), true
+	}
+	if step := b.stepHelp(userID); step != "" {
+		return step + "\n\nSay 'cancel' to stop, then 'help' for everything else I can do.", true
+	}
+	return helpResponse() + b.commands.helpLines(), true
+}
+
+// stepHelp explains the question the bot is waiting on an answer to, or
+// returns "" when there is none.
+func (b *Bot) stepHelp(userID string) string {
+	state, ok := b.state.Peek(userID)
+	switch {
+	case !ok:
+		return ""
+	case state.AwaitingPriority:
+		return fmt.Sprintf("I'm saving '%s' and need its priority. Reply with a number from 1 (can wait) to 5 (urgent); higher ones are sent first.", truncate(state.PendingMessage, 60))
+	case len(state.DeleteCandidates) > 0:
+		return "Several reminders matched what you asked to delete. Reply with the numbers of the ones to remove, such as 1 or 1,3, or 'all'."
+	case len(state.Clarify) > 0:
+		return fmt.Sprintf("I wasn't sure what you meant by '%s'. Reply with one of the options I gave, or say it another way.", truncate(state.PendingMessage, 60))
+	case state.Reschedule != nil:
+		return "I offered to move an overdue reminder to a new time. Reply yes to move it, or no to leave its due date as it is."
+	case len(state.Rebalance) > 0:
+		return "I suggested new priorities for your reminders. Reply yes to apply them all, or no to keep your priorities as they are."
+	case state.Location != nil:
+		return "You shared a location. Reply 'remind me about this place: buy bread' to save a reminder with it, or 'attach this place to R3' to add it to one you have."
+	case state.Attachment != nil:
+		return "You sent a photo or file. Reply 'remind me about this: renew passport' to save a reminder with it, or 'attach this file to R3' to add it to one you have."
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+// hookPath is where automations post reminders.
+const hookPath = "/api/v1/hooks/create-reminder"
+
+// handleHookCommand issues and revokes the user's token for the inbound
+// automation hook. It reports false when the message is not a hook command.
+func (b *Bot) handleHookCommand(userID, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	switch strings.TrimRight(lowerBody, ".!?") {
+	case "hook token", "new hook token", "my hook token":
+		msg, err = b.issueHookToken(userID)
+	case "revoke hook token", "delete hook token":
+		msg, err = b.revokeHookToken(userID)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("hook command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// issueHookToken replaces the user's hook token with a new one and returns
+// it. Only its hash is kept, so it can't be shown again later.
+func (b *Bot) issueHookToken(userID string) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	err = b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.HookToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.HookToken{TokenHash: hashToken(token), UserID: userID}).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("I couldn't create a hook token. Please try again later")
+	}
+
+	endpoint := hookPath
+	if b.cfg.PublicBaseURL != "" {
+		endpoint = b.cfg.PublicBaseURL + hookPath
+	}
+	return fmt.Sprintf("Your hook token: %s\nPOST reminders as JSON to %s with the header 'Authorization: Bearer <token>'. Keep it secret; it replaces any token you had, and 'revoke hook token' turns it off.", token, endpoint), nil
+}
+
+// revokeHookToken deletes the user's hook token.
+func (b *Bot) revokeHookToken(userID string) (string, error) {
+	result := b.db.Where("user_id = ?", userID).Delete(&model.HookToken{})
+	if result.Error != nil {
+		return "", fmt.Errorf("I couldn't revoke your hook token. Please try again later")
+	}
+	if result.RowsAffected == 0 {
+		return "", userError{"You have no hook token. Send 'hook token' to get one."}
+	}
+	return "Hook token revoked. Automations using it can no longer add reminders.", nil
+}
+
+// requireHookToken rejects requests without a user's hook token as a bearer
+// token, and passes the token's owner on like a dashboard session.
+func (b *Bot) requireHookToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		userID := ""
+		if ok && token != "" {
+			var err error
+			if userID, err = b.hookTokenUser(r.Context(), token); err != nil {
+				b.logger.Printf("hooks: look up token: %v", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+				return
+			}
+		}
+		if userID == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="myMemo hooks"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, userID)))
+	}
+}
+
+// hookTokenUser returns the user token belongs to, or "" for none.
+func (b *Bot) hookTokenUser(ctx context.Context, token string) (string, error) {
+	var hook model.HookToken
+	err := b.db.WithContext(ctx).Where("token_hash = ?", hashToken(token)).First(&hook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return hook.UserID, err
+}
+
+// apiHookCreateReminder adds a reminder for the hook token's owner from an
+// automation such as Zapier, IFTTT, or a CI job.
+func (b *Bot) apiHookCreateReminder(w http.ResponseWriter, r *http.Request) {
+	var req reminderInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		b.writeAdminError(w, userError{"invalid JSON body"})
+		return
+	}
+	reminder, err := b.createReminder(r.Context(), sessionUserID(r.Context()), req)
+	if err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, reminderResource(*reminder, time.Now().In(b.cfg.LocalTimezone)))
+}
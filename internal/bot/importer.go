@@ -0,0 +1,271 @@
+package bot
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// Import formats.
+const (
+	ImportKeep    = "keep"
+	ImportTodoist = "todoist"
+)
+
+// maxImportBytes caps the size of an uploaded export.
+const maxImportBytes = 32 << 20
+
+// ImportResult counts what an import created.
+type ImportResult struct {
+	Reminders int `json:"reminders"`
+	Memos     int `json:"memos"`
+	// Skipped counts entries with nothing to import, such as trashed notes,
+	// ticked list items, and Todoist sections.
+	Skipped int `json:"skipped"`
+}
+
+// importedItems is an export mapped onto myMemo: tasks become reminders and
+// free text becomes note memos.
+type importedItems struct {
+	reminders []reminderInput
+	memos     []model.Memo
+	skipped   int
+}
+
+// keepNote is the part of a Google Keep Takeout note the importer uses.
+type keepNote struct {
+	Title       string `json:"title"`
+	TextContent string `json:"textContent"`
+	ListContent []struct {
+		Text      string `json:"text"`
+		IsChecked bool   `json:"isChecked"`
+	} `json:"listContent"`
+	IsTrashed            bool  `json:"isTrashed"`
+	CreatedTimestampUsec int64 `json:"createdTimestampUsec"`
+}
+
+// parseKeepExport reads a Google Keep Takeout export: the Takeout ZIP, one
+// note's JSON file, or a JSON array of notes. Unticked checklist items
+// become reminders and text notes become memos.
+func parseKeepExport(data []byte) (importedItems, error) {
+	var notes []keepNote
+	switch {
+	case bytes.HasPrefix(data, []byte("PK")):
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return importedItems{}, userError{"the Keep export isn't a valid ZIP file"}
+		}
+		for _, file := range archive.File {
+			if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(file.Name), ".json") {
+				continue
+			}
+			note, err := readKeepNote(file)
+			if err != nil {
+				return importedItems{}, userError{fmt.Sprintf("%s: %v", file.Name, err)}
+			}
+			notes = append(notes, note)
+		}
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")):
+		if err := json.Unmarshal(data, &notes); err != nil {
+			return importedItems{}, userError{"the Keep export isn't valid JSON"}
+		}
+	default:
+		var note keepNote
+		if err := json.Unmarshal(data, &note); err != nil {
+			return importedItems{}, userError{"the Keep export isn't valid JSON"}
+		}
+		notes = append(notes, note)
+	}
+
+	var items importedItems
+	for _, note := range notes {
+		text := strings.TrimSpace(note.TextContent)
+		if note.IsTrashed || (text == "" && len(note.ListContent) == 0) {
+			items.skipped++
+			continue
+		}
+		for _, entry := range note.ListContent {
+			content := strings.TrimSpace(entry.Text)
+			if entry.IsChecked || content == "" {
+				items.skipped++
+				continue
+			}
+			items.reminders = append(items.reminders, reminderInput{Content: &content})
+		}
+		if text == "" {
+			continue
+		}
+		memo := model.Memo{Kind: model.MemoKindNote, Title: strings.TrimSpace(note.Title), Content: text}
+		if memo.Title == "" {
+			memo.Title = truncate(text, 40)
+		}
+		if note.CreatedTimestampUsec > 0 {
+			memo.CreatedAt = time.UnixMicro(note.CreatedTimestampUsec)
+		}
+		items.memos = append(items.memos, memo)
+	}
+	return items, nil
+}
+
+func readKeepNote(file *zip.File) (keepNote, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return keepNote{}, err
+	}
+	defer rc.Close()
+	var note keepNote
+	if err := json.NewDecoder(rc).Decode(&note); err != nil {
+		return keepNote{}, errors.New("not a Keep note")
+	}
+	return note, nil
+}
+
+// todoistPriorities maps Todoist's CSV priorities, 1 (urgent) to 4 (none),
+// onto reminder priorities.
+var todoistPriorities = map[string]int{"1": 5, "2": 4, "3": 3, "4": 2}
+
+// parseTodoistCSV reads a Todoist project exported as CSV. Tasks become
+// reminders, with their description on a second line and their date as the
+// due date when it is a plain date, and comments become memos.
+func parseTodoistCSV(data []byte) (importedItems, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return importedItems{}, userError{"the Todoist export is empty or isn't CSV"}
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["TYPE"]; !ok {
+		return importedItems{}, userError{"the Todoist export has no TYPE column"}
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return importedItems{}, userError{"the Todoist export has no CONTENT column"}
+	}
+
+	var items importedItems
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return importedItems{}, userError{fmt.Sprintf("the Todoist export is malformed at line %d", line)}
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		content := field("CONTENT")
+		if content == "" {
+			continue
+		}
+		switch strings.ToLower(field("TYPE")) {
+		case "task":
+			if description := field("DESCRIPTION"); description != "" {
+				content += "\n" + description
+			}
+			in := reminderInput{Content: &content}
+			if priority, ok := todoistPriorities[field("PRIORITY")]; ok {
+				in.Priority = &priority
+			}
+			if date := field("DATE"); len(date) >= len(time.DateOnly) {
+				if _, err := time.Parse(time.DateOnly, date[:len(time.DateOnly)]); err == nil {
+					due := date[:len(time.DateOnly)]
+					in.DueAt = &due
+				}
+			}
+			items.reminders = append(items.reminders, in)
+		case "note":
+			items.memos = append(items.memos, model.Memo{Kind: model.MemoKindNote, Title: truncate(content, 40), Content: content})
+		default:
+			items.skipped++
+		}
+	}
+	return items, nil
+}
+
+// importExport parses an export in format and saves its reminders, in
+// batches with one summarisation request each, and memos for userID.
+func (b *Bot) importExport(ctx context.Context, userID, format string, data []byte) (ImportResult, error) {
+	if userID == "" {
+		return ImportResult{}, userError{"user_id is required"}
+	}
+	var (
+		items importedItems
+		err   error
+	)
+	switch format {
+	case ImportKeep:
+		items, err = parseKeepExport(data)
+	case ImportTodoist:
+		items, err = parseTodoistCSV(data)
+	default:
+		return ImportResult{}, userError{fmt.Sprintf("format must be %s or %s", ImportKeep, ImportTodoist)}
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{Skipped: items.skipped}
+	for start := 0; start < len(items.reminders); start += maxBatchReminders {
+		batch := items.reminders[start:min(start+maxBatchReminders, len(items.reminders))]
+		created, err := b.createReminders(ctx, userID, batch)
+		if err != nil {
+			if isUserError(err) {
+				return result, userError{fmt.Sprintf("after %d reminders: %s", result.Reminders, err)}
+			}
+			return result, err
+		}
+		result.Reminders += len(created)
+	}
+	for i := range items.memos {
+		items.memos[i].UserID = userID
+	}
+	if len(items.memos) > 0 {
+		if err := b.db.WithContext(ctx).Create(&items.memos).Error; err != nil {
+			return result, err
+		}
+		result.Memos = len(items.memos)
+	}
+	return result, nil
+}
+
+// AdminImportHandler imports a Google Keep or Todoist export, posted as the
+// request body, for ?user_id=. ?format= is keep or todoist.
+func (b *Bot) AdminImportHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+		if err != nil {
+			b.writeAdminError(w, userError{fmt.Sprintf("the export must be at most %d MB", maxImportBytes>>20)})
+			return
+		}
+		query := r.URL.Query()
+		result, err := b.importExport(r.Context(), query.Get("user_id"), strings.ToLower(query.Get("format")), data)
+		if err != nil {
+			b.writeAdminError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, result)
+	}))
+}
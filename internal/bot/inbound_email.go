@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultEmailPriority is the priority given to reminders created by email.
+	defaultEmailPriority = 3
+	// maxInboundBodyRunes caps how much of an email body is kept on the reminder.
+	maxInboundBodyRunes = 1000
+	// mailgunMaxSkew is how old a Mailgun webhook timestamp may be.
+	mailgunMaxSkew = 5 * time.Minute
+)
+
+var forwardPrefixPattern = regexp.MustCompile(`(?i)^\s*((fwd?|fw|re)\s*:\s*)+`)
+
+// inboundEmail is the provider-neutral content of a received email.
+type inboundEmail struct {
+	From    string
+	Subject string
+	Text    string
+}
+
+// InboundEmailHandler accepts SendGrid and Mailgun inbound parse webhooks and
+// turns each email into a reminder for the user whose address sent it.
+func (b *Bot) InboundEmailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.cfg.InboundEmailToken == "" && b.cfg.MailgunSigningKey == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseMultipartForm(10 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			http.Error(w, "invalid form body", http.StatusBadRequest)
+			return
+		}
+		if !b.authorizeInboundEmail(r, time.Now()) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		email, err := parseInboundEmail(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Unknown senders still get a 200 so the provider doesn't retry.
+		if err := b.addReminderFromEmail(r.Context(), email); err != nil {
+			b.logger.Printf("inbound email from %s: %v", email.From, err)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// authorizeInboundEmail accepts a valid Mailgun signature or the shared token
+// in the "token" query parameter (used for SendGrid, which doesn't sign).
+func (b *Bot) authorizeInboundEmail(r *http.Request, now time.Time) bool {
+	if key := b.cfg.MailgunSigningKey; key != "" && r.FormValue("signature") != "" {
+		return verifyMailgunSignature(key, r.FormValue("timestamp"), r.FormValue("token"), r.FormValue("signature"), now)
+	}
+	token := b.cfg.InboundEmailToken
+	return token != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) == 1
+}
+
+// verifyMailgunSignature checks Mailgun's HMAC-SHA256 of timestamp+token and
+// rejects stale timestamps.
+func verifyMailgunSignature(key, timestamp, token, signature string, now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > mailgunMaxSkew || skew < -mailgunMaxSkew {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + token))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// parseInboundEmail reads the sender, subject, and plain-text body from either
+// provider's form fields.
+func parseInboundEmail(r *http.Request) (inboundEmail, error) {
+	from := r.FormValue("sender") // Mailgun's bare envelope sender
+	if from == "" {
+		from = r.FormValue("from")
+	}
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return inboundEmail{}, fmt.Errorf("invalid sender address")
+	}
+
+	text := r.FormValue("stripped-text")
+	if text == "" {
+		text = r.FormValue("body-plain")
+	}
+	if text == "" {
+		text = r.FormValue("text")
+	}
+	return inboundEmail{
+		From:    strings.ToLower(addr.Address),
+		Subject: strings.TrimSpace(forwardPrefixPattern.ReplaceAllString(r.FormValue("subject"), "")),
+		Text:    strings.TrimSpace(text),
+	}, nil
+}
+
+// addReminderFromEmail saves the email as a reminder for the user registered
+// with the sender address and tells them on WhatsApp.
+func (b *Bot) addReminderFromEmail(ctx context.Context, email inboundEmail) error {
+	var pref model.UserPreference
+	err := b.db.WithContext(ctx).Where("LOWER(email) = ?", email.From).First(&pref).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("no user registered with this address")
+	}
+	if err != nil {
+		return err
+	}
+
+	content := email.Subject
+	if body := truncate(email.Text, maxInboundBodyRunes); body != "" {
+		content = strings.TrimSpace(content + "\n\n" + body)
+	}
+	if content == "" {
+		return fmt.Errorf("email has no subject or body")
+	}
+	if b.flagged(ctx, pref.UserID, "email", content) {
+		return fmt.Errorf("content flagged by moderation")
+	}
+
+	summary := b.summarizeReminderWithOpenAI(ctx, pref.UserID, content)
+	if summary == content && email.Subject != "" {
+		// Summarising failed; the subject reads better than the whole email.
+		summary = email.Subject
+	}
+	if err := b.saveReminder(ctx, pref.UserID, content, defaultEmailPriority, summary); err != nil {
+		return fmt.Errorf("save reminder: %w", err)
+	}
+
+	reply := fmt.Sprintf("Added from your email: %s (priority %d).", summary, defaultEmailPriority)
+	if err := b.notify(ctx, pref.UserID, reply); err != nil {
+		b.logger.Printf("inbound email: notify %s: %v", pref.UserID, err)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+const (
+	// insightsWindow is how far back completions count towards insights.
+	insightsWindow = 90 * 24 * time.Hour
+	// minWeekdayCompletions is how many completions a user needs before the
+	// insights name a best and worst day; with fewer, one busy week decides.
+	minWeekdayCompletions = 7
+	// maxSnoozedListed caps how many put-off reminders the insights name.
+	maxSnoozedListed = 3
+)
+
+// Insights describes how a user deals with their reminders: how quickly they
+// finish them, on which days, and which ones they keep putting off. Habits,
+// countdowns, and birthdays are left out since they never get done for good.
+type Insights struct {
+	Open int `json:"open"`
+	// Completed counts reminders finished within the last 90 days.
+	Completed int `json:"completed"`
+	// MedianHoursToComplete is the median time from adding a reminder to
+	// finishing it, for those finished within the last 90 days.
+	MedianHoursToComplete *float64       `json:"median_hours_to_complete,omitempty"`
+	CompletedByWeekday    map[string]int `json:"completed_by_weekday"`
+	// BestDay and WorstDay are the weekdays with the most and fewest
+	// completions, once there are enough to tell.
+	BestDay     string            `json:"best_day,omitempty"`
+	WorstDay    string            `json:"worst_day,omitempty"`
+	MostSnoozed []SnoozedReminder `json:"most_snoozed"`
+	// Narrative is a few sentences from OpenAI reading the figures, when
+	// asked for and available.
+	Narrative string `json:"narrative,omitempty"`
+}
+
+// SnoozedReminder is an open reminder whose due date was pushed back.
+type SnoozedReminder struct {
+	Code     string `json:"code"`
+	Summary  string `json:"summary"`
+	Snoozes  int    `json:"snoozes"`
+	DaysOpen int    `json:"days_open"`
+}
+
+// insights works out userID's Insights as of now.
+func (b *Bot) insights(ctx context.Context, userID string, now time.Time) (Insights, error) {
+	var reminders []model.Reminder
+	err := b.db.WithContext(ctx).
+		Where("user_id = ? AND (completed_at IS NULL OR completed_at > ?)", userID, now.Add(-insightsWindow)).
+		Find(&reminders).Error
+	if err != nil {
+		return Insights{}, err
+	}
+
+	result := Insights{CompletedByWeekday: make(map[string]int, 7), MostSnoozed: []SnoozedReminder{}}
+	var (
+		hours   []float64
+		snoozed []model.Reminder
+	)
+	for _, r := range reminders {
+		if r.Recurring() {
+			continue
+		}
+		if r.CompletedAt == nil {
+			result.Open++
+			if r.Snoozes > 0 {
+				snoozed = append(snoozed, r)
+			}
+			continue
+		}
+		result.Completed++
+		result.CompletedByWeekday[r.CompletedAt.In(b.cfg.LocalTimezone).Weekday().String()]++
+		hours = append(hours, max(r.CompletedAt.Sub(r.CreatedAt).Hours(), 0))
+	}
+
+	if len(hours) > 0 {
+		slices.Sort(hours)
+		median := hours[len(hours)/2]
+		if len(hours)%2 == 0 {
+			median = (hours[len(hours)/2-1] + median) / 2
+		}
+		median = math.Round(median*10) / 10
+		result.MedianHoursToComplete = &median
+	}
+
+	if result.Completed >= minWeekdayCompletions {
+		best, worst := -1, -1
+		for _, day := range weekdaysFromMonday {
+			count := result.CompletedByWeekday[day.String()]
+			if best < 0 || count > result.CompletedByWeekday[result.BestDay] {
+				best, result.BestDay = count, day.String()
+			}
+			if worst < 0 || count < result.CompletedByWeekday[result.WorstDay] {
+				worst, result.WorstDay = count, day.String()
+			}
+		}
+		if best == worst {
+			result.BestDay, result.WorstDay = "", ""
+		}
+	}
+
+	slices.SortFunc(snoozed, func(x, y model.Reminder) int {
+		if x.Snoozes != y.Snoozes {
+			return y.Snoozes - x.Snoozes
+		}
+		return x.Code - y.Code
+	})
+	for _, r := range snoozed[:min(len(snoozed), maxSnoozedListed)] {
+		result.MostSnoozed = append(result.MostSnoozed, SnoozedReminder{
+			Code:     r.ShortID(),
+			Summary:  fallback(r.Summary, r.Content),
+			Snoozes:  r.Snoozes,
+			DaysOpen: int(now.Sub(r.CreatedAt).Hours() / 24),
+		})
+	}
+	return result, nil
+}
+
+// weekdaysFromMonday lists the weekdays in the order the insights break ties.
+var weekdaysFromMonday = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// describeInsights renders insights as chat lines, one fact per line, which
+// also serve as the facts OpenAI writes its narrative from.
+func describeInsights(in Insights) []string {
+	lines := []string{fmt.Sprintf("Reminders done in the last 90 days: %d. Still open: %d.", in.Completed, in.Open)}
+	if in.MedianHoursToComplete != nil {
+		lines = append(lines, fmt.Sprintf("You usually finish a reminder %s after adding it.", describeHours(*in.MedianHoursToComplete)))
+	}
+	if in.BestDay != "" {
+		lines = append(lines, fmt.Sprintf("You get the most done on %ss (%d) and the least on %ss (%d).",
+			in.BestDay, in.CompletedByWeekday[in.BestDay], in.WorstDay, in.CompletedByWeekday[in.WorstDay]))
+	}
+	for _, r := range in.MostSnoozed {
+		lines = append(lines, fmt.Sprintf("Put off %s: %s %s (open %d days).", plural(r.Snoozes, "time"), r.Code, truncate(r.Summary, 60), r.DaysOpen))
+	}
+	return lines
+}
+
+// describeHours turns a duration in hours into rough words, e.g. "2 days".
+func describeHours(hours float64) string {
+	switch {
+	case hours < 1:
+		return "within the hour"
+	case hours < 48:
+		return plural(int(math.Round(hours)), "hour")
+	default:
+		return plural(int(math.Round(hours/24)), "day")
+	}
+}
+
+// plural formats n with noun, adding an s unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// narrateInsights fills in in.Narrative from OpenAI when userID may use it.
+// Failures leave it empty.
+func (b *Bot) narrateInsights(ctx context.Context, userID string, in *Insights) {
+	if in.Completed == 0 && in.Open == 0 {
+		return
+	}
+	if !b.useOpenAI(ctx, userID) {
+		return
+	}
+	narrative, err := b.openAI.NarrateInsights(ctx, describeInsights(*in))
+	if err != nil {
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("openai insights error: %v", err)
+		}
+		return
+	}
+	in.Narrative = narrative
+}
+
+// handleInsightsCommand replies to "stats" with the user's insights. It
+// reports false when the message is not an insights command.
+func (b *Bot) handleInsightsCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	switch strings.TrimSuffix(strings.TrimSpace(lowerBody), ".") {
+	case "stats", "my stats", "show my stats", "insights", "my insights":
+	default:
+		return "", false
+	}
+
+	in, err := b.insights(ctx, userID, time.Now())
+	if err != nil {
+		b.logger.Printf("insights for %s: %v", userID, err)
+		return "I couldn't work out your stats right now. Please try again later.", true
+	}
+	if in.Completed == 0 && in.Open == 0 {
+		return "No stats yet. Add a few reminders and mark them done, and I'll show how you're getting on.", true
+	}
+	b.narrateInsights(ctx, userID, &in)
+	msg := strings.Join(describeInsights(in), "\n")
+	if in.Narrative != "" {
+		msg += "\n\n" + in.Narrative
+	}
+	return msg, true
+}
+
+// apiInsights returns the signed-in user's insights, with ?narrative=true
+// adding OpenAI's reading of them.
+func (b *Bot) apiInsights(w http.ResponseWriter, r *http.Request) {
+	userID := sessionUserID(r.Context())
+	in, err := b.insights(r.Context(), userID, time.Now())
+	if err != nil {
+		b.writeAdminError(w, err)
+		return
+	}
+	if r.URL.Query().Get("narrative") == "true" {
+		b.narrateInsights(r.Context(), userID, &in)
+	}
+	writeJSON(w, http.StatusOK, in)
+}
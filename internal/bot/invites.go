@@ -0,0 +1,174 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+const (
+	// inviteLinkTTL is how long the link to an .ics file works. Twilio
+	// fetches it as soon as the message goes out.
+	inviteLinkTTL = 24 * time.Hour
+	// inviteEventLength is how long a timed reminder lasts on the calendar.
+	inviteEventLength = 30 * time.Minute
+)
+
+var inviteReminderPattern = regexp.MustCompile(`(?i)^(?:send\s+(?:me\s+)?(?:an?\s+)?(?:calendar\s+)?invite\s+for\s+(?:reminder\s+)?(r?\d+)|add\s+(?:reminder\s+)?(r?\d+)\s+to\s+my\s+calendar)[.!]*$`)
+
+// handleInviteCommand turns calendar invites on and off, and sends one for a
+// reminder on "send invite for R3" or "add R3 to my calendar". It reports
+// false for other messages.
+func (b *Bot) handleInviteCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!") {
+	case "calendar invites on", "turn on calendar invites", "send me calendar invites":
+		return b.setCalendarInvites(userID, true), true
+	case "calendar invites off", "turn off calendar invites", "stop calendar invites", "stop sending calendar invites":
+		return b.setCalendarInvites(userID, false), true
+	}
+	m := inviteReminderPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if m == nil {
+		return "", false
+	}
+	r, err := b.reminderBySelector(ctx, userID, fallback(m[1], m[2]))
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("invite command: %v", err)
+		}
+		return err.Error(), true
+	}
+	if r.DueAt == nil {
+		return fmt.Sprintf("%s has no due date, so there's nothing to put on a calendar.", r.ShortID()), true
+	}
+	if err := b.sendCalendarInvite(ctx, *r); err != nil {
+		b.logger.Printf("send invite for %s: %v", r.ShortID(), err)
+		return "I couldn't send a calendar invite on this server.", true
+	}
+	return fmt.Sprintf("Sent an invite for %s. Tap it to add it to your calendar.", r.ShortID()), true
+}
+
+// setCalendarInvites turns calendar invites for new reminders on or off.
+func (b *Bot) setCalendarInvites(userID string, on bool) string {
+	if on && b.blobs == nil {
+		return "I can't send calendar invites on this server."
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.CalendarInvites = on
+	}); err != nil {
+		b.logger.Printf("calendar invites for %s: %v", userID, err)
+		return "I couldn't update that setting. Please try again later."
+	}
+	if !on {
+		return "Okay, no more calendar invites."
+	}
+	return "When you save a reminder with a due date, I'll send an invite you can tap to add it to your calendar. Say 'calendar invites off' to stop."
+}
+
+// inviteNewReminder sends an invite for a reminder the user just saved with
+// a due date, if they asked for invites.
+func (b *Bot) inviteNewReminder(r model.Reminder) {
+	if r.DueAt == nil || r.Kind != model.KindReminder || b.blobs == nil || !b.preferences(r.UserID).CalendarInvites {
+		return
+	}
+	if err := b.sendCalendarInvite(context.Background(), r); err != nil {
+		b.logger.Printf("send invite for %s: %v", r.ShortID(), err)
+	}
+}
+
+// sendCalendarInvite stores an .ics file for r and sends it to the user as
+// an attachment, or as a link on channels that can't attach files.
+func (b *Bot) sendCalendarInvite(ctx context.Context, r model.Reminder) error {
+	if b.blobs == nil {
+		return fmt.Errorf("no blob store")
+	}
+	key := inviteKey(r)
+	if err := b.blobs.Put(ctx, key, calendarInvite(r, b.location(b.preferences(r.UserID)), time.Now()), "text/calendar"); err != nil {
+		return err
+	}
+	link, err := b.blobs.SignedURL(ctx, key, inviteLinkTTL)
+	if err != nil {
+		return err
+	}
+	name, to := chatChannel(r.UserID)
+	msg := channel.Message{To: to, Body: b.translate(r.UserID, fmt.Sprintf("Add %s to your calendar:", fallback(r.Summary, r.Content)))}
+	if name == channel.WhatsApp {
+		msg.MediaURL = link
+	} else {
+		msg.Body += " " + link
+	}
+	return b.send(ctx, name, msg)
+}
+
+// inviteKey names the .ics file for r. It is the same each time, so a new
+// invite replaces the old one.
+func inviteKey(r model.Reminder) string {
+	return fmt.Sprintf("invites/%s/%s.ics", hashToken(r.UserID)[:16], r.ShortID())
+}
+
+// calendarInvite renders r as an iCalendar (RFC 5545) event. A due date at
+// midnight is an all-day event. Timed events use floating local times in
+// loc, so a phone shows them at the same clock time the reminder is due.
+func calendarInvite(r model.Reminder, loc *time.Location, now time.Time) []byte {
+	due := r.DueAt.In(loc)
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//myMemo//Reminders//EN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		fmt.Sprintf("UID:reminder-%d@mymemo", r.ID),
+		"DTSTAMP:" + now.UTC().Format("20060102T150405Z"),
+	}
+	if due.Hour() == 0 && due.Minute() == 0 {
+		lines = append(lines,
+			"DTSTART;VALUE=DATE:"+due.Format("20060102"),
+			"DTEND;VALUE=DATE:"+due.AddDate(0, 0, 1).Format("20060102"))
+	} else {
+		lines = append(lines,
+			"DTSTART:"+due.Format("20060102T150405"),
+			"DTEND:"+due.Add(inviteEventLength).Format("20060102T150405"))
+	}
+	if r.RRule != "" {
+		lines = append(lines, "RRULE:"+r.RRule)
+	}
+	lines = append(lines, "SUMMARY:"+icsText(fallback(r.Summary, r.Content)))
+	if r.Summary != "" && r.Summary != r.Content {
+		lines = append(lines, "DESCRIPTION:"+icsText(r.Content))
+	}
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(foldICSLine(line))
+		sb.WriteString("\r\n")
+	}
+	return []byte(sb.String())
+}
+
+// icsText escapes text for an iCalendar property value.
+func icsText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// foldICSLine splits line into pieces of at most 75 bytes, continuing each
+// on a new line that starts with a space, without breaking a UTF-8
+// character.
+func foldICSLine(line string) string {
+	var sb strings.Builder
+	width := 0
+	for _, c := range line {
+		n := len(string(c))
+		if width+n > 75 {
+			sb.WriteString("\r\n ")
+			width = 1
+		}
+		sb.WriteRune(c)
+		width += n
+	}
+	return sb.String()
+}
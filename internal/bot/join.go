@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var joinPage = template.Must(template.ParseFS(dashboardFiles, "web/join.html"))
+
+var connectRequestPattern = regexp.MustCompile(`^how (?:do i|can i|to|do you) (?:connect|join|sign up|set (?:this|you|it) up|start)\b`)
+
+// isConnectRequest reports whether a message asks how to start using the
+// bot, such as "how do I connect?".
+func isConnectRequest(lowerBody string) bool {
+	return connectRequestPattern.MatchString(strings.TrimSpace(lowerBody))
+}
+
+// botNumber returns the default WhatsApp number users write to.
+func (b *Bot) botNumber() string {
+	return sanitizeWhatsAppNumber(strings.TrimSpace(b.cfg.TwilioWhatsAppNumber))
+}
+
+// sandboxKeyword returns the Twilio sandbox code word, such as
+// "bright-tiger", or "" when the bot uses an approved sender.
+func (b *Bot) sandboxKeyword() string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(b.cfg.TwilioSandboxKeyword)), "join "))
+}
+
+// joinLink opens a WhatsApp chat with the bot, with the sandbox join message
+// filled in when there is one.
+func (b *Bot) joinLink() string {
+	link := "https://wa.me/" + strings.TrimPrefix(b.botNumber(), "+")
+	if keyword := b.sandboxKeyword(); keyword != "" {
+		link += "?text=" + url.PathEscape("join "+keyword)
+	}
+	return link
+}
+
+// connectInstructions explains how to start chatting with the bot from a
+// phone.
+func (b *Bot) connectInstructions() string {
+	var msg string
+	if keyword := b.sandboxKeyword(); keyword != "" {
+		msg = fmt.Sprintf("To connect a phone, send \"join %s\" to %s on WhatsApp, or open %s and press send. "+
+			"The sandbox disconnects a phone after 3 days without a message, so send it again if my reminders stop.",
+			keyword, b.botNumber(), b.joinLink())
+	} else {
+		msg = fmt.Sprintf("To connect a phone, message me on WhatsApp at %s, or open %s.", b.botNumber(), b.joinLink())
+	}
+	if b.cfg.PublicBaseURL != "" {
+		msg += fmt.Sprintf("\nThere's a QR code to scan at %s/join.", b.cfg.PublicBaseURL)
+	}
+	return msg
+}
+
+// JoinHandler serves the onboarding page at "/join" and its QR code at
+// "/join/qr.png".
+func (b *Bot) JoinHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/join":
+			var page bytes.Buffer
+			err := joinPage.Execute(&page, map[string]string{
+				"Number":  b.botNumber(),
+				"Keyword": b.sandboxKeyword(),
+				"Link":    b.joinLink(),
+			})
+			if err != nil {
+				b.logger.Printf("join page: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("X-Frame-Options", "DENY")
+			_, _ = w.Write(page.Bytes())
+		case "/join/qr.png":
+			png, err := qrcode.Encode(b.joinLink(), qrcode.Medium, 256)
+			if err != nil {
+				b.logger.Printf("join qr code: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(png)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// markUnjoined records that userID has left the Twilio sandbox, so their
+// scheduled sends stop until they write again.
+func (b *Bot) markUnjoined(userID string) {
+	if b.preferences(userID).UnjoinedAt != nil {
+		return
+	}
+	now := time.Now()
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.UnjoinedAt = &now
+	}); err != nil {
+		b.logger.Printf("sandbox: mark %s unjoined: %v", userID, err)
+		return
+	}
+	b.logger.Printf("sandbox: %s is no longer joined; holding their reminders", userID)
+}
+
+// welcomeBack clears a user's unjoined mark now that they are writing again,
+// returning a note for the reply when it was set.
+func (b *Bot) welcomeBack(userID string) string {
+	if b.preferences(userID).UnjoinedAt == nil {
+		return ""
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.UnjoinedAt = nil
+	}); err != nil {
+		b.logger.Printf("sandbox: clear unjoined mark for %s: %v", userID, err)
+		return ""
+	}
+	return "Welcome back! Some reminders couldn't reach you while you were disconnected. Say 'list reminders' to see them all."
+}
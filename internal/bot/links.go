@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	linkOnlyPattern   = regexp.MustCompile(`(?i)^https?://\S+$`)
+	deleteLinkPattern = regexp.MustCompile(`(?i)^delete\s+links?\s+(.+)$`)
+)
+
+// handleLinkCommand saves bare URLs and manages the read-later list. It
+// reports false when the message is not a link command.
+func (b *Bot) handleLinkCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	switch {
+	case linkOnlyPattern.MatchString(body):
+		msg, err = b.saveLink(ctx, userID, body)
+	case lowerBody == "links" || lowerBody == "my links" || lowerBody == "show my links" || lowerBody == "list my links" || lowerBody == "show links":
+		msg, err = b.listLinks(userID)
+	case deleteLinkPattern.MatchString(body):
+		msg, err = b.deleteLinks(userID, deleteLinkPattern.FindStringSubmatch(body)[1])
+	case strings.HasPrefix(lowerBody, "send me one saved article") || strings.HasPrefix(lowerBody, "send me an article every morning"):
+		msg, err = b.setDailyArticle(userID, true)
+	case lowerBody == "stop sending articles" || lowerBody == "stop daily articles":
+		msg, err = b.setDailyArticle(userID, false)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("link command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// saveLink fetches and summarises a URL and stores it as a link memo.
+func (b *Bot) saveLink(ctx context.Context, userID, url string) (string, error) {
+	memo := &model.Memo{
+		UserID: userID,
+		Kind:   model.MemoKindLink,
+		Title:  url,
+		URL:    url,
+	}
+
+	page, err := b.links.Fetch(ctx, url)
+	if err != nil {
+		b.logger.Printf("fetch link: %v", err)
+		memo.Content = "I couldn't open this page when you saved it."
+	} else {
+		if page.Title != "" {
+			memo.Title = page.Title
+		}
+		memo.URL = page.URL
+		summary := truncate(page.Text, 200)
+		if b.useOpenAI(ctx, userID) {
+			if s, err := b.openAI.SummarizeLink(ctx, page.Title, page.Text); err != nil {
+				b.logger.Printf("openai link summary error: %v", err)
+			} else {
+				summary = s
+			}
+		}
+		memo.Content = summary
+	}
+
+	if err := b.db.Create(memo).Error; err != nil {
+		return "", fmt.Errorf("I couldn't save that link. Please try again later")
+	}
+	return fmt.Sprintf("Saved to your reading list: %s\n%s", memo.Title, memo.Content), nil
+}
+
+// listLinks renders the user's saved links, newest first.
+func (b *Bot) listLinks(userID string) (string, error) {
+	links, err := b.memos(userID, model.MemoKindLink, "")
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your links right now. Please try again later")
+	}
+	if len(links) == 0 {
+		return "", userError{"Your reading list is empty. Send me a link to save it for later."}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your reading list:\n")
+	for i, l := range links {
+		read := ""
+		if l.SentAt != nil {
+			read = " (sent)"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s%s\n   %s\n", i+1, l.Title, read, l.URL))
+	}
+	return sb.String(), nil
+}
+
+// deleteLinks removes links by their position in the reading list.
+func (b *Bot) deleteLinks(userID, selector string) (string, error) {
+	indices := parseIndices(selector)
+	if len(indices) == 0 {
+		return "", userError{"Tell me which link to delete by number, e.g. 'delete link 2'."}
+	}
+	links, err := b.memos(userID, model.MemoKindLink, "")
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your links right now. Please try again later")
+	}
+
+	ids := make([]uint, 0, len(indices))
+	for _, idx := range indices {
+		if idx < 1 || idx > len(links) {
+			return "", userError{fmt.Sprintf("Link %d doesn't exist. Choose between 1 and %d.", idx, len(links))}
+		}
+		ids = append(ids, links[idx-1].ID)
+	}
+	if err := b.db.Where("user_id = ? AND id IN ?", userID, ids).Delete(&model.Memo{}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't delete those links. Please try again later")
+	}
+	return fmt.Sprintf("Deleted link(s): %s.", formatIndices(indices)), nil
+}
+
+// setDailyArticle toggles the morning read-later message.
+func (b *Bot) setDailyArticle(userID string, enabled bool) (string, error) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.DailyArticle = enabled
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't update that setting. Please try again later")
+	}
+	if enabled {
+		return "Every morning I'll send you one article from your reading list. Say 'stop sending articles' to turn it off.", nil
+	}
+	return "Okay, no more morning articles.", nil
+}
+
+// sendDailyArticles sends each subscribed user the oldest link they haven't been sent yet.
+func (b *Bot) sendDailyArticles() {
+	var users []string
+	if err := b.db.Model(&model.UserPreference{}).Where("daily_article = ? AND paused = ? AND opted_out_at IS NULL", true, false).Pluck("user_id", &users).Error; err != nil {
+		b.logger.Printf("scheduler: fetch article subscribers: %v", err)
+		return
+	}
+
+	for _, userID := range users {
+		var link model.Memo
+		err := b.db.Where("user_id = ? AND kind = ? AND sent_at IS NULL", userID, model.MemoKindLink).
+			Order("created_at ASC, id ASC").
+			First(&link).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			b.logger.Printf("scheduler: article for %s: %v", userID, err)
+			continue
+		}
+
+		message := fmt.Sprintf("From your reading list: %s\n%s\n%s", link.Title, link.Content, link.URL)
+		if err := b.notify(context.Background(), userID, message); err != nil {
+			b.logger.Printf("scheduler: send article: %v", err)
+			continue
+		}
+		if err := b.db.Model(&link).Update("sent_at", time.Now()).Error; err != nil {
+			b.logger.Printf("scheduler: mark article sent: %v", err)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	addListItemPattern    = regexp.MustCompile(`(?i)^add\s+(.+?)\s+to\s+(?:my\s+|the\s+)?(.+?)\s+list$`)
+	removeListItemPattern = regexp.MustCompile(`(?i)^(?:remove|delete)\s+(.+?)\s+from\s+(?:my\s+|the\s+)?(.+?)\s+list$`)
+	showListPattern       = regexp.MustCompile(`(?i)^(?:show|view|list)\s+(?:my\s+|the\s+)?(.+?)\s+list$`)
+	deleteListPattern     = regexp.MustCompile(`(?i)^delete\s+(?:my\s+|the\s+)?(.+?)\s+list$`)
+	clearListPattern      = regexp.MustCompile(`(?i)^clear\s+(?:my\s+|the\s+)?(.+?)\s+list$`)
+	listFormatPattern     = regexp.MustCompile(`(?i)^set\s+(?:my\s+|the\s+)?(.+?)\s+list\s+format\s+(?:to\s+)?(bullets|numbered|checklist)$`)
+)
+
+// handleListCommand processes named list commands. It reports false when the
+// message is not a list command.
+func (b *Bot) handleListCommand(userID, body, lowerBody string) (string, bool) {
+	body = strings.TrimSuffix(strings.TrimSpace(body), ".")
+
+	var (
+		msg string
+		err error
+	)
+	switch {
+	case lowerBody == "lists" || lowerBody == "my lists" || lowerBody == "show my lists" || lowerBody == "show lists":
+		msg = b.listLists(userID)
+	case shareListPattern.MatchString(body):
+		m := shareListPattern.FindStringSubmatch(body)
+		msg, err = b.shareList(userID, m[1], m[2], strings.ToLower(m[3]))
+	case unshareListPattern.MatchString(body):
+		m := unshareListPattern.FindStringSubmatch(body)
+		msg, err = b.unshareList(userID, m[1], m[2])
+	case leaveListPattern.MatchString(body):
+		m := leaveListPattern.FindStringSubmatch(body)
+		msg, err = b.leaveList(userID, m[1])
+	case listFormatPattern.MatchString(body):
+		m := listFormatPattern.FindStringSubmatch(body)
+		msg, err = b.setListFormat(userID, m[1], strings.ToLower(m[2]))
+	case addListItemPattern.MatchString(body):
+		m := addListItemPattern.FindStringSubmatch(body)
+		msg, err = b.addListItem(userID, m[2], m[1])
+	case removeListItemPattern.MatchString(body):
+		m := removeListItemPattern.FindStringSubmatch(body)
+		msg, err = b.removeListItem(userID, m[2], m[1])
+	case clearListPattern.MatchString(body):
+		m := clearListPattern.FindStringSubmatch(body)
+		msg, err = b.clearList(userID, m[1])
+	case deleteListPattern.MatchString(body):
+		m := deleteListPattern.FindStringSubmatch(body)
+		msg, err = b.deleteList(userID, m[1])
+	case showListPattern.MatchString(body) && !isReminderListName(showListPattern.FindStringSubmatch(body)[1]):
+		m := showListPattern.FindStringSubmatch(body)
+		msg, err = b.showList(userID, m[1])
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("list command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// listAccess is what a command needs to do with a list.
+type listAccess int
+
+const (
+	listView listAccess = iota
+	listEdit
+	listOwn
+)
+
+// lookupList loads the list called name that userID can see: their own, or
+// else one shared with them. role is "owner" for their own list. found is
+// false when there is no such list.
+func (b *Bot) lookupList(userID, name string) (list model.List, role string, found bool, err error) {
+	name = normalizeListName(name)
+	err = b.db.Where("user_id = ? AND name = ?", userID, name).First(&list).Error
+	if err == nil {
+		return list, "owner", true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return list, "", false, err
+	}
+
+	var members []model.ListMember
+	err = b.db.Joins("JOIN lists ON lists.id = list_members.list_id").
+		Where("list_members.user_id = ? AND lists.name = ?", userID, name).
+		Order("list_members.list_id ASC").
+		Limit(1).
+		Find(&members).Error
+	if err != nil || len(members) == 0 {
+		return list, "", false, err
+	}
+	if err := b.db.First(&list, members[0].ListID).Error; err != nil {
+		return list, "", false, err
+	}
+	return list, members[0].Role, true, nil
+}
+
+// findList loads a list by name and checks that userID may use it as need
+// says. Every list command goes through here, so viewers can't change a
+// shared list and only owners can delete or share one.
+func (b *Bot) findList(userID, name string, need listAccess) (model.List, error) {
+	list, role, found, err := b.lookupList(userID, name)
+	if err != nil {
+		return list, fmt.Errorf("I couldn't load that list. Please try again later")
+	}
+	if !found {
+		return list, userError{fmt.Sprintf("You don't have a %s list.", normalizeListName(name))}
+	}
+	if err := checkListAccess(list, role, need); err != nil {
+		return list, err
+	}
+	return list, nil
+}
+
+func checkListAccess(list model.List, role string, need listAccess) error {
+	switch {
+	case role == "owner":
+		return nil
+	case need == listOwn:
+		return userError{fmt.Sprintf("Only %s can do that to the %s list.", list.UserID, list.Name)}
+	case need == listEdit && role != model.ListRoleEditor:
+		return userError{fmt.Sprintf("%s shared the %s list with you to view only.", list.UserID, list.Name)}
+	}
+	return nil
+}
+
+// addListItem appends an item to a list, creating the list on first use.
+func (b *Bot) addListItem(userID, name, content string) (string, error) {
+	name = normalizeListName(name)
+	list, role, found, err := b.lookupList(userID, name)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't update your %s list. Please try again later", name)
+	}
+	if !found {
+		list = model.List{UserID: userID, Name: name}
+		if err := b.db.Create(&list).Error; err != nil {
+			return "", fmt.Errorf("I couldn't update your %s list. Please try again later", name)
+		}
+	} else if err := checkListAccess(list, role, listEdit); err != nil {
+		return "", err
+	}
+	item := model.ListItem{ListID: list.ID, Content: strings.TrimSpace(content)}
+	if err := b.db.Create(&item).Error; err != nil {
+		return "", fmt.Errorf("I couldn't update your %s list. Please try again later", name)
+	}
+	return fmt.Sprintf("Added '%s' to your %s list.", item.Content, name), nil
+}
+
+// removeListItem deletes items from a list by position or matching text.
+func (b *Bot) removeListItem(userID, name, selector string) (string, error) {
+	list, err := b.findList(userID, name, listEdit)
+	if err != nil {
+		return "", err
+	}
+
+	query := b.db.Where("list_id = ?", list.ID)
+	if indices := parseIndices(selector); len(indices) > 0 {
+		var items []model.ListItem
+		if err := b.db.Where("list_id = ?", list.ID).Order("created_at ASC, id ASC").Find(&items).Error; err != nil {
+			return "", fmt.Errorf("I couldn't update your %s list. Please try again later", list.Name)
+		}
+		ids := make([]uint, 0, len(indices))
+		for _, idx := range indices {
+			if idx < 1 || idx > len(items) {
+				return "", userError{fmt.Sprintf("Item %d doesn't exist. Choose between 1 and %d.", idx, len(items))}
+			}
+			ids = append(ids, items[idx-1].ID)
+		}
+		query = query.Where("id IN ?", ids)
+	} else {
+		query = query.Where("LOWER(content) LIKE ?", "%"+strings.ToLower(strings.TrimSpace(selector))+"%")
+	}
+
+	res := query.Delete(&model.ListItem{})
+	if res.Error != nil {
+		return "", fmt.Errorf("I couldn't update your %s list. Please try again later", list.Name)
+	}
+	if res.RowsAffected == 0 {
+		return "", userError{fmt.Sprintf("I couldn't find '%s' on your %s list.", selector, list.Name)}
+	}
+	return fmt.Sprintf("Removed %d item(s) from your %s list.", res.RowsAffected, list.Name), nil
+}
+
+// showList renders a list using its display format.
+func (b *Bot) showList(userID, name string) (string, error) {
+	list, err := b.findList(userID, name, listView)
+	if err != nil {
+		return "", err
+	}
+	var items []model.ListItem
+	if err := b.db.Where("list_id = ?", list.ID).Order("created_at ASC, id ASC").Find(&items).Error; err != nil {
+		return "", fmt.Errorf("I couldn't load your %s list. Please try again later", list.Name)
+	}
+	if note := b.sharingNote(userID, list); note != "" {
+		return strings.TrimRight(formatList(list, items), "\n") + "\n" + note, nil
+	}
+	return formatList(list, items), nil
+}
+
+// clearList removes every item from a list but keeps the list itself.
+func (b *Bot) clearList(userID, name string) (string, error) {
+	list, err := b.findList(userID, name, listEdit)
+	if err != nil {
+		return "", err
+	}
+	if err := b.db.Where("list_id = ?", list.ID).Delete(&model.ListItem{}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't clear your %s list. Please try again later", list.Name)
+	}
+	return fmt.Sprintf("Your %s list is now empty.", list.Name), nil
+}
+
+// deleteList removes a list and all of its items.
+func (b *Bot) deleteList(userID, name string) (string, error) {
+	list, err := b.findList(userID, name, listOwn)
+	if err != nil {
+		return "", err
+	}
+	err = b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", list.ID).Delete(&model.ListItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("list_id = ?", list.ID).Delete(&model.ListMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&list).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("I couldn't delete your %s list. Please try again later", list.Name)
+	}
+	return fmt.Sprintf("Deleted your %s list.", list.Name), nil
+}
+
+// setListFormat changes how a list is rendered.
+func (b *Bot) setListFormat(userID, name, format string) (string, error) {
+	list, err := b.findList(userID, name, listOwn)
+	if err != nil {
+		return "", err
+	}
+	if err := b.db.Model(&list).Update("format", format).Error; err != nil {
+		return "", fmt.Errorf("I couldn't update your %s list. Please try again later", list.Name)
+	}
+	return fmt.Sprintf("Your %s list will now show as %s.", list.Name, format), nil
+}
+
+// listLists returns an overview of the user's lists, and those shared with
+// them, with item counts.
+func (b *Bot) listLists(userID string) string {
+	var lists []model.List
+	shared := b.db.Model(&model.ListMember{}).Select("list_id").Where("user_id = ?", userID)
+	if err := b.db.Where("user_id = ? OR id IN (?)", userID, shared).Preload("Items").Order("name ASC").Find(&lists).Error; err != nil {
+		b.logger.Printf("list lists error: %v", err)
+		return "I couldn't load your lists right now. Please try again later."
+	}
+	if len(lists) == 0 {
+		return "You have no lists yet. Try 'add War and Peace to my books list'."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your lists:\n")
+	for _, l := range lists {
+		if l.UserID != userID {
+			sb.WriteString(fmt.Sprintf("- %s (%d item(s), shared by %s)\n", l.Name, len(l.Items), l.UserID))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("- %s (%d item(s))\n", l.Name, len(l.Items)))
+	}
+	return sb.String()
+}
+
+func formatList(list model.List, items []model.ListItem) string {
+	if len(items) == 0 {
+		return fmt.Sprintf("Your %s list is empty.", list.Name)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Your %s list:\n", list.Name))
+	for i, item := range items {
+		switch list.Format {
+		case model.ListFormatNumbered:
+			sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, item.Content))
+		case model.ListFormatChecklist:
+			sb.WriteString(fmt.Sprintf("☐ %s\n", item.Content))
+		default:
+			sb.WriteString(fmt.Sprintf("• %s\n", item.Content))
+		}
+	}
+	return sb.String()
+}
+
+// isReminderListName reports whether name refers to the reminder list rather than a named list.
+func isReminderListName(name string) bool {
+	switch normalizeListName(name) {
+	case "reminder", "reminders", "my reminders":
+		return true
+	}
+	return false
+}
+
+func normalizeListName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
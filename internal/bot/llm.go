@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/pathakanu/myMemo/internal/anthropic"
+	"github.com/pathakanu/myMemo/internal/config"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+// languageModel classifies incoming messages and summarises reminders.
+// OpenAI provides it unless LLM_PROVIDER picks another; the other model
+// features, such as categories and moderation, always use OpenAI.
+type languageModel interface {
+	ClassifyIntent(ctx context.Context, content string) (myopenai.Classification, error)
+	SummarizeReminder(ctx context.Context, content, language string) (string, error)
+	SummarizeReminders(ctx context.Context, contents []string, language string) ([]string, error)
+}
+
+// embedder turns text into vectors for semantic search, duplicate
+// detection, and clustering. OpenAI provides it.
+type embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// newLanguageModel returns the language model cfg asks for.
+func newLanguageModel(cfg *config.Config, openAI *myopenai.Client) languageModel {
+	if cfg.LLMProvider == config.ProviderAnthropic {
+		return anthropic.New(cfg.AnthropicAPIKey, cfg.AnthropicModel)
+	}
+	if openAI == nil {
+		return nil
+	}
+	return openAI
+}
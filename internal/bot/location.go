@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+const defaultPlacePriority = 3
+
+// sharedLocation is a WhatsApp location pin waiting to be attached to a
+// reminder.
+type sharedLocation struct {
+	Latitude  float64
+	Longitude float64
+	// Label is the place name or address WhatsApp sent, if any.
+	Label string
+}
+
+var (
+	placeReminderPattern = regexp.MustCompile(`(?i)^remind me (?:about|of) (?:this|that) (?:place|location|spot)(?:\s*[:,-]\s*(.+?))?[.!]*$`)
+	attachPlacePattern   = regexp.MustCompile(`(?i)^(?:attach|add|link) (?:this|that|the) (?:place|location|pin) to (r\d+)[.!]*$`)
+)
+
+// parseLocation reads the Latitude, Longitude, Label, and Address fields
+// Twilio posts when a WhatsApp user shares a location pin.
+func parseLocation(form map[string]string) (sharedLocation, bool) {
+	lat, latErr := strconv.ParseFloat(form["Latitude"], 64)
+	lng, lngErr := strconv.ParseFloat(form["Longitude"], 64)
+	if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return sharedLocation{}, false
+	}
+	return sharedLocation{Latitude: lat, Longitude: lng, Label: fallback(form["Label"], form["Address"])}, true
+}
+
+// receiveLocation remembers a shared pin until the user says what it is for,
+// and moves the user to the pin's timezone if it differs from theirs.
+func (b *Bot) receiveLocation(ctx context.Context, userID string, loc sharedLocation) string {
+	b.state.SetPendingLocation(userID, loc)
+	reply := "Got the location. "
+	if note := b.followPin(ctx, userID, loc); note != "" {
+		reply += note + " "
+	}
+	return reply + "Reply 'remind me about this place: buy bread' to save a reminder with it, or 'attach this place to R3' to add it to one you have."
+}
+
+// handleLocationCommand attaches the last shared pin to a new or existing
+// reminder. It reports false when the message is not about a shared place.
+func (b *Bot) handleLocationCommand(ctx context.Context, userID, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	create := placeReminderPattern.FindStringSubmatch(body)
+	attach := attachPlacePattern.FindStringSubmatch(body)
+	if create == nil && attach == nil {
+		return "", false
+	}
+	loc, ok := b.state.PopPendingLocation(userID)
+	if !ok {
+		return "Share a location pin first (📎 → Location), then tell me what to remind you about there.", true
+	}
+
+	if attach != nil {
+		found, err := b.remindersByCode(ctx, b.db.Where("user_id = ?", userID), parseReminderCodes(attach[1]))
+		if err != nil {
+			b.state.SetPendingLocation(userID, loc)
+			if !isUserError(err) {
+				b.logger.Printf("attach location: %v", err)
+			}
+			return err.Error(), true
+		}
+		r := found[0]
+		if err := b.db.WithContext(ctx).Model(&r).Updates(map[string]any{
+			"latitude":  loc.Latitude,
+			"longitude": loc.Longitude,
+			"place":     loc.Label,
+		}).Error; err != nil {
+			b.logger.Printf("attach location: %v", err)
+			return "I couldn't save the location. Please try again later.", true
+		}
+		return fmt.Sprintf("Added the location to %s %s. I'll include a map link when I remind you.", r.ShortID(), fallback(r.Summary, r.Content)), true
+	}
+
+	content := strings.TrimSpace(create[1])
+	if content == "" {
+		content = "Visit " + fallback(loc.Label, "this place")
+	}
+	lat, lng := loc.Latitude, loc.Longitude
+	reminder := &model.Reminder{
+		UserID:    userID,
+		Content:   content,
+		Priority:  defaultPlacePriority,
+		Summary:   b.summarizeReminderWithOpenAI(ctx, userID, content),
+		Latitude:  &lat,
+		Longitude: &lng,
+		Place:     loc.Label,
+	}
+	if err := b.insertReminder(ctx, reminder); err != nil {
+		b.logger.Printf("save reminder: %v", err)
+		return "I couldn't save the reminder. Please try again.", true
+	}
+	return fmt.Sprintf("Got it! I'll remind you: %s (priority %d), with a map link to the place.", reminder.Summary, reminder.Priority), true
+}
+
+// mapsLink returns a Google Maps link to the reminder's place, or "" when it
+// has none.
+func mapsLink(r model.Reminder) string {
+	if r.Latitude == nil || r.Longitude == nil {
+		return ""
+	}
+	return fmt.Sprintf("https://maps.google.com/?q=%.6f,%.6f", *r.Latitude, *r.Longitude)
+}
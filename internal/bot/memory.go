@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+const (
+	// maxRecentExchanges is how many exchanges per user are kept for
+	// resolving follow-ups.
+	maxRecentExchanges = 5
+	// recentExchangeTTL is how long an exchange stays relevant. A follow-up
+	// an hour later is more likely a new conversation.
+	recentExchangeTTL = 30 * time.Minute
+)
+
+// followUpPattern spots messages that lean on what was just said, such as
+// "actually make that priority 5" or "delete the second one".
+var followUpPattern = regexp.MustCompile(`(?i)\b(?:that|it|them|this one|the (?:first|second|third|fourth|fifth|last|other|same) one|actually|instead)\b`)
+
+// exchange is one message from a user and the bot's reply to it.
+type exchange struct {
+	Message string
+	Reply   string
+	At      time.Time
+}
+
+// exchangeLog keeps each user's last few exchanges in memory. Unlike the
+// conversation state it isn't cleared when a reply is handled.
+type exchangeLog struct {
+	mu     sync.Mutex
+	byUser map[string][]exchange
+}
+
+func newExchangeLog() *exchangeLog {
+	return &exchangeLog{byUser: make(map[string][]exchange)}
+}
+
+// Len returns how many users have recent exchanges held.
+func (l *exchangeLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.byUser)
+}
+
+// Add records an exchange, dropping the oldest beyond maxRecentExchanges.
+func (l *exchangeLog) Add(userID, message, reply string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	turns := append(l.byUser[userID], exchange{Message: message, Reply: reply, At: at})
+	if len(turns) > maxRecentExchanges {
+		turns = turns[len(turns)-maxRecentExchanges:]
+	}
+	l.byUser[userID] = turns
+}
+
+// Recent returns the user's exchanges from the last recentExchangeTTL,
+// oldest first.
+func (l *exchangeLog) Recent(userID string, now time.Time) []exchange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var recent []exchange
+	for _, e := range l.byUser[userID] {
+		if now.Sub(e.At) <= recentExchangeTTL {
+			recent = append(recent, e)
+		}
+	}
+	if len(recent) == 0 {
+		delete(l.byUser, userID)
+	}
+	return recent
+}
+
+type followUpKey struct{}
+
+// handleFollowUp asks the model to rewrite a message that refers back to the
+// recent exchanges into a standalone command, and handles that instead. It
+// reports false when there's nothing to refer back to, the message doesn't
+// look like a follow-up, or the model leaves it unchanged.
+func (b *Bot) handleFollowUp(ctx context.Context, userID, body string) (string, bool) {
+	if ctx.Value(followUpKey{}) != nil || !followUpPattern.MatchString(body) {
+		return "", false
+	}
+	recent := b.recent.Recent(userID, time.Now())
+	if len(recent) == 0 || b.openAI == nil || !b.useOpenAI(ctx, userID) {
+		return "", false
+	}
+
+	turns := make([]myopenai.Turn, len(recent))
+	for i, e := range recent {
+		turns[i] = myopenai.Turn{Message: e.Message, Reply: e.Reply}
+	}
+	rewritten, err := b.openAI.ResolveFollowUp(ctx, turns, body)
+	if err != nil {
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("resolve follow-up: %v", err)
+		}
+		return "", false
+	}
+	rewritten = strings.TrimSpace(rewritten)
+	if rewritten == "" || strings.EqualFold(rewritten, strings.TrimSpace(body)) {
+		return "", false
+	}
+	return b.reply(context.WithValue(ctx, followUpKey{}, true), userID, rewritten), true
+}
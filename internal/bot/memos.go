@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+var (
+	notePattern       = regexp.MustCompile(`(?is)^note\s*:\s*(.+)$`)
+	searchNotePattern = regexp.MustCompile(`(?i)^(?:search|find)\s+(?:my\s+)?notes?\s+(?:for\s+|about\s+)?(.+)$`)
+	deleteNotePattern = regexp.MustCompile(`(?i)^delete\s+notes?\s+(.+)$`)
+)
+
+// handleMemoCommand processes memo capture, listing, search, and deletion. It
+// reports false when the message is not a memo command.
+func (b *Bot) handleMemoCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	switch {
+	case notePattern.MatchString(body):
+		msg, err = b.saveMemo(ctx, userID, notePattern.FindStringSubmatch(body)[1])
+	case lowerBody == "notes" || lowerBody == "my notes" || lowerBody == "show my notes" || lowerBody == "list notes" || lowerBody == "list my notes":
+		msg, err = b.listMemos(userID, "")
+	case searchNotePattern.MatchString(body):
+		msg, err = b.listMemos(userID, searchNotePattern.FindStringSubmatch(body)[1])
+	case deleteNotePattern.MatchString(body):
+		msg, err = b.deleteMemos(userID, deleteNotePattern.FindStringSubmatch(body)[1])
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("memo command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// saveMemo stores a memo with an AI-generated title.
+func (b *Bot) saveMemo(ctx context.Context, userID, content string) (string, error) {
+	content = strings.TrimSpace(content)
+	memo := &model.Memo{
+		UserID:  userID,
+		Kind:    model.MemoKindNote,
+		Title:   b.titleMemoWithOpenAI(ctx, userID, content),
+		Content: content,
+	}
+	if err := b.db.Create(memo).Error; err != nil {
+		return "", fmt.Errorf("I couldn't save that note. Please try again later")
+	}
+	return fmt.Sprintf("Noted: %s", memo.Title), nil
+}
+
+// memos returns the user's memos of a kind, newest first, optionally filtered by a search term.
+func (b *Bot) memos(userID, kind, query string) ([]model.Memo, error) {
+	tx := b.db.Where("user_id = ? AND kind = ?", userID, kind)
+	if query = strings.ToLower(strings.TrimSpace(query)); query != "" {
+		like := "%" + query + "%"
+		tx = tx.Where("LOWER(title) LIKE ? OR LOWER(content) LIKE ?", like, like)
+	}
+	var memos []model.Memo
+	err := tx.Order("created_at DESC, id DESC").Find(&memos).Error
+	return memos, err
+}
+
+// listMemos renders the user's memos, optionally filtered by a search term.
+func (b *Bot) listMemos(userID, query string) (string, error) {
+	memos, err := b.memos(userID, model.MemoKindNote, query)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your notes right now. Please try again later")
+	}
+	if len(memos) == 0 {
+		if query != "" {
+			return "", userError{fmt.Sprintf("I couldn't find any notes about '%s'.", strings.TrimSpace(query))}
+		}
+		return "", userError{"You have no notes yet. Start one with 'note: ...'."}
+	}
+
+	var sb strings.Builder
+	if query != "" {
+		sb.WriteString(fmt.Sprintf("Notes matching '%s':\n", strings.TrimSpace(query)))
+	} else {
+		sb.WriteString("Here are your notes:\n")
+	}
+	for i, m := range memos {
+		sb.WriteString(fmt.Sprintf("%d. %s — %s\n   %s\n", i+1, m.Title, m.CreatedAt.Format("Jan 02 15:04"), truncate(m.Content, 160)))
+	}
+	return sb.String(), nil
+}
+
+// deleteMemos deletes memos by their position in the notes list.
+func (b *Bot) deleteMemos(userID, selector string) (string, error) {
+	indices := parseIndices(selector)
+	if len(indices) == 0 {
+		return "", userError{"Tell me which note to delete by number, e.g. 'delete note 2'."}
+	}
+	memos, err := b.memos(userID, model.MemoKindNote, "")
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your notes right now. Please try again later")
+	}
+
+	ids := make([]uint, 0, len(indices))
+	for _, idx := range indices {
+		if idx < 1 || idx > len(memos) {
+			return "", userError{fmt.Sprintf("Note %d doesn't exist. Choose between 1 and %d.", idx, len(memos))}
+		}
+		ids = append(ids, memos[idx-1].ID)
+	}
+	if err := b.db.Where("user_id = ? AND id IN ?", userID, ids).Delete(&model.Memo{}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't delete those notes. Please try again later")
+	}
+	return fmt.Sprintf("Deleted note(s): %s.", formatIndices(indices)), nil
+}
+
+// titleMemoWithOpenAI generates a short title for a memo.
+func (b *Bot) titleMemoWithOpenAI(ctx context.Context, userID, content string) string {
+	if !b.useOpenAI(ctx, userID) {
+		return truncate(content, 40)
+	}
+	title, err := b.openAI.TitleMemo(ctx, content)
+	if err != nil || strings.TrimSpace(title) == "" {
+		if err != nil {
+			b.logger.Printf("openai title error: %v", err)
+		}
+		return truncate(content, 40)
+	}
+	return title
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "..."
+}
@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/twilio"
+)
+
+// webhookErrorReply answers a webhook request that crashed.
+const webhookErrorReply = "Sorry, something went wrong on my side. Please try again in a moment."
+
+// recoverWebhook wraps a Twilio webhook handler so a panic, such as one from
+// an unexpected payload, is logged with its stack and counted, and the user
+// still gets a TwiML reply instead of a dropped request.
+func (b *Bot) recoverWebhook(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			b.webhookPanics.Add(1)
+			b.logger.Printf("webhook: panic serving %s %s: %v\n%s", r.Method, r.URL.Path, v, debug.Stack())
+			b.writeTwilioResponse(w, b.translate(sanitizeWhatsAppNumber(r.FormValue("From")), webhookErrorReply))
+		}()
+		next(w, r)
+	}
+}
+
+// signedTwilioKey marks a request context whose webhook carried a valid
+// Twilio signature.
+type signedTwilioKey struct{}
+
+// withSignedTwilio marks ctx as serving a signed Twilio webhook.
+func withSignedTwilio(ctx context.Context) context.Context {
+	return context.WithValue(ctx, signedTwilioKey{}, true)
+}
+
+// fromSignedTwilio reports whether ctx serves a signed Twilio webhook, so
+// its From number really is the sender's.
+func fromSignedTwilio(ctx context.Context) bool {
+	signed, _ := ctx.Value(signedTwilioKey{}).(bool)
+	return signed
+}
+
+// requireTwilioSignature refuses webhook requests without a valid
+// X-Twilio-Signature, since anyone can post a form with someone else's From
+// number. A message to a tenant's number is checked against the tenant's
+// own auth token.
+func (b *Bot) requireTwilioSignature(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		tenant, err := b.tenantByNumber(r.Context(), r.PostFormValue("To"))
+		if err != nil {
+			b.logger.Printf("webhook: resolve tenant: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		_, token := b.twilioCredentials(tenant)
+		if !twilio.ValidSignature(token, b.requestURL(r), r.PostForm, r.Header.Get(twilio.SignatureHeader)) {
+			b.logger.Printf("webhook: refused %s %s without a valid %s", r.Method, r.URL.Path, twilio.SignatureHeader)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r.WithContext(withSignedTwilio(r.Context())))
+	}
+}
+
+// requestURL rebuilds the URL Twilio requested: under PUBLIC_BASE_URL when
+// it is set, or else from the Host header and the scheme a proxy reports.
+func (b *Bot) requestURL(r *http.Request) string {
+	if b.cfg.PublicBaseURL != "" {
+		return b.cfg.PublicBaseURL + r.URL.RequestURI()
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// maxLoggedPayload caps how much of a request body is logged.
+const maxLoggedPayload = 4 << 10
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// LogRequests wraps next so that, while REQUEST_LOGGING is on, each request
+// is logged with its method, path, status, and latency. A sample of requests,
+// REQUEST_LOG_PAYLOAD_PERCENT of them, also has its body logged, which helps
+// with intermittent webhook trouble without writing every message out.
+// Message text and credentials in the body are redacted, since users send
+// tokens and passwords in commands such as "connect notion". The query
+// string is left out since callback URLs carry tokens.
+func (b *Bot) LogRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mu.RLock()
+		enabled, payloadPct := b.cfg.RequestLogging, b.cfg.RequestLogPayloadPct
+		b.mu.RUnlock()
+		if !enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var payload []byte
+		if r.Body != nil && payloadPct > 0 && rand.IntN(100) < payloadPct {
+			var err error
+			payload, err = io.ReadAll(io.LimitReader(r.Body, maxLoggedPayload))
+			if err != nil {
+				b.logger.Printf("request log: read %s body: %v", r.URL.Path, err)
+			}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(payload), r.Body), r.Body}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+		latency := time.Since(start)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		if payload != nil {
+			b.logger.Printf("http: %s %s %d %s payload=%q", r.Method, r.URL.Path, rec.status, latency, redactPayload(r.Header.Get("Content-Type"), payload))
+			return
+		}
+		b.logger.Printf("http: %s %s %d %s", r.Method, r.URL.Path, rec.status, latency)
+	})
+}
+
+// redactedFields are form and JSON fields whose values are never logged:
+// message text, which can hold "connect caldav" passwords, and email bodies.
+var redactedFields = map[string]bool{
+	"body":          true,
+	"content":       true,
+	"text":          true,
+	"html":          true,
+	"subject":       true,
+	"body-plain":    true,
+	"body-html":     true,
+	"stripped-text": true,
+}
+
+// redactField reports whether a field's value must not be logged.
+func redactField(name string) bool {
+	name = strings.ToLower(name)
+	return redactedFields[name] || strings.Contains(name, "token") || strings.Contains(name, "password") || strings.Contains(name, "secret")
+}
+
+// redactPayload renders a request body for the log with the values of
+// redacted fields replaced by their length. A body that can't be parsed,
+// perhaps because it was cut short, is logged by size alone.
+func redactPayload(contentType string, payload []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(payload))
+		if err != nil {
+			break
+		}
+		names := make([]string, 0, len(form))
+		for name := range form {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var pairs []string
+		for _, name := range names {
+			for _, value := range form[name] {
+				if redactField(name) {
+					value = fmt.Sprintf("[redacted %d bytes]", len(value))
+				} else {
+					value = url.QueryEscape(value)
+				}
+				pairs = append(pairs, url.QueryEscape(name)+"="+value)
+			}
+		}
+		return strings.Join(pairs, "&")
+	case "application/json":
+		var data any
+		if err := json.Unmarshal(payload, &data); err != nil {
+			break
+		}
+		redacted, err := json.Marshal(redactJSON(data))
+		if err != nil {
+			break
+		}
+		return string(redacted)
+	}
+	return fmt.Sprintf("[%d bytes]", len(payload))
+}
+
+// redactJSON replaces the values of redacted fields anywhere in v.
+func redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for name, value := range v {
+			if s, ok := value.(string); ok && redactField(name) {
+				v[name] = fmt.Sprintf("[redacted %d bytes]", len(s))
+			} else {
+				v[name] = redactJSON(value)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i])
+		}
+	}
+	return v
+}
+
+// Metrics are counters operators can watch for trouble.
+type Metrics struct {
+	WebhookPanics int64 `json:"webhook_panics"`
+	// Events counts reminder events published since startup, by type.
+	Events map[string]int64 `json:"events"`
+}
+
+// AdminMetricsHandler serves the bot's counters as JSON.
+func (b *Bot) AdminMetricsHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, Metrics{
+			WebhookPanics: b.webhookPanics.Load(),
+			Events:        b.eventCounts.Snapshot(),
+		})
+	}))
+}
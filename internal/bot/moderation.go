@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+// moderationRefusal is the reply to a message the moderation check flags.
+const moderationRefusal = "I can't save or repeat that message."
+
+// flagged reports whether content from a user should be refused, when
+// OPENAI_MODERATION is on. source names where it came from, for the log. A
+// failed check lets the content through rather than blocking everyone while
+// the endpoint is down.
+func (b *Bot) flagged(ctx context.Context, userID, source, content string) bool {
+	b.mu.RLock()
+	enabled := b.cfg.ModerateContent
+	b.mu.RUnlock()
+	if !enabled || b.openAI == nil {
+		return false
+	}
+	flagged, err := b.openAI.Moderate(ctx, content)
+	if err != nil {
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("moderation: check %s message from %s: %v", source, userID, err)
+		}
+		return false
+	}
+	if flagged {
+		b.logger.Printf("moderation: flagged %s message from %s (%d characters); not stored", source, userID, len(content))
+	}
+	return flagged
+}
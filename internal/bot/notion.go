@@ -0,0 +1,715 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/notion"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// notionSyncSpec is the cron schedule for pulling changes from connected
+// Notion databases.
+const notionSyncSpec = "0 * * * *"
+
+// defaultNotionPriority is used for pages whose priority is missing or out of range.
+const defaultNotionPriority = 3
+
+// Reminder fields that can be mapped to Notion database properties.
+const (
+	notionFieldTitle    = "title"
+	notionFieldPriority = "priority"
+	notionFieldDue      = "due"
+	notionFieldStatus   = "status"
+)
+
+var (
+	connectNotionPattern = regexp.MustCompile(`(?i)^connect notion\s+(\S+)\s+(\S+)$`)
+	mapNotionPattern     = regexp.MustCompile(`(?i)^map notion (title|priority|due|status) to (.+)$`)
+)
+
+// handleNotionCommand connects, configures, and syncs a user's Notion
+// database. It reports false when the message is not a Notion command.
+func (b *Bot) handleNotionCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	switch {
+	case connectNotionPattern.MatchString(body):
+		m := connectNotionPattern.FindStringSubmatch(body)
+		msg, err = b.connectNotion(ctx, userID, m[1], m[2])
+	case mapNotionPattern.MatchString(body):
+		m := mapNotionPattern.FindStringSubmatch(body)
+		msg, err = b.mapNotionField(ctx, userID, strings.ToLower(m[1]), strings.TrimSpace(m[2]))
+	case lowerBody == "notion" || lowerBody == "notion status":
+		msg, err = b.describeNotion(userID)
+	case lowerBody == "sync notion" || lowerBody == "notion sync":
+		msg, err = b.syncNotionNow(ctx, userID)
+	case lowerBody == "notion pull on" || lowerBody == "notion pull off":
+		msg, err = b.setNotionPull(userID, lowerBody == "notion pull on")
+	case lowerBody == "disconnect notion":
+		msg, err = b.disconnectNotion(userID)
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("notion command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// connectNotion verifies access to a database, detects a field mapping from
+// its schema, and stores the connection.
+func (b *Bot) connectNotion(ctx context.Context, userID, token, database string) (string, error) {
+	databaseID, ok := notion.ParseDatabaseID(database)
+	if !ok {
+		return "", userError{"That doesn't look like a Notion database link or ID."}
+	}
+	conn := &model.NotionConnection{
+		UserID:     userID,
+		Token:      token,
+		DatabaseID: databaseID,
+	}
+	schema, err := b.notionSchema(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	detectNotionMapping(conn, schema)
+	if conn.TitleProperty == "" {
+		return "", userError{"That Notion database has no title property, so I can't store reminders in it."}
+	}
+
+	if err := b.saveNotionConnection(conn); err != nil {
+		return "", fmt.Errorf("I couldn't save your Notion connection. Please try again later")
+	}
+	return fmt.Sprintf("Connected to Notion database '%s'. New reminders will be added there.\n%s", fallback(schema.Name(), "Untitled"), notionMappingSummary(conn)), nil
+}
+
+// mapNotionField points a reminder field at a different database property.
+// "none" stops syncing that field.
+func (b *Bot) mapNotionField(ctx context.Context, userID, field, property string) (string, error) {
+	conn, err := b.notionConnection(userID)
+	if err != nil {
+		return "", err
+	}
+	schema, err := b.notionSchema(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	if err := setNotionField(conn, schema, field, property); err != nil {
+		return "", err
+	}
+	if err := b.saveNotionConnection(conn); err != nil {
+		return "", fmt.Errorf("I couldn't update your Notion mapping. Please try again later")
+	}
+	return "Updated your Notion mapping.\n" + notionMappingSummary(conn), nil
+}
+
+// describeNotion reports the user's Notion connection and mapping.
+func (b *Bot) describeNotion(userID string) (string, error) {
+	conn, err := b.notionConnection(userID)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("Connected to Notion.\n")
+	sb.WriteString(notionMappingSummary(conn))
+	if conn.Pull {
+		sb.WriteString("\nChanges made in Notion are pulled in every hour.")
+	}
+	if conn.LastSyncedAt != nil {
+		sb.WriteString("\nLast synced " + conn.LastSyncedAt.In(b.cfg.LocalTimezone).Format("Jan 02 15:04") + ".")
+	}
+	return sb.String(), nil
+}
+
+// syncNotionNow pulls the user's Notion database immediately.
+func (b *Bot) syncNotionNow(ctx context.Context, userID string) (string, error) {
+	conn, err := b.notionConnection(userID)
+	if err != nil {
+		return "", err
+	}
+	imported, completed, err := b.pullNotion(ctx, conn)
+	if err != nil {
+		return "", b.notionError("sync", err)
+	}
+	return fmt.Sprintf("Synced with Notion: %d new reminder(s), %d marked done.", imported, completed), nil
+}
+
+// setNotionPull toggles the hourly pull from Notion.
+func (b *Bot) setNotionPull(userID string, enabled bool) (string, error) {
+	conn, err := b.notionConnection(userID)
+	if err != nil {
+		return "", err
+	}
+	conn.Pull = enabled
+	if err := b.saveNotionConnection(conn); err != nil {
+		return "", fmt.Errorf("I couldn't update that setting. Please try again later")
+	}
+	if enabled {
+		return "I'll pull new and completed items from Notion every hour.", nil
+	}
+	return "Okay, I'll only push reminders to Notion from now on.", nil
+}
+
+// disconnectNotion removes the user's Notion connection. Pages already in
+// Notion are left alone.
+func (b *Bot) disconnectNotion(userID string) (string, error) {
+	result := b.db.Where("user_id = ?", userID).Delete(&model.NotionConnection{})
+	if result.Error != nil {
+		return "", fmt.Errorf("I couldn't disconnect Notion. Please try again later")
+	}
+	if result.RowsAffected == 0 {
+		return "", userError{"Notion isn't connected."}
+	}
+	return "Disconnected from Notion. Existing pages stay in your database.", nil
+}
+
+// notionConnection loads the user's connection, returning a userError when
+// there is none.
+func (b *Bot) notionConnection(userID string) (*model.NotionConnection, error) {
+	var conn model.NotionConnection
+	err := b.db.Where("user_id = ?", userID).First(&conn).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, userError{"Notion isn't connected. Send 'connect notion <integration token> <database link>' first."}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("I couldn't load your Notion connection. Please try again later")
+	}
+	return &conn, nil
+}
+
+func (b *Bot) saveNotionConnection(conn *model.NotionConnection) error {
+	return b.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(conn).Error
+}
+
+// notionSchema fetches the connected database's schema.
+func (b *Bot) notionSchema(ctx context.Context, conn *model.NotionConnection) (*notion.Database, error) {
+	if b.notion == nil {
+		return nil, userError{"Notion sync isn't available right now."}
+	}
+	schema, err := b.notion.Database(ctx, conn.Token, conn.DatabaseID)
+	if err != nil {
+		return nil, b.notionError("load database", err)
+	}
+	return schema, nil
+}
+
+// notionError turns a Notion API failure into a reply. Access problems are
+// the user's to fix; anything else is logged.
+func (b *Bot) notionError(action string, err error) error {
+	var apiErr *notion.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Status {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return userError{"Notion rejected that token or database. Check the token and share the database with your integration."}
+		}
+	}
+	b.logger.Printf("notion: %s: %v", action, err)
+	return userError{"I couldn't reach Notion right now. Please try again later."}
+}
+
+// detectNotionMapping fills unmapped fields from conventionally named
+// properties in schema.
+func detectNotionMapping(conn *model.NotionConnection, schema *notion.Database) {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := schema.Properties[name]
+		lower := strings.ToLower(name)
+		switch {
+		case prop.Type == notion.TypeTitle && conn.TitleProperty == "":
+			setNotionField(conn, schema, notionFieldTitle, name)
+		case lower == "priority" && conn.PriorityProperty == "":
+			setNotionField(conn, schema, notionFieldPriority, name)
+		case (lower == "due" || lower == "due date" || lower == "deadline") && conn.DueProperty == "":
+			setNotionField(conn, schema, notionFieldDue, name)
+		case (lower == "status" || lower == "done") && conn.StatusProperty == "":
+			setNotionField(conn, schema, notionFieldStatus, name)
+		}
+	}
+}
+
+// setNotionField maps field to the named property, checking that the
+// property exists and has a type the field can be stored in.
+func setNotionField(conn *model.NotionConnection, schema *notion.Database, field, property string) error {
+	if strings.EqualFold(property, "none") {
+		switch field {
+		case notionFieldTitle:
+			return userError{"Reminders need a title property."}
+		case notionFieldPriority:
+			conn.PriorityProperty, conn.PriorityType = "", ""
+		case notionFieldDue:
+			conn.DueProperty = ""
+		case notionFieldStatus:
+			conn.StatusProperty, conn.StatusType, conn.OpenStatus, conn.DoneStatus = "", "", "", ""
+		}
+		return nil
+	}
+
+	name, prop, ok := findNotionProperty(schema, property)
+	if !ok {
+		return userError{fmt.Sprintf("The Notion database has no property called '%s'.", property)}
+	}
+	wrongType := func(want string) error {
+		return userError{fmt.Sprintf("'%s' is a %s property, but %s needs %s.", name, prop.Type, field, want)}
+	}
+
+	switch field {
+	case notionFieldTitle:
+		if prop.Type != notion.TypeTitle {
+			return wrongType("the title property")
+		}
+		conn.TitleProperty = name
+	case notionFieldPriority:
+		if prop.Type != notion.TypeNumber && prop.Type != notion.TypeSelect {
+			return wrongType("a number or select property")
+		}
+		conn.PriorityProperty, conn.PriorityType = name, prop.Type
+	case notionFieldDue:
+		if prop.Type != notion.TypeDate {
+			return wrongType("a date property")
+		}
+		conn.DueProperty = name
+	case notionFieldStatus:
+		if prop.Type != notion.TypeStatus && prop.Type != notion.TypeSelect && prop.Type != notion.TypeCheckbox {
+			return wrongType("a status, select, or checkbox property")
+		}
+		conn.StatusProperty, conn.StatusType = name, prop.Type
+		conn.OpenStatus, conn.DoneStatus = pickStatusOptions(prop.Options())
+	}
+	return nil
+}
+
+func findNotionProperty(schema *notion.Database, name string) (string, notion.Property, bool) {
+	if prop, ok := schema.Properties[name]; ok {
+		return name, prop, true
+	}
+	for actual, prop := range schema.Properties {
+		if strings.EqualFold(actual, name) {
+			return actual, prop, true
+		}
+	}
+	return "", notion.Property{}, false
+}
+
+// pickStatusOptions chooses which status options mean open and done,
+// preferring conventional names and falling back to the first and last.
+func pickStatusOptions(options []notion.Option) (open, done string) {
+	if len(options) == 0 {
+		return "", ""
+	}
+	for _, o := range options {
+		switch strings.ToLower(o.Name) {
+		case "not started", "to do", "todo", "open":
+			if open == "" {
+				open = o.Name
+			}
+		case "done", "complete", "completed":
+			if done == "" {
+				done = o.Name
+			}
+		}
+	}
+	if open == "" {
+		open = options[0].Name
+	}
+	if done == "" {
+		done = options[len(options)-1].Name
+	}
+	return open, done
+}
+
+func notionMappingSummary(conn *model.NotionConnection) string {
+	mapped := func(property string) string {
+		if property == "" {
+			return "not synced"
+		}
+		return property
+	}
+	status := mapped(conn.StatusProperty)
+	if conn.StatusProperty != "" && conn.StatusType != notion.TypeCheckbox {
+		status = fmt.Sprintf("%s (%s / %s)", conn.StatusProperty, conn.OpenStatus, conn.DoneStatus)
+	}
+	return fmt.Sprintf("Title → %s\nPriority → %s\nDue date → %s\nStatus → %s",
+		conn.TitleProperty, mapped(conn.PriorityProperty), mapped(conn.DueProperty), status)
+}
+
+// notionProperties renders a reminder as page properties using conn's mapping.
+func notionProperties(conn *model.NotionConnection, reminder model.Reminder, done bool) map[string]notion.PropertyValue {
+	props := map[string]notion.PropertyValue{
+		conn.TitleProperty: notion.TitleValue(fallback(reminder.Summary, reminder.Content)),
+	}
+
+	switch conn.PriorityType {
+	case notion.TypeNumber:
+		priority := float64(reminder.Priority)
+		props[conn.PriorityProperty] = notion.PropertyValue{Number: &priority}
+	case notion.TypeSelect:
+		props[conn.PriorityProperty] = notion.PropertyValue{Select: &notion.Option{Name: strconv.Itoa(reminder.Priority)}}
+	}
+
+	if conn.DueProperty != "" && reminder.DueAt != nil {
+		props[conn.DueProperty] = notion.PropertyValue{Date: &notion.Date{Start: reminder.DueAt.Format("2006-01-02")}}
+	}
+
+	status := conn.OpenStatus
+	if done {
+		status = conn.DoneStatus
+	}
+	switch conn.StatusType {
+	case notion.TypeStatus:
+		if status != "" {
+			props[conn.StatusProperty] = notion.PropertyValue{Status: &notion.Option{Name: status}}
+		}
+	case notion.TypeSelect:
+		if status != "" {
+			props[conn.StatusProperty] = notion.PropertyValue{Select: &notion.Option{Name: status}}
+		}
+	case notion.TypeCheckbox:
+		props[conn.StatusProperty] = notion.PropertyValue{Checkbox: &done}
+	}
+	return props
+}
+
+// notionItem is a reminder read back from a Notion page.
+type notionItem struct {
+	Title    string
+	Priority int
+	DueAt    *time.Time
+	Done     bool
+}
+
+// readNotionPage extracts reminder fields from page using conn's mapping.
+func readNotionPage(conn *model.NotionConnection, page notion.Page, loc *time.Location) notionItem {
+	item := notionItem{
+		Title:    strings.TrimSpace(notion.PlainText(page.Properties[conn.TitleProperty].Title)),
+		Priority: defaultNotionPriority,
+	}
+
+	if conn.PriorityProperty != "" {
+		value := page.Properties[conn.PriorityProperty]
+		priority := 0
+		switch {
+		case value.Number != nil:
+			priority = int(*value.Number)
+		case value.Select != nil:
+			priority, _ = strconv.Atoi(strings.TrimSpace(value.Select.Name))
+		}
+		if priority >= 1 && priority <= 5 {
+			item.Priority = priority
+		}
+	}
+
+	if conn.DueProperty != "" {
+		if date := page.Properties[conn.DueProperty].Date; date != nil {
+			if due, ok := parseNotionDate(date.Start, loc); ok {
+				item.DueAt = &due
+			}
+		}
+	}
+
+	if conn.StatusProperty != "" {
+		value := page.Properties[conn.StatusProperty]
+		switch {
+		case value.Checkbox != nil:
+			item.Done = *value.Checkbox
+		case value.Status != nil:
+			item.Done = conn.DoneStatus != "" && value.Status.Name == conn.DoneStatus
+		case value.Select != nil:
+			item.Done = conn.DoneStatus != "" && value.Select.Name == conn.DoneStatus
+		}
+	}
+	return item
+}
+
+func parseNotionDate(value string, loc *time.Location) (time.Time, bool) {
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), true
+	}
+	return time.Time{}, false
+}
+
+// pullNotion imports new open pages as reminders and completes reminders
+// whose pages were marked done in Notion.
+func (b *Bot) pullNotion(ctx context.Context, conn *model.NotionConnection) (imported, completed int, err error) {
+	if b.notion == nil {
+		return 0, 0, errors.New("notion client not configured")
+	}
+	pages, err := b.notion.QueryDatabase(ctx, conn.Token, conn.DatabaseID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var linked []model.Reminder
+	if err := b.db.Where("user_id = ? AND notion_page_id <> ''", conn.UserID).Find(&linked).Error; err != nil {
+		return 0, 0, err
+	}
+	byPage := make(map[string]model.Reminder, len(linked))
+	for _, r := range linked {
+		byPage[r.NotionPageID] = r
+	}
+
+	now := time.Now().In(b.cfg.LocalTimezone)
+	for _, page := range pages {
+		if page.Archived {
+			continue
+		}
+		item := readNotionPage(conn, page, b.cfg.LocalTimezone)
+		if item.Title == "" {
+			continue
+		}
+
+		existing, ok := byPage[page.ID]
+		switch {
+		case !ok && !item.Done:
+			reminder := &model.Reminder{
+				UserID:       conn.UserID,
+				Content:      item.Title,
+				Summary:      item.Title,
+				Priority:     item.Priority,
+				DueAt:        item.DueAt,
+				NotionPageID: page.ID,
+			}
+			if err := b.db.Create(reminder).Error; err != nil {
+				return imported, completed, err
+			}
+			b.publish(webhook.EventReminderCreated, *reminder, "")
+			imported++
+		case ok && existing.CompletedAt == nil && item.Done && !existing.IsHabit():
+			if err := b.db.Model(&existing).Update("completed_at", now).Error; err != nil {
+				return imported, completed, err
+			}
+			b.publish(webhook.EventReminderCompleted, existing, "")
+			completed++
+		case ok && existing.CompletedAt == nil:
+			if err := b.db.Model(&existing).Updates(map[string]any{
+				"priority": item.Priority,
+				"due_at":   item.DueAt,
+			}).Error; err != nil {
+				return imported, completed, err
+			}
+		}
+	}
+
+	if err := b.db.Model(conn).Update("last_synced_at", now).Error; err != nil {
+		b.logger.Printf("notion: record sync for %s: %v", conn.UserID, err)
+	}
+	return imported, completed, nil
+}
+
+// pullNotionConnections runs a pull for every connection that opted in.
+func (b *Bot) pullNotionConnections() {
+	var conns []model.NotionConnection
+	if err := b.db.Where("pull = ?", true).Find(&conns).Error; err != nil {
+		b.logger.Printf("scheduler: fetch notion connections: %v", err)
+		return
+	}
+	for i := range conns {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		if _, _, err := b.pullNotion(ctx, &conns[i]); err != nil {
+			b.logger.Printf("scheduler: notion pull for %s: %v", conns[i].UserID, err)
+		}
+		cancel()
+	}
+}
+
+// pushToNotion mirrors a reminder lifecycle event into the owner's Notion
+// database in the background. Reminders that came from Notion are not
+// pushed back on creation.
+func (b *Bot) pushToNotion(eventType string, reminder model.Reminder) {
+	if b.notion == nil {
+		return
+	}
+	switch eventType {
+	case webhook.EventReminderCreated:
+		if reminder.NotionPageID != "" {
+			return
+		}
+	case webhook.EventReminderUpdated, webhook.EventReminderCompleted, webhook.EventReminderDeleted:
+		if reminder.NotionPageID == "" || (eventType == webhook.EventReminderCompleted && reminder.IsHabit()) {
+			return
+		}
+	default:
+		return
+	}
+
+	var conn model.NotionConnection
+	err := b.db.Where("user_id = ?", reminder.UserID).First(&conn).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		b.logger.Printf("notion: load connection for %s: %v", reminder.UserID, err)
+		return
+	}
+
+	b.sends.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		var err error
+		switch eventType {
+		case webhook.EventReminderCreated:
+			var page *notion.Page
+			page, err = b.notion.CreatePage(ctx, conn.Token, conn.DatabaseID, notionProperties(&conn, reminder, false))
+			if err == nil {
+				err = b.db.Model(&model.Reminder{}).Where("id = ?", reminder.ID).Update("notion_page_id", page.ID).Error
+			}
+		case webhook.EventReminderUpdated:
+			err = b.notion.UpdatePage(ctx, conn.Token, reminder.NotionPageID, notionProperties(&conn, reminder, reminder.CompletedAt != nil))
+		case webhook.EventReminderCompleted:
+			err = b.notion.UpdatePage(ctx, conn.Token, reminder.NotionPageID, notionProperties(&conn, reminder, true))
+		case webhook.EventReminderDeleted:
+			err = b.notion.ArchivePage(ctx, conn.Token, reminder.NotionPageID)
+		}
+		if err != nil {
+			b.logger.Printf("notion: %s reminder %d: %v", eventType, reminder.ID, err)
+		}
+	})
+}
+
+// NotionConnectionRequest configures a user's Notion connection through the
+// admin API. Properties maps reminder fields (title, priority, due, status)
+// to database property names; unmapped fields are detected from the schema.
+type NotionConnectionRequest struct {
+	UserID     string            `json:"user_id"`
+	Token      string            `json:"token"`
+	DatabaseID string            `json:"database_id"`
+	Pull       bool              `json:"pull"`
+	Properties map[string]string `json:"properties"`
+}
+
+// NotionConnectionState describes a stored Notion connection. The token is
+// never returned.
+type NotionConnectionState struct {
+	UserID       string            `json:"user_id"`
+	DatabaseID   string            `json:"database_id"`
+	Pull         bool              `json:"pull"`
+	Properties   map[string]string `json:"properties"`
+	OpenStatus   string            `json:"open_status,omitempty"`
+	DoneStatus   string            `json:"done_status,omitempty"`
+	LastSyncedAt *time.Time        `json:"last_synced_at,omitempty"`
+}
+
+func notionConnectionState(conn *model.NotionConnection) NotionConnectionState {
+	props := map[string]string{notionFieldTitle: conn.TitleProperty}
+	for field, property := range map[string]string{
+		notionFieldPriority: conn.PriorityProperty,
+		notionFieldDue:      conn.DueProperty,
+		notionFieldStatus:   conn.StatusProperty,
+	} {
+		if property != "" {
+			props[field] = property
+		}
+	}
+	return NotionConnectionState{
+		UserID:       conn.UserID,
+		DatabaseID:   conn.DatabaseID,
+		Pull:         conn.Pull,
+		Properties:   props,
+		OpenStatus:   conn.OpenStatus,
+		DoneStatus:   conn.DoneStatus,
+		LastSyncedAt: conn.LastSyncedAt,
+	}
+}
+
+// AdminNotionHandler lets operators inspect (GET), configure (PUT), and remove
+// (DELETE) a user's Notion connection.
+func (b *Bot) AdminNotionHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			conn, err := b.notionConnection(r.URL.Query().Get("user_id"))
+			if err != nil {
+				b.writeAdminError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, notionConnectionState(conn))
+		case http.MethodPut:
+			var req NotionConnectionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			conn, err := b.configureNotion(r.Context(), req)
+			if err != nil {
+				b.writeAdminError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, notionConnectionState(conn))
+		case http.MethodDelete:
+			if _, err := b.disconnectNotion(r.URL.Query().Get("user_id")); err != nil {
+				b.writeAdminError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+// configureNotion stores a connection from an admin request, applying explicit
+// property mappings before detecting the rest.
+func (b *Bot) configureNotion(ctx context.Context, req NotionConnectionRequest) (*model.NotionConnection, error) {
+	if req.UserID == "" || req.Token == "" {
+		return nil, userError{"user_id and token are required"}
+	}
+	databaseID, ok := notion.ParseDatabaseID(req.DatabaseID)
+	if !ok {
+		return nil, userError{"database_id is not a Notion database ID or link"}
+	}
+	conn := &model.NotionConnection{
+		UserID:     req.UserID,
+		Token:      req.Token,
+		DatabaseID: databaseID,
+		Pull:       req.Pull,
+	}
+	schema, err := b.notionSchema(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for field, property := range req.Properties {
+		switch field {
+		case notionFieldTitle, notionFieldPriority, notionFieldDue, notionFieldStatus:
+		default:
+			return nil, userError{fmt.Sprintf("unknown field %q; use title, priority, due, or status", field)}
+		}
+		if err := setNotionField(conn, schema, field, property); err != nil {
+			return nil, err
+		}
+	}
+	detectNotionMapping(conn, schema)
+	if conn.TitleProperty == "" {
+		return nil, userError{"the database has no title property"}
+	}
+	if err := b.saveNotionConnection(conn); err != nil {
+		return nil, fmt.Errorf("save notion connection: %w", err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/openapi"
+)
+
+// apiDocument is the OpenAPI description of the dashboard REST API, built
+// once on first use.
+var apiDocument = sync.OnceValue(newAPIDocument)
+
+func newAPIDocument() *openapi.Document {
+	num := func(v float64) *float64 { return &v }
+	one := 1
+	maxBatch := maxBatchReminders
+	no := false
+	public := []map[string][]string{}
+	hookToken := []map[string][]string{{"hookToken": {}}}
+
+	jsonBody := func(schema *openapi.Schema) map[string]openapi.MediaType {
+		return map[string]openapi.MediaType{"application/json": {Schema: schema}}
+	}
+	errorResponse := func(description string) openapi.Response {
+		return openapi.Response{Description: description, Content: jsonBody(openapi.Ref("Error"))}
+	}
+	idParam := openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int64"}}
+
+	priority := func(description string) *openapi.Schema {
+		return &openapi.Schema{Type: "integer", Minimum: num(1), Maximum: num(5), Description: description}
+	}
+	dueAt := &openapi.Schema{Type: "string", Description: "Due date as YYYY-MM-DD or an RFC 3339 timestamp. An empty string clears it."}
+
+	return &openapi.Document{
+		OpenAPI: "3.0.3",
+		Info: openapi.Info{
+			Title:       "myMemo API",
+			Version:     "1.0.0",
+			Description: "Reminders API behind the myMemo web dashboard. Sign in with a magic link to get a session cookie; requests that change data must be JSON.",
+		},
+		Servers:  []openapi.Server{{URL: "/api"}},
+		Security: []map[string][]string{{"session": {}}},
+		Paths: map[string]openapi.PathItem{
+			"/openapi.json": {
+				"get": {
+					OperationID: "getOpenAPI",
+					Summary:     "This document",
+					Tags:        []string{"meta"},
+					Security:    &public,
+					Responses:   map[string]openapi.Response{"200": {Description: "OpenAPI document"}},
+				},
+			},
+			"/me": {
+				"get": {
+					OperationID: "getMe",
+					Summary:     "The signed-in user",
+					Tags:        []string{"session"},
+					Responses: map[string]openapi.Response{
+						"200": {Description: "Current user", Content: jsonBody(openapi.Ref("Me"))},
+						"401": errorResponse("Not signed in"),
+					},
+				},
+			},
+			"/reminders": {
+				"get": {
+					OperationID: "listReminders",
+					Summary:     "List open or completed reminders",
+					Tags:        []string{"reminders"},
+					Parameters: []openapi.Parameter{{
+						Name: "status", In: "query",
+						Description: "Open reminders (default) in list order, or the 100 most recently completed.",
+						Schema:      &openapi.Schema{Type: "string", Enum: []string{"open", "completed"}},
+					}, {
+						Name: "q", In: "query",
+						Description: "Only reminders whose content or summary contains every word, matched on word stems where the database supports it.",
+						Schema:      &openapi.Schema{Type: "string"},
+					}},
+					Responses: map[string]openapi.Response{
+						"200": {Description: "Reminders", Content: jsonBody(openapi.Ref("ReminderList"))},
+						"400": errorResponse("Invalid status"),
+						"401": errorResponse("Not signed in"),
+					},
+				},
+				"post": {
+					OperationID: "createReminder",
+					Summary:     "Add a reminder",
+					Tags:        []string{"reminders"},
+					RequestBody: &openapi.RequestBody{Required: true, Content: jsonBody(openapi.Ref("ReminderCreate"))},
+					Responses: map[string]openapi.Response{
+						"201": {Description: "Created reminder", Content: jsonBody(openapi.Ref("Reminder"))},
+						"400": errorResponse("Invalid request"),
+						"401": errorResponse("Not signed in"),
+					},
+				},
+			},
+			"/reminders/batch": {
+				"post": {
+					OperationID: "createReminders",
+					Summary:     "Add several reminders at once, all or none",
+					Tags:        []string{"reminders"},
+					RequestBody: &openapi.RequestBody{Required: true, Content: jsonBody(openapi.Ref("ReminderBatch"))},
+					Responses: map[string]openapi.Response{
+						"201": {Description: "Created reminders, in request order", Content: jsonBody(openapi.Ref("ReminderList"))},
+						"400": errorResponse("Invalid request"),
+						"401": errorResponse("Not signed in"),
+					},
+				},
+			},
+			"/reminders/{id}": {
+				"get": {
+					OperationID: "getReminder",
+					Summary:     "Get a reminder",
+					Tags:        []string{"reminders"},
+					Parameters:  []openapi.Parameter{idParam},
+					Responses: map[string]openapi.Response{
+						"200": {Description: "Reminder", Content: jsonBody(openapi.Ref("Reminder"))},
+						"404": errorResponse("No such reminder"),
+					},
+				},
+				"patch": {
+					OperationID: "updateReminder",
+					Summary:     "Edit a reminder's content, priority, or due date",
+					Tags:        []string{"reminders"},
+					Parameters:  []openapi.Parameter{idParam},
+					RequestBody: &openapi.RequestBody{Required: true, Content: jsonBody(openapi.Ref("ReminderUpdate"))},
+					Responses: map[string]openapi.Response{
+						"200": {Description: "Updated reminder", Content: jsonBody(openapi.Ref("Reminder"))},
+						"400": errorResponse("Invalid request"),
+						"404": errorResponse("No such reminder"),
+					},
+				},
+				"delete": {
+					OperationID: "deleteReminder",
+					Summary:     "Delete a reminder",
+					Tags:        []string{"reminders"},
+					Parameters:  []openapi.Parameter{idParam},
+					Responses: map[string]openapi.Response{
+						"204": {Description: "Deleted"},
+						"404": errorResponse("No such reminder"),
+					},
+				},
+			},
+			"/reminders/{id}/complete": {
+				"post": {
+					OperationID: "completeReminder",
+					Summary:     "Mark a reminder done, or check in a habit",
+					Tags:        []string{"reminders"},
+					Parameters:  []openapi.Parameter{idParam},
+					Responses: map[string]openapi.Response{
+						"200": {Description: "Completed reminder", Content: jsonBody(openapi.Ref("Completion"))},
+						"400": errorResponse("Already completed"),
+						"404": errorResponse("No such reminder"),
+					},
+				},
+			},
+			"/v1/hooks/create-reminder": {
+				"post": {
+					OperationID: "hookCreateReminder",
+					Summary:     "Add a reminder for the token's owner from an automation such as Zapier or IFTTT",
+					Tags:        []string{"hooks"},
+					Security:    &hookToken,
+					RequestBody: &openapi.RequestBody{Required: true, Content: jsonBody(openapi.Ref("HookReminder"))},
+					Responses: map[string]openapi.Response{
+						"201": {Description: "Created reminder", Content: jsonBody(openapi.Ref("Reminder"))},
+						"400": errorResponse("Invalid request"),
+						"401": errorResponse("Missing, wrong, or revoked token"),
+					},
+				},
+			},
+			"/insights": {
+				"get": {
+					OperationID: "getInsights",
+					Summary:     "Completion times, best and worst days, and most put-off reminders",
+					Tags:        []string{"session"},
+					Parameters: []openapi.Parameter{{
+						Name: "narrative", In: "query",
+						Description: "true adds a few sentences from OpenAI reading the figures, when it is configured.",
+						Schema:      &openapi.Schema{Type: "boolean"},
+					}},
+					Responses: map[string]openapi.Response{
+						"200": {Description: "Insights", Content: jsonBody(openapi.Ref("Insights"))},
+						"401": errorResponse("Not signed in"),
+					},
+				},
+			},
+			"/export": {
+				"get": {
+					OperationID: "exportData",
+					Summary:     "Download everything stored for the user",
+					Tags:        []string{"session"},
+					Responses: map[string]openapi.Response{
+						"200": {
+							Description: "ZIP of reminders.json, memos.json, lists.json, preferences.json, and deliveries.json",
+							Content:     map[string]openapi.MediaType{"application/zip": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}},
+						},
+						"401": errorResponse("Not signed in"),
+					},
+				},
+			},
+		},
+		Components: openapi.Components{
+			SecuritySchemes: map[string]openapi.SecurityScheme{
+				"session":   {Type: "apiKey", In: "cookie", Name: sessionCookieName, Description: "Session cookie set by the magic sign-in link."},
+				"hookToken": {Type: "http", Scheme: "bearer", Description: "A user's hook token, from sending \"hook token\" to the bot. Reminders are added for that user."},
+			},
+			Schemas: map[string]*openapi.Schema{
+				"Reminder": {
+					Type:     "object",
+					Required: []string{"id", "code", "content", "summary", "priority", "kind", "created_at"},
+					Properties: map[string]*openapi.Schema{
+						"id":            {Type: "integer", Format: "int64"},
+						"code":          {Type: "string", Description: "Stable per-user code, e.g. R7, accepted by chat commands."},
+						"content":       {Type: "string"},
+						"summary":       {Type: "string", Description: "One-line summary, or the content when none was generated."},
+						"priority":      priority("1 (low) to 5 (high)."),
+						"kind":          {Type: "string", Enum: []string{model.KindReminder, model.KindHabit, model.KindCountdown, model.KindBirthday, model.KindAnniversary}},
+						"category":      {Type: "string", Description: "One of the configured categories, e.g. work, when one fits."},
+						"time_of_day":   {Type: "string", Enum: []string{model.TimeMorning, model.TimeAfternoon, model.TimeEvening}, Description: "Part of the day the reminder waits for, when it was added with one, e.g. \"in the evening\"."},
+						"rrule":         {Type: "string", Description: "iCalendar repeat rule, e.g. FREQ=MONTHLY;BYDAY=1MO, for a repeating reminder. due_at is its next occurrence."},
+						"business_days": {Type: "boolean", Description: "The reminder skips weekends and public holidays, and its due date moves to the next working day."},
+						"streak":        {Type: "integer", Description: "Current streak in days, for habits."},
+						"skips":         {Type: "integer", Description: "Days a habit was skipped on purpose, which don't break its streak."},
+						"due_at":        {Type: "string", Format: "date-time"},
+						"completed_at":  {Type: "string", Format: "date-time"},
+						"created_at":    {Type: "string", Format: "date-time"},
+					},
+				},
+				"ReminderList": {
+					Type:       "object",
+					Required:   []string{"reminders"},
+					Properties: map[string]*openapi.Schema{"reminders": {Type: "array", Items: openapi.Ref("Reminder")}},
+				},
+				"ReminderCreate": {
+					Type:                 "object",
+					Required:             []string{"content"},
+					AdditionalProperties: &no,
+					Properties: map[string]*openapi.Schema{
+						"content":  {Type: "string", MinLength: &one},
+						"priority": priority("1 (low) to 5 (high). Defaults to 3."),
+						"due_at":   dueAt,
+					},
+				},
+				"HookReminder": {
+					Type:                 "object",
+					Required:             []string{"content"},
+					AdditionalProperties: &no,
+					Properties: map[string]*openapi.Schema{
+						"content":  {Type: "string", MinLength: &one},
+						"priority": priority("1 (low) to 5 (high). Defaults to 3."),
+						"due_at":   {Type: "string", Description: "Due date as YYYY-MM-DD or an RFC 3339 timestamp."},
+					},
+				},
+				"ReminderBatch": {
+					Type:                 "object",
+					Required:             []string{"reminders"},
+					AdditionalProperties: &no,
+					Properties: map[string]*openapi.Schema{
+						"reminders": {Type: "array", Items: openapi.Ref("ReminderCreate"), MinItems: &one, MaxItems: &maxBatch},
+					},
+				},
+				"ReminderUpdate": {
+					Type:                 "object",
+					AdditionalProperties: &no,
+					Description:          "Fields to change; omitted fields are left as they are.",
+					Properties: map[string]*openapi.Schema{
+						"content":  {Type: "string", MinLength: &one},
+						"priority": priority("1 (low) to 5 (high)."),
+						"due_at":   dueAt,
+					},
+				},
+				"Completion": {
+					Type:     "object",
+					Required: []string{"message", "reminder"},
+					Properties: map[string]*openapi.Schema{
+						"message":  {Type: "string", Description: "The same confirmation the bot would send, e.g. a streak update."},
+						"reminder": openapi.Ref("Reminder"),
+					},
+				},
+				"Insights": {
+					Type:     "object",
+					Required: []string{"open", "completed", "completed_by_weekday", "most_snoozed"},
+					Properties: map[string]*openapi.Schema{
+						"open":                     {Type: "integer", Description: "Open reminders, leaving out habits, countdowns, and birthdays."},
+						"completed":                {Type: "integer", Description: "Reminders finished in the last 90 days."},
+						"median_hours_to_complete": {Type: "number", Description: "Median hours from adding to finishing, over the last 90 days."},
+						"completed_by_weekday":     {Type: "object", Description: "Completions per weekday name, e.g. Monday, in the server's timezone."},
+						"best_day":                 {Type: "string", Description: "Weekday with the most completions, once there are at least seven."},
+						"worst_day":                {Type: "string", Description: "Weekday with the fewest completions, once there are at least seven."},
+						"most_snoozed": {Type: "array", Items: &openapi.Schema{
+							Type:     "object",
+							Required: []string{"code", "summary", "snoozes", "days_open"},
+							Properties: map[string]*openapi.Schema{
+								"code":      {Type: "string"},
+								"summary":   {Type: "string"},
+								"snoozes":   {Type: "integer", Description: "Times the due date was pushed back."},
+								"days_open": {Type: "integer"},
+							},
+						}},
+						"narrative": {Type: "string"},
+					},
+				},
+				"Me": {
+					Type:       "object",
+					Required:   []string{"user_id"},
+					Properties: map[string]*openapi.Schema{"user_id": {Type: "string"}},
+				},
+				"Error": {
+					Type:       "object",
+					Required:   []string{"error"},
+					Properties: map[string]*openapi.Schema{"error": {Type: "string"}},
+				},
+			},
+		},
+	}
+}
+
+// serveOpenAPI returns the API description. It is public so SDK generators
+// can fetch it.
+func serveOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, apiDocument())
+}
+
+// validated checks the JSON body of a request against the operation at path
+// in the API document before calling next. path is relative to the /api
+// server URL.
+func (b *Bot) validated(path string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "could not read request body"})
+			return
+		}
+		if err := apiDocument().ValidateRequest(r.Method, path, body); err != nil {
+			var invalid *openapi.ValidationError
+			if !errors.As(err, &invalid) {
+				b.writeAdminError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": invalid.Error()})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// Carrier-style opt-out keywords. They only count when sent on their own.
+var (
+	optOutKeywords = map[string]struct{}{"stop": {}, "stopall": {}, "unsubscribe": {}}
+	optInKeywords  = map[string]struct{}{"start": {}, "unstop": {}}
+)
+
+// handleOptOutCommand processes STOP/UNSUBSCRIBE and START. Opted-out users
+// get no scheduled messages until they opt back in, but still get replies to
+// their own messages. It reports false when the message is not a keyword.
+func (b *Bot) handleOptOutCommand(userID, lowerBody string) (string, bool) {
+	keyword := strings.TrimRight(strings.TrimSpace(lowerBody), ".!")
+	if _, ok := optOutKeywords[keyword]; ok {
+		now := time.Now()
+		if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+			p.OptedOutAt = &now
+		}); err != nil {
+			b.logger.Printf("opt-out %s: %v", userID, err)
+			return "I couldn't unsubscribe you. Please try again later.", true
+		}
+		b.state.PopPendingMessage(userID)
+		return "You're unsubscribed and won't get any more scheduled messages from me. Reply START to opt back in.", true
+	}
+	if _, ok := optInKeywords[keyword]; ok {
+		if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+			p.OptedOutAt = nil
+		}); err != nil {
+			b.logger.Printf("opt-in %s: %v", userID, err)
+			return "I couldn't subscribe you again. Please try again later.", true
+		}
+		return "You're subscribed again. Scheduled reminders will resume from the next run.", true
+	}
+	return "", false
+}
+
+// optedOut reports whether a user has asked not to receive scheduled messages.
+func (b *Bot) optedOut(userID string) bool {
+	return b.preferences(userID).OptedOutAt != nil
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// placeholderPattern matches a placeholder such as {weekday} in reminder text.
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// hasPlaceholders reports whether text uses any placeholder expandPlaceholders
+// knows. Such reminders keep their own words instead of an OpenAI summary,
+// which could drop or reword the placeholders.
+func hasPlaceholders(text string) bool {
+	for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		if _, ok := placeholderValue(m[1], model.Reminder{}, time.Time{}); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPlaceholders fills in the placeholders in a reminder message as of
+// now, when it is sent, so "Water plants, last done {days_since_created} days
+// ago" stays current. Unknown placeholders, and ones r has no value for, are
+// left as written.
+func expandPlaceholders(text string, r model.Reminder, now time.Time) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := match[1 : len(match)-1]
+		if value, ok := placeholderValue(name, r, now); ok {
+			return value
+		}
+		return match
+	})
+}
+
+// placeholderValue returns the value of one placeholder, and false for names
+// that aren't placeholders. It returns the placeholder itself when r has no
+// value for it.
+func placeholderValue(name string, r model.Reminder, now time.Time) (string, bool) {
+	switch name {
+	case "date":
+		return now.Format("Mon Jan 2"), true
+	case "weekday":
+		return now.Weekday().String(), true
+	case "time":
+		return now.Format("3:04pm"), true
+	case "days_since_created":
+		if r.CreatedAt.IsZero() {
+			return "{" + name + "}", true
+		}
+		return strconv.Itoa(daysUntil(now, r.CreatedAt.In(now.Location()))), true
+	case "days_until_due":
+		if r.DueAt == nil {
+			return "{" + name + "}", true
+		}
+		return strconv.Itoa(daysUntil(*r.DueAt, now)), true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// postponeAllPattern matches "push everything to tomorrow" and "postpone all
+// my reminders by 2 days", capturing the number of days or the day.
+var postponeAllPattern = regexp.MustCompile(`^(?:push|postpone|move|defer|snooze)\s+(?:everything|all(?:\s+(?:of\s+)?my)?(?:\s+reminders)?|my reminders)\s+(?:(?:by|for)\s+(\d+|a|one|two|three|four|five|six|seven)\s+(days?|weeks?)|(?:to|until|till)\s+(.+?))[.!]*$`)
+
+// handlePostponeAllCommand moves all of the user's open reminders to a later
+// day with "push everything to tomorrow" or "postpone all by 2 days". It
+// reports false for other messages.
+func (b *Bot) handlePostponeAllCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	m := postponeAllPattern.FindStringSubmatch(strings.TrimSpace(lowerBody))
+	if m == nil {
+		return "", false
+	}
+	now := time.Now().In(b.location(b.preferences(userID)))
+	var day time.Time
+	if m[3] != "" {
+		var ok bool
+		if day, ok = parseDayWord(m[3], now); !ok {
+			return fmt.Sprintf("I didn't understand '%s'. Try 'push everything to tomorrow' or 'postpone all by 2 days'.", m[3]), true
+		}
+	} else {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			n = map[string]int{"a": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7}[m[1]]
+		}
+		if strings.HasPrefix(m[2], "week") {
+			n *= 7
+		}
+		day = startOfDay(now).AddDate(0, 0, n)
+	}
+	if !day.After(startOfDay(now)) {
+		return "Pick a day after today, such as 'push everything to tomorrow'.", true
+	}
+
+	moved, dueMoved, err := b.postponeAll(ctx, userID, day)
+	if err != nil {
+		b.logger.Printf("postpone all for %s: %v", userID, err)
+		return "I couldn't move your reminders. Please try again later.", true
+	}
+	if moved == 0 {
+		return "You don't have any open reminders to move.", true
+	}
+	msg := fmt.Sprintf("Okay, I'll hold %s until %s.", plural(moved, "reminder"), describeDay(day, now))
+	switch {
+	case dueMoved == 1:
+		msg += " 1 reminder due before then is now due that day."
+	case dueMoved > 1:
+		msg += fmt.Sprintf(" %d reminders due before then are now due that day.", dueMoved)
+	}
+	return msg, true
+}
+
+// postponeAll keeps the user's open reminders out of dispatches before day
+// and moves due dates that fall before it onto it, keeping their time of
+// day. Countdowns, birthdays, and anniversaries keep their dates, and
+// reminders already held past day stay held. It reports how many reminders
+// are held and how many of those had their due date moved.
+func (b *Bot) postponeAll(ctx context.Context, userID string, day time.Time) (int, int, error) {
+	var reminders, changed []model.Reminder
+	dueMoved := 0
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND completed_at IS NULL", userID).Find(&reminders).Error; err != nil {
+			return err
+		}
+		for _, r := range reminders {
+			updates := map[string]any{}
+			if r.SnoozedUntil == nil || r.SnoozedUntil.Before(day) {
+				updates["snoozed_until"] = day
+			}
+			if r.Kind == model.KindReminder && r.DueAt != nil && r.DueAt.Before(day) {
+				due := r.DueAt.In(day.Location())
+				moved := time.Date(day.Year(), day.Month(), day.Day(), due.Hour(), due.Minute(), 0, 0, day.Location())
+				updates["due_at"] = b.workingDue(r, moved)
+				updates["snoozes"] = gorm.Expr("snoozes + 1")
+				dueMoved++
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if err := tx.Model(&r).Updates(updates).Error; err != nil {
+				return err
+			}
+			changed = append(changed, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, r := range changed {
+		b.emit(webhook.EventReminderUpdated, r, "")
+	}
+	return len(reminders), dueMoved, nil
+}
+
+// describeDay names a day after today for a reply: "tomorrow", a weekday in
+// the coming week, or a date.
+func describeDay(day, now time.Time) string {
+	switch days := daysUntil(day, now); {
+	case days <= 1:
+		return "tomorrow"
+	case days < 7:
+		return day.Format("Monday")
+	}
+	return day.Format("Mon Jan 2")
+}
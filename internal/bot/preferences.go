@@ -0,0 +1,315 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/i18n"
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// preferences returns the stored preferences for a user, or an empty record when none exist.
+func (b *Bot) preferences(userID string) model.UserPreference {
+	pref := model.UserPreference{UserID: userID}
+	if err := b.db.Where("user_id = ?", userID).First(&pref).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		b.logger.Printf("preferences: load %s: %v", userID, err)
+	}
+	return pref
+}
+
+// updatePreferences applies update to the user's preferences and saves the
+// columns it changed, so concurrent updates to other settings survive.
+func (b *Bot) updatePreferences(userID string, update func(*model.UserPreference)) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		pref := model.UserPreference{UserID: userID}
+		err := tx.Where("user_id = ?", userID).First(&pref).Error
+		found := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("load preferences for %s: %w", userID, err)
+		}
+		before := pref
+		update(&pref)
+
+		changed, err := changedColumns(tx, &before, &pref)
+		if err != nil {
+			return err
+		}
+		if found && len(changed) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns(append(changed, "updated_at")),
+		}).Create(&pref).Error
+	})
+}
+
+// changedColumns returns the columns whose values differ between before
+// and after, leaving out the primary key and update timestamps.
+func changedColumns(db *gorm.DB, before, after any) ([]string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(after); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	var changed []string
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || field.PrimaryKey || field.AutoUpdateTime != 0 {
+			continue
+		}
+		old, _ := field.ValueOf(ctx, reflect.ValueOf(before).Elem())
+		cur, _ := field.ValueOf(ctx, reflect.ValueOf(after).Elem())
+		if !reflect.DeepEqual(old, cur) {
+			changed = append(changed, field.DBName)
+		}
+	}
+	return changed, nil
+}
+
+// handleSettingsCommand processes per-user settings such as reminder spacing,
+// delivery time, quiet hours, and pausing. It reports false when the message
+// is not a settings command.
+func (b *Bot) handleSettingsCommand(userID, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	switch lowerBody = strings.TrimSuffix(strings.TrimSpace(lowerBody), "."); lowerBody {
+	case "pause reminders", "pause my reminders":
+		msg, err = b.setPaused(userID, true)
+	case "resume reminders", "resume my reminders", "unpause reminders":
+		msg, err = b.setPaused(userID, false)
+	case "call me for urgent reminders", "call alerts on", "turn on call alerts":
+		msg, err = b.setCallAlerts(userID, true)
+	case "stop calling me", "call alerts off", "turn off call alerts":
+		msg, err = b.setCallAlerts(userID, false)
+	case "tips on", "turn on tips", "send me tips", "announcements on", "subscribe to announcements":
+		msg, err = b.setTips(userID, true)
+	case "tips off", "turn off tips", "stop tips", "stop sending tips", "announcements off", "unsubscribe from announcements":
+		msg, err = b.setTips(userID, false)
+	case "reset delivery time", "clear delivery time", "clear my delivery time":
+		msg, err = b.setDeliveryHour(userID, nil)
+	case "quiet hours off", "no quiet hours", "turn off quiet hours", "clear quiet hours":
+		msg, err = b.setQuietHours(userID, nil, nil)
+	default:
+		if m := languageRequestPattern.FindStringSubmatch(lowerBody); m != nil {
+			msg, err = b.setLanguage(userID, m[1])
+			break
+		}
+		if order, ok := parseSortRequest(lowerBody); ok {
+			msg, err = b.setSortOrder(userID, order)
+			break
+		}
+		if bucket, hour, ok := parseBucketRequest(lowerBody); ok {
+			msg, err = b.setBucketHour(userID, bucket, hour)
+			break
+		}
+		if hour, ok := parseDeliveryTimeRequest(lowerBody); ok {
+			msg, err = b.setDeliveryHour(userID, &hour)
+			break
+		}
+		if first, last, ok := parseQuietHoursRequest(lowerBody); ok {
+			msg, err = b.setQuietHours(userID, &first, &last)
+			break
+		}
+		gap, ok := parseGapRequest(lowerBody)
+		if !ok {
+			return "", false
+		}
+		msg, err = b.setReminderGap(userID, gap)
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("settings command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// setPaused stops or restarts scheduled sends for a user.
+func (b *Bot) setPaused(userID string, paused bool) (string, error) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.Paused = paused
+		p.PausedByAdmin = false
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't update your reminders. Please try again later")
+	}
+	if paused {
+		return "Reminders paused. Say 'resume reminders' when you want them back.", nil
+	}
+	return "Reminders resumed. You'll get them again from the next scheduled run.", nil
+}
+
+// setCallAlerts opts a user in or out of phone calls for priority 5
+// reminders on top of the usual message.
+func (b *Bot) setCallAlerts(userID string, on bool) (string, error) {
+	if _, ok := b.channels[channel.Voice]; !ok {
+		return "Phone calls aren't set up on this bot.", nil
+	}
+	if strings.HasPrefix(userID, discordUserPrefix) {
+		return "Phone calls need a WhatsApp number. Message me on WhatsApp to turn them on.", nil
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.CallAlerts = on
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't update your call alerts. Please try again later")
+	}
+	if on {
+		return "I'll call you as well when a priority 5 reminder is due. Say 'stop calling me' to turn calls off.", nil
+	}
+	return "No more calls. Priority 5 reminders will come by message only.", nil
+}
+
+var languageRequestPattern = regexp.MustCompile(`^(?:set (?:my )?language(?: to)?|language:?|reply in|speak) (\pL+)$`)
+
+// setLanguage stores the language replies are written in.
+func (b *Bot) setLanguage(userID, name string) (string, error) {
+	language, ok := i18n.Lookup(name)
+	if !ok {
+		var names []string
+		for _, l := range i18n.Languages() {
+			names = append(names, l.Name)
+		}
+		return fmt.Sprintf("I don't speak %s yet. Try %s.", name, strings.Join(names, ", ")), nil
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.Language = language.Code
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't change your language. Please try again later")
+	}
+	return fmt.Sprintf("Language set to %s.", language.Native), nil
+}
+
+// translate renders an English reply in the user's language.
+func (b *Bot) translate(userID, text string) string {
+	return i18n.Translate(b.preferences(userID).Language, text)
+}
+
+// reminderGap returns the spacing between a user's scheduled reminders.
+func (b *Bot) reminderGap(userID string) time.Duration {
+	if pref := b.preferences(userID); pref.ReminderGapMinutes != nil {
+		return time.Duration(*pref.ReminderGapMinutes) * time.Minute
+	}
+	if tenant, err := b.userTenant(context.Background(), userID); err != nil {
+		b.logger.Printf("preferences: load tenant for %s: %v", userID, err)
+	} else if tenant != nil && tenant.ReminderGapMinutes != nil {
+		return time.Duration(*tenant.ReminderGapMinutes) * time.Minute
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.cfg.ReminderGap
+}
+
+// setReminderGap stores a per-user gap and returns a confirmation message.
+func (b *Bot) setReminderGap(userID string, gap time.Duration) (string, error) {
+	minutes := int(gap / time.Minute)
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.ReminderGapMinutes = &minutes
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't update your reminder spacing. Please try again later")
+	}
+	if minutes == 0 {
+		return "Done! I'll send all your reminders at once.", nil
+	}
+	return fmt.Sprintf("Done! I'll space your reminders %s apart.", formatGap(gap)), nil
+}
+
+var sortRequestPattern = regexp.MustCompile(`^sort (?:my )?(?:reminders |list )?by (priority|date|date added|due date|due)$`)
+
+// parseSortRequest recognises "sort by priority", "sort by date", and
+// "sort my reminders by due date".
+func parseSortRequest(body string) (string, bool) {
+	matches := sortRequestPattern.FindStringSubmatch(body)
+	if matches == nil {
+		return "", false
+	}
+	switch matches[1] {
+	case "priority":
+		return model.SortPriority, true
+	case "due date", "due":
+		return model.SortDue, true
+	default:
+		return model.SortDate, true
+	}
+}
+
+// setSortOrder stores the order used for a user's lists and daily dispatch.
+func (b *Bot) setSortOrder(userID, order string) (string, error) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.SortOrder = order
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't change your sort order. Please try again later")
+	}
+	switch order {
+	case model.SortDate:
+		return "Done! I'll list and send your reminders oldest first.", nil
+	case model.SortDue:
+		return "Done! I'll list and send your reminders by due date, soonest first.", nil
+	}
+	return "Done! I'll list and send your reminders highest priority first.", nil
+}
+
+// reminderOrder returns the ORDER BY clause for the user's chosen order.
+// Priorities are compared after aging. Ties fall back to creation order so
+// positions stay predictable.
+func (b *Bot) reminderOrder(userID string) clause.OrderBy {
+	priority, vars := b.agedPriorityExpr(time.Now())
+	switch b.preferences(userID).SortOrder {
+	case model.SortDate:
+		return orderBy("created_at ASC, id ASC")
+	case model.SortDue:
+		return orderBy("CASE WHEN due_at IS NULL THEN 1 ELSE 0 END, due_at ASC, "+priority+" DESC, created_at ASC", vars...)
+	}
+	return orderBy(priority+" DESC, created_at ASC", vars...)
+}
+
+var gapRequestPattern = regexp.MustCompile(`^send (?:my )?reminders (\d+)\s*(m|min|mins|minute|minutes|h|hr|hrs|hour|hours) apart$`)
+
+// parseGapRequest recognises "send my reminders 10 minutes apart" and
+// "send my reminders all at once".
+func parseGapRequest(body string) (time.Duration, bool) {
+	body = strings.TrimSpace(strings.TrimSuffix(body, "."))
+	switch body {
+	case "send my reminders all at once", "send reminders all at once", "send my reminders together":
+		return 0, true
+	}
+
+	matches := gapRequestPattern.FindStringSubmatch(body)
+	if len(matches) < 3 {
+		return 0, false
+	}
+	amount, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+	if strings.HasPrefix(matches[2], "h") {
+		return time.Duration(amount) * time.Hour, true
+	}
+	return time.Duration(amount) * time.Minute, true
+}
+
+func formatGap(gap time.Duration) string {
+	if gap%time.Hour == 0 {
+		hours := int(gap / time.Hour)
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+	minutes := int(gap / time.Minute)
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/i18n"
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// handleProfileCommand answers "my settings" with the user's settings, each
+// with the command that changes it. It reports false for other messages.
+func (b *Bot) handleProfileCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!?") {
+	case "my settings", "settings", "show my settings", "show settings", "my profile", "profile":
+	default:
+		return "", false
+	}
+	return b.profile(ctx, userID), true
+}
+
+// profile describes userID's settings.
+func (b *Bot) profile(ctx context.Context, userID string) string {
+	pref := b.preferences(userID)
+	loc := b.location(pref)
+	now := time.Now().In(loc)
+
+	var sb strings.Builder
+	sb.WriteString("Your settings:\n")
+	if pref.Paused {
+		sb.WriteString("- Reminders: paused. Change: 'resume reminders'\n")
+	}
+	fmt.Fprintf(&sb, "- Timezone: %s (%s there). Change: 'set timezone Europe/London'\n", zoneName(loc), now.Format("3:04pm"))
+
+	delivery := "when the daily run starts"
+	if run, ok := b.dispatchTime(now); ok {
+		delivery = "at " + run.Format("3:04pm") + ", when the daily run starts"
+		if pref.DeliveryHour != nil && *pref.DeliveryHour > run.Hour() {
+			delivery = "from " + formatHour(*pref.DeliveryHour)
+		}
+	}
+	fmt.Fprintf(&sb, "- Delivery time: %s. Change: 'set delivery time 6pm'\n", delivery)
+
+	fmt.Fprintf(&sb, "- Language: %s. Change: 'set language Spanish'\n", i18n.ByCode(pref.Language).Name)
+
+	switch {
+	case pref.Email == "":
+		sb.WriteString("- Digest: off, reminders come as WhatsApp messages. Change: 'my email is you@example.com'\n")
+	case pref.Delivery == model.DeliveryEmail:
+		fmt.Fprintf(&sb, "- Digest: a daily email to %s instead of WhatsApp messages. Change: 'send my reminders by whatsapp'\n", pref.Email)
+	case pref.Delivery == model.DeliveryBoth:
+		fmt.Fprintf(&sb, "- Digest: a daily email to %s as well as WhatsApp messages. Change: 'send my reminders by whatsapp'\n", pref.Email)
+	default:
+		fmt.Fprintf(&sb, "- Digest: off, reminders come as WhatsApp messages. Change: 'send my reminders by email' for %s\n", pref.Email)
+	}
+
+	if pref.QuietStart != nil && pref.QuietEnd != nil {
+		fmt.Fprintf(&sb, "- Quiet hours: %s to %s. Change: 'quiet hours 11pm to 6am' or 'quiet hours off'\n", formatHour(*pref.QuietStart), formatHour(*pref.QuietEnd))
+	} else {
+		sb.WriteString("- Quiet hours: off. Change: 'quiet hours 10pm to 7am'\n")
+	}
+
+	if pref.Tips {
+		sb.WriteString("- Tips and announcements: on. Change: 'tips off'\n")
+	} else {
+		sb.WriteString("- Tips and announcements: off. Change: 'tips on'\n")
+	}
+
+	if pref.MorningBriefing {
+		fmt.Fprintf(&sb, "- Morning briefing: on, at %s. Change: 'morning briefing off'\n", formatHour(bucketHour(pref, model.TimeMorning)))
+	} else {
+		sb.WriteString("- Morning briefing: off. Change: 'morning briefing on'\n")
+	}
+
+	if pref.CalendarInvites {
+		sb.WriteString("- Calendar invites: on. Change: 'calendar invites off'\n")
+	} else {
+		sb.WriteString("- Calendar invites: off. Change: 'calendar invites on'\n")
+	}
+
+	if quota := b.quotaUsage(ctx, userID, now); quota != "" {
+		fmt.Fprintf(&sb, "- %s\n", quota)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// quotaUsage describes how many of their tenant's monthly OpenAI calls the
+// user's workspace has made, or returns "" when there is no limit.
+func (b *Bot) quotaUsage(ctx context.Context, userID string, now time.Time) string {
+	tenant, err := b.userTenant(ctx, userID)
+	if err != nil {
+		b.logger.Printf("profile: load tenant for %s: %v", userID, err)
+		return ""
+	}
+	if tenant == nil || tenant.OpenAIMonthlyLimit <= 0 {
+		return ""
+	}
+	var usage model.OpenAIUsage
+	err = b.db.WithContext(ctx).
+		Where("tenant_id = ? AND month = ?", tenant.ID, now.In(b.cfg.LocalTimezone).Format("2006-01")).
+		Limit(1).
+		Find(&usage).Error
+	if err != nil {
+		b.logger.Printf("profile: load openai usage for %s: %v", tenant.Name, err)
+		return ""
+	}
+	return fmt.Sprintf("Smart features this month: %d of %d requests used by %s", usage.Calls, tenant.OpenAIMonthlyLimit, tenant.Name)
+}
@@ -0,0 +1,297 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+// defaultProjectPriority is the priority of a reminder added with "project
+// kitchen: buy tiles".
+const defaultProjectPriority = 3
+
+var (
+	newProjectPattern        = regexp.MustCompile(`(?i)^(?:new\s+)?project\s*:\s*(.+?)[.!]*$`)
+	projectReminderPattern   = regexp.MustCompile(`(?i)^project\s+([^:]+?)\s*:\s*(.+?)$`)
+	addToProjectPattern      = regexp.MustCompile(`(?i)^(?:add|move|put)\s+(r?\d+)\s+(?:to|into|in)\s+(?:the\s+)?(?:project\s+(.+?)|(.+?)\s+project)[.!]*$`)
+	removeFromProjectPattern = regexp.MustCompile(`(?i)^(?:remove|take)\s+(r?\d+)\s+(?:out\s+)?(?:of|from)\s+(?:the\s+|its\s+)?(?:project\s+(.+?)|(.+?)\s+project|project)[.!]*$`)
+	showProjectPattern       = regexp.MustCompile(`(?i)^(?:list|show|view)\s+(?:my\s+|the\s+)?(?:project\s+(.+?)|(.+?)\s+project)[.!?]*$`)
+	deleteProjectPattern     = regexp.MustCompile(`(?i)^(?:delete|remove|close)\s+(?:my\s+|the\s+)?(?:project\s+(.+?)|(.+?)\s+project)[.!]*$`)
+)
+
+// projectProgress is how far along one project is.
+type projectProgress struct {
+	Name  string
+	Total int
+	Done  int
+	// DoneSince counts the reminders finished since the time asked about.
+	DoneSince int
+}
+
+// handleProjectCommand starts projects with "project: kitchen renovation",
+// adds reminders to them, and lists them with their progress. It reports
+// false for other messages.
+func (b *Bot) handleProjectCommand(ctx context.Context, userID, body, lowerBody string) (string, bool) {
+	body = strings.TrimSpace(body)
+	var (
+		msg string
+		err error
+	)
+	switch lower := strings.Trim(strings.TrimSpace(lowerBody), ".!?"); {
+	case lower == "projects" || lower == "my projects" || lower == "show my projects" || lower == "list projects":
+		msg, err = b.listProjects(ctx, userID)
+	case newProjectPattern.MatchString(body):
+		msg, err = b.createProject(ctx, userID, newProjectPattern.FindStringSubmatch(body)[1])
+	case projectReminderPattern.MatchString(body):
+		m := projectReminderPattern.FindStringSubmatch(body)
+		// "Project update: send the slides" is a reminder like any other
+		// unless the user has a project by that name.
+		if !b.userHas(&model.Project{}, "user_id = ? AND name LIKE ?", userID, normalizeListName(m[1])+"%") {
+			return "", false
+		}
+		msg, err = b.addProjectReminder(ctx, userID, m[1], m[2])
+	case addToProjectPattern.MatchString(body):
+		m := addToProjectPattern.FindStringSubmatch(body)
+		msg, err = b.moveToProject(ctx, userID, m[1], fallback(m[2], m[3]))
+	case removeFromProjectPattern.MatchString(body):
+		msg, err = b.removeFromProject(ctx, userID, removeFromProjectPattern.FindStringSubmatch(body)[1])
+	case showProjectPattern.MatchString(body):
+		m := showProjectPattern.FindStringSubmatch(body)
+		msg, err = b.showProject(ctx, userID, fallback(m[1], m[2]))
+	case deleteProjectPattern.MatchString(body):
+		m := deleteProjectPattern.FindStringSubmatch(body)
+		msg, err = b.deleteProject(ctx, userID, fallback(m[1], m[2]))
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("project command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// createProject starts a project called name.
+func (b *Bot) createProject(ctx context.Context, userID, name string) (string, error) {
+	name = normalizeListName(name)
+	var existing int64
+	if err := b.db.WithContext(ctx).Model(&model.Project{}).Where("user_id = ? AND name = ?", userID, name).Count(&existing).Error; err != nil {
+		return "", fmt.Errorf("I couldn't start that project. Please try again later")
+	}
+	if existing > 0 {
+		return "", userError{fmt.Sprintf("You already have a project called %s.", name)}
+	}
+	if err := b.db.WithContext(ctx).Create(&model.Project{UserID: userID, Name: name}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't start that project. Please try again later")
+	}
+	short := strings.Fields(name)[0]
+	return fmt.Sprintf("Started the %s project. Add reminders with 'project %s: buy tiles' or 'add R3 to project %s', and see them with 'list project %s'.", name, short, short, short), nil
+}
+
+// findProject loads the user's project called name, or the only one whose
+// name starts with it, so "kitchen" finds "kitchen renovation".
+func (b *Bot) findProject(ctx context.Context, userID, name string) (model.Project, error) {
+	name = normalizeListName(name)
+	var project model.Project
+	err := b.db.WithContext(ctx).Where("user_id = ? AND name = ?", userID, name).First(&project).Error
+	if err == nil {
+		return project, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return project, fmt.Errorf("I couldn't load that project. Please try again later")
+	}
+
+	var matches []model.Project
+	if err := b.db.WithContext(ctx).Where("user_id = ? AND name LIKE ?", userID, name+"%").Order("name ASC").Find(&matches).Error; err != nil {
+		return project, fmt.Errorf("I couldn't load that project. Please try again later")
+	}
+	switch len(matches) {
+	case 0:
+		return project, userError{fmt.Sprintf("You don't have a project called %s. Start one with 'project: %s'.", name, name)}
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, p := range matches {
+		names[i] = p.Name
+	}
+	return project, userError{fmt.Sprintf("Several projects start with %s: %s. Use the full name.", name, strings.Join(names, ", "))}
+}
+
+// addProjectReminder saves content as a new reminder in the named project.
+func (b *Bot) addProjectReminder(ctx context.Context, userID, name, content string) (string, error) {
+	project, err := b.findProject(ctx, userID, name)
+	if err != nil {
+		return "", err
+	}
+	reminder := &model.Reminder{
+		UserID:    userID,
+		Content:   content,
+		Priority:  defaultProjectPriority,
+		Summary:   b.summarizeReminderWithOpenAI(ctx, userID, content),
+		ProjectID: &project.ID,
+	}
+	if err := b.insertReminder(ctx, reminder); err != nil {
+		return "", fmt.Errorf("I couldn't save the reminder. Please try again")
+	}
+	return fmt.Sprintf("Added %s %s to the %s project (priority %d).", reminder.ShortID(), reminder.Summary, project.Name, reminder.Priority), nil
+}
+
+// moveToProject puts an existing reminder in the named project.
+func (b *Bot) moveToProject(ctx context.Context, userID, selector, name string) (string, error) {
+	reminder, err := b.reminderBySelector(ctx, userID, selector)
+	if err != nil {
+		return "", err
+	}
+	project, err := b.findProject(ctx, userID, name)
+	if err != nil {
+		return "", err
+	}
+	if err := b.db.WithContext(ctx).Model(reminder).Update("project_id", project.ID).Error; err != nil {
+		return "", fmt.Errorf("I couldn't move that reminder. Please try again later")
+	}
+	return fmt.Sprintf("Added %s %s to the %s project.", reminder.ShortID(), fallback(reminder.Summary, reminder.Content), project.Name), nil
+}
+
+// removeFromProject takes a reminder out of its project.
+func (b *Bot) removeFromProject(ctx context.Context, userID, selector string) (string, error) {
+	reminder, err := b.reminderBySelector(ctx, userID, selector)
+	if err != nil {
+		return "", err
+	}
+	if reminder.ProjectID == nil {
+		return "", userError{fmt.Sprintf("%s isn't in a project.", reminder.ShortID())}
+	}
+	if err := b.db.WithContext(ctx).Model(reminder).Update("project_id", nil).Error; err != nil {
+		return "", fmt.Errorf("I couldn't update that reminder. Please try again later")
+	}
+	return fmt.Sprintf("Took %s %s out of its project.", reminder.ShortID(), fallback(reminder.Summary, reminder.Content)), nil
+}
+
+// showProject lists a project's reminders, open ones numbered by their place
+// in the full list so "done 3" works on the result, then finished ones.
+func (b *Bot) showProject(ctx context.Context, userID, name string) (string, error) {
+	project, err := b.findProject(ctx, userID, name)
+	if err != nil {
+		return "", err
+	}
+	var reminders []model.Reminder
+	if err := b.db.WithContext(ctx).Where("user_id = ? AND project_id = ?", userID, project.ID).Order("completed_at IS NOT NULL, code ASC").Find(&reminders).Error; err != nil {
+		return "", fmt.Errorf("I couldn't load that project. Please try again later")
+	}
+	if len(reminders) == 0 {
+		return fmt.Sprintf("The %s project has no reminders yet. Add one with 'project %s: ...'.", project.Name, project.Name), nil
+	}
+	open, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load that project. Please try again later")
+	}
+	position := make(map[uint]int, len(open))
+	for i, r := range open {
+		position[r.ID] = i + 1
+	}
+
+	done := 0
+	for _, r := range reminders {
+		if r.CompletedAt != nil {
+			done++
+		}
+	}
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Project %s: %d/%d done\n", bold(project.Name), done, len(reminders))
+	for _, r := range reminders {
+		text := fallback(r.Summary, r.Content)
+		switch {
+		case r.CompletedAt != nil:
+			fmt.Fprintf(&sb, "✓ %s %s\n", r.ShortID(), strike(text))
+		case position[r.ID] > 0:
+			fmt.Fprintf(&sb, "%d. %s [%s] %s\n", position[r.ID], r.ShortID(), b.priorityLabel(r, now), text)
+		default:
+			// Waiting on another reminder, so not in the numbered list.
+			fmt.Fprintf(&sb, "- %s [%d] %s\n", r.ShortID(), r.Priority, text)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// listProjects lists the user's projects with their progress.
+func (b *Bot) listProjects(ctx context.Context, userID string) (string, error) {
+	progress, err := b.projectProgress(ctx, userID, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your projects. Please try again later")
+	}
+	if len(progress) == 0 {
+		return "You don't have any projects yet. Start one with 'project: kitchen renovation'.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("Your projects:\n")
+	for _, p := range progress {
+		fmt.Fprintf(&sb, "- %s: %d/%d done\n", p.Name, p.Done, p.Total)
+	}
+	sb.WriteString("Say 'list project <name>' to see one.")
+	return sb.String(), nil
+}
+
+// deleteProject removes a project. Its reminders stay, outside any project.
+func (b *Bot) deleteProject(ctx context.Context, userID, name string) (string, error) {
+	project, err := b.findProject(ctx, userID, name)
+	if err != nil {
+		return "", err
+	}
+	err = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Reminder{}).Where("project_id = ?", project.ID).Update("project_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&project).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("I couldn't delete that project. Please try again later")
+	}
+	return fmt.Sprintf("Deleted the %s project. Its reminders are still saved.", project.Name), nil
+}
+
+// projectProgress counts each of the user's projects' reminders, how many
+// are done, and how many were finished since since, in name order.
+func (b *Bot) projectProgress(ctx context.Context, userID string, since time.Time) ([]projectProgress, error) {
+	var projects []model.Project
+	if err := b.db.WithContext(ctx).Where("user_id = ?", userID).Order("name ASC").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		return nil, nil
+	}
+	var reminders []model.Reminder
+	if err := b.db.WithContext(ctx).Select("project_id", "completed_at").Where("user_id = ? AND project_id IS NOT NULL", userID).Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	progress := make([]projectProgress, len(projects))
+	index := make(map[uint]int, len(projects))
+	for i, p := range projects {
+		progress[i].Name = p.Name
+		index[p.ID] = i
+	}
+	for _, r := range reminders {
+		i, ok := index[*r.ProjectID]
+		if !ok {
+			continue
+		}
+		progress[i].Total++
+		if r.CompletedAt != nil {
+			progress[i].Done++
+			if !r.CompletedAt.Before(since) {
+				progress[i].DoneSince++
+			}
+		}
+	}
+	return progress, nil
+}
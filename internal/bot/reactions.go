@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// Quick actions a reaction or quoted reply to a reminder message can take.
+const (
+	reactionDone   = "done"
+	reactionSnooze = "snooze"
+	reactionDelete = "delete"
+)
+
+// reactionActions maps the emoji a user can react to a reminder message with
+// onto the action it takes.
+var reactionActions = map[string]string{
+	"👍": reactionDone,
+	"✅": reactionDone,
+	"🔁": reactionSnooze,
+	"❌": reactionDelete,
+}
+
+// parseReaction returns the action for a message that is only one of the
+// reaction emoji, in any skin tone.
+func parseReaction(body string) (string, bool) {
+	emoji := strings.Map(func(r rune) rune {
+		// Drop the emoji presentation selector and skin tone modifiers.
+		if r == '\uFE0F' || (r >= 0x1F3FB && r <= 0x1F3FF) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(body))
+	action, ok := reactionActions[emoji]
+	return action, ok
+}
+
+// quotedActionPattern matches a reply quoting a reminder message that asks
+// for one of the quick actions, such as "done" or "delete this".
+var quotedActionPattern = regexp.MustCompile(`^(?:(done|finished|did it|mark (?:this|it) (?:as )?done)|(snooze|snooze (?:this|it)|later|tomorrow)|(delete|delete (?:this|it)|remove (?:this|it)|cancel (?:this|it)))[.!]*$`)
+
+// handleReaction applies a reaction to a reminder message: 👍 marks the
+// reminder done, 🔁 snoozes it for a day, and ❌ deletes it. Twilio delivers
+// a reaction as a message whose body is the emoji and whose
+// OriginalRepliedMessageSid names the message reacted to. A bare emoji with
+// no such ID applies to the last reminder sent to the user, unless the bot
+// is waiting on an answer. It reports false for other messages.
+func (b *Bot) handleReaction(ctx context.Context, userID, body, repliedSID string) (string, bool) {
+	action, ok := parseReaction(body)
+	if !ok {
+		return "", false
+	}
+	if _, pending := b.state.Peek(userID); pending && repliedSID == "" {
+		return "", false
+	}
+	delivery, ok := b.reactedDelivery(ctx, userID, repliedSID)
+	if !ok {
+		if repliedSID == "" {
+			return "", false
+		}
+		return "I can only act on reactions to reminder messages.", true
+	}
+	return b.quickAction(ctx, userID, delivery.ReminderID, action), true
+}
+
+// handleQuotedReply applies "done", "snooze", or "delete this" sent as a
+// reply quoting a reminder message to exactly that reminder. It reports
+// false for other messages, and for replies quoting anything else, which go
+// through the usual handlers.
+func (b *Bot) handleQuotedReply(ctx context.Context, userID, lowerBody, repliedSID string) (string, bool) {
+	if repliedSID == "" {
+		return "", false
+	}
+	m := quotedActionPattern.FindStringSubmatch(strings.TrimSpace(lowerBody))
+	if m == nil {
+		return "", false
+	}
+	delivery, ok := b.reactedDelivery(ctx, userID, repliedSID)
+	if !ok {
+		return "", false
+	}
+	action := reactionDelete
+	switch {
+	case m[1] != "":
+		action = reactionDone
+	case m[2] != "":
+		action = reactionSnooze
+	}
+	return b.quickAction(ctx, userID, delivery.ReminderID, action), true
+}
+
+// quickAction marks the reminder done, snoozes it, or deletes it, and
+// returns the reply for the user.
+func (b *Bot) quickAction(ctx context.Context, userID string, reminderID uint, action string) string {
+	var r model.Reminder
+	if err := b.db.WithContext(ctx).Where("id = ? AND user_id = ? AND completed_at IS NULL", reminderID, userID).First(&r).Error; err != nil {
+		return "That reminder is already done or gone."
+	}
+	now := time.Now().In(b.cfg.LocalTimezone)
+	switch action {
+	case reactionDone:
+		msg, err := b.completeReminder(ctx, &r, now)
+		if err != nil {
+			if !isUserError(err) {
+				b.logger.Printf("quick action done %s: %v", r.ShortID(), err)
+			}
+			return err.Error()
+		}
+		return msg
+	case reactionSnooze:
+		return b.snoozeReminder(ctx, r, now)
+	default:
+		deleted, err := b.removeReminders(ctx, b.db.Where("id = ? AND user_id = ?", r.ID, userID))
+		if err != nil {
+			b.logger.Printf("quick action delete %s: %v", r.ShortID(), err)
+			return "I couldn't delete that reminder. Please try again later."
+		}
+		if len(deleted) == 0 {
+			return "That reminder is already done or gone."
+		}
+		return fmt.Sprintf("Deleted '%s'.", fallback(r.Summary, r.Content))
+	}
+}
+
+// reactedDelivery finds the reminder message a reaction or reply is about:
+// the one with Twilio ID sid, or without one the last WhatsApp reminder
+// message sent to the user in the follow-up window.
+func (b *Bot) reactedDelivery(ctx context.Context, userID, sid string) (model.ReminderDelivery, bool) {
+	query := b.db.WithContext(ctx).Where("user_id = ?", userID)
+	if sid != "" {
+		query = query.Where("message_sid = ?", sid)
+	} else {
+		query = query.Where("channel = ? AND created_at > ?", channel.WhatsApp, time.Now().Add(-followUpWindow))
+	}
+	var delivery model.ReminderDelivery
+	err := query.Order("id DESC").Limit(1).Find(&delivery).Error
+	if err != nil {
+		b.logger.Printf("reaction for %s: %v", userID, err)
+	}
+	return delivery, err == nil && delivery.ID != 0
+}
+
+// snoozeReminder keeps r out of dispatches until tomorrow, moving its due
+// date on to tomorrow if it falls before then.
+func (b *Bot) snoozeReminder(ctx context.Context, r model.Reminder, now time.Time) string {
+	tomorrow := startOfDay(now).AddDate(0, 0, 1)
+	updates := map[string]any{"snoozed_until": tomorrow}
+	if r.DueAt != nil && r.DueAt.Before(tomorrow) {
+		updates["due_at"] = b.workingDue(r, tomorrow)
+		updates["snoozes"] = gorm.Expr("snoozes + 1")
+	}
+	if err := b.db.WithContext(ctx).Model(&r).Updates(updates).Error; err != nil {
+		b.logger.Printf("snooze %s: %v", r.ShortID(), err)
+		return "I couldn't snooze that reminder. Please try again later."
+	}
+	b.emit(webhook.EventReminderUpdated, r, "")
+	return fmt.Sprintf("Snoozed '%s' until tomorrow.", fallback(r.Summary, r.Content))
+}
@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+// priorityChange is a new priority proposed for a reminder, waiting for the
+// user to confirm it.
+type priorityChange struct {
+	ReminderID uint
+	Priority   int
+}
+
+// handleRebalanceCommand answers "rebalance" with new priorities for the
+// user's open reminders, which are applied only once the user says yes. It
+// reports false for other messages.
+func (b *Bot) handleRebalanceCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!") {
+	case "rebalance", "rebalance priorities", "rebalance my priorities", "rebalance my reminders":
+	default:
+		return "", false
+	}
+	msg, err := b.proposeRebalance(ctx, userID)
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("rebalance for %s: %v", userID, err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// proposeRebalance asks OpenAI for new priorities and offers them.
+func (b *Bot) proposeRebalance(ctx context.Context, userID string) (string, error) {
+	open, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your reminders. Please try again later")
+	}
+	if len(open) < 2 {
+		return "", userError{"You need at least two open reminders to rebalance."}
+	}
+	if b.openAI == nil || !b.useOpenAI(ctx, userID) {
+		return "", userError{"Rebalancing needs OpenAI, which isn't set up. Say 'set R3 priority 2' to change one yourself."}
+	}
+
+	now := time.Now().In(b.cfg.LocalTimezone)
+	described := make([]string, len(open))
+	for i, r := range open {
+		due := ""
+		if r.DueAt != nil {
+			due = ", due " + r.DueAt.In(now.Location()).Format("Mon Jan 2")
+		}
+		described[i] = fmt.Sprintf("%s (priority %d%s): %s", r.ShortID(), r.Priority, due, fallback(r.Summary, r.Content))
+	}
+	proposed, err := b.openAI.RebalancePriorities(ctx, described)
+	if err != nil {
+		if errors.Is(err, myopenai.ErrClientNotInitialised) {
+			return "", userError{"Rebalancing needs OpenAI, which isn't set up. Say 'set R3 priority 2' to change one yourself."}
+		}
+		return "", fmt.Errorf("I couldn't work out new priorities right now. Please try again later")
+	}
+
+	changes, lines := rebalanceChanges(open, proposed)
+	if len(changes) == 0 {
+		return "Your priorities already look balanced. Nothing to change.", nil
+	}
+	b.state.SetRebalance(userID, changes)
+	return fmt.Sprintf("Here's how I'd rebalance your priorities:\n%s\nReply yes to apply these, or no to keep things as they are.", strings.Join(lines, "\n")), nil
+}
+
+// rebalanceChanges keeps the proposed changes that name one of open by its
+// code and actually change a valid priority, and describes each one.
+func rebalanceChanges(open []model.Reminder, proposed []myopenai.PriorityChange) ([]priorityChange, []string) {
+	byCode := make(map[string]model.Reminder, len(open))
+	for _, r := range open {
+		byCode[r.ShortID()] = r
+	}
+	var (
+		changes []priorityChange
+		lines   []string
+		seen    = make(map[uint]bool)
+	)
+	for _, p := range proposed {
+		r, ok := byCode[strings.ToUpper(strings.TrimSpace(p.Code))]
+		if !ok || seen[r.ID] || p.Priority < 1 || p.Priority > maxPriority || p.Priority == r.Priority {
+			continue
+		}
+		seen[r.ID] = true
+		changes = append(changes, priorityChange{ReminderID: r.ID, Priority: p.Priority})
+		line := fmt.Sprintf("- %s %s: %d → %d", r.ShortID(), truncate(fallback(r.Summary, r.Content), 40), r.Priority, p.Priority)
+		if reason := strings.TrimSpace(p.Reason); reason != "" {
+			line += " (" + strings.TrimSuffix(reason, ".") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return changes, lines
+}
+
+// handleRebalanceReply applies or drops proposed priorities. Any reply other
+// than yes or no is handled as a new message.
+func (b *Bot) handleRebalanceReply(ctx context.Context, userID, lowerBody string) (string, bool) {
+	changes, ok := b.state.PopRebalance(userID)
+	if !ok {
+		return "", false
+	}
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!") {
+	case "yes", "yes please", "y", "yep", "ok", "okay", "sure", "apply", "do it":
+	case "no", "no thanks", "n", "nope", "leave it", "keep them":
+		return "Okay, your priorities stay as they are.", true
+	default:
+		return "", false
+	}
+
+	applied := 0
+	for _, c := range changes {
+		var r model.Reminder
+		if err := b.db.WithContext(ctx).Where("id = ? AND user_id = ? AND completed_at IS NULL", c.ReminderID, userID).First(&r).Error; err != nil {
+			continue
+		}
+		if err := b.db.WithContext(ctx).Model(&r).Update("priority", c.Priority).Error; err != nil {
+			b.logger.Printf("rebalance %s: %v", r.ShortID(), err)
+			continue
+		}
+		b.emit(webhook.EventReminderUpdated, r, "")
+		applied++
+	}
+	if applied == 0 {
+		return "Those reminders are already done or gone, so nothing changed.", true
+	}
+	return fmt.Sprintf("Done, I changed the priority of %s.", plural(applied, "reminder")), true
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordJobRun stores the time a named job last completed.
+func (b *Bot) recordJobRun(name string, at time.Time) {
+	run := model.JobRun{Name: name, LastRunAt: at}
+	if err := b.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&run).Error; err != nil {
+		b.logger.Printf("scheduler: record %s run: %v", name, err)
+	}
+}
+
+// recoverMissedJobs runs any job whose most recent occurrence today passed
+// without a recorded run. Jobs with no run history are baselined instead so
+// a fresh install doesn't fire everything on first start.
+func (b *Bot) recoverMissedJobs(now time.Time) {
+	for _, entry := range b.cron.Entries() {
+		name := b.jobName(entry.ID)
+		due := lastOccurrenceToday(entry.Schedule, now)
+		if due.IsZero() {
+			continue
+		}
+
+		var run model.JobRun
+		err := b.db.Where("name = ?", name).First(&run).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			b.recordJobRun(name, now)
+			continue
+		}
+		if err != nil {
+			b.logger.Printf("scheduler: load %s run: %v", name, err)
+			continue
+		}
+		if run.LastRunAt.Before(due) {
+			b.logger.Printf("scheduler: %s missed its %s run, catching up", name, due.Format(time.Kitchen))
+			entry.Job.Run()
+		}
+	}
+}
+
+// lastOccurrenceToday returns the latest time at or before now, on now's
+// calendar day, at which schedule fires. It returns the zero time when the
+// schedule has not fired yet today.
+func lastOccurrenceToday(schedule cron.Schedule, now time.Time) time.Time {
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+
+	var last time.Time
+	for next := schedule.Next(midnight.Add(-time.Second)); !next.After(now); next = schedule.Next(next) {
+		last = next
+	}
+	return last
+}
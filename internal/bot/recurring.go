@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"github.com/pathakanu/myMemo/internal/recurrence"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+// repeatHintPattern spots reminders that may repeat, so only those cost an
+// OpenAI call.
+var repeatHintPattern = regexp.MustCompile(`(?i)\b(?:every|each|daily|weekly|fortnightly|monthly|yearly|annually)\b`)
+
+// recurrenceRule works out how often content repeats. OpenAI reads it when
+// the user may use it; otherwise, or when its answer doesn't parse, only the
+// plainest phrases such as "every 3 days" are understood.
+func (b *Bot) recurrenceRule(ctx context.Context, userID, content string) (recurrence.Rule, bool) {
+	if !repeatHintPattern.MatchString(content) {
+		return recurrence.Rule{}, false
+	}
+	if b.openAI != nil && b.useOpenAI(ctx, userID) {
+		text, err := b.openAI.ParseRecurrence(ctx, content)
+		switch {
+		case err == nil && text == "":
+			return recurrence.Rule{}, false
+		case err == nil:
+			rule, err := recurrence.Parse(text)
+			if err == nil {
+				return rule, true
+			}
+			b.logger.Printf("recurrence: unusable rule %q: %v", text, err)
+		case !errors.Is(err, myopenai.ErrClientNotInitialised):
+			b.logger.Printf("openai recurrence error: %v", err)
+		}
+	}
+	return recurrence.FromText(content)
+}
+
+// firstOccurrence returns the first day, from now's on, that rule falls on.
+func firstOccurrence(rule recurrence.Rule, now time.Time) time.Time {
+	today := startOfDay(now)
+	return rule.Next(today, today.AddDate(0, 0, -1))
+}
+
+// scheduledOccurrence returns the occurrence a repeating reminder's rule
+// counts the next one from: the day the rule fell on, not the working day
+// DueAt may have moved to.
+func scheduledOccurrence(r model.Reminder) *time.Time {
+	if r.OccursAt != nil {
+		return r.OccursAt
+	}
+	return r.DueAt
+}
+
+// occursAt returns the OccursAt to store for an occurrence delivered on due,
+// nil when it wasn't moved.
+func occursAt(occurrence, due time.Time) *time.Time {
+	if due.Equal(occurrence) {
+		return nil
+	}
+	return &occurrence
+}
+
+// dueBy reports whether a repeating reminder's next occurrence is on or
+// before now's day.
+func dueBy(r model.Reminder, now time.Time) bool {
+	return r.DueAt == nil || r.DueAt.Before(startOfDay(now).AddDate(0, 0, 1))
+}
+
+// rollRecurring moves the user's repeating reminders whose day passed
+// without being done on to their next occurrence from today. Occurrences
+// held by a blackout that ended yesterday stay put so they go out today.
+func (b *Bot) rollRecurring(ctx context.Context, userID string, now time.Time) {
+	cutoff := startOfDay(now)
+	if held := b.heldSince(ctx, userID, now); !held.IsZero() {
+		cutoff = held
+	}
+	var passed []model.Reminder
+	err := b.db.WithContext(ctx).
+		Where("user_id = ? AND rrule <> '' AND completed_at IS NULL AND due_at < ?", userID, cutoff).
+		Find(&passed).Error
+	if err != nil {
+		b.logger.Printf("scheduler: load repeating reminders for %s: %v", userID, err)
+		return
+	}
+	for _, r := range passed {
+		rule, err := recurrence.Parse(r.RRule)
+		if err != nil {
+			b.logger.Printf("scheduler: %s: %v", r.ShortID(), err)
+			continue
+		}
+		next := rule.Next(scheduledOccurrence(r).In(now.Location()), now.AddDate(0, 0, -1))
+		if next.IsZero() {
+			continue
+		}
+		due := b.workingDue(r, next)
+		if err := b.db.WithContext(ctx).Model(&r).Updates(map[string]any{"due_at": due, "occurs_at": occursAt(next, due)}).Error; err != nil {
+			b.logger.Printf("scheduler: move %s to its next occurrence: %v", r.ShortID(), err)
+		}
+	}
+}
+
+// completeOccurrence marks the current occurrence of a repeating reminder
+// done by moving it on to the one after.
+func (b *Bot) completeOccurrence(ctx context.Context, reminder *model.Reminder, now time.Time) (string, error) {
+	text := fallback(reminder.Summary, reminder.Content)
+	rule, err := recurrence.Parse(reminder.RRule)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't work out when %s repeats", reminder.ShortID())
+	}
+	anchor, after := startOfDay(now), now
+	if reminder.DueAt != nil {
+		anchor = scheduledOccurrence(*reminder).In(now.Location())
+		after = later(now, reminder.DueAt.In(now.Location()))
+	}
+	next := rule.Next(anchor, after)
+	if next.IsZero() {
+		return "", userError{fmt.Sprintf("%s has no more dates coming up. Say 'delete %s' to remove it.", text, reminder.ShortID())}
+	}
+	due := b.workingDue(*reminder, next)
+	if err := b.db.WithContext(ctx).Model(reminder).Updates(map[string]any{"due_at": due, "occurs_at": occursAt(next, due)}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't update that reminder. Please try again later")
+	}
+	b.emit(webhook.EventReminderCompleted, *reminder, "")
+	return fmt.Sprintf("Marked '%s' as done. It repeats %s, next on %s.", text, rule.Describe(), due.Format("Mon Jan 2")), nil
+}
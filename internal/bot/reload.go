@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/config"
+	"github.com/pathakanu/myMemo/internal/database"
+	"github.com/robfig/cron/v3"
+)
+
+// Reload applies the settings that can change without a restart: the daily
+// dispatch schedule, the default reminder gap, priority aging, the database
+// log level, request logging, and the holiday calendar.
+// Other changes in cfg are ignored until the next start. Pending sends and
+// the rest of the scheduler are left alone.
+func (b *Bot) Reload(cfg *config.Config) error {
+	spec := cfg.DispatchSchedule
+	if spec == "" {
+		spec = dailyDispatchSpec
+	}
+	if _, err := cron.ParseStandard(spec); err != nil {
+		return fmt.Errorf("DISPATCH_SCHEDULE: %w", err)
+	}
+	if _, err := database.ParseLogLevel(cfg.LogLevel); err != nil {
+		return fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	if _, err := config.ParsePriorityAging(cfg.PriorityAging); err != nil {
+		return fmt.Errorf("PRIORITY_AGING_DAYS: %w", err)
+	}
+	if _, err := config.ParseCategories(cfg.ReminderCategories); err != nil {
+		return fmt.Errorf("REMINDER_CATEGORIES: %w", err)
+	}
+
+	if _, err := newCalendar(cfg.HolidayCountry, cfg.HolidayDates); err != nil {
+		return fmt.Errorf("HOLIDAY_COUNTRY or HOLIDAY_DATES: %w", err)
+	}
+
+	if cfg.ModerateContent && b.cfg.OpenAIDeployment != "" {
+		return fmt.Errorf("OPENAI_MODERATION: not available with Azure OpenAI")
+	}
+
+	b.mu.Lock()
+	oldSpec := b.dispatchSpecLocked()
+	oldLevel := b.cfg.LogLevel
+	b.cfg.ReminderGap = cfg.ReminderGap
+	b.cfg.DispatchSchedule = cfg.DispatchSchedule
+	b.cfg.PriorityAging = cfg.PriorityAging
+	b.cfg.ReminderCategories = cfg.ReminderCategories
+	b.cfg.ModerateContent = cfg.ModerateContent
+	b.cfg.LogLevel = cfg.LogLevel
+	b.cfg.RequestLogging = cfg.RequestLogging
+	b.cfg.RequestLogPayloadPct = cfg.RequestLogPayloadPct
+	b.cfg.HolidayCountry = cfg.HolidayCountry
+	b.cfg.HolidayDates = cfg.HolidayDates
+	b.mu.Unlock()
+
+	if cfg.LogLevel != oldLevel {
+		if err := database.SetLogLevel(b.db, cfg.LogLevel); err != nil {
+			return err
+		}
+	}
+	if spec != oldSpec && b.cron != nil {
+		b.removeJobs("daily-reminders", "daily-articles", "daily-tips")
+		if err := b.addDispatchJobs(spec); err != nil {
+			return err
+		}
+		b.logger.Printf("config: daily dispatch now runs on %q", spec)
+	}
+	return nil
+}
+
+// dispatchSpec returns the cron schedule for the daily dispatch jobs.
+func (b *Bot) dispatchSpec() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.dispatchSpecLocked()
+}
+
+func (b *Bot) dispatchSpecLocked() string {
+	if b.cfg.DispatchSchedule != "" {
+		return b.cfg.DispatchSchedule
+	}
+	return dailyDispatchSpec
+}
+
+// dispatchTime returns when the daily dispatch runs on now's day, in now's
+// location. It reports false when the schedule can't be read.
+func (b *Bot) dispatchTime(now time.Time) (time.Time, bool) {
+	schedule, err := cron.ParseStandard(b.dispatchSpec())
+	if err != nil {
+		return time.Time{}, false
+	}
+	run := schedule.Next(startOfDay(now.In(b.cfg.LocalTimezone)).Add(-time.Second))
+	return run.In(now.Location()), true
+}
+
+// removeJobs unregisters the named cron jobs.
+func (b *Bot) removeJobs(names ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, name := range b.jobs {
+		for _, n := range names {
+			if name == n {
+				b.cron.Remove(id)
+				delete(b.jobs, id)
+			}
+		}
+	}
+}
+
+// jobName returns the name a cron entry was registered under.
+func (b *Bot) jobName(id cron.EntryID) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.jobs[id]
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+	"github.com/pathakanu/myMemo/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// rescheduleSpec offers to move overdue reminders every morning.
+const rescheduleSpec = "0 10 * * *"
+
+// rescheduleOffer is a new due time proposed for an overdue reminder,
+// waiting for the user to say yes.
+type rescheduleOffer struct {
+	ReminderID uint
+	DueAt      time.Time
+}
+
+// sendRescheduleSuggestions offers each user a new time for their most
+// overdue reminder, once it has been overdue for RESCHEDULE_AFTER_DAYS. A
+// reminder is offered once per due date, so a declined offer isn't repeated.
+func (b *Bot) sendRescheduleSuggestions() {
+	after := b.cfg.RescheduleAfterDays
+	if after <= 0 {
+		return
+	}
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+
+	var overdue []model.Reminder
+	err := b.db.WithContext(ctx).
+		Where("completed_at IS NULL AND kind = ? AND COALESCE(rrule, '') = '' AND due_at < ?", model.KindReminder, startOfDay(now).AddDate(0, 0, -after)).
+		Where("reschedule_offered_at IS NULL OR reschedule_offered_at < due_at").
+		Order("due_at ASC").
+		Find(&overdue).Error
+	if err != nil {
+		b.logger.Printf("scheduler: fetch overdue reminders: %v", err)
+		return
+	}
+
+	offered := make(map[string]bool)
+	for _, r := range overdue {
+		if offered[r.UserID] {
+			continue
+		}
+		if pref := b.preferences(r.UserID); pref.Paused || pref.OptedOutAt != nil || b.blackedOut(ctx, r.UserID, now) {
+			continue
+		}
+		offered[r.UserID] = true
+		if err := b.offerReschedule(ctx, r, now); err != nil {
+			b.logger.Printf("scheduler: offer to reschedule %s for %s: %v", r.ShortID(), r.UserID, err)
+		}
+	}
+}
+
+// offerReschedule asks the user whether to move r to a suggested time.
+func (b *Bot) offerReschedule(ctx context.Context, r model.Reminder, now time.Time) error {
+	when := b.workingDue(r, b.suggestDueTime(ctx, r, now))
+	if err := b.db.WithContext(ctx).Model(&r).Update("reschedule_offered_at", now).Error; err != nil {
+		return err
+	}
+	b.state.SetReschedule(r.UserID, rescheduleOffer{ReminderID: r.ID, DueAt: when})
+	msg := fmt.Sprintf("'%s' was due %s. Want me to move it to %s? Reply yes or no.",
+		fallback(r.Summary, r.Content), r.DueAt.In(now.Location()).Format("Mon Jan 2"), describeTime(when, now))
+	return b.notify(ctx, r.UserID, msg)
+}
+
+// suggestDueTime asks the model for a new time for r, falling back to
+// tomorrow morning.
+func (b *Bot) suggestDueTime(ctx context.Context, r model.Reminder, now time.Time) time.Time {
+	if b.useOpenAI(ctx, r.UserID) {
+		when, err := b.openAI.SuggestReschedule(ctx, fallback(r.Summary, r.Content), *r.DueAt, now)
+		if err == nil {
+			return when
+		}
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("openai reschedule error: %v", err)
+		}
+	}
+	return startOfDay(now).AddDate(0, 0, 1).Add(9 * time.Hour)
+}
+
+// describeTime names t relative to now, e.g. "Saturday morning (Sat Oct 18,
+// 09:00)".
+func describeTime(t, now time.Time) string {
+	t = t.In(now.Location())
+	var day string
+	switch days := int(startOfDay(t).Sub(startOfDay(now)).Hours()+12) / 24; {
+	case days == 0:
+		day = "today"
+	case days == 1:
+		day = "tomorrow"
+	case days < 7:
+		day = t.Format("Monday")
+	default:
+		day = t.Format("Mon Jan 2")
+	}
+	part := "evening"
+	switch {
+	case t.Hour() < 12:
+		part = "morning"
+	case t.Hour() < 17:
+		part = "afternoon"
+	}
+	return fmt.Sprintf("%s %s (%s)", day, part, t.Format("Mon Jan 2, 15:04"))
+}
+
+// handleRescheduleReply answers a pending offer to move an overdue reminder.
+// Any reply other than yes or no is handled as a new message.
+func (b *Bot) handleRescheduleReply(ctx context.Context, userID, lowerBody string) (string, bool) {
+	offer, ok := b.state.PopReschedule(userID)
+	if !ok {
+		return "", false
+	}
+	var accept bool
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!") {
+	case "yes", "yes please", "y", "yep", "ok", "okay", "sure", "sounds good":
+		accept = true
+	case "no", "no thanks", "n", "nope", "leave it":
+	default:
+		return "", false
+	}
+
+	var r model.Reminder
+	if err := b.db.WithContext(ctx).Where("id = ? AND user_id = ? AND completed_at IS NULL", offer.ReminderID, userID).First(&r).Error; err != nil {
+		return "That reminder is already done or gone, so there's nothing to move.", true
+	}
+	text := fallback(r.Summary, r.Content)
+	if !accept {
+		return fmt.Sprintf("Okay, I'll leave '%s' as it is.", text), true
+	}
+	updates := map[string]any{"due_at": offer.DueAt}
+	if r.DueAt != nil && offer.DueAt.After(*r.DueAt) {
+		updates["snoozes"] = gorm.Expr("snoozes + 1")
+	}
+	if err := b.db.WithContext(ctx).Model(&r).Updates(updates).Error; err != nil {
+		b.logger.Printf("reschedule %s: %v", r.ShortID(), err)
+		return "I couldn't move that reminder. Please try again later.", true
+	}
+	b.emit(webhook.EventReminderUpdated, r, "")
+	return fmt.Sprintf("Done, '%s' is now due %s.", text, describeTime(offer.DueAt, time.Now().In(b.cfg.LocalTimezone))), true
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+// weeklyReviewSpec sends the weekly review on Sunday evenings.
+const weeklyReviewSpec = "0 18 * * 0"
+
+// staleAfter is how long an open reminder can sit untouched before the
+// weekly review points it out.
+const staleAfter = 14 * 24 * time.Hour
+
+// maxStaleListed caps how many stale reminders the review names.
+const maxStaleListed = 5
+
+// sendWeeklyReviews sends every active user a recap of their week.
+func (b *Bot) sendWeeklyReviews() {
+	var users []string
+	if err := b.db.Model(&model.Reminder{}).Distinct().Pluck("user_id", &users).Error; err != nil {
+		b.logger.Printf("scheduler: fetch review users: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+	now := time.Now().In(b.cfg.LocalTimezone)
+	for _, userID := range users {
+		if pref := b.preferences(userID); pref.Paused || pref.OptedOutAt != nil {
+			continue
+		}
+		msg, err := b.weeklyReview(ctx, userID, now)
+		if err != nil {
+			b.logger.Printf("scheduler: weekly review for %s: %v", userID, err)
+			continue
+		}
+		if msg == "" {
+			continue
+		}
+		if err := b.notify(ctx, userID, msg); err != nil {
+			b.logger.Printf("scheduler: send weekly review to %s: %v", userID, err)
+		}
+	}
+}
+
+// weeklyReview renders a user's review for the week ending at now: how many
+// reminders they finished and have open, which have sat untouched for over
+// two weeks, and what the model suggests doing about those. It returns an
+// empty string for users with nothing to report.
+func (b *Bot) weeklyReview(ctx context.Context, userID string, now time.Time) (string, error) {
+	db := b.db.WithContext(ctx).Model(&model.Reminder{})
+	var done int64
+	if err := db.Where("user_id = ? AND completed_at >= ?", userID, now.AddDate(0, 0, -7)).Count(&done).Error; err != nil {
+		return "", err
+	}
+	open, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if done == 0 && len(open) == 0 {
+		return "", nil
+	}
+
+	var stale []model.Reminder
+	for _, r := range open {
+		if !r.Recurring() && now.Sub(r.CreatedAt) > staleAfter {
+			stale = append(stale, r)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Your weekly review: %d done this week, %d still open.\n", done, len(open))
+	if line := categoryBreakdown(open); line != "" {
+		sb.WriteString(line + "\n")
+	}
+	if progress, err := b.projectProgress(ctx, userID, now.AddDate(0, 0, -7)); err != nil {
+		b.logger.Printf("weekly review: project progress for %s: %v", userID, err)
+	} else if line := projectBreakdown(progress); line != "" {
+		sb.WriteString(line + "\n")
+	}
+	if len(stale) == 0 {
+		sb.WriteString("Nothing has been waiting more than two weeks. Nice work!")
+		return sb.String(), nil
+	}
+
+	sb.WriteString("Untouched for over two weeks:\n")
+	described := make([]string, 0, len(stale))
+	for i, r := range stale {
+		days := int(now.Sub(r.CreatedAt) / (24 * time.Hour))
+		described = append(described, fmt.Sprintf("%s: %s (saved %d days ago, priority %d)", r.ShortID(), fallback(r.Summary, r.Content), days, r.Priority))
+		if i < maxStaleListed {
+			fmt.Fprintf(&sb, "- %s %s (%d days)\n", r.ShortID(), fallback(r.Summary, r.Content), days)
+		}
+	}
+	if extra := len(stale) - maxStaleListed; extra > 0 {
+		fmt.Fprintf(&sb, "…and %d more.\n", extra)
+	}
+
+	if b.useOpenAI(ctx, userID) {
+		suggestion, err := b.openAI.SuggestReview(ctx, described)
+		switch {
+		case err == nil && suggestion != "":
+			sb.WriteString(suggestion)
+			return strings.TrimSpace(sb.String()), nil
+		case err != nil && !errors.Is(err, myopenai.ErrClientNotInitialised):
+			b.logger.Printf("openai review error: %v", err)
+		}
+	}
+	sb.WriteString("Reply 'delete R…' to drop one, or 'done R…' if it's finished.")
+	return sb.String(), nil
+}
+
+// categoryBreakdown counts open reminders per category, largest first, e.g.
+// "By category: work 3, home 2." It returns an empty string when none are
+// categorized.
+func categoryBreakdown(open []model.Reminder) string {
+	counts := make(map[string]int)
+	for _, r := range open {
+		if r.Category != "" {
+			counts[r.Category]++
+		}
+	}
+	if len(counts) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, counts[name])
+	}
+	return "By category: " + strings.Join(parts, ", ") + "."
+}
+
+// projectBreakdown sums up progress on each project with reminders, e.g.
+// "Projects: kitchen renovation 2/5 done (1 this week), garden 0/3 done." It
+// returns an empty string when there are none.
+func projectBreakdown(progress []projectProgress) string {
+	var parts []string
+	for _, p := range progress {
+		if p.Total == 0 {
+			continue
+		}
+		part := fmt.Sprintf("%s %d/%d done", p.Name, p.Done, p.Total)
+		if p.DoneSince > 0 {
+			part += fmt.Sprintf(" (%d this week)", p.DoneSince)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Projects: " + strings.Join(parts, ", ") + "."
+}
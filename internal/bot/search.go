@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/database"
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+var searchReminderPattern = regexp.MustCompile(`(?i)^(?:search|find)\s+(?:my\s+)?(?:reminders?|tasks?)\s+(?:for\s+|about\s+|matching\s+|with\s+)?(.+?)[?.!]*$`)
+
+// handleSearchCommand lists open reminders matching a search such as "find
+// reminders about dentist appointment". It reports false when the message is
+// not a search.
+func (b *Bot) handleSearchCommand(ctx context.Context, userID, body string) (string, bool) {
+	match := searchReminderPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if match == nil {
+		return "", false
+	}
+	msg, err := b.searchReminderList(ctx, userID, match[1])
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("search reminders: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// searchReminders returns the user's open reminders whose content or summary
+// contains every word of query, in list order. When no words match it falls
+// back to the reminders closest in meaning, nearest first.
+func (b *Bot) searchReminders(ctx context.Context, userID, query string) ([]model.Reminder, error) {
+	tx := b.db.WithContext(ctx).Where("user_id = ? AND completed_at IS NULL", userID).Where("NOT " + waitingOnParent)
+	var reminders []model.Reminder
+	err := database.SearchReminders(tx, database.SearchMode(b.db), query).
+		Clauses(b.reminderOrder(userID)).
+		Find(&reminders).Error
+	if err != nil || len(reminders) > 0 || strings.TrimSpace(query) == "" {
+		return reminders, err
+	}
+	return b.semanticSearch(ctx, userID, query)
+}
+
+// searchReminderList renders matches numbered by their place in the full
+// list, so "done 3" and "delete reminder 3" work on the result.
+func (b *Bot) searchReminderList(ctx context.Context, userID, query string) (string, error) {
+	query = strings.TrimSpace(query)
+	matches, err := b.searchReminders(ctx, userID, query)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't search your reminders right now. Please try again later")
+	}
+	if len(matches) == 0 {
+		return "", userError{fmt.Sprintf("I couldn't find any reminders about '%s'.", query)}
+	}
+	open, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't search your reminders right now. Please try again later")
+	}
+	position := make(map[uint]int, len(open))
+	for i, r := range open {
+		position[r.ID] = i + 1
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Reminders matching '%s':\n", query))
+	for _, r := range matches {
+		sb.WriteString(fmt.Sprintf("%d. %s [%s] %s\n", position[r.ID], r.ShortID(), b.priorityLabel(r, time.Now()), fallback(r.Summary, r.Content)))
+	}
+	return sb.String(), nil
+}
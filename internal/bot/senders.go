@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/twilio"
+)
+
+// senderCooldown is how long a pool number that failed a send is tried last.
+const senderCooldown = 5 * time.Minute
+
+// senderPool holds the deployment's WhatsApp numbers when more than one is
+// configured. New users are spread across them in turn and then keep their
+// number, so their chat stays in one thread, unless it starts failing.
+type senderPool struct {
+	numbers []string
+	senders map[string]channel.Sender
+
+	mu          sync.Mutex
+	next        int
+	failedUntil map[string]time.Time
+}
+
+func newSenderPool(numbers []string, senders map[string]channel.Sender) *senderPool {
+	return &senderPool{numbers: numbers, senders: senders, failedUntil: make(map[string]time.Time)}
+}
+
+// candidates returns the numbers to try for a user in order: their assigned
+// number, or the next one in turn for a new user, then the rest. Numbers in
+// their cooldown go last rather than being skipped, so a send is still
+// attempted when every number is failing.
+func (p *senderPool) candidates(assigned string, now time.Time) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	start := slices.Index(p.numbers, assigned)
+	if start < 0 {
+		start = p.next % len(p.numbers)
+		p.next++
+	}
+	var healthy, cooling []string
+	for i := range p.numbers {
+		number := p.numbers[(start+i)%len(p.numbers)]
+		if now.Before(p.failedUntil[number]) {
+			cooling = append(cooling, number)
+		} else {
+			healthy = append(healthy, number)
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+// reportFailure puts number in its cooldown.
+func (p *senderPool) reportFailure(number string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failedUntil[number] = now.Add(senderCooldown)
+}
+
+// sendFromPool sends msg from the recipient's pool number, failing over to
+// the others in turn. The number that gets the message through becomes the
+// recipient's number.
+func (b *Bot) sendFromPool(ctx context.Context, msg channel.Message) error {
+	assigned := b.preferences(msg.To).SenderNumber
+	var errs []error
+	for _, number := range b.pool.candidates(assigned, time.Now()) {
+		err := b.pool.senders[number].Send(ctx, msg)
+		if err == nil {
+			if number != assigned {
+				b.assignSender(msg.To, number)
+			}
+			return nil
+		}
+		if twilio.IsNotJoined(err) {
+			// The recipient left the sandbox; no other number will do
+			// better.
+			return err
+		}
+		b.logger.Printf("sender pool: %s failed: %v", number, err)
+		b.pool.reportFailure(number, time.Now())
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// pinSender keeps a user on the pool number they just wrote to, so replies
+// and reminders come from the number they are chatting with.
+func (b *Bot) pinSender(userID, to string) {
+	number := sanitizeWhatsAppNumber(to)
+	if b.pool == nil || b.pool.senders[number] == nil || b.preferences(userID).SenderNumber == number {
+		return
+	}
+	b.assignSender(userID, number)
+}
+
+func (b *Bot) assignSender(userID, number string) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.SenderNumber = number
+	}); err != nil {
+		b.logger.Printf("sender pool: assign %s to %s: %v", number, userID, err)
+	}
+}
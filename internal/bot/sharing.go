@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	shareListPattern   = regexp.MustCompile(`(?i)^share\s+(?:my\s+|the\s+)?(.+?)\s+list\s+with\s+([+\d][\d\s().-]*?)(?:\s+as\s+(?:an?\s+)?(viewer|editor))?$`)
+	unshareListPattern = regexp.MustCompile(`(?i)^(?:stop\s+sharing|unshare)\s+(?:my\s+|the\s+)?(.+?)\s+list\s+with\s+([+\d][\d\s().-]*)$`)
+	leaveListPattern   = regexp.MustCompile(`(?i)^leave\s+(?:my\s+|the\s+)?(.+?)\s+list$`)
+)
+
+// phoneNumber normalises a number typed in chat, such as "+1 (555) 123-4567",
+// to the E.164 form users are identified by.
+func phoneNumber(text string) (string, bool) {
+	number := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' || r == '+' {
+			return r
+		}
+		return -1
+	}, text)
+	if !strings.HasPrefix(number, "+") {
+		number = "+" + number
+	}
+	return number, tenantNumberPattern.MatchString(number)
+}
+
+// shareList gives member access to one of the owner's lists, or changes
+// their role if they already have it, and lets them know.
+func (b *Bot) shareList(ownerID, name, number, role string) (string, error) {
+	list, err := b.findList(ownerID, name, listOwn)
+	if err != nil {
+		return "", err
+	}
+	memberID, ok := phoneNumber(number)
+	if !ok {
+		return "", userError{fmt.Sprintf("'%s' doesn't look like a phone number. Include the country code, e.g. +14155550123.", strings.TrimSpace(number))}
+	}
+	if memberID == ownerID {
+		return "", userError{"That's your own number."}
+	}
+	if role == "" {
+		role = model.ListRoleEditor
+	}
+
+	member := model.ListMember{ListID: list.ID, UserID: memberID, Role: role}
+	err = b.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "list_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(&member).Error
+	if err != nil {
+		return "", fmt.Errorf("I couldn't share your %s list. Please try again later", list.Name)
+	}
+
+	can := "view and edit"
+	if role == model.ListRoleViewer {
+		can = "view"
+	}
+	invite := fmt.Sprintf("%s shared their %s list with you, so you can %s it. Say 'show my %s list' to see it.", ownerID, list.Name, can, list.Name)
+	if err := b.notify(context.Background(), memberID, invite); err != nil {
+		b.logger.Printf("share list: notify %s: %v", memberID, err)
+	}
+	return fmt.Sprintf("Shared your %s list with %s as %s.", list.Name, memberID, role), nil
+}
+
+// unshareList removes a member from one of the owner's lists.
+func (b *Bot) unshareList(ownerID, name, number string) (string, error) {
+	list, err := b.findList(ownerID, name, listOwn)
+	if err != nil {
+		return "", err
+	}
+	memberID, _ := phoneNumber(number)
+	res := b.db.Where("list_id = ? AND user_id = ?", list.ID, memberID).Delete(&model.ListMember{})
+	if res.Error != nil {
+		return "", fmt.Errorf("I couldn't update your %s list. Please try again later", list.Name)
+	}
+	if res.RowsAffected == 0 {
+		return "", userError{fmt.Sprintf("Your %s list isn't shared with %s.", list.Name, memberID)}
+	}
+	return fmt.Sprintf("%s can no longer see your %s list.", memberID, list.Name), nil
+}
+
+// leaveList removes the user from a list someone else shared with them.
+func (b *Bot) leaveList(userID, name string) (string, error) {
+	list, role, found, err := b.lookupList(userID, name)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load that list. Please try again later")
+	}
+	if !found || role == "owner" {
+		return "", userError{fmt.Sprintf("Nobody has shared a %s list with you.", normalizeListName(name))}
+	}
+	if err := b.db.Where("list_id = ? AND user_id = ?", list.ID, userID).Delete(&model.ListMember{}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't update the %s list. Please try again later", list.Name)
+	}
+	return fmt.Sprintf("You've left the %s list shared by %s.", list.Name, list.UserID), nil
+}
+
+// sharingNote describes who else can see a list, for the bottom of showList.
+func (b *Bot) sharingNote(userID string, list model.List) string {
+	if list.UserID != userID {
+		return fmt.Sprintf("Shared with you by %s.", list.UserID)
+	}
+	var members []model.ListMember
+	if err := b.db.Where("list_id = ?", list.ID).Order("created_at ASC").Find(&members).Error; err != nil {
+		b.logger.Printf("list members: %v", err)
+		return ""
+	}
+	if len(members) == 0 {
+		return ""
+	}
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = fmt.Sprintf("%s (%s)", m.UserID, m.Role)
+	}
+	return "Shared with " + strings.Join(names, ", ") + "."
+}
@@ -0,0 +1,246 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+var (
+	addStepPattern    = regexp.MustCompile(`(?i)^add\s+(?:a\s+)?(steps?|sub-?tasks?)\s+(.+?)\s+to\s+(?:reminder\s+)?(r?\d+)[.!]*$`)
+	checkStepPattern  = regexp.MustCompile(`(?i)^(check off|tick off|uncheck|untick)\s+(?:step\s+)?(.+?)(?:\s+(?:on|in|for|from)\s+(?:reminder\s+)?(r?\d+))?[.!]*$`)
+	removeStepPattern = regexp.MustCompile(`(?i)^(?:remove|delete)\s+step\s+(.+?)\s+from\s+(?:reminder\s+)?(r?\d+)[.!]*$`)
+)
+
+// handleStepCommand keeps a checklist of steps on a reminder: "add step buy
+// paint to reminder 2" adds one, "check off paint" ticks it, and "uncheck"
+// and "remove step ... from 2" undo them. It reports false for other
+// messages.
+func (b *Bot) handleStepCommand(ctx context.Context, userID, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	var (
+		msg string
+		err error
+	)
+	if m := addStepPattern.FindStringSubmatch(body); m != nil {
+		texts := []string{m[2]}
+		if strings.HasSuffix(strings.ToLower(m[1]), "s") {
+			// "add steps sand, prime, paint to 2" adds three.
+			texts = strings.Split(m[2], ",")
+		}
+		msg, err = b.addSteps(ctx, userID, m[3], texts)
+	} else if m := checkStepPattern.FindStringSubmatch(body); m != nil {
+		verb := strings.ToLower(m[1])
+		msg, err = b.checkStep(ctx, userID, m[2], m[3], verb == "check off" || verb == "tick off")
+	} else if m := removeStepPattern.FindStringSubmatch(body); m != nil {
+		msg, err = b.removeStep(ctx, userID, m[1], m[2])
+	} else {
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("step command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// addSteps appends texts to the checklist of the reminder selector names.
+func (b *Bot) addSteps(ctx context.Context, userID, selector string, texts []string) (string, error) {
+	reminder, err := b.reminderBySelector(ctx, userID, selector)
+	if err != nil {
+		return "", err
+	}
+	var items []model.ReminderItem
+	for _, text := range texts {
+		if text = strings.TrimSpace(text); text != "" {
+			items = append(items, model.ReminderItem{ReminderID: reminder.ID, Text: text})
+		}
+	}
+	if len(items) == 0 {
+		return "", userError{"Say what the step is, e.g. 'add step buy paint to 2'."}
+	}
+	err = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		return tx.Model(reminder).Update("steps", gorm.Expr("steps + ?", len(items))).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("add steps to %s: %w", reminder.ShortID(), err)
+	}
+	reminder.Steps += len(items)
+
+	what := fmt.Sprintf("'%s'", items[0].Text)
+	if len(items) > 1 {
+		what = plural(len(items), "step")
+	}
+	return fmt.Sprintf("Added %s to %s %s (%s). Say 'check off %s' when it's done.",
+		what, reminder.ShortID(), fallback(reminder.Summary, reminder.Content), stepProgress(*reminder), items[0].Text), nil
+}
+
+// checkStep checks off the step matching text, or unchecks it when done is
+// false. Without a selector it looks through all of the user's open
+// reminders.
+func (b *Bot) checkStep(ctx context.Context, userID, text, selector string, done bool) (string, error) {
+	reminder, item, err := b.findStep(ctx, userID, text, selector, done)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case done && item.DoneAt != nil:
+		return "", userError{fmt.Sprintf("'%s' is already checked off.", item.Text)}
+	case !done && item.DoneAt == nil:
+		return "", userError{fmt.Sprintf("'%s' isn't checked off.", item.Text)}
+	}
+	var doneAt *time.Time
+	delta := -1
+	if done {
+		now := time.Now()
+		doneAt, delta = &now, 1
+	}
+	err = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&item).Update("done_at", doneAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(reminder).Update("steps_done", gorm.Expr("steps_done + ?", delta)).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("check step %d: %w", item.ID, err)
+	}
+	reminder.StepsDone += delta
+
+	name := fmt.Sprintf("%s %s", reminder.ShortID(), fallback(reminder.Summary, reminder.Content))
+	switch {
+	case !done:
+		return fmt.Sprintf("Unchecked '%s' on %s (%s).", item.Text, name, stepProgress(*reminder)), nil
+	case reminder.StepsDone >= reminder.Steps:
+		return fmt.Sprintf("Checked off '%s'. That was the last step of %s — reply 'done %s' to finish it.", item.Text, name, reminder.ShortID()), nil
+	}
+	return fmt.Sprintf("Checked off '%s' on %s (%s).", item.Text, name, stepProgress(*reminder)), nil
+}
+
+// removeStep deletes the step matching text from the reminder selector names.
+func (b *Bot) removeStep(ctx context.Context, userID, text, selector string) (string, error) {
+	reminder, item, err := b.findStep(ctx, userID, text, selector, true)
+	if err != nil {
+		if !isUserError(err) {
+			return "", err
+		}
+		// The step may already be checked off.
+		if reminder, item, err = b.findStep(ctx, userID, text, selector, false); err != nil {
+			return "", err
+		}
+	}
+	err = b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+		updates := map[string]any{"steps": gorm.Expr("steps - 1")}
+		if item.DoneAt != nil {
+			updates["steps_done"] = gorm.Expr("steps_done - 1")
+		}
+		return tx.Model(reminder).Updates(updates).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("remove step %d: %w", item.ID, err)
+	}
+	return fmt.Sprintf("Removed '%s' from %s.", item.Text, reminder.ShortID()), nil
+}
+
+// findStep finds the step matching text: by its number on the reminder
+// selector names, or by its words among the steps of that reminder or, with
+// no selector, of every open reminder. It looks at open steps when open is
+// true and at checked-off ones otherwise.
+func (b *Bot) findStep(ctx context.Context, userID, text, selector string, open bool) (*model.Reminder, model.ReminderItem, error) {
+	text = strings.TrimSpace(text)
+	var reminders []model.Reminder
+	if selector != "" {
+		reminder, err := b.reminderBySelector(ctx, userID, selector)
+		if err != nil {
+			return nil, model.ReminderItem{}, err
+		}
+		reminders = []model.Reminder{*reminder}
+	} else {
+		err := b.db.WithContext(ctx).Where("user_id = ? AND completed_at IS NULL AND steps > 0", userID).Find(&reminders).Error
+		if err != nil {
+			return nil, model.ReminderItem{}, fmt.Errorf("I couldn't look up your steps right now. Please try again later")
+		}
+	}
+	byID := make(map[uint]*model.Reminder, len(reminders))
+	ids := make([]uint, 0, len(reminders))
+	for i := range reminders {
+		byID[reminders[i].ID] = &reminders[i]
+		ids = append(ids, reminders[i].ID)
+	}
+	if len(ids) == 0 {
+		return nil, model.ReminderItem{}, userError{"None of your reminders have steps. Add one with 'add step buy paint to 2'."}
+	}
+
+	var items []model.ReminderItem
+	if err := b.db.WithContext(ctx).Where("reminder_id IN ?", ids).Order("id").Find(&items).Error; err != nil {
+		return nil, model.ReminderItem{}, fmt.Errorf("I couldn't look up your steps right now. Please try again later")
+	}
+	// "check off step 2 on R4" picks the second step of R4.
+	if n, err := strconv.Atoi(text); err == nil && selector != "" {
+		if n < 1 || n > len(items) {
+			return nil, model.ReminderItem{}, userError{fmt.Sprintf("%s has %s.", reminders[0].ShortID(), plural(len(items), "step"))}
+		}
+		return byID[items[n-1].ReminderID], items[n-1], nil
+	}
+
+	lowerText := strings.ToLower(text)
+	var matches []model.ReminderItem
+	for _, item := range items {
+		if (item.DoneAt == nil) != open {
+			continue
+		}
+		lowerItem := strings.ToLower(item.Text)
+		if lowerItem == lowerText {
+			return byID[item.ReminderID], item, nil
+		}
+		if strings.Contains(lowerItem, lowerText) {
+			matches = append(matches, item)
+		}
+	}
+	state := "an open"
+	if !open {
+		state = "a checked-off"
+	}
+	switch len(matches) {
+	case 0:
+		return nil, model.ReminderItem{}, userError{fmt.Sprintf("I couldn't find %s step matching '%s'.", state, text)}
+	case 1:
+		return byID[matches[0].ReminderID], matches[0], nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "'%s' matches %s. Which one?\n", text, plural(len(matches), "step"))
+	for _, item := range matches {
+		fmt.Fprintf(&sb, "- %s on %s\n", item.Text, byID[item.ReminderID].ShortID())
+	}
+	sb.WriteString("Say it in full, or add the reminder, e.g. 'check off paint on R2'.")
+	return nil, model.ReminderItem{}, userError{sb.String()}
+}
+
+// stepProgress renders how many of r's steps are done, such as "2/5".
+func stepProgress(r model.Reminder) string {
+	return fmt.Sprintf("%d/%d", r.StepsDone, r.Steps)
+}
+
+// reminderSteps loads r's checklist in the order the steps were added.
+func (b *Bot) reminderSteps(ctx context.Context, r model.Reminder) ([]model.ReminderItem, error) {
+	var items []model.ReminderItem
+	if r.Steps == 0 {
+		return nil, nil
+	}
+	err := b.db.WithContext(ctx).Where("reminder_id = ?", r.ID).Order("id").Find(&items).Error
+	return items, err
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	saveTemplatePattern     = regexp.MustCompile(`(?i)^save template\s+([\p{L}\p{N}_-]+)\s*[:=-]\s*(.+)$`)
+	deleteTemplatePattern   = regexp.MustCompile(`(?i)^delete template\s+([\p{L}\p{N}_-]+)$`)
+	templatePriorityPattern = regexp.MustCompile(`(?i)\bpriority\s*([1-5])\b`)
+)
+
+// reservedTemplateNames cannot be used as template names because they clash with commands.
+var reservedTemplateNames = map[string]struct{}{
+	"help":      {},
+	"templates": {},
+	"list":      {},
+	"delete":    {},
+	"clear":     {},
+	"done":      {},
+	"habit":     {},
+}
+
+// handleTemplateCommand processes template management commands. It reports
+// false when the message is not a template command.
+func (b *Bot) handleTemplateCommand(userID, body, lowerBody string) (string, bool) {
+	switch {
+	case lowerBody == "templates" || lowerBody == "list templates" || lowerBody == "show templates":
+		return b.listTemplates(userID), true
+	case saveTemplatePattern.MatchString(body):
+		matches := saveTemplatePattern.FindStringSubmatch(body)
+		msg, err := b.saveTemplate(userID, matches[1], matches[2])
+		if err != nil {
+			if !isUserError(err) {
+				b.logger.Printf("save template: %v", err)
+			}
+			return err.Error(), true
+		}
+		return msg, true
+	case deleteTemplatePattern.MatchString(body):
+		matches := deleteTemplatePattern.FindStringSubmatch(body)
+		msg, err := b.deleteTemplate(userID, matches[1])
+		if err != nil {
+			if !isUserError(err) {
+				b.logger.Printf("delete template: %v", err)
+			}
+			return err.Error(), true
+		}
+		return msg, true
+	}
+	return "", false
+}
+
+// saveTemplate creates or replaces a named template for the user.
+func (b *Bot) saveTemplate(userID, name, content string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	content = strings.TrimSpace(content)
+	if _, reserved := reservedTemplateNames[name]; reserved {
+		return "", userError{fmt.Sprintf("'%s' is a command word, so it can't be a template name.", name)}
+	}
+
+	tmpl := model.Template{
+		UserID:   userID,
+		Name:     name,
+		Content:  content,
+		Priority: parseTemplatePriority(content),
+	}
+	err := b.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "priority"}),
+	}).Create(&tmpl).Error
+	if err != nil {
+		return "", fmt.Errorf("I couldn't save that template. Please try again later")
+	}
+	return fmt.Sprintf("Template '%s' saved. Send '%s' any time to add it as a reminder.", name, name), nil
+}
+
+// deleteTemplate removes a named template for the user.
+func (b *Bot) deleteTemplate(userID, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	res := b.db.Where("user_id = ? AND name = ?", userID, name).Delete(&model.Template{})
+	if res.Error != nil {
+		return "", fmt.Errorf("I couldn't delete that template. Please try again later")
+	}
+	if res.RowsAffected == 0 {
+		return "", userError{fmt.Sprintf("You don't have a template called '%s'.", name)}
+	}
+	return fmt.Sprintf("Template '%s' deleted.", name), nil
+}
+
+// listTemplates returns a human-readable list of the user's templates.
+func (b *Bot) listTemplates(userID string) string {
+	var templates []model.Template
+	if err := b.db.Where("user_id = ?", userID).Order("name ASC").Find(&templates).Error; err != nil {
+		b.logger.Printf("list templates error: %v", err)
+		return "I couldn't load your templates right now. Please try again later."
+	}
+	if len(templates) == 0 {
+		return "You have no templates yet. Try 'save template gym: Go to the gym, priority 4'."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your templates:\n")
+	for _, t := range templates {
+		sb.WriteString(fmt.Sprintf("- %s → %s\n", t.Name, t.Content))
+	}
+	return sb.String()
+}
+
+// findTemplate looks up a template whose name matches a one-word message.
+func (b *Bot) findTemplate(userID, lowerBody string) (model.Template, bool) {
+	var tmpl model.Template
+	if strings.ContainsAny(lowerBody, " \t\n") {
+		return tmpl, false
+	}
+	err := b.db.Where("user_id = ? AND name = ?", userID, lowerBody).First(&tmpl).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			b.logger.Printf("find template: %v", err)
+		}
+		return tmpl, false
+	}
+	return tmpl, true
+}
+
+func parseTemplatePriority(content string) int {
+	matches := templatePriorityPattern.FindStringSubmatch(content)
+	if len(matches) < 2 {
+		return 0
+	}
+	priority, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0
+	}
+	return priority
+}
@@ -0,0 +1,287 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/twilio"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantMismatchReply answers users who write to a workspace's number other
+// than the one they belong to.
+const tenantMismatchReply = "This number belongs to a different myMemo workspace. Please message the number you signed up with."
+
+var tenantNumberPattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// inboundTenant returns the tenant served by the number a message was sent to,
+// or nil for the deployment's default number. Users messaging a tenant number
+// for the first time are assigned to that tenant. ok is false when the user
+// belongs to a different workspace than the one they wrote to.
+func (b *Bot) inboundTenant(ctx context.Context, userID, to string) (tenant *model.Tenant, ok bool, err error) {
+	if tenant, err = b.tenantByNumber(ctx, to); err != nil {
+		return nil, false, err
+	}
+
+	var member model.TenantUser
+	err = b.db.WithContext(ctx).Where("user_id = ?", userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if tenant == nil {
+			return nil, true, nil
+		}
+		member = model.TenantUser{UserID: userID, TenantID: tenant.ID}
+		if err := b.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+			return nil, false, err
+		}
+		b.logger.Printf("tenant: %s joined %s", userID, tenant.Name)
+		return tenant, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return tenant, tenant != nil && member.TenantID == tenant.ID, nil
+}
+
+// tenantByNumber returns the tenant whose WhatsApp number is to, or nil
+// when to is the default number.
+func (b *Bot) tenantByNumber(ctx context.Context, to string) (*model.Tenant, error) {
+	number := sanitizeWhatsAppNumber(to)
+	if number == "" {
+		return nil, nil
+	}
+	var tenants []model.Tenant
+	if err := b.db.WithContext(ctx).Where("whats_app_number = ?", number).Limit(1).Find(&tenants).Error; err != nil || len(tenants) == 0 {
+		return nil, err
+	}
+	return &tenants[0], nil
+}
+
+// userTenant returns the tenant a user belongs to, or nil when they use the
+// deployment's default number.
+func (b *Bot) userTenant(ctx context.Context, userID string) (*model.Tenant, error) {
+	// Find rather than First: most users have no tenant, and this runs on
+	// every send.
+	var tenants []model.Tenant
+	err := b.db.WithContext(ctx).
+		Joins("JOIN tenant_users ON tenant_users.tenant_id = tenants.id").
+		Where("tenant_users.user_id = ?", userID).
+		Limit(1).
+		Find(&tenants).Error
+	if err != nil || len(tenants) == 0 {
+		return nil, err
+	}
+	return &tenants[0], nil
+}
+
+// twilioCredentials returns the Twilio account to use for tenant: its own
+// when it has one, or else the server's. tenant may be nil.
+func (b *Bot) twilioCredentials(tenant *model.Tenant) (sid, token string) {
+	if tenant != nil && tenant.TwilioAccountSID != "" {
+		return tenant.TwilioAccountSID, tenant.TwilioAuthToken
+	}
+	return b.cfg.TwilioAccountSID, b.cfg.TwilioAuthToken
+}
+
+// tenantSender returns the WhatsApp sender for the tenant a recipient belongs
+// to, or nil when they use the default number.
+func (b *Bot) tenantSender(ctx context.Context, to string) channel.Sender {
+	tenant, err := b.userTenant(ctx, to)
+	if err != nil {
+		b.logger.Printf("tenant: load tenant for %s: %v", to, err)
+		return nil
+	}
+	if tenant == nil {
+		return nil
+	}
+
+	b.tenantMu.Lock()
+	defer b.tenantMu.Unlock()
+	if sender, ok := b.tenantSenders[tenant.ID]; ok {
+		return sender
+	}
+	if b.tenantSenders == nil {
+		b.tenantSenders = make(map[uint]channel.Sender)
+	}
+	sid, token := b.twilioCredentials(tenant)
+	sender := channel.NewWhatsApp(twilio.New(sid, token, tenant.WhatsAppNumber))
+	b.tenantSenders[tenant.ID] = sender
+	return sender
+}
+
+// useOpenAI reports whether an OpenAI call may be made for userID, counting
+// it against their tenant's monthly limit. Callers fall back to their non-AI
+// behaviour when it returns false.
+func (b *Bot) useOpenAI(ctx context.Context, userID string) bool {
+	tenant, err := b.userTenant(ctx, userID)
+	if err != nil {
+		b.logger.Printf("tenant: load tenant for %s: %v", userID, err)
+		return true
+	}
+	if tenant == nil || tenant.OpenAIMonthlyLimit <= 0 {
+		return true
+	}
+
+	usage := model.OpenAIUsage{TenantID: tenant.ID, Month: time.Now().In(b.cfg.LocalTimezone).Format("2006-01")}
+	db := b.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+		b.logger.Printf("tenant: record openai usage: %v", err)
+		return true
+	}
+	res := db.Model(&model.OpenAIUsage{}).
+		Where("tenant_id = ? AND month = ? AND calls < ?", usage.TenantID, usage.Month, tenant.OpenAIMonthlyLimit).
+		Update("calls", gorm.Expr("calls + 1"))
+	if res.Error != nil {
+		b.logger.Printf("tenant: record openai usage: %v", res.Error)
+		return true
+	}
+	if res.RowsAffected == 0 {
+		b.logger.Printf("tenant: %s reached its OpenAI limit for %s", tenant.Name, usage.Month)
+		return false
+	}
+	return true
+}
+
+// TenantSummary describes a tenant for operators. The Twilio auth token is
+// never returned.
+type TenantSummary struct {
+	Name               string `json:"name"`
+	WhatsAppNumber     string `json:"whatsapp_number"`
+	TwilioAccountSID   string `json:"twilio_account_sid,omitempty"`
+	ReminderGapMinutes *int   `json:"reminder_gap_minutes,omitempty"`
+	OpenAIMonthlyLimit int    `json:"openai_monthly_limit"`
+	OpenAICallsMonth   int    `json:"openai_calls_this_month"`
+	Users              int64  `json:"users"`
+}
+
+// tenantRequest is the body of POST /admin/tenants.
+type tenantRequest struct {
+	Name               string `json:"name"`
+	WhatsAppNumber     string `json:"whatsapp_number"`
+	TwilioAccountSID   string `json:"twilio_account_sid"`
+	TwilioAuthToken    string `json:"twilio_auth_token"`
+	ReminderGapMinutes *int   `json:"reminder_gap_minutes"`
+	OpenAIMonthlyLimit int    `json:"openai_monthly_limit"`
+}
+
+// AdminTenantsHandler lists tenants (GET) or creates or replaces one by name
+// (POST).
+func (b *Bot) AdminTenantsHandler() http.Handler {
+	return b.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			tenants, err := b.tenantSummaries(r.Context())
+			if err != nil {
+				b.writeAdminError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"tenants": tenants})
+		case http.MethodPost:
+			var req tenantRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			tenant, err := b.saveTenant(r.Context(), req)
+			if err != nil {
+				b.writeAdminError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, TenantSummary{
+				Name:               tenant.Name,
+				WhatsAppNumber:     tenant.WhatsAppNumber,
+				TwilioAccountSID:   tenant.TwilioAccountSID,
+				ReminderGapMinutes: tenant.ReminderGapMinutes,
+				OpenAIMonthlyLimit: tenant.OpenAIMonthlyLimit,
+			})
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+func (b *Bot) saveTenant(ctx context.Context, req tenantRequest) (*model.Tenant, error) {
+	name := strings.TrimSpace(req.Name)
+	number := sanitizeWhatsAppNumber(strings.TrimSpace(req.WhatsAppNumber))
+	switch {
+	case name == "":
+		return nil, userError{"name is required"}
+	case !tenantNumberPattern.MatchString(number):
+		return nil, userError{"whatsapp_number must be in E.164 format, e.g. +14155238886"}
+	case slices.Contains(b.cfg.SenderNumbers(), number):
+		return nil, userError{"whatsapp_number is one of the deployment's default numbers"}
+	case (req.TwilioAccountSID == "") != (req.TwilioAuthToken == ""):
+		return nil, userError{"twilio_account_sid and twilio_auth_token must be set together"}
+	case req.ReminderGapMinutes != nil && *req.ReminderGapMinutes < 0:
+		return nil, userError{"reminder_gap_minutes must not be negative"}
+	case req.OpenAIMonthlyLimit < 0:
+		return nil, userError{"openai_monthly_limit must not be negative"}
+	}
+
+	db := b.db.WithContext(ctx)
+	var other model.Tenant
+	err := db.Where("whats_app_number = ? AND name <> ?", number, name).First(&other).Error
+	if err == nil {
+		return nil, userError{"whatsapp_number is already used by tenant " + other.Name}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	tenant := model.Tenant{Name: name}
+	if err := db.Where("name = ?", name).FirstOrInit(&tenant).Error; err != nil {
+		return nil, err
+	}
+	tenant.WhatsAppNumber = number
+	tenant.TwilioAccountSID = req.TwilioAccountSID
+	tenant.TwilioAuthToken = req.TwilioAuthToken
+	tenant.ReminderGapMinutes = req.ReminderGapMinutes
+	tenant.OpenAIMonthlyLimit = req.OpenAIMonthlyLimit
+	if err := db.Save(&tenant).Error; err != nil {
+		return nil, err
+	}
+
+	b.tenantMu.Lock()
+	delete(b.tenantSenders, tenant.ID)
+	b.tenantMu.Unlock()
+	return &tenant, nil
+}
+
+func (b *Bot) tenantSummaries(ctx context.Context) ([]TenantSummary, error) {
+	db := b.db.WithContext(ctx)
+	var tenants []model.Tenant
+	if err := db.Order("name ASC").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+	month := time.Now().In(b.cfg.LocalTimezone).Format("2006-01")
+
+	out := make([]TenantSummary, len(tenants))
+	for i, t := range tenants {
+		out[i] = TenantSummary{
+			Name:               t.Name,
+			WhatsAppNumber:     t.WhatsAppNumber,
+			TwilioAccountSID:   t.TwilioAccountSID,
+			ReminderGapMinutes: t.ReminderGapMinutes,
+			OpenAIMonthlyLimit: t.OpenAIMonthlyLimit,
+		}
+		if err := db.Model(&model.TenantUser{}).Where("tenant_id = ?", t.ID).Count(&out[i].Users).Error; err != nil {
+			return nil, err
+		}
+		var usage model.OpenAIUsage
+		err := db.Where("tenant_id = ? AND month = ?", t.ID, month).First(&usage).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		out[i].OpenAICallsMonth = usage.Calls
+	}
+	return out, nil
+}
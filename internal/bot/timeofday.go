@@ -0,0 +1,263 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// defaultBucketHours are the hours morning, afternoon, and evening reminders
+// go out at until a user picks their own.
+var defaultBucketHours = map[string]int{
+	model.TimeMorning:   9,
+	model.TimeAfternoon: 14,
+	model.TimeEvening:   19,
+}
+
+// bucketRanges bounds the hours a user may pick for each part of the day.
+var bucketRanges = map[string][2]int{
+	model.TimeMorning:   {4, 11},
+	model.TimeAfternoon: {12, 17},
+	model.TimeEvening:   {17, 23},
+}
+
+var timeOfDayPattern = regexp.MustCompile(`(?i)\b(?:(?:in the|this|every|tomorrow)\s+(morning|afternoon|evening)|(tonight|at night))\b`)
+
+// parseTimeOfDay finds a part of the day in a reminder, as in "remind me in
+// the evening to water plants" or "call mum tonight", and returns it or "".
+func parseTimeOfDay(content string) string {
+	matches := timeOfDayPattern.FindStringSubmatch(content)
+	switch {
+	case matches == nil:
+		return ""
+	case matches[2] != "":
+		return model.TimeEvening
+	}
+	return strings.ToLower(matches[1])
+}
+
+// bucketHour returns the hour pref's user gets reminders for bucket at.
+func bucketHour(pref model.UserPreference, bucket string) int {
+	var hour *int
+	switch bucket {
+	case model.TimeMorning:
+		hour = pref.MorningHour
+	case model.TimeAfternoon:
+		hour = pref.AfternoonHour
+	case model.TimeEvening:
+		hour = pref.EveningHour
+	}
+	if hour != nil {
+		return *hour
+	}
+	return defaultBucketHours[bucket]
+}
+
+// dispatchSlots orders a dispatch's reminders and picks when each goes out.
+// Reminders without a time of day start at now, or at the user's delivery
+// hour once it is later; the rest wait for their bucket's hour, or follow
+// straight on once it has passed. Every send is at least gap after the one
+// before it. A send in the user's quiet hours waits for them to end, and is
+// left to the next dispatch when they end on a later day.
+func dispatchSlots(due []model.Reminder, pref model.UserPreference, gap time.Duration, now time.Time) ([]model.Reminder, []time.Time) {
+	y, m, d := now.Date()
+	start := func(r model.Reminder) time.Time {
+		if r.TimeOfDay == "" {
+			if pref.DeliveryHour == nil {
+				return now
+			}
+			return later(now, time.Date(y, m, d, *pref.DeliveryHour, 0, 0, 0, now.Location()))
+		}
+		return later(now, time.Date(y, m, d, bucketHour(pref, r.TimeOfDay), 0, 0, 0, now.Location()))
+	}
+	ordered := slices.Clone(due)
+	slices.SortStableFunc(ordered, func(x, y model.Reminder) int {
+		return start(x).Compare(start(y))
+	})
+
+	kept := ordered[:0]
+	slots := make([]time.Time, 0, len(ordered))
+	next := now
+	for _, r := range ordered {
+		at := later(next, start(r))
+		if end, quiet := quietEnd(pref, at); quiet {
+			if startOfDay(end).After(startOfDay(at)) {
+				continue
+			}
+			at = end
+		}
+		kept = append(kept, r)
+		slots = append(slots, at)
+		next = at.Add(gap)
+	}
+	return kept, slots
+}
+
+// quietEnd reports whether t falls in pref's quiet hours, and when they end.
+func quietEnd(pref model.UserPreference, t time.Time) (time.Time, bool) {
+	if pref.QuietStart == nil || pref.QuietEnd == nil {
+		return time.Time{}, false
+	}
+	first, last, hour := *pref.QuietStart, *pref.QuietEnd, t.Hour()
+	y, m, d := t.Date()
+	switch {
+	case first < last && hour >= first && hour < last, first > last && hour < last:
+		return time.Date(y, m, d, last, 0, 0, 0, t.Location()), true
+	case first > last && hour >= first:
+		return time.Date(y, m, d+1, last, 0, 0, 0, t.Location()), true
+	}
+	return time.Time{}, false
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+var bucketRequestPattern = regexp.MustCompile(`^(?:set\s+)?(?:my\s+)?(morning|afternoon|evening)\s+(?:reminders\s+)?(?:is\s+|to\s+|at\s+|starts at\s+)?(\d{1,2})(?::00)?\s*(am|pm)?$`)
+
+// parseBucketRequest recognises "set evening to 7pm" and "morning at 8",
+// returning the bucket and the hour in 24-hour time. An hour before noon
+// without am or pm is taken as pm for the afternoon and evening.
+func parseBucketRequest(body string) (string, int, bool) {
+	matches := bucketRequestPattern.FindStringSubmatch(body)
+	if matches == nil {
+		return "", 0, false
+	}
+	hour, err := strconv.Atoi(matches[2])
+	if err != nil || hour > 23 || (matches[3] != "" && (hour < 1 || hour > 12)) {
+		return "", 0, false
+	}
+	switch {
+	case matches[3] == "am" && hour == 12:
+		hour = 0
+	case matches[3] == "pm" && hour < 12:
+		hour += 12
+	case matches[3] == "" && matches[1] != model.TimeMorning && hour < 12:
+		hour += 12
+	}
+	return matches[1], hour, true
+}
+
+// setBucketHour stores the hour userID gets bucket's reminders at.
+func (b *Bot) setBucketHour(userID, bucket string, hour int) (string, error) {
+	bounds := bucketRanges[bucket]
+	if hour < bounds[0] || hour > bounds[1] {
+		return "", userError{fmt.Sprintf("Pick an hour between %s and %s for the %s.", formatHour(bounds[0]), formatHour(bounds[1]), bucket)}
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		switch bucket {
+		case model.TimeMorning:
+			p.MorningHour = &hour
+		case model.TimeAfternoon:
+			p.AfternoonHour = &hour
+		case model.TimeEvening:
+			p.EveningHour = &hour
+		}
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't change that time. Please try again later")
+	}
+	return fmt.Sprintf("Done! I'll send your %s reminders at %s.", bucket, formatHour(hour)), nil
+}
+
+// formatHour renders an hour of the day as "9am" or "7pm".
+func formatHour(hour int) string {
+	switch {
+	case hour == 0:
+		return "12am"
+	case hour < 12:
+		return fmt.Sprintf("%dam", hour)
+	case hour == 12:
+		return "12pm"
+	}
+	return fmt.Sprintf("%dpm", hour-12)
+}
+
+var (
+	deliveryTimeRequestPattern = regexp.MustCompile(`^(?:set\s+)?(?:my\s+)?(?:delivery|reminder)\s+time\s+(?:to\s+|at\s+|is\s+)?(\d{1,2})(?::00)?\s*(am|pm)?$`)
+	quietHoursRequestPattern   = regexp.MustCompile(`^(?:set\s+)?(?:my\s+)?quiet\s+hours\s+(?:to\s+|are\s+|from\s+)?(\d{1,2})(?::00)?\s*(am|pm)?\s*(?:to|-|until|till)\s*(\d{1,2})(?::00)?\s*(am|pm)?$`)
+)
+
+// clockHour reads an hour such as "7" with "pm" as 19. Without am or pm the
+// hour is taken as 24-hour time.
+func clockHour(digits, suffix string) (int, bool) {
+	hour, err := strconv.Atoi(digits)
+	switch {
+	case err != nil || hour > 23:
+		return 0, false
+	case suffix == "":
+		return hour, true
+	case hour < 1 || hour > 12:
+		return 0, false
+	case hour == 12:
+		hour = 0
+	}
+	if suffix == "pm" {
+		hour += 12
+	}
+	return hour, true
+}
+
+// parseDeliveryTimeRequest recognises "set delivery time 7am", returning the
+// hour in 24-hour time.
+func parseDeliveryTimeRequest(body string) (int, bool) {
+	matches := deliveryTimeRequestPattern.FindStringSubmatch(body)
+	if matches == nil {
+		return 0, false
+	}
+	return clockHour(matches[1], matches[2])
+}
+
+// parseQuietHoursRequest recognises "quiet hours 10pm to 7am", returning the
+// hours they start and end at in 24-hour time.
+func parseQuietHoursRequest(body string) (int, int, bool) {
+	matches := quietHoursRequestPattern.FindStringSubmatch(body)
+	if matches == nil {
+		return 0, 0, false
+	}
+	first, ok1 := clockHour(matches[1], matches[2])
+	last, ok2 := clockHour(matches[3], matches[4])
+	return first, last, ok1 && ok2
+}
+
+// setDeliveryHour stores the hour userID's daily reminders start going out,
+// or clears it when hour is nil.
+func (b *Bot) setDeliveryHour(userID string, hour *int) (string, error) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.DeliveryHour = hour
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't change your delivery time. Please try again later")
+	}
+	run, ok := b.dispatchTime(time.Now().In(b.location(b.preferences(userID))))
+	switch {
+	case hour == nil:
+		return "Done! Your reminders will go out as soon as the daily run starts.", nil
+	case ok && *hour < run.Hour():
+		return fmt.Sprintf("Done! The daily run starts at %s your time, so your reminders will still go out then rather than at %s.", run.Format("3:04pm"), formatHour(*hour)), nil
+	}
+	return fmt.Sprintf("Done! Your reminders will start going out at %s.", formatHour(*hour)), nil
+}
+
+// setQuietHours stores the hours userID gets no reminders in, or clears them
+// when first and last are nil.
+func (b *Bot) setQuietHours(userID string, first, last *int) (string, error) {
+	if first != nil && *first == *last {
+		return "", userError{"Quiet hours need to start and end at different times, as in 'quiet hours 10pm to 7am'."}
+	}
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.QuietStart, p.QuietEnd = first, last
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't change your quiet hours. Please try again later")
+	}
+	if first == nil {
+		return "Quiet hours are off. Reminders can come at any time again.", nil
+	}
+	return fmt.Sprintf("Done! No reminders from %s to %s; any that would land then wait until it ends.", formatHour(*first), formatHour(*last)), nil
+}
@@ -0,0 +1,212 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	myopenai "github.com/pathakanu/myMemo/internal/openai"
+)
+
+var timezoneRequestPattern = regexp.MustCompile(`(?i)^(?:(?:i'?m|i am)\s+(?:now\s+in\s+(.+?)|in\s+(.+?)\s+now)|i(?:'ve| have)?\s+(?:just\s+)?(?:moved|landed|arrived)\s+(?:to|in)\s+(.+?)|(?:set\s+|change\s+)?(?:my\s+)?time\s?zone\s+(?:to\s+|is\s+)?(.+?))[.!]*$`)
+
+// cityTimezones answers "I'm in London now" for well-known cities without an
+// OpenAI call.
+var cityTimezones = map[string]string{
+	"amsterdam":     "Europe/Amsterdam",
+	"bangalore":     "Asia/Kolkata",
+	"bengaluru":     "Asia/Kolkata",
+	"berlin":        "Europe/Berlin",
+	"chicago":       "America/Chicago",
+	"delhi":         "Asia/Kolkata",
+	"dubai":         "Asia/Dubai",
+	"hong kong":     "Asia/Hong_Kong",
+	"lisbon":        "Europe/Lisbon",
+	"london":        "Europe/London",
+	"los angeles":   "America/Los_Angeles",
+	"madrid":        "Europe/Madrid",
+	"mexico city":   "America/Mexico_City",
+	"mumbai":        "Asia/Kolkata",
+	"new york":      "America/New_York",
+	"nyc":           "America/New_York",
+	"paris":         "Europe/Paris",
+	"san francisco": "America/Los_Angeles",
+	"sao paulo":     "America/Sao_Paulo",
+	"seattle":       "America/Los_Angeles",
+	"singapore":     "Asia/Singapore",
+	"sydney":        "Australia/Sydney",
+	"tokyo":         "Asia/Tokyo",
+	"toronto":       "America/Toronto",
+}
+
+// location returns the timezone pref's user is in, or the server's.
+func (b *Bot) location(pref model.UserPreference) *time.Location {
+	if pref.Timezone != "" {
+		if loc, err := time.LoadLocation(pref.Timezone); err == nil {
+			return loc
+		}
+	}
+	return b.cfg.LocalTimezone
+}
+
+// handleTimezoneCommand moves the user to where they say they are, as in
+// "I'm in London now" or "set timezone Asia/Kolkata". It reports false for
+// other messages.
+func (b *Bot) handleTimezoneCommand(ctx context.Context, userID, body string) (string, bool) {
+	m := timezoneRequestPattern.FindStringSubmatch(strings.TrimSpace(body))
+	if m == nil {
+		return "", false
+	}
+	place := strings.TrimSpace(m[1] + m[2] + m[3] + m[4])
+	loc, ok := b.placeTimezone(ctx, userID, place)
+	switch {
+	case !ok && m[4] == "":
+		// "I'm now in a meeting" is not about timezones.
+		return "", false
+	case !ok:
+		return fmt.Sprintf("I don't know which timezone %s is in. Try a nearby city, or a zone like 'set timezone Europe/London'.", place), true
+	}
+	msg, err := b.moveTimezone(userID, loc)
+	if err != nil {
+		b.logger.Printf("timezone for %s: %v", userID, err)
+		return err.Error(), true
+	}
+	return "Got it! " + msg, true
+}
+
+// placeTimezone looks up the timezone of a place named in a message: an IANA
+// name, a well-known city, or anywhere OpenAI recognises.
+func (b *Bot) placeTimezone(ctx context.Context, userID, place string) (*time.Location, bool) {
+	if strings.Contains(place, "/") || strings.EqualFold(place, "UTC") {
+		for _, name := range []string{place, ianaCase(place)} {
+			if loc, err := time.LoadLocation(name); err == nil {
+				return loc, true
+			}
+		}
+	}
+	if name, ok := cityTimezones[strings.ToLower(place)]; ok {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, true
+		}
+	}
+	return b.resolveTimezone(ctx, userID, place)
+}
+
+// resolveTimezone asks OpenAI for the timezone of place, when the user may
+// use it.
+func (b *Bot) resolveTimezone(ctx context.Context, userID, place string) (*time.Location, bool) {
+	if b.openAI == nil || !b.useOpenAI(ctx, userID) {
+		return nil, false
+	}
+	name, err := b.openAI.ResolveTimezone(ctx, place)
+	if err != nil {
+		if !errors.Is(err, myopenai.ErrClientNotInitialised) {
+			b.logger.Printf("openai timezone error: %v", err)
+		}
+		return nil, false
+	}
+	if name == "" {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		b.logger.Printf("timezone: unknown zone %q for %q", name, place)
+		return nil, false
+	}
+	return loc, true
+}
+
+// pinTimezone works out the timezone of a shared location pin. Without
+// OpenAI it falls back to the whole-hour offset of the pin's longitude.
+func (b *Bot) pinTimezone(ctx context.Context, userID string, pin sharedLocation) *time.Location {
+	if loc, ok := b.resolveTimezone(ctx, userID, fmt.Sprintf("%.4f,%.4f", pin.Latitude, pin.Longitude)); ok {
+		return loc
+	}
+	hours := int(math.Round(pin.Longitude / 15))
+	if hours == 0 {
+		return time.UTC
+	}
+	// Etc zones count the other way: Etc/GMT-5 is five hours ahead of UTC.
+	loc, err := time.LoadLocation(fmt.Sprintf("Etc/GMT%+d", -hours))
+	if err != nil {
+		return time.FixedZone(fmt.Sprintf("UTC%+d", hours), hours*3600)
+	}
+	return loc
+}
+
+// ianaCase capitalises each part of a zone name, so "america/new_york"
+// loads as "America/New_York".
+func ianaCase(name string) string {
+	if strings.EqualFold(name, "UTC") {
+		return "UTC"
+	}
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '_' })
+	seps := strings.FieldsFunc(name, func(r rune) bool { return r != '/' && r != '_' })
+	var sb strings.Builder
+	for i, part := range parts {
+		sb.WriteString(strings.ToUpper(part[:1]) + strings.ToLower(part[1:]))
+		if i < len(seps) {
+			sb.WriteString(seps[i])
+		}
+	}
+	return sb.String()
+}
+
+// moveTimezone stores loc as the user's timezone and moves their waiting
+// reminders so each still goes out at the same time of day there.
+func (b *Bot) moveTimezone(userID string, loc *time.Location) (string, error) {
+	from := b.location(b.preferences(userID))
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.Timezone = loc.String()
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't change your timezone. Please try again later")
+	}
+	moved := b.sends.Shift(userID, func(at time.Time) time.Time {
+		return sameWallClock(at, from, loc)
+	})
+	msg := fmt.Sprintf("You're on %s time now (%s there).", zoneName(loc), time.Now().In(loc).Format("3:04pm"))
+	if moved > 0 {
+		msg += fmt.Sprintf(" I moved %s so they still arrive at the same time of day.", plural(moved, "waiting reminder"))
+	}
+	return msg, nil
+}
+
+// zoneName names loc for a reply, writing Etc zones as a UTC offset.
+func zoneName(loc *time.Location) string {
+	if !strings.HasPrefix(loc.String(), "Etc/") {
+		return loc.String()
+	}
+	if _, offset := time.Now().In(loc).Zone(); offset != 0 {
+		return fmt.Sprintf("UTC%+d", offset/3600)
+	}
+	return "UTC"
+}
+
+// sameWallClock returns the time in to that reads the same on the clock as t
+// does in from.
+func sameWallClock(t time.Time, from, to *time.Location) time.Time {
+	t = t.In(from)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), to)
+}
+
+// followPin moves the user to a shared pin's timezone when its UTC offset
+// differs from theirs, and returns a note saying so or "".
+func (b *Bot) followPin(ctx context.Context, userID string, pin sharedLocation) string {
+	now := time.Now()
+	loc := b.pinTimezone(ctx, userID, pin)
+	_, current := now.In(b.location(b.preferences(userID))).Zone()
+	if _, offset := now.In(loc).Zone(); offset == current {
+		return ""
+	}
+	msg, err := b.moveTimezone(userID, loc)
+	if err != nil {
+		b.logger.Printf("timezone for %s: %v", userID, err)
+		return ""
+	}
+	return msg
+}
@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// tipInterval is the least time between two tips to the same user.
+const tipInterval = 7 * 24 * time.Hour
+
+// tip is a usage tip about one feature. tried reports whether the user has
+// already used the feature, in which case the tip isn't sent.
+type tip struct {
+	key   string
+	text  string
+	tried func(b *Bot, pref model.UserPreference) bool
+}
+
+// tips are sent in order, skipping features the user has tried and tips
+// they have already had.
+var tips = []tip{
+	{
+		key:  "habits",
+		text: "Track something you do every day with \"habit: meditate 10 minutes\", then say \"done\" each day to keep the streak going.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Reminder{}, "user_id = ? AND kind = ?", pref.UserID, model.KindHabit)
+		},
+	},
+	{
+		key:  "recurring",
+		text: "\"Water the plants every 3 days\" saves a reminder that comes back on its own, so you only add it once.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Reminder{}, "user_id = ? AND rrule <> ''", pref.UserID)
+		},
+	},
+	{
+		key:  "time-of-day",
+		text: "\"Remind me in the evening to water plants\" holds a reminder until the morning, afternoon, or evening.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Reminder{}, "user_id = ? AND time_of_day <> ''", pref.UserID)
+		},
+	},
+	{
+		key:  "templates",
+		text: "Add the same reminder often? \"Save template gym: Go to the gym, priority 4\", then just send \"gym\".",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Template{}, "user_id = ?", pref.UserID)
+		},
+	},
+	{
+		key:  "lists",
+		text: "Keep named lists with \"add milk to my shopping list\", and \"show my shopping list\" to see one.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.List{}, "user_id = ?", pref.UserID)
+		},
+	},
+	{
+		key:  "notes",
+		text: "Jot something down without a reminder: \"note: the spare key is with Asha\". \"Notes\" shows them all.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Memo{}, "user_id = ? AND kind = ?", pref.UserID, model.MemoKindNote)
+		},
+	},
+	{
+		key:  "links",
+		text: "Send a link on its own to save it to your reading list, and \"show my links\" when you have time to read.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Memo{}, "user_id = ? AND kind = ?", pref.UserID, model.MemoKindLink)
+		},
+	},
+	{
+		key:  "birthdays",
+		text: "\"Birthday: Asha on March 3\" gets you a heads-up every year, so you never miss one.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Reminder{}, "user_id = ? AND kind IN ?", pref.UserID, []string{model.KindBirthday, model.KindAnniversary})
+		},
+	},
+	{
+		key:  "location",
+		text: "Share a location pin, then \"remind me about this place: buy bread\" to get a map link with the reminder.",
+		tried: func(b *Bot, pref model.UserPreference) bool {
+			return b.userHas(&model.Reminder{}, "user_id = ? AND latitude IS NOT NULL", pref.UserID)
+		},
+	},
+	{
+		key:  "quiet-hours",
+		text: "\"Quiet hours 10pm to 7am\" keeps your nights free of reminders.",
+		tried: func(_ *Bot, pref model.UserPreference) bool {
+			return pref.QuietStart != nil
+		},
+	},
+	{
+		key:  "digest",
+		text: "Prefer email? \"My email is you@example.com\", then \"send my reminders by email\" for one daily digest.",
+		tried: func(_ *Bot, pref model.UserPreference) bool {
+			return pref.Email != ""
+		},
+	},
+}
+
+// userHas reports whether any row of value's table matches query. Errors
+// count as a match, so a failing lookup never sends a tip about something the
+// user already does.
+func (b *Bot) userHas(value any, query string, args ...any) bool {
+	var n int64
+	if err := b.db.Model(value).Where(query, args...).Limit(1).Count(&n).Error; err != nil {
+		b.logger.Printf("tips: check feature use: %v", err)
+		return true
+	}
+	return n > 0
+}
+
+// nextTip returns the first tip the user hasn't had about a feature they
+// haven't tried.
+func (b *Bot) nextTip(pref model.UserPreference) (tip, bool) {
+	sent := strings.Split(pref.TipsSent, ",")
+	for _, t := range tips {
+		if slices.Contains(sent, t.key) || t.tried(b, pref) {
+			continue
+		}
+		return t, true
+	}
+	return tip{}, false
+}
+
+// setTips subscribes the user to tips and announcements, or unsubscribes
+// them.
+func (b *Bot) setTips(userID string, on bool) (string, error) {
+	if err := b.updatePreferences(userID, func(p *model.UserPreference) {
+		p.Tips = on
+	}); err != nil {
+		return "", fmt.Errorf("I couldn't update that setting. Please try again later")
+	}
+	if on {
+		return "Now and then I'll send you a tip about something you haven't tried yet, plus news about the bot. Say 'tips off' to stop them.", nil
+	}
+	return "Okay, no more tips or announcements.", nil
+}
+
+// sendTips sends each subscribed user a tip, at most once every tipInterval.
+func (b *Bot) sendTips() {
+	var prefs []model.UserPreference
+	if err := b.db.Where("tips = ? AND paused = ? AND opted_out_at IS NULL AND unjoined_at IS NULL", true, false).Find(&prefs).Error; err != nil {
+		b.logger.Printf("scheduler: fetch tip subscribers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, pref := range prefs {
+		if pref.TipSentAt != nil && now.Sub(*pref.TipSentAt) < tipInterval {
+			continue
+		}
+		t, ok := b.nextTip(pref)
+		if !ok {
+			continue
+		}
+		if err := b.notify(context.Background(), pref.UserID, "Tip: "+t.text+"\n\nSay 'tips off' to stop these."); err != nil {
+			b.logger.Printf("scheduler: send tip: %v", err)
+			continue
+		}
+		if err := b.updatePreferences(pref.UserID, func(p *model.UserPreference) {
+			p.TipSentAt = &now
+			p.TipsSent = strings.TrimPrefix(p.TipsSent+","+t.key, ",")
+		}); err != nil {
+			b.logger.Printf("scheduler: mark tip sent: %v", err)
+		}
+	}
+}
+
+// announce sends text to every user subscribed to announcements and returns
+// how many there are. The messages go out in the background.
+func (b *Bot) announce(text string) (int, error) {
+	var users []string
+	if err := b.db.Model(&model.UserPreference{}).Where("tips = ? AND opted_out_at IS NULL AND unjoined_at IS NULL", true).Pluck("user_id", &users).Error; err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, nil
+	}
+	message := "Announcement: " + text + "\n\nSay 'tips off' to stop announcements."
+	if !b.sends.Go(func() {
+		for _, userID := range users {
+			if err := b.notify(context.Background(), userID, message); err != nil {
+				b.logger.Printf("announce to %s: %v", userID, err)
+			}
+		}
+	}) {
+		return 0, fmt.Errorf("shutting down")
+	}
+	return len(users), nil
+}
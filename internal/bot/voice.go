@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/channel"
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+// callAbout phones the user about reminder when it is priority 5 and either
+// its message failed or the user has opted into call alerts. body is the
+// message text; only its first line is read out. It reports whether a call
+// was placed.
+func (b *Bot) callAbout(ctx context.Context, reminder model.Reminder, body string, messageFailed bool) bool {
+	if reminder.Priority != 5 || strings.HasPrefix(reminder.UserID, discordUserPrefix) {
+		return false
+	}
+	if _, ok := b.channels[channel.Voice]; !ok {
+		return false
+	}
+	pref := b.preferences(reminder.UserID)
+	if !messageFailed && !pref.CallAlerts {
+		return false
+	}
+	msg := channel.Message{
+		To:       reminder.UserID,
+		Body:     b.translate(reminder.UserID, body),
+		Language: pref.Language,
+	}
+	err := b.send(ctx, channel.Voice, msg)
+	b.recordDelivery(ctx, reminder, channel.Voice, err)
+	if err != nil {
+		b.logger.Printf("scheduler: call about reminder %d: %v", reminder.ID, err)
+		return false
+	}
+	return true
+}
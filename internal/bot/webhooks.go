@@ -0,0 +1,208 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/netguard"
+	"github.com/pathakanu/myMemo/internal/webhook"
+)
+
+var (
+	addWebhookPattern    = regexp.MustCompile(`(?i)^add webhook\s+(https?://\S+)(?:\s+for\s+([a-z.,\s]+))?$`)
+	deleteWebhookPattern = regexp.MustCompile(`(?i)^delete webhooks?\s+(.+)$`)
+)
+
+// handleWebhookCommand manages a user's outbound webhooks. It reports false
+// when the message is not a webhook command.
+func (b *Bot) handleWebhookCommand(userID, body, lowerBody string) (string, bool) {
+	var (
+		msg string
+		err error
+	)
+	switch {
+	case addWebhookPattern.MatchString(body):
+		m := addWebhookPattern.FindStringSubmatch(body)
+		msg, err = b.addWebhook(userID, m[1], m[2])
+	case lowerBody == "webhooks" || lowerBody == "list webhooks" || lowerBody == "show my webhooks":
+		msg, err = b.listWebhooks(userID)
+	case deleteWebhookPattern.MatchString(body):
+		msg, err = b.deleteWebhooks(userID, deleteWebhookPattern.FindStringSubmatch(body)[1])
+	default:
+		return "", false
+	}
+
+	if err != nil {
+		if !isUserError(err) {
+			b.logger.Printf("webhook command: %v", err)
+		}
+		return err.Error(), true
+	}
+	return msg, true
+}
+
+// addWebhook registers an endpoint and returns its signing secret to the user.
+func (b *Bot) addWebhook(userID, url, events string) (string, error) {
+	selected, err := parseWebhookEvents(events)
+	if err != nil {
+		return "", err
+	}
+	if err := netguard.CheckURL(url); err != nil {
+		return "", userError{"Webhooks can only be delivered to public addresses."}
+	}
+	hook := &model.Webhook{
+		UserID: userID,
+		URL:    url,
+		Secret: webhook.NewSecret(),
+		Events: strings.Join(selected, ","),
+	}
+	if err := b.db.Create(hook).Error; err != nil {
+		return "", fmt.Errorf("I couldn't save that webhook. Please try again later")
+	}
+
+	scope := "all reminder events"
+	if hook.Events != "" {
+		scope = hook.Events
+	}
+	return fmt.Sprintf("Webhook added for %s.\nSigning secret: %s\nVerify the %s header (HMAC-SHA256 of the body).", scope, hook.Secret, webhook.SignatureHeader), nil
+}
+
+// listWebhooks renders the user's registered webhooks.
+func (b *Bot) listWebhooks(userID string) (string, error) {
+	hooks, err := b.webhooks(userID)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your webhooks right now. Please try again later")
+	}
+	if len(hooks) == 0 {
+		return "", userError{"You have no webhooks. Add one with 'add webhook https://...'."}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your webhooks:\n")
+	for i, h := range hooks {
+		events := h.Events
+		if events == "" {
+			events = "all events"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s (%s)\n", i+1, h.URL, events))
+	}
+	return sb.String(), nil
+}
+
+// deleteWebhooks removes webhooks by their position in the webhook list.
+func (b *Bot) deleteWebhooks(userID, selector string) (string, error) {
+	indices := parseIndices(selector)
+	if len(indices) == 0 {
+		return "", userError{"Tell me which webhook to delete by number, e.g. 'delete webhook 1'."}
+	}
+	hooks, err := b.webhooks(userID)
+	if err != nil {
+		return "", fmt.Errorf("I couldn't load your webhooks right now. Please try again later")
+	}
+
+	ids := make([]uint, 0, len(indices))
+	for _, idx := range indices {
+		if idx < 1 || idx > len(hooks) {
+			return "", userError{fmt.Sprintf("Webhook %d doesn't exist. Choose between 1 and %d.", idx, len(hooks))}
+		}
+		ids = append(ids, hooks[idx-1].ID)
+	}
+	if err := b.db.Where("user_id = ? AND id IN ?", userID, ids).Delete(&model.Webhook{}).Error; err != nil {
+		return "", fmt.Errorf("I couldn't delete those webhooks. Please try again later")
+	}
+	return fmt.Sprintf("Deleted webhook(s): %s.", formatIndices(indices)), nil
+}
+
+func (b *Bot) webhooks(userID string) ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	err := b.db.Where("user_id = ?", userID).Order("created_at ASC, id ASC").Find(&hooks).Error
+	return hooks, err
+}
+
+// emit publishes a reminder lifecycle event on the event bus, where
+// subscribeEffects hooks up webhooks, Notion, the scheduler, and metrics.
+func (b *Bot) emit(eventType string, reminder model.Reminder, message string) {
+	b.events.publish(newReminderEvent(eventType, reminder, message, ""))
+}
+
+// publish emits an event for a change that came from Notion, so it isn't
+// pushed back there.
+func (b *Bot) publish(eventType string, reminder model.Reminder, message string) {
+	b.events.publish(newReminderEvent(eventType, reminder, message, originNotion))
+}
+
+func newReminderEvent(eventType string, reminder model.Reminder, message, origin string) reminderEvent {
+	event := webhook.NewEvent(eventType, reminder.UserID, webhook.Reminder{
+		ID:       reminder.ID,
+		Content:  reminder.Content,
+		Summary:  reminder.Summary,
+		Priority: reminder.Priority,
+		Kind:     reminder.Kind,
+		Streak:   reminder.Streak,
+		Message:  message,
+	})
+	return reminderEvent{Event: event, Reminder: reminder, Origin: origin}
+}
+
+// emitWebhooks delivers an event to every matching webhook of the reminder's
+// owner. Deliveries run in the background on the send queue.
+func (b *Bot) emitWebhooks(event webhook.Event) {
+	if b.hooks == nil {
+		return
+	}
+	hooks, err := b.webhooks(event.UserID)
+	if err != nil {
+		b.logger.Printf("webhooks: load for %s: %v", event.UserID, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !subscribed(hook, event.Type) {
+			continue
+		}
+		b.sends.Go(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if err := b.hooks.Deliver(ctx, hook.URL, hook.Secret, event); err != nil {
+				b.logger.Printf("webhooks: %v", err)
+			}
+		})
+	}
+}
+
+func subscribed(hook model.Webhook, eventType string) bool {
+	if hook.Events == "" {
+		return true
+	}
+	for _, e := range strings.Split(hook.Events, ",") {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWebhookEvents(input string) ([]string, error) {
+	fields := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	selected := make([]string, 0, len(fields))
+	for _, f := range fields {
+		known := false
+		for _, t := range webhook.EventTypes {
+			if f == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, userError{fmt.Sprintf("Unknown event '%s'. Choose from %s.", f, strings.Join(webhook.EventTypes, ", "))}
+		}
+		selected = append(selected, f)
+	}
+	return selected, nil
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"github.com/pathakanu/myMemo/internal/recurrence"
+)
+
+// weekEntry is one reminder on one day of the week view.
+type weekEntry struct {
+	Reminder model.Reminder
+	At       time.Time
+}
+
+// handleWeekCommand shows the user's due-dated reminders for this week or
+// next, grouped by day from Monday to Sunday. It reports false for other
+// messages.
+func (b *Bot) handleWeekCommand(ctx context.Context, userID, lowerBody string) (string, bool) {
+	weeks := 0
+	switch strings.Trim(strings.TrimSpace(lowerBody), ".!?") {
+	case "my week", "show my week", "show me my week", "what's my week", "what does my week look like", "this week", "show this week":
+	case "next week", "show next week", "my next week", "show my next week", "what's next week":
+		weeks = 1
+	default:
+		return "", false
+	}
+	now := time.Now().In(b.location(b.preferences(userID)))
+	msg, err := b.weekView(ctx, userID, now, weeks)
+	if err != nil {
+		b.logger.Printf("week view for %s: %v", userID, err)
+		return "I couldn't load your week right now. Please try again later.", true
+	}
+	return msg, true
+}
+
+// weekView renders the week starting weeks Mondays after the one on or
+// before now. Repeating reminders appear on each day they fall in it, and
+// reminders overdue from earlier weeks are counted at the end.
+func (b *Bot) weekView(ctx context.Context, userID string, now time.Time, weeks int) (string, error) {
+	open, err := b.openReminders(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	today := startOfDay(now)
+	monday := today.AddDate(0, 0, -(int(now.Weekday())+6)%7+7*weeks)
+	sunday := monday.AddDate(0, 0, 6)
+	end := monday.AddDate(0, 0, 7)
+
+	days := make([][]weekEntry, 7)
+	overdue := 0
+	for _, r := range open {
+		if r.DueAt == nil {
+			continue
+		}
+		at := r.DueAt.In(now.Location())
+		var rule recurrence.Rule
+		repeats := false
+		if r.RRule != "" {
+			if parsed, err := recurrence.Parse(r.RRule); err == nil {
+				rule, repeats = parsed, true
+			}
+		}
+		if at.Before(monday) {
+			if !repeats {
+				if r.Kind == model.KindReminder {
+					overdue++
+				}
+				continue
+			}
+			at = rule.Next(at, monday.AddDate(0, 0, -1))
+		}
+		for !at.IsZero() && at.Before(end) {
+			day := daysUntil(at, monday)
+			days[day] = append(days[day], weekEntry{Reminder: r, At: at})
+			if !repeats {
+				break
+			}
+			at = rule.Next(at, at)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Your week, %s to %s:\n", monday.Format("Mon Jan 2"), sunday.Format("Mon Jan 2"))
+	for i, entries := range days {
+		day := monday.AddDate(0, 0, i)
+		label := bold(day.Format("Mon 2"))
+		if day.Equal(today) {
+			label += " (today)"
+		}
+		if len(entries) == 0 {
+			fmt.Fprintf(&sb, "%s: —\n", label)
+			continue
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			if !entries[i].At.Equal(entries[j].At) {
+				return entries[i].At.Before(entries[j].At)
+			}
+			return entries[i].Reminder.Priority > entries[j].Reminder.Priority
+		})
+		fmt.Fprintf(&sb, "%s:\n", label)
+		for _, e := range entries {
+			fmt.Fprintf(&sb, "- %s %s", e.Reminder.ShortID(), fallback(e.Reminder.Summary, e.Reminder.Content))
+			if e.At.Hour() != 0 || e.At.Minute() != 0 {
+				fmt.Fprintf(&sb, ", %s", e.At.Format("3:04pm"))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if overdue > 0 && weeks == 0 {
+		fmt.Fprintf(&sb, "Also %s overdue from before this week. Say 'list overdue' to see them.\n", plural(overdue, "reminder"))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/holidays"
+	"github.com/pathakanu/myMemo/internal/model"
+)
+
+var businessDaysPattern = regexp.MustCompile(`(?i)\b(?:(?:on\s+)?(?:business|working|work)\s+days\s+only|only\s+on\s+(?:business|working|work)\s+days|(?:on\s+)?weekdays\s+only|only\s+on\s+weekdays)\b`)
+
+// parseBusinessDays reports whether a reminder asks to stick to working
+// days, as in "file timesheets every day, business days only".
+func parseBusinessDays(content string) bool {
+	return businessDaysPattern.MatchString(content)
+}
+
+// calendar returns the working-day calendar set by HOLIDAY_COUNTRY and
+// HOLIDAY_DATES.
+func (b *Bot) calendar() *holidays.Calendar {
+	b.mu.RLock()
+	country, dates := b.cfg.HolidayCountry, b.cfg.HolidayDates
+	b.mu.RUnlock()
+	cal, err := newCalendar(country, dates)
+	if err != nil {
+		b.logger.Printf("holidays: %v", err)
+		return &holidays.Calendar{}
+	}
+	return cal
+}
+
+func newCalendar(country string, dates []string) (*holidays.Calendar, error) {
+	extra := make([]time.Time, 0, len(dates))
+	for _, value := range dates {
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, day)
+	}
+	return holidays.New(country, extra)
+}
+
+// workingDue moves due on to the next working day when reminder keeps to
+// business days.
+func (b *Bot) workingDue(reminder model.Reminder, due time.Time) time.Time {
+	if !reminder.BusinessDays {
+		return due
+	}
+	return b.calendar().NextWorkingDay(due)
+}
@@ -0,0 +1,350 @@
+// Package caldav keeps reminders as tasks (VTODOs) in a CalDAV task list,
+// such as iCloud Reminders or Nextcloud Tasks. It speaks just enough of
+// RFC 4791 to find a task list, write and delete tasks, and read them back.
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/netguard"
+)
+
+// Account is a task list on a CalDAV server and the credentials for it.
+// Servers such as iCloud want an app-specific password.
+type Account struct {
+	// CollectionURL is the task list itself, ending in a slash.
+	CollectionURL string
+	Username      string
+	Password      string
+}
+
+// ErrInsecureURL means a server address or a link the server returned
+// isn't https. Credentials are only ever sent over TLS.
+var ErrInsecureURL = errors.New("caldav: server address must be https")
+
+// ErrNoTaskList means the server has no calendar that holds tasks where
+// FindTaskList looked.
+var ErrNoTaskList = errors.New("caldav: no task list found")
+
+// HTTPError is a CalDAV request the server refused.
+type HTTPError struct {
+	Method string
+	URL    string
+	Status int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("caldav: %s %s: %d %s", e.Method, e.URL, e.Status, http.StatusText(e.Status))
+}
+
+// Client makes CalDAV requests. Credentials are passed per call because
+// every user connects their own server.
+type Client struct {
+	http *http.Client
+}
+
+// New returns a Client that only connects to public addresses over https,
+// including after redirects.
+func New() *Client {
+	client := netguard.NewClient(30 * time.Second)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return ErrInsecureURL
+		}
+		if len(via) >= 10 {
+			return errors.New("caldav: too many redirects")
+		}
+		return nil
+	}
+	return NewWithClient(client)
+}
+
+// NewWithClient returns a Client that uses client, mainly for tests.
+func NewWithClient(client *http.Client) *Client {
+	return &Client{http: client}
+}
+
+// FindTaskList returns the URL of the task list at serverURL: serverURL
+// itself when it is one, or else the first calendar that holds tasks under
+// the user's calendar home, preferring one named Reminders or Tasks.
+func (c *Client) FindTaskList(ctx context.Context, serverURL, username, password string) (string, error) {
+	acct := Account{Username: username, Password: password}
+	props := `<d:resourcetype/><d:current-user-principal/><c:calendar-home-set/><c:supported-calendar-component-set/>`
+	responses, err := c.propfind(ctx, acct, serverURL, "0", props)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range responses {
+		if r.isTaskList() {
+			return resolve(serverURL, r.Href), nil
+		}
+	}
+
+	home := ""
+	for _, r := range responses {
+		if href := r.prop().CalendarHome.Href; href != "" {
+			home = resolve(serverURL, href)
+		}
+	}
+	if home == "" {
+		principal := ""
+		for _, r := range responses {
+			if href := r.prop().Principal.Href; href != "" {
+				principal = resolve(serverURL, href)
+			}
+		}
+		if principal == "" {
+			return "", fmt.Errorf("%w: %s names no user principal", ErrNoTaskList, serverURL)
+		}
+		found, err := c.propfind(ctx, acct, principal, "0", `<c:calendar-home-set/>`)
+		if err != nil {
+			return "", err
+		}
+		for _, r := range found {
+			if href := r.prop().CalendarHome.Href; href != "" {
+				home = resolve(principal, href)
+			}
+		}
+		if home == "" {
+			return "", fmt.Errorf("%w: %s has no calendar home", ErrNoTaskList, principal)
+		}
+	}
+
+	calendars, err := c.propfind(ctx, acct, home, "1", `<d:resourcetype/><d:displayname/><c:supported-calendar-component-set/>`)
+	if err != nil {
+		return "", err
+	}
+	best := ""
+	for _, r := range calendars {
+		if !r.isTaskList() {
+			continue
+		}
+		switch strings.ToLower(r.prop().DisplayName) {
+		case "reminders", "tasks":
+			return resolve(home, r.Href), nil
+		}
+		if best == "" {
+			best = resolve(home, r.Href)
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w under %s", ErrNoTaskList, home)
+	}
+	return best, nil
+}
+
+// Put writes todo to the task list, replacing any task with its UID.
+func (c *Client) Put(ctx context.Context, acct Account, todo Todo) error {
+	req, err := c.request(ctx, acct, http.MethodPut, acct.taskURL(todo.UID), todo.Encode())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	_, err = c.do(req)
+	return err
+}
+
+// Delete removes the task with uid. A task that is already gone is not an
+// error.
+func (c *Client) Delete(ctx context.Context, acct Account, uid string) error {
+	req, err := c.request(ctx, acct, http.MethodDelete, acct.taskURL(uid), nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(req)
+	if e, ok := err.(*HTTPError); ok && e.Status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Todos returns every task in the task list.
+func (c *Client) Todos(ctx context.Context, acct Account) ([]Todo, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`
+	req, err := c.request(ctx, acct, "REPORT", acct.CollectionURL, []byte(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	data, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("caldav: decode task list: %w", err)
+	}
+	var todos []Todo
+	for _, r := range ms.Responses {
+		if r.prop().CalendarData == "" {
+			continue
+		}
+		if todo, ok := ParseTodo(r.prop().CalendarData); ok {
+			todos = append(todos, todo)
+		}
+	}
+	return todos, nil
+}
+
+// taskURL is where the task with uid is stored.
+func (a Account) taskURL(uid string) string {
+	return strings.TrimRight(a.CollectionURL, "/") + "/" + url.PathEscape(uid) + ".ics"
+}
+
+func (c *Client) propfind(ctx context.Context, acct Account, target, depth, props string) ([]response, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?><d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop>` + props + `</d:prop></d:propfind>`
+	req, err := c.request(ctx, acct, "PROPFIND", target, []byte(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", depth)
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	data, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("caldav: decode %s: %w", target, err)
+	}
+	return ms.Responses, nil
+}
+
+func (c *Client) request(ctx context.Context, acct Account, method, target string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: build request: %w", err)
+	}
+	if req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrInsecureURL, target)
+	}
+	req.SetBasicAuth(acct.Username, acct.Password)
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: %s %s: %w", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, fmt.Errorf("caldav: read %s: %w", req.URL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPError{Method: req.Method, URL: req.URL.String(), Status: resp.StatusCode}
+	}
+	return data, nil
+}
+
+// resolve makes href, which servers often send as an absolute path,
+// absolute against base.
+func resolve(base, href string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return href
+	}
+	h, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return href
+	}
+	return b.ResolveReference(h).String()
+}
+
+type multistatus struct {
+	Responses []response `xml:"DAV: response"`
+}
+
+type response struct {
+	Href      string     `xml:"DAV: href"`
+	Propstats []propstat `xml:"DAV: propstat"`
+}
+
+type propstat struct {
+	Prop   prop   `xml:"DAV: prop"`
+	Status string `xml:"DAV: status"`
+}
+
+type prop struct {
+	ResourceType struct {
+		Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	DisplayName string `xml:"DAV: displayname"`
+	Principal   struct {
+		Href string `xml:"DAV: href"`
+	} `xml:"DAV: current-user-principal"`
+	CalendarHome struct {
+		Href string `xml:"DAV: href"`
+	} `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	Components struct {
+		Comps []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+// prop merges the properties the server found, skipping those it reports
+// as missing.
+func (r response) prop() prop {
+	var merged prop
+	for _, ps := range r.Propstats {
+		if ps.Status != "" && !strings.Contains(ps.Status, " 200 ") {
+			continue
+		}
+		p := ps.Prop
+		if p.ResourceType.Calendar != nil {
+			merged.ResourceType = p.ResourceType
+		}
+		merged.DisplayName = firstNonEmpty(merged.DisplayName, p.DisplayName)
+		merged.Principal.Href = firstNonEmpty(merged.Principal.Href, p.Principal.Href)
+		merged.CalendarHome.Href = firstNonEmpty(merged.CalendarHome.Href, p.CalendarHome.Href)
+		merged.Components.Comps = append(merged.Components.Comps, p.Components.Comps...)
+		merged.CalendarData = firstNonEmpty(merged.CalendarData, p.CalendarData)
+	}
+	return merged
+}
+
+// isTaskList reports whether the response is a calendar that holds tasks.
+// A calendar that doesn't list its components holds any kind.
+func (r response) isTaskList() bool {
+	p := r.prop()
+	if p.ResourceType.Calendar == nil {
+		return false
+	}
+	if len(p.Components.Comps) == 0 {
+		return true
+	}
+	for _, comp := range p.Components.Comps {
+		if strings.EqualFold(comp.Name, "VTODO") {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
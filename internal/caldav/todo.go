@@ -0,0 +1,161 @@
+package caldav
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Todo is one task in a task list.
+type Todo struct {
+	UID         string
+	Summary     string
+	Description string
+	// Due is when the task is due. A time at midnight is sent as a date.
+	Due *time.Time
+	// Priority is 1 (low) to 5 (urgent), as on reminders, or 0 for none.
+	Priority int
+	// Completed is when the task was done, or nil while it is open.
+	Completed *time.Time
+}
+
+// Encode renders the task as an iCalendar (RFC 5545) object.
+func (t Todo) Encode() []byte {
+	now := time.Now().UTC()
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//myMemo//Reminders//EN",
+		"BEGIN:VTODO",
+		"UID:" + t.UID,
+		"DTSTAMP:" + now.Format("20060102T150405Z"),
+		"SUMMARY:" + escape(t.Summary),
+	}
+	if t.Description != "" {
+		lines = append(lines, "DESCRIPTION:"+escape(t.Description))
+	}
+	if t.Due != nil {
+		if t.Due.Hour() == 0 && t.Due.Minute() == 0 {
+			lines = append(lines, "DUE;VALUE=DATE:"+t.Due.Format("20060102"))
+		} else {
+			lines = append(lines, "DUE:"+t.Due.Format("20060102T150405"))
+		}
+	}
+	if t.Priority > 0 {
+		// iCalendar runs from 1 (highest) to 9 (lowest).
+		lines = append(lines, fmt.Sprintf("PRIORITY:%d", 11-2*min(t.Priority, 5)))
+	}
+	if t.Completed != nil {
+		lines = append(lines, "STATUS:COMPLETED", "COMPLETED:"+t.Completed.UTC().Format("20060102T150405Z"))
+	} else {
+		lines = append(lines, "STATUS:NEEDS-ACTION")
+	}
+	lines = append(lines, "END:VTODO", "END:VCALENDAR")
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(fold(line))
+		sb.WriteString("\r\n")
+	}
+	return []byte(sb.String())
+}
+
+// ParseTodo reads the first VTODO in an iCalendar object. Only the fields
+// the sync needs are filled in: UID, SUMMARY, and whether it is done.
+func ParseTodo(data string) (Todo, bool) {
+	var t Todo
+	inTodo, found := false, false
+	for _, line := range unfold(data) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VTODO") && !found {
+				inTodo = true
+			}
+			continue
+		case "END":
+			if strings.EqualFold(value, "VTODO") && inTodo {
+				inTodo, found = false, true
+			}
+			continue
+		}
+		if !inTodo {
+			continue
+		}
+		switch strings.ToUpper(name) {
+		case "UID":
+			t.UID = value
+		case "SUMMARY":
+			t.Summary = unescape(value)
+		case "DESCRIPTION":
+			t.Description = unescape(value)
+		case "STATUS":
+			if strings.EqualFold(value, "COMPLETED") && t.Completed == nil {
+				done := time.Now().UTC()
+				t.Completed = &done
+			}
+		case "COMPLETED":
+			if at, ok := parseTime(value); ok {
+				t.Completed = &at
+			}
+		}
+	}
+	return t, found && t.UID != ""
+}
+
+// parseTime reads a DATE-TIME or DATE value. Times without a zone are read
+// as UTC, which is close enough for a completion time.
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// escape escapes text for an iCalendar property value.
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// unescape undoes escape.
+func unescape(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(text)
+}
+
+// fold splits line into pieces of at most 75 bytes, continuing each on a
+// new line that starts with a space, without breaking a UTF-8 character.
+func fold(line string) string {
+	var sb strings.Builder
+	width := 0
+	for _, c := range line {
+		n := len(string(c))
+		if width+n > 75 {
+			sb.WriteString("\r\n ")
+			width = 1
+		}
+		sb.WriteRune(c)
+		width += n
+	}
+	return sb.String()
+}
+
+// unfold splits data into logical lines, joining folded continuations.
+func unfold(data string) []string {
+	var lines []string
+	for _, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += raw[1:]
+			continue
+		}
+		if raw != "" {
+			lines = append(lines, raw)
+		}
+	}
+	return lines
+}
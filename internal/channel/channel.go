@@ -0,0 +1,33 @@
+package channel
+
+import "context"
+
+// Channel names.
+const (
+	WhatsApp = "whatsapp"
+	Email    = "email"
+	Discord  = "discord"
+	Voice    = "voice"
+)
+
+// Message is a notification addressed to a single recipient. Channels that
+// have no notion of a subject ignore it.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Language is the ISO 639-1 code of Body, for channels that read it
+	// aloud. Empty means English.
+	Language string
+	// StatusCallback is a URL the channel reports delivery progress to, for
+	// channels that support it.
+	StatusCallback string
+	// MediaURL links to a file sent along with Body, for channels that
+	// attach files. Others ignore it.
+	MediaURL string
+}
+
+// Sender delivers messages over one channel.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
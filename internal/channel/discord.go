@@ -0,0 +1,27 @@
+package channel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pathakanu/myMemo/internal/discord"
+)
+
+// DiscordSender delivers messages as Discord DMs from the bot user.
+// Recipients are Discord user IDs.
+type DiscordSender struct {
+	client *discord.Client
+}
+
+// NewDiscord wraps a Discord client as a Sender.
+func NewDiscord(client *discord.Client) *DiscordSender {
+	return &DiscordSender{client: client}
+}
+
+// Send delivers msg.Body to msg.To as a direct message.
+func (s *DiscordSender) Send(ctx context.Context, msg Message) error {
+	if s.client == nil {
+		return errors.New("discord: client not configured")
+	}
+	return s.client.SendDM(ctx, msg.To, msg.Body)
+}
@@ -0,0 +1,144 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// EmailConfig selects and configures an email provider. SendGrid is used when
+// SendGridAPIKey is set, SMTP when SMTPHost is set.
+type EmailConfig struct {
+	From           string
+	SendGridAPIKey string
+	SMTPHost       string
+	SMTPPort       int
+	SMTPUsername   string
+	SMTPPassword   string
+}
+
+// NewEmail returns the configured email Sender, or nil when email delivery is
+// not configured.
+func NewEmail(cfg EmailConfig) Sender {
+	switch {
+	case cfg.From == "":
+		return nil
+	case cfg.SendGridAPIKey != "":
+		return NewSendGrid(cfg.SendGridAPIKey, cfg.From)
+	case cfg.SMTPHost != "":
+		return NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	}
+	return nil
+}
+
+// SMTPSender delivers plain-text email through an SMTP relay using STARTTLS
+// when the server offers it.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTP returns an SMTPSender for host:port. Authentication is skipped when
+// username is empty.
+func NewSMTP(host string, port int, username, password, from string) *SMTPSender {
+	if port == 0 {
+		port = 587
+	}
+	s := &SMTPSender{
+		addr: net.JoinHostPort(host, fmt.Sprint(port)),
+		from: from,
+	}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// Send delivers msg to the address in msg.To.
+func (s *SMTPSender) Send(_ context.Context, msg Message) error {
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, composeEmail(s.from, msg)); err != nil {
+		return fmt.Errorf("smtp: send to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// composeEmail renders msg as an RFC 5322 plain-text message.
+func composeEmail(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// SendGridSender delivers email through the SendGrid v3 mail API.
+type SendGridSender struct {
+	// BaseURL overrides the API root, mainly for tests.
+	BaseURL string
+	apiKey  string
+	from    string
+	http    *http.Client
+}
+
+// NewSendGrid returns a SendGridSender that sends from the given address.
+func NewSendGrid(apiKey, from string) *SendGridSender {
+	return &SendGridSender{
+		BaseURL: "https://api.sendgrid.com",
+		apiKey:  apiKey,
+		from:    from,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Send delivers msg to the address in msg.To.
+func (s *SendGridSender) Send(ctx context.Context, msg Message) error {
+	type address struct {
+		Email string `json:"email"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	payload := map[string]any{
+		"personalizations": []map[string]any{{"to": []address{{Email: msg.To}}}},
+		"from":             address{Email: s.from},
+		"subject":          msg.Subject,
+		"content":          []content{{Type: "text/plain", Value: msg.Body}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("sendgrid: encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.BaseURL, "/")+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sendgrid: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("sendgrid: send to %s: %w", msg.To, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid: send to %s: unexpected status %d", msg.To, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package channel
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+
+	"github.com/pathakanu/myMemo/internal/twilio"
+)
+
+// voiceLocales maps reply languages to the locales Twilio's text-to-speech
+// reads them in.
+var voiceLocales = map[string]string{
+	"es": "es-ES",
+	"fr": "fr-FR",
+	"pt": "pt-BR",
+}
+
+// VoiceSender reads messages aloud in a phone call placed through Twilio
+// Voice. Recipients are phone numbers.
+type VoiceSender struct {
+	client *twilio.Client
+	from   string
+}
+
+// NewVoice wraps a Twilio client as a Sender that calls from the voice
+// number from.
+func NewVoice(client *twilio.Client, from string) *VoiceSender {
+	return &VoiceSender{client: client, from: from}
+}
+
+// Send calls msg.To and reads out the first line of msg.Body twice, so a
+// link on a later line isn't spelled out.
+func (s *VoiceSender) Send(_ context.Context, msg Message) error {
+	if s.client == nil {
+		return errors.New("voice: twilio client not configured")
+	}
+	twiml, err := voiceTwiML(msg)
+	if err != nil {
+		return err
+	}
+	return s.client.PlaceCall(s.from, msg.To, twiml)
+}
+
+// voiceTwiML renders the TwiML that reads msg aloud.
+func voiceTwiML(msg Message) (string, error) {
+	text, _, _ := strings.Cut(strings.TrimSpace(msg.Body), "\n")
+	locale := voiceLocales[msg.Language]
+	if locale == "" {
+		locale = "en-US"
+	}
+	type say struct {
+		Language string `xml:"language,attr"`
+		Text     string `xml:",chardata"`
+	}
+	response := struct {
+		XMLName xml.Name `xml:"Response"`
+		Say     []say    `xml:"Say"`
+	}{
+		Say: []say{{locale, text}, {locale, text}},
+	}
+	out, err := xml.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,29 @@
+package channel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pathakanu/myMemo/internal/twilio"
+)
+
+// WhatsAppSender delivers messages through Twilio's WhatsApp API. Recipients
+// are phone numbers.
+type WhatsAppSender struct {
+	client *twilio.Client
+}
+
+// NewWhatsApp wraps a Twilio client as a Sender.
+func NewWhatsApp(client *twilio.Client) *WhatsAppSender {
+	return &WhatsAppSender{client: client}
+}
+
+// Send delivers msg.Body to msg.To, with the file at msg.MediaURL when it is
+// set. Twilio posts the message's progress to msg.StatusCallback when it is
+// set.
+func (s *WhatsAppSender) Send(_ context.Context, msg Message) error {
+	if s.client == nil {
+		return errors.New("whatsapp: twilio client not configured")
+	}
+	return s.client.SendWhatsAppMedia(msg.To, msg.Body, msg.MediaURL, msg.StatusCallback)
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/sigv4"
+)
+
+// awsSecretsProvider reads a JSON secret from AWS Secrets Manager. The
+// secret's SecretString must be an object such as
+// {"TWILIO_AUTH_TOKEN": "...", "OPENAI_API_KEY": "..."}.
+type awsSecretsProvider struct {
+	http     *http.Client
+	secretID string
+	region   string
+	endpoint string
+	creds    sigv4.Credentials
+}
+
+func (p *awsSecretsProvider) Secrets(ctx context.Context) (map[string]string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": p.secretID})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signer := sigv4.Signer{Credentials: p.creds, Region: p.region, Service: "secretsmanager"}
+	signer.Sign(req, body, time.Now())
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("secrets manager returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode secrets manager response: %w", err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(out.SecretString), &data); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object", p.secretID)
+	}
+	return stringValues(data), nil
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,26 +15,123 @@ import (
 
 // Config stores runtime configuration loaded from environment variables.
 type Config struct {
-	Port                 string
-	TwilioAccountSID     string
-	TwilioAuthToken      string
-	TwilioWhatsAppNumber string
-	OpenAIAPIKey         string
-	DatabaseURL          string
-	LocalTimezone        *time.Location
+	Port                    string
+	GRPCPort                string
+	TLSDomains              []string
+	TLSCacheDir             string
+	TLSEmail                string
+	TwilioAccountSID        string
+	TwilioAuthToken         string
+	TwilioWhatsAppNumber    string
+	TwilioSenderPool        []string
+	TwilioSandboxKeyword    string
+	TwilioVoiceNumber       string
+	TwilioStatusCallbacks   bool
+	TwilioMessagesPerSecond int
+	OpenAIAPIKey            string
+	OpenAIBaseURL           string
+	OpenAIDeployment        string
+	OpenAIAPIVersion        string
+	ModerateContent         bool
+	LLMProvider             string
+	AnthropicAPIKey         string
+	AnthropicModel          string
+	SecretsProvider         string
+	DatabaseURL             string
+	DBStatementTimeout      time.Duration
+	DBMaxOpenConns          int
+	DBMaxIdleConns          int
+	DBConnMaxLifetime       time.Duration
+	LocalTimezone           *time.Location
+	ReminderGap             time.Duration
+	DispatchSchedule        string
+	PriorityAging           string
+	ReminderCategories      string
+	RescheduleAfterDays     int
+	MessageArchiveDays      int
+	HolidayCountry          string
+	HolidayDates            []string
+	LogLevel                string
+	RequestLogging          bool
+	RequestLogPayloadPct    int
+	AdminToken              string
+	AdminNumbers            []string
+	PublicBaseURL           string
+	BlobStore               string
+	BlobDir                 string
+	BlobSigningKey          string
+	CredentialsKey          string
+	S3Bucket                string
+	S3Region                string
+	S3Endpoint              string
+	S3AccessKeyID           string
+	S3SecretAccessKey       string
+	S3SessionToken          string
+	S3PathStyle             bool
+	EmailFrom               string
+	SendGridAPIKey          string
+	SMTPHost                string
+	SMTPPort                int
+	SMTPUsername            string
+	SMTPPassword            string
+	InboundEmailToken       string
+	MailgunSigningKey       string
+	DiscordBotToken         string
+	DiscordPublicKey        string
+	DiscordApplicationID    string
 }
 
+// processEnv records which variables were set before .env was read, so
+// Reload can pick up .env edits without overriding the real environment.
+var processEnv map[string]bool
+
 // Load reads configuration values and prepares defaults where applicable.
 func Load() *Config {
+	if processEnv == nil {
+		processEnv = make(map[string]bool)
+		for _, kv := range os.Environ() {
+			processEnv[strings.SplitN(kv, "=", 2)[0]] = true
+		}
+	}
 	_ = godotenv.Load()
+	return load()
+}
+
+// Reload re-reads .env and the environment for a SIGHUP reload. Values in
+// .env replace earlier .env values but never variables set by the process
+// environment.
+func Reload() *Config {
+	if values, err := godotenv.Read(); err == nil {
+		for key, value := range values {
+			if !processEnv[key] {
+				os.Setenv(key, value)
+			}
+		}
+	}
+	return load()
+}
 
+func load() *Config {
 	port := getenvDefault("PORT", "8080")
 	accountSID := os.Getenv("TWILIO_ACCOUNT_SID")
 	authToken := os.Getenv("TWILIO_AUTH_TOKEN")
 	whatsAppNumber := os.Getenv("TWILIO_WHATSAPP_NUMBER")
 	openAIKey := os.Getenv("OPENAI_API_KEY")
 	databaseURL := os.Getenv("DATABASE_URL")
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	statementTimeout := ParseIntEnv("DB_STATEMENT_TIMEOUT_SECONDS", 10)
+	if statementTimeout < 0 {
+		log.Printf("config: DB_STATEMENT_TIMEOUT_SECONDS must not be negative, defaulting to 10")
+		statementTimeout = 10
+	}
 	timezoneName := getenvDefault("LOCAL_TIMEZONE", "Local")
+	gapMinutes := ParseIntEnv("REMINDER_GAP_MINUTES", 60)
+	if gapMinutes < 0 {
+		log.Printf("config: REMINDER_GAP_MINUTES must not be negative, defaulting to 60")
+		gapMinutes = 60
+	}
+
+	s3Endpoint := strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/")
 
 	location, err := time.LoadLocation(timezoneName)
 	if err != nil {
@@ -39,14 +140,130 @@ func Load() *Config {
 	}
 
 	return &Config{
-		Port:                 port,
-		TwilioAccountSID:     accountSID,
-		TwilioAuthToken:      authToken,
-		TwilioWhatsAppNumber: whatsAppNumber,
-		OpenAIAPIKey:         openAIKey,
-		DatabaseURL:          databaseURL,
-		LocalTimezone:        location,
+		Port:                    port,
+		GRPCPort:                os.Getenv("GRPC_PORT"),
+		TLSDomains:              splitList(strings.ToLower(os.Getenv("TLS_DOMAINS"))),
+		TLSCacheDir:             getenvDefault("TLS_CACHE_DIR", "certs"),
+		TLSEmail:                os.Getenv("TLS_EMAIL"),
+		TwilioAccountSID:        accountSID,
+		TwilioAuthToken:         authToken,
+		TwilioWhatsAppNumber:    whatsAppNumber,
+		TwilioSandboxKeyword:    os.Getenv("TWILIO_SANDBOX_KEYWORD"),
+		TwilioSenderPool:        splitNumbers(os.Getenv("TWILIO_WHATSAPP_POOL")),
+		TwilioVoiceNumber:       strings.TrimSpace(os.Getenv("TWILIO_VOICE_NUMBER")),
+		TwilioStatusCallbacks:   ParseBoolEnv("TWILIO_STATUS_CALLBACKS", false),
+		TwilioMessagesPerSecond: ParseIntEnv("TWILIO_MESSAGES_PER_SECOND", 10),
+		OpenAIAPIKey:            openAIKey,
+		OpenAIBaseURL:           strings.TrimRight(os.Getenv("OPENAI_BASE_URL"), "/"),
+		OpenAIDeployment:        os.Getenv("OPENAI_AZURE_DEPLOYMENT"),
+		OpenAIAPIVersion:        getenvDefault("OPENAI_API_VERSION", DefaultAzureAPIVersion),
+		ModerateContent:         ParseBoolEnv("OPENAI_MODERATION", false),
+		LLMProvider:             strings.ToLower(getenvDefault("LLM_PROVIDER", ProviderOpenAI)),
+		AnthropicAPIKey:         os.Getenv("ANTHROPIC_API_KEY"),
+		AnthropicModel:          os.Getenv("ANTHROPIC_MODEL"),
+		SecretsProvider:         strings.ToLower(os.Getenv("SECRETS_PROVIDER")),
+		DatabaseURL:             databaseURL,
+		DBStatementTimeout:      time.Duration(statementTimeout) * time.Second,
+		DBMaxOpenConns:          ParseIntEnv("DB_MAX_OPEN_CONNS", 10),
+		DBMaxIdleConns:          ParseIntEnv("DB_MAX_IDLE_CONNS", 5),
+		DBConnMaxLifetime:       time.Duration(ParseIntEnv("DB_CONN_MAX_LIFETIME_MINUTES", 30)) * time.Minute,
+		LocalTimezone:           location,
+		ReminderGap:             time.Duration(gapMinutes) * time.Minute,
+		DispatchSchedule:        os.Getenv("DISPATCH_SCHEDULE"),
+		PriorityAging:           os.Getenv("PRIORITY_AGING_DAYS"),
+		ReminderCategories:      os.Getenv("REMINDER_CATEGORIES"),
+		RescheduleAfterDays:     ParseIntEnv("RESCHEDULE_AFTER_DAYS", 3),
+		MessageArchiveDays:      ParseIntEnv("MESSAGE_ARCHIVE_DAYS", 90),
+		HolidayCountry:          strings.ToUpper(strings.TrimSpace(os.Getenv("HOLIDAY_COUNTRY"))),
+		HolidayDates:            splitList(os.Getenv("HOLIDAY_DATES")),
+		LogLevel:                os.Getenv("LOG_LEVEL"),
+		RequestLogging:          ParseBoolEnv("REQUEST_LOGGING", false),
+		RequestLogPayloadPct:    ParseIntEnv("REQUEST_LOG_PAYLOAD_PERCENT", 10),
+		AdminToken:              adminToken,
+		AdminNumbers:            splitNumbers(os.Getenv("ADMIN_PHONE_NUMBERS")),
+		PublicBaseURL:           strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/"),
+		BlobStore:               strings.ToLower(getenvDefault("BLOB_STORE", "disk")),
+		BlobDir:                 getenvDefault("BLOB_DIR", "blobs"),
+		BlobSigningKey:          os.Getenv("BLOB_SIGNING_KEY"),
+		CredentialsKey:          os.Getenv("CREDENTIALS_KEY"),
+		S3Bucket:                os.Getenv("S3_BUCKET"),
+		S3Region:                getenvDefault("S3_REGION", os.Getenv("AWS_REGION")),
+		S3Endpoint:              s3Endpoint,
+		S3AccessKeyID:           getenvDefault("S3_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID")),
+		S3SecretAccessKey:       getenvDefault("S3_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		S3SessionToken:          getenvDefault("S3_SESSION_TOKEN", os.Getenv("AWS_SESSION_TOKEN")),
+		S3PathStyle:             ParseBoolEnv("S3_PATH_STYLE", s3Endpoint != ""),
+		EmailFrom:               os.Getenv("EMAIL_FROM"),
+		SendGridAPIKey:          os.Getenv("SENDGRID_API_KEY"),
+		SMTPHost:                os.Getenv("SMTP_HOST"),
+		SMTPPort:                ParseIntEnv("SMTP_PORT", 587),
+		SMTPUsername:            os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:            os.Getenv("SMTP_PASSWORD"),
+		InboundEmailToken:       os.Getenv("INBOUND_EMAIL_TOKEN"),
+		MailgunSigningKey:       os.Getenv("MAILGUN_SIGNING_KEY"),
+		DiscordBotToken:         os.Getenv("DISCORD_BOT_TOKEN"),
+		DiscordPublicKey:        os.Getenv("DISCORD_PUBLIC_KEY"),
+		DiscordApplicationID:    os.Getenv("DISCORD_APPLICATION_ID"),
+	}
+}
+
+// Language model providers for LLM_PROVIDER.
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when
+// OPENAI_API_VERSION is unset.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// ParsePriorityAging parses PRIORITY_AGING_DAYS, a comma-separated list of
+// ascending day counts such as "7,14,30": a reminder open longer than each
+// one is treated as one priority level higher. An empty value disables aging.
+func ParsePriorityAging(value string) ([]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var days []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q is not a positive number of days", strings.TrimSpace(field))
+		}
+		if len(days) > 0 && n <= days[len(days)-1] {
+			return nil, fmt.Errorf("days must be in ascending order")
+		}
+		days = append(days, n)
+	}
+	return days, nil
+}
+
+// DefaultCategories are the reminder categories used when
+// REMINDER_CATEGORIES is unset.
+var DefaultCategories = []string{"work", "home", "health", "finance", "errands", "social"}
+
+var categoryPattern = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+// ParseCategories parses REMINDER_CATEGORIES, a comma-separated list of
+// single-word category names such as "work,home,health". An empty value
+// gives DefaultCategories.
+func ParseCategories(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultCategories, nil
 	}
+	var categories []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		name := strings.ToLower(strings.TrimSpace(field))
+		if !categoryPattern.MatchString(name) {
+			return nil, fmt.Errorf("%q is not a single word", strings.TrimSpace(field))
+		}
+		if !seen[name] {
+			seen[name] = true
+			categories = append(categories, name)
+		}
+	}
+	return categories, nil
 }
 
 func getenvDefault(key, def string) string {
@@ -57,6 +274,41 @@ func getenvDefault(key, def string) string {
 	return value
 }
 
+// SenderNumbers returns the WhatsApp numbers the deployment sends from: the
+// default number first, then the pool, without "whatsapp:" prefixes or
+// repeats.
+func (c *Config) SenderNumbers() []string {
+	numbers := []string{strings.TrimPrefix(strings.TrimSpace(c.TwilioWhatsAppNumber), "whatsapp:")}
+	for _, number := range c.TwilioSenderPool {
+		if !slices.Contains(numbers, number) {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+// splitNumbers parses a comma-separated list of phone numbers.
+func splitNumbers(value string) []string {
+	var numbers []string
+	for _, part := range strings.Split(value, ",") {
+		if number := strings.TrimPrefix(strings.TrimSpace(part), "whatsapp:"); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+// splitList parses a comma-separated list, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		if item := strings.TrimSpace(part); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // ParseIntEnv returns the integer value for an environment variable or the provided default.
 func ParseIntEnv(key string, def int) int {
 	value := os.Getenv(key)
@@ -71,3 +323,18 @@ func ParseIntEnv(key string, def int) int {
 	}
 	return parsed
 }
+
+// ParseBoolEnv returns the boolean value for an environment variable or the provided default.
+func ParseBoolEnv(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("config: unable to parse %s=%q as bool: %v", key, value, err)
+		return def
+	}
+	return parsed
+}
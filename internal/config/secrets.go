@@ -0,0 +1,146 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/sigv4"
+)
+
+// Secrets providers selectable with SECRETS_PROVIDER.
+const (
+	SecretsVault = "vault"
+	SecretsAWS   = "aws"
+)
+
+// SecretsProvider fetches a set of named secret values.
+type SecretsProvider interface {
+	Secrets(ctx context.Context) (map[string]string, error)
+}
+
+// LoadSecrets replaces TWILIO_AUTH_TOKEN, OPENAI_API_KEY, and
+// ANTHROPIC_API_KEY with the values stored under those names in the secret
+// read by the SECRETS_PROVIDER provider. Keys missing from the secret keep
+// their environment value. It does nothing when no provider is configured.
+func (c *Config) LoadSecrets(ctx context.Context) error {
+	if c.SecretsProvider == "" {
+		return nil
+	}
+	provider, err := newSecretsProvider(c.SecretsProvider)
+	if err != nil {
+		return err
+	}
+	secrets, err := provider.Secrets(ctx)
+	if err != nil {
+		return fmt.Errorf("load secrets from %s: %w", c.SecretsProvider, err)
+	}
+
+	for key, target := range map[string]*string{
+		"TWILIO_AUTH_TOKEN": &c.TwilioAuthToken,
+		"OPENAI_API_KEY":    &c.OpenAIAPIKey,
+		"ANTHROPIC_API_KEY": &c.AnthropicAPIKey,
+	} {
+		if value := secrets[key]; value != "" {
+			*target = value
+			log.Printf("config: %s loaded from %s", key, c.SecretsProvider)
+		}
+	}
+	return nil
+}
+
+// newSecretsProvider builds the named provider from its environment settings.
+func newSecretsProvider(name string) (SecretsProvider, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch name {
+	case SecretsVault:
+		p := &vaultProvider{
+			http:  client,
+			addr:  strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+			token: os.Getenv("VAULT_TOKEN"),
+			path:  strings.Trim(os.Getenv("VAULT_SECRET_PATH"), "/"),
+		}
+		if p.addr == "" || p.token == "" || p.path == "" {
+			return nil, fmt.Errorf("SECRETS_PROVIDER=vault needs VAULT_ADDR, VAULT_TOKEN, and VAULT_SECRET_PATH")
+		}
+		return p, nil
+	case SecretsAWS:
+		region := getenvDefault("AWS_REGION", os.Getenv("AWS_DEFAULT_REGION"))
+		p := &awsSecretsProvider{
+			http:     client,
+			secretID: os.Getenv("AWS_SECRET_ID"),
+			region:   region,
+			endpoint: getenvDefault("AWS_ENDPOINT_URL_SECRETS_MANAGER", "https://secretsmanager."+region+".amazonaws.com"),
+			creds: sigv4.Credentials{
+				AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			},
+		}
+		if p.secretID == "" || p.region == "" || p.creds.AccessKeyID == "" || p.creds.SecretAccessKey == "" {
+			return nil, fmt.Errorf("SECRETS_PROVIDER=aws needs AWS_SECRET_ID, AWS_REGION, AWS_ACCESS_KEY_ID, and AWS_SECRET_ACCESS_KEY")
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown SECRETS_PROVIDER %q (want %s or %s)", name, SecretsVault, SecretsAWS)
+	}
+}
+
+// vaultProvider reads a HashiCorp Vault KV secret over the HTTP API.
+type vaultProvider struct {
+	http  *http.Client
+	addr  string
+	token string
+	// path is the API path of the secret after /v1/, e.g. "secret/data/mymemo"
+	// for KV version 2.
+	path string
+}
+
+func (p *vaultProvider) Secrets(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+p.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode vault response: %w", err)
+	}
+	data := payload.Data
+	// KV version 2 nests the values under data.data next to data.metadata.
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+	return stringValues(data), nil
+}
+
+// stringValues keeps the string entries of a decoded JSON object.
+func stringValues(data map[string]any) map[string]string {
+	out := make(map[string]string, len(data))
+	for key, value := range data {
+		if s, ok := value.(string); ok {
+			out[key] = s
+		}
+	}
+	return out
+}
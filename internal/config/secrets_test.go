@@ -0,0 +1,166 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVaultProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want map[string]string
+	}{
+		{
+			name: "kv version 1",
+			path: "secret/mymemo",
+			body: `{"data": {"TWILIO_AUTH_TOKEN": "twilio", "retries": 3}}`,
+			want: map[string]string{"TWILIO_AUTH_TOKEN": "twilio"},
+		},
+		{
+			name: "kv version 2",
+			path: "secret/data/mymemo",
+			body: `{"data": {"data": {"OPENAI_API_KEY": "openai"}, "metadata": {"version": 4}}}`,
+			want: map[string]string{"OPENAI_API_KEY": "openai"},
+		},
+		{
+			// A version 1 secret may hold a key named data; only version 2
+			// responses, which carry metadata, are unwrapped.
+			name: "kv version 1 with a data key",
+			path: "secret/mymemo",
+			body: `{"data": {"data": {"OPENAI_API_KEY": "openai"}, "ANTHROPIC_API_KEY": "anthropic"}}`,
+			want: map[string]string{"ANTHROPIC_API_KEY": "anthropic"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/"+tt.path || r.Header.Get("X-Vault-Token") != "vault-token" {
+					http.Error(w, "permission denied", http.StatusForbidden)
+					return
+				}
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			p := &vaultProvider{http: srv.Client(), addr: srv.URL, token: "vault-token", path: tt.path}
+			got, err := p.Secrets(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Secrets() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Fatalf("Secrets() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretsProviderErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	for name, provider := range map[string]SecretsProvider{
+		"vault": &vaultProvider{http: srv.Client(), addr: srv.URL, token: "vault-token", path: "secret/mymemo"},
+		"aws":   &awsSecretsProvider{http: srv.Client(), secretID: "mymemo", region: "us-east-1", endpoint: srv.URL},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := provider.Secrets(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "403 Forbidden") || !strings.Contains(err.Error(), "permission denied") {
+				t.Fatalf("expected the status and body in the error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAWSSecretsProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || req.SecretId != "mymemo" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"SecretString": `{"TWILIO_AUTH_TOKEN": "twilio", "retries": 3}`})
+	}))
+	defer srv.Close()
+
+	p := &awsSecretsProvider{http: srv.Client(), secretID: "mymemo", region: "us-east-1", endpoint: srv.URL}
+	p.creds.AccessKeyID, p.creds.SecretAccessKey = "AKID", "secret"
+	got, err := p.Secrets(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got["TWILIO_AUTH_TOKEN"] != "twilio" {
+		t.Fatalf("Secrets() = %v, want only TWILIO_AUTH_TOKEN", got)
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"data": {"data": {"OPENAI_API_KEY": "from-vault", "ANTHROPIC_API_KEY": ""}, "metadata": {}}}`)
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL+"/")
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("VAULT_SECRET_PATH", "/secret/data/mymemo")
+
+	cfg := &Config{
+		SecretsProvider: SecretsVault,
+		TwilioAuthToken: "from-env",
+		OpenAIAPIKey:    "from-env",
+		AnthropicAPIKey: "from-env",
+	}
+	if err := cfg.LoadSecrets(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OpenAIAPIKey != "from-vault" {
+		t.Fatalf("expected OPENAI_API_KEY from vault, got %q", cfg.OpenAIAPIKey)
+	}
+	if cfg.TwilioAuthToken != "from-env" || cfg.AnthropicAPIKey != "from-env" {
+		t.Fatalf("expected missing and empty keys to keep their env values, got %q and %q", cfg.TwilioAuthToken, cfg.AnthropicAPIKey)
+	}
+}
+
+func TestLoadSecretsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "sealed", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "vault-token")
+	t.Setenv("VAULT_SECRET_PATH", "secret/mymemo")
+
+	cfg := &Config{SecretsProvider: SecretsVault, TwilioAuthToken: "from-env"}
+	if err := cfg.LoadSecrets(context.Background()); err == nil || !strings.Contains(err.Error(), "load secrets from vault") {
+		t.Fatalf("expected the provider error, got %v", err)
+	}
+	if cfg.TwilioAuthToken != "from-env" {
+		t.Fatalf("expected a failed load to leave the config alone, got %q", cfg.TwilioAuthToken)
+	}
+
+	t.Setenv("VAULT_TOKEN", "")
+	if err := cfg.LoadSecrets(context.Background()); err == nil || !strings.Contains(err.Error(), "needs VAULT_ADDR") {
+		t.Fatalf("expected missing vault settings to be reported, got %v", err)
+	}
+	cfg.SecretsProvider = "gcp"
+	if err := cfg.LoadSecrets(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown SECRETS_PROVIDER") {
+		t.Fatalf("expected an unknown provider to be reported, got %v", err)
+	}
+	if err := (&Config{}).LoadSecrets(context.Background()); err != nil {
+		t.Fatalf("expected no provider to be a no-op, got %v", err)
+	}
+}
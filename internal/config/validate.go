@@ -0,0 +1,185 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/holidays"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	// domainPattern matches a fully qualified host name such as
+	// memo.example.com.
+	domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+)
+
+// Validate reports every missing or malformed setting at once so the server
+// can refuse to start with a clear message instead of failing on the first
+// send or query.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for _, required := range []struct{ name, value string }{
+		{"TWILIO_ACCOUNT_SID", c.TwilioAccountSID},
+		{"TWILIO_AUTH_TOKEN", c.TwilioAuthToken},
+		{"TWILIO_WHATSAPP_NUMBER", c.TwilioWhatsAppNumber},
+	} {
+		if strings.TrimSpace(required.value) == "" {
+			fail("%s is required", required.name)
+		}
+	}
+	if c.TwilioAccountSID != "" && !strings.HasPrefix(c.TwilioAccountSID, "AC") {
+		fail("TWILIO_ACCOUNT_SID must start with AC")
+	}
+	if number := strings.TrimPrefix(c.TwilioWhatsAppNumber, "whatsapp:"); number != "" && !e164Pattern.MatchString(number) {
+		fail("TWILIO_WHATSAPP_NUMBER must be in E.164 format, e.g. +14155238886 (got %q)", c.TwilioWhatsAppNumber)
+	}
+	for _, number := range c.TwilioSenderPool {
+		if !e164Pattern.MatchString(number) {
+			fail("TWILIO_WHATSAPP_POOL numbers must be in E.164 format, e.g. +14155238886 (got %q)", number)
+		}
+	}
+	if c.TwilioVoiceNumber != "" && !e164Pattern.MatchString(c.TwilioVoiceNumber) {
+		fail("TWILIO_VOICE_NUMBER must be in E.164 format, e.g. +14155238886 (got %q)", c.TwilioVoiceNumber)
+	}
+
+	if c.DatabaseURL != "" && !validDatabaseURL(c.DatabaseURL) {
+		fail("DATABASE_URL must be a postgres:// URL or a key=value connection string")
+	}
+	if !validPort(c.Port) {
+		fail("PORT must be a port number (got %q)", c.Port)
+	}
+	if c.GRPCPort != "" {
+		if !validPort(c.GRPCPort) {
+			fail("GRPC_PORT must be a port number (got %q)", c.GRPCPort)
+		} else if c.GRPCPort == c.Port {
+			fail("GRPC_PORT must differ from PORT")
+		}
+	}
+	for _, domain := range c.TLSDomains {
+		if !domainPattern.MatchString(domain) {
+			fail("TLS_DOMAINS entries must be host names such as memo.example.com (got %q)", domain)
+		}
+	}
+	if len(c.TLSDomains) > 0 && (c.GRPCPort == "80" || c.GRPCPort == "443") {
+		fail("GRPC_PORT must not be 80 or 443 when TLS_DOMAINS is set")
+	}
+	if c.TwilioMessagesPerSecond < 0 {
+		fail("TWILIO_MESSAGES_PER_SECOND must not be negative (got %d)", c.TwilioMessagesPerSecond)
+	}
+	if c.RequestLogPayloadPct < 0 || c.RequestLogPayloadPct > 100 {
+		fail("REQUEST_LOG_PAYLOAD_PERCENT must be between 0 and 100 (got %d)", c.RequestLogPayloadPct)
+	}
+	if c.TwilioStatusCallbacks && c.PublicBaseURL == "" {
+		fail("TWILIO_STATUS_CALLBACKS needs PUBLIC_BASE_URL so Twilio can reach the status callback")
+	}
+	if c.PublicBaseURL != "" {
+		if u, err := url.Parse(c.PublicBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("PUBLIC_BASE_URL must be an absolute http(s) URL (got %q)", c.PublicBaseURL)
+		}
+	}
+	switch c.BlobStore {
+	case "", "disk":
+	case "s3":
+		if c.S3Bucket == "" || c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			fail("BLOB_STORE=s3 requires S3_BUCKET, S3_ACCESS_KEY_ID, and S3_SECRET_ACCESS_KEY")
+		}
+	default:
+		fail("BLOB_STORE must be disk or s3 (got %q)", c.BlobStore)
+	}
+	if c.S3Endpoint != "" {
+		if u, err := url.Parse(c.S3Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("S3_ENDPOINT must be an absolute http(s) URL (got %q)", c.S3Endpoint)
+		}
+	}
+	if c.HolidayCountry != "" && !slices.Contains(holidays.Countries(), c.HolidayCountry) {
+		fail("HOLIDAY_COUNTRY must be one of %s (got %q)", strings.Join(holidays.Countries(), ", "), c.HolidayCountry)
+	}
+	for _, day := range c.HolidayDates {
+		if _, err := time.Parse(time.DateOnly, day); err != nil {
+			fail("HOLIDAY_DATES entries must be dates such as 2026-12-24 (got %q)", day)
+		}
+	}
+	if c.DispatchSchedule != "" {
+		if _, err := cron.ParseStandard(c.DispatchSchedule); err != nil {
+			fail("DISPATCH_SCHEDULE must be a cron expression: %v", err)
+		}
+	}
+	if c.ModerateContent && c.OpenAIAPIKey == "" {
+		fail("OPENAI_MODERATION requires OPENAI_API_KEY")
+	}
+	if c.OpenAIBaseURL != "" {
+		if u, err := url.Parse(c.OpenAIBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("OPENAI_BASE_URL must be an absolute http(s) URL (got %q)", c.OpenAIBaseURL)
+		}
+	}
+	if c.OpenAIDeployment != "" {
+		if c.OpenAIBaseURL == "" {
+			fail("OPENAI_AZURE_DEPLOYMENT requires OPENAI_BASE_URL, the Azure OpenAI endpoint")
+		}
+		if c.ModerateContent {
+			fail("OPENAI_MODERATION is not available with Azure OpenAI, which filters content itself")
+		}
+	}
+	switch c.LLMProvider {
+	case "", ProviderOpenAI:
+	case ProviderAnthropic:
+		if c.AnthropicAPIKey == "" {
+			fail("LLM_PROVIDER=anthropic requires ANTHROPIC_API_KEY")
+		}
+	default:
+		fail("LLM_PROVIDER must be openai or anthropic (got %q)", c.LLMProvider)
+	}
+	if _, err := ParsePriorityAging(c.PriorityAging); err != nil {
+		fail("PRIORITY_AGING_DAYS must be ascending day counts such as 7,14,30: %v", err)
+	}
+	if c.RescheduleAfterDays < 0 {
+		fail("RESCHEDULE_AFTER_DAYS must not be negative")
+	}
+	if c.MessageArchiveDays < 0 {
+		fail("MESSAGE_ARCHIVE_DAYS must not be negative")
+	}
+	if _, err := ParseCategories(c.ReminderCategories); err != nil {
+		fail("REMINDER_CATEGORIES must be comma-separated single words such as work,home,health: %v", err)
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "", "silent", "error", "warn", "warning", "info", "debug":
+	default:
+		fail("LOG_LEVEL must be silent, error, warn, or info (got %q)", c.LogLevel)
+	}
+	if c.SMTPHost != "" || c.SendGridAPIKey != "" {
+		if c.EmailFrom == "" {
+			fail("EMAIL_FROM is required when SMTP_HOST or SENDGRID_API_KEY is set")
+		}
+		if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+			fail("SMTP_PORT must be between 1 and 65535")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// validDatabaseURL accepts the URL and keyword/value forms pgx understands.
+func validDatabaseURL(dsn string) bool {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		return err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql")
+	}
+	return strings.Contains(dsn, "=")
+}
@@ -1,38 +1,89 @@
 package database
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/pathakanu/myMemo/internal/model"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-// New creates a GORM database connection.
-// When databaseURL is provided PostgreSQL is used, otherwise SQLite is used.
-func New(databaseURL string) (*gorm.DB, error) {
+// pingTimeout bounds the connectivity check in New.
+const pingTimeout = 5 * time.Second
+
+// Options tunes the connection pool, statement limits, and query logging.
+// Zero values keep database/sql's defaults, except StatementTimeout where
+// zero disables it.
+type Options struct {
+	MaxOpenConns     int
+	MaxIdleConns     int
+	ConnMaxLifetime  time.Duration
+	StatementTimeout time.Duration
+	// LogLevel is a ParseLogLevel value; see also SetLogLevel.
+	LogLevel string
+}
+
+// New creates a GORM database connection and checks that the database is
+// reachable. When databaseURL is provided PostgreSQL is used, otherwise
+// SQLite is used.
+func New(databaseURL string, opts Options) (*gorm.DB, error) {
 	var (
 		db  *gorm.DB
 		err error
 	)
 
+	level, err := ParseLogLevel(opts.LogLevel)
+	if err != nil {
+		return nil, err
+	}
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Warn),
+		Logger: newLevelLogger(level),
+		// New pings with a timeout below rather than blocking in Open.
+		DisableAutomaticPing: true,
 	}
 
+	backend := "SQLite reminders.db"
 	if databaseURL != "" {
+		backend = "PostgreSQL (check DATABASE_URL)"
 		db, err = gorm.Open(postgres.Open(databaseURL), gormConfig)
 	} else {
 		db, err = gorm.Open(sqlite.Open("reminders.db"), gormConfig)
 	}
 	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", backend, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("cannot reach %s: %w", backend, err)
+	}
+
+	if err := SetStatementTimeout(db, opts.StatementTimeout); err != nil {
 		return nil, err
 	}
 
-	if err := db.AutoMigrate(&model.Reminder{}); err != nil {
+	if err := Migrate(db); err != nil {
 		return nil, err
 	}
 
@@ -40,6 +91,79 @@ func New(databaseURL string) (*gorm.DB, error) {
 	return db, nil
 }
 
+// Migrate applies the schema for every model used by the bot, including the
+// reminder full-text index and embeddings table, and numbers reminders saved
+// before they had codes.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&model.Reminder{},
+		&model.OutboxMessage{},
+		&model.SentMessage{},
+		&model.UserPreference{},
+		&model.Template{},
+		&model.List{},
+		&model.ListItem{},
+		&model.ListMember{},
+		&model.Memo{},
+		&model.JobRun{},
+		&model.Webhook{},
+		&model.NotionConnection{},
+		&model.CalDAVConnection{},
+		&model.LoginToken{},
+		&model.Session{},
+		&model.HookToken{},
+		&model.Tenant{},
+		&model.TenantUser{},
+		&model.OpenAIUsage{},
+		&model.ReminderDelivery{},
+		&model.Blackout{},
+		&model.Feedback{},
+		&model.SharedReminder{},
+		&model.ArchivedMessage{},
+		&model.Project{},
+		&model.ReminderItem{},
+	)
+	if err != nil {
+		return err
+	}
+	if err := backfillReminderCodes(db); err != nil {
+		return fmt.Errorf("backfill reminder codes: %w", err)
+	}
+	if err := migrateReminderSearch(db); err != nil {
+		return err
+	}
+	return migrateEmbeddings(db)
+}
+
+// backfillReminderCodes gives reminders without a code the next codes for
+// their user, oldest first.
+func backfillReminderCodes(db *gorm.DB) error {
+	var missing []model.Reminder
+	if err := db.Select("id", "user_id").Where("code = 0").Order("user_id, created_at, id").Find(&missing).Error; err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		last := make(map[string]int)
+		for _, r := range missing {
+			code, ok := last[r.UserID]
+			if !ok {
+				if err := tx.Model(&model.Reminder{}).Where("user_id = ?", r.UserID).Select("COALESCE(MAX(code), 0)").Scan(&code).Error; err != nil {
+					return err
+				}
+			}
+			code++
+			last[r.UserID] = code
+			if err := tx.Model(&model.Reminder{}).Where("id = ?", r.ID).Update("code", code).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func logBackend(db *gorm.DB) {
 	dialector := db.Dialector.Name()
 	switch strings.ToLower(dialector) {
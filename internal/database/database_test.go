@@ -0,0 +1,64 @@
+package database
+
+import (
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// inTempDir runs the test in an empty directory, where New creates its
+// SQLite reminders.db.
+func inTempDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestNewAppliesPoolSettings(t *testing.T) {
+	inTempDir(t)
+	db, err := New("", Options{MaxOpenConns: 3, MaxIdleConns: 2, ConnMaxLifetime: time.Minute, LogLevel: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	if got := sqlDB.Stats().MaxOpenConnections; got != 3 {
+		t.Fatalf("MaxOpenConnections = %d, want 3", got)
+	}
+	if !db.Migrator().HasTable("reminders") {
+		t.Fatal("expected New to migrate the schema")
+	}
+}
+
+func TestNewStopsWhenPingFails(t *testing.T) {
+	// Take a free port and close it so nothing is listening there.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	_, err = New("postgres://memo:secret@"+addr+"/memo?sslmode=disable&connect_timeout=2", Options{LogLevel: "silent"})
+	if err == nil || !strings.Contains(err.Error(), "cannot reach PostgreSQL") {
+		t.Fatalf("expected New to fail on the ping, got %v", err)
+	}
+}
+
+func TestNewRejectsUnknownLogLevel(t *testing.T) {
+	if _, err := New("", Options{LogLevel: "loud"}); err == nil {
+		t.Fatal("expected an unknown log level to be rejected")
+	}
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"log"
+	"sort"
+
+	"github.com/pathakanu/myMemo/internal/model"
+	"gorm.io/gorm"
+)
+
+// migrateEmbeddings creates the reminder embeddings table. PostgreSQL needs
+// the pgvector extension for it; without the extension the table is skipped
+// and semantic features stay off.
+func migrateEmbeddings(db *gorm.DB) error {
+	if db.Dialector.Name() == "postgres" {
+		if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS vector`).Error; err != nil {
+			log.Printf("database: semantic search disabled, pgvector is unavailable: %v", err)
+			return nil
+		}
+	}
+	return db.AutoMigrate(&model.ReminderEmbedding{})
+}
+
+// EmbeddingsEnabled reports whether db can store reminder embeddings.
+func EmbeddingsEnabled(db *gorm.DB) bool {
+	return db.Migrator().HasTable(&model.ReminderEmbedding{})
+}
+
+// Neighbor is a reminder found by NearestReminders.
+type Neighbor struct {
+	ReminderID uint
+	// Distance is the cosine distance from the query, from 0 (same
+	// direction) to 2.
+	Distance float64
+}
+
+// NearestReminders returns up to limit of userID's open reminders whose
+// embeddings from embeddingModel are closest to vector, nearest first.
+// PostgreSQL ranks them with pgvector; SQLite compares every vector of the
+// user in Go, which is fine at one user's scale.
+func NearestReminders(db *gorm.DB, userID, embeddingModel string, vector model.Vector, limit int) ([]Neighbor, error) {
+	open := db.Table("reminder_embeddings AS e").
+		Joins("JOIN reminders AS r ON r.id = e.reminder_id").
+		Where("e.user_id = ? AND e.model = ? AND r.completed_at IS NULL", userID, embeddingModel)
+
+	if db.Dialector.Name() == "postgres" {
+		var neighbors []Neighbor
+		err := open.Select("e.reminder_id, e.embedding <=> ?::vector AS distance", vector.String()).
+			Order("distance").
+			Limit(limit).
+			Scan(&neighbors).Error
+		return neighbors, err
+	}
+
+	var rows []struct {
+		ReminderID uint
+		Embedding  model.Vector
+	}
+	if err := open.Select("e.reminder_id, e.embedding").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	neighbors := make([]Neighbor, len(rows))
+	for i, row := range rows {
+		neighbors[i] = Neighbor{ReminderID: row.ReminderID, Distance: vector.CosineDistance(row.Embedding)}
+	}
+	sort.SliceStable(neighbors, func(i, j int) bool { return neighbors[i].Distance < neighbors[j].Distance })
+	if len(neighbors) > limit {
+		neighbors = neighbors[:limit]
+	}
+	return neighbors, nil
+}
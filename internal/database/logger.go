@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ParseLogLevel maps a LOG_LEVEL value (silent, error, warn, or info) to a
+// GORM log level. Empty means warn.
+func ParseLogLevel(level string) (logger.LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "silent":
+		return logger.Silent, nil
+	case "error":
+		return logger.Error, nil
+	case "", "warn", "warning":
+		return logger.Warn, nil
+	case "info", "debug":
+		return logger.Info, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want silent, error, warn, or info)", level)
+}
+
+// levelLogger is a GORM logger whose level can be changed while queries are
+// running.
+type levelLogger struct {
+	current atomic.Value // logger.Interface
+}
+
+func newLevelLogger(level logger.LogLevel) *levelLogger {
+	l := &levelLogger{}
+	l.current.Store(logger.Default.LogMode(level))
+	return l
+}
+
+func (l *levelLogger) get() logger.Interface {
+	return l.current.Load().(logger.Interface)
+}
+
+func (l *levelLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return l.get().LogMode(level)
+}
+
+func (l *levelLogger) Info(ctx context.Context, msg string, data ...any) {
+	l.get().Info(ctx, msg, data...)
+}
+
+func (l *levelLogger) Warn(ctx context.Context, msg string, data ...any) {
+	l.get().Warn(ctx, msg, data...)
+}
+
+func (l *levelLogger) Error(ctx context.Context, msg string, data ...any) {
+	l.get().Error(ctx, msg, data...)
+}
+
+func (l *levelLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.get().Trace(ctx, begin, fc, err)
+}
+
+// SetLogLevel changes the query log level of a connection opened by New.
+func SetLogLevel(db *gorm.DB, level string) error {
+	parsed, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	l, ok := db.Logger.(*levelLogger)
+	if !ok {
+		return fmt.Errorf("database logger does not support changing levels")
+	}
+	l.current.Store(logger.Default.LogMode(parsed))
+	return nil
+}
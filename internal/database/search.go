@@ -0,0 +1,119 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Full-text search modes reported by SearchMode.
+const (
+	SearchPostgres = "postgres"
+	SearchFTS5     = "fts5"
+	SearchLike     = "like"
+)
+
+// reminderSearchTable is the SQLite FTS5 index over reminders.
+const reminderSearchTable = "reminders_fts"
+
+// errNoFTS5 means SQLite was built without the FTS5 extension.
+var errNoFTS5 = errors.New("SQLite FTS5 unavailable")
+
+// migrateReminderSearch creates a full-text index on reminder content and
+// summary: an English tsvector GIN index on PostgreSQL, or a Porter-stemmed
+// FTS5 table kept in sync by triggers on SQLite. SQLite builds without FTS5
+// (the sqlite_fts5 build tag) fall back to LIKE matching.
+func migrateReminderSearch(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return db.Exec(`CREATE INDEX IF NOT EXISTS idx_reminders_search ON reminders
+			USING GIN (to_tsvector('english', content || ' ' || coalesce(summary, '')))`).Error
+	case "sqlite":
+		if db.Migrator().HasTable(reminderSearchTable) {
+			return nil
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec(`CREATE VIRTUAL TABLE ` + reminderSearchTable + ` USING fts5(
+				content, summary, content='reminders', content_rowid='id', tokenize='porter unicode61')`).Error
+			if err != nil {
+				return fmt.Errorf("%w: %v", errNoFTS5, err)
+			}
+			for _, stmt := range []string{
+				`CREATE TRIGGER reminders_fts_insert AFTER INSERT ON reminders BEGIN
+					INSERT INTO reminders_fts(rowid, content, summary) VALUES (new.id, new.content, new.summary);
+				END`,
+				`CREATE TRIGGER reminders_fts_delete AFTER DELETE ON reminders BEGIN
+					INSERT INTO reminders_fts(reminders_fts, rowid, content, summary) VALUES ('delete', old.id, old.content, old.summary);
+				END`,
+				`CREATE TRIGGER reminders_fts_update AFTER UPDATE OF content, summary ON reminders BEGIN
+					INSERT INTO reminders_fts(reminders_fts, rowid, content, summary) VALUES ('delete', old.id, old.content, old.summary);
+					INSERT INTO reminders_fts(rowid, content, summary) VALUES (new.id, new.content, new.summary);
+				END`,
+				`INSERT INTO reminders_fts(reminders_fts) VALUES ('rebuild')`,
+			} {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if errors.Is(err, errNoFTS5) {
+			log.Printf("database: reminder search will use LIKE matching: %v", err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// SearchMode reports which full-text search db supports for reminders.
+func SearchMode(db *gorm.DB) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return SearchPostgres
+	case "sqlite":
+		if db.Migrator().HasTable(reminderSearchTable) {
+			return SearchFTS5
+		}
+	}
+	return SearchLike
+}
+
+// SearchReminders narrows tx, a query on reminders, to rows whose content or
+// summary contains every word of query. PostgreSQL and FTS5 match stemmed
+// words ("appointments" finds "appointment"); the LIKE fallback matches
+// substrings. mode comes from SearchMode.
+func SearchReminders(tx *gorm.DB, mode, query string) *gorm.DB {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return tx
+	}
+	switch mode {
+	case SearchPostgres:
+		return tx.Where("to_tsvector('english', content || ' ' || coalesce(summary, '')) @@ plainto_tsquery('english', ?)", strings.Join(terms, " "))
+	case SearchFTS5:
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = `"` + term + `"`
+		}
+		return tx.Where("id IN (SELECT rowid FROM "+reminderSearchTable+" WHERE "+reminderSearchTable+" MATCH ?)", strings.Join(quoted, " "))
+	default:
+		for _, term := range terms {
+			like := "%" + term + "%"
+			tx = tx.Where("(LOWER(content) LIKE ? OR LOWER(summary) LIKE ?)", like, like)
+		}
+		return tx
+	}
+}
+
+// searchTerms splits query into lower-case words, dropping punctuation so it
+// can't be read as FTS5 query syntax.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
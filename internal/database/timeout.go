@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const statementCancelKey = "mymemo:statement_cancel"
+
+// SetStatementTimeout bounds every create, query, update, and delete by
+// timeout on top of whatever context the caller passed with WithContext. Row
+// and Raw scans are left alone because their rows are read after the
+// callbacks return.
+func SetStatementTimeout(db *gorm.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		ctx, cancel := context.WithTimeout(tx.Statement.Context, timeout)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(statementCancelKey, cancel)
+	}
+	after := func(tx *gorm.DB) {
+		if cancel, ok := tx.InstanceGet(statementCancelKey); ok {
+			cancel.(context.CancelFunc)()
+		}
+	}
+
+	cb := db.Callback()
+	registrations := []error{
+		cb.Create().Before("*").Register("mymemo:statement_timeout", before),
+		cb.Create().After("*").Register("mymemo:statement_cancel", after),
+		cb.Query().Before("*").Register("mymemo:statement_timeout", before),
+		cb.Query().After("*").Register("mymemo:statement_cancel", after),
+		cb.Update().Before("*").Register("mymemo:statement_timeout", before),
+		cb.Update().After("*").Register("mymemo:statement_cancel", after),
+		cb.Delete().Before("*").Register("mymemo:statement_timeout", before),
+		cb.Delete().After("*").Register("mymemo:statement_cancel", after),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
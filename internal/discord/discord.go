@@ -0,0 +1,262 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://discord.com/api/v10"
+	// maxMessageRunes is Discord's message content limit.
+	maxMessageRunes = 2000
+)
+
+// Interaction types sent to the interactions endpoint.
+const (
+	InteractionPing               = 1
+	InteractionApplicationCommand = 2
+)
+
+// Interaction response types.
+const (
+	ResponsePong                   = 1
+	ResponseChannelMessage         = 4
+	ResponseDeferredChannelMessage = 5
+)
+
+// MessageFlagEphemeral shows a response only to the user who invoked the command.
+const MessageFlagEphemeral = 1 << 6
+
+// Command and option types used when registering commands.
+const (
+	CommandTypeChatInput = 1
+	OptionTypeString     = 3
+)
+
+// User is a Discord user.
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Interaction is an incoming slash command or ping.
+type Interaction struct {
+	ID            string          `json:"id"`
+	ApplicationID string          `json:"application_id"`
+	Type          int             `json:"type"`
+	Token         string          `json:"token"`
+	GuildID       string          `json:"guild_id,omitempty"`
+	Data          InteractionData `json:"data"`
+	Member        *struct {
+		User User `json:"user"`
+	} `json:"member,omitempty"`
+	User *User `json:"user,omitempty"`
+}
+
+// Caller returns the user who triggered the interaction, in a guild or a DM.
+func (i Interaction) Caller() (User, bool) {
+	switch {
+	case i.Member != nil:
+		return i.Member.User, true
+	case i.User != nil:
+		return *i.User, true
+	}
+	return User{}, false
+}
+
+// InteractionData carries the invoked command and its options.
+type InteractionData struct {
+	Name    string              `json:"name"`
+	Options []InteractionOption `json:"options,omitempty"`
+}
+
+// Option returns the string value of the named option.
+func (d InteractionData) Option(name string) string {
+	for _, o := range d.Options {
+		if o.Name == name {
+			var s string
+			if err := json.Unmarshal(o.Value, &s); err == nil {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// InteractionOption is one argument of a slash command.
+type InteractionOption struct {
+	Name  string          `json:"name"`
+	Type  int             `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// InteractionResponse answers an interaction.
+type InteractionResponse struct {
+	Type int           `json:"type"`
+	Data *ResponseData `json:"data,omitempty"`
+}
+
+// ResponseData is the message sent in reply to an interaction.
+type ResponseData struct {
+	Content string `json:"content,omitempty"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+// Command is an application command definition.
+type Command struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Type         int             `json:"type,omitempty"`
+	Options      []CommandOption `json:"options,omitempty"`
+	DMPermission *bool           `json:"dm_permission,omitempty"`
+}
+
+// CommandOption declares an argument of a Command.
+type CommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Client calls the Discord REST API as a bot.
+type Client struct {
+	// BaseURL overrides the API root, mainly for tests.
+	BaseURL string
+	token   string
+	http    *http.Client
+}
+
+// New returns a Client authenticated with a bot token.
+func New(token string) *Client {
+	return &Client{
+		BaseURL: defaultBaseURL,
+		token:   token,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// SendDM opens (or reuses) a DM channel with userID and posts content to it,
+// split into several messages if it exceeds Discord's length limit.
+func (c *Client) SendDM(ctx context.Context, userID, content string) error {
+	var channel struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/users/@me/channels", true, map[string]string{"recipient_id": userID}, &channel); err != nil {
+		return err
+	}
+	for _, chunk := range splitMessage(content) {
+		if err := c.do(ctx, http.MethodPost, "/channels/"+channel.ID+"/messages", true, map[string]string{"content": chunk}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterCommands replaces the application's global commands with cmds.
+func (c *Client) RegisterCommands(ctx context.Context, applicationID string, cmds []Command) error {
+	return c.do(ctx, http.MethodPut, "/applications/"+applicationID+"/commands", true, cmds, nil)
+}
+
+// EditOriginalResponse sets the content of a deferred interaction response.
+// Interaction tokens authorise the call, so no bot token is sent.
+func (c *Client) EditOriginalResponse(ctx context.Context, applicationID, interactionToken, content string) error {
+	chunks := splitMessage(content)
+	path := "/webhooks/" + applicationID + "/" + interactionToken
+	if err := c.do(ctx, http.MethodPatch, path+"/messages/@original", false, map[string]string{"content": chunks[0]}, nil); err != nil {
+		return err
+	}
+	for _, chunk := range chunks[1:] {
+		if err := c.do(ctx, http.MethodPost, path, false, map[string]any{"content": chunk, "flags": MessageFlagEphemeral}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, auth bool, payload, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("discord: encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("discord: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DiscordBot (https://github.com/pathakanu/myMemo, 1.0)")
+	if auth {
+		req.Header.Set("Authorization", "Bot "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("discord: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("discord: %s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("discord: decode response: %w", err)
+	}
+	return nil
+}
+
+// splitMessage breaks content into chunks that fit in one Discord message,
+// preferring to split on line breaks.
+func splitMessage(content string) []string {
+	runes := []rune(content)
+	if len(runes) <= maxMessageRunes {
+		return []string{content}
+	}
+	var chunks []string
+	for len(runes) > maxMessageRunes {
+		cut := maxMessageRunes
+		for i := maxMessageRunes - 1; i > maxMessageRunes/2; i-- {
+			if runes[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		chunks = append(chunks, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// ParsePublicKey decodes the hex application public key from the developer portal.
+func ParsePublicKey(hexKey string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(hexKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("discord: invalid public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Verify checks the Ed25519 signature Discord attaches to interaction requests.
+func Verify(key ed25519.PublicKey, signature, timestamp string, body []byte) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	msg := make([]byte, 0, len(timestamp)+len(body))
+	msg = append(msg, timestamp...)
+	msg = append(msg, body...)
+	return ed25519.Verify(key, msg, sig)
+}
@@ -0,0 +1,209 @@
+// Package holidays knows which days are working days: weekends, the public
+// holidays of a handful of countries, and any extra dates an operator adds.
+package holidays
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// rules returns a country's public holidays in year, keyed by civil date.
+type rules func(year int) map[time.Time]string
+
+var countries = map[string]rules{
+	"US": unitedStates,
+	"GB": unitedKingdom,
+	"IN": india,
+	"DE": germany,
+}
+
+// Countries lists the ISO 3166-1 alpha-2 codes with a built-in calendar.
+func Countries() []string {
+	codes := make([]string, 0, len(countries))
+	for code := range countries {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// Calendar tells working days from weekends and holidays. The zero value
+// only knows weekends.
+type Calendar struct {
+	rules rules
+	extra map[time.Time]bool
+}
+
+// New returns the calendar for country, which may be empty for weekends
+// only, with extra dates added as holidays.
+func New(country string, extra []time.Time) (*Calendar, error) {
+	c := &Calendar{extra: make(map[time.Time]bool, len(extra))}
+	if country != "" {
+		r, ok := countries[strings.ToUpper(country)]
+		if !ok {
+			return nil, fmt.Errorf("holidays: no calendar for %q", country)
+		}
+		c.rules = r
+	}
+	for _, day := range extra {
+		c.extra[civil(day)] = true
+	}
+	return c, nil
+}
+
+// Holiday returns the name of the public holiday on day's date, if any.
+func (c *Calendar) Holiday(day time.Time) (string, bool) {
+	date := civil(day)
+	if c.extra[date] {
+		return "holiday", true
+	}
+	if c.rules == nil {
+		return "", false
+	}
+	// A holiday can be observed in the year before, as New Year's Day on a
+	// Saturday is in the US.
+	for _, year := range []int{date.Year(), date.Year() + 1} {
+		if name, ok := c.rules(year)[date]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// WorkingDay reports whether day is a weekday and not a holiday.
+func (c *Calendar) WorkingDay(day time.Time) bool {
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	_, holiday := c.Holiday(day)
+	return !holiday
+}
+
+// NextWorkingDay returns day if it is a working day, or the same time of day
+// on the first working day after it.
+func (c *Calendar) NextWorkingDay(day time.Time) time.Time {
+	for range 366 {
+		if c.WorkingDay(day) {
+			return day
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+func civil(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekday returns the nth weekday of month, counting from the end when n
+// is negative.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		last := date(year, month+1, 0)
+		return last.AddDate(0, 0, -((int(last.Weekday())-int(weekday)+7)%7)+7*(n+1))
+	}
+	first := date(year, month, 1)
+	return first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+7*(n-1))
+}
+
+// easter returns Easter Sunday in the Gregorian calendar.
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
+
+// observedUS moves a holiday on a Saturday to Friday and one on a Sunday to
+// Monday, as US federal holidays are.
+func observedUS(day time.Time) time.Time {
+	switch day.Weekday() {
+	case time.Saturday:
+		return day.AddDate(0, 0, -1)
+	case time.Sunday:
+		return day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// substituteGB moves a holiday on a weekend to the next weekday not already
+// taken, as UK bank holidays are.
+func substituteGB(days map[time.Time]string, day time.Time, name string) {
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || days[day] != "" {
+		day = day.AddDate(0, 0, 1)
+	}
+	days[day] = name
+}
+
+func unitedStates(year int) map[time.Time]string {
+	return map[time.Time]string{
+		observedUS(date(year, time.January, 1)):           "New Year's Day",
+		nthWeekday(year, time.January, time.Monday, 3):    "Martin Luther King Jr. Day",
+		nthWeekday(year, time.February, time.Monday, 3):   "Washington's Birthday",
+		nthWeekday(year, time.May, time.Monday, -1):       "Memorial Day",
+		observedUS(date(year, time.June, 19)):             "Juneteenth",
+		observedUS(date(year, time.July, 4)):              "Independence Day",
+		nthWeekday(year, time.September, time.Monday, 1):  "Labor Day",
+		nthWeekday(year, time.October, time.Monday, 2):    "Columbus Day",
+		observedUS(date(year, time.November, 11)):         "Veterans Day",
+		nthWeekday(year, time.November, time.Thursday, 4): "Thanksgiving",
+		observedUS(date(year, time.December, 25)):         "Christmas Day",
+	}
+}
+
+// unitedKingdom returns the bank holidays of England and Wales.
+func unitedKingdom(year int) map[time.Time]string {
+	e := easter(year)
+	days := map[time.Time]string{
+		e.AddDate(0, 0, -2):                            "Good Friday",
+		e.AddDate(0, 0, 1):                             "Easter Monday",
+		nthWeekday(year, time.May, time.Monday, 1):     "Early May bank holiday",
+		nthWeekday(year, time.May, time.Monday, -1):    "Spring bank holiday",
+		nthWeekday(year, time.August, time.Monday, -1): "Summer bank holiday",
+	}
+	substituteGB(days, date(year, time.January, 1), "New Year's Day")
+	substituteGB(days, date(year, time.December, 25), "Christmas Day")
+	substituteGB(days, date(year, time.December, 26), "Boxing Day")
+	return days
+}
+
+// india returns India's three national holidays; state and religious
+// holidays vary too much to list.
+func india(year int) map[time.Time]string {
+	return map[time.Time]string{
+		date(year, time.January, 26): "Republic Day",
+		date(year, time.August, 15):  "Independence Day",
+		date(year, time.October, 2):  "Gandhi Jayanti",
+	}
+}
+
+// germany returns Germany's nationwide public holidays.
+func germany(year int) map[time.Time]string {
+	e := easter(year)
+	return map[time.Time]string{
+		date(year, time.January, 1):   "Neujahr",
+		e.AddDate(0, 0, -2):           "Karfreitag",
+		e.AddDate(0, 0, 1):            "Ostermontag",
+		date(year, time.May, 1):       "Tag der Arbeit",
+		e.AddDate(0, 0, 39):           "Christi Himmelfahrt",
+		e.AddDate(0, 0, 50):           "Pfingstmontag",
+		date(year, time.October, 3):   "Tag der Deutschen Einheit",
+		date(year, time.December, 25): "Erster Weihnachtstag",
+		date(year, time.December, 26): "Zweiter Weihnachtstag",
+	}
+}
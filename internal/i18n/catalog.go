@@ -0,0 +1,207 @@
+package i18n
+
+// catalog maps the bot's English reply formats to their translations. A
+// translation must use the same verbs in the same order, or say which
+// argument it wants with an explicit index such as %[2]s.
+var catalog = map[string]map[string]string{
+	"What priority should I set? Reply with a number between 1 (low) and 5 (high).": {
+		"es": "¿Qué prioridad le pongo? Responde con un número del 1 (baja) al 5 (alta).",
+		"fr": "Quelle priorité dois-je mettre ? Réponds avec un chiffre de 1 (basse) à 5 (haute).",
+		"pt": "Que prioridade devo definir? Responda com um número de 1 (baixa) a 5 (alta).",
+	},
+	"Please send a priority between 1 (lowest) and 5 (highest).": {
+		"es": "Envía una prioridad entre 1 (la más baja) y 5 (la más alta).",
+		"fr": "Envoie une priorité entre 1 (la plus basse) et 5 (la plus haute).",
+		"pt": "Envie uma prioridade entre 1 (mais baixa) e 5 (mais alta).",
+	},
+	"I lost track of that reminder. Please send it again.": {
+		"es": "Perdí la pista de ese recordatorio. Envíalo de nuevo, por favor.",
+		"fr": "J'ai perdu ce rappel. Peux-tu le renvoyer ?",
+		"pt": "Perdi esse lembrete. Envie-o novamente, por favor.",
+	},
+	"Got it! I'll remind you: %s (priority %d).": {
+		"es": "¡Listo! Te recordaré: %s (prioridad %d).",
+		"fr": "C'est noté ! Je te rappellerai : %s (priorité %d).",
+		"pt": "Pronto! Vou te lembrar: %s (prioridade %d).",
+	},
+	"Looks like you already have %s: %s.": {
+		"es": "Parece que ya tienes %s: %s.",
+		"fr": "On dirait que tu as déjà %s : %s.",
+		"pt": "Parece que você já tem %s: %s.",
+	},
+	"I couldn't save the reminder. Please try again.": {
+		"es": "No pude guardar el recordatorio. Inténtalo de nuevo.",
+		"fr": "Je n'ai pas pu enregistrer le rappel. Réessaie.",
+		"pt": "Não consegui salvar o lembrete. Tente novamente.",
+	},
+	"Here are your reminders:": {
+		"es": "Estos son tus recordatorios:",
+		"fr": "Voici tes rappels :",
+		"pt": "Aqui estão seus lembretes:",
+	},
+	"Here are your reminders (%s):": {
+		"es": "Estos son tus recordatorios (%s):",
+		"fr": "Voici tes rappels (%s) :",
+		"pt": "Aqui estão seus lembretes (%s):",
+	},
+	"You have no reminders yet. Send me one to get started!": {
+		"es": "Todavía no tienes recordatorios. ¡Envíame uno para empezar!",
+		"fr": "Tu n'as pas encore de rappels. Envoie-m'en un pour commencer !",
+		"pt": "Você ainda não tem lembretes. Envie um para começar!",
+	},
+	"…and %d more, reply 'more' to continue.": {
+		"es": "…y %d más, responde 'more' para continuar.",
+		"fr": "…et %d de plus, réponds 'more' pour continuer.",
+		"pt": "…e mais %d, responda 'more' para continuar.",
+	},
+	"Reminder: %s (priority %d)": {
+		"es": "Recordatorio: %s (prioridad %d)",
+		"fr": "Rappel : %s (priorité %d)",
+		"pt": "Lembrete: %s (prioridade %d)",
+	},
+	"Marked '%s' as done.": {
+		"es": "Marqué '%s' como hecho.",
+		"fr": "'%s' marqué comme fait.",
+		"pt": "'%s' marcado como feito.",
+	},
+	"Deleted reminder(s): %s.": {
+		"es": "Recordatorios borrados: %s.",
+		"fr": "Rappels supprimés : %s.",
+		"pt": "Lembretes apagados: %s.",
+	},
+	"Deleted reminders matching '%s'.": {
+		"es": "Borré los recordatorios que coinciden con '%s'.",
+		"fr": "Rappels correspondant à '%s' supprimés.",
+		"pt": "Apaguei os lembretes que correspondem a '%s'.",
+	},
+	"Which should I delete? Reply with the number(s), 'all', or 'cancel'.": {
+		"es": "¿Cuál borro? Responde con el número o números, 'all' o 'cancel'.",
+		"fr": "Lequel dois-je supprimer ? Réponds avec le ou les numéros, 'all' ou 'cancel'.",
+		"pt": "Qual devo apagar? Responda com o(s) número(s), 'all' ou 'cancel'.",
+	},
+	"Okay, I won't delete anything.": {
+		"es": "De acuerdo, no borraré nada.",
+		"fr": "D'accord, je ne supprime rien.",
+		"pt": "Certo, não vou apagar nada.",
+	},
+	"Deleted: %s.": {
+		"es": "Borrado: %s.",
+		"fr": "Supprimé : %s.",
+		"pt": "Apagado: %s.",
+	},
+	"You don't have any reminders yet.": {
+		"es": "Todavía no tienes recordatorios.",
+		"fr": "Tu n'as pas encore de rappels.",
+		"pt": "Você ainda não tem lembretes.",
+	},
+	"You don't have any reminders to clear.": {
+		"es": "No tienes recordatorios que borrar.",
+		"fr": "Tu n'as aucun rappel à effacer.",
+		"pt": "Você não tem lembretes para apagar.",
+	},
+	"You have no reminders matching: %s.": {
+		"es": "No tienes recordatorios que coincidan con: %s.",
+		"fr": "Tu n'as aucun rappel correspondant à : %s.",
+		"pt": "Você não tem lembretes que correspondam a: %s.",
+	},
+	"Reminders matching '%s':": {
+		"es": "Recordatorios que coinciden con '%s':",
+		"fr": "Rappels correspondant à '%s' :",
+		"pt": "Lembretes que correspondem a '%s':",
+	},
+	"Reminders paused. Say 'resume reminders' when you want them back.": {
+		"es": "Recordatorios en pausa. Di 'resume reminders' cuando quieras retomarlos.",
+		"fr": "Rappels en pause. Dis 'resume reminders' pour les reprendre.",
+		"pt": "Lembretes pausados. Diga 'resume reminders' quando quiser retomá-los.",
+	},
+	"Reminders resumed. You'll get them again from the next scheduled run.": {
+		"es": "Recordatorios reanudados. Volverás a recibirlos desde el próximo envío programado.",
+		"fr": "Rappels repris. Tu les recevras à nouveau dès le prochain envoi prévu.",
+		"pt": "Lembretes retomados. Você voltará a recebê-los a partir do próximo envio programado.",
+	},
+	"I'll call you as well when a priority 5 reminder is due. Say 'stop calling me' to turn calls off.": {
+		"es": "También te llamaré cuando venza un recordatorio de prioridad 5. Di 'stop calling me' para desactivar las llamadas.",
+		"fr": "Je t'appellerai aussi quand un rappel de priorité 5 arrive à échéance. Dis 'stop calling me' pour arrêter les appels.",
+		"pt": "Também vou te ligar quando um lembrete de prioridade 5 vencer. Diga 'stop calling me' para desativar as ligações.",
+	},
+	"No more calls. Priority 5 reminders will come by message only.": {
+		"es": "No más llamadas. Los recordatorios de prioridad 5 llegarán solo por mensaje.",
+		"fr": "Plus d'appels. Les rappels de priorité 5 arriveront uniquement par message.",
+		"pt": "Sem mais ligações. Os lembretes de prioridade 5 chegarão só por mensagem.",
+	},
+	"Welcome back! Some reminders couldn't reach you while you were disconnected. Say 'list reminders' to see them all.": {
+		"es": "¡Bienvenido de nuevo! Algunos recordatorios no te llegaron mientras estabas desconectado. Di 'list reminders' para verlos todos.",
+		"fr": "Bon retour ! Certains rappels ne t'ont pas atteint pendant ta déconnexion. Dis 'list reminders' pour tous les voir.",
+		"pt": "Bem-vindo de volta! Alguns lembretes não chegaram enquanto você estava desconectado. Diga 'list reminders' para ver todos.",
+	},
+	"Today's the day: %s!": {
+		"es": "¡Hoy es el día: %s!",
+		"fr": "C'est le grand jour : %s !",
+		"pt": "Chegou o dia: %s!",
+	},
+	"Your weekly review: %d done this week, %d still open.": {
+		"es": "Tu resumen semanal: %d hechos esta semana, %d pendientes.",
+		"fr": "Ton bilan de la semaine : %d faits cette semaine, %d encore ouverts.",
+		"pt": "Seu resumo semanal: %d concluídos esta semana, %d ainda abertos.",
+	},
+	"Untouched for over two weeks:": {
+		"es": "Sin tocar desde hace más de dos semanas:",
+		"fr": "Pas touchés depuis plus de deux semaines :",
+		"pt": "Sem mexer há mais de duas semanas:",
+	},
+	"Your lists:": {
+		"es": "Tus listas:",
+		"fr": "Tes listes :",
+		"pt": "Suas listas:",
+	},
+	"You have no lists yet. Try 'add War and Peace to my books list'.": {
+		"es": "Todavía no tienes listas. Prueba 'add War and Peace to my books list'.",
+		"fr": "Tu n'as pas encore de listes. Essaie 'add War and Peace to my books list'.",
+		"pt": "Você ainda não tem listas. Experimente 'add War and Peace to my books list'.",
+	},
+	"Added '%s' to your %s list.": {
+		"es": "Añadí '%s' a tu lista %s.",
+		"fr": "'%s' ajouté à ta liste %s.",
+		"pt": "Adicionei '%s' à sua lista %s.",
+	},
+	"Your %s list:": {
+		"es": "Tu lista %s:",
+		"fr": "Ta liste %s :",
+		"pt": "Sua lista %s:",
+	},
+	"Your %s list is empty.": {
+		"es": "Tu lista %s está vacía.",
+		"fr": "Ta liste %s est vide.",
+		"pt": "Sua lista %s está vazia.",
+	},
+	"Your %s list is now empty.": {
+		"es": "Tu lista %s ahora está vacía.",
+		"fr": "Ta liste %s est maintenant vide.",
+		"pt": "Sua lista %s agora está vazia.",
+	},
+	"Deleted your %s list.": {
+		"es": "Borré tu lista %s.",
+		"fr": "Ta liste %s a été supprimée.",
+		"pt": "Apaguei sua lista %s.",
+	},
+	"You don't have a %s list.": {
+		"es": "No tienes una lista %s.",
+		"fr": "Tu n'as pas de liste %s.",
+		"pt": "Você não tem uma lista %s.",
+	},
+	"You can say things like:": {
+		"es": "Puedes decir cosas como:",
+		"fr": "Tu peux dire par exemple :",
+		"pt": "Você pode dizer coisas como:",
+	},
+	"Language set to %s.": {
+		"es": "Idioma cambiado a %s.",
+		"fr": "Langue réglée sur %s.",
+		"pt": "Idioma definido como %s.",
+	},
+	"I don't speak %s yet. Try %s.": {
+		"es": "Todavía no hablo %s. Prueba con %s.",
+		"fr": "Je ne parle pas encore %s. Essaie %s.",
+		"pt": "Ainda não falo %s. Experimente %s.",
+	},
+}
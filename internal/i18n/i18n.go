@@ -0,0 +1,133 @@
+// Package i18n translates the bot's canned replies.
+//
+// The catalog is keyed by the English format strings the bot uses, so a reply
+// is translated by matching each of its lines against those formats and
+// re-rendering the captured values into the translation. Lines with no
+// catalog entry stay in English.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Language is a reply language users can pick.
+type Language struct {
+	Code   string // ISO 639-1, as stored in preferences
+	Name   string // English name, as passed to the language model
+	Native string
+}
+
+// English is the language the bot is written in.
+var English = Language{Code: "en", Name: "English", Native: "English"}
+
+var languages = []Language{
+	English,
+	{Code: "es", Name: "Spanish", Native: "español"},
+	{Code: "fr", Name: "French", Native: "français"},
+	{Code: "pt", Name: "Portuguese", Native: "português"},
+}
+
+// Languages returns every supported language, English first.
+func Languages() []Language {
+	return append([]Language(nil), languages...)
+}
+
+// Lookup finds a language by code or by its English or native name.
+func Lookup(name string) (Language, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, l := range languages {
+		if name == l.Code || name == strings.ToLower(l.Name) || name == l.Native || name == stripAccents(l.Native) {
+			return l, true
+		}
+	}
+	return Language{}, false
+}
+
+// ByCode returns the language for a stored code, or English.
+func ByCode(code string) Language {
+	for _, l := range languages {
+		if l.Code == code {
+			return l
+		}
+	}
+	return English
+}
+
+// Translate renders text, line by line, in the language with the given
+// code. Lines the catalog doesn't know are left as they are.
+func Translate(code, text string) string {
+	if code == "" || code == English.Code || text == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = translateLine(code, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func translateLine(code, line string) string {
+	for _, e := range entries {
+		translation, ok := e.translations[code]
+		if !ok {
+			continue
+		}
+		m := e.pattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		args := make([]any, len(m)-1)
+		for i, v := range m[1:] {
+			args[i] = v
+		}
+		return fmt.Sprintf(translation, args...)
+	}
+	return line
+}
+
+type entry struct {
+	pattern      *regexp.Regexp
+	translations map[string]string
+}
+
+var entries = compile(catalog)
+
+var verbPattern = regexp.MustCompile(`%(\[\d+\])?[sd]`)
+
+// compile turns each English format into an anchored pattern that captures
+// its arguments, and rewrites the translations to take those captures as
+// strings.
+func compile(catalog map[string]map[string]string) []entry {
+	out := make([]entry, 0, len(catalog))
+	for format, translations := range catalog {
+		var pattern strings.Builder
+		pattern.WriteString("^")
+		last := 0
+		for _, loc := range verbPattern.FindAllStringIndex(format, -1) {
+			pattern.WriteString(regexp.QuoteMeta(format[last:loc[0]]))
+			if strings.HasSuffix(format[loc[0]:loc[1]], "d") {
+				pattern.WriteString(`(-?\d+)`)
+			} else {
+				pattern.WriteString(`(.+?)`)
+			}
+			last = loc[1]
+		}
+		pattern.WriteString(regexp.QuoteMeta(format[last:]))
+		pattern.WriteString("$")
+
+		asStrings := make(map[string]string, len(translations))
+		for code, t := range translations {
+			asStrings[code] = verbPattern.ReplaceAllStringFunc(t, func(v string) string {
+				return strings.TrimSuffix(strings.TrimSuffix(v, "d"), "s") + "s"
+			})
+		}
+		out = append(out, entry{pattern: regexp.MustCompile(pattern.String()), translations: asStrings})
+	}
+	return out
+}
+
+func stripAccents(s string) string {
+	return strings.NewReplacer("ñ", "n", "ç", "c", "ê", "e", "é", "e", "è", "e", "á", "a", "ã", "a", "ó", "o", "õ", "o", "í", "i", "ú", "u").Replace(s)
+}
@@ -0,0 +1,105 @@
+package linkfetch
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/netguard"
+)
+
+const (
+	maxBodyBytes = 2 << 20
+	maxTextRunes = 4000
+)
+
+// Page is the readable content extracted from a fetched URL.
+type Page struct {
+	URL   string
+	Title string
+	Text  string
+}
+
+// Fetcher downloads web pages and extracts their title and visible text.
+type Fetcher struct {
+	client *http.Client
+}
+
+// New returns a Fetcher with a conservative timeout. It only connects to
+// public addresses, so a saved link can't reach the server's own network.
+func New() *Fetcher {
+	return NewWithClient(netguard.NewClient(10 * time.Second))
+}
+
+// NewWithClient returns a Fetcher that uses client, mainly for tests.
+func NewWithClient(client *http.Client) *Fetcher {
+	return &Fetcher{client: client}
+}
+
+// Fetch downloads url and extracts its title and text.
+func (f *Fetcher) Fetch(ctx context.Context, url string) (Page, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Page{}, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("User-Agent", "myMemo/1.0 (+https://github.com/pathakanu/myMemo)")
+	req.Header.Set("Accept", "text/html,text/plain;q=0.9,*/*;q=0.5")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Page{}, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Page{}, fmt.Errorf("fetch %s: unexpected status %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return Page{}, fmt.Errorf("read %s: %w", url, err)
+	}
+
+	page := Page{URL: resp.Request.URL.String()}
+	if strings.Contains(resp.Header.Get("Content-Type"), "text/plain") {
+		page.Text = limitRunes(collapseSpace(string(body)), maxTextRunes)
+		return page, nil
+	}
+	page.Title, page.Text = extract(string(body))
+	return page, nil
+}
+
+var (
+	titlePattern     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	invisiblePattern = regexp.MustCompile(`(?is)<(script|style|noscript|svg|head)[^>]*>.*?</(script|style|noscript|svg|head)>`)
+	tagPattern       = regexp.MustCompile(`(?s)<[^>]+>`)
+	spacePattern     = regexp.MustCompile(`\s+`)
+)
+
+func extract(doc string) (string, string) {
+	var title string
+	if m := titlePattern.FindStringSubmatch(doc); len(m) > 1 {
+		title = collapseSpace(html.UnescapeString(m[1]))
+	}
+	text := invisiblePattern.ReplaceAllString(doc, " ")
+	text = tagPattern.ReplaceAllString(text, " ")
+	text = collapseSpace(html.UnescapeString(text))
+	return title, limitRunes(text, maxTextRunes)
+}
+
+func collapseSpace(s string) string {
+	return strings.TrimSpace(spacePattern.ReplaceAllString(s, " "))
+}
+
+func limitRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Message directions.
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+// ArchivedMessage is one chat message to or from a user, kept for
+// MESSAGE_ARCHIVE_DAYS so the user can look back at what they sent.
+type ArchivedMessage struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID string `gorm:"index:idx_archived_messages_user_created;not null"`
+	// Direction is "in" for a message from the user and "out" for one the
+	// bot sent.
+	Direction string    `gorm:"not null"`
+	Body      string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"index:idx_archived_messages_user_created;autoCreateTime"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Blackout is a stretch of days, such as a trip, when a user gets no
+// reminders. FirstDay and LastDay are midnight in the server's timezone.
+type Blackout struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    string    `gorm:"index;not null"`
+	FirstDay  time.Time `gorm:"not null"`
+	LastDay   time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
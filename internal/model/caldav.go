@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// CalDAVConnection links a user to a task list on a CalDAV server, such as
+// iCloud Reminders or Nextcloud Tasks, that mirrors their reminders.
+type CalDAVConnection struct {
+	UserID string `gorm:"primaryKey"`
+	// CollectionURL is the task list reminders are written to.
+	CollectionURL string `gorm:"not null"`
+	Username      string `gorm:"not null"`
+	// Password is the app-specific password the user created for the bot,
+	// encrypted with CREDENTIALS_KEY.
+	Password     string `gorm:"not null"`
+	LastSyncedAt *time.Time
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ReminderItem is one step on a reminder's checklist, such as "buy paint" on
+// "paint the fence".
+type ReminderItem struct {
+	ID         uint   `gorm:"primaryKey"`
+	ReminderID uint   `gorm:"index;not null"`
+	Text       string `gorm:"type:text;not null"`
+	// DoneAt is when the step was checked off; nil while it is open.
+	DoneAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
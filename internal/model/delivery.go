@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Message statuses reported by Twilio status callbacks, in the order a
+// message moves through them. Failed and undelivered are final.
+const (
+	DeliveryQueued      = "queued"
+	DeliverySent        = "sent"
+	DeliveryDelivered   = "delivered"
+	DeliveryRead        = "read"
+	DeliveryFailed      = "failed"
+	DeliveryUndelivered = "undelivered"
+)
+
+// ReminderDelivery records one message sent to a user about a reminder, on
+// any channel, so they can see when they were reminded. WhatsApp messages
+// sent with a status callback have their status tracked as it changes, and
+// follow-ups go only to those that failed or went unread.
+type ReminderDelivery struct {
+	ID         uint   `gorm:"primaryKey"`
+	ReminderID uint   `gorm:"index;not null"`
+	UserID     string `gorm:"index;not null"`
+	// Channel is the channel name the message went out on, such as whatsapp,
+	// email, or voice.
+	Channel string `gorm:"not null;default:whatsapp"`
+	Status  string `gorm:"index;not null;default:queued"`
+	// CallbackHash is the SHA-256 hash of the token in the status callback
+	// URL, which proves a callback came for this delivery. It is empty for
+	// messages without a status callback.
+	CallbackHash string `gorm:"not null"`
+	// FollowedUp is set once the message has been resent or escalated to a
+	// call, and on follow-ups themselves and untracked messages, so each
+	// reminder gets at most one.
+	FollowedUp bool `gorm:"not null;default:false"`
+	// MessageSID is Twilio's ID for the message, learned from its status
+	// callbacks, so a reaction to the message can be traced to the reminder.
+	MessageSID string `gorm:"column:message_sid;index"`
+	ReadAt     *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+// Failed reports whether the message never reached the user.
+func (d ReminderDelivery) Failed() bool {
+	return d.Status == DeliveryFailed || d.Status == DeliveryUndelivered
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ReminderEmbedding is the embedding of a reminder's content, used for
+// semantic search, duplicate detection, and clustering.
+type ReminderEmbedding struct {
+	ReminderID uint   `gorm:"primaryKey;autoIncrement:false"`
+	UserID     string `gorm:"index;not null"`
+	// Model names the embedding model, so vectors from different models
+	// are never compared.
+	Model     string `gorm:"not null"`
+	Embedding Vector `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+// Vector is an embedding. PostgreSQL stores it as a pgvector vector and
+// SQLite as a blob of little-endian float32s.
+type Vector []float32
+
+// GormDataType names the type for GORM's schema parser.
+func (Vector) GormDataType() string {
+	return "vector"
+}
+
+// GormDBDataType picks the column type for the database in use.
+func (Vector) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "vector"
+	}
+	return "blob"
+}
+
+// GormValue encodes v for the database in use.
+func (v Vector) GormValue(_ context.Context, db *gorm.DB) clause.Expr {
+	if db.Dialector.Name() == "postgres" {
+		return clause.Expr{SQL: "?::vector", Vars: []any{v.String()}}
+	}
+	return clause.Expr{SQL: "?", Vars: []any{v.bytes()}}
+}
+
+// Scan reads a pgvector value, which arrives as text such as "[1,2,3]", or
+// a SQLite blob.
+func (v *Vector) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return v.parse(src)
+	case []byte:
+		if len(src)%4 != 0 {
+			return fmt.Errorf("vector blob of %d bytes", len(src))
+		}
+		out := make(Vector, len(src)/4)
+		for i := range out {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(src[i*4:]))
+		}
+		*v = out
+		return nil
+	case nil:
+		*v = nil
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into a vector", src)
+}
+
+// String formats v in pgvector's text form.
+func (v Vector) String() string {
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func (v Vector) bytes() []byte {
+	out := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(out[i*4:], math.Float32bits(f))
+	}
+	return out
+}
+
+func (v *Vector) parse(text string) error {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+		return fmt.Errorf("malformed vector %q", text)
+	}
+	text = text[1 : len(text)-1]
+	out := Vector{}
+	if text != "" {
+		for _, part := range strings.Split(text, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+			if err != nil {
+				return fmt.Errorf("malformed vector: %w", err)
+			}
+			out = append(out, float32(f))
+		}
+	}
+	*v = out
+	return nil
+}
+
+// CosineDistance returns 1 minus the cosine similarity of v and w: 0 for
+// vectors pointing the same way, up to 2 for opposite ones. Vectors of
+// different lengths, or with no magnitude, are 1 apart.
+func (v Vector) CosineDistance(w Vector) float64 {
+	if len(v) != len(w) {
+		return 1
+	}
+	var dot, vv, ww float64
+	for i := range v {
+		dot += float64(v[i]) * float64(w[i])
+		vv += float64(v[i]) * float64(v[i])
+		ww += float64(w[i]) * float64(w[i])
+	}
+	if vv == 0 || ww == 0 {
+		return 1
+	}
+	return 1 - dot/math.Sqrt(vv*ww)
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Feedback is a bug report or comment a user sent with "feedback: ...",
+// stored with enough of the user's context to follow it up.
+type Feedback struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID string `gorm:"index;not null"`
+	Text   string `gorm:"type:text;not null"`
+	// RecentMessages is the user's last few messages before the feedback,
+	// each followed by the bot's reply.
+	RecentMessages string `gorm:"type:text"`
+	OpenReminders  int64  `gorm:"not null;default:0"`
+	Language       string
+	Timezone       string
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// JobRun records the last successful run of a named scheduler job.
+type JobRun struct {
+	Name      string    `gorm:"primaryKey"`
+	LastRunAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// List display formats.
+const (
+	ListFormatBullets   = "bullets"
+	ListFormatNumbered  = "numbered"
+	ListFormatChecklist = "checklist"
+)
+
+// List is a user-named collection of free-form items such as books or groceries.
+type List struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"uniqueIndex:idx_list_user_name;not null"`
+	Name      string `gorm:"uniqueIndex:idx_list_user_name;not null"`
+	Format    string `gorm:"not null;default:bullets"`
+	Items     []ListItem
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Roles a list owner can give the people they share it with.
+const (
+	ListRoleViewer = "viewer"
+	ListRoleEditor = "editor"
+)
+
+// ListMember gives another user access to a List. Viewers can read it;
+// editors can also add, remove, and clear items. Only the owner can share,
+// rename, or delete it.
+type ListMember struct {
+	ListID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	UserID    string    `gorm:"primaryKey;index"`
+	Role      string    `gorm:"not null;default:editor"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// ListItem is a single entry on a List.
+type ListItem struct {
+	ID        uint      `gorm:"primaryKey"`
+	ListID    uint      `gorm:"index;not null"`
+	Content   string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
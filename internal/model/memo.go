@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Memo kinds.
+const (
+	MemoKindNote = "note"
+	MemoKindLink = "link"
+)
+
+// Memo is free-form text captured with "note: ..." or a saved link. Memos are
+// never scheduled like reminders.
+type Memo struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"index;not null"`
+	Kind      string `gorm:"index;not null;default:note"`
+	Title     string `gorm:"not null"`
+	Content   string `gorm:"type:text;not null"`
+	URL       string `gorm:"type:text"`
+	SentAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// NotionConnection links a user to a Notion database that mirrors their
+// reminders. The *Property fields name the database columns reminder fields
+// map to; an empty name leaves that field unsynced.
+type NotionConnection struct {
+	UserID           string `gorm:"primaryKey"`
+	Token            string `gorm:"not null"`
+	DatabaseID       string `gorm:"not null"`
+	TitleProperty    string `gorm:"not null"`
+	PriorityProperty string
+	PriorityType     string
+	DueProperty      string
+	StatusProperty   string
+	StatusType       string
+	OpenStatus       string
+	DoneStatus       string
+	Pull             bool `gorm:"not null;default:false"`
+	LastSyncedAt     *time.Time
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+}
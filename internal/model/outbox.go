@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// OutboxMessage is a scheduled reminder send that was still pending when the
+// bot shut down. It is replayed when the scheduler starts again.
+type OutboxMessage struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     string    `gorm:"index;not null"`
+	ReminderID uint      `gorm:"index"`
+	Body       string    `gorm:"type:text;not null"`
+	SendAt     time.Time `gorm:"not null"`
+	// IdempotencyKey names the reminder and the day it was scheduled for,
+	// so a replayed or retried send is delivered at most once.
+	IdempotencyKey string    `gorm:"index"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+// SentMessage records the idempotency key of a reminder message that went
+// out, so a second send with the same key is dropped.
+type SentMessage struct {
+	IdempotencyKey string    `gorm:"primaryKey"`
+	UserID         string    `gorm:"index;not null"`
+	SentAt         time.Time `gorm:"index;not null"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// Orders a user can choose for lists and the daily dispatch.
+const (
+	SortPriority = "priority"
+	SortDate     = "date"
+	SortDue      = "due"
+)
+
+// Delivery modes for the daily reminder dispatch.
+const (
+	DeliveryWhatsApp = "whatsapp"
+	DeliveryEmail    = "email"
+	DeliveryBoth     = "both"
+)
+
+// UserPreference stores per-user settings that override global defaults.
+type UserPreference struct {
+	UserID             string `gorm:"primaryKey"`
+	ReminderGapMinutes *int
+	DailyArticle       bool `gorm:"not null;default:false"`
+	Paused             bool `gorm:"not null;default:false"`
+	PausedByAdmin      bool `gorm:"not null;default:false"` // Paused came from "admin pause-all"
+	Email              string
+	Delivery           string     `gorm:"not null;default:whatsapp"`
+	OptedOutAt         *time.Time // set while the user has opted out with STOP
+	SortOrder          string     `gorm:"not null;default:priority"`
+	BirthdayLeadDays   *int       // days before a birthday to send a heads-up
+	Language           string     // ISO 639-1 code for replies; empty means English
+	CallAlerts         bool       `gorm:"not null;default:false"` // phone about priority 5 reminders too
+	SenderNumber       string     // pool number the user hears from; empty until assigned
+	UnjoinedAt         *time.Time // set while the user has dropped out of the Twilio sandbox
+	MorningHour        *int       // hour morning reminders are sent; nil means the default
+	AfternoonHour      *int       // hour afternoon reminders are sent; nil means the default
+	EveningHour        *int       // hour evening reminders are sent; nil means the default
+	Timezone           string     // IANA name of where the user is; empty means the server's
+	DeliveryHour       *int       // hour the daily reminders start going out; nil means at dispatch
+	QuietStart         *int       // hour quiet hours begin; nil means none
+	QuietEnd           *int       // hour quiet hours end, the next day when before QuietStart
+	Tips               bool       `gorm:"not null;default:false"` // opted in to usage tips and announcements
+	TipsSent           string     // comma-separated keys of the tips already sent
+	TipSentAt          *time.Time // when the last tip went out
+	NoShares           bool       `gorm:"not null;default:false"` // refuses reminders other users share
+	MorningBriefing    bool       `gorm:"not null;default:false"` // gets a plan for the day at the morning hour
+	CalendarInvites    bool       `gorm:"not null;default:false"` // gets an .ics file for each new reminder with a due date
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime"`
+}
+
+// EmailDelivery reports whether the daily digest should be emailed.
+func (p UserPreference) EmailDelivery() bool {
+	return p.Email != "" && (p.Delivery == DeliveryEmail || p.Delivery == DeliveryBoth)
+}
+
+// WhatsAppDelivery reports whether reminders should be sent over WhatsApp.
+// Users without an email address always get WhatsApp.
+func (p UserPreference) WhatsAppDelivery() bool {
+	return p.Delivery != DeliveryEmail || p.Email == ""
+}
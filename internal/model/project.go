@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Project groups a user's reminders under a name such as "kitchen
+// renovation", so they can be listed together and their progress tracked.
+type Project struct {
+	ID     uint   `gorm:"primaryKey"`
+	UserID string `gorm:"uniqueIndex:idx_project_user_name;not null"`
+	// Name is stored in lower case.
+	Name      string    `gorm:"uniqueIndex:idx_project_user_name;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
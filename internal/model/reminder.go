@@ -1,13 +1,146 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reminder kinds.
+const (
+	KindReminder  = "reminder"
+	KindHabit     = "habit"
+	KindCountdown = "countdown"
+	// Birthdays and anniversaries come back every year on DueAt, which
+	// moves on a year once the day has passed.
+	KindBirthday    = "birthday"
+	KindAnniversary = "anniversary"
+)
+
+// Times of day a reminder can be sent in instead of at dispatch time.
+const (
+	TimeMorning   = "morning"
+	TimeAfternoon = "afternoon"
+	TimeEvening   = "evening"
+)
+
+// Countdown intervals.
+const (
+	IntervalDaily  = "daily"
+	IntervalWeekly = "weekly"
+)
 
 // Reminder represents a saved reminder for a WhatsApp user.
 type Reminder struct {
-	ID        uint      `gorm:"primaryKey"`
-	UserID    string    `gorm:"index;not null"`
-	Content   string    `gorm:"type:text;not null"`
-	Priority  int       `gorm:"not null"`
-	Summary   string    `gorm:"type:text"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID     uint   `gorm:"primaryKey"`
+	UserID string `gorm:"index;not null"`
+	// Code numbers a user's reminders in creation order. Unlike list
+	// positions it never changes, so "delete R7" is safe to repeat.
+	Code        int    `gorm:"not null;default:0"`
+	Content     string `gorm:"type:text;not null"`
+	Priority    int    `gorm:"not null"`
+	Summary     string `gorm:"type:text"`
+	Kind        string `gorm:"not null;default:reminder"`
+	Streak      int    `gorm:"not null;default:0"`
+	LastCheckIn *time.Time
+	// LastSkip is the last day the habit was skipped on purpose, which
+	// neither breaks nor extends its streak. Skips counts such days.
+	LastSkip *time.Time
+	Skips    int `gorm:"not null;default:0"`
+	DueAt    *time.Time
+	// Category is one of the configured categories, such as "work", picked
+	// when the reminder is saved. It is empty when none fits.
+	Category string `gorm:"index"`
+	// Interval is how often a countdown is sent, daily or weekly.
+	Interval string
+	// RRule is an iCalendar repeat rule, e.g. "FREQ=DAILY;INTERVAL=3", for a
+	// reminder that comes back on a schedule. DueAt is its next occurrence.
+	RRule string `gorm:"column:rrule"`
+	// OccursAt is the day RRule puts the next occurrence on when business
+	// days moved DueAt off it, and is what the occurrence after is counted
+	// from. It is nil when DueAt is the occurrence.
+	OccursAt *time.Time
+	// BusinessDays keeps the reminder to working days: it isn't sent on
+	// weekends or public holidays, and a due date on one moves to the next
+	// working day.
+	BusinessDays bool `gorm:"not null;default:false"`
+	// TimeOfDay is morning, afternoon, or evening for a reminder that should
+	// wait for that part of the day, or empty to send it at dispatch time.
+	TimeOfDay string
+	// Latitude, Longitude, and Place record a location pin the user shared
+	// for the reminder.
+	Latitude  *float64
+	Longitude *float64
+	Place     string
+	// AttachmentKey is where a photo or file the user sent with the
+	// reminder is kept in the blob store, and AttachmentType its MIME type.
+	AttachmentKey  string
+	AttachmentType string
+	// Steps counts the reminder's checklist items and StepsDone those
+	// checked off, so lists can show progress without loading them.
+	Steps     int `gorm:"not null;default:0"`
+	StepsDone int `gorm:"not null;default:0"`
+	// ProjectID is the project the reminder belongs to, if any.
+	ProjectID *uint `gorm:"index"`
+	// ParentID is the reminder this one waits for. It stays out of lists and
+	// dispatches until the parent is done or deleted.
+	ParentID *uint `gorm:"index"`
+	// RescheduleOfferedAt is when the bot last offered to move the reminder
+	// after it went overdue.
+	RescheduleOfferedAt *time.Time
+	// Snoozes counts the times the due date was pushed back.
+	Snoozes int `gorm:"not null;default:0"`
+	// SnoozedUntil keeps the reminder out of dispatches before that day,
+	// after the user snoozed it.
+	SnoozedUntil *time.Time
+	NotionPageID string `gorm:"index"`
+	// CalDAVSynced is set once the reminder has been written to the user's
+	// CalDAV task list, so a task deleted there isn't written back.
+	CalDAVSynced bool       `gorm:"column:caldav_synced;not null;default:false"`
+	CompletedAt  *time.Time `gorm:"index"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+}
+
+// ShortID returns the user-facing code for the reminder, e.g. "R7".
+func (r Reminder) ShortID() string {
+	return fmt.Sprintf("R%d", r.Code)
+}
+
+// BeforeCreate gives a new reminder the next code for its user.
+func (r *Reminder) BeforeCreate(tx *gorm.DB) error {
+	if r.Code != 0 {
+		return nil
+	}
+	var last int
+	err := tx.Session(&gorm.Session{NewDB: true}).Model(&Reminder{}).
+		Where("user_id = ?", r.UserID).
+		Select("COALESCE(MAX(code), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	r.Code = last + 1
+	return nil
+}
+
+// IsHabit reports whether the reminder is a daily habit.
+func (r Reminder) IsHabit() bool {
+	return r.Kind == KindHabit
+}
+
+// IsCountdown reports whether the reminder counts down the days to DueAt.
+func (r Reminder) IsCountdown() bool {
+	return r.Kind == KindCountdown && r.DueAt != nil
+}
+
+// IsAnnual reports whether the reminder is a birthday or anniversary.
+func (r Reminder) IsAnnual() bool {
+	return (r.Kind == KindBirthday || r.Kind == KindAnniversary) && r.DueAt != nil
+}
+
+// Recurring reports whether the reminder is sent again and again by design,
+// rather than sitting open until someone deals with it.
+func (r Reminder) Recurring() bool {
+	return r.RRule != "" || (r.Kind != KindReminder && r.Kind != "")
 }
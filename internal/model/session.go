@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// LoginToken is a single-use magic link token. Only the SHA-256 hash of the
+// token is stored.
+type LoginToken struct {
+	TokenHash string    `gorm:"primaryKey"`
+	UserID    string    `gorm:"index;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// HookToken lets automations add reminders for one user through the inbound
+// hook. Each user has at most one; only the SHA-256 hash of it is stored.
+type HookToken struct {
+	TokenHash string    `gorm:"primaryKey"`
+	UserID    string    `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Session is a signed-in web dashboard session. Only the SHA-256 hash of the
+// cookie value is stored.
+type Session struct {
+	TokenHash string    `gorm:"primaryKey"`
+	UserID    string    `gorm:"index;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// SharedReminder is a copy of a reminder one user sent another with "share 3
+// with +14155550123". The recipient can save it to their own reminders.
+type SharedReminder struct {
+	ID         uint   `gorm:"primaryKey"`
+	FromUserID string `gorm:"not null"`
+	ToUserID   string `gorm:"index;not null"`
+	Content    string `gorm:"type:text;not null"`
+	Summary    string `gorm:"type:text"`
+	Priority   int    `gorm:"not null"`
+	DueAt      *time.Time
+	RRule      string `gorm:"column:rrule"`
+	// SavedAt is when the recipient saved the reminder; nil until then.
+	SavedAt   *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Template is a named reminder shortcut a user can trigger with a single word.
+type Template struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    string    `gorm:"uniqueIndex:idx_template_user_name;not null"`
+	Name      string    `gorm:"uniqueIndex:idx_template_user_name;not null"`
+	Content   string    `gorm:"type:text;not null"`
+	Priority  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
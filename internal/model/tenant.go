@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Tenant is a workspace served from its own Twilio WhatsApp number. Users
+// belong to the tenant whose number they first message; users of the
+// deployment's default number have no tenant.
+type Tenant struct {
+	ID             uint   `gorm:"primaryKey"`
+	Name           string `gorm:"uniqueIndex;not null"`
+	WhatsAppNumber string `gorm:"uniqueIndex;not null"` // E.164, without the whatsapp: prefix
+	// TwilioAccountSID and TwilioAuthToken override the deployment's Twilio
+	// account when the number lives in a different one.
+	TwilioAccountSID   string `gorm:"column:twilio_account_sid"`
+	TwilioAuthToken    string
+	ReminderGapMinutes *int
+	// OpenAIMonthlyLimit caps the OpenAI calls made for the tenant's users each
+	// calendar month. Zero means unlimited.
+	OpenAIMonthlyLimit int       `gorm:"column:openai_monthly_limit;not null;default:0"`
+	CreatedAt          time.Time `gorm:"autoCreateTime"`
+}
+
+// TenantUser assigns a user to a tenant.
+type TenantUser struct {
+	UserID    string    `gorm:"primaryKey"`
+	TenantID  uint      `gorm:"index;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// OpenAIUsage counts a tenant's OpenAI calls in one month ("2006-01").
+type OpenAIUsage struct {
+	TenantID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Month    string `gorm:"primaryKey"`
+	Calls    int    `gorm:"not null;default:0"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Webhook is a user-registered HTTP endpoint that receives reminder lifecycle events.
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    string    `gorm:"index;not null"`
+	URL       string    `gorm:"type:text;not null"`
+	Secret    string    `gorm:"not null"`
+	Events    string    `gorm:"type:text"` // comma-separated; empty means all events
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,267 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://api.notion.com/v1"
+	apiVersion     = "2022-06-28"
+)
+
+// Property types the bot knows how to read and write.
+const (
+	TypeTitle    = "title"
+	TypeRichText = "rich_text"
+	TypeNumber   = "number"
+	TypeDate     = "date"
+	TypeStatus   = "status"
+	TypeSelect   = "select"
+	TypeCheckbox = "checkbox"
+)
+
+// Database is the schema of a Notion database.
+type Database struct {
+	ID         string              `json:"id"`
+	Title      []RichText          `json:"title"`
+	Properties map[string]Property `json:"properties"`
+}
+
+// Name returns the plain-text database title.
+func (d Database) Name() string {
+	return PlainText(d.Title)
+}
+
+// Property describes one column of a database.
+type Property struct {
+	ID     string       `json:"id"`
+	Type   string       `json:"type"`
+	Status *OptionsList `json:"status,omitempty"`
+	Select *OptionsList `json:"select,omitempty"`
+}
+
+// Options returns the choices of a status or select property.
+func (p Property) Options() []Option {
+	switch {
+	case p.Status != nil:
+		return p.Status.Options
+	case p.Select != nil:
+		return p.Select.Options
+	}
+	return nil
+}
+
+// OptionsList holds the choices of a status or select property.
+type OptionsList struct {
+	Options []Option `json:"options"`
+}
+
+// Option is a single status or select choice.
+type Option struct {
+	Name string `json:"name"`
+}
+
+// Page is a row of a database.
+type Page struct {
+	ID         string                   `json:"id"`
+	Archived   bool                     `json:"archived"`
+	Properties map[string]PropertyValue `json:"properties"`
+}
+
+// PropertyValue is the value of a page property. Only the field matching
+// Type is set.
+type PropertyValue struct {
+	Type     string     `json:"type,omitempty"`
+	Title    []RichText `json:"title,omitempty"`
+	RichText []RichText `json:"rich_text,omitempty"`
+	Number   *float64   `json:"number,omitempty"`
+	Date     *Date      `json:"date,omitempty"`
+	Status   *Option    `json:"status,omitempty"`
+	Select   *Option    `json:"select,omitempty"`
+	Checkbox *bool      `json:"checkbox,omitempty"`
+}
+
+// RichText is a run of text in a title or rich_text property.
+type RichText struct {
+	PlainText string `json:"plain_text,omitempty"`
+	Text      *Text  `json:"text,omitempty"`
+}
+
+// Text is the content of a RichText run when writing.
+type Text struct {
+	Content string `json:"content"`
+}
+
+// Date is the value of a date property. Start is an ISO 8601 date or datetime.
+type Date struct {
+	Start string `json:"start"`
+}
+
+// PlainText joins the plain text of a list of rich text runs.
+func PlainText(runs []RichText) string {
+	var sb strings.Builder
+	for _, r := range runs {
+		if r.PlainText != "" {
+			sb.WriteString(r.PlainText)
+		} else if r.Text != nil {
+			sb.WriteString(r.Text.Content)
+		}
+	}
+	return sb.String()
+}
+
+// TitleValue builds a title property value.
+func TitleValue(text string) PropertyValue {
+	return PropertyValue{Title: []RichText{{Text: &Text{Content: text}}}}
+}
+
+// APIError is an error response from the Notion API.
+type APIError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("notion: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Client calls the Notion REST API. Tokens are passed per call because every
+// user connects their own integration.
+type Client struct {
+	// BaseURL overrides the API root, mainly for tests.
+	BaseURL string
+	http    *http.Client
+}
+
+// New returns a Client for the public Notion API.
+func New() *Client {
+	return &Client{
+		BaseURL: defaultBaseURL,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Database fetches the schema of a database the integration has access to.
+func (c *Client) Database(ctx context.Context, token, databaseID string) (*Database, error) {
+	var db Database
+	if err := c.do(ctx, token, http.MethodGet, "/databases/"+databaseID, nil, &db); err != nil {
+		return nil, err
+	}
+	return &db, nil
+}
+
+// CreatePage adds a row to a database.
+func (c *Client) CreatePage(ctx context.Context, token, databaseID string, properties map[string]PropertyValue) (*Page, error) {
+	payload := map[string]any{
+		"parent":     map[string]string{"database_id": databaseID},
+		"properties": properties,
+	}
+	var page Page
+	if err := c.do(ctx, token, http.MethodPost, "/pages", payload, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdatePage changes properties of an existing page.
+func (c *Client) UpdatePage(ctx context.Context, token, pageID string, properties map[string]PropertyValue) error {
+	return c.do(ctx, token, http.MethodPatch, "/pages/"+pageID, map[string]any{"properties": properties}, nil)
+}
+
+// ArchivePage moves a page to the trash.
+func (c *Client) ArchivePage(ctx context.Context, token, pageID string) error {
+	return c.do(ctx, token, http.MethodPatch, "/pages/"+pageID, map[string]any{"archived": true}, nil)
+}
+
+// QueryDatabase returns every page in a database, following pagination.
+func (c *Client) QueryDatabase(ctx context.Context, token, databaseID string) ([]Page, error) {
+	var pages []Page
+	cursor := ""
+	for {
+		payload := map[string]any{"page_size": 100}
+		if cursor != "" {
+			payload["start_cursor"] = cursor
+		}
+		var resp struct {
+			Results    []Page `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := c.do(ctx, token, http.MethodPost, "/databases/"+databaseID+"/query", payload, &resp); err != nil {
+			return nil, err
+		}
+		pages = append(pages, resp.Results...)
+		if !resp.HasMore || resp.NextCursor == "" {
+			return pages, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+func (c *Client) do(ctx context.Context, token, method, path string, payload, out any) error {
+	var body io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("notion: encode request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return fmt.Errorf("notion: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Notion-Version", apiVersion)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("notion: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{Status: resp.StatusCode}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(apiErr); err != nil {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		apiErr.Status = resp.StatusCode
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("notion: decode response: %w", err)
+	}
+	return nil
+}
+
+var databaseIDPattern = regexp.MustCompile(`(?i)([0-9a-f]{8})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{12})`)
+
+// ParseDatabaseID extracts a database ID from a raw ID or a Notion URL.
+func ParseDatabaseID(input string) (string, bool) {
+	// Notion URLs end with the ID, optionally followed by "?v=<view id>".
+	if i := strings.IndexAny(input, "?#"); i >= 0 {
+		input = input[:i]
+	}
+	matches := databaseIDPattern.FindAllStringSubmatch(input, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+	m := matches[len(matches)-1]
+	return strings.ToLower(strings.Join(m[1:], "-")), true
+}
@@ -2,12 +2,15 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/azure"
 	"github.com/openai/openai-go/v3/option"
 )
 
@@ -16,6 +19,8 @@ type Client struct {
 	apiKey string
 	client *openai.Client
 	model  openai.ChatModel
+
+	retries retryCounters
 }
 
 // ErrClientNotInitialised is returned when attempting to call the API without a configured client.
@@ -37,32 +42,200 @@ const (
 	IntentClearReminders Intent = "clear_reminders"
 	// IntentHelp asks for usage guidance.
 	IntentHelp Intent = "help"
+	// IntentConnect asks how to start chatting with the bot, such as how to
+	// join the Twilio sandbox from another phone.
+	IntentConnect Intent = "connect"
+	// IntentCompleteReminder marks a reminder as done. It only appears in
+	// actions returned by PlanActions.
+	IntentCompleteReminder Intent = "complete_reminder"
 )
 
+// Options points the client somewhere other than api.openai.com. The zero
+// value uses OpenAI directly.
+type Options struct {
+	// BaseURL replaces the OpenAI API URL, e.g. for a proxy. With
+	// AzureDeployment it is the Azure OpenAI resource endpoint, such as
+	// https://acme.openai.azure.com.
+	BaseURL string
+	// AzureDeployment is the Azure OpenAI deployment that serves chat
+	// completions. Setting it switches to Azure's URLs and api-key header.
+	AzureDeployment string
+	// APIVersion is the Azure OpenAI API version, e.g. 2024-10-21.
+	APIVersion string
+}
+
 // New returns an OpenAI client when apiKey is provided, otherwise nil is returned.
 func New(apiKey string) *Client {
+	return NewWithOptions(apiKey, Options{})
+}
+
+// NewWithOptions is New for a client configured by opts, such as one backed
+// by Azure OpenAI.
+func NewWithOptions(apiKey string, opts Options) *Client {
 	if apiKey == "" {
 		return &Client{}
 	}
-	client := openai.NewClient(option.WithAPIKey(apiKey))
+	// The SDK's own retries are off so withRetry alone decides, and counts.
+	requestOptions := []option.RequestOption{option.WithMaxRetries(0)}
+	model := openai.ChatModelGPT4oMini
+	switch {
+	case opts.AzureDeployment != "":
+		// Azure routes by deployment, which the SDK takes from the model.
+		requestOptions = append(requestOptions, azure.WithEndpoint(opts.BaseURL, opts.APIVersion), azure.WithAPIKey(apiKey))
+		model = opts.AzureDeployment
+	case opts.BaseURL != "":
+		requestOptions = append(requestOptions, option.WithBaseURL(opts.BaseURL), option.WithAPIKey(apiKey))
+	default:
+		requestOptions = append(requestOptions, option.WithAPIKey(apiKey))
+	}
+	client := openai.NewClient(requestOptions...)
 	return &Client{
 		apiKey: apiKey,
 		client: &client,
-		model:  openai.ChatModelGPT4oMini,
+		model:  model,
 	}
 }
 
-// SummarizeReminder asks the model to summarise the provided content.
-func (c *Client) SummarizeReminder(ctx context.Context, content string) (string, error) {
+// SummarizeReminder asks the model to summarise the provided content in the
+// named language. An empty language means English.
+func (c *Client) SummarizeReminder(ctx context.Context, content, language string) (string, error) {
 	if strings.TrimSpace(content) == "" {
 		return "", fmt.Errorf("content cannot be empty")
 	}
 	if c.client == nil {
 		// fallback: return truncated content when API key is missing.
-		if len(content) > 80 {
-			return content[:80] + "...", nil
+		return truncateSummary(content), nil
+	}
+
+	return c.complete(ctx, 15*time.Second, completionRequest{
+		System:      summarySystemPrompt(language),
+		User:        fmt.Sprintf("Summarise the following reminder in one sentence: %s", content),
+		Temperature: 0.3,
+		MaxTokens:   60,
+	})
+}
+
+// summaryBatchSize caps how many reminders go into one SummarizeReminders
+// prompt, keeping each reply well inside the completion token limit.
+const summaryBatchSize = 25
+
+// SummarizeReminders summarises each of contents in one short sentence,
+// like SummarizeReminder, but with one request per batch of reminders rather
+// than one per reminder. The summaries come back in the order of contents.
+func (c *Client) SummarizeReminders(ctx context.Context, contents []string, language string) ([]string, error) {
+	for _, content := range contents {
+		if strings.TrimSpace(content) == "" {
+			return nil, fmt.Errorf("content cannot be empty")
 		}
-		return content, nil
+	}
+	summaries := make([]string, 0, len(contents))
+	if c.client == nil {
+		for _, content := range contents {
+			summaries = append(summaries, truncateSummary(content))
+		}
+		return summaries, nil
+	}
+
+	for start := 0; start < len(contents); start += summaryBatchSize {
+		batch := contents[start:min(start+summaryBatchSize, len(contents))]
+		var numbered strings.Builder
+		for i, content := range batch {
+			fmt.Fprintf(&numbered, "%d. %s\n", i+1, strings.ReplaceAll(content, "\n", " "))
+		}
+		reply, err := c.complete(ctx, 30*time.Second, completionRequest{
+			System: summarySystemPrompt(language) + ` You will get a numbered list of reminders. ` +
+				`Reply with only a JSON array of strings holding one summary per reminder, in the same order.`,
+			User:        numbered.String(),
+			Temperature: 0.3,
+			MaxTokens:   int64(60 * len(batch)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		reply = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(reply, "```json"), "```"), "```")
+
+		var batchSummaries []string
+		if err := json.Unmarshal([]byte(reply), &batchSummaries); err != nil {
+			return nil, fmt.Errorf("parse summaries: %w", err)
+		}
+		if len(batchSummaries) != len(batch) {
+			return nil, fmt.Errorf("got %d summaries for %d reminders", len(batchSummaries), len(batch))
+		}
+		summaries = append(summaries, batchSummaries...)
+	}
+	return summaries, nil
+}
+
+// summarySystemPrompt is the system prompt for summaries in the named
+// language.
+func summarySystemPrompt(language string) string {
+	system := "You summarise reminder texts in one short sentence."
+	if language != "" && language != "English" {
+		system += " Write the summary in " + language + "."
+	}
+	return system
+}
+
+// truncateSummary stands in for a summary when there is no API key.
+func truncateSummary(content string) string {
+	if short := limitRunes(content, 80); short != content {
+		return short + "..."
+	}
+	return content
+}
+
+// limitRunes cuts s to at most max runes, so it never splits a UTF-8
+// character the way slicing bytes can.
+func limitRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// TitleMemo asks the model for a short title describing a free-form memo.
+func (c *Client) TitleMemo(ctx context.Context, content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("content cannot be empty")
+	}
+	if c.client == nil {
+		return fallbackTitle(content), nil
+	}
+
+	title, err := c.complete(ctx, 15*time.Second, completionRequest{
+		System:      "You write titles for personal notes. Reply with a title of at most six words and no quotes.",
+		User:        content,
+		Temperature: 0.3,
+		MaxTokens:   20,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(title, "\"' "), nil
+}
+
+// Classification is the model's reading of a message.
+type Classification struct {
+	Intent Intent
+	// Confidence is the probability the model gave its label, from 0 to 1.
+	Confidence float64
+	// Alternative is the runner-up label, or IntentUnknown when there was
+	// no close second.
+	Alternative Intent
+}
+
+var intentLabels = []Intent{IntentAddReminder, IntentListReminders, IntentDeleteReminder, IntentClearReminders, IntentHelp, IntentConnect, IntentUnknown}
+
+// ClassifyIntent uses the language model to infer the user's intent. The
+// confidence comes from the token log probabilities of the label.
+func (c *Client) ClassifyIntent(ctx context.Context, content string) (Classification, error) {
+	unknown := Classification{Intent: IntentUnknown, Alternative: IntentUnknown}
+	if strings.TrimSpace(content) == "" {
+		return unknown, fmt.Errorf("content cannot be empty")
+	}
+	if c.client == nil {
+		return unknown, ErrClientNotInitialised
 	}
 
 	req := openai.ChatCompletionNewParams{
@@ -71,90 +244,538 @@ func (c *Client) SummarizeReminder(ctx context.Context, content string) (string,
 			{
 				OfSystem: &openai.ChatCompletionSystemMessageParam{
 					Content: openai.ChatCompletionSystemMessageParamContentUnion{
-						OfString: openai.String("You summarise reminder texts in one short sentence."),
+						OfString: openai.String("Classify the user's request for a reminder bot. Reply with exactly one label: add_reminder, list_reminders, delete_reminder, clear_reminders, help, connect (how to set up or join the bot), or unknown."),
 					},
 				},
 			},
 			{
 				OfUser: &openai.ChatCompletionUserMessageParam{
 					Content: openai.ChatCompletionUserMessageParamContentUnion{
-						OfString: openai.String(fmt.Sprintf("Summarise the following reminder in one sentence: %s", content)),
+						OfString: openai.String(content),
 					},
 				},
 			},
 		},
-		Temperature:         openai.Float(0.3),
-		MaxCompletionTokens: openai.Int(60),
+		Temperature:         openai.Float(0.0),
+		MaxCompletionTokens: openai.Int(8),
+		Logprobs:            openai.Bool(true),
+		TopLogprobs:         openai.Int(3),
+	}
+
+	var resp *openai.ChatCompletion
+	err := c.withRetry(ctx, func() error {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		var err error
+		resp, err = c.client.Chat.Completions.New(ctx, req)
+		return err
+	})
+	if err != nil {
+		return unknown, err
+	}
+	if len(resp.Choices) == 0 {
+		return unknown, fmt.Errorf("no completion received")
+	}
+
+	choice := resp.Choices[0]
+	result := Classification{
+		Intent:      ParseIntent(choice.Message.Content),
+		Confidence:  1,
+		Alternative: IntentUnknown,
+	}
+	if tokens := choice.Logprobs.Content; len(tokens) > 0 {
+		var sum float64
+		for _, t := range tokens {
+			sum += t.Logprob
+		}
+		result.Confidence = math.Exp(sum)
+		// Labels differ from their first token, so the runner-up for the
+		// first token names the alternative.
+		for _, alt := range tokens[0].TopLogprobs {
+			if intent := intentForPrefix(alt.Token); intent != IntentUnknown && intent != result.Intent {
+				result.Alternative = intent
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// ParseIntent maps a label from a model to an Intent, or IntentUnknown.
+func ParseIntent(label string) Intent {
+	label = strings.ToLower(strings.TrimSpace(label))
+	for _, intent := range intentLabels {
+		if Intent(label) == intent {
+			return intent
+		}
+	}
+	return IntentUnknown
+}
+
+// intentForPrefix returns the label that starts with token, if exactly one
+// does.
+func intentForPrefix(token string) Intent {
+	token = strings.ToLower(strings.TrimSpace(token))
+	if token == "" {
+		return IntentUnknown
+	}
+	match := IntentUnknown
+	for _, intent := range intentLabels {
+		if strings.HasPrefix(string(intent), token) {
+			if match != IntentUnknown {
+				return IntentUnknown
+			}
+			match = intent
+		}
+	}
+	return match
+}
+
+// Action is one of the steps a compound message asks for.
+type Action struct {
+	Intent Intent `json:"intent"`
+	// Text is the reminder to save for IntentAddReminder, and otherwise the
+	// words or code (such as R3) naming an existing reminder.
+	Text string `json:"text"`
+	// Priority is the priority the user gave a new reminder, or 0.
+	Priority int `json:"priority,omitempty"`
+}
+
+// PlanActions splits a message such as "delete the rent reminder and remind
+// me to call the plumber" into the actions it asks for, in order.
+func (c *Client) PlanActions(ctx context.Context, content string) ([]Action, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("content cannot be empty")
+	}
+	if c.client == nil {
+		return nil, ErrClientNotInitialised
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
+	reply, err := c.complete(ctx, 15*time.Second, completionRequest{
+		System: `Split the user's message to a reminder bot into the actions it asks for, in order. ` +
+			`Reply with only a JSON array of objects with "intent" (add_reminder, delete_reminder, or complete_reminder), ` +
+			`"text" (for add_reminder, the reminder to save in the user's words; otherwise words or a code such as R3 identifying an existing reminder), ` +
+			`and "priority" (1 to 5, only if the user gave one).`,
+		User:        content,
+		Temperature: 0,
+		MaxTokens:   300,
+	})
+	if err != nil {
+		return nil, err
+	}
+	reply = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(reply, "```json"), "```"), "```")
+
+	var actions []Action
+	if err := json.Unmarshal([]byte(reply), &actions); err != nil {
+		return nil, fmt.Errorf("parse actions: %w", err)
+	}
+	for i, a := range actions {
+		switch a.Intent {
+		case IntentAddReminder, IntentDeleteReminder, IntentCompleteReminder:
+		default:
+			return nil, fmt.Errorf("action %d has unsupported intent %q", i+1, a.Intent)
+		}
+		if strings.TrimSpace(a.Text) == "" {
+			return nil, fmt.Errorf("action %d has no text", i+1)
+		}
+	}
+	return actions, nil
+}
+
+// Categorize asks the model which of categories fits a reminder best. It
+// returns an empty string when none does.
+func (c *Client) Categorize(ctx context.Context, content string, categories []string) (string, error) {
+	if strings.TrimSpace(content) == "" || len(categories) == 0 {
+		return "", fmt.Errorf("content and categories cannot be empty")
+	}
+	if c.client == nil {
+		return "", ErrClientNotInitialised
+	}
 
-	resp, err := c.client.Chat.Completions.New(ctx, req)
+	label, err := c.complete(ctx, 10*time.Second, completionRequest{
+		System:      fmt.Sprintf("You sort reminders into categories. Reply with exactly one of: %s, or none.", strings.Join(categories, ", ")),
+		User:        content,
+		Temperature: 0,
+		MaxTokens:   5,
+	})
 	if err != nil {
 		return "", err
 	}
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no completion received")
+	label = strings.ToLower(strings.Trim(label, "\"'. "))
+	for _, category := range categories {
+		if label == category {
+			return category, nil
+		}
 	}
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	return "", nil
 }
 
-// ClassifyIntent uses the language model to infer the user's intent.
-func (c *Client) ClassifyIntent(ctx context.Context, content string) (Intent, error) {
+// SuggestReschedule asks the model for a realistic new time for a reminder
+// that went overdue. now gives the current time and time zone; the result
+// is always after it.
+func (c *Client) SuggestReschedule(ctx context.Context, content string, due, now time.Time) (time.Time, error) {
 	if strings.TrimSpace(content) == "" {
-		return IntentUnknown, fmt.Errorf("content cannot be empty")
+		return time.Time{}, fmt.Errorf("content cannot be empty")
 	}
 	if c.client == nil {
-		return IntentUnknown, ErrClientNotInitialised
+		return time.Time{}, ErrClientNotInitialised
 	}
 
+	reply, err := c.complete(ctx, 10*time.Second, completionRequest{
+		System: "You help reschedule overdue reminders. Suggest a realistic new time within the next week that suits the task, " +
+			"such as errands at the weekend or calls during office hours. Reply with only the time as YYYY-MM-DD HH:MM.",
+		User:        fmt.Sprintf("Reminder: %s\nWas due: %s\nNow: %s", content, due.In(now.Location()).Format("Monday 2006-01-02"), now.Format("Monday 2006-01-02 15:04")),
+		Temperature: 0.3,
+		MaxTokens:   20,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	suggested, err := time.ParseInLocation("2006-01-02 15:04", strings.TrimSpace(reply), now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse suggested time %q: %w", reply, err)
+	}
+	if !suggested.After(now) {
+		return time.Time{}, fmt.Errorf("suggested time %s is not in the future", suggested.Format("2006-01-02 15:04"))
+	}
+	return suggested, nil
+}
+
+// Moderate reports whether the moderation endpoint flags text as abusive or
+// unsafe.
+func (c *Client) Moderate(ctx context.Context, text string) (bool, error) {
+	if strings.TrimSpace(text) == "" {
+		return false, nil
+	}
+	if c.client == nil {
+		return false, ErrClientNotInitialised
+	}
+
+	var resp *openai.ModerationNewResponse
+	err := c.withRetry(ctx, func() error {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		var err error
+		resp, err = c.client.Moderations.New(ctx, openai.ModerationNewParams{
+			Input: openai.ModerationNewParamsInputUnion{OfString: openai.String(text)},
+			Model: openai.ModerationModelOmniModerationLatest,
+		})
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, result := range resp.Results {
+		if result.Flagged {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EmbeddingModel is the model behind Embed. Vectors from different models
+// can't be compared, so callers store it alongside each vector.
+const EmbeddingModel = openai.EmbeddingModelTextEmbedding3Small
+
+// Embed returns an embedding for each of texts, in order, from one request.
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if c.client == nil {
+		return nil, ErrClientNotInitialised
+	}
+
+	var resp *openai.CreateEmbeddingResponse
+	err := c.withRetry(ctx, func() error {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		var err error
+		resp, err = c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+			Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+			Model: EmbeddingModel,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || int(data.Index) >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		vector := make([]float32, len(data.Embedding))
+		for i, f := range data.Embedding {
+			vector[i] = float32(f)
+		}
+		vectors[data.Index] = vector
+	}
+	return vectors, nil
+}
+
+// Turn is one earlier message from the user and the bot's reply to it.
+type Turn struct {
+	Message string
+	Reply   string
+}
+
+// ResolveFollowUp rewrites a message that refers back to the conversation,
+// such as "actually make that priority 5", into a standalone command using
+// the recent turns, oldest first. A message that stands on its own comes back
+// unchanged.
+func (c *Client) ResolveFollowUp(ctx context.Context, history []Turn, message string) (string, error) {
+	if strings.TrimSpace(message) == "" {
+		return "", fmt.Errorf("message cannot be empty")
+	}
+	if c.client == nil {
+		return "", ErrClientNotInitialised
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Conversation:\n")
+	for _, t := range history {
+		fmt.Fprintf(&sb, "User: %s\nBot: %s\n", t.Message, t.Reply)
+	}
+	fmt.Fprintf(&sb, "\nNew message: %s", message)
+
+	return c.complete(ctx, 10*time.Second, completionRequest{
+		System: "You help a WhatsApp reminder bot understand follow-up messages. Rewrite the user's new message as one standalone command, " +
+			"naming reminders by their code (such as R3), their list number, or a few words of their text, as shown in the conversation. " +
+			"Commands include \"set R3 priority 5\", \"delete R3\", \"done R3\", \"show R3\", and \"remind me to ...\". " +
+			"Reply with the command only. If the message doesn't refer back to the conversation, reply with it unchanged.",
+		User:        sb.String(),
+		Temperature: 0,
+		MaxTokens:   60,
+	})
+}
+
+// SummarizeLink asks the model to summarise a fetched web page for a read-later list.
+func (c *Client) SummarizeLink(ctx context.Context, title, text string) (string, error) {
+	if strings.TrimSpace(title) == "" && strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("content cannot be empty")
+	}
+	if c.client == nil {
+		if short := limitRunes(text, 200); short != text {
+			return short + "...", nil
+		}
+		return text, nil
+	}
+
+	return c.complete(ctx, 20*time.Second, completionRequest{
+		System:      "You summarise web pages for a read-later list in two short sentences. Focus on what the reader will learn.",
+		User:        fmt.Sprintf("Title: %s\n\n%s", title, text),
+		Temperature: 0.3,
+		MaxTokens:   120,
+	})
+}
+
+// SuggestReview asks the model which long-untouched reminders to drop or
+// reschedule. Each entry of stale describes one reminder, such as
+// "R3: Renew passport (saved 30 days ago)".
+func (c *Client) SuggestReview(ctx context.Context, stale []string) (string, error) {
+	if len(stale) == 0 {
+		return "", fmt.Errorf("no reminders to review")
+	}
+	if c.client == nil {
+		return "", ErrClientNotInitialised
+	}
+
+	return c.complete(ctx, 20*time.Second, completionRequest{
+		System:      "You help someone tidy their to-do list. Given reminders they haven't touched in weeks, suggest in at most three short sentences which to drop and which to reschedule. Refer to reminders by their code, e.g. R3.",
+		User:        strings.Join(stale, "\n"),
+		Temperature: 0.4,
+		MaxTokens:   150,
+	})
+}
+
+// PlanDay asks the model for one sentence on where to start the day. Each
+// entry of reminders describes one due today or overdue, such as "R3: Call
+// the bank (overdue, open 5 days, priority 4)".
+func (c *Client) PlanDay(ctx context.Context, reminders []string) (string, error) {
+	if len(reminders) == 0 {
+		return "", fmt.Errorf("no reminders to plan")
+	}
+	if c.client == nil {
+		return "", ErrClientNotInitialised
+	}
+
+	return c.complete(ctx, 20*time.Second, completionRequest{
+		System:      "You help someone plan their morning. Given what is due today or overdue on their to-do list, write one short, friendly sentence on what to start with and why, such as \"Start with the bank call, it's been pending 5 days.\" Refer to reminders by name, not code.",
+		User:        strings.Join(reminders, "\n"),
+		Temperature: 0.4,
+		MaxTokens:   60,
+	})
+}
+
+// PriorityChange is a new priority proposed for one reminder.
+type PriorityChange struct {
+	// Code names the reminder, e.g. R3.
+	Code     string `json:"code"`
+	Priority int    `json:"priority"`
+	Reason   string `json:"reason"`
+}
+
+// RebalancePriorities asks the model to spread out the priorities of open
+// reminders. Each entry of reminders describes one, such as "R3 (priority 5,
+// due Fri Mar 6): Renew passport". Only reminders whose priority should
+// change are returned.
+func (c *Client) RebalancePriorities(ctx context.Context, reminders []string) ([]PriorityChange, error) {
+	if len(reminders) == 0 {
+		return nil, fmt.Errorf("no reminders to rebalance")
+	}
+	if c.client == nil {
+		return nil, ErrClientNotInitialised
+	}
+
+	reply, err := c.complete(ctx, 20*time.Second, completionRequest{
+		System: `You help someone whose to-do list has too many top priorities. Priorities run from 1 (can wait) to 5 (urgent). ` +
+			`Given their open reminders, propose new priorities so only the truly urgent ones stay high, taking due dates into account. ` +
+			`Reply with only a JSON array of objects with "code" (e.g. R3), "priority" (1 to 5), and "reason" (a few words), ` +
+			`listing only reminders whose priority should change.`,
+		User:        strings.Join(reminders, "\n"),
+		Temperature: 0.2,
+		MaxTokens:   600,
+	})
+	if err != nil {
+		return nil, err
+	}
+	reply = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(reply, "```json"), "```"), "```")
+
+	var changes []PriorityChange
+	if err := json.Unmarshal([]byte(reply), &changes); err != nil {
+		return nil, fmt.Errorf("parse priorities: %w", err)
+	}
+	return changes, nil
+}
+
+// ParseRecurrence reads how often a reminder repeats and returns it as an
+// iCalendar RRULE, e.g. "FREQ=MONTHLY;BYDAY=1MO", or "" when it doesn't
+// repeat.
+func (c *Client) ParseRecurrence(ctx context.Context, content string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("no reminder to read")
+	}
+	if c.client == nil {
+		return "", ErrClientNotInitialised
+	}
+
+	rule, err := c.complete(ctx, 10*time.Second, completionRequest{
+		System:      "You read how often a reminder repeats. Reply with an iCalendar RRULE using only FREQ (DAILY, WEEKLY, MONTHLY, or YEARLY), INTERVAL, BYDAY, and BYMONTHDAY, without the RRULE: prefix. For example \"every 3 days\" is FREQ=DAILY;INTERVAL=3 and \"first Monday of the month\" is FREQ=MONTHLY;BYDAY=1MO. Reply NONE if it doesn't repeat.",
+		User:        content,
+		Temperature: 0,
+		MaxTokens:   30,
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(rule, "NONE") {
+		return "", nil
+	}
+	return rule, nil
+}
+
+// ResolveTimezone names the IANA timezone, e.g. "Europe/London", of a place
+// given by name or as "latitude,longitude", or "" when it can't tell.
+func (c *Client) ResolveTimezone(ctx context.Context, place string) (string, error) {
+	if strings.TrimSpace(place) == "" {
+		return "", fmt.Errorf("no place to look up")
+	}
+	if c.client == nil {
+		return "", ErrClientNotInitialised
+	}
+
+	zone, err := c.complete(ctx, 10*time.Second, completionRequest{
+		System:      "You name the IANA timezone of a place, given as a name or as latitude,longitude. Reply with the timezone only, e.g. Europe/London or America/New_York. Reply NONE if it isn't a real place.",
+		User:        place,
+		Temperature: 0,
+		MaxTokens:   20,
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(zone, "NONE") {
+		return "", nil
+	}
+	return zone, nil
+}
+
+// NarrateInsights turns facts about how someone deals with their reminders,
+// one per line, into a short encouraging reading of them.
+func (c *Client) NarrateInsights(ctx context.Context, facts []string) (string, error) {
+	if len(facts) == 0 {
+		return "", fmt.Errorf("no insights to narrate")
+	}
+	if c.client == nil {
+		return "", ErrClientNotInitialised
+	}
+
+	return c.complete(ctx, 20*time.Second, completionRequest{
+		System:      "You coach someone on getting through their to-do list. Given facts about how they complete and put off reminders, write at most three short, friendly sentences on one pattern you notice and one thing to try. Refer to reminders by their code, e.g. R3. Don't repeat the numbers back.",
+		User:        strings.Join(facts, "\n"),
+		Temperature: 0.5,
+		MaxTokens:   150,
+	})
+}
+
+// completionRequest describes a single system+user chat completion.
+type completionRequest struct {
+	System      string
+	User        string
+	Temperature float64
+	MaxTokens   int64
+}
+
+// complete runs a chat completion and returns the trimmed text of the first
+// choice. timeout applies to each attempt.
+func (c *Client) complete(ctx context.Context, timeout time.Duration, r completionRequest) (string, error) {
 	req := openai.ChatCompletionNewParams{
 		Model: c.model,
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			{
 				OfSystem: &openai.ChatCompletionSystemMessageParam{
 					Content: openai.ChatCompletionSystemMessageParamContentUnion{
-						OfString: openai.String("Classify the user's request for a reminder bot. Reply with exactly one label: add_reminder, list_reminders, delete_reminder, clear_reminders, help, or unknown."),
+						OfString: openai.String(r.System),
 					},
 				},
 			},
 			{
 				OfUser: &openai.ChatCompletionUserMessageParam{
 					Content: openai.ChatCompletionUserMessageParamContentUnion{
-						OfString: openai.String(content),
+						OfString: openai.String(r.User),
 					},
 				},
 			},
 		},
-		Temperature:         openai.Float(0.0),
-		MaxCompletionTokens: openai.Int(8),
+		Temperature:         openai.Float(r.Temperature),
+		MaxCompletionTokens: openai.Int(r.MaxTokens),
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	resp, err := c.client.Chat.Completions.New(ctx, req)
+	var resp *openai.ChatCompletion
+	err := c.withRetry(ctx, func() error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		var err error
+		resp, err = c.client.Chat.Completions.New(ctx, req)
+		return err
+	})
 	if err != nil {
-		return IntentUnknown, err
+		return "", err
 	}
 	if len(resp.Choices) == 0 {
-		return IntentUnknown, fmt.Errorf("no completion received")
-	}
-
-	label := strings.TrimSpace(resp.Choices[0].Message.Content)
-	switch Intent(strings.ToLower(label)) {
-	case IntentAddReminder:
-		return IntentAddReminder, nil
-	case IntentListReminders:
-		return IntentListReminders, nil
-	case IntentDeleteReminder:
-		return IntentDeleteReminder, nil
-	case IntentClearReminders:
-		return IntentClearReminders, nil
-	case IntentHelp:
-		return IntentHelp, nil
-	default:
-		return IntentUnknown, nil
+		return "", fmt.Errorf("no completion received")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+// fallbackTitle builds a title from the first few words when no model is available.
+func fallbackTitle(content string) string {
+	words := strings.Fields(content)
+	if len(words) > 6 {
+		return strings.Join(words[:6], " ") + "..."
 	}
+	return strings.Join(words, " ")
 }
@@ -0,0 +1,98 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	openai "github.com/openai/openai-go/v3"
+)
+
+// Requests that fail with a rate limit (429) or a server error (5xx) are
+// retried up to maxRetries times. The delay before retry n is drawn at
+// random from [0, retryBaseDelay*2^n), capped at retryMaxDelay, so callers
+// that failed together do not retry together.
+const (
+	maxRetries     = 3
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+)
+
+// RetryStats counts OpenAI requests and their retries since the client was
+// created.
+type RetryStats struct {
+	// Requests is the number of calls made, not counting retries.
+	Requests int64 `json:"requests"`
+	// Retries is the number of extra attempts after a transient failure.
+	Retries int64 `json:"retries"`
+	// Recovered is the number of calls that succeeded after a retry.
+	Recovered int64 `json:"recovered"`
+	// Exhausted is the number of calls that still failed after the last
+	// retry.
+	Exhausted int64 `json:"exhausted"`
+}
+
+type retryCounters struct {
+	requests, retries, recovered, exhausted atomic.Int64
+}
+
+// RetryStats returns the client's retry counters.
+func (c *Client) RetryStats() RetryStats {
+	return RetryStats{
+		Requests:  c.retries.requests.Load(),
+		Retries:   c.retries.retries.Load(),
+		Recovered: c.retries.recovered.Load(),
+		Exhausted: c.retries.exhausted.Load(),
+	}
+}
+
+// withRetry calls call, retrying it with backoff while it fails with a
+// transient error. It gives up early, returning the last error, when ctx is
+// done.
+func (c *Client) withRetry(ctx context.Context, call func() error) error {
+	c.retries.requests.Add(1)
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil {
+			if attempt > 0 {
+				c.retries.recovered.Add(1)
+			}
+			return nil
+		}
+		if !retryable(err) {
+			return err
+		}
+		if attempt == maxRetries {
+			c.retries.exhausted.Add(1)
+			return err
+		}
+
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		c.retries.retries.Add(1)
+	}
+}
+
+// retryable reports whether err is a rate limit or server error worth
+// trying again.
+func retryable(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+}
+
+// backoff returns a random delay before retry attempt+1.
+func backoff(attempt int) time.Duration {
+	ceiling := min(retryBaseDelay<<attempt, retryMaxDelay)
+	return rand.N(ceiling)
+}
@@ -0,0 +1,353 @@
+// Package recurrence parses, describes, and steps through repeat rules for
+// reminders, such as "every 3 days" or "the first Monday of every month".
+//
+// Rules are stored in the iCalendar RRULE syntax (RFC 5545), restricted to
+// the parts a reminder needs: FREQ, INTERVAL, BYDAY, and BYMONTHDAY.
+// Occurrences fall on whole days; the time of day is left to the scheduler.
+package recurrence
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// searchDays bounds how far ahead Next looks for an occurrence.
+const searchDays = 10 * 366
+
+// Weekday is a BYDAY entry: a day of the week, and for monthly rules an
+// optional position in the month such as 1 (first) or -1 (last).
+type Weekday struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed repeat rule.
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []Weekday
+	ByMonthDay []int
+}
+
+var dayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var byDayPattern = regexp.MustCompile(`^([+-]?[1-5])?(SU|MO|TU|WE|TH|FR|SA)$`)
+
+// Parse reads an RRULE such as "FREQ=MONTHLY;BYDAY=1MO", with or without the
+// "RRULE:" prefix.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("recurrence: malformed part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = value
+			default:
+				return Rule{}, fmt.Errorf("recurrence: unsupported frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 365 {
+				return Rule{}, fmt.Errorf("recurrence: invalid interval %q", value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				m := byDayPattern.FindStringSubmatch(code)
+				if m == nil {
+					return Rule{}, fmt.Errorf("recurrence: invalid day %q", code)
+				}
+				day := Weekday{Day: dayCodes[m[2]]}
+				if m[1] != "" {
+					day.N, _ = strconv.Atoi(m[1])
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return Rule{}, fmt.Errorf("recurrence: invalid month day %q", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return Rule{}, fmt.Errorf("recurrence: unsupported part %q", key)
+		}
+	}
+	if rule.Freq == "" {
+		return Rule{}, fmt.Errorf("recurrence: FREQ is required")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly && rule.Freq != Monthly {
+		return Rule{}, fmt.Errorf("recurrence: BYDAY needs a weekly or monthly rule")
+	}
+	if len(rule.ByMonthDay) > 0 && (rule.Freq != Monthly || len(rule.ByDay) > 0) {
+		return Rule{}, fmt.Errorf("recurrence: BYMONTHDAY needs a monthly rule without BYDAY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly {
+			return Rule{}, fmt.Errorf("recurrence: numbered days need a monthly rule")
+		}
+	}
+	return rule, nil
+}
+
+// String returns the rule in canonical RRULE form.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = strings.ToUpper(day.Day.String()[:2])
+			if day.N != 0 {
+				codes[i] = strconv.Itoa(day.N) + codes[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, n := range r.ByMonthDay {
+			days[i] = strconv.Itoa(n)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence on a day after after's, counting the
+// rule from start, which should itself be an occurrence. It keeps start's
+// time of day and location, and returns the zero time when there is none
+// within ten years.
+func (r Rule) Next(start, after time.Time) time.Time {
+	after = after.In(start.Location())
+	day := civil(start)
+	if a := civil(after).AddDate(0, 0, 1); a.After(day) {
+		day = a
+	}
+	for range searchDays {
+		if r.matches(civil(start), day) {
+			y, m, d := day.Date()
+			return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// civil returns t's calendar date at midnight UTC, so day arithmetic isn't
+// thrown off by daylight saving.
+func civil(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// matches reports whether day, a civil date on or after start, is an
+// occurrence.
+func (r Rule) matches(start, day time.Time) bool {
+	switch r.Freq {
+	case Daily:
+		return daysBetween(start, day)%r.Interval == 0
+	case Weekly:
+		// Weeks start on Monday, as RRULE's default WKST does.
+		weeks := daysBetween(mondayOf(start), mondayOf(day)) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return slices.ContainsFunc(r.ByDay, func(w Weekday) bool { return w.Day == day.Weekday() })
+	case Monthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		switch {
+		case len(r.ByDay) > 0:
+			return slices.ContainsFunc(r.ByDay, func(w Weekday) bool { return w.Day == day.Weekday() && matchesPosition(w.N, day) })
+		case len(r.ByMonthDay) > 0:
+			return slices.ContainsFunc(r.ByMonthDay, func(n int) bool { return matchesMonthDay(n, day) })
+		}
+		return day.Day() == start.Day()
+	case Yearly:
+		return (day.Year()-start.Year())%r.Interval == 0 && day.Month() == start.Month() && day.Day() == start.Day()
+	}
+	return false
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func mondayOf(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func daysInMonth(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// matchesPosition reports whether day is the nth of its weekday in its
+// month, counting from the end when n is negative. Zero matches every one.
+func matchesPosition(n int, day time.Time) bool {
+	switch {
+	case n > 0:
+		return (day.Day()-1)/7+1 == n
+	case n < 0:
+		return (daysInMonth(day)-day.Day())/7+1 == -n
+	}
+	return true
+}
+
+// matchesMonthDay reports whether day is day n of its month, counting from
+// the end when n is negative.
+func matchesMonthDay(n int, day time.Time) bool {
+	if n < 0 {
+		n = daysInMonth(day) + n + 1
+	}
+	return day.Day() == n
+}
+
+var ordinals = map[int]string{1: "first", 2: "second", 3: "third", 4: "fourth", 5: "fifth", -1: "last"}
+
+// Describe renders the rule in words, e.g. "every 3 days" or "on the first
+// Monday of every month".
+func (r Rule) Describe() string {
+	unit := map[string]string{Daily: "day", Weekly: "week", Monthly: "month", Yearly: "year"}[r.Freq]
+	every := "every " + unit
+	switch {
+	case r.Interval == 2:
+		every = "every other " + unit
+	case r.Interval > 2:
+		every = fmt.Sprintf("every %d %ss", r.Interval, unit)
+	}
+
+	switch {
+	case len(r.ByDay) > 0 && r.Freq == Weekly:
+		if r.Interval == 1 {
+			return "every " + joinDays(r.ByDay)
+		}
+		return every + " on " + joinDays(r.ByDay)
+	case len(r.ByDay) > 0:
+		names := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			names[i] = day.Day.String()
+			if ordinal, ok := ordinals[day.N]; ok {
+				names[i] = ordinal + " " + names[i]
+			} else if day.N != 0 {
+				names[i] = fmt.Sprintf("%dth %s", day.N, names[i])
+			}
+		}
+		return fmt.Sprintf("on the %s of %s", strings.Join(names, " and "), every)
+	case len(r.ByMonthDay) > 0:
+		days := make([]string, len(r.ByMonthDay))
+		for i, n := range r.ByMonthDay {
+			days[i] = monthDayName(n)
+		}
+		return fmt.Sprintf("on the %s of %s", strings.Join(days, " and "), every)
+	}
+	return every
+}
+
+func joinDays(days []Weekday) string {
+	names := make([]string, len(days))
+	for i, day := range days {
+		names[i] = day.Day.String()
+	}
+	return strings.Join(names, " and ")
+}
+
+func monthDayName(n int) string {
+	switch {
+	case n == -1:
+		return "last day"
+	case n < 0:
+		return fmt.Sprintf("%s last day", ordinalSuffix(-n))
+	}
+	return ordinalSuffix(n)
+}
+
+func ordinalSuffix(n int) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+		return fmt.Sprintf("%dth", n)
+	case n%10 == 1:
+		return fmt.Sprintf("%dst", n)
+	case n%10 == 2:
+		return fmt.Sprintf("%dnd", n)
+	case n%10 == 3:
+		return fmt.Sprintf("%drd", n)
+	}
+	return fmt.Sprintf("%dth", n)
+}
+
+var (
+	everyIntervalPattern = regexp.MustCompile(`(?i)\b(?:every|each)\s+(\d{1,3}|other|second)\s+(day|week|month|year)s?\b`)
+	everyUnitPattern     = regexp.MustCompile(`(?i)\b(?:every|each)\s+(day|morning|afternoon|evening|night|week|month|year)\b`)
+	everyWeekdayPattern  = regexp.MustCompile(`(?i)\b(?:every|each)\s+(other\s+)?(monday|tuesday|wednesday|thursday|friday|saturday|sunday)s?\b`)
+)
+
+// FromText recognises the plainest repeat phrases, such as "every day",
+// "every 3 weeks", and "every other Friday", for when no language model is
+// available to read the rest. It reports false when it finds none.
+func FromText(text string) (Rule, bool) {
+	if m := everyIntervalPattern.FindStringSubmatch(text); m != nil {
+		n := 2
+		if v, err := strconv.Atoi(m[1]); err == nil {
+			n = v
+		}
+		if n < 1 || n > 365 {
+			return Rule{}, false
+		}
+		return Rule{Freq: unitFreq(m[2]), Interval: n}, true
+	}
+	if m := everyWeekdayPattern.FindStringSubmatch(text); m != nil {
+		interval := 1
+		if m[1] != "" {
+			interval = 2
+		}
+		code := strings.ToUpper(m[2][:2])
+		return Rule{Freq: Weekly, Interval: interval, ByDay: []Weekday{{Day: dayCodes[code]}}}, true
+	}
+	if m := everyUnitPattern.FindStringSubmatch(text); m != nil {
+		return Rule{Freq: unitFreq(m[1]), Interval: 1}, true
+	}
+	return Rule{}, false
+}
+
+func unitFreq(unit string) string {
+	switch strings.ToLower(unit) {
+	case "week":
+		return Weekly
+	case "month":
+		return Monthly
+	case "year":
+		return Yearly
+	}
+	return Daily
+}
@@ -0,0 +1,41 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceRules(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC) // a Wednesday
+	for _, tc := range []struct {
+		rule, describe string
+		after          time.Time
+		want           string
+	}{
+		{"FREQ=DAILY;INTERVAL=3", "every 3 days", start, "2026-03-07"},
+		{"RRULE:freq=weekly;interval=2;byday=FR", "every other week on Friday", start.AddDate(0, 0, 3), "2026-03-20"},
+		{"FREQ=MONTHLY;BYDAY=1MO", "on the first Monday of every month", start, "2026-04-06"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "on the last Friday of every month", start, "2026-03-27"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "on the last day of every month", start.AddDate(0, 0, 30), "2026-04-30"},
+	} {
+		rule, err := Parse(tc.rule)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.rule, err)
+		}
+		if got := rule.Describe(); got != tc.describe {
+			t.Fatalf("%s describes as %q, want %q", rule, got, tc.describe)
+		}
+		if got := rule.Next(start, tc.after).Format(time.DateOnly); got != tc.want {
+			t.Fatalf("%s next after %s = %s, want %s", rule, tc.after.Format(time.DateOnly), got, tc.want)
+		}
+	}
+	for _, bad := range []string{"FREQ=HOURLY", "FREQ=DAILY;COUNT=3", "FREQ=WEEKLY;BYDAY=1MO", "INTERVAL=2"} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+	if rule, ok := FromText("put the bins out every other Tuesday"); !ok || rule.String() != "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU" {
+		t.Fatalf("unexpected rule from text %v %v", rule, ok)
+	}
+}
@@ -0,0 +1,5 @@
+// Package reminderpb holds the protobuf messages and gRPC service definition
+// for the reminders API.
+package reminderpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative reminders.proto
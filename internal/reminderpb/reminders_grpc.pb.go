@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: reminders.proto
+
+package reminderpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Reminders_CreateReminder_FullMethodName = "/mymemo.reminders.v1.Reminders/CreateReminder"
+	Reminders_ListReminders_FullMethodName  = "/mymemo.reminders.v1.Reminders/ListReminders"
+	Reminders_DeleteReminder_FullMethodName = "/mymemo.reminders.v1.Reminders/DeleteReminder"
+	Reminders_WatchEvents_FullMethodName    = "/mymemo.reminders.v1.Reminders/WatchEvents"
+)
+
+// RemindersClient is the client API for Reminders service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Reminders gives other services access to users' reminders without going
+// through WhatsApp. Calls must carry the admin token as
+// "authorization: Bearer <token>" metadata.
+type RemindersClient interface {
+	// CreateReminder adds a reminder for a user.
+	CreateReminder(ctx context.Context, in *CreateReminderRequest, opts ...grpc.CallOption) (*Reminder, error)
+	// ListReminders returns a user's open reminders, highest priority first.
+	ListReminders(ctx context.Context, in *ListRemindersRequest, opts ...grpc.CallOption) (*ListRemindersResponse, error)
+	// DeleteReminder removes one of a user's reminders.
+	DeleteReminder(ctx context.Context, in *DeleteReminderRequest, opts ...grpc.CallOption) (*DeleteReminderResponse, error)
+	// WatchEvents streams reminder lifecycle events as they happen.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type remindersClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRemindersClient(cc grpc.ClientConnInterface) RemindersClient {
+	return &remindersClient{cc}
+}
+
+func (c *remindersClient) CreateReminder(ctx context.Context, in *CreateReminderRequest, opts ...grpc.CallOption) (*Reminder, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Reminder)
+	err := c.cc.Invoke(ctx, Reminders_CreateReminder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remindersClient) ListReminders(ctx context.Context, in *ListRemindersRequest, opts ...grpc.CallOption) (*ListRemindersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRemindersResponse)
+	err := c.cc.Invoke(ctx, Reminders_ListReminders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remindersClient) DeleteReminder(ctx context.Context, in *DeleteReminderRequest, opts ...grpc.CallOption) (*DeleteReminderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteReminderResponse)
+	err := c.cc.Invoke(ctx, Reminders_DeleteReminder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *remindersClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Reminders_ServiceDesc.Streams[0], Reminders_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Reminders_WatchEventsClient = grpc.ServerStreamingClient[Event]
+
+// RemindersServer is the server API for Reminders service.
+// All implementations must embed UnimplementedRemindersServer
+// for forward compatibility.
+//
+// Reminders gives other services access to users' reminders without going
+// through WhatsApp. Calls must carry the admin token as
+// "authorization: Bearer <token>" metadata.
+type RemindersServer interface {
+	// CreateReminder adds a reminder for a user.
+	CreateReminder(context.Context, *CreateReminderRequest) (*Reminder, error)
+	// ListReminders returns a user's open reminders, highest priority first.
+	ListReminders(context.Context, *ListRemindersRequest) (*ListRemindersResponse, error)
+	// DeleteReminder removes one of a user's reminders.
+	DeleteReminder(context.Context, *DeleteReminderRequest) (*DeleteReminderResponse, error)
+	// WatchEvents streams reminder lifecycle events as they happen.
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedRemindersServer()
+}
+
+// UnimplementedRemindersServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRemindersServer struct{}
+
+func (UnimplementedRemindersServer) CreateReminder(context.Context, *CreateReminderRequest) (*Reminder, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateReminder not implemented")
+}
+func (UnimplementedRemindersServer) ListReminders(context.Context, *ListRemindersRequest) (*ListRemindersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReminders not implemented")
+}
+func (UnimplementedRemindersServer) DeleteReminder(context.Context, *DeleteReminderRequest) (*DeleteReminderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteReminder not implemented")
+}
+func (UnimplementedRemindersServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedRemindersServer) mustEmbedUnimplementedRemindersServer() {}
+func (UnimplementedRemindersServer) testEmbeddedByValue()                   {}
+
+// UnsafeRemindersServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RemindersServer will
+// result in compilation errors.
+type UnsafeRemindersServer interface {
+	mustEmbedUnimplementedRemindersServer()
+}
+
+func RegisterRemindersServer(s grpc.ServiceRegistrar, srv RemindersServer) {
+	// If the following call pancis, it indicates UnimplementedRemindersServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Reminders_ServiceDesc, srv)
+}
+
+func _Reminders_CreateReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateReminderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemindersServer).CreateReminder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reminders_CreateReminder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemindersServer).CreateReminder(ctx, req.(*CreateReminderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reminders_ListReminders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRemindersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemindersServer).ListReminders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reminders_ListReminders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemindersServer).ListReminders(ctx, req.(*ListRemindersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reminders_DeleteReminder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteReminderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RemindersServer).DeleteReminder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Reminders_DeleteReminder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RemindersServer).DeleteReminder(ctx, req.(*DeleteReminderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Reminders_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RemindersServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Reminders_WatchEventsServer = grpc.ServerStreamingServer[Event]
+
+// Reminders_ServiceDesc is the grpc.ServiceDesc for Reminders service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Reminders_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mymemo.reminders.v1.Reminders",
+	HandlerType: (*RemindersServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateReminder",
+			Handler:    _Reminders_CreateReminder_Handler,
+		},
+		{
+			MethodName: "ListReminders",
+			Handler:    _Reminders_ListReminders_Handler,
+		},
+		{
+			MethodName: "DeleteReminder",
+			Handler:    _Reminders_DeleteReminder_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Reminders_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "reminders.proto",
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inlineTypes are the media types Handler lets a browser show in place. The
+// files come from users and are served from the dashboard's origin, so
+// anything else, such as HTML or SVG that could run script there, is sent
+// as a download in a sandbox.
+var inlineTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// Disk keeps blobs as files under a directory. Its links point at Handler,
+// which checks their signature and expiry before serving the file.
+type Disk struct {
+	dir     string
+	baseURL string
+	key     []byte
+}
+
+// NewDisk returns a Disk rooted at dir whose links start with baseURL and
+// are signed with signingKey.
+func NewDisk(dir, baseURL string, signingKey []byte) *Disk {
+	return &Disk{dir: dir, baseURL: strings.TrimRight(baseURL, "/"), key: signingKey}
+}
+
+func (d *Disk) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes data to key, replacing any blob already there.
+func (d *Disk) Put(_ context.Context, key string, data []byte, _ string) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a reader never sees half a blob.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get reads the blob at key.
+func (d *Disk) Get(_ context.Context, key string) ([]byte, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Delete removes the blob at key. Deleting a missing blob is not an error.
+func (d *Disk) Delete(_ context.Context, key string) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL returns a link to key under the base URL that Handler accepts
+// until ttl has passed.
+func (d *Disk) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if d.baseURL == "" {
+		return "", errors.New("storage: links need a base URL")
+	}
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {d.sign(key, expires)}}
+	return d.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+func (d *Disk) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, d.key)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler serves blobs to holders of a valid link. Mount it with the base
+// URL's path stripped, e.g. http.StripPrefix("/files", disk.Handler()).
+func (d *Disk) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		expires := r.URL.Query().Get("expires")
+		unix, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || !validKey(key) || time.Now().Unix() > unix ||
+			!hmac.Equal([]byte(d.sign(key, expires)), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "this link is invalid or has expired", http.StatusForbidden)
+			return
+		}
+		data, err := d.Get(r.Context(), key)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "could not read file", http.StatusInternalServerError)
+			return
+		}
+		contentType := mime.TypeByExtension(path.Ext(key))
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if mediaType, _, _ := mime.ParseMediaType(contentType); !inlineTypes[mediaType] {
+			w.Header().Set("Content-Security-Policy", "sandbox")
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/sigv4"
+)
+
+// maxPresignTTL is the longest a presigned S3 link can last.
+const maxPresignTTL = 7 * 24 * time.Hour
+
+// S3Config configures an S3 or S3-compatible bucket, such as MinIO.
+type S3Config struct {
+	Bucket string
+	Region string
+	// Endpoint is the S3 API URL, such as http://localhost:9000 for MinIO.
+	// Empty means AWS in Region.
+	Endpoint string
+	// PathStyle puts the bucket in the path rather than the host name, as
+	// MinIO and most other S3-compatible servers expect.
+	PathStyle       bool
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3 keeps blobs as objects in a bucket, signing requests with AWS
+// Signature Version 4. Its links are presigned GET URLs.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	http     *http.Client
+}
+
+// NewS3 returns an S3 store for cfg.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("storage: S3 needs a bucket, an access key ID, and a secret access key")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("storage: S3 endpoint must be an absolute http(s) URL (got %q)", endpoint)
+	}
+	return &S3{cfg: cfg, endpoint: u, http: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// objectURL returns the URL of key in the bucket, with its path escaped the
+// way Signature Version 4 expects.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	prefix := u.Path
+	if s.cfg.PathStyle {
+		prefix += "/" + s.cfg.Bucket
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = prefix + "/" + key
+	u.RawPath = sigv4.EscapePath(prefix) + "/" + sigv4.EscapePath(key)
+	return &u
+}
+
+// Put uploads data as the object at key.
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	_, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	return err
+}
+
+// Get downloads the object at key.
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil, "")
+}
+
+// Delete removes the object at key. S3 doesn't treat deleting a missing
+// object as an error either.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	return err
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body []byte, contentType string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("storage: invalid key %q", key)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL = s.objectURL(key)
+	req.Host = req.URL.Host
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, body, time.Now())
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 %s %s returned %s: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// SignedURL returns a presigned GET link to key. S3 caps presigned links at
+// seven days, so longer ttls are shortened.
+func (s *S3) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return s.presign(key, ttl, time.Now()), nil
+}
+
+func (s *S3) presign(key string, ttl time.Duration, now time.Time) string {
+	ttl = min(max(ttl, time.Second), maxPresignTTL)
+	return s.signer().Presign(http.MethodGet, s.objectURL(key), ttl, now).String()
+}
+
+// sign adds Signature Version 4 headers to req, signing every header it has.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	req.Header.Set("X-Amz-Content-Sha256", sigv4.HashPayload(body))
+	s.signer().Sign(req, body, now)
+}
+
+func (s *S3) signer() sigv4.Signer {
+	return sigv4.Signer{
+		Credentials: sigv4.Credentials{
+			AccessKeyID:     s.cfg.AccessKeyID,
+			SecretAccessKey: s.cfg.SecretAccessKey,
+			SessionToken:    s.cfg.SessionToken,
+		},
+		Region:  s.cfg.Region,
+		Service: "s3",
+	}
+}
@@ -0,0 +1,72 @@
+// Package storage keeps files such as media attachments and export bundles,
+// on local disk or in an S3-compatible bucket, and hands out time-limited
+// links to them.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a key with no blob.
+var ErrNotFound = errors.New("storage: blob not found")
+
+// BlobStore stores blobs by key. Keys are slash-separated paths such as
+// "media/42/photo.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a link anyone can download the blob from until ttl
+	// has passed.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Backends for Config.Backend.
+const (
+	BackendDisk = "disk"
+	BackendS3   = "s3"
+)
+
+// Config selects and configures a BlobStore.
+type Config struct {
+	Backend string
+	// Dir is where the disk backend keeps blobs.
+	Dir string
+	// BaseURL is the public URL the disk backend's Handler is served at,
+	// such as https://memo.example.com/files. Without it the disk backend
+	// can't make links.
+	BaseURL string
+	// SigningKey signs the disk backend's links.
+	SigningKey []byte
+	S3         S3Config
+}
+
+// New returns the BlobStore cfg selects.
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", BackendDisk:
+		return NewDisk(cfg.Dir, cfg.BaseURL, cfg.SigningKey), nil
+	case BackendS3:
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q (want %s or %s)", cfg.Backend, BackendDisk, BackendS3)
+	}
+}
+
+// validKey reports whether key is a relative path without empty, "." or
+// ".." segments, so no backend can be led outside its root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -1,14 +1,27 @@
 package twilio
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	// "github.com/caarlos0/env/v11"
 	twilio "github.com/twilio/twilio-go"
+	"github.com/twilio/twilio-go/client"
 	openapi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
+// NotJoinedCode is the Twilio error code for a message to a number that
+// hasn't joined the WhatsApp sandbox, or whose 3-day membership lapsed.
+const NotJoinedCode = 63015
+
+// IsNotJoined reports whether err is Twilio refusing a message because the
+// recipient isn't in the WhatsApp sandbox.
+func IsNotJoined(err error) bool {
+	var restErr *client.TwilioRestError
+	return errors.As(err, &restErr) && restErr.Code == NotJoinedCode
+}
+
 // Client wraps Twilio messaging operations required by the bot.
 type Client struct {
 	client       *twilio.RestClient
@@ -25,6 +38,20 @@ func New(accountSID, authToken, fromWhatsApp string) *Client {
 
 // SendWhatsAppMessage sends a WhatsApp message via Twilio's API.
 func (c *Client) SendWhatsAppMessage(to, body string) error {
+	return c.SendWhatsAppMessageWithStatus(to, body, "")
+}
+
+// SendWhatsAppMessageWithStatus sends a WhatsApp message and asks Twilio to
+// post its status changes, such as delivered and read, to statusCallback.
+// An empty statusCallback sends without callbacks.
+func (c *Client) SendWhatsAppMessageWithStatus(to, body, statusCallback string) error {
+	return c.SendWhatsAppMedia(to, body, "", statusCallback)
+}
+
+// SendWhatsAppMedia sends a WhatsApp message with the file at mediaURL
+// attached. Twilio downloads the file, so the URL must be public. An empty
+// mediaURL sends text only.
+func (c *Client) SendWhatsAppMedia(to, body, mediaURL, statusCallback string) error {
 	if c.client == nil {
 		return fmt.Errorf("twilio client not initialised")
 	}
@@ -45,7 +72,14 @@ func (c *Client) SendWhatsAppMessage(to, body string) error {
 	params.SetTo(recipient)
 	params.SetFrom(sender)
 	params.SetBody(body)
+	if mediaURL != "" {
+		params.SetMediaUrl([]string{mediaURL})
+	}
+	if statusCallback != "" {
+		params.SetStatusCallback(statusCallback)
+	}
 
+	outbound.Wait()
 	resp, err := c.client.Api.CreateMessage(params)
 	if err != nil {
 		return fmt.Errorf("twilio send message error: %w", err)
@@ -55,6 +89,31 @@ func (c *Client) SendWhatsAppMessage(to, body string) error {
 	return err
 }
 
+// PlaceCall phones to from the voice number from and runs twiml when the
+// call is answered.
+func (c *Client) PlaceCall(from, to, twiml string) error {
+	if c.client == nil {
+		return fmt.Errorf("twilio client not initialised")
+	}
+	from = normalizePhoneNumber(from)
+	to = normalizePhoneNumber(to)
+	if from == "" || to == "" {
+		return fmt.Errorf("caller and callee numbers are required")
+	}
+
+	params := &openapi.CreateCallParams{}
+	params.SetTo(to)
+	params.SetFrom(from)
+	params.SetTwiml(twiml)
+
+	resp, err := c.client.Api.CreateCall(params)
+	if err != nil {
+		return fmt.Errorf("twilio create call error: %w", err)
+	}
+	fmt.Printf("Twilio call placed, SID: %s\n", *resp.Sid)
+	return nil
+}
+
 func normalizeWhatsAppAddress(number string) string {
 	trimmed := strings.TrimSpace(number)
 	if trimmed == "" {
@@ -68,3 +127,12 @@ func normalizeWhatsAppAddress(number string) string {
 	}
 	return "whatsapp:+" + trimmed
 }
+
+// normalizePhoneNumber turns a WhatsApp address or bare number into E.164.
+func normalizePhoneNumber(number string) string {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(number), "whatsapp:")
+	if trimmed == "" || strings.HasPrefix(trimmed, "+") {
+		return trimmed
+	}
+	return "+" + trimmed
+}
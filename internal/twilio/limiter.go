@@ -0,0 +1,68 @@
+package twilio
+
+import (
+	"sync"
+	"time"
+)
+
+// outbound paces WhatsApp messages sent by every Client, so a large dispatch
+// and webhook replies together stay under Twilio's messaging limit instead of
+// running into 429s.
+var outbound = &Limiter{}
+
+// SetRateLimit caps outbound WhatsApp messages at perSecond, shared by all
+// clients. Zero or less removes the cap.
+func SetRateLimit(perSecond int) {
+	outbound.SetRate(perSecond)
+}
+
+// Limiter is a token bucket that refills at a fixed rate and holds up to one
+// second's worth of tokens. The zero value lets everything through.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second; 0 means unlimited
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a Limiter allowing perSecond events a second.
+func NewLimiter(perSecond int) *Limiter {
+	l := &Limiter{}
+	l.SetRate(perSecond)
+	return l
+}
+
+// SetRate changes the rate, starting with a full bucket.
+func (l *Limiter) SetRate(perSecond int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = max(float64(perSecond), 0)
+	l.tokens = l.rate
+	l.last = time.Now()
+}
+
+// Wait blocks until a token is available and takes it.
+func (l *Limiter) Wait() {
+	if delay := l.reserve(time.Now()); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// reserve takes a token, going into debt if the bucket is empty, and returns
+// how long the caller must wait for it.
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate == 0 {
+		return 0
+	}
+	if now.After(l.last) {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.rate)
+		l.last = now
+	}
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
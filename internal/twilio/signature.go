@@ -0,0 +1,46 @@
+package twilio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SignatureHeader carries Twilio's signature of a webhook request.
+const SignatureHeader = "X-Twilio-Signature"
+
+// Signature returns the signature Twilio sends with a form POST to fullURL:
+// the base64 HMAC-SHA1, keyed by the account's auth token, of the URL
+// followed by every form field name and value in sorted order.
+func Signature(authToken, fullURL string, form url.Values) string {
+	var sb strings.Builder
+	sb.WriteString(fullURL)
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := append([]string(nil), form[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			sb.WriteString(name)
+			sb.WriteString(value)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(sb.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ValidSignature reports whether signature is Twilio's signature of a form
+// POST to fullURL. It is always false without an auth token.
+func ValidSignature(authToken, fullURL string, form url.Values, signature string) bool {
+	if authToken == "" || signature == "" {
+		return false
+	}
+	return hmac.Equal([]byte(Signature(authToken, fullURL, form)), []byte(signature))
+}
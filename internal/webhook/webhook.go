@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pathakanu/myMemo/internal/netguard"
+)
+
+// Event types emitted over outbound webhooks.
+const (
+	EventReminderCreated   = "reminder.created"
+	EventReminderUpdated   = "reminder.updated"
+	EventReminderSent      = "reminder.sent"
+	EventReminderCompleted = "reminder.completed"
+	EventReminderDeleted   = "reminder.deleted"
+)
+
+// EventTypes lists every event type a webhook can subscribe to.
+var EventTypes = []string{
+	EventReminderCreated,
+	EventReminderUpdated,
+	EventReminderSent,
+	EventReminderCompleted,
+	EventReminderDeleted,
+}
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body.
+const SignatureHeader = "X-MyMemo-Signature"
+
+// Event is the JSON payload delivered to webhook endpoints.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	UserID     string    `json:"user_id"`
+	Reminder   Reminder  `json:"reminder"`
+}
+
+// Reminder is the reminder snapshot included in an Event.
+type Reminder struct {
+	ID       uint   `json:"id"`
+	Content  string `json:"content,omitempty"`
+	Summary  string `json:"summary,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Streak   int    `json:"streak,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Client delivers signed events to webhook endpoints.
+type Client struct {
+	http     *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// New returns a Client that retries failed deliveries a few times. It only
+// delivers to public addresses, so an endpoint can't reach the server's own
+// network.
+func New() *Client {
+	return NewWithClient(netguard.NewClient(10 * time.Second))
+}
+
+// NewWithClient returns a Client that delivers with client, mainly for tests.
+func NewWithClient(client *http.Client) *Client {
+	return &Client{
+		http:     client,
+		attempts: 3,
+		backoff:  2 * time.Second,
+	}
+}
+
+// NewEvent builds an event with a random ID stamped with the current time.
+func NewEvent(eventType, userID string, reminder Reminder) Event {
+	return Event{
+		ID:         newID(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		UserID:     userID,
+		Reminder:   reminder,
+	}
+}
+
+// Deliver posts event to url, signing the body with secret. Non-2xx
+// responses and transport errors are retried with linear backoff.
+func (c *Client) Deliver(ctx context.Context, url, secret string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	signature := Sign(secret, body)
+
+	var lastErr error
+	for attempt := 1; attempt <= c.attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt-1) * c.backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "myMemo-Webhooks/1.0")
+		req.Header.Set("X-MyMemo-Event", event.Type)
+		req.Header.Set("X-MyMemo-Delivery", event.ID)
+		req.Header.Set(SignatureHeader, "sha256="+signature)
+
+		resp, err := c.http.Do(req)
+		if errors.Is(err, netguard.ErrPrivateAddress) {
+			return fmt.Errorf("deliver %s to %s: %w", event.Type, url, err)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("deliver %s to %s: %w", event.Type, url, lastErr)
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a random signing secret for a new webhook.
+func NewSecret() string {
+	return "whsec_" + newID()
+}
+
+func newID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -15,55 +17,172 @@ import (
 	"github.com/pathakanu/myMemo/internal/database"
 	myopenai "github.com/pathakanu/myMemo/internal/openai"
 	"github.com/pathakanu/myMemo/internal/twilio"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
 )
 
 func main() {
 	logger := log.New(os.Stdout, "[myMemo] ", log.LstdFlags|log.Lshortfile)
 	cfg := config.Load()
-	// fmt.Println("Configuration loaded: ", cfg)
+	secretsCtx, cancelSecrets := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := cfg.LoadSecrets(secretsCtx); err != nil {
+		logger.Fatalf("secrets: %v", err)
+	}
+	cancelSecrets()
+	if err := cfg.Validate(); err != nil {
+		logger.Fatalf("invalid configuration:\n%v", err)
+	}
 
-	db, err := database.New(cfg.DatabaseURL)
+	db, err := database.New(cfg.DatabaseURL, database.Options{
+		MaxOpenConns:     cfg.DBMaxOpenConns,
+		MaxIdleConns:     cfg.DBMaxIdleConns,
+		ConnMaxLifetime:  cfg.DBConnMaxLifetime,
+		StatementTimeout: cfg.DBStatementTimeout,
+		LogLevel:         cfg.LogLevel,
+	})
 	if err != nil {
 		logger.Fatalf("database init failed: %v", err)
 	}
 
-	openAIClient := myopenai.New(cfg.OpenAIAPIKey)
+	openAIClient := myopenai.NewWithOptions(cfg.OpenAIAPIKey, myopenai.Options{
+		BaseURL:         cfg.OpenAIBaseURL,
+		AzureDeployment: cfg.OpenAIDeployment,
+		APIVersion:      cfg.OpenAIAPIVersion,
+	})
 	fmt.Println("Twilio WhatsApp Number:", cfg.TwilioWhatsAppNumber)
+	twilio.SetRateLimit(cfg.TwilioMessagesPerSecond)
 	twilioClient := twilio.New(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioWhatsAppNumber)
 
 	reminderBot := bot.New(cfg, db, openAIClient, twilioClient, logger)
 	if err := reminderBot.StartScheduler(); err != nil {
 		logger.Fatalf("scheduler start: %v", err)
 	}
+	registerCtx, cancelRegister := context.WithTimeout(context.Background(), 15*time.Second)
+	if err := reminderBot.RegisterDiscordCommands(registerCtx); err != nil {
+		logger.Printf("discord: register commands: %v", err)
+	}
+	cancelRegister()
 
-	http.Handle("/twilio/webhook", reminderBot.Handler())
+	// A mux of our own keeps the handlers net/http/pprof registers on
+	// http.DefaultServeMux from being served without auth.
+	mux := http.NewServeMux()
+	mux.Handle("/twilio/webhook", reminderBot.LogRequests(reminderBot.Handler()))
+	mux.Handle("/twilio/status", reminderBot.LogRequests(reminderBot.StatusCallbackHandler()))
+	mux.Handle("/join", reminderBot.JoinHandler())
+	mux.Handle("/join/", reminderBot.JoinHandler())
+	mux.Handle("/discord/interactions", reminderBot.DiscordInteractionsHandler())
+	mux.Handle("/email/inbound", reminderBot.InboundEmailHandler())
+	mux.Handle("/admin/scheduler", reminderBot.AdminSchedulerHandler())
+	mux.Handle("/admin/notion", reminderBot.AdminNotionHandler())
+	mux.Handle("/admin/users", reminderBot.AdminUsersHandler())
+	mux.Handle("/admin/reminders", reminderBot.AdminRemindersHandler())
+	mux.Handle("/admin/dispatch", reminderBot.AdminDispatchHandler())
+	mux.Handle("/admin/export", reminderBot.AdminExportHandler())
+	mux.Handle("/admin/import", reminderBot.AdminImportHandler())
+	mux.Handle("/admin/tenants", reminderBot.AdminTenantsHandler())
+	mux.Handle("/admin/openai", reminderBot.AdminOpenAIHandler())
+	mux.Handle("/admin/metrics", reminderBot.AdminMetricsHandler())
+	mux.Handle("/debug/", reminderBot.DebugHandler())
+	mux.Handle("/api/", reminderBot.LogRequests(reminderBot.APIHandler()))
+	mux.Handle("/auth/", reminderBot.AuthHandler())
+	mux.Handle("/files/", reminderBot.FilesHandler())
+	mux.Handle("/", reminderBot.DashboardHandler())
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
-		Handler: nil,
+		Handler: mux,
+	}
+	servers := []*http.Server{server}
+
+	if len(cfg.TLSDomains) > 0 {
+		// Let's Encrypt certificates for the configured domains. Port 80
+		// answers HTTP-01 challenges and redirects everything else to HTTPS.
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSDomains...),
+			Cache:      autocert.DirCache(cfg.TLSCacheDir),
+			Email:      cfg.TLSEmail,
+		}
+		server.Addr = ":443"
+		server.TLSConfig = manager.TLSConfig()
+		challenges := &http.Server{
+			Addr:    ":80",
+			Handler: manager.HTTPHandler(nil),
+		}
+		servers = append(servers, challenges)
+		go func() {
+			if err := challenges.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("acme challenge server error: %v", err)
+			}
+		}()
 	}
 
 	go func() {
-		logger.Printf("server starting on :%s", cfg.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			logger.Printf("server starting on :443 for %s", strings.Join(cfg.TLSDomains, ", "))
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			logger.Printf("server starting on :%s", cfg.Port)
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Fatalf("server error: %v", err)
 		}
 	}()
 
-	waitForShutdown(server, reminderBot, logger)
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			logger.Fatalf("grpc listen: %v", err)
+		}
+		grpcServer = reminderBot.GRPCServer()
+		go func() {
+			logger.Printf("grpc server starting on :%s", cfg.GRPCPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Fatalf("grpc server error: %v", err)
+			}
+		}()
+	}
+
+	waitForShutdown(servers, grpcServer, reminderBot, logger)
 }
 
-func waitForShutdown(server *http.Server, reminderBot *bot.Bot, logger *log.Logger) {
-	stopCtx := make(chan os.Signal, 1)
-	signal.Notify(stopCtx, syscall.SIGINT, syscall.SIGTERM)
-	<-stopCtx
+func waitForShutdown(servers []*http.Server, grpcServer *grpc.Server, reminderBot *bot.Bot, logger *log.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		logger.Println("reloading configuration...")
+		if err := reminderBot.Reload(config.Reload()); err != nil {
+			logger.Printf("reload failed, keeping previous settings: %v", err)
+		}
+	}
 	logger.Println("shutting down...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Printf("server shutdown error: %v", err)
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Printf("server shutdown error: %v", err)
+		}
+	}
+	if grpcServer != nil {
+		reminderBot.CloseEventStreams()
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
 	}
-	reminderBot.StopScheduler()
+	reminderBot.StopScheduler(ctx)
 }